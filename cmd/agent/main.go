@@ -27,15 +27,20 @@
 	"github.com/rancher/rancher/pkg/agent/clean"
 	"github.com/rancher/rancher/pkg/agent/clean/adunmigration"
 	"github.com/rancher/rancher/pkg/agent/cluster"
+	"github.com/rancher/rancher/pkg/agent/mgmtcache"
 	"github.com/rancher/rancher/pkg/agent/node"
+	"github.com/rancher/rancher/pkg/agent/preflight"
 	"github.com/rancher/rancher/pkg/agent/rancher"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/cavalidator"
 	"github.com/rancher/rancher/pkg/features"
 	"github.com/rancher/rancher/pkg/logserver"
+	"github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/rkenodeconfigclient"
+	"github.com/rancher/rancher/pkg/tunnelserver/tunnelheaders"
 	"github.com/rancher/remotedialer"
 	"github.com/rancher/wrangler/v3/pkg/signals"
 	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
 )
 
 var (
@@ -90,6 +95,8 @@ func runArgs(ctx context.Context) error {
 	switch os.Args[1] {
 	case "clean":
 		return clean.Run(ctx, os.Args)
+	case "preflight":
+		return preflight.Run(ctx)
 	default:
 		return run(ctx)
 	}
@@ -202,6 +209,7 @@ func run(ctx context.Context) error {
 	headers := http.Header{
 		Token:                      {token},
 		rkenodeconfigclient.Params: {base64.StdEncoding.EncodeToString(bytes)},
+		tunnelheaders.AgentVersion: {VERSION},
 	}
 
 	serverURL, err := url.Parse(server)
@@ -331,8 +339,18 @@ func run(ctx context.Context) error {
 		}
 	}
 
+	var agentCache *mgmtcache.Cache
+	if isCluster() {
+		if cfg, err := rest.InClusterConfig(); err != nil {
+			logrus.Warnf("Unable to start the agent cache: %v", err)
+		} else if agentCache, err = mgmtcache.Start(topContext, cfg, namespace.System); err != nil {
+			logrus.Warnf("Unable to start the agent cache: %v", err)
+		}
+	}
+
 	onConnect := func(ctx context.Context, _ *remotedialer.Session) error {
 		connected()
+		agentCache.Connected()
 		connectConfig := fmt.Sprintf("https://%s/v3/connect/config", serverURL.Host)
 		httpClient := http.Client{
 			Timeout: 300 * time.Second,
@@ -408,6 +426,7 @@ func run(ctx context.Context) error {
 			}
 			return false
 		}, onConnect)
+		agentCache.Disconnected()
 		time.Sleep(5 * time.Second)
 	}
 }
@@ -0,0 +1,542 @@
+// Package mgmtcache keeps a persistent copy of the ClusterAuthTokens and ClusterUserAttributes the management plane
+// syncs to the cluster, so that authentication through the local cluster auth endpoint keeps working for the cached
+// tokens while the agent is disconnected from Rancher.
+//
+// The cache follows the objects of the cluster, including their deletion, which is authoritative. The cached objects
+// are only restored when the agent starts and doesn't connect to Rancher, ie. after the cluster was restored from a
+// backup. Once the agent has been disconnected for longer than the configured staleness limit the tokens are
+// disabled, since they may have been revoked in Rancher in the meantime. When the agent reconnects, the tokens it
+// disabled are restored and the management plane reconciles them.
+//
+// The cache is persisted in a secret, encrypted with a key kept in another namespace. Only the leader of the cluster
+// agent replicas writes the cache and the objects.
+package mgmtcache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	clusterv3 "github.com/rancher/rancher/pkg/apis/cluster.cattle.io/v3"
+	clusterfactory "github.com/rancher/rancher/pkg/generated/controllers/cluster.cattle.io"
+	clustercontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/generated/controllers/apiextensions.k8s.io"
+	corefactory "github.com/rancher/wrangler/v3/pkg/generated/controllers/core"
+	"github.com/rancher/wrangler/v3/pkg/kv"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// DisabledAnnotation is set on the ClusterAuthTokens the agent disabled because the cache exceeded its staleness
+	// limit.
+	DisabledAnnotation = "cluster.cattle.io/disabled-by-agent-cache"
+
+	clusterAuthTokenCRD = "clusterauthtokens.cluster.cattle.io"
+	leaderLockName      = "cattle-agent-cache"
+	persistInterval     = 30 * time.Second
+	// heartbeatInterval is how often the last connection time is persisted while the agent is connected, precise
+	// enough for a staleness limit in hours without writing the cache all the time.
+	heartbeatInterval = 10 * time.Minute
+	// startupGrace is how long the agent waits to connect when it starts before restoring the cached objects, since
+	// the agent it replaces may still be connected and syncing them.
+	startupGrace = time.Minute
+	// retryInterval is how long the agent waits before retrying to elect the leader or to start the cache.
+	retryInterval = 30 * time.Second
+)
+
+type cachedToken struct {
+	UserName      string `json:"userName"`
+	ExpiresAt     string `json:"expiresAt,omitempty"`
+	SecretKeyHash string `json:"hash"`
+	Enabled       bool   `json:"enabled"`
+}
+
+type cachedUserAttribute struct {
+	Groups          []string                       `json:"groups,omitempty"`
+	LastRefresh     string                         `json:"lastRefresh,omitempty"`
+	NeedsRefresh    bool                           `json:"needsRefresh"`
+	Enabled         bool                           `json:"enabled"`
+	ExtraByProvider map[string]map[string][]string `json:"extraByProvider,omitempty"`
+}
+
+// snapshot is the persisted content of the cache.
+type snapshot struct {
+	// LastConnected is the last time the agent was known to be connected to Rancher.
+	LastConnected  time.Time                      `json:"lastConnected"`
+	Tokens         map[string]cachedToken         `json:"tokens"`
+	UserAttributes map[string]cachedUserAttribute `json:"userAttributes"`
+}
+
+// Cache caches the ClusterAuthTokens and ClusterUserAttributes of the cluster. The methods of a nil Cache do nothing,
+// so that the agent runs without one when the local cluster auth endpoint is not enabled.
+type Cache struct {
+	namespace      string
+	tokens         clustercontrollers.ClusterAuthTokenController
+	userAttributes clustercontrollers.ClusterUserAttributeController
+	store          store
+	maxStaleness   time.Duration
+	now            func() time.Time
+
+	lock sync.Mutex
+	// leader is set once the agent is the leader of the replicas and started when it became it.
+	leader  bool
+	started time.Time
+	// connected is set while the agent is connected to Rancher. restoreDone is set once the cached objects were
+	// restored, or the agent connected, after it started.
+	connected     bool
+	restoreDone   bool
+	dirty         bool
+	staleEnforced bool
+	state         snapshot
+}
+
+// Start starts caching the objects of the namespace once the agent is the leader of the replicas, loading the cache
+// persisted by the previous leader. It returns a nil Cache when the local cluster auth endpoint is not enabled in the
+// cluster.
+func Start(ctx context.Context, cfg *rest.Config, namespace string) (*Cache, error) {
+	crds, err := apiextensions.NewFactoryFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := crds.Apiextensions().V1().CustomResourceDefinition().Get(clusterAuthTokenCRD, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		logrus.Debugf("[agentCache] %s not found, the local cluster auth endpoint is disabled", clusterAuthTokenCRD)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	clusters, err := clusterfactory.NewFactoryFromConfigWithNamespace(cfg, namespace)
+	if err != nil {
+		return nil, err
+	}
+	core, err := corefactory.NewFactoryFromConfigWithNamespace(cfg, namespace)
+	if err != nil {
+		return nil, err
+	}
+	k8s, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCache(namespace,
+		clusters.Cluster().V3().ClusterAuthToken(),
+		clusters.Cluster().V3().ClusterUserAttribute(),
+		&secretStore{namespace: namespace, secrets: core.Core().V1().Secret()},
+		maxStaleness())
+
+	// the cache is optional: failures are logged and retried, they don't stop the agent
+	go func() {
+		for {
+			if err := runAsLeader(ctx, namespace, k8s, func(leaderCtx context.Context) {
+				c.lead(ctx, leaderCtx, clusters.Start)
+			}, c.stopLeading); err != nil {
+				logrus.Errorf("[agentCache] failed to elect the leader of the agent replicas: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryInterval):
+			}
+		}
+	}()
+	return c, nil
+}
+
+// runAsLeader runs lead while the agent is the leader of the replicas, until it loses the lease or ctx is done. Unlike
+// leader.RunOrDie, losing the lease doesn't stop the agent.
+func runAsLeader(ctx context.Context, namespace string, k8s kubernetes.Interface, lead func(context.Context), stopped func()) error {
+	id, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, namespace, leaderLockName, k8s.CoreV1(), k8s.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: id})
+	if err != nil {
+		return err
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   45 * time.Second,
+		RenewDeadline:   30 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: lead,
+			OnStoppedLeading: stopped,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	elector.Run(ctx)
+	return nil
+}
+
+// lead loads the cache persisted by the previous leader and runs the handlers until leaderCtx is done. The caches of
+// the objects are started with ctx, once, and keep running when the agent loses the lease.
+func (c *Cache) lead(ctx, leaderCtx context.Context, start func(context.Context, int) error) {
+	c.load()
+	c.lock.Lock()
+	c.leader = true
+	c.started = c.now()
+	c.lock.Unlock()
+
+	c.tokens.OnChange(leaderCtx, "agent-cache-tokens", c.onToken)
+	c.userAttributes.OnChange(leaderCtx, "agent-cache-user-attributes", c.onUserAttribute)
+	for {
+		err := start(ctx, 1)
+		if err == nil {
+			break
+		}
+		logrus.Errorf("[agentCache] failed to start, retrying in %s: %v", retryInterval, err)
+		select {
+		case <-leaderCtx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+
+	ticker := time.NewTicker(persistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.tick()
+		case <-leaderCtx.Done():
+			return
+		}
+	}
+}
+
+// stopLeading records that the agent lost the lease, so that it no longer writes the cache and the objects.
+func (c *Cache) stopLeading() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.leader = false
+}
+
+func newCache(namespace string, tokens clustercontrollers.ClusterAuthTokenController, userAttributes clustercontrollers.ClusterUserAttributeController,
+	store store, maxStaleness time.Duration) *Cache {
+	return &Cache{
+		namespace:      namespace,
+		tokens:         tokens,
+		userAttributes: userAttributes,
+		store:          store,
+		maxStaleness:   maxStaleness,
+		now:            time.Now,
+		started:        time.Now(),
+		state: snapshot{
+			Tokens:         map[string]cachedToken{},
+			UserAttributes: map[string]cachedUserAttribute{},
+		},
+	}
+}
+
+// maxStaleness returns the staleness limit the agent is deployed with, the default of the setting if it is not set or
+// invalid.
+func maxStaleness() time.Duration {
+	value := os.Getenv(settings.GetEnvKey(settings.AgentCacheMaxStaleness.Name))
+	if value == "" {
+		value = settings.AgentCacheMaxStaleness.Default
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Warnf("[agentCache] invalid %s %q, using %s: %v", settings.AgentCacheMaxStaleness.Name, value, settings.AgentCacheMaxStaleness.Default, err)
+		d, _ = time.ParseDuration(settings.AgentCacheMaxStaleness.Default)
+	}
+	return d
+}
+
+// Connected records that the agent connected to Rancher and restores the tokens disabled while it was disconnected.
+func (c *Cache) Connected() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.connected = true
+	c.restoreDone = true
+	c.staleEnforced = false
+	c.state.LastConnected = c.now()
+	c.dirty = true
+	isLeader := c.leader
+	c.lock.Unlock()
+
+	if !isLeader {
+		return
+	}
+	c.persist()
+	tokens, err := c.tokens.Cache().List(c.namespace, labels.Everything())
+	if err != nil {
+		logrus.Errorf("[agentCache] failed to list the cluster auth tokens: %v", err)
+		return
+	}
+	for _, token := range tokens {
+		if token.Annotations[DisabledAnnotation] == "true" {
+			c.tokens.Enqueue(token.Namespace, token.Name)
+		}
+	}
+}
+
+// Disconnected records that the agent lost its connection to Rancher.
+func (c *Cache) Disconnected() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	if !c.connected {
+		c.lock.Unlock()
+		return
+	}
+	c.connected = false
+	c.state.LastConnected = c.now()
+	c.dirty = true
+	c.lock.Unlock()
+
+	c.persist()
+}
+
+func (c *Cache) load() {
+	data, err := c.store.load()
+	if err != nil {
+		logrus.Errorf("[agentCache] failed to load the cache: %v", err)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	var state snapshot
+	if err := json.Unmarshal(data, &state); err != nil {
+		logrus.Warnf("[agentCache] discarding the persisted cache: %v", err)
+		return
+	}
+	if state.Tokens == nil {
+		state.Tokens = map[string]cachedToken{}
+	}
+	if state.UserAttributes == nil {
+		state.UserAttributes = map[string]cachedUserAttribute{}
+	}
+	c.lock.Lock()
+	c.state = state
+	c.lock.Unlock()
+	logrus.Infof("[agentCache] loaded %d cluster auth tokens last synced with Rancher at %s", len(state.Tokens), state.LastConnected.Format(time.RFC3339))
+}
+
+// persist writes the cache if it changed since it was last written and the agent is the leader.
+func (c *Cache) persist() {
+	c.lock.Lock()
+	if !c.dirty || !c.leader {
+		c.lock.Unlock()
+		return
+	}
+	data, err := json.Marshal(c.state)
+	c.dirty = false
+	c.lock.Unlock()
+	if err != nil {
+		logrus.Errorf("[agentCache] failed to encode the cache: %v", err)
+		return
+	}
+
+	if err := c.store.save(data); err != nil {
+		logrus.Errorf("[agentCache] failed to persist the cache: %v", err)
+		c.lock.Lock()
+		c.dirty = true
+		c.lock.Unlock()
+	}
+}
+
+// tick refreshes the last connection time, restores the cached objects if the agent did not connect after it
+// started, enforces the staleness limit and persists the cache if it changed.
+func (c *Cache) tick() {
+	c.lock.Lock()
+	if c.connected && c.now().Sub(c.state.LastConnected) >= heartbeatInterval {
+		c.state.LastConnected = c.now()
+		c.dirty = true
+	}
+	restore := !c.connected && !c.restoreDone && c.now().Sub(c.started) > startupGrace
+	if restore {
+		c.restoreDone = true
+	}
+	enforce := c.stale() && !c.staleEnforced
+	if enforce {
+		c.staleEnforced = true
+		logrus.Warnf("[agentCache] disconnected from Rancher since %s, disabling the cluster auth tokens", c.state.LastConnected.Format(time.RFC3339))
+	}
+	c.lock.Unlock()
+
+	if restore {
+		c.restore()
+	}
+	if enforce {
+		c.enqueueTokens()
+	}
+	c.persist()
+}
+
+// stale returns whether the agent has been disconnected for longer than the staleness limit. It must be called with
+// the lock held.
+func (c *Cache) stale() bool {
+	return !c.connected && c.maxStaleness > 0 && !c.state.LastConnected.IsZero() && c.now().Sub(c.state.LastConnected) > c.maxStaleness
+}
+
+func (c *Cache) enqueueTokens() {
+	tokens, err := c.tokens.Cache().List(c.namespace, labels.Everything())
+	if err != nil {
+		logrus.Errorf("[agentCache] failed to list the cluster auth tokens: %v", err)
+		return
+	}
+	for _, token := range tokens {
+		c.tokens.Enqueue(token.Namespace, token.Name)
+	}
+}
+
+// restore creates the cached objects missing when the agent started.
+func (c *Cache) restore() {
+	c.lock.Lock()
+	tokens := make(map[string]cachedToken, len(c.state.Tokens))
+	for name, cached := range c.state.Tokens {
+		tokens[name] = cached
+	}
+	userAttributes := make(map[string]cachedUserAttribute, len(c.state.UserAttributes))
+	for name, cached := range c.state.UserAttributes {
+		userAttributes[name] = cached
+	}
+	c.lock.Unlock()
+
+	for name, cached := range tokens {
+		if _, err := c.tokens.Cache().Get(c.namespace, name); !apierrors.IsNotFound(err) {
+			continue
+		}
+		logrus.Infof("[agentCache] restoring cluster auth token %s", name)
+		token := &clusterv3.ClusterAuthToken{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: c.namespace,
+			},
+		}
+		setToken(token, cached)
+		if _, err := c.tokens.Create(token); err != nil && !apierrors.IsAlreadyExists(err) {
+			logrus.Errorf("[agentCache] failed to restore cluster auth token %s: %v", name, err)
+		}
+	}
+
+	for name, cached := range userAttributes {
+		if _, err := c.userAttributes.Cache().Get(c.namespace, name); !apierrors.IsNotFound(err) {
+			continue
+		}
+		logrus.Infof("[agentCache] restoring cluster user attribute %s", name)
+		_, err := c.userAttributes.Create(&clusterv3.ClusterUserAttribute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: c.namespace,
+			},
+			Groups:          cached.Groups,
+			LastRefresh:     cached.LastRefresh,
+			NeedsRefresh:    cached.NeedsRefresh,
+			Enabled:         cached.Enabled,
+			ExtraByProvider: cached.ExtraByProvider,
+		})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			logrus.Errorf("[agentCache] failed to restore cluster user attribute %s: %v", name, err)
+		}
+	}
+}
+
+func (c *Cache) onToken(key string, obj *clusterv3.ClusterAuthToken) (*clusterv3.ClusterAuthToken, error) {
+	_, name := kv.RSplit(key, "/")
+
+	c.lock.Lock()
+	cached, isCached := c.state.Tokens[name]
+	stale := c.stale()
+	isLeader := c.leader
+	if obj == nil {
+		if isCached {
+			delete(c.state.Tokens, name)
+			c.dirty = true
+		}
+	} else if obj.Annotations[DisabledAnnotation] != "true" {
+		if current := toCachedToken(obj); !isCached || current != cached {
+			c.state.Tokens[name] = current
+			c.dirty = true
+		}
+	}
+	c.lock.Unlock()
+
+	if obj == nil || !isLeader {
+		return obj, nil
+	}
+	disabled := obj.Annotations[DisabledAnnotation] == "true"
+	switch {
+	case disabled && !stale:
+		// reconnected, restore the token as it was before it was disabled
+		obj = obj.DeepCopy()
+		delete(obj.Annotations, DisabledAnnotation)
+		if isCached {
+			obj.Enabled = cached.Enabled
+		}
+		return c.tokens.Update(obj)
+	case !disabled && stale && obj.Enabled:
+		obj = obj.DeepCopy()
+		if obj.Annotations == nil {
+			obj.Annotations = map[string]string{}
+		}
+		obj.Annotations[DisabledAnnotation] = "true"
+		obj.Enabled = false
+		return c.tokens.Update(obj)
+	}
+	return obj, nil
+}
+
+func (c *Cache) onUserAttribute(key string, obj *clusterv3.ClusterUserAttribute) (*clusterv3.ClusterUserAttribute, error) {
+	_, name := kv.RSplit(key, "/")
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached, isCached := c.state.UserAttributes[name]
+	if obj == nil {
+		if isCached {
+			delete(c.state.UserAttributes, name)
+			c.dirty = true
+		}
+	} else if current := toCachedUserAttribute(obj); !isCached || !reflect.DeepEqual(current, cached) {
+		c.state.UserAttributes[name] = current
+		c.dirty = true
+	}
+	return obj, nil
+}
+
+func toCachedToken(token *clusterv3.ClusterAuthToken) cachedToken {
+	return cachedToken{
+		UserName:      token.UserName,
+		ExpiresAt:     token.ExpiresAt,
+		SecretKeyHash: token.SecretKeyHash,
+		Enabled:       token.Enabled,
+	}
+}
+
+func setToken(token *clusterv3.ClusterAuthToken, cached cachedToken) {
+	token.UserName = cached.UserName
+	token.ExpiresAt = cached.ExpiresAt
+	token.SecretKeyHash = cached.SecretKeyHash
+	token.Enabled = cached.Enabled
+}
+
+func toCachedUserAttribute(attribute *clusterv3.ClusterUserAttribute) cachedUserAttribute {
+	return cachedUserAttribute{
+		Groups:          attribute.Groups,
+		LastRefresh:     attribute.LastRefresh,
+		NeedsRefresh:    attribute.NeedsRefresh,
+		Enabled:         attribute.Enabled,
+		ExtraByProvider: attribute.ExtraByProvider,
+	}
+}
@@ -0,0 +1,277 @@
+package mgmtcache
+
+import (
+	"testing"
+	"time"
+
+	clusterv3 "github.com/rancher/rancher/pkg/apis/cluster.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type memoryStore struct {
+	data []byte
+}
+
+func (m *memoryStore) load() ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *memoryStore) save(data []byte) error {
+	m.data = data
+	return nil
+}
+
+func newToken(name string, enabled bool) *clusterv3.ClusterAuthToken {
+	return &clusterv3.ClusterAuthToken{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "cattle-system",
+		},
+		UserName:      "u-1",
+		SecretKeyHash: "hash-" + name,
+		Enabled:       enabled,
+	}
+}
+
+type mocks struct {
+	tokens         *fake.MockControllerInterface[*clusterv3.ClusterAuthToken, *clusterv3.ClusterAuthTokenList]
+	tokenCache     *fake.MockCacheInterface[*clusterv3.ClusterAuthToken]
+	userAttributes *fake.MockControllerInterface[*clusterv3.ClusterUserAttribute, *clusterv3.ClusterUserAttributeList]
+}
+
+func newTestCache(t *testing.T, store store, maxStaleness time.Duration) (*Cache, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		tokens:         fake.NewMockControllerInterface[*clusterv3.ClusterAuthToken, *clusterv3.ClusterAuthTokenList](ctrl),
+		tokenCache:     fake.NewMockCacheInterface[*clusterv3.ClusterAuthToken](ctrl),
+		userAttributes: fake.NewMockControllerInterface[*clusterv3.ClusterUserAttribute, *clusterv3.ClusterUserAttributeList](ctrl),
+	}
+	m.tokens.EXPECT().Enqueue(gomock.Any(), gomock.Any()).AnyTimes()
+	m.tokens.EXPECT().Cache().Return(m.tokenCache).AnyTimes()
+	c := newCache("cattle-system", m.tokens, m.userAttributes, store, maxStaleness)
+	c.leader = true
+	return c, m
+}
+
+type countingStore struct {
+	memoryStore
+	saves int
+}
+
+func (c *countingStore) save(data []byte) error {
+	c.saves++
+	return c.memoryStore.save(data)
+}
+
+func TestPersistAndLoad(t *testing.T) {
+	store := &memoryStore{}
+	c, m := newTestCache(t, store, time.Hour)
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+
+	_, err := c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	c.persist()
+	require.NotEmpty(t, store.data)
+
+	loaded, _ := newTestCache(t, store, time.Hour)
+	loaded.load()
+	assert.Equal(t, c.state.Tokens, loaded.state.Tokens)
+	assert.False(t, loaded.state.LastConnected.IsZero())
+}
+
+func TestSecretStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	secrets := fake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](ctrl)
+	stored := map[string]*corev1.Secret{}
+	secrets.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(namespace, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+		if secret, ok := stored[namespace+"/"+name]; ok {
+			return secret, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}).AnyTimes()
+	save := func(secret *corev1.Secret) (*corev1.Secret, error) {
+		stored[secret.Namespace+"/"+secret.Name] = secret
+		return secret, nil
+	}
+	secrets.EXPECT().Create(gomock.Any()).DoAndReturn(save).AnyTimes()
+	secrets.EXPECT().Update(gomock.Any()).DoAndReturn(save).AnyTimes()
+	s := &secretStore{namespace: "cattle-system", secrets: secrets}
+
+	require.NoError(t, s.save([]byte(`{"tokens":{"t-1":{"hash":"hash-t-1"}}}`)))
+	require.Contains(t, stored, KeyNamespace+"/"+KeySecretName)
+	require.Contains(t, stored, "cattle-system/"+SecretName)
+	// the cache is encrypted and its key is kept in another namespace
+	assert.NotContains(t, string(stored["cattle-system/"+SecretName].Data[dataKey]), "hash-t-1")
+	assert.Len(t, stored[KeyNamespace+"/"+KeySecretName].Data[keyKey], keySize)
+
+	data, err := s.load()
+	require.NoError(t, err)
+	assert.Equal(t, `{"tokens":{"t-1":{"hash":"hash-t-1"}}}`, string(data))
+
+	// the cache can't be read without its key
+	delete(stored, KeyNamespace+"/"+KeySecretName)
+	_, err = s.load()
+	assert.Error(t, err)
+}
+
+func TestEncryption(t *testing.T) {
+	key := make([]byte, keySize)
+	encrypted, err := encrypt(key, []byte("data"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "data")
+
+	decrypted, err := decrypt(key, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(decrypted))
+
+	otherKey := make([]byte, keySize)
+	otherKey[0] = 1
+	_, err = decrypt(otherKey, encrypted)
+	assert.Error(t, err)
+}
+
+func TestPersistOnlyOnChangeFromTheLeader(t *testing.T) {
+	store := &countingStore{}
+	c, m := newTestCache(t, store, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+	assert.Equal(t, 1, store.saves)
+
+	// unchanged
+	now = now.Add(persistInterval)
+	c.tick()
+	assert.Equal(t, 1, store.saves)
+
+	_, err := c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	c.tick()
+	assert.Equal(t, 2, store.saves)
+
+	// the last connection time is only refreshed every heartbeatInterval
+	now = now.Add(heartbeatInterval)
+	c.tick()
+	assert.Equal(t, 3, store.saves)
+
+	// the other replicas don't write the cache
+	c.leader = false
+	_, err = c.onToken("cattle-system/t-2", newToken("t-2", true))
+	require.NoError(t, err)
+	c.tick()
+	assert.Equal(t, 3, store.saves)
+}
+
+func TestDeletesAreAuthoritative(t *testing.T) {
+	c, m := newTestCache(t, &memoryStore{}, time.Hour)
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+	_, err := c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	c.Disconnected()
+
+	// deleted and modified tokens are not reverted while disconnected
+	modified := newToken("t-1", true)
+	modified.SecretKeyHash = "another hash"
+	_, err = c.onToken("cattle-system/t-1", modified)
+	require.NoError(t, err)
+	assert.Equal(t, "another hash", c.state.Tokens["t-1"].SecretKeyHash)
+
+	_, err = c.onToken("cattle-system/t-1", nil)
+	require.NoError(t, err)
+	assert.NotContains(t, c.state.Tokens, "t-1")
+}
+
+func TestRestoreOnStartup(t *testing.T) {
+	store := &memoryStore{}
+	c, m := newTestCache(t, store, time.Hour)
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+	_, err := c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	_, err = c.onToken("cattle-system/t-2", newToken("t-2", true))
+	require.NoError(t, err)
+	c.persist()
+
+	restarted, m := newTestCache(t, store, time.Hour)
+	now := time.Now()
+	restarted.now = func() time.Time { return now }
+	restarted.started = now
+	restarted.load()
+
+	// a new agent which did not connect yet leaves the objects to the agent it replaces
+	restarted.tick()
+
+	m.tokenCache.EXPECT().Get("cattle-system", "t-1").Return(nil, apierrors.NewNotFound(schema.GroupResource{}, "t-1"))
+	m.tokenCache.EXPECT().Get("cattle-system", "t-2").Return(newToken("t-2", true), nil)
+	m.tokens.EXPECT().Create(gomock.Any()).DoAndReturn(func(token *clusterv3.ClusterAuthToken) (*clusterv3.ClusterAuthToken, error) {
+		assert.Equal(t, "t-1", token.Name)
+		assert.Equal(t, "hash-t-1", token.SecretKeyHash)
+		assert.True(t, token.Enabled)
+		return token, nil
+	})
+	now = now.Add(2 * startupGrace)
+	restarted.tick()
+
+	// the objects are only restored once
+	restarted.tick()
+}
+func TestStaleness(t *testing.T) {
+	c, m := newTestCache(t, &memoryStore{}, time.Hour)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+	_, err := c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	c.Disconnected()
+
+	now = now.Add(2 * time.Hour)
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return([]*clusterv3.ClusterAuthToken{newToken("t-1", true)}, nil)
+	c.tick()
+	assert.True(t, c.staleEnforced)
+
+	var disabled *clusterv3.ClusterAuthToken
+	m.tokens.EXPECT().Update(gomock.Any()).DoAndReturn(func(token *clusterv3.ClusterAuthToken) (*clusterv3.ClusterAuthToken, error) {
+		disabled = token
+		return token, nil
+	})
+	_, err = c.onToken("cattle-system/t-1", newToken("t-1", true))
+	require.NoError(t, err)
+	require.NotNil(t, disabled)
+	assert.False(t, disabled.Enabled)
+	assert.Equal(t, "true", disabled.Annotations[DisabledAnnotation])
+
+	// once reconnected, the token is restored as it was cached and the cache is not updated with the disabled token
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return([]*clusterv3.ClusterAuthToken{disabled}, nil)
+	c.Connected()
+	m.tokens.EXPECT().Update(gomock.Any()).DoAndReturn(func(token *clusterv3.ClusterAuthToken) (*clusterv3.ClusterAuthToken, error) {
+		assert.True(t, token.Enabled)
+		assert.NotContains(t, token.Annotations, DisabledAnnotation)
+		return token, nil
+	})
+	_, err = c.onToken("cattle-system/t-1", disabled)
+	require.NoError(t, err)
+	assert.True(t, c.state.Tokens["t-1"].Enabled)
+}
+
+func TestNoStalenessLimit(t *testing.T) {
+	c, m := newTestCache(t, &memoryStore{}, 0)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	m.tokenCache.EXPECT().List(gomock.Any(), gomock.Any()).Return(nil, nil)
+	c.Connected()
+	c.Disconnected()
+
+	now = now.Add(30 * 24 * time.Hour)
+	c.tick()
+	assert.False(t, c.staleEnforced)
+}
@@ -0,0 +1,156 @@
+package mgmtcache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretName is the name of the secret the encrypted cache is persisted in, in the namespace of the agent.
+	SecretName = "cattle-agent-cache"
+	// KeySecretName is the name of the secret holding the key of the cache, in KeyNamespace. The key is kept apart from
+	// the cache, so that reading the secrets of the namespace of the agent isn't enough to read the cache.
+	KeySecretName = "cattle-agent-cache-key"
+	KeyNamespace  = "kube-system"
+
+	dataKey = "cache"
+	keyKey  = "key"
+	keySize = 32
+)
+
+// store persists the cache.
+type store interface {
+	load() ([]byte, error)
+	save(data []byte) error
+}
+
+// secretStore persists the cache encrypted with AES-GCM in a secret. The key is generated when the cache is first
+// saved. If it is lost, the cache can't be decrypted and is discarded.
+type secretStore struct {
+	namespace string
+	secrets   corecontrollers.SecretClient
+}
+
+func (s *secretStore) load() ([]byte, error) {
+	secret, err := s.secrets.Get(s.namespace, SecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data := secret.Data[dataKey]
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	key, err := s.key(false)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(key, data)
+}
+
+func (s *secretStore) save(data []byte) error {
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encrypt(key, data)
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.secrets.Get(s.namespace, SecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = s.secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      SecretName,
+				Namespace: s.namespace,
+			},
+			Data: map[string][]byte{dataKey: encrypted},
+		})
+		return err
+	} else if err != nil {
+		return err
+	}
+	secret = secret.DeepCopy()
+	secret.Data = map[string][]byte{dataKey: encrypted}
+	_, err = s.secrets.Update(secret)
+	return err
+}
+
+// key returns the key of the cache, generating it if it doesn't exist and create is set.
+func (s *secretStore) key(create bool) ([]byte, error) {
+	secret, err := s.secrets.Get(KeyNamespace, KeySecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && create {
+		key := make([]byte, keySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		secret, err = s.secrets.Create(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      KeySecretName,
+				Namespace: KeyNamespace,
+			},
+			Data: map[string][]byte{keyKey: key},
+		})
+		if apierrors.IsAlreadyExists(err) {
+			secret, err = s.secrets.Get(KeyNamespace, KeySecretName, metav1.GetOptions{})
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("the key of the cache %s/%s is missing", KeyNamespace, KeySecretName)
+	} else if err != nil {
+		return nil, err
+	}
+	if key := secret.Data[keyKey]; len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("the key of the cache %s/%s is invalid", KeyNamespace, KeySecretName)
+}
+
+// encrypt seals the data with AES-GCM, prefixing it with the nonce.
+func encrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted cache is truncated")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the cache: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,389 @@
+// Package preflight checks that a cluster can be imported into Rancher before the agent is installed: that Rancher is
+// reachable, that its CA matches the checksum the agent is deployed with, that the service account of the agent has
+// the permissions it needs, and that the Kubernetes version of the cluster is supported. Once done, the checks delete
+// the stand-in service account of the agent and their own service account, with their RBAC.
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/rancher/wrangler/v3/pkg/kubeconfig"
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	ServerEnv          = "CATTLE_SERVER"
+	CAChecksumEnv      = "CATTLE_CA_CHECKSUM"
+	K8sVersionRangeEnv = "CATTLE_PREFLIGHT_K8S_VERSION_RANGE"
+	ReportURLEnv       = "CATTLE_PREFLIGHT_REPORT_URL"
+
+	CheckConnectivity  = "connectivity"
+	CheckCACertificate = "ca-certificate"
+	CheckPermissions   = "permissions"
+	CheckK8sVersion    = "kubernetes-version"
+
+	// Namespace, AgentServiceAccount and ServiceAccount are the namespace and service accounts of the stand-in of the
+	// agent and of the checks, AgentName and Name the names of their RBAC.
+	Namespace           = "cattle-system"
+	AgentServiceAccount = "cattle-preflight-agent"
+	AgentName           = "cattle-preflight-agent"
+	ServiceAccount      = "cattle-preflight"
+	Name                = "cattle-preflight"
+	BindingName         = "cattle-preflight-binding"
+
+	requestTimeout = 10 * time.Second
+)
+
+// CheckResult is the result of a pre-flight check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of the pre-flight checks, written to stdout and sent to Rancher.
+type Report struct {
+	Passed bool          `json:"passed"`
+	Time   time.Time     `json:"time"`
+	Checks []CheckResult `json:"checks"`
+}
+
+type checker struct {
+	server       string
+	caChecksum   string
+	versionRange string
+	k8s          kubernetes.Interface
+	// rootCAs are the CAs trusted to connect to Rancher, the system ones when nil.
+	rootCAs *x509.CertPool
+}
+
+// Run runs the pre-flight checks with the configuration of the environment, writes the report to stdout and sends it
+// to Rancher. It returns an error if a check failed.
+func Run(ctx context.Context) error {
+	cfg, err := kubeconfig.GetNonInteractiveClientConfig("").ClientConfig()
+	if err != nil {
+		return err
+	}
+	k8s, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	c := &checker{
+		server:       strings.TrimSuffix(os.Getenv(ServerEnv), "/"),
+		caChecksum:   os.Getenv(CAChecksumEnv),
+		versionRange: os.Getenv(K8sVersionRangeEnv),
+		k8s:          k8s,
+	}
+	report := c.run(ctx)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	if reportURL := os.Getenv(ReportURLEnv); reportURL != "" {
+		if err := c.send(ctx, reportURL, report); err != nil {
+			logrus.Warnf("Failed to send the pre-flight report to Rancher: %v", err)
+		}
+	}
+
+	if err := c.cleanup(ctx); err != nil {
+		logrus.Warnf("Failed to delete the service account and RBAC of the pre-flight checks: %v", err)
+	}
+
+	if !report.Passed {
+		return errors.New("pre-flight checks failed")
+	}
+	return nil
+}
+
+func (c *checker) run(ctx context.Context) *Report {
+	report := &Report{
+		Passed: true,
+		Time:   time.Now().UTC(),
+	}
+	for _, check := range []func(context.Context) CheckResult{
+		c.checkConnectivity,
+		c.checkCACertificate,
+		c.checkPermissions,
+		c.checkK8sVersion,
+	} {
+		result := check(ctx)
+		report.Passed = report.Passed && result.Passed
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+func (c *checker) get(ctx context.Context, client *http.Client, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s%s returned %s", c.server, path, resp.Status)
+	}
+	return body, nil
+}
+
+func insecureClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+}
+
+func (c *checker) trustedClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: c.rootCAs},
+	}}
+}
+
+// checkConnectivity checks that Rancher can be reached, without verifying its certificate.
+func (c *checker) checkConnectivity(ctx context.Context) CheckResult {
+	result := CheckResult{Name: CheckConnectivity}
+	if c.server == "" {
+		result.Message = ServerEnv + " is not set"
+		return result
+	}
+	body, err := c.get(ctx, insecureClient(), "/ping")
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to connect to %s, check that the cluster has egress to the server-url of Rancher: %v", c.server, err)
+		return result
+	}
+	if strings.TrimSpace(string(body)) != "pong" {
+		result.Message = fmt.Sprintf("%s/ping did not answer pong, check that %s is the server-url of Rancher", c.server, c.server)
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+// checkCACertificate checks that the CA certificate of Rancher matches the CA checksum, when set, and that the
+// certificate of Rancher is trusted, with this CA or with the system CAs.
+func (c *checker) checkCACertificate(ctx context.Context) CheckResult {
+	result := CheckResult{Name: CheckCACertificate}
+	if c.server == "" {
+		result.Message = ServerEnv + " is not set"
+		return result
+	}
+
+	if c.caChecksum != "" {
+		body, err := c.get(ctx, insecureClient(), "/v3/settings/cacerts")
+		if err != nil {
+			result.Message = fmt.Sprintf("failed to get the CA certificate of Rancher: %v", err)
+			return result
+		}
+		var setting struct {
+			Value string `json:"value"`
+		}
+		if err := json.Unmarshal(body, &setting); err != nil {
+			result.Message = fmt.Sprintf("failed to parse the cacerts setting of Rancher: %v", err)
+			return result
+		}
+		if setting.Value == "" {
+			result.Message = fmt.Sprintf("%s is set but there is no CA certificate configured in the cacerts setting of Rancher", CAChecksumEnv)
+			return result
+		}
+
+		ca := setting.Value
+		if !strings.HasSuffix(ca, "\n") {
+			ca += "\n"
+		}
+		digest := sha256.Sum256([]byte(ca))
+		if checksum := hex.EncodeToString(digest[:]); checksum != c.caChecksum {
+			result.Message = fmt.Sprintf("the checksum of the cacerts setting of Rancher (%s) does not match %s (%s)", checksum, CAChecksumEnv, c.caChecksum)
+			return result
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			result.Message = "the cacerts setting of Rancher does not contain a PEM encoded certificate"
+			return result
+		}
+		c.rootCAs = pool
+	}
+
+	if _, err := c.get(ctx, c.trustedClient(), "/ping"); err != nil {
+		result.Message = fmt.Sprintf("failed to securely connect to %s, check that its certificate is valid, includes its intermediate certificates and is signed by the CA in the cacerts setting or by a public CA: %v", c.server, err)
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+// checkPermissions checks that the stand-in of the service account of the agent, bound like the agent, is allowed
+// everything.
+func (c *checker) checkPermissions(ctx context.Context) CheckResult {
+	result := CheckResult{Name: CheckPermissions}
+	agent := serviceaccount.MakeUsername(Namespace, AgentServiceAccount)
+	review, err := c.k8s.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   agent,
+			Groups: append(serviceaccount.MakeGroupNames(Namespace), user.AllAuthenticated),
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     "*",
+				Group:    "*",
+				Resource: "*",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to review the permissions of the agent: %v", err)
+		return result
+	}
+	if !review.Status.Allowed {
+		result.Message = fmt.Sprintf("%s is not allowed all verbs on all resources, check that it is bound to the %s ClusterRole", agent, AgentName)
+		if review.Status.Reason != "" {
+			result.Message += ": " + review.Status.Reason
+		}
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+// checkK8sVersion checks that the version of Kubernetes of the cluster is in the range supported by Rancher.
+func (c *checker) checkK8sVersion(ctx context.Context) CheckResult {
+	result := CheckResult{Name: CheckK8sVersion}
+	info, err := c.k8s.Discovery().ServerVersion()
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to get the version of Kubernetes: %v", err)
+		return result
+	}
+	if c.versionRange == "" {
+		result.Passed = true
+		result.Message = info.GitVersion
+		return result
+	}
+
+	supported, err := semver.ParseRange(c.versionRange)
+	if err != nil {
+		result.Message = fmt.Sprintf("invalid supported version range %q: %v", c.versionRange, err)
+		return result
+	}
+	version, err := semver.ParseTolerant(info.GitVersion)
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to parse the version of Kubernetes %s: %v", info.GitVersion, err)
+		return result
+	}
+	// distributions add pre-release and build metadata, ie. v1.29.4-eks-036c24b or v1.29.4+k3s1
+	version.Pre = nil
+	version.Build = nil
+	if !supported(version) {
+		result.Message = fmt.Sprintf("Kubernetes %s is not in the range of versions supported by Rancher (%s)", info.GitVersion, c.versionRange)
+		return result
+	}
+	result.Passed = true
+	result.Message = info.GitVersion
+	return result
+}
+
+// send posts the report to Rancher, trusting the CA checked by checkCACertificate.
+func (c *checker) send(ctx context.Context, reportURL string, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reportURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.trustedClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("POST %s returned %s", reportURL, resp.Status)
+	}
+	return nil
+}
+
+// cleanup deletes the stand-in of the agent, then the service account and RBAC of the checks. They are made dependents
+// of the ClusterRole of the checks, which is deleted last, as the checks lose their permissions with it.
+func (c *checker) cleanup(ctx context.Context) error {
+	if err := c.k8s.RbacV1().ClusterRoleBindings().Delete(ctx, AgentName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := c.k8s.RbacV1().ClusterRoles().Delete(ctx, AgentName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err := c.k8s.CoreV1().ServiceAccounts(Namespace).Delete(ctx, AgentServiceAccount, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	clusterRole, err := c.k8s.RbacV1().ClusterRoles().Get(ctx, Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"ownerReferences": []metav1.OwnerReference{{
+				APIVersion: rbacv1.SchemeGroupVersion.String(),
+				Kind:       "ClusterRole",
+				Name:       clusterRole.Name,
+				UID:        clusterRole.UID,
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.k8s.CoreV1().ServiceAccounts(Namespace).Patch(ctx, ServiceAccount, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	if _, err := c.k8s.RbacV1().Roles(Namespace).Patch(ctx, Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	if _, err := c.k8s.RbacV1().RoleBindings(Namespace).Patch(ctx, Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	if _, err := c.k8s.RbacV1().ClusterRoleBindings().Patch(ctx, BindingName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	return c.k8s.RbacV1().ClusterRoles().Delete(ctx, Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
@@ -0,0 +1,199 @@
+package preflight
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newRancher(t *testing.T, cacerts func(string) string) (*httptest.Server, string) {
+	var ca string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/ping":
+			fmt.Fprint(rw, "pong")
+		case "/v3/settings/cacerts":
+			_ = json.NewEncoder(rw).Encode(map[string]string{"value": cacerts(ca)})
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ca = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	return srv, ca
+}
+
+func checksum(ca string) string {
+	digest := sha256.Sum256([]byte(ca))
+	return hex.EncodeToString(digest[:])
+}
+
+func newK8s(gitVersion string, allowed bool) *fake.Clientset {
+	k8s := fake.NewSimpleClientset()
+	k8s.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: gitVersion}
+	k8s.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		// only the agent is allowed everything, not the service account of the checks
+		review.Status.Allowed = allowed && review.Spec.User == "system:serviceaccount:cattle-system:cattle-preflight-agent"
+		return true, review, nil
+	})
+	return k8s
+}
+
+func results(report *Report) map[string]bool {
+	passed := map[string]bool{}
+	for _, check := range report.Checks {
+		passed[check.Name] = check.Passed
+	}
+	return passed
+}
+
+func TestRun(t *testing.T) {
+	srv, ca := newRancher(t, func(ca string) string { return ca })
+
+	c := &checker{
+		server:       srv.URL,
+		caChecksum:   checksum(ca),
+		versionRange: ">=1.27.0 <1.31.0",
+		k8s:          newK8s("v1.30.2+k3s1", true),
+	}
+	report := c.run(context.Background())
+	assert.True(t, report.Passed, "%+v", report.Checks)
+	assert.Equal(t, map[string]bool{
+		CheckConnectivity:  true,
+		CheckCACertificate: true,
+		CheckPermissions:   true,
+		CheckK8sVersion:    true,
+	}, results(report))
+}
+
+func TestRunFailures(t *testing.T) {
+	srv, ca := newRancher(t, func(ca string) string { return ca })
+
+	tests := []struct {
+		name     string
+		checker  *checker
+		expected map[string]bool
+	}{
+		{
+			name: "CA checksum mismatch",
+			checker: &checker{
+				server:     srv.URL,
+				caChecksum: checksum("another CA\n"),
+				k8s:        newK8s("v1.30.2", true),
+			},
+			expected: map[string]bool{CheckConnectivity: true, CheckCACertificate: false, CheckPermissions: true, CheckK8sVersion: true},
+		},
+		{
+			name: "private CA without checksum",
+			checker: &checker{
+				server: srv.URL,
+				k8s:    newK8s("v1.30.2", true),
+			},
+			expected: map[string]bool{CheckConnectivity: true, CheckCACertificate: false, CheckPermissions: true, CheckK8sVersion: true},
+		},
+		{
+			name: "unreachable server",
+			checker: &checker{
+				server:     "https://127.0.0.1:1",
+				caChecksum: checksum(ca),
+				k8s:        newK8s("v1.30.2", true),
+			},
+			expected: map[string]bool{CheckConnectivity: false, CheckCACertificate: false, CheckPermissions: true, CheckK8sVersion: true},
+		},
+		{
+			name: "missing permissions and unsupported version",
+			checker: &checker{
+				server:       srv.URL,
+				caChecksum:   checksum(ca),
+				versionRange: ">=1.27.0 <1.31.0",
+				k8s:          newK8s("v1.26.15-eks-db838b0", false),
+			},
+			expected: map[string]bool{CheckConnectivity: true, CheckCACertificate: true, CheckPermissions: false, CheckK8sVersion: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.checker.run(context.Background())
+			assert.False(t, report.Passed)
+			assert.Equal(t, tt.expected, results(report))
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received Report
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&received))
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := &checker{rootCAs: srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+	report := &Report{Checks: []CheckResult{{Name: CheckConnectivity, Passed: true}}}
+	require.NoError(t, c.send(context.Background(), srv.URL+"/v3/import/token_c-1/preflight", report))
+	assert.Equal(t, report.Checks, received.Checks)
+}
+
+func TestCleanup(t *testing.T) {
+	k8s := fake.NewSimpleClientset(
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: Name, UID: "uid-1"}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: BindingName}},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: Namespace}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: Name, Namespace: Namespace}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: ServiceAccount, Namespace: Namespace}},
+		&rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: AgentName}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: AgentName}},
+		&corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: AgentServiceAccount, Namespace: Namespace}},
+	)
+	c := &checker{k8s: k8s}
+	require.NoError(t, c.cleanup(context.Background()))
+
+	ctx := context.Background()
+	sa, err := k8s.CoreV1().ServiceAccounts(Namespace).Get(ctx, ServiceAccount, metav1.GetOptions{})
+	require.NoError(t, err)
+	role, err := k8s.RbacV1().Roles(Namespace).Get(ctx, Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	roleBinding, err := k8s.RbacV1().RoleBindings(Namespace).Get(ctx, Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	binding, err := k8s.RbacV1().ClusterRoleBindings().Get(ctx, BindingName, metav1.GetOptions{})
+	require.NoError(t, err)
+	for _, owners := range [][]metav1.OwnerReference{sa.OwnerReferences, role.OwnerReferences, roleBinding.OwnerReferences, binding.OwnerReferences} {
+		require.Len(t, owners, 1)
+		assert.Equal(t, "ClusterRole", owners[0].Kind)
+		assert.Equal(t, Name, owners[0].Name)
+		assert.Equal(t, "uid-1", string(owners[0].UID))
+	}
+
+	_, err = k8s.RbacV1().ClusterRoles().Get(ctx, Name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// nothing of the stand-in of the agent is left
+	_, err = k8s.RbacV1().ClusterRoleBindings().Get(ctx, AgentName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = k8s.RbacV1().ClusterRoles().Get(ctx, AgentName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = k8s.CoreV1().ServiceAccounts(Namespace).Get(ctx, AgentServiceAccount, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -17,9 +17,11 @@
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	mgmtclient "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	"github.com/rancher/rancher/pkg/controllers/management/k3sbasedupgrade"
+	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/kontainer-engine/service"
 	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/ref"
 	mgmtSchema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -62,6 +64,10 @@ func (v *Validator) Validator(request *types.APIContext, schema *types.Schema, d
 		return err
 	}
 
+	if err := v.validateEgressProxy(request, schema, &clusterSpec); err != nil {
+		return err
+	}
+
 	if err := v.validateAKSConfig(request, data, &clusterSpec); err != nil {
 		return err
 	}
@@ -526,6 +532,45 @@ func validateEKSKubernetesVersion(spec *v32.ClusterSpec, prevCluster *v3.Cluster
 	return nil
 }
 
+// validateEgressProxy validates that the credential secret of the egress proxy is in the namespace of the cluster or
+// is a cloud credential, and that the user has access to it, as its credentials are sent to the proxy.
+func (v *Validator) validateEgressProxy(request *types.APIContext, schema *types.Schema, spec *v32.ClusterSpec) error {
+	if spec.EgressProxy == nil || spec.EgressProxy.CredentialSecret == "" {
+		return nil
+	}
+	credentialSecret := spec.EgressProxy.CredentialSecret
+
+	if request.Method == http.MethodPut {
+		prevCluster, err := v.ClusterLister.Get("", request.ID)
+		if err != nil {
+			return err
+		}
+		// Only check that the user has access to the secret if the secret is being changed.
+		if prevCluster.Spec.EgressProxy != nil && prevCluster.Spec.EgressProxy.CredentialSecret == credentialSecret {
+			return nil
+		}
+	}
+
+	secretNamespace, secretName := ref.Parse(credentialSecret)
+	switch {
+	case secretNamespace == namespace.GlobalNamespace:
+		return validateCredentialAuth(request, credentialSecret)
+	case request.Method == http.MethodPut && secretNamespace == request.ID:
+		secretState := map[string]interface{}{
+			"name":        secretName,
+			"id":          credentialSecret,
+			"namespaceId": secretNamespace,
+		}
+		if err := request.AccessControl.CanDo(corev1.SecretGroupVersionKind.Group, corev1.SecretResource.Name, "get", request, secretState, schema); err != nil {
+			return httperror.NewAPIError(httperror.NotFound, "error accessing egress proxy credential secret")
+		}
+		return nil
+	default:
+		return httperror.NewFieldAPIError(httperror.InvalidOption, "egressProxy.credentialSecret",
+			fmt.Sprintf("must be a cloud credential or a secret in the namespace of the cluster, got %s", credentialSecret))
+	}
+}
+
 // validateCredentialAuth validates that a user has access to the credential they are setting.
 func validateCredentialAuth(request *types.APIContext, credential string) error {
 	var accessCred mgmtclient.CloudCredential
@@ -17,6 +17,8 @@
 
 type ClusterImport struct {
 	Clusters v3.ClusterInterface
+	// ClusterByToken returns the cluster of a registration token.
+	ClusterByToken func(token string) (*v3.Cluster, error)
 }
 
 func (ch *ClusterImport) ClusterImportHandler(resp http.ResponseWriter, req *http.Request) {
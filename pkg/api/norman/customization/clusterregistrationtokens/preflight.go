@@ -0,0 +1,109 @@
+package clusterregistrationtokens
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/urlbuilder"
+	"github.com/rancher/rancher/pkg/agent/preflight"
+	"github.com/rancher/rancher/pkg/image"
+	schema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// PreflightReportAnnotation holds the last report of the pre-flight checks of an imported cluster.
+	PreflightReportAnnotation = "clusters.management.cattle.io/import-preflight-report"
+
+	maxPreflightReportSize = 64 * 1024
+)
+
+// PreflightHandler writes the manifest of the pre-flight checks of the cluster of the registration token, which report
+// back to Rancher.
+func (ch *ClusterImport) PreflightHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "text/plain")
+	token := mux.Vars(req)["token"]
+	clusterID := mux.Vars(req)["clusterId"]
+
+	cluster, err := ch.ClusterByToken(token)
+	if err != nil || cluster == nil || cluster.Name != clusterID {
+		http.Error(resp, "invalid registration token", http.StatusUnauthorized)
+		return
+	}
+
+	urlBuilder, err := urlbuilder.New(req, schema.Version, types.NewSchemas())
+	if err != nil {
+		resp.WriteHeader(500)
+		resp.Write([]byte(err.Error()))
+		return
+	}
+	url := settings.ServerURL.Get()
+	if url == "" {
+		url = urlBuilder.RelativeToRoot("")
+	}
+
+	reportURL := url + "/v3/import/" + token + "_" + clusterID + "/preflight"
+	if err = systemtemplate.PreflightTemplate(resp, image.Resolve(settings.AgentImage.Get()), url, reportURL, cluster, nil); err != nil {
+		resp.WriteHeader(500)
+		resp.Write([]byte(err.Error()))
+	}
+}
+
+// PreflightReportHandler records the report of the pre-flight checks of the cluster of the registration token on the
+// cluster.
+func (ch *ClusterImport) PreflightReportHandler(resp http.ResponseWriter, req *http.Request) {
+	token := mux.Vars(req)["token"]
+	clusterID := mux.Vars(req)["clusterId"]
+
+	cluster, err := ch.ClusterByToken(token)
+	if err != nil || cluster == nil || cluster.Name != clusterID {
+		http.Error(resp, "invalid registration token", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPreflightReportSize+1))
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPreflightReportSize {
+		http.Error(resp, "report too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var report preflight.Report
+	if err := json.Unmarshal(body, &report); err != nil {
+		http.Error(resp, "invalid report: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := json.Marshal(&report)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := ch.Clusters.Get(clusterID, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if cluster.Annotations == nil {
+			cluster.Annotations = map[string]string{}
+		}
+		cluster.Annotations[PreflightReportAnnotation] = string(data)
+		_, err = ch.Clusters.Update(cluster)
+		return err
+	})
+	if err != nil {
+		logrus.Errorf("failed to record the pre-flight report of cluster [%s]: %v", clusterID, err)
+		http.Error(resp, "failed to record the report", http.StatusInternalServerError)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
@@ -5,7 +5,6 @@
 	"fmt"
 	"strings"
 
-	"github.com/mitchellh/mapstructure"
 	"github.com/rancher/norman/api/access"
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
@@ -16,17 +15,25 @@
 	"github.com/rancher/rancher/pkg/clustermanager"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	projectpkg "github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/rancher/pkg/ref"
 	"github.com/rancher/rancher/pkg/resourcequota"
 	mgmtschema "github.com/rancher/rancher/pkg/schemas/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	quota "k8s.io/apiserver/pkg/quota/v1"
 )
 
 const roleTemplatesRequired = "authz.management.cattle.io/creator-role-bindings"
 const quotaField = "resourceQuota"
 const namespaceQuotaField = "namespaceDefaultResourceQuota"
+const parentProjectField = "parentProjectName"
+const limitRangeField = "limitRange"
+const containerResourceLimitField = "containerDefaultResourceLimit"
 
 type projectStore struct {
 	types.Store
@@ -59,9 +66,21 @@ func (s *projectStore) Create(apiContext *types.APIContext, schema *types.Schema
 		return nil, err
 	}
 
+	if err := s.validateParentProject(apiContext, data, convert.ToString(data["clusterId"]), ""); err != nil {
+		return nil, err
+	}
+
+	if err := validateLimitRange(data); err != nil {
+		return nil, err
+	}
+
 	values.PutValue(data, annotation, "annotations", roleTemplatesRequired)
 
-	return s.Store.Create(apiContext, schema, data)
+	result, err := s.Store.Create(apiContext, schema, data)
+	if err != nil {
+		return result, err
+	}
+	return result, s.acceptSubProject(data, result)
 }
 
 func (s *projectStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
@@ -69,7 +88,20 @@ func (s *projectStore) Update(apiContext *types.APIContext, schema *types.Schema
 		return nil, err
 	}
 
-	return s.Store.Update(apiContext, schema, data, id)
+	clusterName, projectName := ref.Parse(id)
+	if err := s.validateParentProject(apiContext, data, clusterName, projectName); err != nil {
+		return nil, err
+	}
+
+	if err := validateLimitRange(data); err != nil {
+		return nil, err
+	}
+
+	result, err := s.Store.Update(apiContext, schema, data, id)
+	if err != nil {
+		return result, err
+	}
+	return result, s.acceptSubProject(data, result)
 }
 
 func (s *projectStore) Delete(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
@@ -82,6 +114,15 @@ func (s *projectStore) Delete(apiContext *types.APIContext, schema *types.Schema
 	if proj.Labels["authz.management.cattle.io/system-project"] == "true" {
 		return nil, httperror.NewAPIError(httperror.MethodNotAllowed, "System Project cannot be deleted")
 	}
+	projects, err := s.projectLister.List(proj.Namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		if p.Spec.ParentProjectName == proj.Name && p.Name != proj.Name && p.DeletionTimestamp == nil {
+			return nil, httperror.NewAPIError(httperror.MethodNotAllowed, fmt.Sprintf("Project has sub-project %s and cannot be deleted", p.Name))
+		}
+	}
 	return s.Store.Delete(apiContext, schema, id)
 }
 
@@ -107,6 +148,151 @@ func (s *projectStore) createProjectAnnotation() (string, error) {
 	return string(d), nil
 }
 
+// validateLimitRange checks that the LimitRange of the project, with the container defaults of the project merged into
+// it, can be applied to its namespaces.
+func validateLimitRange(data map[string]interface{}) error {
+	var limitRange *v32.ProjectLimitRange
+	if limitRangeO := data[limitRangeField]; limitRangeO != nil {
+		limitRange = &v32.ProjectLimitRange{}
+		if err := convert.ToObj(limitRangeO, limitRange); err != nil {
+			return err
+		}
+		items, err := resourcequota.ConvertLimitRangeToLimitRangeItems(limitRange)
+		if err != nil {
+			return httperror.NewFieldAPIError(httperror.InvalidFormat, limitRangeField, err.Error())
+		}
+		if err := resourcequota.ValidateLimitRangeItems(items); err != nil {
+			return httperror.NewFieldAPIError(httperror.InvalidOption, limitRangeField, err.Error())
+		}
+	}
+	var containerDefaults *v32.ContainerResourceLimit
+	if containerDefaultsO := data[containerResourceLimitField]; containerDefaultsO != nil {
+		containerDefaults = &v32.ContainerResourceLimit{}
+		if err := convert.ToObj(containerDefaultsO, containerDefaults); err != nil {
+			return err
+		}
+	}
+	if _, err := resourcequota.LimitRangeItems(containerDefaults, limitRange); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, containerResourceLimitField, err.Error())
+	}
+	return nil
+}
+
+// validateParentProject checks that the user can add sub-projects to the parent project, unless it already accepts the
+// project, that the parent is in the same cluster and doesn't make a cycle, and that the resource quota of the project
+// can be carved out of the resource quota of the parent.
+func (s *projectStore) validateParentProject(apiContext *types.APIContext, data map[string]interface{}, clusterName, projectName string) error {
+	parentName := convert.ToString(data[parentProjectField])
+	if parentName == "" {
+		return nil
+	}
+	if parentName == projectName {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, parentProjectField, "project cannot be its own parent")
+	}
+	parent, err := s.projectLister.Get(clusterName, parentName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	accepted := parent != nil && projectName != "" && projectpkg.AcceptsSubProject(parent, projectName)
+	if !accepted && !canAddSubProjects(apiContext, clusterName, parentName) {
+		// the same error as for a missing parent, so that the existence of projects isn't revealed
+		return httperror.NewFieldAPIError(httperror.InvalidReference, parentProjectField, fmt.Sprintf("project %s not found in cluster %s", parentName, clusterName))
+	}
+	if parent == nil {
+		return httperror.NewFieldAPIError(httperror.InvalidReference, parentProjectField, fmt.Sprintf("project %s not found in cluster %s", parentName, clusterName))
+	}
+	if parent.DeletionTimestamp != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidReference, parentProjectField, fmt.Sprintf("project %s is being deleted", parentName))
+	}
+	if parent.Labels["authz.management.cattle.io/system-project"] == "true" {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, parentProjectField, "System Project cannot have sub-projects")
+	}
+
+	var current *v32.Project
+	if projectName != "" {
+		current, err = s.projectLister.Get(clusterName, projectName)
+		if err != nil {
+			return err
+		}
+		if current.Labels["authz.management.cattle.io/system-project"] == "true" {
+			return httperror.NewFieldAPIError(httperror.InvalidOption, parentProjectField, "System Project cannot have a parent project")
+		}
+		projects, err := s.projectLister.List(clusterName, labels.Everything())
+		if err != nil {
+			return err
+		}
+		if projectpkg.NewTree(projects).IsDescendant(parentName, projectName) {
+			return httperror.NewFieldAPIError(httperror.InvalidOption, parentProjectField, fmt.Sprintf("project %s is a sub-project of the project", parentName))
+		}
+	}
+
+	if parent.Spec.ResourceQuota == nil {
+		return nil
+	}
+	quotaO := data[quotaField]
+	if quotaO == nil {
+		return httperror.NewFieldAPIError(httperror.MissingRequired, quotaField, fmt.Sprintf("is required when the parent project %s has a resource quota", parentName))
+	}
+	var projectQuota mgmtclient.ProjectResourceQuota
+	if err := convert.ToObj(quotaO, &projectQuota); err != nil {
+		return err
+	}
+	projectQuotaLimit, err := limitToLimit(projectQuota.Limit)
+	if err != nil {
+		return err
+	}
+
+	// the limit of the project is carved out of the limit of its parent, where it is already used if the parent
+	// doesn't change
+	usedLimit := parent.Spec.ResourceQuota.UsedLimit.DeepCopy()
+	if current != nil && projectpkg.ParentName(current) == parentName && current.Spec.ResourceQuota != nil {
+		used, err := resourcequota.ConvertLimitToResourceList(usedLimit)
+		if err != nil {
+			return err
+		}
+		currentLimit, err := resourcequota.ConvertLimitToResourceList(&current.Spec.ResourceQuota.Limit)
+		if err != nil {
+			return err
+		}
+		usedLimit, err = resourcequota.ConvertResourceListToLimit(quota.RemoveZeros(quota.Subtract(used, currentLimit)))
+		if err != nil {
+			return err
+		}
+	}
+	isFit, exceeded, err := resourcequota.IsQuotaFit(projectQuotaLimit, []*v32.ResourceQuotaLimit{usedLimit}, &parent.Spec.ResourceQuota.Limit)
+	if err != nil {
+		return err
+	}
+	if !isFit {
+		return httperror.NewFieldAPIError(httperror.MaxLimitExceeded, quotaField, fmt.Sprintf("exceeds the limit left in the parent project %s on fields: %s",
+			parentName, utils.FormatResourceList(exceeded)))
+	}
+	return nil
+}
+
+// canAddSubProjects returns whether the user can add sub-projects to the project: the quota of a sub-project is carved
+// out of the quota of its parent, and a sub-project may join the network isolation of its parent, so it takes the
+// rights to update the parent or to add members to it.
+func canAddSubProjects(apiContext *types.APIContext, clusterName, projectName string) bool {
+	project := map[string]interface{}{"id": projectName, "namespaceId": clusterName}
+	if apiContext.AccessControl.CanDo(v3.ProjectGroupVersionKind.Group, v3.ProjectResource.Name, "update", apiContext, project, apiContext.Schema) == nil {
+		return true
+	}
+	bindings := map[string]interface{}{"namespaceId": projectName}
+	return apiContext.AccessControl.CanDo(v3.ProjectRoleTemplateBindingGroupVersionKind.Group, v3.ProjectRoleTemplateBindingResource.Name, "create", apiContext, bindings, apiContext.Schema) == nil
+}
+
+// acceptSubProject records that the parent of the written project accepts it as a sub-project, once the user was
+// allowed to set it by validateParentProject.
+func (s *projectStore) acceptSubProject(data, result map[string]interface{}) error {
+	parentName := convert.ToString(data[parentProjectField])
+	if parentName == "" {
+		return nil
+	}
+	clusterName, projectName := ref.Parse(convert.ToString(result["id"]))
+	return projectpkg.AcceptSubProject(s.scaledContext.Wrangler.Mgmt.Project(), clusterName, parentName, projectName)
+}
+
 func (s *projectStore) validateResourceQuota(apiContext *types.APIContext, data map[string]interface{}, id string) error {
 	quotaO, quotaOk := data[quotaField]
 	if quotaO == nil {
@@ -142,14 +328,20 @@ func (s *projectStore) validateResourceQuota(apiContext *types.APIContext, data
 	if err != nil {
 		return err
 	}
+	if err := resourcequota.ValidateExtendedLimit(projectQuotaLimit); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, quotaField, err.Error())
+	}
+	if err := resourcequota.ValidateExtendedLimit(nsQuotaLimit); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, namespaceQuotaField, err.Error())
+	}
 
 	// limits in namespace default quota should include all limits defined in the project quota
-	projectQuotaLimitMap, err := convert.EncodeToMap(projectQuotaLimit)
+	projectQuotaLimitMap, err := resourcequota.ConvertLimitToResourceList(projectQuotaLimit)
 	if err != nil {
 		return err
 	}
 
-	nsQuotaLimitMap, err := convert.EncodeToMap(nsQuotaLimit)
+	nsQuotaLimitMap, err := resourcequota.ConvertLimitToResourceList(nsQuotaLimit)
 	if err != nil {
 		return err
 	}
@@ -188,21 +380,25 @@ func (s *projectStore) isQuotaFit(apiContext *types.APIContext, nsQuotaLimit *v3
 
 	// check if fields were added or removed
 	// and update project's namespaces accordingly
-	defaultQuotaLimitMap, err := convert.EncodeToMap(nsQuotaLimit)
+	defaultQuotaLimitMap, err := resourcequota.ConvertLimitToResourceList(nsQuotaLimit)
 	if err != nil {
 		return err
 	}
 
-	usedQuotaLimitMap := map[string]interface{}{}
+	usedQuotaLimitMap := corev1.ResourceList{}
 	if project.ResourceQuota != nil && project.ResourceQuota.UsedLimit != nil {
-		usedQuotaLimitMap, err = convert.EncodeToMap(project.ResourceQuota.UsedLimit)
+		usedLimit, err := limitToLimit(project.ResourceQuota.UsedLimit)
+		if err != nil {
+			return err
+		}
+		usedQuotaLimitMap, err = resourcequota.ConvertLimitToResourceList(usedLimit)
 		if err != nil {
 			return err
 		}
 	}
 
-	limitToAdd := map[string]interface{}{}
-	limitToRemove := map[string]interface{}{}
+	limitToAdd := corev1.ResourceList{}
+	limitToRemove := corev1.ResourceList{}
 	for key, value := range defaultQuotaLimitMap {
 		if _, ok := usedQuotaLimitMap[key]; !ok {
 			limitToAdd[key] = value
@@ -220,13 +416,7 @@ func (s *projectStore) isQuotaFit(apiContext *types.APIContext, nsQuotaLimit *v3
 		delete(usedQuotaLimitMap, key)
 	}
 
-	var usedLimitToCheck mgmtclient.ResourceQuotaLimit
-	err = convert.ToObj(usedQuotaLimitMap, &usedLimitToCheck)
-	if err != nil {
-		return err
-	}
-
-	usedQuotaLimit, err := limitToLimit(&usedLimitToCheck)
+	usedQuotaLimit, err := resourcequota.ConvertResourceListToLimit(usedQuotaLimitMap)
 	if err != nil {
 		return err
 	}
@@ -244,11 +434,7 @@ func (s *projectStore) isQuotaFit(apiContext *types.APIContext, nsQuotaLimit *v3
 	}
 
 	// check if default quota is enough to set on namespaces
-	toAppend := &mgmtclient.ResourceQuotaLimit{}
-	if err := mapstructure.Decode(limitToAdd, toAppend); err != nil {
-		return err
-	}
-	converted, err := limitToLimit(toAppend)
+	converted, err := resourcequota.ConvertResourceListToLimit(limitToAdd)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,165 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	projectpkg "github.com/rancher/rancher/pkg/project"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// accessControl grants the verbs on the resources in the namespaces, e.g. "update" on "projects" in "c-1".
+type accessControl struct {
+	types.AccessControl
+	grants map[string]bool
+}
+
+func (a *accessControl) CanDo(apiGroup, resource, verb string, apiContext *types.APIContext, obj map[string]interface{}, schema *types.Schema) error {
+	if a.grants[verb+" "+resource+" "+obj["namespaceId"].(string)] {
+		return nil
+	}
+	return httperror.NewAPIError(httperror.PermissionDenied, "denied")
+}
+
+func TestValidateParentProject(t *testing.T) {
+	projects := map[string]*v32.Project{
+		"p-org": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "p-org",
+				Namespace:   "c-1",
+				Annotations: map[string]string{projectpkg.SubProjectsAnnotation: "p-team"},
+			},
+		},
+		"p-team": {ObjectMeta: metav1.ObjectMeta{Name: "p-team", Namespace: "c-1"}},
+		"p-app":  {ObjectMeta: metav1.ObjectMeta{Name: "p-app", Namespace: "c-1"}},
+	}
+	store := &projectStore{
+		projectLister: &fakes.ProjectListerMock{
+			GetFunc: func(namespace, name string) (*v32.Project, error) {
+				if p, ok := projects[name]; ok {
+					return p, nil
+				}
+				return nil, apierrors.NewNotFound(v32.Resource("projects"), name)
+			},
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v32.Project, error) {
+				var result []*v32.Project
+				for _, p := range projects {
+					result = append(result, p)
+				}
+				return result, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		projectName string
+		parentName  string
+		grants      map[string]bool
+		wantErr     bool
+	}{
+		{
+			name:        "no rights on the parent",
+			projectName: "p-app",
+			parentName:  "p-org",
+			wantErr:     true,
+		},
+		{
+			name:        "no rights on a missing parent",
+			projectName: "p-app",
+			parentName:  "p-missing",
+			wantErr:     true,
+		},
+		{
+			name:        "parent can be updated",
+			projectName: "p-app",
+			parentName:  "p-org",
+			grants:      map[string]bool{"update projects c-1": true},
+		},
+		{
+			name:        "members can be added to the parent",
+			projectName: "p-app",
+			parentName:  "p-org",
+			grants:      map[string]bool{"create projectroletemplatebindings p-org": true},
+		},
+		{
+			name:        "parent already accepts the project",
+			projectName: "p-team",
+			parentName:  "p-org",
+		},
+		{
+			name:        "new project",
+			projectName: "",
+			parentName:  "p-org",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiContext := &types.APIContext{AccessControl: &accessControl{grants: tt.grants}}
+			err := store.validateParentProject(apiContext, map[string]interface{}{parentProjectField: tt.parentName}, "c-1", tt.projectName)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, httperror.NewFieldAPIError(httperror.InvalidReference, parentProjectField, "project "+tt.parentName+" not found in cluster c-1").Error())
+		})
+	}
+}
+
+func TestValidateLimitRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "container defaults within the limit range",
+			data: map[string]interface{}{
+				limitRangeField:             map[string]interface{}{"limits": []interface{}{map[string]interface{}{"type": "Container", "max": map[string]interface{}{"cpu": "2"}}}},
+				containerResourceLimitField: map[string]interface{}{"limitsCpu": "1"},
+			},
+		},
+		{
+			name: "container defaults above the max of the limit range",
+			data: map[string]interface{}{
+				limitRangeField:             map[string]interface{}{"limits": []interface{}{map[string]interface{}{"type": "Container", "max": map[string]interface{}{"cpu": "2"}}}},
+				containerResourceLimitField: map[string]interface{}{"limitsCpu": "3"},
+			},
+			wantErr: containerResourceLimitField,
+		},
+		{
+			name: "invalid limit range",
+			data: map[string]interface{}{
+				limitRangeField: map[string]interface{}{"limits": []interface{}{map[string]interface{}{"type": "Pod", "min": map[string]interface{}{"cpu": "2"}, "max": map[string]interface{}{"cpu": "1"}}}},
+			},
+			wantErr: limitRangeField,
+		},
+		{
+			name: "invalid container defaults",
+			data: map[string]interface{}{
+				containerResourceLimitField: map[string]interface{}{"requestsCpu": "2", "limitsCpu": "1"},
+			},
+			wantErr: containerResourceLimitField,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLimitRange(tt.data)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *httperror.APIError
+			if assert.ErrorAs(t, err, &apiErr) {
+				assert.Equal(t, tt.wantErr, apiErr.FieldName)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@
 	"fmt"
 	"net/http"
 
+	"github.com/rancher/norman/httperror"
 	"github.com/rancher/norman/types"
 	"github.com/rancher/norman/types/convert"
 	client "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/labels"
 )
@@ -29,6 +31,14 @@ func (w Wrapper) Validator(request *types.APIContext, schema *types.Schema, data
 		if administrative && context != "cluster" {
 			return fmt.Errorf("Only cluster roles can be administrative")
 		}
+
+		rt := &v3.RoleTemplate{}
+		if err := convert.ToObj(data, rt); err != nil {
+			return err
+		}
+		if err := rbac.ValidateNamespaceSelectedRules(rt); err != nil {
+			return httperror.NewAPIError(httperror.InvalidBodyContent, err.Error())
+		}
 	}
 
 	if request.Method != http.MethodPut {
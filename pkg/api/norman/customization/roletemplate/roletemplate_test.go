@@ -1,6 +1,7 @@
 package roletemplate
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/rancher/norman/types"
@@ -81,3 +82,41 @@ func TestDelete(t *testing.T) {
 	assert.Equal(resource.Links["remove"], "")
 	assert.Equal(resource.Links["update"], "/test/link")
 }
+
+func TestValidatorNamespaceSelectedRules(t *testing.T) {
+	w := Wrapper{RoleTemplateLister: &fakes.RoleTemplateListerMock{}}
+	request := &types.APIContext{Method: http.MethodPost}
+	selectedRules := func(resourceNames ...interface{}) []interface{} {
+		return []interface{}{
+			map[string]interface{}{
+				"namespaceSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "web"}},
+				"rules": []interface{}{
+					map[string]interface{}{
+						"apiGroups":     []interface{}{""},
+						"resources":     []interface{}{"secrets"},
+						"resourceNames": resourceNames,
+						"verbs":         []interface{}{"get"},
+					},
+				},
+			},
+		}
+	}
+
+	err := w.Validator(request, nil, map[string]interface{}{
+		"context":                "project",
+		"namespaceSelectedRules": selectedRules("web-*"),
+	})
+	assert.NoError(t, err)
+
+	err = w.Validator(request, nil, map[string]interface{}{
+		"context":                "project",
+		"namespaceSelectedRules": selectedRules("web-*-db"),
+	})
+	assert.ErrorContains(t, err, `resource name pattern "web-*-db" must end with a single "*"`)
+
+	err = w.Validator(request, nil, map[string]interface{}{
+		"context":                "cluster",
+		"namespaceSelectedRules": selectedRules("web-*"),
+	})
+	assert.ErrorContains(t, err, "only allowed in role templates with the project context")
+}
@@ -33,6 +33,7 @@
 	"github.com/rancher/rancher/pkg/api/norman/customization/secret"
 	"github.com/rancher/rancher/pkg/api/norman/customization/setting"
 	appStore "github.com/rancher/rancher/pkg/api/norman/store/app"
+	expirystore "github.com/rancher/rancher/pkg/api/norman/store/bindingexpiry"
 	catalogStore "github.com/rancher/rancher/pkg/api/norman/store/catalog"
 	"github.com/rancher/rancher/pkg/api/norman/store/cert"
 	"github.com/rancher/rancher/pkg/api/norman/store/cluster"
@@ -549,11 +550,13 @@ func PodSecurityAdmissionConfigurationTemplate(schemas *types.Schemas, managemen
 func ClusterRoleTemplateBinding(schemas *types.Schemas, management *config.ScaledContext) {
 	schema := schemas.Schema(&managementschema.Version, client.ClusterRoleTemplateBindingType)
 	schema.Validator = roletemplatebinding.NewCRTBValidator(management)
+	schema.Store = expirystore.Wrap(schema.Store)
 }
 
 func ProjectRoleTemplateBinding(schemas *types.Schemas, management *config.ScaledContext) {
 	schema := schemas.Schema(&managementschema.Version, client.ProjectRoleTemplateBindingType)
 	schema.Validator = roletemplatebinding.NewPRTBValidator(management)
+	schema.Store = expirystore.Wrap(schema.Store)
 }
 
 func GlobalRole(schemas *types.Schemas, management *config.ScaledContext) {
@@ -570,7 +573,7 @@ func GlobalRole(schemas *types.Schemas, management *config.ScaledContext) {
 func GlobalRoleBindings(schemas *types.Schemas, management *config.ScaledContext) {
 	schema := schemas.Schema(&managementschema.Version, client.GlobalRoleBindingType)
 	grLister := management.Management.GlobalRoles("").Controller().Lister()
-	schema.Store = grbstore.Wrap(schema.Store, grLister)
+	schema.Store = expirystore.Wrap(grbstore.Wrap(schema.Store, grLister))
 	schema.Validator = globalrolebinding.Validator
 }
 
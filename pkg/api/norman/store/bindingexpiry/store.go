@@ -0,0 +1,87 @@
+// Package expirystore records the user changing the expiry of a GlobalRoleBinding, ClusterRoleTemplateBinding or
+// ProjectRoleTemplateBinding, so that the grant of the new expiry is attributed to that user.
+package expirystore
+
+import (
+	"time"
+
+	"github.com/rancher/norman/httperror"
+	"github.com/rancher/norman/types"
+	"github.com/rancher/norman/types/convert"
+	"github.com/rancher/norman/types/values"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/bindingexpiry"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	expiresAtField = "expiresAt"
+	ttlField       = "ttl"
+)
+
+func Wrap(store types.Store) types.Store {
+	return &expiryStore{
+		Store: store,
+	}
+}
+
+type expiryStore struct {
+	types.Store
+}
+
+func (s *expiryStore) Create(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}) (map[string]interface{}, error) {
+	// the grant of a new binding is attributed to its creator
+	dropAnnotations(data)
+	return s.Store.Create(apiContext, schema, data)
+}
+
+func (s *expiryStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
+	existing, err := s.Store.ByID(apiContext, schema, id)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, ttl := existing[expiresAtField], existing[ttlField]
+	if v, ok := data[expiresAtField]; ok {
+		expiresAt = v
+	}
+	if v, ok := data[ttlField]; ok {
+		ttl = v
+	}
+
+	spec, err := expirySpec(expiresAt, ttl)
+	if err != nil {
+		return nil, err
+	}
+	existingSpec, err := expirySpec(existing[expiresAtField], existing[ttlField])
+	if err != nil {
+		return nil, err
+	}
+	// only this store records who set the expiry
+	dropAnnotations(data)
+	if spec != existingSpec {
+		values.PutValue(data, apiContext.Request.Header.Get("Impersonate-User"), "annotations", bindingexpiry.ExpirySetByAnnotation)
+		values.PutValue(data, spec, "annotations", bindingexpiry.ExpirySetForAnnotation)
+	}
+	return s.Store.Update(apiContext, schema, data, id)
+}
+
+func dropAnnotations(data map[string]interface{}) {
+	if annotations, ok := values.GetValue(data, "annotations"); ok {
+		if annotations, ok := annotations.(map[string]interface{}); ok {
+			delete(annotations, bindingexpiry.ExpirySetByAnnotation)
+			delete(annotations, bindingexpiry.ExpirySetForAnnotation)
+		}
+	}
+}
+
+// expirySpec returns the expiry of the expiresAt and ttl fields of a binding, as recorded by the controller.
+func expirySpec(expiresAt, ttl interface{}) (string, error) {
+	var at *metav1.Time
+	if value := convert.ToString(expiresAt); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return "", httperror.NewFieldAPIError(httperror.InvalidFormat, expiresAtField, err.Error())
+		}
+		at = &metav1.Time{Time: t}
+	}
+	return bindingexpiry.ExpirySpec(at, convert.ToString(ttl)), nil
+}
@@ -0,0 +1,75 @@
+package expirystore
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rancher/norman/types"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/bindingexpiry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dummyStore struct {
+	types.Store
+	existing map[string]interface{}
+}
+
+func (ds dummyStore) ByID(apiContext *types.APIContext, schema *types.Schema, id string) (map[string]interface{}, error) {
+	return ds.existing, nil
+}
+
+func (ds dummyStore) Update(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string) (map[string]interface{}, error) {
+	return data, nil
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "expiry changed",
+			data: map[string]interface{}{"ttl": "4h"},
+			want: map[string]interface{}{
+				bindingexpiry.ExpirySetByAnnotation:  "u-owner",
+				bindingexpiry.ExpirySetForAnnotation: "ttl=4h",
+			},
+		},
+		{
+			name: "expires at takes precedence",
+			data: map[string]interface{}{"expiresAt": "2024-01-01T10:00:00+02:00"},
+			want: map[string]interface{}{
+				bindingexpiry.ExpirySetByAnnotation:  "u-owner",
+				bindingexpiry.ExpirySetForAnnotation: "expiresAt=2024-01-01T08:00:00Z",
+			},
+		},
+		{
+			name: "expiry unchanged",
+			data: map[string]interface{}{
+				"ttl": "8h",
+				"annotations": map[string]interface{}{
+					bindingexpiry.ExpirySetByAnnotation:  "u-admin",
+					bindingexpiry.ExpirySetForAnnotation: "ttl=8h",
+				},
+			},
+			want: map[string]interface{}{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := Wrap(dummyStore{existing: map[string]interface{}{"ttl": "8h"}})
+			apiContext := &types.APIContext{Request: &http.Request{Header: http.Header{"Impersonate-User": []string{"u-owner"}}}}
+
+			data, err := store.Update(apiContext, nil, tt.data, "grb-1")
+			require.NoError(t, err)
+			annotations, _ := data["annotations"].(map[string]interface{})
+			if len(tt.want) == 0 {
+				assert.Empty(t, annotations)
+				return
+			}
+			assert.Equal(t, tt.want, annotations)
+		})
+	}
+}
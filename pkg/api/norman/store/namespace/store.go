@@ -54,6 +54,10 @@ func (p *Store) Create(apiContext *types.APIContext, schema *types.Schema, data
 		return nil, err
 	}
 
+	if err := p.validateLimitRange(apiContext, schema, data, "", false); err != nil {
+		return nil, err
+	}
+
 	return p.Store.Create(apiContext, schema, data)
 }
 
@@ -62,26 +66,77 @@ func (p *Store) Update(apiContext *types.APIContext, schema *types.Schema, data
 		return nil, err
 	}
 
+	if err := p.validateLimitRange(apiContext, schema, data, id, true); err != nil {
+		return nil, err
+	}
+
 	return p.Store.Update(apiContext, schema, data, id)
 }
 
-func (p *Store) validateResourceQuota(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string, update bool) error {
-	quota := data[quotaField]
+// getProject returns the project of the namespace, or nil if it isn't in a project.
+func (p *Store) getProject(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string, update bool) (*mgmtclient.Project, error) {
 	projectID := convert.ToString(data["projectId"])
 	if update {
 		var ns clusterclient.Namespace
 		if err := access.ByID(apiContext, &schema.Version, clusterclient.NamespaceType, id, &ns); err != nil {
-			return err
+			return nil, err
 		}
 		projectID = ns.ProjectID
 	}
 	if projectID == "" {
-		return nil
+		return nil, nil
 	}
 	var project mgmtclient.Project
 	if err := access.ByID(apiContext, &mgmtschema.Version, mgmtclient.ProjectType, projectID, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// validateLimitRange checks that the container defaults of the namespace, completed by the ones of its project, can be
+// merged into the LimitRange of the project.
+func (p *Store) validateLimitRange(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string, update bool) error {
+	project, err := p.getProject(apiContext, schema, data, id, update)
+	if err != nil || project == nil {
+		return err
+	}
+	var nsLimit, projectLimit *v32.ContainerResourceLimit
+	if crlMap := convert.ToMapInterface(data[containerResourceLimitField]); len(crlMap) > 0 {
+		nsLimit = &v32.ContainerResourceLimit{}
+		if err := convert.ToObj(crlMap, nsLimit); err != nil {
+			return err
+		}
+	}
+	if project.ContainerDefaultResourceLimit != nil {
+		projectLimit = &v32.ContainerResourceLimit{}
+		if err := convert.ToObj(project.ContainerDefaultResourceLimit, projectLimit); err != nil {
+			return err
+		}
+	}
+	var limitRange *v32.ProjectLimitRange
+	if project.LimitRange != nil {
+		limitRange = &v32.ProjectLimitRange{}
+		if err := convert.ToObj(project.LimitRange, limitRange); err != nil {
+			return err
+		}
+	}
+	containerDefaults, err := resourcequota.NamespaceContainerDefaults(nsLimit, projectLimit)
+	if err != nil {
 		return err
 	}
+	if _, err := resourcequota.LimitRangeItems(containerDefaults, limitRange); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidOption, containerResourceLimitField, err.Error())
+	}
+	return nil
+}
+
+func (p *Store) validateResourceQuota(apiContext *types.APIContext, schema *types.Schema, data map[string]interface{}, id string, update bool) error {
+	quota := data[quotaField]
+	project, err := p.getProject(apiContext, schema, data, id, update)
+	if err != nil || project == nil {
+		return err
+	}
+	projectID := project.ID
 	if project.ResourceQuota == nil {
 		return nil
 	}
@@ -105,14 +160,17 @@ func (p *Store) validateResourceQuota(apiContext *types.APIContext, schema *type
 	if err != nil {
 		return err
 	}
+	if err := resourcequota.ValidateExtendedLimit(nsQuotaLimit); err != nil {
+		return httperror.NewFieldAPIError(httperror.InvalidFormat, quotaField, err.Error())
+	}
 
 	// limits in namespace should include all limits defined on a project
-	projectQuotaLimitMap, err := convert.EncodeToMap(projectQuotaLimit)
+	projectQuotaLimitMap, err := resourcequota.ConvertLimitToResourceList(projectQuotaLimit)
 	if err != nil {
 		return err
 	}
 
-	nsQuotaLimitMap, err := convert.EncodeToMap(nsQuotaLimit)
+	nsQuotaLimitMap, err := resourcequota.ConvertLimitToResourceList(nsQuotaLimit)
 	if err != nil {
 		return err
 	}
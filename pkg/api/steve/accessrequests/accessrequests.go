@@ -0,0 +1,83 @@
+// Package accessrequests customizes the AccessRequest API: the requester is set to the user creating the request,
+// and approvers decide on requests with the approve and deny actions.
+package accessrequests
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/features"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const creatorIDAnnotation = "field.cattle.io/creatorId"
+
+// AccessRequestDecisionInput is the input of the approve and deny actions.
+type AccessRequestDecisionInput struct {
+	// Reason is the comment of the approver on the decision.
+	Reason string `json:"reason,omitempty"`
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	d := &decision{
+		requests: clients.Mgmt.AccessRequest(),
+		approvers: &approvers{
+			grbs:  clients.Mgmt.GlobalRoleBinding().Cache(),
+			crtbs: clients.Mgmt.ClusterRoleTemplateBinding().Cache(),
+			prtbs: clients.Mgmt.ProjectRoleTemplateBinding().Cache(),
+		},
+	}
+
+	server.BaseSchemas.MustImportAndCustomize(AccessRequestDecisionInput{}, nil)
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "management.cattle.io",
+		Kind:  "AccessRequest",
+		StoreFactory: func(innerStore types.Store) types.Store {
+			return &store{
+				Store: innerStore,
+			}
+		},
+		Customize: func(schema *types.APISchema) {
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			if schema.ResourceActions == nil {
+				schema.ResourceActions = map[string]schemas.Action{}
+			}
+			for _, action := range []string{approveAction, denyAction} {
+				schema.ActionHandlers[action] = d
+				schema.ResourceActions[action] = schemas.Action{
+					Input: "accessRequestDecisionInput",
+				}
+			}
+		},
+	})
+}
+
+// store sets the requester of the access requests to the user creating them and records that user as the creator,
+// so that users can't request access on behalf of others. The controller fails the requests whose requester isn't
+// their creator, e.g. those not created through this store.
+type store struct {
+	types.Store
+}
+
+func (s *store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return types.APIObject{}, validation.Unauthorized
+	}
+	obj := data.Data()
+	obj.SetNested(user.GetName(), "spec", "userName")
+	obj.SetNested(user.GetName(), "metadata", "annotations", creatorIDAnnotation)
+	delete(obj, "status")
+	data.Object = obj
+	return s.Store.Create(apiOp, schema, data)
+}
@@ -0,0 +1,163 @@
+package accessrequests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	approveAction = "approve"
+	denyAction    = "deny"
+
+	adminGlobalRole = "admin"
+)
+
+// decision serves the approve and deny actions of the access requests.
+type decision struct {
+	requests  mgmtcontrollers.AccessRequestClient
+	approvers *approvers
+}
+
+func (d *decision) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+
+	var input AccessRequestDecisionInput
+	if err := json.NewDecoder(req.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidBodyContent, err.Error()))
+		return
+	}
+
+	accessRequest, err := d.decide(user, apiRequest.Namespace, apiRequest.Name, apiRequest.Action, input.Reason)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	logrus.Infof("[accessRequest] access request [%s/%s] of user [%s] for RoleTemplate [%s] %s by [%s]: %s",
+		accessRequest.Namespace, accessRequest.Name, accessRequest.Spec.UserName, accessRequest.Spec.RoleTemplateName,
+		strings.ToLower(accessRequest.Status.State), user.GetName(), input.Reason)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// decide records the decision of the user on a pending access request.
+func (d *decision) decide(userInfo user.Info, namespace, name, action, reason string) (*v3.AccessRequest, error) {
+	accessRequest, err := d.requests.Get(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if accessRequest.Status.State != v3.AccessRequestPending {
+		return nil, apierror.NewAPIError(validation.InvalidState, fmt.Sprintf("access request is %s", strings.ToLower(accessRequest.Status.State)))
+	}
+	if accessRequest.Spec.UserName == userInfo.GetName() {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, "users can't decide on their own access requests")
+	}
+	allowed, err := d.approvers.isApprover(userInfo, accessRequest)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, "user is not an approver of the access request")
+	}
+
+	accessRequest = accessRequest.DeepCopy()
+	accessRequest.Status.State = v3.AccessRequestDenied
+	if action == approveAction {
+		accessRequest.Status.State = v3.AccessRequestApproved
+	}
+	now := metav1.NewTime(time.Now())
+	accessRequest.Status.DecidedBy = userInfo.GetName()
+	accessRequest.Status.DecidedAt = &now
+	accessRequest.Status.Reason = reason
+	// a conflict means another approver decided concurrently, the update is not retried
+	return d.requests.UpdateStatus(accessRequest)
+}
+
+// approvers determines who decides on access requests: the admins, the members of the cluster with one of the
+// AccessRequestClusterApproverRoles and, for project requests, the members of the project with one of the
+// AccessRequestProjectApproverRoles.
+type approvers struct {
+	grbs  mgmtcontrollers.GlobalRoleBindingCache
+	crtbs mgmtcontrollers.ClusterRoleTemplateBindingCache
+	prtbs mgmtcontrollers.ProjectRoleTemplateBindingCache
+}
+
+func (a *approvers) isApprover(userInfo user.Info, accessRequest *v3.AccessRequest) (bool, error) {
+	grbs, err := a.grbs.List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, grb := range grbs {
+		if grb.GlobalRoleName == adminGlobalRole && isSubject(userInfo, grb.UserName, grb.GroupPrincipalName) {
+			return true, nil
+		}
+	}
+
+	clusterRoles := roleNames(settings.AccessRequestClusterApproverRoles.Get())
+	crtbs, err := a.crtbs.List(accessRequest.Spec.ClusterName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, crtb := range crtbs {
+		if crtb.DeletionTimestamp == nil && slices.Contains(clusterRoles, crtb.RoleTemplateName) &&
+			isSubject(userInfo, crtb.UserName, crtb.GroupPrincipalName) {
+			return true, nil
+		}
+	}
+
+	if !accessRequest.IsProjectRequest() {
+		return false, nil
+	}
+	_, projectName, _ := strings.Cut(accessRequest.Spec.ProjectName, ":")
+	projectRoles := roleNames(settings.AccessRequestProjectApproverRoles.Get())
+	prtbs, err := a.prtbs.List(projectName, labels.Everything())
+	if err != nil {
+		return false, err
+	}
+	for _, prtb := range prtbs {
+		if prtb.DeletionTimestamp == nil && prtb.ProjectName == accessRequest.Spec.ProjectName &&
+			slices.Contains(projectRoles, prtb.RoleTemplateName) && isSubject(userInfo, prtb.UserName, prtb.GroupPrincipalName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isSubject returns whether a binding to the user or to the group principal applies to the user.
+func isSubject(userInfo user.Info, userName, groupPrincipalName string) bool {
+	if userName != "" {
+		return userName == userInfo.GetName()
+	}
+	return groupPrincipalName != "" && slices.Contains(userInfo.GetGroups(), groupPrincipalName)
+}
+
+func roleNames(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
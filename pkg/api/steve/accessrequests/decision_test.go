@@ -0,0 +1,116 @@
+package accessrequests
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func newDecision(t *testing.T, accessRequest *v3.AccessRequest) *decision {
+	ctrl := gomock.NewController(t)
+
+	grbs := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbs.EXPECT().List(gomock.Any()).Return([]*v3.GlobalRoleBinding{
+		{UserName: "u-admin", GlobalRoleName: "admin"},
+		{UserName: "u-user", GlobalRoleName: "user"},
+	}, nil).AnyTimes()
+	crtbs := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbs.EXPECT().List("c-1", gomock.Any()).Return([]*v3.ClusterRoleTemplateBinding{
+		{UserName: "u-cluster-owner", ClusterName: "c-1", RoleTemplateName: "cluster-owner"},
+		{UserName: "u-cluster-member", ClusterName: "c-1", RoleTemplateName: "cluster-member"},
+		{GroupPrincipalName: "okta_group://oncall-leads", ClusterName: "c-1", RoleTemplateName: "cluster-owner"},
+	}, nil).AnyTimes()
+	prtbs := fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl)
+	prtbs.EXPECT().List("p-1", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{
+		{UserName: "u-project-owner", ProjectName: "c-1:p-1", RoleTemplateName: "project-owner"},
+	}, nil).AnyTimes()
+
+	requests := fake.NewMockControllerInterface[*v3.AccessRequest, *v3.AccessRequestList](ctrl)
+	requests.EXPECT().Get(accessRequest.Namespace, accessRequest.Name, gomock.Any()).Return(accessRequest, nil).AnyTimes()
+	requests.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.AccessRequest) (*v3.AccessRequest, error) {
+		return obj, nil
+	}).AnyTimes()
+
+	return &decision{
+		requests: requests,
+		approvers: &approvers{
+			grbs:  grbs,
+			crtbs: crtbs,
+			prtbs: prtbs,
+		},
+	}
+}
+
+func newAccessRequest(namespace, projectName string) *v3.AccessRequest {
+	return &v3.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: "request", Namespace: namespace},
+		Spec: v3.AccessRequestSpec{
+			UserName:         "u-requester",
+			RoleTemplateName: "cluster-owner",
+			ClusterName:      "c-1",
+			ProjectName:      projectName,
+		},
+		Status: v3.AccessRequestStatus{State: v3.AccessRequestPending},
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         user.Info
+		projectName  string
+		wantApproved bool
+	}{
+		{name: "admin", user: &user.DefaultInfo{Name: "u-admin"}, wantApproved: true},
+		{name: "cluster owner", user: &user.DefaultInfo{Name: "u-cluster-owner"}, wantApproved: true},
+		{name: "cluster owner group", user: &user.DefaultInfo{Name: "u-lead", Groups: []string{"okta_group://oncall-leads"}}, wantApproved: true},
+		{name: "cluster member", user: &user.DefaultInfo{Name: "u-cluster-member"}},
+		{name: "project owner on cluster request", user: &user.DefaultInfo{Name: "u-project-owner"}},
+		{name: "project owner", user: &user.DefaultInfo{Name: "u-project-owner"}, projectName: "c-1:p-1", wantApproved: true},
+		{name: "cluster owner on project request", user: &user.DefaultInfo{Name: "u-cluster-owner"}, projectName: "c-1:p-1", wantApproved: true},
+		{name: "requester", user: &user.DefaultInfo{Name: "u-requester"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := "c-1"
+			if tt.projectName != "" {
+				namespace = "p-1"
+			}
+			d := newDecision(t, newAccessRequest(namespace, tt.projectName))
+
+			got, err := d.decide(tt.user, namespace, "request", approveAction, "on-call")
+			if !tt.wantApproved {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, v3.AccessRequestApproved, got.Status.State)
+			assert.Equal(t, tt.user.GetName(), got.Status.DecidedBy)
+			assert.Equal(t, "on-call", got.Status.Reason)
+			assert.NotNil(t, got.Status.DecidedAt)
+		})
+	}
+}
+
+func TestDecideNotPending(t *testing.T) {
+	accessRequest := newAccessRequest("c-1", "")
+	accessRequest.Status.State = v3.AccessRequestDenied
+	d := newDecision(t, accessRequest)
+
+	_, err := d.decide(&user.DefaultInfo{Name: "u-admin"}, "c-1", "request", approveAction, "")
+	assert.ErrorContains(t, err, "access request is denied")
+}
+
+func TestDeny(t *testing.T) {
+	d := newDecision(t, newAccessRequest("c-1", ""))
+
+	got, err := d.decide(&user.DefaultInfo{Name: "u-cluster-owner"}, "c-1", "request", denyAction, "not on call")
+	require.NoError(t, err)
+	assert.Equal(t, v3.AccessRequestDenied, got.Status.State)
+}
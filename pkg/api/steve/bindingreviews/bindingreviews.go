@@ -0,0 +1,101 @@
+// Package bindingreviews customizes the BindingReview API: the users allowed to update a review confirm its binding
+// with the confirm action, which records who confirmed it.
+package bindingreviews
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/features"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const confirmAction = "confirm"
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	c := &confirmation{
+		reviews: clients.Mgmt.BindingReview(),
+		now:     time.Now,
+	}
+
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "management.cattle.io",
+		Kind:  "BindingReview",
+		Customize: func(schema *types.APISchema) {
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			if schema.ResourceActions == nil {
+				schema.ResourceActions = map[string]schemas.Action{}
+			}
+			schema.ActionHandlers[confirmAction] = c
+			schema.ResourceActions[confirmAction] = schemas.Action{}
+		},
+	})
+}
+
+// confirmation serves the confirm action of the binding reviews.
+type confirmation struct {
+	reviews mgmtcontrollers.BindingReviewClient
+	now     func() time.Time
+}
+
+func (c *confirmation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	user, ok := request.UserFrom(req.Context())
+	if !ok {
+		apiRequest.WriteError(validation.Unauthorized)
+		return
+	}
+	// the action is allowed to the users who can update the review, as they could confirm it with an update
+	if err := apiRequest.AccessControl.CanDo(apiRequest, "management.cattle.io/bindingreviews", "update", apiRequest.Namespace, apiRequest.Name); err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.PermissionDenied, "user can't update the binding review"))
+		return
+	}
+
+	review, err := c.confirm(user, apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+	logrus.Infof("[bindingReview] %s [%s] of user [%s] confirmed by [%s] in review [%s/%s]",
+		review.Spec.BindingKind, review.Spec.BindingName, review.Spec.UserName, user.GetName(), review.Namespace, review.Name)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// confirm records the confirmation of the binding of an open review.
+func (c *confirmation) confirm(userInfo user.Info, namespace, name string) (*v3.BindingReview, error) {
+	review, err := c.reviews.Get(namespace, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if review.Status.State != v3.BindingReviewOpen {
+		return nil, apierror.NewAPIError(validation.InvalidState, "binding review is not open")
+	}
+	if review.Spec.UserName == userInfo.GetName() {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, "users can't confirm their own bindings")
+	}
+
+	review = review.DeepCopy()
+	now := metav1.NewTime(c.now())
+	review.Status.State = v3.BindingReviewConfirmed
+	review.Status.ConfirmedBy = userInfo.GetName()
+	review.Status.ConfirmedAt = &now
+	review.Status.Message = "the binding was confirmed"
+	return c.reviews.UpdateStatus(review)
+}
@@ -0,0 +1,55 @@
+package bindingreviews
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+func newConfirmation(t *testing.T, state string) *confirmation {
+	ctrl := gomock.NewController(t)
+	reviews := fake.NewMockControllerInterface[*v3.BindingReview, *v3.BindingReviewList](ctrl)
+	reviews.EXPECT().Get("c-1", "review-x", gomock.Any()).Return(&v3.BindingReview{
+		ObjectMeta: metav1.ObjectMeta{Name: "review-x", Namespace: "c-1"},
+		Spec:       v3.BindingReviewSpec{BindingKind: "ClusterRoleTemplateBinding", BindingName: "binding", UserName: "u-inactive"},
+		Status:     v3.BindingReviewStatus{State: state},
+	}, nil).AnyTimes()
+	reviews.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.BindingReview) (*v3.BindingReview, error) {
+		return obj, nil
+	}).AnyTimes()
+	return &confirmation{
+		reviews: reviews,
+		now:     func() time.Time { return time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	c := newConfirmation(t, v3.BindingReviewOpen)
+
+	got, err := c.confirm(&user.DefaultInfo{Name: "u-owner"}, "c-1", "review-x")
+	require.NoError(t, err)
+	assert.Equal(t, v3.BindingReviewConfirmed, got.Status.State)
+	assert.Equal(t, "u-owner", got.Status.ConfirmedBy)
+	assert.NotNil(t, got.Status.ConfirmedAt)
+}
+
+func TestConfirmOwnBinding(t *testing.T) {
+	c := newConfirmation(t, v3.BindingReviewOpen)
+
+	_, err := c.confirm(&user.DefaultInfo{Name: "u-inactive"}, "c-1", "review-x")
+	assert.ErrorContains(t, err, "users can't confirm their own bindings")
+}
+
+func TestConfirmRemoved(t *testing.T) {
+	c := newConfirmation(t, v3.BindingReviewRemoved)
+
+	_, err := c.confirm(&user.DefaultInfo{Name: "u-owner"}, "c-1", "review-x")
+	assert.ErrorContains(t, err, "binding review is not open")
+}
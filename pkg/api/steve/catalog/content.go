@@ -154,14 +154,26 @@ func (i *contentDownload) serveChart(apiContext *types.APIRequest, rw http.Respo
 // getIndex retrieves the index file from the Helm repository.
 // By default, the index file contains versions filtered by rancher version and the local cluster's k8s version;
 // If "skipFilter" is set to "true" in the API request, the index file will contain all versions for all charts;
-// if "k8sVersion" is set, the index file will contain versions filtered by rancher version and the k8s version.
+// if "k8sVersion" is set, the index file will contain versions filtered by rancher version and the k8s version;
+// if "os" or "arch" (comma separated) are set, the index file will only contain versions supporting them.
 func (i *contentDownload) getIndex(apiContext *types.APIRequest) (*repo.IndexFile, error) {
 	namespace, name := nsAndName(apiContext)
 	query := apiContext.Request.URL.Query()
 	rawValue := query.Get("skipFilter")
 	skipFilter := strings.ToLower(rawValue) == "true"
 	targetClusterVersion := query.Get("k8sVersion")
-	return i.contentManager.Index(namespace, name, targetClusterVersion, skipFilter)
+
+	var architectures []string
+	if rawArch := query.Get("arch"); rawArch != "" {
+		architectures = strings.Split(rawArch, ",")
+	}
+
+	return i.contentManager.IndexWithOptions(namespace, name, content.IndexOptions{
+		TargetK8sVersion:    targetClusterVersion,
+		TargetOS:            query.Get("os"),
+		TargetArchitectures: architectures,
+		SkipFilter:          skipFilter,
+	})
 }
 
 // nsAndName returns the namespace and name from the API context. If the
@@ -1,6 +1,7 @@
 package catalog
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/rancher/apiserver/pkg/types"
@@ -81,6 +82,12 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			apiRequest.Namespace, apiRequest.Name)
 	}
 
+	var valuesErr *helmop.ValuesValidationError
+	if errors.As(err, &valuesErr) {
+		writeValuesValidationError(apiRequest, valuesErr)
+		return
+	}
+
 	if err != nil {
 		apiRequest.WriteError(err)
 		return
@@ -99,6 +106,26 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	})
 }
 
+// writeValuesValidationError writes a 422 error response listing every invalid field of the values of the request,
+// so that clients can point users at all the fields to fix at once. The fieldName of the error is the first invalid field.
+func writeValuesValidationError(apiRequest *types.APIRequest, valuesErr *helmop.ValuesValidationError) {
+	data := map[string]interface{}{
+		"type":        "error",
+		"status":      validation.InvalidBodyContent.Status,
+		"code":        validation.InvalidBodyContent.Code,
+		"message":     valuesErr.Error(),
+		"fieldErrors": valuesErr.Errors,
+	}
+	if len(valuesErr.Errors) > 0 {
+		data["fieldName"] = valuesErr.Errors[0].Field
+	}
+
+	apiRequest.WriteResponse(validation.InvalidBodyContent.Status, types.APIObject{
+		Type:   "error",
+		Object: data,
+	})
+}
+
 // OnAdd is registered as a callback of a Kubernetes Informer.
 // It is invoked when a new object is added to the Kubernetes cluster.
 // It purges old roles related to the object being added.
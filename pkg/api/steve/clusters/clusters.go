@@ -54,6 +54,7 @@ func Register(ctx context.Context, server *steve.Server, wrangler *wrangler.Cont
 	})
 
 	server.BaseSchemas.MustImportAndCustomize(GenerateKubeconfigOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(ClusterConnectivityOutput{}, nil)
 	server.SchemaFactory.AddTemplate(schema2.Template{
 		Group:     "management.cattle.io",
 		Kind:      "Cluster",
@@ -64,6 +65,7 @@ func Register(ctx context.Context, server *steve.Server, wrangler *wrangler.Cont
 			}
 			schema.LinkHandlers["shell"] = shell
 			schema.LinkHandlers["log"] = log
+			schema.LinkHandlers["connectivity"] = connectivity{sessions: wrangler.TunnelSessions}
 			if schema.ActionHandlers == nil {
 				schema.ActionHandlers = map[string]http.Handler{}
 			}
@@ -0,0 +1,47 @@
+package clusters
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/api/steve/proxy"
+	"github.com/rancher/rancher/pkg/tunnelserver"
+)
+
+// connectivity serves the history of the tunnel sessions of the agents of a cluster.
+type connectivity struct {
+	sessions *tunnelserver.SessionHistory
+}
+
+func (c connectivity) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+
+	output := &ClusterConnectivityOutput{}
+	if sessions, ok := c.sessions.Get(apiRequest.Name); ok {
+		output.ClusterAgent = &sessions
+	}
+	if sessions, ok := c.sessions.Get(proxy.Prefix + apiRequest.Name); ok {
+		output.APIProxy = &sessions
+	}
+
+	// the IPs of the agents are only shown to the users who can manage the cluster, not to anyone who can read it
+	if apiRequest.AccessControl.CanDo(apiRequest, "management.cattle.io/clusters", "update", "", apiRequest.Name) != nil {
+		hideClientIPs(output.ClusterAgent)
+		hideClientIPs(output.APIProxy)
+	}
+
+	apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+		Type:   "clusterConnectivityOutput",
+		ID:     apiRequest.Name,
+		Object: output,
+	})
+}
+
+func hideClientIPs(sessions *tunnelserver.ClientSessions) {
+	if sessions == nil {
+		return
+	}
+	for i := range sessions.Sessions {
+		sessions.Sessions[i].ClientIP = ""
+	}
+}
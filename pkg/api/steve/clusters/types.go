@@ -1,5 +1,16 @@
 package clusters
 
+import "github.com/rancher/rancher/pkg/tunnelserver"
+
 type GenerateKubeconfigOutput struct {
 	Config string `json:"config,omitempty"`
 }
+
+// ClusterConnectivityOutput is the history of the tunnel sessions of the agents of a cluster handled by the
+// Rancher server answering the request.
+type ClusterConnectivityOutput struct {
+	// ClusterAgent is the session history of the cluster agent.
+	ClusterAgent *tunnelserver.ClientSessions `json:"clusterAgent,omitempty"`
+	// APIProxy is the session history of the tunnel used to proxy the Kubernetes API of the cluster.
+	APIProxy *tunnelserver.ClientSessions `json:"apiProxy,omitempty"`
+}
@@ -0,0 +1,97 @@
+// Package membershippolicies customizes the ProjectMembershipPolicy API: the user creating or updating a policy is
+// recorded as its author.
+package membershippolicies
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/features"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/util/retry"
+)
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "management.cattle.io",
+		Kind:  "ProjectMembershipPolicy",
+		StoreFactory: func(innerStore types.Store) types.Store {
+			return &store{
+				Store:    innerStore,
+				policies: clients.Mgmt.ProjectMembershipPolicy(),
+			}
+		},
+	})
+}
+
+// store records the user creating or updating a policy as its author, in the status of the policy along with the
+// generation it was recorded for, so that the policy only grants the RoleTemplates its last author could grant. The
+// status is written with the client of Rancher: users can't write it, and whatever the request, e.g. a patch, the
+// author is recorded for the generation it produced. The controller doesn't grant anything for the policies whose
+// author wasn't recorded for their current generation, e.g. those last written without this store.
+type store struct {
+	types.Store
+	policies mgmtcontrollers.ProjectMembershipPolicyClient
+}
+
+func (s *store) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return types.APIObject{}, validation.Unauthorized
+	}
+	result, err := s.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return result, err
+	}
+	return result, s.recordAuthor(result, user.GetName())
+}
+
+func (s *store) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return types.APIObject{}, validation.Unauthorized
+	}
+	result, err := s.Store.Update(apiOp, schema, data, id)
+	if err != nil {
+		return result, err
+	}
+	return result, s.recordAuthor(result, user.GetName())
+}
+
+// recordAuthor records the author of the policy written by the request, unless it was written again since.
+func (s *store) recordAuthor(written types.APIObject, author string) error {
+	obj := written.Data()
+	namespace, name := obj.String("metadata", "namespace"), obj.String("metadata", "name")
+	generation, err := convert.ToNumber(obj.Map("metadata")["generation"])
+	if err != nil {
+		return err
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		policy, err := s.policies.Get(namespace, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if policy.Generation != generation {
+			// the author of the newer generation is recorded by the request which wrote it, if any
+			return nil
+		}
+		policy = policy.DeepCopy()
+		policy.Status.Author = author
+		policy.Status.AuthorGeneration = generation
+		_, err = s.policies.UpdateStatus(policy)
+		return err
+	})
+	if err != nil {
+		logrus.Errorf("[membershipPolicy] failed to record [%s] as the author of policy [%s/%s]: %v", author, namespace, name, err)
+	}
+	return err
+}
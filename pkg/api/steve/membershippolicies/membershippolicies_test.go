@@ -0,0 +1,42 @@
+package membershippolicies
+
+import (
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func written(generation int64) types.APIObject {
+	return types.APIObject{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"namespace": "c-1", "name": "teams", "generation": generation},
+	}}
+}
+
+func TestRecordAuthor(t *testing.T) {
+	policies := fake.NewMockClientInterface[*v3.ProjectMembershipPolicy, *v3.ProjectMembershipPolicyList](gomock.NewController(t))
+	s := &store{policies: policies}
+	policy := &v3.ProjectMembershipPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "c-1", Name: "teams", Generation: 3},
+		Status:     v3.ProjectMembershipPolicyStatus{Author: "u-admin", AuthorGeneration: 2},
+	}
+	policies.EXPECT().Get("c-1", "teams", gomock.Any()).Return(policy, nil).Times(2)
+
+	var recorded *v3.ProjectMembershipPolicy
+	policies.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.ProjectMembershipPolicy) (*v3.ProjectMembershipPolicy, error) {
+		recorded = obj
+		return obj, nil
+	})
+	require.NoError(t, s.recordAuthor(written(3), "u-owner"))
+	require.NotNil(t, recorded)
+	assert.Equal(t, "u-owner", recorded.Status.Author)
+	assert.Equal(t, int64(3), recorded.Status.AuthorGeneration)
+
+	// the policy was written again since, the author of the request isn't recorded for the newer generation
+	require.NoError(t, s.recordAuthor(written(2), "u-owner"))
+}
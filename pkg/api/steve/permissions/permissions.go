@@ -0,0 +1,314 @@
+// Package permissions adds the effectivePermissions and whoCan collection actions to the User API. They resolve the
+// permissions granted by GlobalRoleBindings, ClusterRoleTemplateBindings and ProjectRoleTemplateBindings, and explain
+// which binding and which role grant each rule. It also adds the previewChange collection action to the RoleTemplate
+// and GlobalRole APIs, which computes the bindings, subjects, clusters and rules a change would affect before it is
+// saved.
+package permissions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/features"
+	"github.com/rancher/rancher/pkg/wrangler"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	effectivePermissionsAction = "effectivePermissions"
+	whoCanAction               = "whoCan"
+	previewChangeAction        = "previewChange"
+
+	roleTemplateSchemaID = "management.cattle.io.roletemplate"
+	globalRoleSchemaID   = "management.cattle.io.globalrole"
+)
+
+// Scopes of the permissions.
+const (
+	// ScopeGlobal permissions apply cluster-wide in the local cluster.
+	ScopeGlobal = "global"
+	// ScopeNamespace permissions apply in a single namespace of the local cluster.
+	ScopeNamespace = "namespace"
+	// ScopeFleetWorkspace permissions apply in every fleet workspace besides the local one.
+	ScopeFleetWorkspace = "fleetWorkspace"
+	// ScopeCluster permissions apply cluster-wide in a downstream cluster.
+	ScopeCluster = "cluster"
+	// ScopeProject permissions apply in every namespace of a project.
+	ScopeProject = "project"
+	// ScopeProjectNamespaces permissions apply in the namespaces of a project matching a label selector.
+	ScopeProjectNamespaces = "projectNamespaces"
+)
+
+// EffectivePermissionsInput is the input of the effectivePermissions action. Exactly one of UserName and
+// GroupPrincipalName must be set.
+type EffectivePermissionsInput struct {
+	// UserName is the name of the user whose permissions are resolved, including those of the groups of the user.
+	UserName string `json:"userName,omitempty"`
+	// GroupPrincipalName is the name of the group principal whose permissions are resolved.
+	GroupPrincipalName string `json:"groupPrincipalName,omitempty"`
+	// ClusterName is the downstream cluster to resolve the permissions in. The permissions in the management plane
+	// are resolved if it is empty.
+	ClusterName string `json:"clusterName,omitempty"`
+	// ProjectName restricts the project permissions to a single project of the cluster, in the format
+	// <cluster>:<project>.
+	ProjectName string `json:"projectName,omitempty"`
+}
+
+// EffectivePermissionsOutput is the output of the effectivePermissions action.
+type EffectivePermissionsOutput struct {
+	UserName string `json:"userName,omitempty"`
+	// GroupPrincipalNames are the group principals whose permissions were included.
+	GroupPrincipalNames []string     `json:"groupPrincipalNames"`
+	Permissions         []Permission `json:"permissions"`
+	// Warnings list the roles that could not be resolved.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// WhoCanInput is the input of the whoCan action.
+type WhoCanInput struct {
+	Verb     string `json:"verb"`
+	APIGroup string `json:"apiGroup,omitempty"`
+	Resource string `json:"resource"`
+	// Subresource is the subresource of the resource, like log for pods. Rules only allow it if they list the
+	// resource and subresource, like pods/log, or a wildcard.
+	Subresource  string `json:"subresource,omitempty"`
+	ResourceName string `json:"resourceName,omitempty"`
+	// Namespace restricts the namespaced permissions of the management plane to a single namespace.
+	Namespace string `json:"namespace,omitempty"`
+	// ClusterName is the downstream cluster to resolve the permissions in. The permissions in the management plane
+	// are resolved if it is empty.
+	ClusterName string `json:"clusterName,omitempty"`
+	// ProjectName restricts the project permissions to a single project of the cluster, in the format
+	// <cluster>:<project>.
+	ProjectName string `json:"projectName,omitempty"`
+}
+
+// WhoCanOutput is the output of the whoCan action.
+type WhoCanOutput struct {
+	Principals  []Principal  `json:"principals"`
+	Permissions []Permission `json:"permissions"`
+	// Warnings list the roles that could not be resolved.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Principal is a user or a group principal.
+type Principal struct {
+	// Kind is either User or Group.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Permission is a rule granted to a principal, with the binding and the role granting it.
+type Permission struct {
+	// SubjectKind is either User or Group.
+	SubjectKind string `json:"subjectKind"`
+	SubjectName string `json:"subjectName"`
+	// Scope is where the rule applies: global, namespace, fleetWorkspace, cluster, project or projectNamespaces.
+	Scope       string `json:"scope"`
+	ClusterName string `json:"clusterName,omitempty"`
+	ProjectName string `json:"projectName,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	// NamespaceSelector selects the namespaces of the project a projectNamespaces rule applies in.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// BindingKind is either GlobalRoleBinding, ClusterRoleTemplateBinding or ProjectRoleTemplateBinding.
+	BindingKind      string `json:"bindingKind"`
+	BindingNamespace string `json:"bindingNamespace,omitempty"`
+	BindingName      string `json:"bindingName"`
+	// BoundRoleName is the role referenced by the binding.
+	BoundRoleName string `json:"boundRoleName"`
+	// RoleKind and RoleName identify the role defining the rule, either the bound role or a role it inherits from.
+	RoleKind string            `json:"roleKind"`
+	RoleName string            `json:"roleName"`
+	Rule     rbacv1.PolicyRule `json:"rule"`
+}
+
+func Register(server *steve.Server, clients *wrangler.Context) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	r := &resolver{
+		grbs:           clients.Mgmt.GlobalRoleBinding().Cache(),
+		globalRoles:    clients.Mgmt.GlobalRole().Cache(),
+		crtbs:          clients.Mgmt.ClusterRoleTemplateBinding().Cache(),
+		prtbs:          clients.Mgmt.ProjectRoleTemplateBinding().Cache(),
+		roleTemplates:  clients.Mgmt.RoleTemplate().Cache(),
+		clusterRoles:   clients.RBAC.ClusterRole().Cache(),
+		userAttributes: clients.Mgmt.UserAttribute().Cache(),
+	}
+	h := &handler{
+		resolver: r,
+		previewer: &previewer{
+			resolver: r,
+			clusters: clients.Mgmt.Cluster().Cache(),
+		},
+	}
+
+	server.BaseSchemas.MustImportAndCustomize(EffectivePermissionsInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(EffectivePermissionsOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(WhoCanInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(WhoCanOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(ChangePreview{}, nil)
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "management.cattle.io",
+		Kind:  "User",
+		Customize: func(schema *types.APISchema) {
+			if schema.ActionHandlers == nil {
+				schema.ActionHandlers = map[string]http.Handler{}
+			}
+			if schema.CollectionActions == nil {
+				schema.CollectionActions = map[string]schemas.Action{}
+			}
+			schema.ActionHandlers[effectivePermissionsAction] = h
+			schema.CollectionActions[effectivePermissionsAction] = schemas.Action{
+				Input:  "effectivePermissionsInput",
+				Output: "effectivePermissionsOutput",
+			}
+			schema.ActionHandlers[whoCanAction] = h
+			schema.CollectionActions[whoCanAction] = schemas.Action{
+				Input:  "whoCanInput",
+				Output: "whoCanOutput",
+			}
+		},
+	})
+	for _, kind := range []string{"RoleTemplate", "GlobalRole"} {
+		server.SchemaFactory.AddTemplate(schema2.Template{
+			Group: "management.cattle.io",
+			Kind:  kind,
+			Customize: func(schema *types.APISchema) {
+				if schema.ActionHandlers == nil {
+					schema.ActionHandlers = map[string]http.Handler{}
+				}
+				if schema.CollectionActions == nil {
+					schema.CollectionActions = map[string]schemas.Action{}
+				}
+				schema.ActionHandlers[previewChangeAction] = h
+				schema.CollectionActions[previewChangeAction] = schemas.Action{
+					Input:  schema.ID,
+					Output: "changePreview",
+				}
+			},
+		})
+	}
+}
+
+// handler serves the effectivePermissions, whoCan and previewChange actions. As they reveal the bindings of every
+// principal, they are only allowed to users who can list all the bindings.
+type handler struct {
+	resolver  *resolver
+	previewer *previewer
+}
+
+func (h *handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	for _, resource := range []string{"globalrolebindings", "clusterroletemplatebindings", "projectroletemplatebindings"} {
+		if err := apiRequest.AccessControl.CanDo(apiRequest, "management.cattle.io/"+resource, "list", "", ""); err != nil {
+			apiRequest.WriteError(apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("listing all %s is required", resource)))
+			return
+		}
+	}
+
+	var (
+		output any
+		err    error
+	)
+	switch apiRequest.Action {
+	case effectivePermissionsAction:
+		var input EffectivePermissionsInput
+		if err = decode(req.Body, &input); err == nil {
+			output, err = h.effectivePermissions(&input)
+		}
+	case whoCanAction:
+		var input WhoCanInput
+		if err = decode(req.Body, &input); err == nil {
+			output, err = h.whoCan(&input)
+		}
+	case previewChangeAction:
+		output, err = h.previewChange(apiRequest.Type, req.Body)
+	}
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(output); err != nil {
+		apiRequest.WriteError(err)
+	}
+}
+
+func (h *handler) effectivePermissions(input *EffectivePermissionsInput) (*EffectivePermissionsOutput, error) {
+	if (input.UserName == "") == (input.GroupPrincipalName == "") {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, "exactly one of userName and groupPrincipalName is required")
+	}
+	if err := validateScope(input.ClusterName, input.ProjectName); err != nil {
+		return nil, err
+	}
+	return h.resolver.effectivePermissions(input)
+}
+
+func (h *handler) whoCan(input *WhoCanInput) (*WhoCanOutput, error) {
+	if input.Verb == "" || input.Resource == "" {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, "verb and resource are required")
+	}
+	if err := validateScope(input.ClusterName, input.ProjectName); err != nil {
+		return nil, err
+	}
+	return h.resolver.whoCan(input)
+}
+
+// previewChange previews saving the RoleTemplate or GlobalRole in the body. The change isn't persisted.
+func (h *handler) previewChange(schemaID string, body io.Reader) (*ChangePreview, error) {
+	switch schemaID {
+	case roleTemplateSchemaID:
+		var roleTemplate v3.RoleTemplate
+		if err := decode(body, &roleTemplate); err != nil {
+			return nil, err
+		}
+		if roleTemplate.Name == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "metadata.name is required")
+		}
+		return h.previewer.previewRoleTemplate(&roleTemplate)
+	case globalRoleSchemaID:
+		var globalRole v3.GlobalRole
+		if err := decode(body, &globalRole); err != nil {
+			return nil, err
+		}
+		if globalRole.Name == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "metadata.name is required")
+		}
+		return h.previewer.previewGlobalRole(&globalRole)
+	}
+	return nil, apierror.NewAPIError(validation.InvalidAction, fmt.Sprintf("%s is not supported on %s", previewChangeAction, schemaID))
+}
+
+func validateScope(clusterName, projectName string) error {
+	if projectName == "" {
+		return nil
+	}
+	if clusterName == "" {
+		return apierror.NewAPIError(validation.InvalidBodyContent, "clusterName is required with projectName")
+	}
+	if projectCluster, _, ok := strings.Cut(projectName, ":"); !ok || projectCluster != clusterName {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("projectName %q must be in the format %s:<project>", projectName, clusterName))
+	}
+	return nil
+}
+
+func decode(body io.Reader, input any) error {
+	if err := json.NewDecoder(body).Decode(input); err != nil && !errors.Is(err, io.EOF) {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	return nil
+}
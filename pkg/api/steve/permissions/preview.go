@@ -0,0 +1,549 @@
+package permissions
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	roleKind = "Role"
+
+	// globalRoleCRNameAnnotation holds the name of the ClusterRole backing the rules of a GlobalRole, when it isn't
+	// the default one.
+	globalRoleCRNameAnnotation = "authz.management.cattle.io/cr-name"
+	// fleetWorkspaceClusterRulesName and fleetWorkspaceVerbsName suffix the ClusterRoles backing the fleet workspace
+	// permissions of a GlobalRole.
+	fleetWorkspaceClusterRulesName = "fwcr"
+	fleetWorkspaceVerbsName        = "fwv"
+)
+
+// ChangePreview is the output of the previewChange action: the effect saving a RoleTemplate or a GlobalRole would
+// have on the permissions granted by its bindings.
+type ChangePreview struct {
+	// Bindings are the bindings whose permissions change.
+	Bindings []AffectedBinding `json:"bindings"`
+	// UserNames and GroupPrincipalNames are the subjects of the affected bindings.
+	UserNames           []string `json:"userNames"`
+	GroupPrincipalNames []string `json:"groupPrincipalNames"`
+	// ClusterNames are the clusters where the permissions of the affected bindings change, local being the
+	// management plane.
+	ClusterNames []string `json:"clusterNames"`
+	// Roles are the rules added to and removed from the roles granted by the bindings.
+	Roles []RoleChange `json:"roles"`
+	// Warnings list the roles that could not be resolved.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// AffectedBinding is a binding whose permissions change.
+type AffectedBinding struct {
+	// Kind is either GlobalRoleBinding, ClusterRoleTemplateBinding or ProjectRoleTemplateBinding.
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SubjectKind string `json:"subjectKind"`
+	SubjectName string `json:"subjectName"`
+	// BoundRoleName is the role referenced by the binding, either the changed role or a RoleTemplate inheriting from
+	// it.
+	BoundRoleName string `json:"boundRoleName"`
+	ClusterName   string `json:"clusterName,omitempty"`
+	ProjectName   string `json:"projectName,omitempty"`
+}
+
+// RoleChange is the rules added to and removed from a role granted by the bindings. The roles of RoleTemplates are
+// the ClusterRoles named after them in the downstream clusters, and the Roles holding their NamespaceSelectedRules.
+type RoleChange struct {
+	// Kind is either ClusterRole or Role.
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// NamespaceSelector selects the namespaces of the projects the Role of NamespaceSelectedRules holds the rules in,
+	// all of them if it is empty.
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	// ClusterNames are the clusters the role is granted in by the bindings.
+	ClusterNames []string            `json:"clusterNames"`
+	Added        []rbacv1.PolicyRule `json:"added"`
+	Removed      []rbacv1.PolicyRule `json:"removed"`
+}
+
+// bindingKindOrder orders the affected bindings from the broadest to the narrowest.
+var bindingKindOrder = map[string]int{
+	globalRoleBindingKind:          0,
+	clusterRoleTemplateBindingKind: 1,
+	projectRoleTemplateBindingKind: 2,
+}
+
+// previewer computes the effect of saving a RoleTemplate or a GlobalRole on the permissions granted by the bindings,
+// following the same rules as the resolver.
+type previewer struct {
+	*resolver
+	clusters mgmtcontrollers.ClusterCache
+}
+
+// previewRoleTemplate previews saving the RoleTemplate. The ClusterRoles and the Roles of the NamespaceSelectedRules of
+// the RoleTemplate and of the templates it inherits from are compared, and the bindings of the RoleTemplate and of the
+// templates inheriting from it are affected.
+func (p *previewer) previewRoleTemplate(proposed *v3.RoleTemplate) (*ChangePreview, error) {
+	preview := newChangePreview()
+
+	current, err := p.roleTemplates.Get(proposed.Name)
+	if apierrors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		return nil, err
+	}
+	var before []rbac.TemplateRules
+	if current != nil {
+		before, err = rbac.RulesByTemplate(p.clusterRoles, p.roleTemplates, current)
+		if err != nil {
+			preview.Warnings = append(preview.Warnings, fmt.Sprintf("resolving the current RoleTemplate [%s]: %v", current.Name, err))
+		}
+	}
+	after, err := rbac.RulesByTemplate(p.clusterRoles, &proposedRoleTemplates{RoleTemplateCache: p.roleTemplates, proposed: proposed}, proposed)
+	if err != nil {
+		return nil, fmt.Errorf("resolving the proposed RoleTemplate [%s]: %w", proposed.Name, err)
+	}
+
+	preview.Roles = diffTemplateRules(before, after)
+	// a new RoleTemplate isn't bound yet
+	if len(preview.Roles) == 0 || current == nil {
+		return preview.finish(), nil
+	}
+
+	affected, err := p.inheritingRoleTemplates(proposed.Name)
+	if err != nil {
+		return nil, err
+	}
+	globalRoles, err := p.globalRoles.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("listing GlobalRoles: %w", err)
+	}
+	inheritingGlobalRoles := map[string]bool{}
+	for _, globalRole := range globalRoles {
+		if slices.ContainsFunc(globalRole.InheritedClusterRoles, func(roleTemplateName string) bool { return affected[roleTemplateName] }) {
+			inheritingGlobalRoles[globalRole.Name] = true
+		}
+	}
+	var downstream []string
+	if len(inheritingGlobalRoles) > 0 {
+		downstream, err = p.downstreamClusterNames()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.addGlobalRoleBindings(preview, inheritingGlobalRoles, downstream); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.addRoleTemplateBindings(preview, affected); err != nil {
+		return nil, err
+	}
+
+	for i := range preview.Roles {
+		preview.Roles[i].ClusterNames = roleClusterNames(preview.Roles[i], preview.Bindings, downstream)
+	}
+	return preview.finish(), nil
+}
+
+// roleClusterNames returns the clusters the bindings grant the changed role of a RoleTemplate in. The changed roles
+// belong to the RoleTemplate or to the templates it inherits from, so all the bindings of the templates inheriting from
+// it grant their ClusterRoles, while only the ProjectRoleTemplateBindings grant the Roles of their
+// NamespaceSelectedRules. GlobalRoleBindings grant the ClusterRoles in all the downstream clusters.
+func roleClusterNames(role RoleChange, bindings []AffectedBinding, downstream []string) []string {
+	names := []string{}
+	for _, binding := range bindings {
+		switch {
+		case role.Kind == roleKind && binding.Kind != projectRoleTemplateBindingKind:
+		case binding.Kind == globalRoleBindingKind:
+			names = append(names, downstream...)
+		default:
+			names = append(names, binding.ClusterName)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+// previewGlobalRole previews saving the GlobalRole. The roles backing its rules in the management plane, and the
+// roles it grants in the downstream clusters are compared, and its bindings are affected.
+func (p *previewer) previewGlobalRole(proposed *v3.GlobalRole) (*ChangePreview, error) {
+	preview := newChangePreview()
+
+	current, err := p.globalRoles.Get(proposed.Name)
+	if apierrors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		return nil, err
+	}
+	compared := current
+	if compared == nil {
+		compared = &v3.GlobalRole{}
+		compared.Name = proposed.Name
+	}
+
+	var clusterNames []string
+	localChanges := localRoleChanges(compared, proposed)
+	if len(localChanges) > 0 {
+		clusterNames = append(clusterNames, localClusterName)
+	}
+	downstreamChanges := p.downstreamRoleChanges(preview, compared, proposed)
+	if len(downstreamChanges) > 0 {
+		downstream, err := p.downstreamClusterNames()
+		if err != nil {
+			return nil, err
+		}
+		for i := range downstreamChanges {
+			downstreamChanges[i].ClusterNames = downstream
+		}
+		clusterNames = append(clusterNames, downstream...)
+	}
+	preview.Roles = append(localChanges, downstreamChanges...)
+
+	// a new GlobalRole isn't bound yet
+	if len(preview.Roles) > 0 && current != nil {
+		if err := p.addGlobalRoleBindings(preview, map[string]bool{proposed.Name: true}, clusterNames); err != nil {
+			return nil, err
+		}
+	}
+	return preview.finish(), nil
+}
+
+// localRoleChanges compares the roles backing the rules, the namespaced rules and the fleet workspace permissions of
+// a GlobalRole in the management plane.
+func localRoleChanges(current, proposed *v3.GlobalRole) []RoleChange {
+	changes := []RoleChange{}
+	crName := "cattle-globalrole-" + current.Name
+	if annotation, ok := current.Annotations[globalRoleCRNameAnnotation]; ok {
+		crName = annotation
+	}
+	changes = appendRoleChange(changes, RoleChange{Kind: clusterRoleKind, Name: crName}, current.Rules, proposed.Rules)
+
+	var namespaces []string
+	for namespace := range current.NamespacedRules {
+		namespaces = append(namespaces, namespace)
+	}
+	for namespace := range proposed.NamespacedRules {
+		if _, ok := current.NamespacedRules[namespace]; !ok {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		changes = appendRoleChange(changes, RoleChange{Kind: roleKind, Namespace: namespace, Name: name.SafeConcatName(current.Name, namespace)},
+			current.NamespacedRules[namespace], proposed.NamespacedRules[namespace])
+	}
+
+	changes = appendRoleChange(changes, RoleChange{Kind: clusterRoleKind, Name: name.SafeConcatName(current.Name, fleetWorkspaceClusterRulesName)},
+		fleetWorkspaceResourceRules(current.InheritedFleetWorkspacePermissions), fleetWorkspaceResourceRules(proposed.InheritedFleetWorkspacePermissions))
+	changes = appendRoleChange(changes, RoleChange{Kind: clusterRoleKind, Name: name.SafeConcatName(current.Name, fleetWorkspaceVerbsName)},
+		fleetWorkspaceVerbsRules(current.InheritedFleetWorkspacePermissions), fleetWorkspaceVerbsRules(proposed.InheritedFleetWorkspacePermissions))
+
+	for i := range changes {
+		changes[i].ClusterNames = []string{localClusterName}
+	}
+	return changes
+}
+
+// downstreamRoleChanges compares the cluster-admin role and the ClusterRoles of the InheritedClusterRoles granted by a
+// GlobalRole in the downstream clusters.
+func (p *previewer) downstreamRoleChanges(preview *ChangePreview, current, proposed *v3.GlobalRole) []RoleChange {
+	var changes []RoleChange
+	var currentAdminRules, proposedAdminRules []rbacv1.PolicyRule
+	if rbac.IsAdminGlobalRole(current) {
+		currentAdminRules = clusterAdminRules
+	}
+	if rbac.IsAdminGlobalRole(proposed) {
+		proposedAdminRules = clusterAdminRules
+	}
+	changes = appendRoleChange(changes, RoleChange{Kind: clusterRoleKind, Name: "cluster-admin"}, currentAdminRules, proposedAdminRules)
+
+	before := p.inheritedTemplateRules(preview, current)
+	after := p.inheritedTemplateRules(preview, proposed)
+	return append(changes, diffTemplateRules(before, after)...)
+}
+
+// inheritedTemplateRules returns the rules of the InheritedClusterRoles of a GlobalRole, grouped by RoleTemplate.
+func (p *previewer) inheritedTemplateRules(preview *ChangePreview, globalRole *v3.GlobalRole) []rbac.TemplateRules {
+	var result []rbac.TemplateRules
+	for _, roleTemplateName := range globalRole.InheritedClusterRoles {
+		roleTemplate, err := p.roleTemplates.Get(roleTemplateName)
+		if err == nil {
+			var byTemplate []rbac.TemplateRules
+			byTemplate, err = rbac.RulesByTemplate(p.clusterRoles, p.roleTemplates, roleTemplate)
+			if err == nil {
+				result = append(result, byTemplate...)
+				continue
+			}
+		}
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("resolving RoleTemplate [%s] of GlobalRole [%s]: %v", roleTemplateName, globalRole.Name, err))
+	}
+	return result
+}
+
+// inheritingRoleTemplates returns the RoleTemplate and the names of the RoleTemplates inheriting from it, directly or
+// not.
+func (p *previewer) inheritingRoleTemplates(roleTemplateName string) (map[string]bool, error) {
+	roleTemplates, err := p.roleTemplates.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("listing RoleTemplates: %w", err)
+	}
+	inheriting := map[string]bool{roleTemplateName: true}
+	for changed := true; changed; {
+		changed = false
+		for _, roleTemplate := range roleTemplates {
+			if inheriting[roleTemplate.Name] {
+				continue
+			}
+			if slices.ContainsFunc(roleTemplate.RoleTemplateNames, func(name string) bool { return inheriting[name] }) {
+				inheriting[roleTemplate.Name] = true
+				changed = true
+			}
+		}
+	}
+	return inheriting, nil
+}
+
+// addGlobalRoleBindings adds the bindings of the GlobalRoles, whose permissions change in the clusters.
+func (p *previewer) addGlobalRoleBindings(preview *ChangePreview, globalRoleNames map[string]bool, clusterNames []string) error {
+	grbs, err := p.grbs.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing GlobalRoleBindings: %w", err)
+	}
+	for _, grb := range grbs {
+		if grb.DeletionTimestamp != nil || !globalRoleNames[grb.GlobalRoleName] {
+			continue
+		}
+		kind, subject := subjectOf(grb.UserName, grb.GroupPrincipalName)
+		if kind == "" {
+			continue
+		}
+		preview.addBinding(AffectedBinding{
+			Kind:          globalRoleBindingKind,
+			Name:          grb.Name,
+			SubjectKind:   kind,
+			SubjectName:   subject,
+			BoundRoleName: grb.GlobalRoleName,
+		}, clusterNames...)
+	}
+	return nil
+}
+
+// addRoleTemplateBindings adds the ClusterRoleTemplateBindings and ProjectRoleTemplateBindings of the RoleTemplates.
+// The bindings materializing the InheritedClusterRoles of a GlobalRoleBinding are accounted for by the latter.
+func (p *previewer) addRoleTemplateBindings(preview *ChangePreview, roleTemplateNames map[string]bool) error {
+	crtbs, err := p.crtbs.List("", labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing ClusterRoleTemplateBindings: %w", err)
+	}
+	for _, crtb := range crtbs {
+		if crtb.DeletionTimestamp != nil || crtb.Labels[grbOwnerLabel] != "" || !roleTemplateNames[crtb.RoleTemplateName] {
+			continue
+		}
+		kind, subject := subjectOf(crtb.UserName, crtb.GroupPrincipalName)
+		if kind == "" {
+			continue
+		}
+		preview.addBinding(AffectedBinding{
+			Kind:          clusterRoleTemplateBindingKind,
+			Namespace:     crtb.Namespace,
+			Name:          crtb.Name,
+			SubjectKind:   kind,
+			SubjectName:   subject,
+			BoundRoleName: crtb.RoleTemplateName,
+			ClusterName:   crtb.ClusterName,
+		}, crtb.ClusterName)
+	}
+
+	prtbs, err := p.prtbs.List("", labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing ProjectRoleTemplateBindings: %w", err)
+	}
+	for _, prtb := range prtbs {
+		if prtb.DeletionTimestamp != nil || !roleTemplateNames[prtb.RoleTemplateName] {
+			continue
+		}
+		kind, subject := subjectOf(prtb.UserName, prtb.GroupPrincipalName)
+		if kind == "" {
+			continue
+		}
+		clusterName, _, _ := strings.Cut(prtb.ProjectName, ":")
+		preview.addBinding(AffectedBinding{
+			Kind:          projectRoleTemplateBindingKind,
+			Namespace:     prtb.Namespace,
+			Name:          prtb.Name,
+			SubjectKind:   kind,
+			SubjectName:   subject,
+			BoundRoleName: prtb.RoleTemplateName,
+			ClusterName:   clusterName,
+			ProjectName:   prtb.ProjectName,
+		}, clusterName)
+	}
+	return nil
+}
+
+// downstreamClusterNames returns the names of the clusters the InheritedClusterRoles of GlobalRoles apply in.
+func (p *previewer) downstreamClusterNames() ([]string, error) {
+	clusters, err := p.clusters.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("listing clusters: %w", err)
+	}
+	var names []string
+	for _, cluster := range clusters {
+		if cluster.Name != localClusterName && cluster.DeletionTimestamp == nil {
+			names = append(names, cluster.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func newChangePreview() *ChangePreview {
+	return &ChangePreview{
+		Bindings:            []AffectedBinding{},
+		UserNames:           []string{},
+		GroupPrincipalNames: []string{},
+		ClusterNames:        []string{},
+		Roles:               []RoleChange{},
+	}
+}
+
+func (preview *ChangePreview) addBinding(binding AffectedBinding, clusterNames ...string) {
+	preview.Bindings = append(preview.Bindings, binding)
+	if binding.SubjectKind == rbacv1.UserKind {
+		preview.UserNames = append(preview.UserNames, binding.SubjectName)
+	} else {
+		preview.GroupPrincipalNames = append(preview.GroupPrincipalNames, binding.SubjectName)
+	}
+	preview.ClusterNames = append(preview.ClusterNames, clusterNames...)
+}
+
+// finish sorts the bindings and removes the duplicate subjects and clusters.
+func (preview *ChangePreview) finish() *ChangePreview {
+	sort.SliceStable(preview.Bindings, func(i, j int) bool {
+		a, b := preview.Bindings[i], preview.Bindings[j]
+		if a.Kind != b.Kind {
+			return bindingKindOrder[a.Kind] < bindingKindOrder[b.Kind]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	for _, names := range []*[]string{&preview.UserNames, &preview.GroupPrincipalNames, &preview.ClusterNames} {
+		sort.Strings(*names)
+		*names = slices.Compact(*names)
+	}
+	return preview
+}
+
+// diffTemplateRules compares the rules of the ClusterRoles of RoleTemplates, and of the Roles of their
+// NamespaceSelectedRules for each of their namespace selectors.
+func diffTemplateRules(before, after []rbac.TemplateRules) []RoleChange {
+	rulesByName := func(byTemplate []rbac.TemplateRules) map[string][]rbacv1.PolicyRule {
+		result := map[string][]rbacv1.PolicyRule{}
+		for _, templateRules := range byTemplate {
+			result[templateRules.RoleTemplate.Name] = templateRules.Rules
+		}
+		return result
+	}
+	selectedRulesByName := func(byTemplate []rbac.TemplateRules) map[string]map[string][]rbacv1.PolicyRule {
+		result := map[string]map[string][]rbacv1.PolicyRule{}
+		for _, templateRules := range byTemplate {
+			result[templateRules.RoleTemplate.Name] = namespaceSelectedRulesBySelector(templateRules.RoleTemplate)
+		}
+		return result
+	}
+	beforeRules, afterRules := rulesByName(before), rulesByName(after)
+	beforeSelected, afterSelected := selectedRulesByName(before), selectedRulesByName(after)
+
+	var names []string
+	for roleTemplateName := range beforeRules {
+		names = append(names, roleTemplateName)
+	}
+	for roleTemplateName := range afterRules {
+		if _, ok := beforeRules[roleTemplateName]; !ok {
+			names = append(names, roleTemplateName)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []RoleChange{}
+	for _, roleTemplateName := range names {
+		changes = appendRoleChange(changes, RoleChange{Kind: clusterRoleKind, Name: roleTemplateName}, beforeRules[roleTemplateName], afterRules[roleTemplateName])
+
+		var selectors []string
+		for selector := range beforeSelected[roleTemplateName] {
+			selectors = append(selectors, selector)
+		}
+		for selector := range afterSelected[roleTemplateName] {
+			if _, ok := beforeSelected[roleTemplateName][selector]; !ok {
+				selectors = append(selectors, selector)
+			}
+		}
+		sort.Strings(selectors)
+		for _, selector := range selectors {
+			change := RoleChange{Kind: roleKind, Name: rbac.NamespaceSelectedRoleName(roleTemplateName), NamespaceSelector: selector}
+			changes = appendRoleChange(changes, change, beforeSelected[roleTemplateName][selector], afterSelected[roleTemplateName][selector])
+		}
+	}
+	return changes
+}
+
+// namespaceSelectedRulesBySelector returns the NamespaceSelectedRules of a RoleTemplate by namespace selector.
+func namespaceSelectedRulesBySelector(roleTemplate *v3.RoleTemplate) map[string][]rbacv1.PolicyRule {
+	result := map[string][]rbacv1.PolicyRule{}
+	for _, selected := range roleTemplate.NamespaceSelectedRules {
+		// an empty selector selects all the namespaces of the project
+		selector := ""
+		if len(selected.NamespaceSelector.MatchLabels) > 0 || len(selected.NamespaceSelector.MatchExpressions) > 0 {
+			selector = metav1.FormatLabelSelector(&selected.NamespaceSelector)
+		}
+		result[selector] = append(result[selector], selected.Rules...)
+	}
+	return result
+}
+
+// appendRoleChange appends the change of a role if its rules differ.
+func appendRoleChange(changes []RoleChange, change RoleChange, before, after []rbacv1.PolicyRule) []RoleChange {
+	change.Added = missingRules(after, before)
+	change.Removed = missingRules(before, after)
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return changes
+	}
+	return append(changes, change)
+}
+
+// missingRules returns the rules that are not in others.
+func missingRules(rules, others []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	missing := []rbacv1.PolicyRule{}
+	for _, rule := range rules {
+		if !slices.ContainsFunc(others, func(other rbacv1.PolicyRule) bool { return equality.Semantic.DeepEqual(rule, other) }) {
+			missing = append(missing, rule)
+		}
+	}
+	return missing
+}
+
+// proposedRoleTemplates returns the proposed RoleTemplate in place of the current one, so the RoleTemplates
+// inheriting from it are resolved with its proposed rules.
+type proposedRoleTemplates struct {
+	mgmtcontrollers.RoleTemplateCache
+	proposed *v3.RoleTemplate
+}
+
+func (c *proposedRoleTemplates) Get(name string) (*v3.RoleTemplate, error) {
+	if name == c.proposed.Name {
+		return c.proposed, nil
+	}
+	return c.RoleTemplateCache.Get(name)
+}
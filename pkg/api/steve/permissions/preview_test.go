@@ -0,0 +1,313 @@
+package permissions
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newPreviewer(t *testing.T) *previewer {
+	ctrl := gomock.NewController(t)
+
+	grbs := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbs.EXPECT().List(gomock.Any()).Return([]*v3.GlobalRoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "grb-operator"}, UserName: "u-alice", GlobalRoleName: "operator"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "grb-user"}, GroupPrincipalName: devsGroup, GlobalRoleName: "user"},
+	}, nil).AnyTimes()
+
+	globalRoles := []*v3.GlobalRole{
+		{ObjectMeta: metav1.ObjectMeta{Name: "user"}, Rules: []rbacv1.PolicyRule{listNodes}},
+		{
+			ObjectMeta:            metav1.ObjectMeta{Name: "operator"},
+			Rules:                 []rbacv1.PolicyRule{getPods},
+			NamespacedRules:       map[string][]rbacv1.PolicyRule{"cattle-system": {createPods}},
+			InheritedClusterRoles: []string{"cluster-member"},
+		},
+	}
+	globalRoleCache := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRole](ctrl)
+	globalRoleCache.EXPECT().List(gomock.Any()).Return(globalRoles, nil).AnyTimes()
+	globalRoleCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.GlobalRole, error) {
+		for _, globalRole := range globalRoles {
+			if globalRole.Name == name {
+				return globalRole, nil
+			}
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "globalroles"}, name)
+	}).AnyTimes()
+
+	crtbs := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbs.EXPECT().List("", gomock.Any()).Return([]*v3.ClusterRoleTemplateBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "crtb-owner", Namespace: "c-1"}, UserName: "u-bob", ClusterName: "c-1", RoleTemplateName: "cluster-owner"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "crtb-member", Namespace: "c-2"}, GroupPrincipalName: devsGroup, ClusterName: "c-2", RoleTemplateName: "cluster-member"},
+		{
+			ObjectMeta:       metav1.ObjectMeta{Name: "crtb-grb", Namespace: "c-1", Labels: map[string]string{grbOwnerLabel: "grb-operator"}},
+			UserName:         "u-alice",
+			ClusterName:      "c-1",
+			RoleTemplateName: "cluster-member",
+		},
+	}, nil).AnyTimes()
+
+	prtbs := fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl)
+	prtbs.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prtb-carol", Namespace: "p-1"}, UserName: "u-carol", ProjectName: "c-1:p-1", RoleTemplateName: "view-pods"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prtb-dave", Namespace: "p-1"}, UserName: "u-dave", ProjectName: "c-1:p-1", RoleTemplateName: "project-member"},
+	}, nil).AnyTimes()
+
+	roleTemplates := []*v3.RoleTemplate{
+		{ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner"}, Context: "cluster", Rules: []rbacv1.PolicyRule{allRules}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cluster-member"}, Context: "cluster", Rules: []rbacv1.PolicyRule{listNodes}, RoleTemplateNames: []string{"view-pods"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "project-member"}, Context: "project", Rules: []rbacv1.PolicyRule{createPods}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "view-pods"}, Rules: []rbacv1.PolicyRule{getPods}},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().List(gomock.Any()).Return(roleTemplates, nil).AnyTimes()
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		for _, roleTemplate := range roleTemplates {
+			if roleTemplate.Name == name {
+				return roleTemplate, nil
+			}
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+	}).AnyTimes()
+
+	clusters := fake.NewMockNonNamespacedCacheInterface[*v3.Cluster](ctrl)
+	clusters.EXPECT().List(gomock.Any()).Return([]*v3.Cluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "local"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-1"}},
+	}, nil).AnyTimes()
+
+	return &previewer{
+		resolver: &resolver{
+			grbs:          grbs,
+			globalRoles:   globalRoleCache,
+			crtbs:         crtbs,
+			prtbs:         prtbs,
+			roleTemplates: roleTemplateCache,
+			clusterRoles:  fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl),
+		},
+		clusters: clusters,
+	}
+}
+
+// affected summarizes the bindings as "kind namespace/name subject".
+func affected(bindings []AffectedBinding) []string {
+	var result []string
+	for _, binding := range bindings {
+		result = append(result, binding.Kind+" "+binding.Namespace+"/"+binding.Name+" "+binding.SubjectName)
+	}
+	return result
+}
+
+func TestPreviewRoleTemplate(t *testing.T) {
+	p := newPreviewer(t)
+	listPods := rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "view-pods"},
+		Rules:      []rbacv1.PolicyRule{listPods},
+	})
+	require.NoError(t, err)
+
+	// the bindings of view-pods and of cluster-member, which inherits from it, are affected
+	assert.Equal(t, []string{
+		"GlobalRoleBinding /grb-operator u-alice",
+		"ClusterRoleTemplateBinding c-2/crtb-member " + devsGroup,
+		"ProjectRoleTemplateBinding p-1/prtb-carol u-carol",
+	}, affected(got.Bindings))
+	assert.Equal(t, []string{"u-alice", "u-carol"}, got.UserNames)
+	assert.Equal(t, []string{devsGroup}, got.GroupPrincipalNames)
+	assert.Equal(t, []string{"c-1", "c-2"}, got.ClusterNames)
+	assert.Equal(t, []RoleChange{{
+		Kind:         clusterRoleKind,
+		Name:         "view-pods",
+		ClusterNames: []string{"c-1", "c-2"},
+		Added:        []rbacv1.PolicyRule{listPods},
+		Removed:      []rbacv1.PolicyRule{getPods},
+	}}, got.Roles)
+	assert.Empty(t, got.Warnings)
+}
+
+func TestPreviewRoleTemplateInheritance(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta:        metav1.ObjectMeta{Name: "project-member"},
+		Context:           "project",
+		Rules:             []rbacv1.PolicyRule{createPods},
+		RoleTemplateNames: []string{"view-pods"},
+	})
+	require.NoError(t, err)
+
+	// the ClusterRole of view-pods is now granted by the bindings of project-member
+	assert.Equal(t, []string{"ProjectRoleTemplateBinding p-1/prtb-dave u-dave"}, affected(got.Bindings))
+	assert.Equal(t, []RoleChange{{
+		Kind:         clusterRoleKind,
+		Name:         "view-pods",
+		ClusterNames: []string{"c-1"},
+		Added:        []rbacv1.PolicyRule{getPods},
+		Removed:      []rbacv1.PolicyRule{},
+	}}, got.Roles)
+}
+
+func TestPreviewRoleTemplateNamespaceSelectedRules(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "project-member"},
+		Context:    "project",
+		Rules:      []rbacv1.PolicyRule{createPods},
+		NamespaceSelectedRules: []v3.NamespaceSelectedRules{
+			{NamespaceSelector: webTeam, Rules: []rbacv1.PolicyRule{getWebCMs}},
+			{Rules: []rbacv1.PolicyRule{getPods}},
+		},
+	})
+	require.NoError(t, err)
+
+	// the Role holding the NamespaceSelectedRules changes for each of their selectors
+	assert.Equal(t, []string{"ProjectRoleTemplateBinding p-1/prtb-dave u-dave"}, affected(got.Bindings))
+	assert.Equal(t, []RoleChange{
+		{
+			Kind:         roleKind,
+			Name:         "project-member-namespace-selected",
+			ClusterNames: []string{"c-1"},
+			Added:        []rbacv1.PolicyRule{getPods},
+			Removed:      []rbacv1.PolicyRule{},
+		},
+		{
+			Kind:              roleKind,
+			Name:              "project-member-namespace-selected",
+			NamespaceSelector: "team=web",
+			ClusterNames:      []string{"c-1"},
+			Added:             []rbacv1.PolicyRule{getWebCMs},
+			Removed:           []rbacv1.PolicyRule{},
+		},
+	}, got.Roles)
+}
+
+func TestPreviewRoleTemplateRoleClusterNames(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta:             metav1.ObjectMeta{Name: "view-pods"},
+		Rules:                  []rbacv1.PolicyRule{getPods},
+		NamespaceSelectedRules: []v3.NamespaceSelectedRules{{NamespaceSelector: webTeam, Rules: []rbacv1.PolicyRule{getWebCMs}}},
+	})
+	require.NoError(t, err)
+
+	// the Role of the NamespaceSelectedRules is only granted by the ProjectRoleTemplateBinding in c-1, not by the
+	// bindings of cluster-member in c-2
+	assert.Equal(t, []string{"c-1", "c-2"}, got.ClusterNames)
+	assert.Equal(t, []RoleChange{{
+		Kind:              roleKind,
+		Name:              "view-pods-namespace-selected",
+		NamespaceSelector: "team=web",
+		ClusterNames:      []string{"c-1"},
+		Added:             []rbacv1.PolicyRule{getWebCMs},
+		Removed:           []rbacv1.PolicyRule{},
+	}}, got.Roles)
+}
+
+func TestPreviewRoleTemplateUnchanged(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner", Labels: map[string]string{"edited": "true"}},
+		Context:    "cluster",
+		Rules:      []rbacv1.PolicyRule{allRules},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, got.Bindings)
+	assert.Empty(t, got.Roles)
+}
+
+func TestPreviewNewRoleTemplate(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "new"},
+		Rules:      []rbacv1.PolicyRule{listNodes},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, got.Bindings)
+	require.Len(t, got.Roles, 1)
+	assert.Equal(t, []rbacv1.PolicyRule{listNodes}, got.Roles[0].Added)
+
+	_, err = p.previewRoleTemplate(&v3.RoleTemplate{
+		ObjectMeta:        metav1.ObjectMeta{Name: "new"},
+		RoleTemplateNames: []string{"missing"},
+	})
+	assert.ErrorContains(t, err, `roletemplates "missing" not found`)
+}
+
+func TestPreviewGlobalRole(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewGlobalRole(&v3.GlobalRole{
+		ObjectMeta:            metav1.ObjectMeta{Name: "operator"},
+		Rules:                 []rbacv1.PolicyRule{getPods},
+		NamespacedRules:       map[string][]rbacv1.PolicyRule{"cattle-system": {createPods, listNodes}},
+		InheritedClusterRoles: []string{"cluster-owner"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"GlobalRoleBinding /grb-operator u-alice"}, affected(got.Bindings))
+	assert.Equal(t, []string{"c-1", "c-2", "local"}, got.ClusterNames)
+	assert.Equal(t, []RoleChange{
+		{
+			Kind:         roleKind,
+			Namespace:    "cattle-system",
+			Name:         "operator-cattle-system",
+			ClusterNames: []string{"local"},
+			Added:        []rbacv1.PolicyRule{listNodes},
+			Removed:      []rbacv1.PolicyRule{},
+		},
+		{
+			Kind:         clusterRoleKind,
+			Name:         "cluster-member",
+			ClusterNames: []string{"c-1", "c-2"},
+			Added:        []rbacv1.PolicyRule{},
+			Removed:      []rbacv1.PolicyRule{listNodes},
+		},
+		{
+			Kind:         clusterRoleKind,
+			Name:         "cluster-owner",
+			ClusterNames: []string{"c-1", "c-2"},
+			Added:        []rbacv1.PolicyRule{allRules},
+			Removed:      []rbacv1.PolicyRule{},
+		},
+		{
+			Kind:         clusterRoleKind,
+			Name:         "view-pods",
+			ClusterNames: []string{"c-1", "c-2"},
+			Added:        []rbacv1.PolicyRule{},
+			Removed:      []rbacv1.PolicyRule{getPods},
+		},
+	}, got.Roles)
+}
+
+func TestPreviewGlobalRoleAdmin(t *testing.T) {
+	p := newPreviewer(t)
+
+	got, err := p.previewGlobalRole(&v3.GlobalRole{
+		ObjectMeta: metav1.ObjectMeta{Name: "user"},
+		Rules:      clusterAdminRules,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"GlobalRoleBinding /grb-user " + devsGroup}, affected(got.Bindings))
+	assert.Equal(t, []string{devsGroup}, got.GroupPrincipalNames)
+	require.Len(t, got.Roles, 2)
+	assert.Equal(t, "cattle-globalrole-user", got.Roles[0].Name)
+	assert.Equal(t, []string{"local"}, got.Roles[0].ClusterNames)
+	assert.Equal(t, "cluster-admin", got.Roles[1].Name)
+	assert.Equal(t, clusterAdminRules, got.Roles[1].Added)
+}
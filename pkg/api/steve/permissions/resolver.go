@@ -0,0 +1,438 @@
+package permissions
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
+	wrbacv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	rbacauthorizer "k8s.io/kubernetes/plugin/pkg/auth/authorizer/rbac"
+)
+
+const (
+	localClusterName = "local"
+	// grbOwnerLabel is set on the ClusterRoleTemplateBindings created for the InheritedClusterRoles of a
+	// GlobalRoleBinding. Those are resolved from the GlobalRoleBinding directly.
+	grbOwnerLabel = "authz.management.cattle.io/grb-owner"
+
+	globalRoleBindingKind          = "GlobalRoleBinding"
+	globalRoleKind                 = "GlobalRole"
+	clusterRoleTemplateBindingKind = "ClusterRoleTemplateBinding"
+	projectRoleTemplateBindingKind = "ProjectRoleTemplateBinding"
+	roleTemplateKind               = "RoleTemplate"
+	clusterRoleKind                = "ClusterRole"
+)
+
+// clusterAdminRules are the rules of the cluster-admin ClusterRole, granted in the downstream clusters by admin
+// GlobalRoles.
+var clusterAdminRules = []rbacv1.PolicyRule{
+	{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}},
+	{Verbs: []string{"*"}, NonResourceURLs: []string{"*"}},
+}
+
+// resolver resolves the permissions granted by the GlobalRoleBindings, ClusterRoleTemplateBindings and
+// ProjectRoleTemplateBindings, following the same rules as the controllers materializing them.
+type resolver struct {
+	grbs           mgmtcontrollers.GlobalRoleBindingCache
+	globalRoles    mgmtcontrollers.GlobalRoleCache
+	crtbs          mgmtcontrollers.ClusterRoleTemplateBindingCache
+	prtbs          mgmtcontrollers.ProjectRoleTemplateBindingCache
+	roleTemplates  mgmtcontrollers.RoleTemplateCache
+	clusterRoles   wrbacv1.ClusterRoleCache
+	userAttributes mgmtcontrollers.UserAttributeCache
+}
+
+// subjectFilter returns whether the permissions granted to a subject are resolved.
+type subjectFilter func(kind, name string) bool
+
+// resolution holds the permissions resolved for a query.
+type resolution struct {
+	permissions []Permission
+	warnings    []string
+}
+
+// effectivePermissions resolves the permissions of a user, including those of the groups the user is a member of, or
+// of a group principal. The permissions in the management plane are resolved if clusterName is empty, those in the
+// downstream cluster otherwise.
+func (r *resolver) effectivePermissions(input *EffectivePermissionsInput) (*EffectivePermissionsOutput, error) {
+	output := &EffectivePermissionsOutput{
+		UserName:            input.UserName,
+		GroupPrincipalNames: []string{},
+	}
+	if input.UserName != "" {
+		userAttribute, err := r.userAttributes.Get(input.UserName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if userAttribute != nil {
+			for _, principals := range userAttribute.GroupPrincipals {
+				for _, principal := range principals.Items {
+					output.GroupPrincipalNames = append(output.GroupPrincipalNames, principal.Name)
+				}
+			}
+			sort.Strings(output.GroupPrincipalNames)
+		}
+	} else {
+		output.GroupPrincipalNames = append(output.GroupPrincipalNames, input.GroupPrincipalName)
+	}
+
+	res, err := r.resolve(input.ClusterName, input.ProjectName, func(kind, name string) bool {
+		if kind == rbacv1.UserKind {
+			return input.UserName != "" && name == input.UserName
+		}
+		return slices.Contains(output.GroupPrincipalNames, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	output.Permissions = res.permissions
+	output.Warnings = res.warnings
+	return output, nil
+}
+
+// whoCan resolves the principals allowed to perform a verb on a resource, with the permissions allowing it.
+func (r *resolver) whoCan(input *WhoCanInput) (*WhoCanOutput, error) {
+	res, err := r.resolve(input.ClusterName, input.ProjectName, func(string, string) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	output := &WhoCanOutput{
+		Principals:  []Principal{},
+		Permissions: []Permission{},
+		Warnings:    res.warnings,
+	}
+	for _, permission := range res.permissions {
+		if permission.Scope == ScopeNamespace && input.Namespace != "" && permission.Namespace != input.Namespace {
+			continue
+		}
+		rule := permission.Rule
+		if permission.Scope == ScopeProjectNamespaces {
+			rule = namespaceSelectedRuleFor(rule, input.ResourceName)
+		}
+		if !ruleAllows(rule, input) {
+			continue
+		}
+		output.Permissions = append(output.Permissions, permission)
+		principal := Principal{Kind: permission.SubjectKind, Name: permission.SubjectName}
+		if !slices.Contains(output.Principals, principal) {
+			output.Principals = append(output.Principals, principal)
+		}
+	}
+	return output, nil
+}
+
+// resolve collects the permissions granted to the subjects accepted by the filter, in the management plane if
+// clusterName is empty, or in the downstream cluster and optionally only in one of its projects.
+func (r *resolver) resolve(clusterName, projectName string, isSubject subjectFilter) (*resolution, error) {
+	res := &resolution{permissions: []Permission{}}
+	if err := r.resolveGlobalRoleBindings(res, clusterName, isSubject); err != nil {
+		return nil, err
+	}
+	if clusterName == "" {
+		return res, nil
+	}
+	if err := r.resolveClusterRoleTemplateBindings(res, clusterName, isSubject); err != nil {
+		return nil, err
+	}
+	if err := r.resolveProjectRoleTemplateBindings(res, clusterName, projectName, isSubject); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *resolver) resolveGlobalRoleBindings(res *resolution, clusterName string, isSubject subjectFilter) error {
+	grbs, err := r.grbs.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing GlobalRoleBindings: %w", err)
+	}
+	sort.Slice(grbs, func(i, j int) bool { return grbs[i].Name < grbs[j].Name })
+
+	for _, grb := range grbs {
+		if grb.DeletionTimestamp != nil {
+			continue
+		}
+		kind, name := subjectOf(grb.UserName, grb.GroupPrincipalName)
+		if kind == "" || !isSubject(kind, name) {
+			continue
+		}
+		globalRole, err := r.globalRoles.Get(grb.GlobalRoleName)
+		if apierrors.IsNotFound(err) {
+			res.warnings = append(res.warnings, fmt.Sprintf("GlobalRole [%s] of GlobalRoleBinding [%s] not found", grb.GlobalRoleName, grb.Name))
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		base := Permission{
+			SubjectKind:   kind,
+			SubjectName:   name,
+			BindingKind:   globalRoleBindingKind,
+			BindingName:   grb.Name,
+			RoleKind:      globalRoleKind,
+			RoleName:      globalRole.Name,
+			BoundRoleName: globalRole.Name,
+		}
+
+		if clusterName == "" {
+			base.Scope = ScopeGlobal
+			base.ClusterName = localClusterName
+			res.add(base, globalRole.Rules)
+
+			namespaces := make([]string, 0, len(globalRole.NamespacedRules))
+			for namespace := range globalRole.NamespacedRules {
+				namespaces = append(namespaces, namespace)
+			}
+			sort.Strings(namespaces)
+			for _, namespace := range namespaces {
+				permission := base
+				permission.Scope = ScopeNamespace
+				permission.Namespace = namespace
+				res.add(permission, globalRole.NamespacedRules[namespace])
+			}
+
+			permission := base
+			permission.Scope = ScopeFleetWorkspace
+			res.add(permission, fleetWorkspaceResourceRules(globalRole.InheritedFleetWorkspacePermissions))
+			res.add(permission, fleetWorkspaceVerbsRules(globalRole.InheritedFleetWorkspacePermissions))
+			continue
+		}
+
+		// the InheritedClusterRoles and the admin permissions only apply in downstream clusters
+		if clusterName == localClusterName {
+			continue
+		}
+		base.Scope = ScopeCluster
+		base.ClusterName = clusterName
+		if rbac.IsAdminGlobalRole(globalRole) {
+			permission := base
+			permission.RoleKind = clusterRoleKind
+			permission.RoleName = "cluster-admin"
+			res.add(permission, clusterAdminRules)
+		}
+		for _, roleTemplateName := range globalRole.InheritedClusterRoles {
+			r.addRoleTemplate(res, base, roleTemplateName)
+		}
+	}
+	return nil
+}
+
+func (r *resolver) resolveClusterRoleTemplateBindings(res *resolution, clusterName string, isSubject subjectFilter) error {
+	crtbs, err := r.crtbs.List(clusterName, labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing ClusterRoleTemplateBindings: %w", err)
+	}
+	sort.Slice(crtbs, func(i, j int) bool { return crtbs[i].Name < crtbs[j].Name })
+
+	for _, crtb := range crtbs {
+		if crtb.DeletionTimestamp != nil || crtb.ClusterName != clusterName || crtb.Labels[grbOwnerLabel] != "" {
+			continue
+		}
+		kind, name := subjectOf(crtb.UserName, crtb.GroupPrincipalName)
+		if kind == "" || !isSubject(kind, name) {
+			continue
+		}
+		r.addRoleTemplate(res, Permission{
+			SubjectKind:      kind,
+			SubjectName:      name,
+			Scope:            ScopeCluster,
+			ClusterName:      clusterName,
+			BindingKind:      clusterRoleTemplateBindingKind,
+			BindingNamespace: crtb.Namespace,
+			BindingName:      crtb.Name,
+		}, crtb.RoleTemplateName)
+	}
+	return nil
+}
+
+func (r *resolver) resolveProjectRoleTemplateBindings(res *resolution, clusterName, projectName string, isSubject subjectFilter) error {
+	prtbs, err := r.prtbs.List("", labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing ProjectRoleTemplateBindings: %w", err)
+	}
+	sort.Slice(prtbs, func(i, j int) bool {
+		if prtbs[i].Namespace != prtbs[j].Namespace {
+			return prtbs[i].Namespace < prtbs[j].Namespace
+		}
+		return prtbs[i].Name < prtbs[j].Name
+	})
+
+	for _, prtb := range prtbs {
+		if prtb.DeletionTimestamp != nil || !strings.HasPrefix(prtb.ProjectName, clusterName+":") {
+			continue
+		}
+		if projectName != "" && prtb.ProjectName != projectName {
+			continue
+		}
+		kind, name := subjectOf(prtb.UserName, prtb.GroupPrincipalName)
+		if kind == "" || !isSubject(kind, name) {
+			continue
+		}
+		r.addRoleTemplate(res, Permission{
+			SubjectKind:      kind,
+			SubjectName:      name,
+			Scope:            ScopeProject,
+			ClusterName:      clusterName,
+			ProjectName:      prtb.ProjectName,
+			BindingKind:      projectRoleTemplateBindingKind,
+			BindingNamespace: prtb.Namespace,
+			BindingName:      prtb.Name,
+		}, prtb.RoleTemplateName)
+	}
+	return nil
+}
+
+// addRoleTemplate adds the rules of a RoleTemplate and of all the templates it inherits from, attributed to the
+// template defining them. Each of those templates is a ClusterRole in the downstream cluster.
+func (r *resolver) addRoleTemplate(res *resolution, base Permission, roleTemplateName string) {
+	if base.BoundRoleName == "" {
+		base.BoundRoleName = roleTemplateName
+	}
+	base.RoleKind = roleTemplateKind
+
+	roleTemplate, err := r.roleTemplates.Get(roleTemplateName)
+	if err == nil {
+		var byTemplate []rbac.TemplateRules
+		byTemplate, err = rbac.RulesByTemplate(r.clusterRoles, r.roleTemplates, roleTemplate)
+		if err == nil {
+			for _, templateRules := range byTemplate {
+				permission := base
+				permission.RoleName = templateRules.RoleTemplate.Name
+				rules := templateRules.Rules
+				if !templateRules.RoleTemplate.External {
+					rules = toLowerRules(rules)
+				}
+				res.add(permission, rules)
+				if base.Scope == ScopeProject {
+					res.addNamespaceSelectedRules(permission, templateRules.RoleTemplate)
+				}
+			}
+			return
+		}
+	}
+	res.warnings = append(res.warnings, fmt.Sprintf("resolving RoleTemplate [%s] of %s [%s]: %v", roleTemplateName, base.BindingKind, bindingKey(base), err))
+}
+
+// addNamespaceSelectedRules adds the NamespaceSelectedRules of a project RoleTemplate, granted by a Role in the
+// matching namespaces of the project. Invalid ones aren't granted by the controllers, which is reported as a warning.
+func (res *resolution) addNamespaceSelectedRules(base Permission, roleTemplate *v3.RoleTemplate) {
+	if len(roleTemplate.NamespaceSelectedRules) == 0 {
+		return
+	}
+	if err := rbac.ValidateNamespaceSelectedRules(roleTemplate); err != nil {
+		res.warnings = append(res.warnings, fmt.Sprintf("the namespaceSelectedRules of RoleTemplate [%s] of %s [%s] aren't granted: %v",
+			roleTemplate.Name, base.BindingKind, bindingKey(base), err))
+		return
+	}
+
+	base.Scope = ScopeProjectNamespaces
+	base.RoleKind = roleKind
+	base.RoleName = rbac.NamespaceSelectedRoleName(roleTemplate.Name)
+	for _, selected := range roleTemplate.NamespaceSelectedRules {
+		permission := base
+		permission.NamespaceSelector = selected.NamespaceSelector.DeepCopy()
+		res.add(permission, selected.Rules)
+	}
+}
+
+// namespaceSelectedRuleFor returns the rule of NamespaceSelectedRules as granted for the resource name: its resource
+// name patterns grant the names starting with their prefix.
+func namespaceSelectedRuleFor(rule rbacv1.PolicyRule, resourceName string) rbacv1.PolicyRule {
+	if resourceName == "" || !rbac.HasResourceNamePatterns(rule) {
+		return rule
+	}
+	for _, name := range rule.ResourceNames {
+		if prefix, ok := rbac.ResourceNamePrefix(name); ok && strings.HasPrefix(resourceName, prefix) {
+			rule = *rule.DeepCopy()
+			rule.ResourceNames = []string{resourceName}
+			return rule
+		}
+	}
+	return rule
+}
+
+// fleetWorkspaceResourceRules returns the rules a GlobalRole grants in the fleet workspaces.
+func fleetWorkspaceResourceRules(fleet *v3.FleetWorkspacePermission) []rbacv1.PolicyRule {
+	if fleet == nil {
+		return nil
+	}
+	return fleet.ResourceRules
+}
+
+// fleetWorkspaceVerbsRules returns the rules a GlobalRole grants on the fleet workspaces themselves.
+func fleetWorkspaceVerbsRules(fleet *v3.FleetWorkspacePermission) []rbacv1.PolicyRule {
+	if fleet == nil || len(fleet.WorkspaceVerbs) == 0 {
+		return nil
+	}
+	return []rbacv1.PolicyRule{{
+		Verbs:     fleet.WorkspaceVerbs,
+		APIGroups: []string{"management.cattle.io"},
+		Resources: []string{"fleetworkspaces"},
+	}}
+}
+
+func (res *resolution) add(base Permission, rules []rbacv1.PolicyRule) {
+	for _, rule := range rules {
+		permission := base
+		permission.Rule = rule
+		res.permissions = append(res.permissions, permission)
+	}
+}
+
+// subjectOf returns the kind and name of the subject of a binding, or an empty kind for bindings that have not been
+// resolved to a user or a group principal yet.
+func subjectOf(userName, groupPrincipalName string) (string, string) {
+	if userName != "" {
+		return rbacv1.UserKind, userName
+	}
+	if groupPrincipalName != "" {
+		return rbacv1.GroupKind, groupPrincipalName
+	}
+	return "", ""
+}
+
+func bindingKey(permission Permission) string {
+	if permission.BindingNamespace == "" {
+		return permission.BindingName
+	}
+	return permission.BindingNamespace + "/" + permission.BindingName
+}
+
+// ruleAllows returns whether the rule allows the request as the Kubernetes RBAC authorizer does: verbs, resources and
+// resource names match exactly, subresources only match the rules listing them, and requests without a resource name
+// are only allowed by rules without resource names.
+func ruleAllows(rule rbacv1.PolicyRule, input *WhoCanInput) bool {
+	return rbacauthorizer.RuleAllows(authorizer.AttributesRecord{
+		Verb:            input.Verb,
+		APIGroup:        input.APIGroup,
+		Resource:        input.Resource,
+		Subresource:     input.Subresource,
+		Name:            input.ResourceName,
+		ResourceRequest: true,
+	}, &rule)
+}
+
+// toLowerRules returns the rules of RoleTemplates as the controllers grant them in the ClusterRoles they create, with
+// lowercase resources and verbs.
+func toLowerRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	result := make([]rbacv1.PolicyRule, 0, len(rules))
+	for _, rule := range rules {
+		lower := *rule.DeepCopy()
+		for i := range lower.Resources {
+			lower.Resources[i] = strings.ToLower(lower.Resources[i])
+		}
+		for i := range lower.Verbs {
+			lower.Verbs[i] = strings.ToLower(lower.Verbs[i])
+		}
+		result = append(result, lower)
+	}
+	return result
+}
@@ -0,0 +1,293 @@
+package permissions
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const devsGroup = "okta_group://devs"
+
+var (
+	getPods    = rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+	createPods = rbacv1.PolicyRule{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+	listNodes  = rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"nodes"}}
+	allRules   = rbacv1.PolicyRule{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}
+	getWebCMs  = rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"web-*"}}
+	webTeam    = metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}
+)
+
+func newResolver(t *testing.T) *resolver {
+	ctrl := gomock.NewController(t)
+
+	grbs := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbs.EXPECT().List(gomock.Any()).Return([]*v3.GlobalRoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "grb-user"}, UserName: "u-alice", GlobalRoleName: "operator"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "grb-admin"}, UserName: "u-admin", GlobalRoleName: "admin"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "grb-group"}, GroupPrincipalName: devsGroup, GlobalRoleName: "user"},
+	}, nil).AnyTimes()
+
+	globalRoles := map[string]*v3.GlobalRole{
+		"admin": {ObjectMeta: metav1.ObjectMeta{Name: "admin"}, Builtin: true, Rules: []rbacv1.PolicyRule{allRules}},
+		"user":  {ObjectMeta: metav1.ObjectMeta{Name: "user"}, Rules: []rbacv1.PolicyRule{listNodes}},
+		"operator": {
+			ObjectMeta:            metav1.ObjectMeta{Name: "operator"},
+			Rules:                 []rbacv1.PolicyRule{getPods},
+			NamespacedRules:       map[string][]rbacv1.PolicyRule{"cattle-system": {createPods}},
+			InheritedClusterRoles: []string{"cluster-member"},
+			InheritedFleetWorkspacePermissions: &v3.FleetWorkspacePermission{
+				WorkspaceVerbs: []string{"get"},
+			},
+		},
+	}
+	globalRoleCache := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRole](ctrl)
+	globalRoleCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.GlobalRole, error) {
+		if globalRole, ok := globalRoles[name]; ok {
+			return globalRole, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "globalroles"}, name)
+	}).AnyTimes()
+
+	crtbs := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbs.EXPECT().List("c-1", gomock.Any()).Return([]*v3.ClusterRoleTemplateBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "crtb-owner", Namespace: "c-1"}, UserName: "u-bob", ClusterName: "c-1", RoleTemplateName: "cluster-owner"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "crtb-group", Namespace: "c-1"}, GroupPrincipalName: devsGroup, ClusterName: "c-1", RoleTemplateName: "cluster-member"},
+		{
+			ObjectMeta:       metav1.ObjectMeta{Name: "crtb-grb", Namespace: "c-1", Labels: map[string]string{grbOwnerLabel: "grb-user"}},
+			UserName:         "u-alice",
+			ClusterName:      "c-1",
+			RoleTemplateName: "cluster-member",
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "crtb-missing", Namespace: "c-1"}, UserName: "u-carol", ClusterName: "c-1", RoleTemplateName: "missing"},
+	}, nil).AnyTimes()
+
+	prtbs := fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl)
+	prtbs.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prtb-alice", Namespace: "p-1"}, UserName: "u-alice", ProjectName: "c-1:p-1", RoleTemplateName: "project-member"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prtb-other", Namespace: "p-2"}, UserName: "u-alice", ProjectName: "c-2:p-2", RoleTemplateName: "project-member"},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prtb-invalid", Namespace: "p-1"}, UserName: "u-carol", ProjectName: "c-1:p-1", RoleTemplateName: "invalid-selected"},
+	}, nil).AnyTimes()
+
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"cluster-owner":  {ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner"}, Context: "cluster", Rules: []rbacv1.PolicyRule{allRules}},
+		"cluster-member": {ObjectMeta: metav1.ObjectMeta{Name: "cluster-member"}, Context: "cluster", Rules: []rbacv1.PolicyRule{listNodes}, RoleTemplateNames: []string{"view-pods"}},
+		"project-member": {
+			ObjectMeta:             metav1.ObjectMeta{Name: "project-member"},
+			Context:                "project",
+			Rules:                  []rbacv1.PolicyRule{createPods},
+			NamespaceSelectedRules: []v3.NamespaceSelectedRules{{NamespaceSelector: webTeam, Rules: []rbacv1.PolicyRule{getWebCMs}}},
+		},
+		"view-pods": {ObjectMeta: metav1.ObjectMeta{Name: "view-pods"}, Rules: []rbacv1.PolicyRule{getPods}},
+		// patterns require explicit resources
+		"invalid-selected": {
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-selected"},
+			Context:    "project",
+			NamespaceSelectedRules: []v3.NamespaceSelectedRules{{Rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"*"}, ResourceNames: []string{"web-*"}},
+			}}},
+		},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		if roleTemplate, ok := roleTemplates[name]; ok {
+			return roleTemplate, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+	}).AnyTimes()
+
+	userAttributes := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+	userAttributes.EXPECT().Get("u-alice").Return(&v3.UserAttribute{
+		GroupPrincipals: map[string]v3.Principals{
+			"okta": {Items: []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: devsGroup}}}},
+		},
+	}, nil).AnyTimes()
+	userAttributes.EXPECT().Get(gomock.Any()).Return(nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, "")).AnyTimes()
+
+	return &resolver{
+		grbs:           grbs,
+		globalRoles:    globalRoleCache,
+		crtbs:          crtbs,
+		prtbs:          prtbs,
+		roleTemplates:  roleTemplateCache,
+		clusterRoles:   fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl),
+		userAttributes: userAttributes,
+	}
+}
+
+// grants summarizes the permissions as "binding/role:scope verb resource".
+func grants(permissions []Permission) []string {
+	var result []string
+	for _, permission := range permissions {
+		grant := permission.BindingName + "/" + permission.RoleName + ":" + permission.Scope
+		if permission.Namespace != "" {
+			grant += "[" + permission.Namespace + "]"
+		}
+		result = append(result, grant+" "+permission.Rule.Verbs[0]+" "+permission.Rule.Resources[0])
+	}
+	return result
+}
+
+func TestEffectivePermissionsManagementPlane(t *testing.T) {
+	r := newResolver(t)
+
+	got, err := r.effectivePermissions(&EffectivePermissionsInput{UserName: "u-alice"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{devsGroup}, got.GroupPrincipalNames)
+	assert.Equal(t, []string{
+		"grb-group/user:global list nodes",
+		"grb-user/operator:global get pods",
+		"grb-user/operator:namespace[cattle-system] create pods",
+		"grb-user/operator:fleetWorkspace get fleetworkspaces",
+	}, grants(got.Permissions))
+	assert.Empty(t, got.Warnings)
+}
+
+func TestEffectivePermissionsCluster(t *testing.T) {
+	r := newResolver(t)
+
+	got, err := r.effectivePermissions(&EffectivePermissionsInput{UserName: "u-alice", ClusterName: "c-1"})
+	require.NoError(t, err)
+	// the permissions of the CRTB materializing the InheritedClusterRoles are attributed to the GRB
+	assert.Equal(t, []string{
+		"grb-user/cluster-member:cluster list nodes",
+		"grb-user/view-pods:cluster get pods",
+		"crtb-group/cluster-member:cluster list nodes",
+		"crtb-group/view-pods:cluster get pods",
+		"prtb-alice/project-member:project create pods",
+		"prtb-alice/project-member-namespace-selected:projectNamespaces get configmaps",
+	}, grants(got.Permissions))
+	assert.Equal(t, "operator", got.Permissions[1].BoundRoleName)
+	assert.Equal(t, "cluster-member", got.Permissions[3].BoundRoleName)
+	assert.Equal(t, "c-1:p-1", got.Permissions[4].ProjectName)
+	// the NamespaceSelectedRules are granted by a Role in the selected namespaces of the project
+	assert.Equal(t, roleKind, got.Permissions[5].RoleKind)
+	assert.Equal(t, "project-member", got.Permissions[5].BoundRoleName)
+	assert.Equal(t, &webTeam, got.Permissions[5].NamespaceSelector)
+	assert.Equal(t, getWebCMs, got.Permissions[5].Rule)
+	assert.Empty(t, got.Warnings)
+
+	// the invalid NamespaceSelectedRules aren't granted
+	got, err = r.effectivePermissions(&EffectivePermissionsInput{UserName: "u-carol", ClusterName: "c-1"})
+	require.NoError(t, err)
+	assert.Empty(t, got.Permissions)
+	require.Len(t, got.Warnings, 2)
+	assert.Contains(t, got.Warnings[1], "the namespaceSelectedRules of RoleTemplate [invalid-selected]")
+
+	got, err = r.effectivePermissions(&EffectivePermissionsInput{GroupPrincipalName: devsGroup, ClusterName: "c-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"crtb-group/cluster-member:cluster list nodes",
+		"crtb-group/view-pods:cluster get pods",
+	}, grants(got.Permissions))
+}
+
+func TestWhoCan(t *testing.T) {
+	r := newResolver(t)
+
+	got, err := r.whoCan(&WhoCanInput{Verb: "get", Resource: "pods", ClusterName: "c-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []Principal{
+		{Kind: rbacv1.UserKind, Name: "u-admin"},
+		{Kind: rbacv1.UserKind, Name: "u-alice"},
+		{Kind: rbacv1.GroupKind, Name: devsGroup},
+		{Kind: rbacv1.UserKind, Name: "u-bob"},
+	}, got.Principals)
+	assert.Equal(t, "cluster-admin", got.Permissions[0].RoleName)
+	assert.Equal(t, []string{
+		"resolving RoleTemplate [missing] of ClusterRoleTemplateBinding [c-1/crtb-missing]: roletemplates \"missing\" not found",
+		"the namespaceSelectedRules of RoleTemplate [invalid-selected] of ProjectRoleTemplateBinding [p-1/prtb-invalid] aren't granted: " +
+			"invalid rule namespaceSelectedRules[0].rules[0]: rules with resource name patterns can't use wildcard apiGroups or resources",
+	}, got.Warnings)
+
+	got, err = r.whoCan(&WhoCanInput{Verb: "create", Resource: "pods", ClusterName: "c-1", ProjectName: "c-1:p-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"grb-admin/cluster-admin:cluster * *",
+		"crtb-owner/cluster-owner:cluster * *",
+		"prtb-alice/project-member:project create pods",
+	}, grants(got.Permissions))
+
+	// the resource name patterns of NamespaceSelectedRules match the names starting with their prefix
+	got, err = r.whoCan(&WhoCanInput{Verb: "get", Resource: "configmaps", ResourceName: "web-config", ClusterName: "c-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"grb-admin/cluster-admin:cluster * *",
+		"crtb-owner/cluster-owner:cluster * *",
+		"prtb-alice/project-member-namespace-selected:projectNamespaces get configmaps",
+	}, grants(got.Permissions))
+	got, err = r.whoCan(&WhoCanInput{Verb: "get", Resource: "configmaps", ResourceName: "db-config", ClusterName: "c-1"})
+	require.NoError(t, err)
+	assert.Len(t, got.Permissions, 2)
+
+	got, err = r.whoCan(&WhoCanInput{Verb: "create", Resource: "pods", Namespace: "fleet-default"})
+	require.NoError(t, err)
+	assert.Equal(t, []Principal{{Kind: rbacv1.UserKind, Name: "u-admin"}}, got.Principals)
+}
+
+func TestRuleAllows(t *testing.T) {
+	tests := []struct {
+		name         string
+		rule         rbacv1.PolicyRule
+		subresource  string
+		resourceName string
+		want         bool
+	}{
+		{name: "exact", rule: getPods, want: true},
+		{name: "wildcards", rule: allRules, want: true},
+		{name: "other verb", rule: createPods},
+		{name: "other group", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{"apps"}, Resources: []string{"pods"}}},
+		{name: "case sensitive", rule: rbacv1.PolicyRule{Verbs: []string{"GET"}, APIGroups: []string{""}, Resources: []string{"Pods"}}},
+		{name: "subresource of the resource", rule: getPods, subresource: "log"},
+		{
+			name:        "subresource",
+			rule:        rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods/log"}},
+			subresource: "log",
+			want:        true,
+		},
+		{
+			name:        "any resource of the subresource",
+			rule:        rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"*/log"}},
+			subresource: "log",
+			want:        true,
+		},
+		{name: "resource of the subresource", rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods/log"}}},
+		{
+			name:         "resource name",
+			rule:         rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web"}},
+			resourceName: "web",
+			want:         true,
+		},
+		{
+			name:         "other resource name",
+			rule:         rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web"}},
+			resourceName: "db",
+		},
+		{
+			name: "resource names without name",
+			rule: rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &WhoCanInput{Verb: "get", Resource: "pods", Subresource: tt.subresource, ResourceName: tt.resourceName}
+			assert.Equal(t, tt.want, ruleAllows(tt.rule, input))
+		})
+	}
+}
+
+func TestToLowerRules(t *testing.T) {
+	rules := []rbacv1.PolicyRule{{Verbs: []string{"GET"}, APIGroups: []string{"Apps"}, Resources: []string{"Deployments/Scale"}}}
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{Verbs: []string{"get"}, APIGroups: []string{"Apps"}, Resources: []string{"deployments/scale"}},
+	}, toLowerRules(rules))
+	assert.Equal(t, "GET", rules[0].Verbs[0], "the rules of the RoleTemplate must not be modified")
+}
@@ -0,0 +1,81 @@
+package projects
+
+import (
+	"fmt"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/wrangler/v3/pkg/kv"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// parentStore requires the user setting the parent of a project to be allowed to add sub-projects to the parent, i.e.
+// to update it or to create its ProjectRoleTemplateBindings, unless the parent already accepts the project. Once the
+// project is written, the parent is set to accept it, see project.SubProjectsAnnotation.
+type parentStore struct {
+	types.Store
+	projects mgmtcontrollers.ProjectController
+}
+
+func (s *parentStore) Create(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject) (types.APIObject, error) {
+	obj := data.Data()
+	namespace := obj.String("metadata", "namespace")
+	if namespace == "" {
+		namespace = apiOp.Namespace
+	}
+	parentName := obj.String("spec", "parentProjectName")
+	if err := s.checkParent(apiOp, namespace, obj.String("metadata", "name"), parentName); err != nil {
+		return types.APIObject{}, err
+	}
+	result, err := s.Store.Create(apiOp, schema, data)
+	if err != nil {
+		return result, err
+	}
+	return result, s.acceptSubProject(result, parentName)
+}
+
+func (s *parentStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	namespace, name := kv.RSplit(id, "/")
+	if namespace == "" {
+		namespace = apiOp.Namespace
+	}
+	parentName := data.Data().String("spec", "parentProjectName")
+	if err := s.checkParent(apiOp, namespace, name, parentName); err != nil {
+		return types.APIObject{}, err
+	}
+	result, err := s.Store.Update(apiOp, schema, data, id)
+	if err != nil {
+		return result, err
+	}
+	return result, s.acceptSubProject(result, parentName)
+}
+
+func (s *parentStore) checkParent(apiOp *types.APIRequest, namespace, name, parentName string) error {
+	if parentName == "" {
+		return nil
+	}
+	parent, err := s.projects.Cache().Get(namespace, parentName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && name != "" && project.AcceptsSubProject(parent, name) {
+		return nil
+	}
+	if apiOp.AccessControl.CanDo(apiOp, "management.cattle.io/projects", "update", namespace, parentName) == nil ||
+		apiOp.AccessControl.CanDo(apiOp, "management.cattle.io/projectroletemplatebindings", "create", parentName, "") == nil {
+		return nil
+	}
+	// the same error whether the parent exists or not, so that the existence of projects isn't revealed
+	return apierror.NewAPIError(validation.InvalidReference, fmt.Sprintf("project %s not found in cluster %s", parentName, namespace))
+}
+
+func (s *parentStore) acceptSubProject(written types.APIObject, parentName string) error {
+	if parentName == "" {
+		return nil
+	}
+	obj := written.Data()
+	return project.AcceptSubProject(s.projects, obj.String("metadata", "namespace"), parentName, obj.String("metadata", "name"))
+}
@@ -0,0 +1,127 @@
+package projects
+
+import (
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// accessControl grants the verbs on the resources in the namespaces, e.g. "update management.cattle.io/projects c-1".
+type accessControl struct {
+	types.AccessControl
+	grants map[string]bool
+}
+
+func (a *accessControl) CanDo(apiOp *types.APIRequest, resource, verb, namespace, name string) error {
+	if a.grants[verb+" "+resource+" "+namespace] {
+		return nil
+	}
+	return validation.PermissionDenied
+}
+
+type dummyStore struct {
+	types.Store
+}
+
+func (dummyStore) Update(apiOp *types.APIRequest, schema *types.APISchema, data types.APIObject, id string) (types.APIObject, error) {
+	return data, nil
+}
+
+func TestParentStoreUpdate(t *testing.T) {
+	org := &v3.Project{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "p-org",
+			Namespace:   "c-1",
+			Annotations: map[string]string{project.SubProjectsAnnotation: "p-team"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		project    string
+		parentName string
+		grants     map[string]bool
+		wantErr    bool
+		wantAccept bool
+	}{
+		{
+			name:       "no rights on the parent",
+			project:    "p-app",
+			parentName: "p-org",
+			wantErr:    true,
+		},
+		{
+			name:       "no rights on a missing parent",
+			project:    "p-app",
+			parentName: "p-missing",
+			wantErr:    true,
+		},
+		{
+			name:       "parent can be updated",
+			project:    "p-app",
+			parentName: "p-org",
+			grants:     map[string]bool{"update management.cattle.io/projects c-1": true},
+			wantAccept: true,
+		},
+		{
+			name:       "members can be added to the parent",
+			project:    "p-app",
+			parentName: "p-org",
+			grants:     map[string]bool{"create management.cattle.io/projectroletemplatebindings p-org": true},
+			wantAccept: true,
+		},
+		{
+			name:       "parent already accepts the project",
+			project:    "p-team",
+			parentName: "p-org",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			projects := fake.NewMockControllerInterface[*v3.Project, *v3.ProjectList](ctrl)
+			cache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+			projects.EXPECT().Cache().Return(cache).AnyTimes()
+			cache.EXPECT().Get("c-1", gomock.Any()).DoAndReturn(func(namespace, name string) (*v3.Project, error) {
+				if name == org.Name {
+					return org, nil
+				}
+				return nil, apierrors.NewNotFound(v3.Resource("projects"), name)
+			}).AnyTimes()
+			projects.EXPECT().Get("c-1", "p-org", gomock.Any()).Return(org, nil).AnyTimes()
+			var accepted *v3.Project
+			if tt.wantAccept {
+				projects.EXPECT().Update(gomock.Any()).DoAndReturn(func(p *v3.Project) (*v3.Project, error) {
+					accepted = p
+					return p, nil
+				})
+			}
+			store := &parentStore{Store: dummyStore{}, projects: projects}
+			apiOp := &types.APIRequest{AccessControl: &accessControl{grants: tt.grants}}
+			data := types.APIObject{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": tt.project, "namespace": "c-1"},
+				"spec":     map[string]interface{}{"parentProjectName": tt.parentName},
+			}}
+
+			_, err := store.Update(apiOp, nil, data, "c-1/"+tt.project)
+			if tt.wantErr {
+				assert.EqualError(t, err, "InvalidReference 422: project "+tt.parentName+" not found in cluster c-1")
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantAccept {
+				require.NotNil(t, accepted)
+				assert.True(t, project.AcceptsSubProject(accepted, tt.project))
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@
 	"github.com/gorilla/mux"
 	"github.com/rancher/apiserver/pkg/server"
 	"github.com/rancher/apiserver/pkg/types"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/steve/pkg/accesscontrol"
@@ -26,6 +27,7 @@ type projectServer struct {
 	cf             *client.Factory
 	clusterLinks   []string
 	namespaceCache corecontrollers.NamespaceCache
+	projects       mgmtcontrollers.ProjectController
 }
 
 func Projects(ctx context.Context, config *wrangler.Context, server *steveserver.Server) (func(http.Handler) http.Handler, error) {
@@ -41,6 +43,18 @@ func (s *projectServer) Setup(ctx context.Context, config *wrangler.Context, ser
 	s.asl = server.AccessSetLookup
 	s.cf = server.ClientFactory
 	s.namespaceCache = config.Core.Namespace().Cache()
+	s.projects = config.Mgmt.Project()
+
+	server.SchemaFactory.AddTemplate(schema.Template{
+		Group: "management.cattle.io",
+		Kind:  "Project",
+		StoreFactory: func(innerStore types.Store) types.Store {
+			return &parentStore{
+				Store:    innerStore,
+				projects: s.projects,
+			}
+		},
+	})
 
 	server.SchemaFactory.AddTemplate(schema.Template{
 		ID: "management.cattle.io.cluster",
@@ -55,7 +69,10 @@ func (s *projectServer) Setup(ctx context.Context, config *wrangler.Context, ser
 }
 
 func (s *projectServer) newSchemas() *types.APISchemas {
-	store := proxy.NewProxyStore(s.cf, nil, s.asl, s.namespaceCache)
+	store := &parentStore{
+		Store:    proxy.NewProxyStore(s.cf, nil, s.asl, s.namespaceCache),
+		projects: s.projects,
+	}
 	schemas := types.EmptyAPISchemas()
 
 	schemas.MustImportAndCustomize(v3.Project{}, func(schema *types.APISchema) {
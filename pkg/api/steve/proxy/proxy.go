@@ -10,6 +10,7 @@
 
 	gmux "github.com/gorilla/mux"
 	"github.com/rancher/rancher/pkg/api/steve/disallow"
+	"github.com/rancher/rancher/pkg/clusterrouter/ratelimit"
 	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	managementv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
@@ -30,6 +31,7 @@
 type Handler struct {
 	authorizer         authorizer.Authorizer
 	dialerFactory      ClusterDialerFactory
+	limiter            *ratelimit.Limiter
 	requestInfoFactory request.RequestInfoFactory
 }
 
@@ -50,6 +52,7 @@ func RewriteLocalCluster(next http.Handler) http.Handler {
 func NewProxyMiddleware(sar v1.AuthorizationV1Interface,
 	dialerFactory ClusterDialerFactory,
 	clusters v3.ClusterCache,
+	limiter *ratelimit.Limiter,
 	localSupport bool,
 	localCluster http.Handler) (func(http.Handler) http.Handler, error) {
 	cfg := authorizerfactory.DelegatingAuthorizerConfig{
@@ -64,7 +67,7 @@ func NewProxyMiddleware(sar v1.AuthorizationV1Interface,
 		return nil, err
 	}
 
-	proxyHandler := NewProxyHandler(authorizer, dialerFactory, clusters)
+	proxyHandler := NewProxyHandler(authorizer, dialerFactory, clusters, limiter)
 
 	mux := gmux.NewRouter()
 	mux.UseEncodedPath()
@@ -115,10 +118,12 @@ func routeToShellProxy(key, value string, localSupport bool, localCluster http.H
 
 func NewProxyHandler(authorizer authorizer.Authorizer,
 	dialerFactory ClusterDialerFactory,
-	clusters v3.ClusterCache) *Handler {
+	clusters v3.ClusterCache,
+	limiter *ratelimit.Limiter) *Handler {
 	return &Handler{
 		authorizer:         authorizer,
 		dialerFactory:      dialerFactory,
+		limiter:            limiter,
 		requestInfoFactory: request.RequestInfoFactory{APIPrefixes: sets.NewString("apis", "api"), GrouplessAPIPrefixes: sets.NewString("api")},
 	}
 }
@@ -167,6 +172,12 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		rw.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	release, ok := h.limiter.Admit(rw, req, clusterID)
+	if !ok {
+		return
+	}
+	defer release()
+
 	prefix := "/" + gmux.Vars(req)["prefix"]
 	handler, err := h.next(clusterID, prefix)
 	if err != nil {
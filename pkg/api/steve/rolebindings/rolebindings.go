@@ -0,0 +1,66 @@
+// Package rolebindings customizes the GlobalRoleBinding, ClusterRoleTemplateBinding and ProjectRoleTemplateBinding
+// APIs: their creator is the authenticated user creating them, as with the norman API, so that it can be trusted as
+// the grantor of time-bound bindings.
+package rolebindings
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/features"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const creatorIDAnnotation = "field.cattle.io/creatorId"
+
+func Register(server *steve.Server) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	for _, kind := range []string{"GlobalRoleBinding", "ClusterRoleTemplateBinding", "ProjectRoleTemplateBinding"} {
+		server.SchemaFactory.AddTemplate(schema2.Template{
+			Group: "management.cattle.io",
+			Kind:  kind,
+			StoreFactory: func(innerStore types.Store) types.Store {
+				return &store{
+					Store: innerStore,
+				}
+			},
+		})
+	}
+}
+
+// store sets the creator of the bindings to the user creating them and keeps it when they are updated.
+type store struct {
+	types.Store
+}
+
+func (s *store) Create(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (types.APIObject, error) {
+	user, ok := request.UserFrom(apiOp.Context())
+	if !ok {
+		return types.APIObject{}, validation.Unauthorized
+	}
+	setCreator(&obj, user.GetName())
+	return s.Store.Create(apiOp, schema, obj)
+}
+
+func (s *store) Update(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject, id string) (types.APIObject, error) {
+	existing, err := s.Store.ByID(apiOp, schema, id)
+	if err != nil {
+		return types.APIObject{}, err
+	}
+	setCreator(&obj, existing.Data().String("metadata", "annotations", creatorIDAnnotation))
+	return s.Store.Update(apiOp, schema, obj, id)
+}
+
+func setCreator(obj *types.APIObject, creator string) {
+	object := obj.Data()
+	if creator == "" {
+		annotations := object.Map("metadata", "annotations")
+		delete(annotations, creatorIDAnnotation)
+	} else {
+		object.SetNested(creator, "metadata", "annotations", creatorIDAnnotation)
+	}
+	obj.Object = object
+}
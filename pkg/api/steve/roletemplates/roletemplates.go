@@ -0,0 +1,60 @@
+// Package roletemplates customizes the RoleTemplate API: the NamespaceSelectedRules of the RoleTemplates are validated
+// as in the norman API, the controllers not granting the invalid ones.
+package roletemplates
+
+import (
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/features"
+	"github.com/rancher/rancher/pkg/rbac"
+	schema2 "github.com/rancher/steve/pkg/schema"
+	steve "github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func Register(server *steve.Server) {
+	if !features.MCM.Enabled() {
+		return
+	}
+	server.SchemaFactory.AddTemplate(schema2.Template{
+		Group: "management.cattle.io",
+		Kind:  "RoleTemplate",
+		StoreFactory: func(innerStore types.Store) types.Store {
+			return &store{
+				Store: innerStore,
+			}
+		},
+	})
+}
+
+// store rejects the RoleTemplates with invalid NamespaceSelectedRules.
+type store struct {
+	types.Store
+}
+
+func (s *store) Create(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject) (types.APIObject, error) {
+	if err := validate(obj); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Create(apiOp, schema, obj)
+}
+
+func (s *store) Update(apiOp *types.APIRequest, schema *types.APISchema, obj types.APIObject, id string) (types.APIObject, error) {
+	if err := validate(obj); err != nil {
+		return types.APIObject{}, err
+	}
+	return s.Store.Update(apiOp, schema, obj, id)
+}
+
+func validate(obj types.APIObject) error {
+	rt := &v3.RoleTemplate{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Data(), rt); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if err := rbac.ValidateNamespaceSelectedRules(rt); err != nil {
+		return apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package roletemplates
+
+import (
+	"testing"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "without namespace selected rules",
+			data: map[string]interface{}{"context": "cluster"},
+		},
+		{
+			name: "valid namespace selected rules",
+			data: map[string]interface{}{
+				"context": "project",
+				"namespaceSelectedRules": []interface{}{
+					map[string]interface{}{
+						"namespaceSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "web"}},
+						"rules": []interface{}{
+							map[string]interface{}{
+								"apiGroups":     []interface{}{""},
+								"resources":     []interface{}{"configmaps"},
+								"resourceNames": []interface{}{"web-*"},
+								"verbs":         []interface{}{"get"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "namespace selected rules of a cluster role template",
+			data: map[string]interface{}{
+				"context": "cluster",
+				"namespaceSelectedRules": []interface{}{
+					map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{"apiGroups": []interface{}{""}, "resources": []interface{}{"pods"}, "verbs": []interface{}{"get"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pattern with wildcard resources",
+			data: map[string]interface{}{
+				"context": "project",
+				"namespaceSelectedRules": []interface{}{
+					map[string]interface{}{
+						"rules": []interface{}{
+							map[string]interface{}{
+								"apiGroups":     []interface{}{""},
+								"resources":     []interface{}{"*"},
+								"resourceNames": []interface{}{"web-*"},
+								"verbs":         []interface{}{"get"},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(types.APIObject{Object: tt.data})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,11 +3,17 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/api/steve/accessrequests"
+	"github.com/rancher/rancher/pkg/api/steve/bindingreviews"
 	"github.com/rancher/rancher/pkg/api/steve/catalog"
 	"github.com/rancher/rancher/pkg/api/steve/clusters"
 	"github.com/rancher/rancher/pkg/api/steve/disallow"
 	"github.com/rancher/rancher/pkg/api/steve/machine"
+	"github.com/rancher/rancher/pkg/api/steve/membershippolicies"
 	"github.com/rancher/rancher/pkg/api/steve/navlinks"
+	"github.com/rancher/rancher/pkg/api/steve/permissions"
+	"github.com/rancher/rancher/pkg/api/steve/rolebindings"
+	"github.com/rancher/rancher/pkg/api/steve/roletemplates"
 	"github.com/rancher/rancher/pkg/api/steve/settings"
 	"github.com/rancher/rancher/pkg/api/steve/userpreferences"
 	"github.com/rancher/rancher/pkg/wrangler"
@@ -20,6 +26,12 @@ func Setup(ctx context.Context, server *steve.Server, config *wrangler.Context)
 		return err
 	}
 	machine.Register(server, config)
+	accessrequests.Register(server, config)
+	permissions.Register(server, config)
+	bindingreviews.Register(server, config)
+	membershippolicies.Register(server, config)
+	rolebindings.Register(server)
+	roletemplates.Register(server)
 	navlinks.Register(ctx, server)
 	settings.Register(server)
 	disallow.Register(server)
@@ -108,6 +108,53 @@ type RepoSpec struct {
 	// Defaults to false, which keeps the SameOrigin check enabled. Setting this to true is not recommended
 	// in production environments due to the security implications.
 	DisableSameOriginCheck bool `json:"disableSameOriginCheck,omitempty"`
+
+	// Mirror when specified makes Rancher push the charts of this Helm repository
+	// into the given OCI registry so that they can be consumed by air-gapped clusters.
+	Mirror *RepoMirrorSpec `json:"mirror,omitempty"`
+
+	// ChartFilter restricts the charts of the Helm repository that are listed in its index and that can be installed.
+	ChartFilter *RepoChartFilter `json:"chartFilter,omitempty"`
+}
+
+// RepoChartFilter contains the names of the charts of a Helm repository which are allowed or denied.
+// Names can be shell patterns, ie. "rancher-*".
+type RepoChartFilter struct {
+	// Allow is the list of the charts that are allowed. If unspecified, every chart not denied is allowed.
+	Allow []string `json:"allow,omitempty"`
+
+	// Deny is the list of the charts that are denied. Deny takes precedence over Allow.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// RepoMirrorSpec contains details about the OCI registry the charts of a Helm repository are mirrored into.
+type RepoMirrorSpec struct {
+	// URL is the OCI URL of the registry and namespace the charts are pushed to, ie. oci://registry.example.com/charts.
+	// Every chart is pushed into its own repository under this namespace, tagged with the chart version.
+	URL string `json:"url"`
+
+	// VersionRange is a semver constraint, ie. ">= 1.0.0 < 2.0.0", selecting the chart versions to mirror.
+	// If unspecified, every chart version is mirrored.
+	VersionRange string `json:"versionRange,omitempty"`
+
+	// Charts is the list of chart names to mirror. If unspecified, every chart of the Helm repository is mirrored.
+	Charts []string `json:"charts,omitempty"`
+
+	// InsecurePlainHTTP allows insecure connections to the mirror registry without enforcing TLS checks.
+	InsecurePlainHTTP bool `json:"insecurePlainHttp,omitempty"`
+
+	// InsecureSkipTLSverify will disable the TLS verification when pushing to the mirror registry.
+	InsecureSkipTLSverify bool `json:"insecureSkipTLSVerify,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle which will be used to validate the mirror registry's certificate.
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// ClientSecret is the "kubernetes.io/basic-auth" secret used to authenticate with the mirror registry.
+	ClientSecret *SecretReference `json:"clientSecret,omitempty"`
+
+	// GenerateClusterRepo when true makes Rancher create a companion ClusterRepo, named after this one
+	// with a "-mirror" suffix, that points at the mirror registry.
+	GenerateClusterRepo bool `json:"generateClusterRepo,omitempty"`
 }
 
 type RepoCondition string
@@ -116,6 +163,7 @@ type RepoSpec struct {
 	RepoDownloaded         RepoCondition = "Downloaded"
 	FollowerRepoDownloaded RepoCondition = "FollowerDownloaded"
 	OCIDownloaded          RepoCondition = "OCIDownloaded"
+	RepoMirrored           RepoCondition = "Mirrored"
 )
 
 // RepoStatus contains details of the Helm repository that is currently being used in the cluster.
@@ -156,6 +204,15 @@ type RepoStatus struct {
 
 	// If the handler should be skipped or not
 	ShouldNotSkip bool `json:"shouldNotSkip,omitempty"`
+
+	// MirrorObservedGeneration is the generation of the resource that was last mirrored.
+	MirrorObservedGeneration int64 `json:"mirrorObservedGeneration,omitempty"`
+
+	// MirrorIndexConfigMapResourceVersion is the resourceversion of the Helm repository index configmap that was last mirrored.
+	MirrorIndexConfigMapResourceVersion string `json:"mirrorIndexConfigMapResourceVersion,omitempty"`
+
+	// MirroredCharts is the number of chart versions present in the mirror registry after the last mirroring.
+	MirroredCharts int `json:"mirroredCharts,omitempty"`
 }
 
 // +genclient
@@ -185,4 +242,5 @@ type OperationStatus struct {
 	Conditions             []genericcondition.GenericCondition `json:"conditions,omitempty"`
 	AutomaticCPTolerations bool                                `json:"automaticCPTolerations,omitempty"`
 	Tolerations            []corev1.Toleration                 `json:"tolerations,omitempty"`
+	LogSecretName          string                              `json:"logSecretName,omitempty"`
 }
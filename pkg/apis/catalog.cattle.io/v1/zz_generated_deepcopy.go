@@ -427,6 +427,63 @@ func (in *ReleaseStatus) DeepCopy() *ReleaseStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepoChartFilter) DeepCopyInto(out *RepoChartFilter) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoChartFilter.
+func (in *RepoChartFilter) DeepCopy() *RepoChartFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(RepoChartFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepoMirrorSpec) DeepCopyInto(out *RepoMirrorSpec) {
+	*out = *in
+	if in.Charts != nil {
+		in, out := &in.Charts, &out.Charts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientSecret != nil {
+		in, out := &in.ClientSecret, &out.ClientSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepoMirrorSpec.
+func (in *RepoMirrorSpec) DeepCopy() *RepoMirrorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepoMirrorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepoSpec) DeepCopyInto(out *RepoSpec) {
 	*out = *in
@@ -454,6 +511,16 @@ func (in *RepoSpec) DeepCopyInto(out *RepoSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Mirror != nil {
+		in, out := &in.Mirror, &out.Mirror
+		*out = new(RepoMirrorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChartFilter != nil {
+		in, out := &in.ChartFilter, &out.ChartFilter
+		*out = new(RepoChartFilter)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
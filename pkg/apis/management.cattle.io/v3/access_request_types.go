@@ -0,0 +1,82 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AccessRequestPending is the state of an access request waiting for a decision.
+	AccessRequestPending = "Pending"
+	// AccessRequestApproved is the state of an approved access request. The binding granting the requested access
+	// is created once approved.
+	AccessRequestApproved = "Approved"
+	// AccessRequestDenied is the state of a denied access request.
+	AccessRequestDenied = "Denied"
+	// AccessRequestFailed is the state of an access request which can't be granted, e.g. because the requested
+	// RoleTemplate doesn't exist.
+	AccessRequestFailed = "Failed"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AccessRequest is the request of a user for a RoleTemplate on a cluster or a project. Requests for a cluster are in
+// the namespace of the cluster and requests for a project in the namespace of the project, like the
+// ClusterRoleTemplateBindings and ProjectRoleTemplateBindings they materialize into once approved.
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AccessRequestSpec   `json:"spec"`
+	Status AccessRequestStatus `json:"status"`
+}
+
+// AccessRequestSpec is the access requested.
+type AccessRequestSpec struct {
+	// UserName is the name of the user requesting access. It is set to the user creating the request.
+	UserName string `json:"userName"`
+	// RoleTemplateName is the name of the requested RoleTemplate.
+	RoleTemplateName string `json:"roleTemplateName"`
+	// ClusterName is the name of the cluster access is requested to. Must match the namespace for cluster requests.
+	ClusterName string `json:"clusterName"`
+	// ProjectName is the name of the project access is requested to, in the "cluster:project" format of
+	// ProjectRoleTemplateBindings. Empty for cluster requests.
+	// +optional
+	ProjectName string `json:"projectName,omitempty"`
+	// Justification explains to the approvers why access is needed.
+	// +optional
+	Justification string `json:"justification,omitempty"`
+	// Duration is how long the access is granted for once approved, e.g. "8h". Access is granted until revoked when
+	// empty.
+	// +optional
+	Duration string `json:"duration,omitempty"`
+}
+
+// AccessRequestStatus is the decision on an access request.
+type AccessRequestStatus struct {
+	// State is Pending, Approved, Denied or Failed.
+	// +optional
+	State string `json:"state,omitempty"`
+	// DecidedBy is the name of the user who approved or denied the request.
+	// +optional
+	DecidedBy string `json:"decidedBy,omitempty"`
+	// DecidedAt is when the request was approved or denied.
+	// +optional
+	DecidedAt *metav1.Time `json:"decidedAt,omitempty"`
+	// Reason is the comment of the approver on the decision.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// BindingName is the name of the ClusterRoleTemplateBinding or ProjectRoleTemplateBinding granting the access,
+	// in the namespace of the request.
+	// +optional
+	BindingName string `json:"bindingName,omitempty"`
+	// Message describes why the request failed, if it did.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// IsProjectRequest returns whether access is requested to a project rather than to a cluster.
+func (a *AccessRequest) IsProjectRequest() bool {
+	return a.Spec.ProjectName != ""
+}
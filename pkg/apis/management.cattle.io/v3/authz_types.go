@@ -17,6 +17,7 @@
 	ProjectConditionDefaultNamespacesAssigned condition.Cond = "DefaultNamespacesAssigned"
 	ProjectConditionInitialRolesPopulated     condition.Cond = "InitialRolesPopulated"
 	ProjectConditionSystemNamespacesAssigned  condition.Cond = "SystemNamespacesAssigned"
+	ProjectConditionParentProjectValidated    condition.Cond = "ParentProjectValidated"
 )
 
 // +genclient
@@ -111,8 +112,37 @@ type ProjectSpec struct {
 	// See https://kubernetes.io/docs/concepts/policy/limit-range/ for more details.
 	// +optional
 	ContainerDefaultResourceLimit *ContainerResourceLimit `json:"containerDefaultResourceLimit,omitempty"`
+
+	// LimitRange is a specification of the constraints of the LimitRange every namespace of the project receives, such as
+	// the pod and persistent volume claim min/max, ephemeral storage defaults and max limit/request ratios. The defaults of
+	// ContainerDefaultResourceLimit take priority over the container defaults of LimitRange.
+	// See https://kubernetes.io/docs/concepts/policy/limit-range/ for more details.
+	// +optional
+	LimitRange *ProjectLimitRange `json:"limitRange,omitempty"`
+
+	// ParentProjectName is the name of the parent project of a sub-project, in the same cluster. The members of a project
+	// are members of all its sub-projects, and the ResourceQuota limit of a sub-project is carved out of the limit of its parent.
+	// The parent takes effect once validated, as reported by the ParentProjectValidated condition, which requires the parent
+	// to accept the sub-project: it does when the parent is set through the Rancher API by a user who can update the parent
+	// or create its ProjectRoleTemplateBindings.
+	// +optional
+	ParentProjectName string `json:"parentProjectName,omitempty"`
+
+	// NetworkIsolation is the level of the project tree the network isolation of the project applies at, when project
+	// network isolation is enabled in the cluster. With Project, the default, the namespaces of the project are isolated
+	// from the namespaces of every other project, including its sub-projects. With Tree, the namespaces of the project and
+	// of all its sub-projects are isolated together from the other projects.
+	// +optional
+	// +kubebuilder:validation:Enum=Project;Tree
+	NetworkIsolation string `json:"networkIsolation,omitempty" norman:"type=enum,options=Project|Tree"`
 }
 
+// Levels of the project tree network isolation applies at.
+const (
+	ProjectNetworkIsolationProject = "Project"
+	ProjectNetworkIsolationTree    = "Tree"
+)
+
 func (p *ProjectSpec) ObjClusterName() string {
 	return p.ClusterName
 }
@@ -229,6 +259,16 @@ type GlobalRoleBinding struct {
 	// GlobalRoleName is the name of the Global Role that the subject will be bound to. Immutable.
 	// +kubebuilder:validation:Required
 	GlobalRoleName string `json:"globalRoleName" norman:"required,noupdate,type=reference[globalRole]"`
+
+	// ExpiresAt is the time at which the binding expires and is removed together with the permissions it grants
+	// to the subject. Takes precedence over TTL.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// TTL is the duration after the creation of the binding after which it expires, e.g. "8h".
+	// Ignored if ExpiresAt is set. A TTL which isn't a valid duration expires the binding right away.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
 }
 
 // +genclient
@@ -308,6 +348,28 @@ type RoleTemplate struct {
 	// Default is false.
 	// +optional
 	Administrative bool `json:"administrative,omitempty"`
+
+	// NamespaceSelectedRules hold PolicyRules which only apply in the namespaces of the project matching a label selector.
+	// NamespaceSelectedRules are only evaluated if the context of the RoleTemplate is set to project.
+	// +optional
+	NamespaceSelectedRules []NamespaceSelectedRules `json:"namespaceSelectedRules,omitempty"`
+}
+
+// NamespaceSelectedRules hold the PolicyRules of a project RoleTemplate which only apply in the namespaces of the
+// project whose labels match NamespaceSelector.
+type NamespaceSelectedRules struct {
+	// NamespaceSelector selects the namespaces of the project the Rules apply in. An empty selector selects all the
+	// namespaces of the project.
+	// +optional
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Rules hold the PolicyRules granted in the selected namespaces.
+	// ResourceNames may hold prefix patterns ending with a single "*", like "web-*", which grant access to the existing
+	// objects whose names start with the prefix. Rules with prefix patterns must list their APIGroups and Resources
+	// explicitly, without wildcards. The patterns are expanded every 5 minutes: an object created since the last
+	// expansion is only granted once the next one runs.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
 }
 
 // +genclient
@@ -348,6 +410,16 @@ type ProjectRoleTemplateBinding struct {
 	// Deprecated.
 	// +optional
 	ServiceAccount string `json:"serviceAccount,omitempty" norman:"nocreate,noupdate"`
+
+	// ExpiresAt is the time at which the binding expires and is removed together with the permissions it grants
+	// to the subject in the project. Takes precedence over TTL.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// TTL is the duration after the creation of the binding after which it expires, e.g. "8h".
+	// Ignored if ExpiresAt is set. A TTL which isn't a valid duration expires the binding right away.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
 }
 
 func (p *ProjectRoleTemplateBinding) ObjClusterName() string {
@@ -394,6 +466,16 @@ type ClusterRoleTemplateBinding struct {
 	// RoleTemplateName is the name of the role template that defines permissions to perform actions on resources in the cluster. Immutable.
 	// +kubebuilder:validation:Required
 	RoleTemplateName string `json:"roleTemplateName" norman:"required,noupdate,type=reference[roleTemplate]"`
+
+	// ExpiresAt is the time at which the binding expires and is removed together with the permissions it grants
+	// to the subject in the cluster. Takes precedence over TTL.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// TTL is the duration after the creation of the binding after which it expires, e.g. "8h".
+	// Ignored if ExpiresAt is set. A TTL which isn't a valid duration expires the binding right away.
+	// +optional
+	TTL string `json:"ttl,omitempty"`
 }
 
 func (c *ClusterRoleTemplateBinding) ObjClusterName() string {
@@ -0,0 +1,78 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BindingReviewOpen is the state of a review waiting for the binding to be confirmed.
+	BindingReviewOpen = "Open"
+	// BindingReviewConfirmed is the state of a review whose binding was confirmed before the deadline.
+	BindingReviewConfirmed = "Confirmed"
+	// BindingReviewRemoved is the state of a review whose binding was removed as it wasn't confirmed before the
+	// deadline.
+	BindingReviewRemoved = "Removed"
+	// BindingReviewResolved is the state of a review whose binding was no longer flagged at the deadline, e.g.
+	// because its user logged in again, or which no longer exists.
+	BindingReviewResolved = "Resolved"
+	// BindingReviewInvalid is the state of a review which isn't acted upon, as it wasn't opened by the access review
+	// or isn't in the namespace of its binding.
+	BindingReviewInvalid = "Invalid"
+
+	// BindingReviewOpenedByLabel is set on the reviews opened by the access review. Reviews without it are invalid.
+	BindingReviewOpenedByLabel = "management.cattle.io/binding-review-opened-by"
+	// BindingReviewOpenedByAccessReview is the value of BindingReviewOpenedByLabel on the reviews opened by the access
+	// review.
+	BindingReviewOpenedByAccessReview = "access-review"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BindingReview is opened by the access review for a role binding flagged as stale, e.g. because its user is disabled
+// or hasn't logged in recently. The binding must be confirmed before the deadline or it is removed. Reviews of
+// ClusterRoleTemplateBindings and ProjectRoleTemplateBindings are in the namespace of the binding, reviews of
+// GlobalRoleBindings in the cattle-global-data namespace. The spec is immutable.
+type BindingReview struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BindingReviewSpec   `json:"spec"`
+	Status BindingReviewStatus `json:"status"`
+}
+
+// BindingReviewSpec is the binding under review.
+type BindingReviewSpec struct {
+	// BindingKind is GlobalRoleBinding, ClusterRoleTemplateBinding or ProjectRoleTemplateBinding.
+	BindingKind string `json:"bindingKind"`
+	// BindingNamespace is the namespace of the binding, empty for GlobalRoleBindings.
+	// +optional
+	BindingNamespace string `json:"bindingNamespace,omitempty"`
+	// BindingName is the name of the binding.
+	BindingName string `json:"bindingName"`
+	// UserName is the name of the user the binding grants access to.
+	UserName string `json:"userName"`
+	// RoleName is the name of the GlobalRole or RoleTemplate granted by the binding.
+	RoleName string `json:"roleName"`
+	// Findings are the reasons the binding was flagged.
+	Findings []string `json:"findings,omitempty"`
+	// Deadline is the time by which the binding must be confirmed.
+	Deadline metav1.Time `json:"deadline"`
+}
+
+// BindingReviewStatus is the outcome of a review.
+type BindingReviewStatus struct {
+	// State is Open, Confirmed, Removed, Resolved or Invalid.
+	// +optional
+	State string `json:"state,omitempty"`
+	// ConfirmedBy is the name of the user who confirmed the binding.
+	// +optional
+	ConfirmedBy string `json:"confirmedBy,omitempty"`
+	// ConfirmedAt is when the binding was confirmed.
+	// +optional
+	ConfirmedAt *metav1.Time `json:"confirmedAt,omitempty"`
+	// Message describes how the review was closed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -35,6 +35,10 @@ func init() {
 	ClusterActionRotateEncryptionKey   = "rotateEncryptionKey"
 	ClusterActionSaveAsTemplate        = "saveAsTemplate"
 
+	// ClusterHealthCheckConditionPrefix prefixes the types of the conditions reporting the health of the components
+	// of a cluster, as checked by the health checks of the cluster, ie. "healthcheck.cattle.io/etcd".
+	ClusterHealthCheckConditionPrefix = "healthcheck.cattle.io/"
+
 	// ClusterConditionReady Cluster ready to serve API (healthy when true, unhealthy when false)
 	ClusterConditionReady          condition.Cond = "Ready"
 	ClusterConditionPending        condition.Cond = "Pending"
@@ -70,6 +74,9 @@ func init() {
 	ClusterConditionHarvesterCloudProviderConfigMigrated condition.Cond = "HarvesterCloudProviderConfigMigrated"
 	ClusterConditionACISecretsMigrated                   condition.Cond = "ACISecretsMigrated"
 	ClusterConditionRKESecretsMigrated                   condition.Cond = "RKESecretsMigrated"
+	// ClusterConditionNoUpstreamDrift is false when the configuration of a hosted cluster differs from its upstream
+	// configuration, with the differing fields in its message.
+	ClusterConditionNoUpstreamDrift condition.Cond = "NoUpstreamDrift"
 
 	ClusterDriverImported = "imported"
 	ClusterDriverLocal    = "local"
@@ -147,6 +154,21 @@ type ClusterSpec struct {
 	ClusterTemplateAnswers              Answer                      `json:"answers,omitempty"`
 	ClusterTemplateQuestions            []Question                  `json:"questions,omitempty" norman:"nocreate,noupdate"`
 	FleetWorkspaceName                  string                      `json:"fleetWorkspaceName,omitempty"`
+	EgressProxy                         *EgressProxyConfig          `json:"egressProxy,omitempty"`
+}
+
+// EgressProxyConfig is the HTTP CONNECT or SOCKS5 proxy Rancher uses to reach a cluster and its
+// provider, instead of the proxy environment variables of the Rancher server.
+type EgressProxyConfig struct {
+	// URL of the proxy, ie. "http://proxy:3128", "https://proxy:3129" or "socks5://proxy:1080".
+	URL string `json:"url"`
+	// NoProxy is the comma separated list of hosts, domains and CIDRs reached without the proxy,
+	// with the semantics of the NO_PROXY environment variable.
+	NoProxy string `json:"noProxy,omitempty"`
+	// CredentialSecret is the "namespace:name" of the secret holding the "username" and "password"
+	// used to authenticate to the proxy. The secret must be a cloud credential the user has access to,
+	// or be in the namespace of the cluster.
+	CredentialSecret string `json:"credentialSecret,omitempty"`
 }
 
 type ImportedConfig struct {
@@ -35,8 +35,32 @@ type ManagedChartSpec struct {
 
 	RolloutStrategy *fleet.RolloutStrategy `json:"rolloutStrategy,omitempty"`
 	Targets         []fleet.BundleTarget   `json:"targets,omitempty"`
+
+	// ClusterValues are Helm values, keyed by the name of the Fleet cluster, overriding Values
+	// and the values of the target selecting the cluster. They require Targets: the values of
+	// clusters not selected by Targets are ignored, which the ClusterValues condition reports.
+	ClusterValues map[string]*fleet.GenericMap `json:"clusterValues,omitempty"`
 }
 
 type ManagedChartStatus struct {
 	fleet.BundleStatus
+
+	// Clusters is the state of the chart release in every cluster targeted by the ManagedChart.
+	Clusters []ManagedChartClusterStatus `json:"clusters,omitempty"`
+}
+
+// ManagedChartClusterStatus is the state of the chart release of a ManagedChart in a cluster.
+type ManagedChartClusterStatus struct {
+	// ClusterName is the name of the Fleet cluster.
+	ClusterName string `json:"clusterName,omitempty"`
+	// ClusterNamespace is the namespace of the Fleet cluster.
+	ClusterNamespace string `json:"clusterNamespace,omitempty"`
+	// Release is the namespace and name of the Helm release in the cluster.
+	Release string `json:"release,omitempty"`
+	// State is the Fleet state of the release, ie. Ready, NotReady, Modified, ErrApplied.
+	State string `json:"state,omitempty"`
+	// Ready is true once all the resources of the release are ready.
+	Ready bool `json:"ready,omitempty"`
+	// Message describes why the release is not ready, if any.
+	Message string `json:"message,omitempty"`
 }
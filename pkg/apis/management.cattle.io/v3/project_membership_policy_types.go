@@ -0,0 +1,83 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ProjectMembershipPolicy grants membership to the projects of a cluster selected by label, to the group principals
+// and users matched by its rules. The ProjectRoleTemplateBindings granting the membership are created and removed as
+// groups, users and projects change, provided the author of the policy could create them. Policies are in the
+// namespace of their cluster.
+type ProjectMembershipPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProjectMembershipPolicySpec   `json:"spec"`
+	Status ProjectMembershipPolicyStatus `json:"status"`
+}
+
+// ProjectMembershipPolicySpec is the membership granted by a policy.
+type ProjectMembershipPolicySpec struct {
+	// ClusterName is the name of the cluster whose projects the policy grants membership to. Must match the namespace.
+	ClusterName string `json:"clusterName"`
+	// ProjectSelector selects the projects of the cluster by their labels. An empty selector selects all the projects
+	// of the cluster.
+	// +optional
+	ProjectSelector metav1.LabelSelector `json:"projectSelector,omitempty"`
+	// Rules map the principals granted membership to the RoleTemplates they're granted.
+	Rules []ProjectMembershipRule `json:"rules"`
+}
+
+// ProjectMembershipRule grants RoleTemplates on the selected projects to the group principals or to the users it
+// matches. Exactly one of GroupPrincipalPattern and UserAttribute must be set.
+type ProjectMembershipRule struct {
+	// GroupPrincipalPattern matches the names of the group principals granted the RoleTemplates, like
+	// "okta_group://team-*", with the syntax of path.Match. The groups matched are the ones Rancher knows of, from the
+	// UserAttributes of the users who logged in.
+	// +optional
+	GroupPrincipalPattern string `json:"groupPrincipalPattern,omitempty"`
+	// UserAttribute matches the users granted the RoleTemplates by the attributes recorded for them by an auth
+	// provider.
+	// +optional
+	UserAttribute *UserAttributeMatch `json:"userAttribute,omitempty"`
+	// RoleTemplateNames are the names of the project RoleTemplates granted.
+	RoleTemplateNames []string `json:"roleTemplateNames"`
+}
+
+// UserAttributeMatch matches the users with an attribute of UserAttribute.ExtraByProvider matching a pattern.
+type UserAttributeMatch struct {
+	// Provider is the name of the auth provider which recorded the attribute, e.g. "openldap".
+	Provider string `json:"provider"`
+	// Key is the name of the attribute, e.g. "principalid".
+	Key string `json:"key"`
+	// Pattern matches the values of the attribute with the syntax of path.Match. Users with any value matching are
+	// matched.
+	Pattern string `json:"pattern"`
+}
+
+// ProjectMembershipPolicyStatus is the membership granted by a policy.
+type ProjectMembershipPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the status reflects.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Bindings is the number of ProjectRoleTemplateBindings granting the membership of the policy.
+	// +optional
+	Bindings int `json:"bindings,omitempty"`
+	// Author is the user who last created or updated the policy through the Rancher API, recorded by the API. The
+	// policy only grants the RoleTemplates its author could grant.
+	// +optional
+	Author string `json:"author,omitempty"`
+	// AuthorGeneration is the generation of the policy Author was recorded for. A policy changed since without the
+	// Rancher API has no author, and grants nothing.
+	// +optional
+	AuthorGeneration int64 `json:"authorGeneration,omitempty"`
+	// Message describes why the policy can't be applied, if it can't, or which RoleTemplates its author can't grant.
+	// The bindings of a policy which can't be applied are left as they are, the bindings its author can't grant are
+	// removed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -73,6 +73,13 @@ type ResourceQuotaLimit struct {
 	// LimitsMemory is the memory limits across all pods in a non-terminal state.
 	// +optional
 	LimitsMemory string `json:"limitsMemory,omitempty"`
+
+	// Extended holds quota values for resources without a field of their own, keyed by their Kubernetes resource
+	// name, such as extended resources ("requests.nvidia.com/gpu"), storage class storage
+	// ("gold.storageclass.storage.k8s.io/requests.storage"), ephemeral storage ("limits.ephemeral-storage") and object
+	// counts ("count/deployments.apps").
+	// +optional
+	Extended map[string]string `json:"extended,omitempty"`
 }
 
 // ContainerResourceLimit holds quotas limits for individual containers.
@@ -95,3 +102,39 @@ type ContainerResourceLimit struct {
 	// +optional
 	LimitsMemory string `json:"limitsMemory,omitempty"`
 }
+
+// ProjectLimitRange holds the constraints of the LimitRange applied to all the namespaces of a project.
+type ProjectLimitRange struct {
+	// Limits are the constraints by kind of object, at most one per kind.
+	// +optional
+	Limits []ProjectLimitRangeItem `json:"limits,omitempty"`
+}
+
+// ProjectLimitRangeItem holds the constraints of a LimitRange on a kind of object. The values are keyed by Kubernetes
+// resource name, such as "cpu", "memory", "ephemeral-storage" or "storage".
+type ProjectLimitRangeItem struct {
+	// Type is the kind of object the constraints apply to.
+	// +kubebuilder:validation:Enum=Container;Pod;PersistentVolumeClaim
+	Type string `json:"type" norman:"required,type=enum,options=Container|Pod|PersistentVolumeClaim"`
+
+	// Max is the maximum usage of resources.
+	// +optional
+	Max map[string]string `json:"max,omitempty"`
+
+	// Min is the minimum usage of resources.
+	// +optional
+	Min map[string]string `json:"min,omitempty"`
+
+	// Default is the default resource limits of containers. Only valid for the Container type.
+	// +optional
+	Default map[string]string `json:"default,omitempty"`
+
+	// DefaultRequest is the default resource requests of containers. Only valid for the Container type.
+	// +optional
+	DefaultRequest map[string]string `json:"defaultRequest,omitempty"`
+
+	// MaxLimitRequestRatio is the maximum ratio of the limit to the request of resources. Only valid for the Container
+	// and Pod types.
+	// +optional
+	MaxLimitRequestRatio map[string]string `json:"maxLimitRequestRatio,omitempty"`
+}
@@ -0,0 +1,66 @@
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SecretReplicationTargetSynced is the state of a target namespace holding an up-to-date copy of the source secret.
+	SecretReplicationTargetSynced = "Synced"
+	// SecretReplicationTargetError is the state of a target namespace the source secret could not be copied to.
+	SecretReplicationTargetError = "Error"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SecretReplication keeps copies of a secret of the local cluster in namespaces of downstream clusters. The copies
+// are updated when the source secret changes and removed when their namespace stops being selected or the
+// SecretReplication is deleted.
+type SecretReplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretReplicationSpec   `json:"spec"`
+	Status SecretReplicationStatus `json:"status"`
+}
+
+// SecretReplicationSpec selects the secret to replicate and the namespaces it is copied to.
+type SecretReplicationSpec struct {
+	// SourceSecret is the name of the replicated secret, in the namespace of the SecretReplication.
+	SourceSecret string `json:"sourceSecret"`
+	// TargetName is the name of the copies, the name of the source secret when empty.
+	// +optional
+	TargetName string `json:"targetName,omitempty"`
+	// ClusterSelector selects the clusters the secret is copied to by the labels of their management cluster. It must
+	// not be empty: replicating a secret to every cluster has to be asked for with a label all the clusters have.
+	ClusterSelector metav1.LabelSelector `json:"clusterSelector"`
+	// ProjectSelector selects the namespaces of the projects matching it by label. Namespaces are selected when
+	// they match both ProjectSelector and NamespaceSelector, at least one of which is required.
+	// +optional
+	ProjectSelector *metav1.LabelSelector `json:"projectSelector,omitempty"`
+	// NamespaceSelector selects the namespaces by label.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// SecretReplicationStatus is the state of the copies of a SecretReplication.
+type SecretReplicationStatus struct {
+	// Targets is the state of the copy in every selected namespace.
+	// +optional
+	Targets []SecretReplicationTarget `json:"targets,omitempty"`
+}
+
+// SecretReplicationTarget is the state of the copy of the source secret in a namespace of a downstream cluster.
+type SecretReplicationTarget struct {
+	// ClusterName is the name of the management cluster.
+	ClusterName string `json:"clusterName"`
+	// Namespace is the namespace of the copy in the cluster.
+	Namespace string `json:"namespace"`
+	// State is either Synced or Error.
+	State string `json:"state"`
+	// Message describes why the secret could not be copied, if any.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -203,6 +203,103 @@ func (in *APIServiceStatus) DeepCopy() *APIServiceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequest) DeepCopyInto(out *AccessRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequest.
+func (in *AccessRequest) DeepCopy() *AccessRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequest) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestList) DeepCopyInto(out *AccessRequestList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccessRequest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestList.
+func (in *AccessRequestList) DeepCopy() *AccessRequestList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccessRequestList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestSpec) DeepCopyInto(out *AccessRequestSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestSpec.
+func (in *AccessRequestSpec) DeepCopy() *AccessRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccessRequestStatus) DeepCopyInto(out *AccessRequestStatus) {
+	*out = *in
+	if in.DecidedAt != nil {
+		in, out := &in.DecidedAt, &out.DecidedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessRequestStatus.
+func (in *AccessRequestStatus) DeepCopy() *AccessRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccessRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Action) DeepCopyInto(out *Action) {
 	*out = *in
@@ -839,6 +936,109 @@ func (in *BasicLogin) DeepCopy() *BasicLogin {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingReview) DeepCopyInto(out *BindingReview) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingReview.
+func (in *BindingReview) DeepCopy() *BindingReview {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingReview)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BindingReview) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingReviewList) DeepCopyInto(out *BindingReviewList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BindingReview, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingReviewList.
+func (in *BindingReviewList) DeepCopy() *BindingReviewList {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingReviewList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BindingReviewList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingReviewSpec) DeepCopyInto(out *BindingReviewSpec) {
+	*out = *in
+	if in.Findings != nil {
+		in, out := &in.Findings, &out.Findings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Deadline.DeepCopyInto(&out.Deadline)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingReviewSpec.
+func (in *BindingReviewSpec) DeepCopy() *BindingReviewSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingReviewSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingReviewStatus) DeepCopyInto(out *BindingReviewStatus) {
+	*out = *in
+	if in.ConfirmedAt != nil {
+		in, out := &in.ConfirmedAt, &out.ConfirmedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingReviewStatus.
+func (in *BindingReviewStatus) DeepCopy() *BindingReviewStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingReviewStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Capabilities) DeepCopyInto(out *Capabilities) {
 	*out = *in
@@ -1722,6 +1922,10 @@ func (in *ClusterRoleTemplateBinding) DeepCopyInto(out *ClusterRoleTemplateBindi
 	out.Namespaced = in.Namespaced
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -1850,6 +2054,11 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.EgressProxy != nil {
+		in, out := &in.EgressProxy, &out.EgressProxy
+		*out = new(EgressProxyConfig)
+		**out = **in
+	}
 	return
 }
 
@@ -2684,6 +2893,22 @@ func (in *EKSStatus) DeepCopy() *EKSStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressProxyConfig) DeepCopyInto(out *EgressProxyConfig) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressProxyConfig.
+func (in *EgressProxyConfig) DeepCopy() *EgressProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ElasticsearchConfig) DeepCopyInto(out *ElasticsearchConfig) {
 	*out = *in
@@ -3835,6 +4060,10 @@ func (in *GlobalRoleBinding) DeepCopyInto(out *GlobalRoleBinding) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -4968,6 +5197,22 @@ func (in *ManagedChartList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedChartClusterStatus) DeepCopyInto(out *ManagedChartClusterStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedChartClusterStatus.
+func (in *ManagedChartClusterStatus) DeepCopy() *ManagedChartClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedChartClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagedChartSpec) DeepCopyInto(out *ManagedChartSpec) {
 	*out = *in
@@ -4992,6 +5237,20 @@ func (in *ManagedChartSpec) DeepCopyInto(out *ManagedChartSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ClusterValues != nil {
+		in, out := &in.ClusterValues, &out.ClusterValues
+		*out = make(map[string]*v1alpha1.GenericMap, len(*in))
+		for key, val := range *in {
+			var outVal *v1alpha1.GenericMap
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = (*in).DeepCopy()
+			}
+			(*out)[key] = outVal
+		}
+	}
 	return
 }
 
@@ -5009,6 +5268,11 @@ func (in *ManagedChartSpec) DeepCopy() *ManagedChartSpec {
 func (in *ManagedChartStatus) DeepCopyInto(out *ManagedChartStatus) {
 	*out = *in
 	in.BundleStatus.DeepCopyInto(&out.BundleStatus)
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ManagedChartClusterStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -5304,7 +5568,7 @@ func (in *MultiClusterAppStatus) DeepCopy() *MultiClusterAppStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespaceResourceQuota) DeepCopyInto(out *NamespaceResourceQuota) {
 	*out = *in
-	out.Limit = in.Limit
+	in.Limit.DeepCopyInto(&out.Limit)
 	return
 }
 
@@ -5318,6 +5582,30 @@ func (in *NamespaceResourceQuota) DeepCopy() *NamespaceResourceQuota {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceSelectedRules) DeepCopyInto(out *NamespaceSelectedRules) {
+	*out = *in
+	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceSelectedRules.
+func (in *NamespaceSelectedRules) DeepCopy() *NamespaceSelectedRules {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceSelectedRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Node) DeepCopyInto(out *Node) {
 	*out = *in
@@ -6698,6 +6986,80 @@ func (in *ProjectCondition) DeepCopy() *ProjectCondition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectLimitRange) DeepCopyInto(out *ProjectLimitRange) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make([]ProjectLimitRangeItem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectLimitRange.
+func (in *ProjectLimitRange) DeepCopy() *ProjectLimitRange {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectLimitRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectLimitRangeItem) DeepCopyInto(out *ProjectLimitRangeItem) {
+	*out = *in
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Min != nil {
+		in, out := &in.Min, &out.Min
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DefaultRequest != nil {
+		in, out := &in.DefaultRequest, &out.DefaultRequest
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaxLimitRequestRatio != nil {
+		in, out := &in.MaxLimitRequestRatio, &out.MaxLimitRequestRatio
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectLimitRangeItem.
+func (in *ProjectLimitRangeItem) DeepCopy() *ProjectLimitRangeItem {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectLimitRangeItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectList) DeepCopyInto(out *ProjectList) {
 	*out = *in
@@ -6833,6 +7195,133 @@ func (in *ProjectLoggingStatus) DeepCopy() *ProjectLoggingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMembershipPolicy) DeepCopyInto(out *ProjectMembershipPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMembershipPolicy.
+func (in *ProjectMembershipPolicy) DeepCopy() *ProjectMembershipPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMembershipPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProjectMembershipPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMembershipPolicyList) DeepCopyInto(out *ProjectMembershipPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProjectMembershipPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMembershipPolicyList.
+func (in *ProjectMembershipPolicyList) DeepCopy() *ProjectMembershipPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMembershipPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProjectMembershipPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMembershipPolicySpec) DeepCopyInto(out *ProjectMembershipPolicySpec) {
+	*out = *in
+	in.ProjectSelector.DeepCopyInto(&out.ProjectSelector)
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ProjectMembershipRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMembershipPolicySpec.
+func (in *ProjectMembershipPolicySpec) DeepCopy() *ProjectMembershipPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMembershipPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMembershipPolicyStatus) DeepCopyInto(out *ProjectMembershipPolicyStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMembershipPolicyStatus.
+func (in *ProjectMembershipPolicyStatus) DeepCopy() *ProjectMembershipPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMembershipPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectMembershipRule) DeepCopyInto(out *ProjectMembershipRule) {
+	*out = *in
+	if in.UserAttribute != nil {
+		in, out := &in.UserAttribute, &out.UserAttribute
+		*out = new(UserAttributeMatch)
+		**out = **in
+	}
+	if in.RoleTemplateNames != nil {
+		in, out := &in.RoleTemplateNames, &out.RoleTemplateNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectMembershipRule.
+func (in *ProjectMembershipRule) DeepCopy() *ProjectMembershipRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectMembershipRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectNetworkPolicy) DeepCopyInto(out *ProjectNetworkPolicy) {
 	*out = *in
@@ -6934,8 +7423,8 @@ func (in *ProjectNetworkPolicyStatus) DeepCopy() *ProjectNetworkPolicyStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectResourceQuota) DeepCopyInto(out *ProjectResourceQuota) {
 	*out = *in
-	out.Limit = in.Limit
-	out.UsedLimit = in.UsedLimit
+	in.Limit.DeepCopyInto(&out.Limit)
+	in.UsedLimit.DeepCopyInto(&out.UsedLimit)
 	return
 }
 
@@ -6955,6 +7444,10 @@ func (in *ProjectRoleTemplateBinding) DeepCopyInto(out *ProjectRoleTemplateBindi
 	out.Namespaced = in.Namespaced
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -7015,18 +7508,23 @@ func (in *ProjectSpec) DeepCopyInto(out *ProjectSpec) {
 	if in.ResourceQuota != nil {
 		in, out := &in.ResourceQuota, &out.ResourceQuota
 		*out = new(ProjectResourceQuota)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.NamespaceDefaultResourceQuota != nil {
 		in, out := &in.NamespaceDefaultResourceQuota, &out.NamespaceDefaultResourceQuota
 		*out = new(NamespaceResourceQuota)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ContainerDefaultResourceLimit != nil {
 		in, out := &in.ContainerDefaultResourceLimit, &out.ContainerDefaultResourceLimit
 		*out = new(ContainerResourceLimit)
 		**out = **in
 	}
+	if in.LimitRange != nil {
+		in, out := &in.LimitRange, &out.LimitRange
+		*out = new(ProjectLimitRange)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -7196,6 +7694,13 @@ func (in *RancherUserNotificationList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceQuotaLimit) DeepCopyInto(out *ResourceQuotaLimit) {
 	*out = *in
+	if in.Extended != nil {
+		in, out := &in.Extended, &out.Extended
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -7448,6 +7953,13 @@ func (in *RoleTemplate) DeepCopyInto(out *RoleTemplate) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.NamespaceSelectedRules != nil {
+		in, out := &in.NamespaceSelectedRules, &out.NamespaceSelectedRules
+		*out = make([]NamespaceSelectedRules, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -7932,6 +8444,131 @@ func (in *SearchPrincipalsInput) DeepCopy() *SearchPrincipalsInput {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReplication) DeepCopyInto(out *SecretReplication) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReplication.
+func (in *SecretReplication) DeepCopy() *SecretReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretReplication) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReplicationList) DeepCopyInto(out *SecretReplicationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretReplication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReplicationList.
+func (in *SecretReplicationList) DeepCopy() *SecretReplicationList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReplicationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretReplicationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReplicationSpec) DeepCopyInto(out *SecretReplicationSpec) {
+	*out = *in
+	in.ClusterSelector.DeepCopyInto(&out.ClusterSelector)
+	if in.ProjectSelector != nil {
+		in, out := &in.ProjectSelector, &out.ProjectSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReplicationSpec.
+func (in *SecretReplicationSpec) DeepCopy() *SecretReplicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReplicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReplicationStatus) DeepCopyInto(out *SecretReplicationStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]SecretReplicationTarget, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReplicationStatus.
+func (in *SecretReplicationStatus) DeepCopy() *SecretReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReplicationTarget) DeepCopyInto(out *SecretReplicationTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReplicationTarget.
+func (in *SecretReplicationTarget) DeepCopy() *SecretReplicationTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReplicationTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SetPasswordInput) DeepCopyInto(out *SetPasswordInput) {
 	*out = *in
@@ -8686,6 +9323,22 @@ func (in *UserAttributeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserAttributeMatch) DeepCopyInto(out *UserAttributeMatch) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserAttributeMatch.
+func (in *UserAttributeMatch) DeepCopy() *UserAttributeMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(UserAttributeMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UserCondition) DeepCopyInto(out *UserCondition) {
 	*out = *in
@@ -43,6 +43,23 @@ func NewAPIService(namespace, name string, obj APIService) *APIService {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// AccessRequestList is a list of AccessRequest resources
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AccessRequest `json:"items"`
+}
+
+func NewAccessRequest(namespace, name string, obj AccessRequest) *AccessRequest {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("AccessRequest").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ActiveDirectoryProviderList is a list of ActiveDirectoryProvider resources
 type ActiveDirectoryProviderList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -128,6 +145,23 @@ func NewAzureADProvider(namespace, name string, obj AzureADProvider) *AzureADPro
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// BindingReviewList is a list of BindingReview resources
+type BindingReviewList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []BindingReview `json:"items"`
+}
+
+func NewBindingReview(namespace, name string, obj BindingReview) *BindingReview {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("BindingReview").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // CatalogList is a list of Catalog resources
 type CatalogList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -842,6 +876,23 @@ func NewProjectLogging(namespace, name string, obj ProjectLogging) *ProjectLoggi
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ProjectMembershipPolicyList is a list of ProjectMembershipPolicy resources
+type ProjectMembershipPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ProjectMembershipPolicy `json:"items"`
+}
+
+func NewProjectMembershipPolicy(namespace, name string, obj ProjectMembershipPolicy) *ProjectMembershipPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ProjectMembershipPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ProjectNetworkPolicyList is a list of ProjectNetworkPolicy resources
 type ProjectNetworkPolicyList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -995,6 +1046,23 @@ func NewSamlToken(namespace, name string, obj SamlToken) *SamlToken {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// SecretReplicationList is a list of SecretReplication resources
+type SecretReplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []SecretReplication `json:"items"`
+}
+
+func NewSecretReplication(namespace, name string, obj SecretReplication) *SecretReplication {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("SecretReplication").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // SettingList is a list of Setting resources
 type SettingList struct {
 	metav1.TypeMeta `json:",inline"`
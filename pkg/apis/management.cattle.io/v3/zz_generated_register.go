@@ -29,11 +29,13 @@
 
 var (
 	APIServiceResourceName                                = "apiservices"
+	AccessRequestResourceName                             = "accessrequests"
 	ActiveDirectoryProviderResourceName                   = "activedirectoryproviders"
 	AuthConfigResourceName                                = "authconfigs"
 	AuthProviderResourceName                              = "authproviders"
 	AuthTokenResourceName                                 = "authtokens"
 	AzureADProviderResourceName                           = "azureadproviders"
+	BindingReviewResourceName                             = "bindingreviews"
 	CatalogResourceName                                   = "catalogs"
 	CatalogTemplateResourceName                           = "catalogtemplates"
 	CatalogTemplateVersionResourceName                    = "catalogtemplateversions"
@@ -78,6 +80,7 @@
 	ProjectResourceName                                   = "projects"
 	ProjectCatalogResourceName                            = "projectcatalogs"
 	ProjectLoggingResourceName                            = "projectloggings"
+	ProjectMembershipPolicyResourceName                   = "projectmembershippolicies"
 	ProjectNetworkPolicyResourceName                      = "projectnetworkpolicies"
 	ProjectRoleTemplateBindingResourceName                = "projectroletemplatebindings"
 	RancherUserNotificationResourceName                   = "rancherusernotifications"
@@ -87,6 +90,7 @@
 	RoleTemplateResourceName                              = "roletemplates"
 	SamlProviderResourceName                              = "samlproviders"
 	SamlTokenResourceName                                 = "samltokens"
+	SecretReplicationResourceName                         = "secretreplications"
 	SettingResourceName                                   = "settings"
 	TemplateResourceName                                  = "templates"
 	TemplateContentResourceName                           = "templatecontents"
@@ -119,6 +123,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&APIService{},
 		&APIServiceList{},
+		&AccessRequest{},
+		&AccessRequestList{},
 		&ActiveDirectoryProvider{},
 		&ActiveDirectoryProviderList{},
 		&AuthConfig{},
@@ -129,6 +135,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&AuthTokenList{},
 		&AzureADProvider{},
 		&AzureADProviderList{},
+		&BindingReview{},
+		&BindingReviewList{},
 		&Catalog{},
 		&CatalogList{},
 		&CatalogTemplate{},
@@ -217,6 +225,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&ProjectCatalogList{},
 		&ProjectLogging{},
 		&ProjectLoggingList{},
+		&ProjectMembershipPolicy{},
+		&ProjectMembershipPolicyList{},
 		&ProjectNetworkPolicy{},
 		&ProjectNetworkPolicyList{},
 		&ProjectRoleTemplateBinding{},
@@ -235,6 +245,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&SamlProviderList{},
 		&SamlToken{},
 		&SamlTokenList{},
+		&SecretReplication{},
+		&SecretReplicationList{},
 		&Setting{},
 		&SettingList{},
 		&Template{},
@@ -0,0 +1,70 @@
+package accessreview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Report lists every GlobalRoleBinding, ClusterRoleTemplateBinding and ProjectRoleTemplateBinding.
+type Report struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Bindings    []Entry   `json:"bindings"`
+}
+
+// Entry is a binding in the report.
+type Entry struct {
+	// Kind is GlobalRoleBinding, ClusterRoleTemplateBinding or ProjectRoleTemplateBinding.
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// SubjectKind is User, Group, UserPrincipal or ServiceAccount.
+	SubjectKind string `json:"subjectKind"`
+	Subject     string `json:"subject"`
+	// Role is the name of the GlobalRole or RoleTemplate.
+	Role string `json:"role"`
+	// Scope is "global" for GlobalRoleBindings, the cluster for ClusterRoleTemplateBindings and the project for
+	// ProjectRoleTemplateBindings.
+	Scope     string     `json:"scope"`
+	Creator   string     `json:"creator,omitempty"`
+	Created   time.Time  `json:"created"`
+	LastLogin *time.Time `json:"lastLogin,omitempty"`
+	// Findings are the reasons the binding is flagged as stale.
+	Findings []string `json:"findings,omitempty"`
+
+	// managed bindings are maintained by Rancher for another binding, and are reviewed through it.
+	managed bool
+}
+
+// Flagged returns whether the binding is flagged as stale.
+func (e *Entry) Flagged() bool {
+	return len(e.Findings) > 0
+}
+
+// JSON encodes the report as JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// CSV encodes the report as CSV, with a header row and one row per binding.
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"kind", "namespace", "name", "subjectKind", "subject", "role", "scope", "creator", "created", "lastLogin", "findings"}}
+	for _, e := range r.Bindings {
+		var lastLogin string
+		if e.LastLogin != nil {
+			lastLogin = e.LastLogin.UTC().Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			e.Kind, e.Namespace, e.Name, e.SubjectKind, e.Subject, e.Role, e.Scope, e.Creator,
+			e.Created.UTC().Format(time.RFC3339), lastLogin, strings.Join(e.Findings, "; "),
+		})
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,477 @@
+// Package accessreview implements the scheduled access review. Each run reports on every GlobalRoleBinding,
+// ClusterRoleTemplateBinding and ProjectRoleTemplateBinding, flags the bindings of disabled or inactive users and
+// optionally opens BindingReviews for them, which must be confirmed or the bindings are removed.
+package accessreview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/wrangler"
+	wcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// ReportLabel is set on the ConfigMaps holding the access review reports.
+	ReportLabel = "management.cattle.io/access-review-report"
+	// ReportNameLabel holds the name of the report a ConfigMap holds a part of. The report is the name of its first
+	// part.
+	ReportNameLabel = "management.cattle.io/access-review-report-name"
+	// ReportPartAnnotation holds the index of the part of the report held by a ConfigMap, starting at 0.
+	ReportPartAnnotation = "management.cattle.io/access-review-report-part"
+	// ReportJSONKey and ReportCSVKey are the keys of the gzip-compressed report in the binary data of the ConfigMaps.
+	// A large report is split across several ConfigMaps: the data of a key in the parts of the report, in their order,
+	// is the compressed report.
+	ReportJSONKey = "report.json.gz"
+	ReportCSVKey  = "report.csv.gz"
+
+	// reportsToKeep is the number of reports kept in the cattle-system namespace.
+	reportsToKeep = 10
+	// maxPartSize is the size of the data of a part of a report, well below the size limit of a ConfigMap.
+	maxPartSize = 768 * 1024
+
+	creatorIDAnnotation = "field.cattle.io/creatorId"
+	// grbOwnerLabel is set on the ClusterRoleTemplateBindings created for the InheritedClusterRoles of a
+	// GlobalRoleBinding.
+	grbOwnerLabel = "authz.management.cattle.io/grb-owner"
+
+	globalRoleBindingKind          = "GlobalRoleBinding"
+	clusterRoleTemplateBindingKind = "ClusterRoleTemplateBinding"
+	projectRoleTemplateBindingKind = "ProjectRoleTemplateBinding"
+)
+
+// Review is the access review process.
+type Review struct {
+	grbs               mgmtcontrollers.GlobalRoleBindingCache
+	crtbs              mgmtcontrollers.ClusterRoleTemplateBindingCache
+	prtbs              mgmtcontrollers.ProjectRoleTemplateBindingCache
+	userCache          mgmtcontrollers.UserCache
+	userAttributeCache mgmtcontrollers.UserAttributeCache
+	bindingReviewCache mgmtcontrollers.BindingReviewCache
+	bindingReviews     mgmtcontrollers.BindingReviewClient
+	configMaps         wcorev1.ConfigMapClient
+	readSettings       func() (settings, error)
+	now                func() time.Time
+}
+
+// New creates a new instance of Review.
+func New(wContext *wrangler.Context) *Review {
+	return &Review{
+		grbs:               wContext.Mgmt.GlobalRoleBinding().Cache(),
+		crtbs:              wContext.Mgmt.ClusterRoleTemplateBinding().Cache(),
+		prtbs:              wContext.Mgmt.ProjectRoleTemplateBinding().Cache(),
+		userCache:          wContext.Mgmt.User().Cache(),
+		userAttributeCache: wContext.Mgmt.UserAttribute().Cache(),
+		bindingReviewCache: wContext.Mgmt.BindingReview().Cache(),
+		bindingReviews:     wContext.Mgmt.BindingReview(),
+		configMaps:         wContext.Core.ConfigMap(),
+		readSettings:       readSettings,
+		now:                time.Now,
+	}
+}
+
+// Run the access review process.
+func (r *Review) Run(ctx context.Context) error {
+	if ctx.Err() != nil {
+		logrus.Info("accessreview: context canceled, quitting")
+		return nil
+	}
+
+	settings, err := r.readSettings()
+	if err != nil {
+		return fmt.Errorf("error reading settings: %w, access review is disabled", err)
+	}
+
+	now := r.now()
+	report, err := r.buildReport(settings, now)
+	if err != nil {
+		return err
+	}
+
+	var flagged, opened int
+	for _, entry := range report.Bindings {
+		if entry.Flagged() {
+			flagged++
+		}
+	}
+
+	name, err := r.storeReport(report)
+	if err != nil {
+		return fmt.Errorf("error storing report: %w", err)
+	}
+
+	if settings.ShouldOpenReviews() {
+		opened, err = r.openReviews(report, settings, now)
+		if err != nil {
+			return fmt.Errorf("error opening binding reviews: %w", err)
+		}
+	}
+
+	logrus.Infof("accessreview: stored report %s/%s (bindings %d, flagged %d, reviews opened %d)",
+		namespace.System, name, len(report.Bindings), flagged, opened)
+	return nil
+}
+
+// buildReport lists every binding, sorted by kind, namespace and name, and flags the stale ones.
+func (r *Review) buildReport(settings settings, now time.Time) (*Report, error) {
+	report := &Report{GeneratedAt: now.UTC(), Bindings: []Entry{}}
+
+	grbs, err := r.grbs.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing GlobalRoleBindings: %w", err)
+	}
+	for _, grb := range grbs {
+		report.Bindings = append(report.Bindings, grbEntry(grb))
+	}
+
+	crtbs, err := r.crtbs.List("", labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing ClusterRoleTemplateBindings: %w", err)
+	}
+	for _, crtb := range crtbs {
+		report.Bindings = append(report.Bindings, crtbEntry(crtb))
+	}
+
+	prtbs, err := r.prtbs.List("", labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing ProjectRoleTemplateBindings: %w", err)
+	}
+	for _, prtb := range prtbs {
+		report.Bindings = append(report.Bindings, prtbEntry(prtb))
+	}
+
+	sort.Slice(report.Bindings, func(i, j int) bool {
+		a, b := report.Bindings[i], report.Bindings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	for i := range report.Bindings {
+		if err := r.flag(&report.Bindings[i], settings, now); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Findings flags a binding again and returns the reasons it is stale, if it still exists and is. BindingReviews
+// check them again before removing the binding, as the binding may have been fixed since it was flagged.
+func (r *Review) Findings(kind, namespace, name string) ([]string, error) {
+	settings, err := r.readSettings()
+	if err != nil {
+		return nil, fmt.Errorf("error reading settings: %w", err)
+	}
+
+	var entry Entry
+	switch kind {
+	case globalRoleBindingKind:
+		grb, err := r.grbs.Get(name)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		entry = grbEntry(grb)
+	case clusterRoleTemplateBindingKind:
+		crtb, err := r.crtbs.Get(namespace, name)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		entry = crtbEntry(crtb)
+	case projectRoleTemplateBindingKind:
+		prtb, err := r.prtbs.Get(namespace, name)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		entry = prtbEntry(prtb)
+	default:
+		return nil, fmt.Errorf("unknown binding kind %q", kind)
+	}
+
+	if err := r.flag(&entry, settings, r.now()); err != nil {
+		return nil, err
+	}
+	return entry.Findings, nil
+}
+
+// flag sets the last login of the user of the binding and the findings making the binding stale.
+func (r *Review) flag(entry *Entry, settings settings, now time.Time) error {
+	if entry.SubjectKind != "User" {
+		return nil
+	}
+
+	user, err := r.userCache.Get(entry.Subject)
+	if apierrors.IsNotFound(err) {
+		entry.Findings = append(entry.Findings, "user not found")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting user %s: %w", entry.Subject, err)
+	}
+	if user.IsDefaultAdmin() || user.IsSystem() {
+		return nil
+	}
+	if !pointer.BoolDeref(user.Enabled, true) {
+		entry.Findings = append(entry.Findings, "user is disabled")
+	}
+
+	attribs, err := r.userAttributeCache.Get(user.Name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error getting user attributes for %s: %w", user.Name, err)
+	}
+	lastLogin := settings.defaultLastLogin
+	if attribs != nil && attribs.LastLogin != nil && !attribs.LastLogin.IsZero() {
+		lastLogin = attribs.LastLogin.Time
+	}
+	if !lastLogin.IsZero() {
+		entry.LastLogin = &lastLogin
+	}
+
+	if !settings.ShouldFlagInactive() {
+		return nil
+	}
+	switch {
+	case lastLogin.IsZero():
+		// users who were just created get the time to log in
+		if now.Sub(user.CreationTimestamp.Time) > settings.inactiveAfter {
+			entry.Findings = append(entry.Findings, "user never logged in")
+		}
+	case now.Sub(lastLogin) > settings.inactiveAfter:
+		entry.Findings = append(entry.Findings, fmt.Sprintf("user last logged in %d days ago", int(now.Sub(lastLogin).Hours()/24)))
+	}
+	return nil
+}
+
+// storeReport stores the compressed report in new ConfigMaps in the cattle-system namespace and removes the oldest
+// reports.
+func (r *Review) storeReport(report *Report) (string, error) {
+	reportJSON, err := report.JSON()
+	if err != nil {
+		return "", err
+	}
+	reportCSV, err := report.CSV()
+	if err != nil {
+		return "", err
+	}
+	compressedJSON, err := compress(reportJSON)
+	if err != nil {
+		return "", err
+	}
+	compressedCSV, err := compress(reportCSV)
+	if err != nil {
+		return "", err
+	}
+
+	name := "access-review-" + report.GeneratedAt.Format("20060102-150405")
+	parts := splitReport([]reportData{{key: ReportJSONKey, data: compressedJSON}, {key: ReportCSVKey, data: compressedCSV}}, maxPartSize)
+	for i, part := range parts {
+		partName := name
+		if i > 0 {
+			partName = fmt.Sprintf("%s-%d", name, i)
+		}
+		_, err := r.configMaps.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        partName,
+				Namespace:   namespace.System,
+				Labels:      map[string]string{ReportLabel: "true", ReportNameLabel: name},
+				Annotations: map[string]string{ReportPartAnnotation: strconv.Itoa(i)},
+			},
+			BinaryData: part,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	list, err := r.configMaps.List(namespace.System, metav1.ListOptions{LabelSelector: ReportLabel + "=true"})
+	if err != nil {
+		return "", err
+	}
+	reports := map[string][]string{}
+	for _, configMap := range list.Items {
+		reportName := configMap.Labels[ReportNameLabel]
+		if reportName == "" {
+			// reports stored before they were split in parts
+			reportName = configMap.Name
+		}
+		reports[reportName] = append(reports[reportName], configMap.Name)
+	}
+	var names []string
+	for reportName := range reports {
+		names = append(names, reportName)
+	}
+	// the report names sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+	for i := reportsToKeep; i < len(names); i++ {
+		for _, partName := range reports[names[i]] {
+			err := r.configMaps.Delete(namespace.System, partName, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				logrus.Errorf("accessreview: error deleting report %s: %v", partName, err)
+			}
+		}
+	}
+	return name, nil
+}
+
+// reportData is the data of a key of the ConfigMaps holding a report.
+type reportData struct {
+	key  string
+	data []byte
+}
+
+// splitReport splits the data of a report in parts holding at most size bytes.
+func splitReport(data []reportData, size int) []map[string][]byte {
+	parts := []map[string][]byte{{}}
+	free := size
+	for _, d := range data {
+		remaining := d.data
+		for len(remaining) > 0 {
+			if free == 0 {
+				parts = append(parts, map[string][]byte{})
+				free = size
+			}
+			n := min(free, len(remaining))
+			part := parts[len(parts)-1]
+			part[d.key] = append(part[d.key], remaining[:n]...)
+			remaining = remaining[n:]
+			free -= n
+		}
+	}
+	return parts
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// openReviews opens a BindingReview for each flagged binding of a user which doesn't have an open review already.
+// Bindings maintained by Rancher for another binding aren't reviewed, as they are removed along with it.
+func (r *Review) openReviews(report *Report, settings settings, now time.Time) (int, error) {
+	reviews, err := r.bindingReviewCache.List("", labels.SelectorFromSet(labels.Set{
+		v3.BindingReviewOpenedByLabel: v3.BindingReviewOpenedByAccessReview,
+	}))
+	if err != nil {
+		return 0, err
+	}
+	open := map[string]bool{}
+	for _, review := range reviews {
+		if review.Status.State == "" || review.Status.State == v3.BindingReviewOpen {
+			open[reviewKey(review.Spec.BindingKind, review.Spec.BindingNamespace, review.Spec.BindingName)] = true
+		}
+	}
+
+	var opened int
+	deadline := metav1.NewTime(now.Add(settings.confirmationPeriod))
+	for _, entry := range report.Bindings {
+		if !entry.Flagged() || entry.managed || open[reviewKey(entry.Kind, entry.Namespace, entry.Name)] {
+			continue
+		}
+		reviewNamespace := entry.Namespace
+		if reviewNamespace == "" {
+			reviewNamespace = namespace.GlobalNamespace
+		}
+		_, err := r.bindingReviews.Create(&v3.BindingReview{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "review-",
+				Namespace:    reviewNamespace,
+				Labels:       map[string]string{v3.BindingReviewOpenedByLabel: v3.BindingReviewOpenedByAccessReview},
+			},
+			Spec: v3.BindingReviewSpec{
+				BindingKind:      entry.Kind,
+				BindingNamespace: entry.Namespace,
+				BindingName:      entry.Name,
+				UserName:         entry.Subject,
+				RoleName:         entry.Role,
+				Findings:         entry.Findings,
+				Deadline:         deadline,
+			},
+		})
+		if err != nil {
+			// Log the error and move on.
+			logrus.Errorf("accessreview: error opening review for %s %s: %v", entry.Kind, entry.Name, err)
+			continue
+		}
+		opened++
+	}
+	return opened, nil
+}
+
+func grbEntry(grb *v3.GlobalRoleBinding) Entry {
+	entry := newEntry(globalRoleBindingKind, &grb.ObjectMeta, grb.GlobalRoleName, "global")
+	entry.SubjectKind, entry.Subject = subject(grb.UserName, grb.GroupPrincipalName, "", "")
+	return entry
+}
+
+func crtbEntry(crtb *v3.ClusterRoleTemplateBinding) Entry {
+	entry := newEntry(clusterRoleTemplateBindingKind, &crtb.ObjectMeta, crtb.RoleTemplateName, crtb.ClusterName)
+	entry.SubjectKind, entry.Subject = subject(crtb.UserName, crtb.GroupPrincipalName, crtb.UserPrincipalName, "")
+	entry.managed = crtb.Labels[grbOwnerLabel] != ""
+	return entry
+}
+
+func prtbEntry(prtb *v3.ProjectRoleTemplateBinding) Entry {
+	entry := newEntry(projectRoleTemplateBindingKind, &prtb.ObjectMeta, prtb.RoleTemplateName, prtb.ProjectName)
+	entry.SubjectKind, entry.Subject = subject(prtb.UserName, prtb.GroupPrincipalName, prtb.UserPrincipalName, prtb.ServiceAccount)
+	return entry
+}
+
+func newEntry(kind string, meta *metav1.ObjectMeta, role, scope string) Entry {
+	return Entry{
+		Kind:      kind,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Role:      role,
+		Scope:     scope,
+		Creator:   meta.Annotations[creatorIDAnnotation],
+		Created:   meta.CreationTimestamp.UTC(),
+	}
+}
+
+// subject returns the kind and name of the subject of a binding.
+func subject(userName, groupPrincipalName, userPrincipalName, serviceAccount string) (string, string) {
+	switch {
+	case userName != "":
+		return "User", userName
+	case groupPrincipalName != "":
+		return "Group", groupPrincipalName
+	case userPrincipalName != "":
+		return "UserPrincipal", userPrincipalName
+	case serviceAccount != "":
+		return "ServiceAccount", serviceAccount
+	}
+	return "", ""
+}
+
+func reviewKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
@@ -0,0 +1,265 @@
+package accessreview
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+var now = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+type mocks struct {
+	configMaps     *fake.MockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList]
+	bindingReviews *fake.MockControllerInterface[*v3.BindingReview, *v3.BindingReviewList]
+	reviewCache    *fake.MockCacheInterface[*v3.BindingReview]
+}
+
+func meta(namespace, name string, created time.Time) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace:         namespace,
+		Name:              name,
+		CreationTimestamp: metav1.NewTime(created),
+		Annotations:       map[string]string{creatorIDAnnotation: "u-admin"},
+	}
+}
+
+func newReview(t *testing.T, s settings) (*Review, *mocks) {
+	ctrl := gomock.NewController(t)
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	grbs := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbs.EXPECT().List(gomock.Any()).Return([]*v3.GlobalRoleBinding{
+		{ObjectMeta: meta("", "grb-active", longAgo), UserName: "u-active", GlobalRoleName: "user"},
+		{ObjectMeta: meta("", "grb-disabled", longAgo), UserName: "u-disabled", GlobalRoleName: "user"},
+		{ObjectMeta: meta("", "grb-group", longAgo), GroupPrincipalName: "okta_group://devs", GlobalRoleName: "user"},
+		{ObjectMeta: meta("", "grb-admin", longAgo), UserName: "admin", GlobalRoleName: "admin"},
+	}, nil).AnyTimes()
+
+	managed := meta("c-1", "crtb-managed", longAgo)
+	managed.Labels = map[string]string{grbOwnerLabel: "grb-inactive"}
+	crtbs := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbs.EXPECT().List("", gomock.Any()).Return([]*v3.ClusterRoleTemplateBinding{
+		{ObjectMeta: meta("c-1", "crtb-inactive", longAgo), UserName: "u-inactive", ClusterName: "c-1", RoleTemplateName: "cluster-owner"},
+		{ObjectMeta: managed, UserName: "u-inactive", ClusterName: "c-1", RoleTemplateName: "cluster-member"},
+	}, nil).AnyTimes()
+
+	prtbs := fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl)
+	prtbs.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{
+		{ObjectMeta: meta("p-1", "prtb-new", longAgo), UserName: "u-new", ProjectName: "c-1:p-1", RoleTemplateName: "project-member"},
+		{ObjectMeta: meta("p-1", "prtb-deleted", longAgo), UserName: "u-deleted", ProjectName: "c-1:p-1", RoleTemplateName: "project-member"},
+	}, nil).AnyTimes()
+
+	users := map[string]*v3.User{
+		"admin":      {ObjectMeta: metav1.ObjectMeta{Name: "admin", CreationTimestamp: metav1.NewTime(longAgo)}, Username: "admin"},
+		"u-active":   {ObjectMeta: metav1.ObjectMeta{Name: "u-active", CreationTimestamp: metav1.NewTime(longAgo)}},
+		"u-inactive": {ObjectMeta: metav1.ObjectMeta{Name: "u-inactive", CreationTimestamp: metav1.NewTime(longAgo)}},
+		"u-disabled": {ObjectMeta: metav1.ObjectMeta{Name: "u-disabled", CreationTimestamp: metav1.NewTime(longAgo)}, Enabled: pointer.Bool(false)},
+		"u-new":      {ObjectMeta: metav1.ObjectMeta{Name: "u-new", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+	}
+	userCache := fake.NewMockNonNamespacedCacheInterface[*v3.User](ctrl)
+	userCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.User, error) {
+		if user, ok := users[name]; ok {
+			return user, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "users"}, name)
+	}).AnyTimes()
+
+	lastLogins := map[string]time.Time{
+		"u-active":   now.Add(-24 * time.Hour),
+		"u-inactive": now.Add(-100 * 24 * time.Hour),
+		"u-disabled": now.Add(-24 * time.Hour),
+	}
+	userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+	userAttributeCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.UserAttribute, error) {
+		if lastLogin, ok := lastLogins[name]; ok {
+			return &v3.UserAttribute{LastLogin: &metav1.Time{Time: lastLogin}}, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "userattributes"}, name)
+	}).AnyTimes()
+
+	m := &mocks{
+		configMaps:     fake.NewMockControllerInterface[*corev1.ConfigMap, *corev1.ConfigMapList](ctrl),
+		bindingReviews: fake.NewMockControllerInterface[*v3.BindingReview, *v3.BindingReviewList](ctrl),
+		reviewCache:    fake.NewMockCacheInterface[*v3.BindingReview](ctrl),
+	}
+	return &Review{
+		grbs:               grbs,
+		crtbs:              crtbs,
+		prtbs:              prtbs,
+		userCache:          userCache,
+		userAttributeCache: userAttributeCache,
+		bindingReviewCache: m.reviewCache,
+		bindingReviews:     m.bindingReviews,
+		configMaps:         m.configMaps,
+		readSettings:       func() (settings, error) { return s, nil },
+		now:                func() time.Time { return now },
+	}, m
+}
+
+func TestBuildReport(t *testing.T) {
+	s := settings{inactiveAfter: 90 * 24 * time.Hour}
+	r, _ := newReview(t, s)
+
+	report, err := r.buildReport(s, now)
+	require.NoError(t, err)
+
+	findings := map[string][]string{}
+	for _, entry := range report.Bindings {
+		findings[entry.Name] = entry.Findings
+	}
+	assert.Equal(t, map[string][]string{
+		"crtb-inactive": {"user last logged in 100 days ago"},
+		"crtb-managed":  {"user last logged in 100 days ago"},
+		"grb-active":    nil,
+		"grb-admin":     nil,
+		"grb-disabled":  {"user is disabled"},
+		"grb-group":     nil,
+		"prtb-deleted":  {"user not found"},
+		"prtb-new":      nil,
+	}, findings)
+
+	first := report.Bindings[0]
+	assert.Equal(t, "ClusterRoleTemplateBinding", first.Kind)
+	assert.Equal(t, "c-1", first.Scope)
+	assert.Equal(t, "u-admin", first.Creator)
+	assert.Equal(t, now.Add(-100*24*time.Hour), *first.LastLogin)
+}
+
+func TestBuildReportWithoutInactivity(t *testing.T) {
+	r, _ := newReview(t, settings{})
+
+	report, err := r.buildReport(settings{}, now)
+	require.NoError(t, err)
+	for _, entry := range report.Bindings {
+		if entry.Name == "crtb-inactive" {
+			assert.Empty(t, entry.Findings)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := settings{inactiveAfter: 90 * 24 * time.Hour, confirmationPeriod: 14 * 24 * time.Hour}
+	r, m := newReview(t, s)
+
+	var stored *corev1.ConfigMap
+	m.configMaps.EXPECT().Create(gomock.Any()).DoAndReturn(func(configMap *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+		stored = configMap
+		return configMap, nil
+	})
+	// the oldest reports beyond the ones to keep are removed
+	existing := []corev1.ConfigMap{
+		{ObjectMeta: metav1.ObjectMeta{Name: "access-review-20231201-000000"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "access-review-20240101-000000", Labels: map[string]string{ReportNameLabel: "access-review-20240101-000000"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "access-review-20240101-000000-1", Labels: map[string]string{ReportNameLabel: "access-review-20240101-000000"}}},
+	}
+	for i := 2; i <= reportsToKeep; i++ {
+		existing = append(existing, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("access-review-202501%02d-000000", i)}})
+	}
+	existing = append(existing, corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "access-review-20260401-000000", Labels: map[string]string{ReportNameLabel: "access-review-20260401-000000"}}})
+	m.configMaps.EXPECT().List(namespace.System, gomock.Any()).Return(&corev1.ConfigMapList{Items: existing}, nil)
+	// all the parts of the removed reports are removed
+	m.configMaps.EXPECT().Delete(namespace.System, "access-review-20231201-000000", gomock.Any()).Return(nil)
+	m.configMaps.EXPECT().Delete(namespace.System, "access-review-20240101-000000", gomock.Any()).Return(nil)
+	m.configMaps.EXPECT().Delete(namespace.System, "access-review-20240101-000000-1", gomock.Any()).Return(nil)
+
+	// the disabled user already has an open review
+	m.reviewCache.EXPECT().List("", gomock.Any()).Return([]*v3.BindingReview{
+		{
+			Spec:   v3.BindingReviewSpec{BindingKind: "GlobalRoleBinding", BindingName: "grb-disabled"},
+			Status: v3.BindingReviewStatus{State: v3.BindingReviewOpen},
+		},
+	}, nil)
+	var opened []*v3.BindingReview
+	m.bindingReviews.EXPECT().Create(gomock.Any()).DoAndReturn(func(review *v3.BindingReview) (*v3.BindingReview, error) {
+		opened = append(opened, review)
+		return review, nil
+	}).Times(2)
+
+	require.NoError(t, r.Run(context.Background()))
+
+	assert.Equal(t, "access-review-20260401-000000", stored.Name)
+	assert.Equal(t, "true", stored.Labels[ReportLabel])
+	assert.Equal(t, "access-review-20260401-000000", stored.Labels[ReportNameLabel])
+	assert.Equal(t, "0", stored.Annotations[ReportPartAnnotation])
+	var report Report
+	require.NoError(t, json.Unmarshal(decompress(t, stored.BinaryData[ReportJSONKey]), &report))
+	assert.Len(t, report.Bindings, 8)
+	rows, err := csv.NewReader(bytes.NewReader(decompress(t, stored.BinaryData[ReportCSVKey]))).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 9)
+	assert.Equal(t, []string{"ClusterRoleTemplateBinding", "c-1", "crtb-inactive", "User", "u-inactive", "cluster-owner", "c-1", "u-admin",
+		"2025-04-01T00:00:00Z", "2025-12-22T00:00:00Z", "user last logged in 100 days ago"}, rows[1])
+
+	// the binding maintained for a GlobalRoleBinding isn't reviewed
+	require.Len(t, opened, 2)
+	assert.Equal(t, "c-1", opened[0].Namespace)
+	assert.Equal(t, v3.BindingReviewOpenedByAccessReview, opened[0].Labels[v3.BindingReviewOpenedByLabel])
+	assert.Equal(t, "crtb-inactive", opened[0].Spec.BindingName)
+	assert.Equal(t, now.Add(14*24*time.Hour), opened[0].Spec.Deadline.Time)
+	assert.Equal(t, "p-1", opened[1].Namespace)
+	assert.Equal(t, "prtb-deleted", opened[1].Spec.BindingName)
+}
+
+func decompress(t *testing.T, data []byte) []byte {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return decompressed
+}
+
+func TestSplitReport(t *testing.T) {
+	parts := splitReport([]reportData{{key: "a", data: []byte("0123456")}, {key: "b", data: []byte("789")}}, 4)
+	assert.Equal(t, []map[string][]byte{
+		{"a": []byte("0123")},
+		{"a": []byte("456"), "b": []byte("7")},
+		{"b": []byte("89")},
+	}, parts)
+
+	// an empty report is stored in a single part
+	assert.Equal(t, []map[string][]byte{{}}, splitReport(nil, 4))
+}
+
+func TestFindings(t *testing.T) {
+	s := settings{inactiveAfter: 90 * 24 * time.Hour}
+	r, _ := newReview(t, s)
+	ctrl := gomock.NewController(t)
+	longAgo := now.Add(-365 * 24 * time.Hour)
+
+	grbs := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbs.EXPECT().Get("grb-active").Return(&v3.GlobalRoleBinding{ObjectMeta: meta("", "grb-active", longAgo), UserName: "u-active"}, nil)
+	grbs.EXPECT().Get("grb-deleted").Return(nil, apierrors.NewNotFound(schema.GroupResource{Resource: "globalrolebindings"}, "grb-deleted"))
+	crtbs := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbs.EXPECT().Get("c-1", "crtb-inactive").Return(&v3.ClusterRoleTemplateBinding{ObjectMeta: meta("c-1", "crtb-inactive", longAgo), UserName: "u-inactive"}, nil)
+	r.grbs, r.crtbs = grbs, crtbs
+
+	findings, err := r.Findings("ClusterRoleTemplateBinding", "c-1", "crtb-inactive")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user last logged in 100 days ago"}, findings)
+
+	// a binding whose user logged in again, or which was deleted, is no longer flagged
+	findings, err = r.Findings("GlobalRoleBinding", "", "grb-active")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+	findings, err = r.Findings("GlobalRoleBinding", "", "grb-deleted")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
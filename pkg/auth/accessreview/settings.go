@@ -0,0 +1,56 @@
+package accessreview
+
+import (
+	"fmt"
+	"time"
+
+	appsettings "github.com/rancher/rancher/pkg/settings"
+)
+
+// settings control the access review.
+type settings struct {
+	inactiveAfter      time.Duration
+	confirmationPeriod time.Duration
+	defaultLastLogin   time.Time
+}
+
+// ShouldFlagInactive returns true if the bindings of inactive users should be flagged.
+func (s *settings) ShouldFlagInactive() bool {
+	return s.inactiveAfter != 0
+}
+
+// ShouldOpenReviews returns true if BindingReviews should be opened for the flagged bindings.
+func (s *settings) ShouldOpenReviews() bool {
+	return s.confirmationPeriod != 0
+}
+
+// readSettings reads and parses access review settings.
+func readSettings() (settings, error) {
+	var (
+		err    error
+		parsed settings
+	)
+
+	if value := appsettings.AccessReviewInactiveUserAfter.Get(); value != "" {
+		parsed.inactiveAfter, err = time.ParseDuration(value)
+		if err != nil {
+			return settings{}, fmt.Errorf("%s: %w", appsettings.AccessReviewInactiveUserAfter.Name, err)
+		}
+	}
+
+	if value := appsettings.AccessReviewConfirmationPeriod.Get(); value != "" {
+		parsed.confirmationPeriod, err = time.ParseDuration(value)
+		if err != nil {
+			return settings{}, fmt.Errorf("%s: %w", appsettings.AccessReviewConfirmationPeriod.Name, err)
+		}
+	}
+
+	if value := appsettings.UserLastLoginDefault.Get(); value != "" {
+		parsed.defaultLastLogin, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return settings{}, fmt.Errorf("%s: %w", appsettings.UserLastLoginDefault.Name, err)
+		}
+	}
+
+	return parsed, nil
+}
@@ -23,10 +23,10 @@
 	"fmt"
 	"io"
 	"net/url"
-	"strings"
 	"sync"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/rancher/pkg/api/steve/catalog/types"
 	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/catalogv2"
@@ -38,7 +38,6 @@
 	"github.com/rancher/rancher/pkg/settings"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
-	"github.com/sirupsen/logrus"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 	corev1 "k8s.io/api/core/v1"
@@ -57,6 +56,7 @@ type Manager struct {
 	secrets      corecontrollers.SecretCache         // clientset cache for Secrets.
 	clusterRepos catalogcontrollers.ClusterRepoCache // clientset cache for ClusterRepo custom resources.
 	discovery    discovery.DiscoveryInterface        // An interface to the Kubernetes Discovery API. Provides information about the Kubernetes API server.
+	proxy        helmhttp.ProxyFunc                  // returns the proxy used to reach the Helm repositories.
 	IndexCache   map[string]indexCache               // cache for Helm repository index files. Used to store and retrieve index files for faster access.
 	lock         sync.RWMutex                        // read-write mutex used to ensure that some Manager's operations are thread-safe.
 }
@@ -80,9 +80,11 @@ func NewManager(
 	discovery discovery.DiscoveryInterface,
 	configMaps corecontrollers.ConfigMapCache,
 	secrets corecontrollers.SecretCache,
-	clusterRepos catalogcontrollers.ClusterRepoCache) *Manager {
+	clusterRepos catalogcontrollers.ClusterRepoCache,
+	proxy helmhttp.ProxyFunc) *Manager {
 	return &Manager{
 		discovery:    discovery,
+		proxy:        proxy,
 		configMaps:   configMaps,
 		secrets:      secrets,
 		clusterRepos: clusterRepos,
@@ -90,11 +92,35 @@ func NewManager(
 	}
 }
 
+// IndexOptions are the criteria used to filter the chart versions of the index of a Helm repository.
+type IndexOptions struct {
+	// TargetK8sVersion is the Kubernetes version of the target cluster. Defaults to the local cluster's version.
+	TargetK8sVersion string
+	// TargetOS is the operating system of the nodes of the target cluster, ie. "linux" or "windows".
+	// Chart versions are not filtered by operating system if empty.
+	TargetOS string
+	// TargetArchitectures are the architectures of the nodes of the target cluster, ie. "amd64" or "arm64".
+	// Chart versions are not filtered by architecture if empty.
+	TargetArchitectures []string
+	// SkipFilter when true returns every chart version, with the reasons why a chart version would have
+	// been filtered out in its HiddenReasonsAnnotation annotation.
+	SkipFilter bool
+}
+
 // Index (thread-safe) retrieves the Helm repository information for a specific namespace and name.
 // By default, it uses rancher version and the local cluster's k8s version to filter available versions in the returned index file;
 // If skipFilter is true, it will return the entire unfiltered index file;
 // if a valid targetK8sVersion is provided, it will filter versions based on rancher version and the target k8s version.
 func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter bool) (*repo.IndexFile, error) {
+	return c.IndexWithOptions(namespace, name, IndexOptions{
+		TargetK8sVersion: targetK8sVersion,
+		SkipFilter:       skipFilter,
+	})
+}
+
+// IndexWithOptions (thread-safe) retrieves the Helm repository information for a specific namespace and name,
+// filtered with the given options and the chart filter of the repository.
+func (c *Manager) IndexWithOptions(namespace, name string, opts IndexOptions) (*repo.IndexFile, error) {
 	r, err := c.getRepo(namespace, name)
 	if err != nil {
 		return nil, err
@@ -105,8 +131,8 @@ func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter boo
 		return nil, err
 	}
 	var k8sVersion *semver.Version
-	if targetK8sVersion != "" {
-		k8sVersion, err = semver.NewVersion(targetK8sVersion)
+	if opts.TargetK8sVersion != "" {
+		k8sVersion, err = semver.NewVersion(opts.TargetK8sVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -116,12 +142,18 @@ func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter boo
 			return nil, err
 		}
 	}
+	filter := releaseFilter{
+		k8sVersion:    k8sVersion,
+		os:            opts.TargetOS,
+		architectures: opts.TargetArchitectures,
+		chartFilter:   r.spec.ChartFilter,
+	}
 	// Check IndexCache and if it is up-to-date.
 	c.lock.RLock()
 	if cache, ok := c.IndexCache[fmt.Sprintf("%s/%s", r.status.IndexConfigMapNamespace, r.status.IndexConfigMapName)]; ok {
 		if cm.ResourceVersion == cache.revision {
 			c.lock.RUnlock()
-			return c.filterReleases(deepCopyIndex(cache.index), k8sVersion, skipFilter), nil
+			return c.filterIndex(deepCopyIndex(cache.index), filter, opts.SkipFilter), nil
 		}
 	}
 	c.lock.RUnlock()
@@ -159,7 +191,7 @@ func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter boo
 	}
 	c.lock.Unlock()
 
-	return c.filterReleases(deepCopyIndex(index), k8sVersion, skipFilter), nil
+	return c.filterIndex(deepCopyIndex(index), filter, opts.SkipFilter), nil
 }
 
 // Icon Returns an io.ReadCloser and the icon's MIME type for the chart.
@@ -201,12 +233,13 @@ func (c *Manager) Icon(namespace, name, chartName, version string) (io.ReadClose
 		return nil, "", err
 	}
 
-	return helmhttp.Icon(secret, repo.status.URL, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, repo.spec.DisableSameOriginCheck, chart)
+	return helmhttp.Icon(secret, repo.status.URL, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, repo.spec.DisableSameOriginCheck, chart, c.proxy)
 }
 
 // Chart retrieves a specific Helm chart from a Helm repository.
 //
 // Retrieves the index file, fetches the helm chart and repository data.
+// Charts denied by the chart filter of the repository are never returned.
 // Check's the commit status of the repository
 //
 // If the commit status of the repository is not an empty string,
@@ -233,6 +266,12 @@ func (c *Manager) Chart(namespace, name, chartName, version string, skipFilter b
 		return nil, err
 	}
 
+	// The chart filter of the repository is enforced even if skipFilter is true, so that denied charts can never be
+	// downloaded nor installed. Like the index, charts are only filtered on released versions of Rancher.
+	if reason := chartFilterReason(repo.spec.ChartFilter, chartName); reason != "" && settings.IsRelease() {
+		return nil, apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("chart %s of repository %s is not available: %s", chartName, name, reason))
+	}
+
 	// If the commit status of the repository is not an empty string
 	// Return the Chart through Git without checking the secret
 	if repo.status.Commit != "" {
@@ -259,7 +298,7 @@ func (c *Manager) Chart(namespace, name, chartName, version string, skipFilter b
 	case registry.IsOCI(chart.URLs[0]):
 		return oci.Chart(secret, chart, *repo.spec)
 	default:
-		return helmhttp.Chart(secret, repo.status.URL, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, repo.spec.DisableSameOriginCheck, chart)
+		return helmhttp.Chart(secret, repo.status.URL, repo.spec.CABundle, repo.spec.InsecureSkipTLSverify, repo.spec.DisableSameOriginCheck, chart, c.proxy)
 	}
 }
 
@@ -267,7 +306,7 @@ func (c *Manager) Chart(namespace, name, chartName, version string, skipFilter b
 //
 // The function uses the Chart method to get the content of the Helm chart.
 // The Chart method is called with the skipFilter parameter hard-coded to true,
-// meaning that no filtering is applied to the results.
+// meaning that only the chart filter of the repository is applied.
 //
 // Once the chart content is retrieved, the function uses the InfoFromTarball method to extract detailed information.
 //
@@ -363,6 +402,22 @@ func deepCopyIndex(src *repo.IndexFile) *repo.IndexFile {
 	return &deepcopy
 }
 
+// filterIndex filters out the chart versions hidden by the filter: the ones that do not match the Rancher and Kubernetes
+// versions, and the ones the target cluster or the chart filter of the repository do not allow. If skipFilter is true,
+// no chart version is filtered out but the reasons why a chart version would have been are set in its
+// HiddenReasonsAnnotation annotation. Like filterReleases, chart versions are only filtered on released versions of Rancher.
+func (c *Manager) filterIndex(index *repo.IndexFile, filter releaseFilter, skipFilter bool) *repo.IndexFile {
+	if !settings.IsRelease() {
+		return index
+	}
+	filter.setRancherVersion()
+	if skipFilter {
+		return filter.apply(index, filter.hiddenReasons, true)
+	}
+	index = c.filterReleases(index, filter.k8sVersion, false)
+	return filter.apply(index, filter.targetReasons, false)
+}
+
 // filterReleases filters out any chart versions that do not match the Rancher and Kubernetes versions, if specified in the chart's annotations.
 // Returns the filtered or unfiltered IndexFile of a chart repository
 func (c *Manager) filterReleases(index *repo.IndexFile, k8sVersion *semver.Version, skipFilter bool) *repo.IndexFile {
@@ -376,73 +431,12 @@ func (c *Manager) filterReleases(index *repo.IndexFile, k8sVersion *semver.Versi
 		return index
 	}
 
-	// get instance of rancher version and try to parse it
-	rancherVersion, err := semver.NewVersion(settings.ServerVersion.Get())
-	if err != nil {
-		logrus.Errorf("failed to parse server version %s: %v", settings.ServerVersion.Get(), err)
+	filter := releaseFilter{k8sVersion: k8sVersion}
+	filter.setRancherVersion()
+	if filter.rancherVersion == nil {
 		return index
 	}
-	rancherVersionWithoutPrerelease, err := rancherVersion.SetPrerelease("")
-	if err != nil {
-		logrus.Errorf("failed to remove prerelease from %s: %v", settings.ServerVersion.Get(), err)
-		return index
-	}
-
-	for rel, versions := range index.Entries {
-		newVersions := make([]*repo.ChartVersion, 0, len(versions))
-		for _, version := range versions {
-			if constraintStr, ok := version.Annotations["catalog.cattle.io/rancher-version"]; ok {
-				if constraint, err := semver.NewConstraint(constraintStr); err == nil {
-					satisfiesConstraint, errs := constraint.Validate(rancherVersion)
-					// Check if the reason for failure is because it is ignroing prereleases
-					constraintDoesNotMatchPrereleases := false
-					for _, err := range errs {
-						// Comes from error in https://github.com/Masterminds/semver/blob/60c7ae8a99210a90a9457d5de5f6dcbc4dab8e64/constraints.go#L93
-						if strings.Contains(err.Error(), "the constraint is only looking for release versions") {
-							constraintDoesNotMatchPrereleases = true
-							break
-						}
-					}
-					if constraintDoesNotMatchPrereleases {
-						satisfiesConstraint = constraint.Check(&rancherVersionWithoutPrerelease)
-					}
-					if !satisfiesConstraint {
-						continue
-					}
-				} else {
-					logrus.Errorf("failed to parse constraint version %s: %v", constraintStr, err)
-				}
-			}
-			if constraintStr, ok := version.Annotations["catalog.cattle.io/kube-version"]; ok {
-				if constraint, err := semver.NewConstraint(constraintStr); err == nil {
-					if !constraint.Check(k8sVersion) {
-						continue
-					}
-				} else {
-					logrus.Errorf("failed to parse constraint kube-version %s from annotation: %v", constraintStr, err)
-				}
-			}
-			if version.KubeVersion != "" {
-				if constraint, err := semver.NewConstraint(version.KubeVersion); err == nil {
-					if !constraint.Check(k8sVersion) {
-						continue
-					}
-				} else {
-					logrus.Errorf("failed to parse constraint for kubeversion %s: %v", version.KubeVersion, err)
-				}
-
-			}
-			newVersions = append(newVersions, version)
-		}
-
-		if len(newVersions) == 0 {
-			delete(index.Entries, rel)
-		} else {
-			index.Entries[rel] = newVersions
-		}
-	}
-
-	return index
+	return filter.apply(index, filter.versionReasons, false)
 }
 
 // isHTTP - given a string, returns true if it is a valid HTTP or HTTPS URL; false otherwise.
@@ -0,0 +1,219 @@
+package content
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const (
+	// HiddenReasonsAnnotation is set on the chart versions of an index retrieved with skipFilter
+	// to the reasons why the chart version would have been filtered out, separated by "; ".
+	HiddenReasonsAnnotation = "catalog.cattle.io/hidden-reasons"
+
+	rancherVersionAnnotation = "catalog.cattle.io/rancher-version"
+	kubeVersionAnnotation    = "catalog.cattle.io/kube-version"
+	// permitsOSAnnotation is the comma separated list of the operating systems supported by a chart.
+	// Charts without this annotation only support linux.
+	permitsOSAnnotation = "catalog.cattle.io/permits-os"
+	// architecturesAnnotation is the comma separated list of the architectures supported by a chart.
+	// Charts without this annotation support every architecture.
+	architecturesAnnotation = "catalog.cattle.io/architectures"
+
+	defaultOS = "linux"
+)
+
+// releaseFilter holds the criteria used to filter out the chart versions of an index.
+type releaseFilter struct {
+	k8sVersion    *semver.Version
+	os            string
+	architectures []string
+	chartFilter   *v1.RepoChartFilter
+
+	// rancherVersion is nil when chart versions must not be filtered by Rancher and Kubernetes versions.
+	rancherVersion                  *semver.Version
+	rancherVersionWithoutPrerelease *semver.Version
+}
+
+// setRancherVersion sets the Rancher version chart versions are filtered by.
+// Chart versions are only filtered by Rancher and Kubernetes versions for released versions of Rancher.
+//
+// The method settings.IsRelease() checks two things:
+// 1. If the server version does not contain the "head" substring. If "head" is present, it means the server is not a released version.
+// 2. If the server version matches the releasePattern. A valid release version should start with "v" followed by a single digit, such as v1, v2, v3, etc.
+func (f *releaseFilter) setRancherVersion() {
+	f.rancherVersion, f.rancherVersionWithoutPrerelease = nil, nil
+	if !settings.IsRelease() {
+		return
+	}
+
+	// get instance of rancher version and try to parse it
+	rancherVersion, err := semver.NewVersion(settings.ServerVersion.Get())
+	if err != nil {
+		logrus.Errorf("failed to parse server version %s: %v", settings.ServerVersion.Get(), err)
+		return
+	}
+	rancherVersionWithoutPrerelease, err := rancherVersion.SetPrerelease("")
+	if err != nil {
+		logrus.Errorf("failed to remove prerelease from %s: %v", settings.ServerVersion.Get(), err)
+		return
+	}
+
+	f.rancherVersion, f.rancherVersionWithoutPrerelease = rancherVersion, &rancherVersionWithoutPrerelease
+}
+
+// apply filters out the chart versions for which reasons returns any reason. If annotate is true, no chart version is
+// filtered out but the reasons are set in the HiddenReasonsAnnotation annotation of the chart versions instead.
+func (f *releaseFilter) apply(index *repo.IndexFile, reasons func(chartName string, version *repo.ChartVersion) []string, annotate bool) *repo.IndexFile {
+	for rel, versions := range index.Entries {
+		newVersions := make([]*repo.ChartVersion, 0, len(versions))
+		for _, version := range versions {
+			if hidden := reasons(rel, version); len(hidden) > 0 {
+				if !annotate {
+					continue
+				}
+				annotations := make(map[string]string, len(version.Annotations)+1)
+				for k, v := range version.Annotations {
+					annotations[k] = v
+				}
+				annotations[HiddenReasonsAnnotation] = strings.Join(hidden, "; ")
+				version.Annotations = annotations
+			}
+			newVersions = append(newVersions, version)
+		}
+
+		if len(newVersions) == 0 {
+			delete(index.Entries, rel)
+		} else {
+			index.Entries[rel] = newVersions
+		}
+	}
+
+	return index
+}
+
+// hiddenReasons returns why the chart version must be filtered out, if any.
+func (f *releaseFilter) hiddenReasons(chartName string, version *repo.ChartVersion) []string {
+	return append(f.versionReasons(chartName, version), f.targetReasons(chartName, version)...)
+}
+
+// versionReasons returns the Rancher and Kubernetes versions the chart version requires, if they are not satisfied.
+func (f *releaseFilter) versionReasons(_ string, version *repo.ChartVersion) []string {
+	var reasons []string
+
+	if f.rancherVersion != nil {
+		if constraintStr, ok := version.Annotations[rancherVersionAnnotation]; ok && !f.satisfiesRancherVersion(constraintStr) {
+			reasons = append(reasons, fmt.Sprintf("requires Rancher version %s", constraintStr))
+		}
+		if constraintStr, ok := version.Annotations[kubeVersionAnnotation]; ok && !f.satisfiesK8sVersion(constraintStr) {
+			reasons = append(reasons, fmt.Sprintf("requires Kubernetes version %s", constraintStr))
+		}
+		if version.KubeVersion != "" && !f.satisfiesK8sVersion(version.KubeVersion) {
+			reasons = append(reasons, fmt.Sprintf("requires Kubernetes version %s", version.KubeVersion))
+		}
+	}
+
+	return reasons
+}
+
+// targetReasons returns why the target cluster or the chart filter of the repository do not allow the chart version,
+// if they don't.
+func (f *releaseFilter) targetReasons(chartName string, version *repo.ChartVersion) []string {
+	var reasons []string
+
+	if reason := chartFilterReason(f.chartFilter, chartName); reason != "" {
+		reasons = append(reasons, reason)
+	}
+
+	if f.os != "" {
+		permitsOS := defaultOS
+		if value, ok := version.Annotations[permitsOSAnnotation]; ok {
+			permitsOS = value
+		}
+		if !containsItem(permitsOS, f.os) {
+			reasons = append(reasons, fmt.Sprintf("does not support operating system %s", f.os))
+		}
+	}
+
+	if architectures, ok := version.Annotations[architecturesAnnotation]; ok {
+		for _, arch := range f.architectures {
+			if !containsItem(architectures, arch) {
+				reasons = append(reasons, fmt.Sprintf("does not support architecture %s", arch))
+			}
+		}
+	}
+
+	return reasons
+}
+
+func (f *releaseFilter) satisfiesRancherVersion(constraintStr string) bool {
+	constraint, err := semver.NewConstraint(constraintStr)
+	if err != nil {
+		logrus.Errorf("failed to parse constraint version %s: %v", constraintStr, err)
+		return true
+	}
+
+	satisfiesConstraint, errs := constraint.Validate(f.rancherVersion)
+	// Check if the reason for failure is because it is ignroing prereleases
+	for _, err := range errs {
+		// Comes from error in https://github.com/Masterminds/semver/blob/60c7ae8a99210a90a9457d5de5f6dcbc4dab8e64/constraints.go#L93
+		if strings.Contains(err.Error(), "the constraint is only looking for release versions") {
+			return constraint.Check(f.rancherVersionWithoutPrerelease)
+		}
+	}
+	return satisfiesConstraint
+}
+
+func (f *releaseFilter) satisfiesK8sVersion(constraintStr string) bool {
+	constraint, err := semver.NewConstraint(constraintStr)
+	if err != nil {
+		logrus.Errorf("failed to parse constraint kube-version %s: %v", constraintStr, err)
+		return true
+	}
+	return constraint.Check(f.k8sVersion)
+}
+
+// IsChartAllowed returns true if the chart filter of a repository allows the chart.
+func IsChartAllowed(chartFilter *v1.RepoChartFilter, chartName string) bool {
+	return chartFilterReason(chartFilter, chartName) == ""
+}
+
+// chartFilterReason returns why the chart is not available according to the chart filter of its repository, if any.
+func chartFilterReason(chartFilter *v1.RepoChartFilter, chartName string) string {
+	if chartFilter == nil {
+		return ""
+	}
+	if matchesAny(chartFilter.Deny, chartName) {
+		return "denied by the chart filter of the repository"
+	}
+	if len(chartFilter.Allow) > 0 && !matchesAny(chartFilter.Allow, chartName) {
+		return "not allowed by the chart filter of the repository"
+	}
+	return ""
+}
+
+// matchesAny returns true if the name matches any of the shell patterns.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// containsItem returns true if the comma separated list contains the item.
+func containsItem(list, item string) bool {
+	for _, value := range strings.Split(list, ",") {
+		if strings.TrimSpace(value) == item {
+			return true
+		}
+	}
+	return false
+}
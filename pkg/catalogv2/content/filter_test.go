@@ -0,0 +1,142 @@
+package content
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestFilterHiddenReasons(t *testing.T) {
+	tests := []struct {
+		name        string
+		chartName   string
+		annotations map[string]string
+		filter      releaseFilter
+		expected    []string
+	}{
+		{
+			name:      "no criteria",
+			chartName: "test-chart",
+		},
+		{
+			name:      "rancher and kubernetes versions not satisfied",
+			chartName: "test-chart",
+			annotations: map[string]string{
+				rancherVersionAnnotation: "< 2.8.0",
+				kubeVersionAnnotation:    "< 1.20.0",
+			},
+			filter:   releaseFilter{k8sVersion: semver.MustParse("v1.30.0")},
+			expected: []string{"requires Rancher version < 2.8.0", "requires Kubernetes version < 1.20.0"},
+		},
+		{
+			name:      "charts without permits-os only support linux",
+			chartName: "test-chart",
+			filter:    releaseFilter{os: "windows"},
+			expected:  []string{"does not support operating system windows"},
+		},
+		{
+			name:      "permits-os satisfied",
+			chartName: "test-chart",
+			annotations: map[string]string{
+				permitsOSAnnotation: "linux, windows",
+			},
+			filter: releaseFilter{os: "windows"},
+		},
+		{
+			name:      "charts without architectures support every architecture",
+			chartName: "test-chart",
+			filter:    releaseFilter{architectures: []string{"arm64"}},
+		},
+		{
+			name:      "architecture not supported",
+			chartName: "test-chart",
+			annotations: map[string]string{
+				architecturesAnnotation: "amd64",
+			},
+			filter:   releaseFilter{architectures: []string{"amd64", "arm64"}},
+			expected: []string{"does not support architecture arm64"},
+		},
+		{
+			name:      "chart denied by the repository",
+			chartName: "rancher-test",
+			filter: releaseFilter{chartFilter: &v1.RepoChartFilter{
+				Allow: []string{"rancher-*"},
+				Deny:  []string{"rancher-test"},
+			}},
+			expected: []string{"denied by the chart filter of the repository"},
+		},
+		{
+			name:      "chart not allowed by the repository",
+			chartName: "test-chart",
+			filter: releaseFilter{chartFilter: &v1.RepoChartFilter{
+				Allow: []string{"rancher-*"},
+			}},
+			expected: []string{"not allowed by the chart filter of the repository"},
+		},
+		{
+			name:      "chart allowed by the repository",
+			chartName: "rancher-monitoring",
+			filter: releaseFilter{chartFilter: &v1.RepoChartFilter{
+				Allow: []string{"rancher-*"},
+			}},
+		},
+	}
+
+	settings.ServerVersion.Set("v2.9.0")
+	defer settings.ServerVersion.Set("")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version := &repo.ChartVersion{
+				Metadata: &chart.Metadata{
+					Name:        tt.chartName,
+					Version:     "1.0.0",
+					Annotations: tt.annotations,
+				},
+			}
+
+			tt.filter.setRancherVersion()
+			assert.Equal(t, tt.expected, tt.filter.hiddenReasons(tt.chartName, version))
+		})
+	}
+}
+
+func TestFilterIndex(t *testing.T) {
+	original := map[string]string{permitsOSAnnotation: "linux"}
+	index := &repo.IndexFile{
+		Entries: map[string]repo.ChartVersions{
+			"test-chart": {
+				{Metadata: &chart.Metadata{Name: "test-chart", Version: "1.0.0", Annotations: original}},
+			},
+			"other-chart": {
+				{Metadata: &chart.Metadata{Name: "other-chart", Version: "1.0.0", Annotations: map[string]string{permitsOSAnnotation: "windows"}}},
+			},
+		},
+	}
+	filter := releaseFilter{os: "windows"}
+	contentManager := Manager{}
+
+	settings.ServerVersion.Set("v2.9.0")
+	defer settings.ServerVersion.Set("")
+
+	filtered := contentManager.filterIndex(deepCopyIndex(index), filter, false)
+	assert.NotContains(t, filtered.Entries, "test-chart")
+	assert.Contains(t, filtered.Entries, "other-chart")
+
+	unfiltered := contentManager.filterIndex(deepCopyIndex(index), filter, true)
+	assert.Len(t, unfiltered.Entries, 2)
+	assert.Equal(t, "does not support operating system windows", unfiltered.Entries["test-chart"][0].Annotations[HiddenReasonsAnnotation])
+	assert.NotContains(t, unfiltered.Entries["other-chart"][0].Annotations, HiddenReasonsAnnotation)
+	// the annotations of the cached index must not be modified
+	assert.NotContains(t, original, HiddenReasonsAnnotation)
+
+	// chart versions are not filtered on non-released versions of Rancher
+	settings.ServerVersion.Set("dev")
+	assert.Equal(t, deepCopyIndex(index), contentManager.filterIndex(deepCopyIndex(index), filter, false))
+	assert.Equal(t, deepCopyIndex(index), contentManager.filterIndex(deepCopyIndex(index), filter, true))
+}
@@ -0,0 +1,114 @@
+package helmop
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LogSecretType is the type of the secrets storing the logs of completed helm operations.
+	LogSecretType      corev1.SecretType = "catalog.cattle.io/operation-log"
+	logSecretKey                         = "log"
+	truncatedLogHeader                   = "[log truncated]\n"
+)
+
+// NewLogSecret reads the log of an operation and returns a secret, owned by the operation, that stores it gzipped.
+// Only the last maxSize bytes of the log are kept.
+func NewLogSecret(op *catalog.Operation, logReader io.Reader, maxSize int) (*corev1.Secret, error) {
+	log, truncated, err := readTail(logReader, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		log = append([]byte(truncatedLogHeader), log...)
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	if _, err := gz.Write(log); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      LogSecretName(op),
+			Namespace: op.Namespace,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: catalog.SchemeGroupVersion.String(),
+				Kind:       "Operation",
+				Name:       op.Name,
+				UID:        op.UID,
+			}},
+		},
+		Type: LogSecretType,
+		Data: map[string][]byte{
+			logSecretKey: buf.Bytes(),
+		},
+	}, nil
+}
+
+// LogSecretName returns the name of the secret storing the log of the operation.
+func LogSecretName(op *catalog.Operation) string {
+	return name.SafeConcatName(op.Name, "log")
+}
+
+// ReadLogSecret returns the log of an operation stored in the given secret.
+func ReadLogSecret(secret *corev1.Secret) ([]byte, error) {
+	if secret.Type != LogSecretType {
+		return nil, fmt.Errorf("secret %s/%s is not an operation log", secret.Namespace, secret.Name)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(secret.Data[logSecretKey]))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	return io.ReadAll(gz)
+}
+
+// readTail reads everything from the reader and returns the last maxSize bytes read,
+// along with whether anything was dropped. A maxSize lower than 1 means no limit.
+func readTail(r io.Reader, maxSize int) ([]byte, bool, error) {
+	if maxSize < 1 {
+		log, err := io.ReadAll(r)
+		return log, false, err
+	}
+
+	var (
+		log       []byte
+		truncated bool
+		chunk     = make([]byte, 32*1024)
+	)
+	for {
+		n, err := r.Read(chunk)
+		log = append(log, chunk[:n]...)
+		// Trim lazily to avoid copying the tail for every chunk read.
+		if len(log) > 2*maxSize {
+			log = append(log[:0], log[len(log)-maxSize:]...)
+			truncated = true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if len(log) > maxSize {
+		log = log[len(log)-maxSize:]
+		truncated = true
+	}
+	return log, truncated, nil
+}
@@ -0,0 +1,76 @@
+package helmop
+
+import (
+	"strings"
+	"testing"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLogSecret(t *testing.T) {
+	op := &catalog.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "helm-operation-abcde",
+			Namespace: "test-ns",
+			UID:       "1234",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		log      string
+		maxSize  int
+		expected string
+	}{
+		{
+			name:     "log smaller than max size is kept",
+			log:      "helm install\nSUCCESS\n",
+			maxSize:  1024,
+			expected: "helm install\nSUCCESS\n",
+		},
+		{
+			name:     "log bigger than max size is truncated from the start",
+			log:      "helm install\nSUCCESS\n",
+			maxSize:  8,
+			expected: truncatedLogHeader + "SUCCESS\n",
+		},
+		{
+			name:     "log much bigger than max size is truncated from the start",
+			log:      strings.Repeat("a", 100*1024) + "SUCCESS\n",
+			maxSize:  8,
+			expected: truncatedLogHeader + "SUCCESS\n",
+		},
+		{
+			name:     "no max size keeps the whole log",
+			log:      "helm install\nSUCCESS\n",
+			maxSize:  0,
+			expected: "helm install\nSUCCESS\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := NewLogSecret(op, strings.NewReader(tt.log), tt.maxSize)
+			require.NoError(t, err)
+
+			assert.Equal(t, "helm-operation-abcde-log", secret.Name)
+			assert.Equal(t, op.Namespace, secret.Namespace)
+			assert.Equal(t, LogSecretType, secret.Type)
+			require.Len(t, secret.OwnerReferences, 1)
+			assert.Equal(t, op.UID, secret.OwnerReferences[0].UID)
+
+			log, err := ReadLogSecret(secret)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(log))
+		})
+	}
+}
+
+func TestReadLogSecretWrongType(t *testing.T) {
+	_, err := ReadLogSecret(&corev1.Secret{Type: corev1.SecretTypeOpaque})
+	assert.Error(t, err)
+}
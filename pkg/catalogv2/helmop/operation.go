@@ -8,6 +8,7 @@
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/rancher/rancher/pkg/taints"
@@ -22,6 +23,7 @@
 	"time"
 	"unicode/utf8"
 
+	"github.com/gorilla/websocket"
 	"github.com/rancher/apiserver/pkg/types"
 	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
@@ -38,6 +40,7 @@
 	rbacv1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
 	"github.com/rancher/wrangler/v3/pkg/name"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -55,6 +58,9 @@
 	// helmDataPath contains the files such as values.yaml for a given chart and tar of the chart.
 	helmDataPath = "/home/shell/helm"
 	helmRunPath  = "/home/shell/helm-run"
+
+	// base64BinaryWebsocketProtocol is the websocket protocol used by the Kubernetes API server to stream pod logs.
+	base64BinaryWebsocketProtocol = "base64.binary.k8s.io"
 )
 
 var (
@@ -152,6 +158,7 @@ type Operations struct {
 	clusterRepos   catalogcontrollers.ClusterRepoClient // client for cluster repo custom resource
 	ops            catalogcontrollers.OperationClient   // client for operation custom resource
 	pods           corev1controllers.PodClient          // client for pod kubernetes resource
+	secrets        corev1controllers.SecretClient       // client for secret kubernetes resource, used to read the logs of completed operations
 	nodes          corev1controllers.NodeClient
 	apps           catalogcontrollers.AppClient        // client for apps custom resource
 	roles          rbacv1controllers.RoleClient        // client for role kubernetes resource
@@ -166,6 +173,7 @@ func NewOperations(
 	rbac rbacv1controllers.Interface,
 	contentManager *content.Manager,
 	pods corev1controllers.PodClient,
+	secrets corev1controllers.SecretClient,
 	nodes corev1controllers.NodeClient) *Operations {
 	return &Operations{
 		cg:             cg,
@@ -173,6 +181,7 @@ func NewOperations(
 		namespace:      namespaces.System,
 		Impersonator:   podimpersonation.New("helm-op", cg, time.Hour, settings.FullShellImage),
 		pods:           pods,
+		secrets:        secrets,
 		clusterRepos:   catalog.ClusterRepo(),
 		ops:            catalog.Operation(),
 		apps:           catalog.App(),
@@ -297,14 +306,61 @@ func (s *Operations) proxyLogRequest(rw http.ResponseWriter, req *http.Request,
 	return nil
 }
 
+// writePersistedLog writes the log stored when the operation completed to the given http.ResponseWriter.
+// Websocket requests, as made when following the log of a running operation, get the whole log as a single
+// message encoded as the Kubernetes API server does for the base64 websocket protocol.
+func (s *Operations) writePersistedLog(rw http.ResponseWriter, req *http.Request, op *catalog.Operation) error {
+	secret, err := s.secrets.Get(op.Namespace, op.Status.LogSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// check that the secret was created for this operation
+	if len(secret.OwnerReferences) == 0 || secret.OwnerReferences[0].UID != op.UID {
+		return validation.NotFound
+	}
+
+	log, err := ReadLogSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	if !websocket.IsWebSocketUpgrade(req) {
+		rw.Header().Set("Content-Type", "text/plain")
+		_, err = rw.Write(log)
+		return err
+	}
+
+	upgrader := websocket.Upgrader{
+		HandshakeTimeout: 5 * time.Second,
+		CheckOrigin:      func(r *http.Request) bool { return true },
+		Subprotocols:     []string{base64BinaryWebsocketProtocol},
+	}
+	conn, err := upgrader.Upgrade(rw, req, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(base64.StdEncoding.EncodeToString(log))); err != nil {
+		return err
+	}
+	return conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
 // Log receives a response writer, a http request, the namespace and name of an operation.
-// Gets the pod of the operation and proxies the request to get logs of said pod
+// Once the operation completed, writes the log that was stored for it.
+// Otherwise, gets the pod of the operation and proxies the request to get logs of said pod
 func (s *Operations) Log(rw http.ResponseWriter, req *http.Request, namespace, name string) error {
 	op, err := s.ops.Get(namespace, name, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
+	if op.Status.LogSecretName != "" {
+		return s.writePersistedLog(rw, req, op)
+	}
+
 	pod, err := s.pods.Get(op.Status.PodNamespace, op.Status.PodName, metav1.GetOptions{})
 	if err != nil {
 		return err
@@ -726,7 +782,7 @@ func (s *Operations) enableKustomize(annotations map[string]string, upgrade bool
 	return true
 }
 
-// getChartCommand gets the chart based on the input, inject the annotations into it
+// getChartCommand gets the chart based on the input, validates the values against it, inject the annotations into it
 // and then creates and return a Command containing the name of the values file, name of the chart file, the chart data
 // and if the command should use kustomize.sh
 func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion string, upgrade bool, annotations map[string]string, values map[string]interface{}) (Command, error) {
@@ -740,6 +796,14 @@ func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion st
 		return Command{}, err
 	}
 
+	helmChart, err := loader.LoadArchive(bytes.NewReader(chartData))
+	if err != nil {
+		return Command{}, fmt.Errorf("failed to load chart %s:%s: %w", chartName, chartVersion, err)
+	}
+	if err := validateValues(helmChart, values); err != nil {
+		return Command{}, err
+	}
+
 	chartData, err = injectAnnotation(chartData, annotations)
 	if err != nil {
 		return Command{}, err
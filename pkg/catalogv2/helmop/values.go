@@ -0,0 +1,248 @@
+package helmop
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v2"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// questionsFiles are the names of the Rancher questions file at the root of a chart, in order of precedence.
+var questionsFiles = []string{"questions.yaml", "questions.yml"}
+
+// FieldError is a validation error of the value of a single field of the values of a chart.
+type FieldError struct {
+	// Chart is the name of the chart whose values are invalid.
+	Chart string `json:"chart"`
+	// Field is the path of the invalid field in the values, ie. "ingress.tls.source".
+	Field string `json:"field"`
+	// Message describes why the value of the field is invalid.
+	Message string `json:"message"`
+}
+
+// ValuesValidationError is returned when the values of a chart operation don't satisfy
+// the values.schema.json or the required questions of the chart.
+type ValuesValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValuesValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s: %s", fieldErr.Chart, fieldErr.Field, fieldErr.Message))
+	}
+	return "invalid values: " + strings.Join(msgs, "; ")
+}
+
+type questions struct {
+	Questions []v3.Question `yaml:"questions,omitempty"`
+}
+
+// validateValues validates the values given by the user, merged with the default values of the chart,
+// against the values.schema.json of the chart and its dependencies, and checks that every required
+// question of the questions.yaml of the chart that is shown has a value.
+// A *ValuesValidationError is returned if the values are invalid.
+func validateValues(chrt *chart.Chart, values map[string]interface{}) error {
+	// Like Helm, the dependencies disabled by the values are removed from the chart before validating.
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, values); err != nil {
+		return fmt.Errorf("failed to process the dependencies of chart %s: %w", chrt.Name(), err)
+	}
+
+	coalesced, err := chartutil.CoalesceValues(chrt, values)
+	if err != nil {
+		return fmt.Errorf("failed to merge the values of chart %s: %w", chrt.Name(), err)
+	}
+
+	fieldErrs, err := validateSchema(chrt, coalesced, "")
+	if err != nil {
+		return err
+	}
+
+	questionErrs, err := validateQuestions(chrt, coalesced)
+	if err != nil {
+		return err
+	}
+	fieldErrs = append(fieldErrs, questionErrs...)
+
+	if len(fieldErrs) > 0 {
+		return &ValuesValidationError{Errors: fieldErrs}
+	}
+	return nil
+}
+
+// validateSchema validates the values against the schema of the chart and, like Helm, against the schema
+// of every dependency with the values nested under the name of the dependency.
+func validateSchema(chrt *chart.Chart, values map[string]interface{}, prefix string) ([]FieldError, error) {
+	var fieldErrs []FieldError
+
+	if len(chrt.Schema) > 0 {
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(chrt.Schema), gojsonschema.NewBytesLoader(valuesJSON))
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate the values of chart %s against its schema: %w", chrt.Name(), err)
+		}
+		for _, resultErr := range result.Errors() {
+			fieldErrs = append(fieldErrs, FieldError{
+				Chart:   chrt.Name(),
+				Field:   joinField(prefix, schemaErrorField(resultErr)),
+				Message: resultErr.Description(),
+			})
+		}
+	}
+
+	for _, dependency := range chrt.Dependencies() {
+		dependencyValues, _ := values[dependency.Name()].(map[string]interface{})
+		if dependencyValues == nil {
+			dependencyValues = map[string]interface{}{}
+		}
+		dependencyErrs, err := validateSchema(dependency, dependencyValues, joinField(prefix, dependency.Name()))
+		if err != nil {
+			return nil, err
+		}
+		fieldErrs = append(fieldErrs, dependencyErrs...)
+	}
+
+	return fieldErrs, nil
+}
+
+// schemaErrorField returns the path of the field a schema error is about. Errors about missing
+// required properties are reported by gojsonschema on the parent object, so the property is appended.
+func schemaErrorField(resultErr gojsonschema.ResultError) string {
+	field := resultErr.Field()
+	if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+		field = ""
+	}
+	if resultErr.Type() == "required" {
+		if property, ok := resultErr.Details()["property"].(string); ok {
+			field = joinField(field, property)
+		}
+	}
+	return field
+}
+
+// validateQuestions checks that every required question, and required subquestion, of the questions.yaml
+// of the chart which is shown given the values has a value. Questions with a default are always satisfied.
+func validateQuestions(chrt *chart.Chart, values map[string]interface{}) ([]FieldError, error) {
+	var data []byte
+	for _, name := range questionsFiles {
+		for _, file := range chrt.Files {
+			if file.Name == name {
+				data = file.Data
+				break
+			}
+		}
+		if data != nil {
+			break
+		}
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var q questions
+	if err := yaml.Unmarshal(data, &q); err != nil {
+		return nil, fmt.Errorf("failed to parse the questions of chart %s: %w", chrt.Name(), err)
+	}
+
+	var fieldErrs []FieldError
+	for _, question := range q.Questions {
+		if !showIf(question.ShowIf, values) {
+			continue
+		}
+		if question.Required && question.Default == "" && !hasValue(values, question.Variable) {
+			fieldErrs = append(fieldErrs, requiredQuestionError(chrt, question.Variable))
+		}
+
+		if len(question.Subquestions) == 0 ||
+			(question.ShowSubquestionIf != "" && valueString(values, question.Variable) != question.ShowSubquestionIf) {
+			continue
+		}
+		for _, subquestion := range question.Subquestions {
+			if !showIf(subquestion.ShowIf, values) {
+				continue
+			}
+			if subquestion.Required && subquestion.Default == "" && !hasValue(values, subquestion.Variable) {
+				fieldErrs = append(fieldErrs, requiredQuestionError(chrt, subquestion.Variable))
+			}
+		}
+	}
+
+	return fieldErrs, nil
+}
+
+func requiredQuestionError(chrt *chart.Chart, variable string) FieldError {
+	return FieldError{
+		Chart:   chrt.Name(),
+		Field:   variable,
+		Message: "a value is required",
+	}
+}
+
+// showIf evaluates a show_if expression of a question, ie. "a=true&&b.c=value||d=other", against the values.
+// An empty expression is always true.
+func showIf(expression string, values map[string]interface{}) bool {
+	if expression == "" {
+		return true
+	}
+
+	for _, or := range strings.Split(expression, "||") {
+		matches := true
+		for _, and := range strings.Split(or, "&&") {
+			key, expected, ok := strings.Cut(and, "=")
+			if !ok || valueString(values, strings.TrimSpace(key)) != strings.TrimSpace(expected) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the value of the field at the given dotted path in the values.
+func lookup(values map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func hasValue(values map[string]interface{}, path string) bool {
+	value, ok := lookup(values, path)
+	return ok && value != nil && value != ""
+}
+
+func valueString(values map[string]interface{}, path string) string {
+	value, ok := lookup(values, path)
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func joinField(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
@@ -0,0 +1,183 @@
+package helmop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {
+        "repository": {"type": "string"}
+      }
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`
+
+const testQuestions = `questions:
+- variable: hostname
+  required: true
+- variable: ingress.enabled
+  default: "false"
+  show_subquestion_if: true
+  subquestions:
+  - variable: ingress.host
+    required: true
+- variable: tls.source
+  required: true
+  default: rancher
+- variable: tls.secretName
+  required: true
+  show_if: tls.source=secret&&ingress.enabled=true
+`
+
+func TestValidateValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		chart    func() *chart.Chart
+		values   map[string]interface{}
+		expected []FieldError
+	}{
+		{
+			name:  "chart without schema nor questions accepts any values",
+			chart: func() *chart.Chart { return testChart("", "") },
+			values: map[string]interface{}{
+				"anything": true,
+			},
+		},
+		{
+			name:  "values satisfying the schema",
+			chart: func() *chart.Chart { return testChart(testSchema, "") },
+			values: map[string]interface{}{
+				"image":    map[string]interface{}{"repository": "rancher/test"},
+				"replicas": 2,
+			},
+		},
+		{
+			name:  "values not satisfying the schema",
+			chart: func() *chart.Chart { return testChart(testSchema, "") },
+			values: map[string]interface{}{
+				"image":    map[string]interface{}{},
+				"replicas": 0,
+			},
+			expected: []FieldError{
+				{Chart: "test", Field: "image.repository", Message: "repository is required"},
+				{Chart: "test", Field: "replicas", Message: "Must be greater than or equal to 1"},
+			},
+		},
+		{
+			name:   "missing required question",
+			chart:  func() *chart.Chart { return testChart("", testQuestions) },
+			values: map[string]interface{}{},
+			expected: []FieldError{
+				{Chart: "test", Field: "hostname", Message: "a value is required"},
+			},
+		},
+		{
+			name:  "missing required subquestion and shown question",
+			chart: func() *chart.Chart { return testChart("", testQuestions) },
+			values: map[string]interface{}{
+				"hostname": "rancher.test",
+				"ingress":  map[string]interface{}{"enabled": true},
+				"tls":      map[string]interface{}{"source": "secret"},
+			},
+			expected: []FieldError{
+				{Chart: "test", Field: "ingress.host", Message: "a value is required"},
+				{Chart: "test", Field: "tls.secretName", Message: "a value is required"},
+			},
+		},
+		{
+			name:  "all required questions answered",
+			chart: func() *chart.Chart { return testChart("", testQuestions) },
+			values: map[string]interface{}{
+				"hostname": "rancher.test",
+				"ingress":  map[string]interface{}{"enabled": true, "host": "rancher.test"},
+				"tls":      map[string]interface{}{"source": "secret", "secretName": "tls-rancher"},
+			},
+		},
+		{
+			name: "default values of the chart are validated",
+			chart: func() *chart.Chart {
+				c := testChart(testSchema, "")
+				c.Values = map[string]interface{}{
+					"image": map[string]interface{}{"repository": "rancher/test"},
+				}
+				return c
+			},
+			values: map[string]interface{}{},
+		},
+		{
+			name: "values of dependencies are validated against their schema",
+			chart: func() *chart.Chart {
+				c := testChart("", "")
+				c.AddDependency(testChart(testSchema, ""))
+				c.Dependencies()[0].Metadata.Name = "sub"
+				return c
+			},
+			values: map[string]interface{}{
+				"sub": map[string]interface{}{
+					"image": map[string]interface{}{"repository": 1},
+				},
+			},
+			expected: []FieldError{
+				{Chart: "sub", Field: "sub.image.repository", Message: "Invalid type. Expected: string, given: integer"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateValues(tt.chart(), tt.values)
+			if tt.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var valuesErr *ValuesValidationError
+			require.ErrorAs(t, err, &valuesErr)
+			assert.Equal(t, tt.expected, valuesErr.Errors)
+		})
+	}
+}
+
+func TestShowIf(t *testing.T) {
+	values := map[string]interface{}{
+		"a": true,
+		"b": map[string]interface{}{"c": "value"},
+	}
+
+	assert.True(t, showIf("", values))
+	assert.True(t, showIf("a=true", values))
+	assert.True(t, showIf("a=true&&b.c=value", values))
+	assert.False(t, showIf("a=false&&b.c=value", values))
+	assert.True(t, showIf("a=false||b.c=value", values))
+	assert.False(t, showIf("d=true", values))
+	assert.False(t, showIf("invalid", values))
+}
+
+func testChart(schema, questions string) *chart.Chart {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "test",
+			Version:    "1.0.0",
+		},
+	}
+	if schema != "" {
+		c.Schema = []byte(schema)
+	}
+	if questions != "" {
+		c.Files = append(c.Files, &chart.File{Name: "questions.yaml", Data: []byte(questions)})
+	}
+	return c
+}
@@ -13,7 +13,12 @@
 	corev1 "k8s.io/api/core/v1"
 )
 
-func HelmClient(secret *corev1.Secret, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, repoURL string) (*http.Client, error) {
+// ProxyFunc returns the proxy of a request, as the Proxy of a http.Transport.
+type ProxyFunc func(*http.Request) (*url.URL, error)
+
+// HelmClient returns the client of a Helm repository, connecting through the proxy returned by proxy, or through the
+// proxy of the environment if proxy is nil.
+func HelmClient(secret *corev1.Secret, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, repoURL string, proxy ProxyFunc) (*http.Client, error) {
 	var (
 		username  string
 		password  string
@@ -50,6 +55,10 @@ func HelmClient(secret *corev1.Secret, caBundle []byte, insecureSkipTLSVerify bo
 
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	transport.TLSClientConfig = &tlsConfig
+	transport.Proxy = http.ProxyFromEnvironment
+	if proxy != nil {
+		transport.Proxy = proxy
+	}
 	transport.TLSClientConfig.InsecureSkipVerify = insecureSkipTLSVerify
 
 	client := &http.Client{
@@ -21,12 +21,12 @@
 	corev1 "k8s.io/api/core/v1"
 )
 
-func Icon(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, chart *repo.ChartVersion) (io.ReadCloser, string, error) {
+func Icon(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, chart *repo.ChartVersion, proxy ProxyFunc) (io.ReadCloser, string, error) {
 	if len(chart.URLs) == 0 {
 		return nil, "", fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
 	}
 
-	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL)
+	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL, proxy)
 	client.Timeout = 2 * time.Second
 	if err != nil {
 		return nil, "", err
@@ -67,12 +67,12 @@ func Icon(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTL
 	return ioutil.NopCloser(bytes.NewBuffer(data)), path.Ext(u.Path), nil
 }
 
-func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, chart *repo.ChartVersion) (io.ReadCloser, error) {
+func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, chart *repo.ChartVersion, proxy ProxyFunc) (io.ReadCloser, error) {
 	if len(chart.URLs) == 0 {
 		return nil, fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
 	}
 
-	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL)
+	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL, proxy)
 	if err != nil {
 		return nil, err
 	}
@@ -106,8 +106,8 @@ func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipT
 	return ioutil.NopCloser(bytes.NewBuffer(data)), err
 }
 
-func DownloadIndex(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool) (*repo.IndexFile, error) {
-	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL)
+func DownloadIndex(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, proxy ProxyFunc) (*repo.IndexFile, error) {
+	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL, proxy)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,122 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart"
+	helmregistry "helm.sh/helm/v3/pkg/registry"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// VersionTag returns the OCI tag of a chart version. OCI tags cannot contain
+// the "+" character of semver build metadata, so like Helm it is replaced by "_".
+func VersionTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// GetChartOrasRepository returns the oras repository client of the repository
+// a chart is pushed into. Like Helm, the chart name is always the last part of the repository.
+func (o *Client) GetChartOrasRepository(chartName string) (*remote.Repository, error) {
+	chartClient := *o
+	chartClient.repository = path.Join(o.repository, chartName)
+	return chartClient.GetOrasRepository()
+}
+
+// ChartLayerDigest returns the digest of the Helm chart layer of the OCI artifact
+// tagged with the given tag. An empty digest is returned if the tag doesn't exist.
+func ChartLayerDigest(ctx context.Context, orasRepository *remote.Repository, tag string) (digest.Digest, error) {
+	manifestDesc, err := orasRepository.Resolve(ctx, tag)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) || IsErrorCode(err, errcode.ErrorCodeManifestUnknown) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to resolve tag %s: %w", tag, err)
+	}
+	if manifestDesc.MediaType != ocispecv1.MediaTypeImageManifest {
+		return "", fmt.Errorf("the oci artifact tagged %s is not a helm chart", tag)
+	}
+
+	manifestBlob, err := content.FetchAll(ctx, orasRepository, manifestDesc)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch the manifest blob of tag %s: %w", tag, err)
+	}
+	var manifest ocispecv1.Manifest
+	if err := json.Unmarshal(manifestBlob, &manifest); err != nil {
+		return "", fmt.Errorf("unable to unmarshal manifest blob of tag %s: %w", tag, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == helmregistry.ChartLayerMediaType {
+			return layer.Digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("the oci artifact tagged %s has no helm chart layer", tag)
+}
+
+// PushChart pushes the given Helm chart tar as an OCI artifact into the oras repository
+// and tags it with the chart version. The artifact has the same layout as the one pushed
+// by `helm push`, so that it can be consumed by both Helm and OCI ClusterRepos.
+func PushChart(ctx context.Context, orasRepository *remote.Repository, metadata *chart.Metadata, chartTar []byte) (ocispecv1.Descriptor, error) {
+	if int64(len(chartTar)) > maxHelmChartTarSize {
+		return ocispecv1.Descriptor{}, fmt.Errorf("the chart %s:%s has size more than %d which is not supported", metadata.Name, metadata.Version, maxHelmChartTarSize)
+	}
+
+	configBlob, err := json.Marshal(metadata)
+	if err != nil {
+		return ocispecv1.Descriptor{}, fmt.Errorf("unable to marshal the metadata of chart %s:%s: %w", metadata.Name, metadata.Version, err)
+	}
+
+	configDesc := content.NewDescriptorFromBytes(helmregistry.ConfigMediaType, configBlob)
+	if err := pushBlob(ctx, orasRepository, configDesc, configBlob); err != nil {
+		return ocispecv1.Descriptor{}, fmt.Errorf("unable to push the config blob of chart %s:%s: %w", metadata.Name, metadata.Version, err)
+	}
+
+	layerDesc := content.NewDescriptorFromBytes(helmregistry.ChartLayerMediaType, chartTar)
+	if err := pushBlob(ctx, orasRepository, layerDesc, chartTar); err != nil {
+		return ocispecv1.Descriptor{}, fmt.Errorf("unable to push the chart blob of chart %s:%s: %w", metadata.Name, metadata.Version, err)
+	}
+
+	manifestDesc, err := oras.PackManifest(ctx, orasRepository, oras.PackManifestVersion1_0, "", oras.PackManifestOptions{
+		ConfigDescriptor: &configDesc,
+		Layers:           []ocispecv1.Descriptor{layerDesc},
+	})
+	if err != nil {
+		return ocispecv1.Descriptor{}, fmt.Errorf("unable to push the manifest of chart %s:%s: %w", metadata.Name, metadata.Version, err)
+	}
+
+	if err := orasRepository.Tag(ctx, manifestDesc, VersionTag(metadata.Version)); err != nil {
+		return ocispecv1.Descriptor{}, fmt.Errorf("unable to tag chart %s with %s: %w", metadata.Name, metadata.Version, err)
+	}
+
+	return manifestDesc, nil
+}
+
+// pushBlob pushes the blob into the oras repository unless it is already present.
+func pushBlob(ctx context.Context, orasRepository *remote.Repository, desc ocispecv1.Descriptor, blob []byte) error {
+	exists, err := orasRepository.Exists(ctx, desc)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	err = orasRepository.Push(ctx, desc, bytes.NewReader(blob))
+	if err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// spinPushableRegistry starts an in-memory OCI registry supporting the
+// subset of the distribution API used to push and resolve Helm charts.
+func spinPushableRegistry(t *testing.T) *httptest.Server {
+	var (
+		lock      sync.Mutex
+		blobs     = map[string][]byte{}
+		manifests = map[string][]byte{}
+		mediaType = map[string]string{}
+	)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		path := r.URL.Path
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/blobs/uploads/"):
+			w.Header().Set("Location", path+"upload")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && strings.HasSuffix(path, "/blobs/uploads/upload"):
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			blobs[r.URL.Query().Get("digest")] = data
+			w.WriteHeader(http.StatusCreated)
+		case strings.Contains(path, "/blobs/"):
+			data, ok := blobs[path[strings.LastIndex(path, "/")+1:]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			if r.Method == http.MethodGet {
+				w.Write(data)
+			}
+		case r.Method == http.MethodPut && strings.Contains(path, "/manifests/"):
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			dgst := digest.FromBytes(data).String()
+			ref := path[strings.LastIndex(path, "/")+1:]
+			manifests[ref], manifests[dgst] = data, data
+			mediaType[ref], mediaType[dgst] = r.Header.Get("Content-Type"), r.Header.Get("Content-Type")
+			w.Header().Set("Docker-Content-Digest", dgst)
+			w.WriteHeader(http.StatusCreated)
+		case strings.Contains(path, "/manifests/"):
+			ref := path[strings.LastIndex(path, "/")+1:]
+			data, ok := manifests[ref]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", mediaType[ref])
+			w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			if r.Method == http.MethodGet {
+				w.Write(data)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestPushChart(t *testing.T) {
+	chartTar, err := os.ReadFile("../../../tests/testdata/testingchart-0.1.0.tgz")
+	require.NoError(t, err)
+	helmChart, err := loader.LoadArchive(strings.NewReader(string(chartTar)))
+	require.NoError(t, err)
+
+	ts := spinPushableRegistry(t)
+	defer ts.Close()
+
+	ociClient, err := NewClient(strings.Replace(ts.URL, "http", "oci", 1)+"/mirror", v1.RepoSpec{}, nil)
+	require.NoError(t, err)
+	orasRepository, err := ociClient.GetChartOrasRepository(helmChart.Metadata.Name)
+	require.NoError(t, err)
+	orasRepository.PlainHTTP = true
+
+	ctx := context.Background()
+
+	existingDigest, err := ChartLayerDigest(ctx, orasRepository, helmChart.Metadata.Version)
+	assert.NoError(t, err)
+	assert.Empty(t, existingDigest)
+
+	_, err = PushChart(ctx, orasRepository, helmChart.Metadata, chartTar)
+	require.NoError(t, err)
+
+	existingDigest, err = ChartLayerDigest(ctx, orasRepository, helmChart.Metadata.Version)
+	assert.NoError(t, err)
+	assert.Equal(t, digest.FromBytes(chartTar), existingDigest)
+
+	// Pushing the same chart twice must be a no-op for the blobs already present.
+	_, err = PushChart(ctx, orasRepository, helmChart.Metadata, chartTar)
+	assert.NoError(t, err)
+}
+
+func TestVersionTag(t *testing.T) {
+	assert.Equal(t, "1.0.0", VersionTag("1.0.0"))
+	assert.Equal(t, "1.0.0_up2.3.4", VersionTag("1.0.0+up2.3.4"))
+}
@@ -46,6 +46,7 @@
 	ClusterFieldDriver                                               = "driver"
 	ClusterFieldEKSConfig                                            = "eksConfig"
 	ClusterFieldEKSStatus                                            = "eksStatus"
+	ClusterFieldEgressProxy                                          = "egressProxy"
 	ClusterFieldEnableNetworkPolicy                                  = "enableNetworkPolicy"
 	ClusterFieldFailedSpec                                           = "failedSpec"
 	ClusterFieldFleetAgentDeploymentCustomization                    = "fleetAgentDeploymentCustomization"
@@ -127,6 +128,7 @@ type Cluster struct {
 	Driver                                               string                         `json:"driver,omitempty" yaml:"driver,omitempty"`
 	EKSConfig                                            *EKSClusterConfigSpec          `json:"eksConfig,omitempty" yaml:"eksConfig,omitempty"`
 	EKSStatus                                            *EKSStatus                     `json:"eksStatus,omitempty" yaml:"eksStatus,omitempty"`
+	EgressProxy                                          *EgressProxyConfig             `json:"egressProxy,omitempty" yaml:"egressProxy,omitempty"`
 	EnableNetworkPolicy                                  *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
 	FailedSpec                                           *ClusterSpec                   `json:"failedSpec,omitempty" yaml:"failedSpec,omitempty"`
 	FleetAgentDeploymentCustomization                    *AgentDeploymentCustomization  `json:"fleetAgentDeploymentCustomization,omitempty" yaml:"fleetAgentDeploymentCustomization,omitempty"`
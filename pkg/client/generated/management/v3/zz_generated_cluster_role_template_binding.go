@@ -10,6 +10,7 @@
 	ClusterRoleTemplateBindingFieldClusterID        = "clusterId"
 	ClusterRoleTemplateBindingFieldCreated          = "created"
 	ClusterRoleTemplateBindingFieldCreatorID        = "creatorId"
+	ClusterRoleTemplateBindingFieldExpiresAt        = "expiresAt"
 	ClusterRoleTemplateBindingFieldGroupID          = "groupId"
 	ClusterRoleTemplateBindingFieldGroupPrincipalID = "groupPrincipalId"
 	ClusterRoleTemplateBindingFieldLabels           = "labels"
@@ -18,6 +19,7 @@
 	ClusterRoleTemplateBindingFieldOwnerReferences  = "ownerReferences"
 	ClusterRoleTemplateBindingFieldRemoved          = "removed"
 	ClusterRoleTemplateBindingFieldRoleTemplateID   = "roleTemplateId"
+	ClusterRoleTemplateBindingFieldTTL              = "ttl"
 	ClusterRoleTemplateBindingFieldUUID             = "uuid"
 	ClusterRoleTemplateBindingFieldUserID           = "userId"
 	ClusterRoleTemplateBindingFieldUserPrincipalID  = "userPrincipalId"
@@ -29,6 +31,7 @@ type ClusterRoleTemplateBinding struct {
 	ClusterID        string            `json:"clusterId,omitempty" yaml:"clusterId,omitempty"`
 	Created          string            `json:"created,omitempty" yaml:"created,omitempty"`
 	CreatorID        string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
+	ExpiresAt        string            `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
 	GroupID          string            `json:"groupId,omitempty" yaml:"groupId,omitempty"`
 	GroupPrincipalID string            `json:"groupPrincipalId,omitempty" yaml:"groupPrincipalId,omitempty"`
 	Labels           map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
@@ -37,6 +40,7 @@ type ClusterRoleTemplateBinding struct {
 	OwnerReferences  []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
 	Removed          string            `json:"removed,omitempty" yaml:"removed,omitempty"`
 	RoleTemplateID   string            `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
+	TTL              string            `json:"ttl,omitempty" yaml:"ttl,omitempty"`
 	UUID             string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
 	UserID           string            `json:"userId,omitempty" yaml:"userId,omitempty"`
 	UserPrincipalID  string            `json:"userPrincipalId,omitempty" yaml:"userPrincipalId,omitempty"`
@@ -21,6 +21,7 @@
 	ClusterSpecFieldDisplayName                                          = "displayName"
 	ClusterSpecFieldDockerRootDir                                        = "dockerRootDir"
 	ClusterSpecFieldEKSConfig                                            = "eksConfig"
+	ClusterSpecFieldEgressProxy                                          = "egressProxy"
 	ClusterSpecFieldEnableNetworkPolicy                                  = "enableNetworkPolicy"
 	ClusterSpecFieldFleetAgentDeploymentCustomization                    = "fleetAgentDeploymentCustomization"
 	ClusterSpecFieldFleetWorkspaceName                                   = "fleetWorkspaceName"
@@ -56,6 +57,7 @@ type ClusterSpec struct {
 	DisplayName                                          string                         `json:"displayName,omitempty" yaml:"displayName,omitempty"`
 	DockerRootDir                                        string                         `json:"dockerRootDir,omitempty" yaml:"dockerRootDir,omitempty"`
 	EKSConfig                                            *EKSClusterConfigSpec          `json:"eksConfig,omitempty" yaml:"eksConfig,omitempty"`
+	EgressProxy                                          *EgressProxyConfig             `json:"egressProxy,omitempty" yaml:"egressProxy,omitempty"`
 	EnableNetworkPolicy                                  *bool                          `json:"enableNetworkPolicy,omitempty" yaml:"enableNetworkPolicy,omitempty"`
 	FleetAgentDeploymentCustomization                    *AgentDeploymentCustomization  `json:"fleetAgentDeploymentCustomization,omitempty" yaml:"fleetAgentDeploymentCustomization,omitempty"`
 	FleetWorkspaceName                                   string                         `json:"fleetWorkspaceName,omitempty" yaml:"fleetWorkspaceName,omitempty"`
@@ -0,0 +1,14 @@
+package client
+
+const (
+	EgressProxyConfigType                  = "egressProxyConfig"
+	EgressProxyConfigFieldCredentialSecret = "credentialSecret"
+	EgressProxyConfigFieldNoProxy          = "noProxy"
+	EgressProxyConfigFieldURL              = "url"
+)
+
+type EgressProxyConfig struct {
+	CredentialSecret string `json:"credentialSecret,omitempty" yaml:"credentialSecret,omitempty"`
+	NoProxy          string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+	URL              string `json:"url,omitempty" yaml:"url,omitempty"`
+}
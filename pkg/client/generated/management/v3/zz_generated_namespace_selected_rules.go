@@ -0,0 +1,12 @@
+package client
+
+const (
+	NamespaceSelectedRulesType                   = "namespaceSelectedRules"
+	NamespaceSelectedRulesFieldNamespaceSelector = "namespaceSelector"
+	NamespaceSelectedRulesFieldRules             = "rules"
+)
+
+type NamespaceSelectedRules struct {
+	NamespaceSelector *LabelSelector `json:"namespaceSelector,omitempty" yaml:"namespaceSelector,omitempty"`
+	Rules             []PolicyRule   `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
@@ -14,10 +14,13 @@
 	ProjectFieldCreatorID                     = "creatorId"
 	ProjectFieldDescription                   = "description"
 	ProjectFieldLabels                        = "labels"
+	ProjectFieldLimitRange                    = "limitRange"
 	ProjectFieldName                          = "name"
 	ProjectFieldNamespaceDefaultResourceQuota = "namespaceDefaultResourceQuota"
 	ProjectFieldNamespaceId                   = "namespaceId"
+	ProjectFieldNetworkIsolation              = "networkIsolation"
 	ProjectFieldOwnerReferences               = "ownerReferences"
+	ProjectFieldParentProjectName             = "parentProjectName"
 	ProjectFieldRemoved                       = "removed"
 	ProjectFieldResourceQuota                 = "resourceQuota"
 	ProjectFieldState                         = "state"
@@ -36,10 +39,13 @@ type Project struct {
 	CreatorID                     string                  `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
 	Description                   string                  `json:"description,omitempty" yaml:"description,omitempty"`
 	Labels                        map[string]string       `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LimitRange                    *ProjectLimitRange      `json:"limitRange,omitempty" yaml:"limitRange,omitempty"`
 	Name                          string                  `json:"name,omitempty" yaml:"name,omitempty"`
 	NamespaceDefaultResourceQuota *NamespaceResourceQuota `json:"namespaceDefaultResourceQuota,omitempty" yaml:"namespaceDefaultResourceQuota,omitempty"`
 	NamespaceId                   string                  `json:"namespaceId,omitempty" yaml:"namespaceId,omitempty"`
+	NetworkIsolation              string                  `json:"networkIsolation,omitempty" yaml:"networkIsolation,omitempty"`
 	OwnerReferences               []OwnerReference        `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	ParentProjectName             string                  `json:"parentProjectName,omitempty" yaml:"parentProjectName,omitempty"`
 	Removed                       string                  `json:"removed,omitempty" yaml:"removed,omitempty"`
 	ResourceQuota                 *ProjectResourceQuota   `json:"resourceQuota,omitempty" yaml:"resourceQuota,omitempty"`
 	State                         string                  `json:"state,omitempty" yaml:"state,omitempty"`
@@ -0,0 +1,10 @@
+package client
+
+const (
+	ProjectLimitRangeType        = "projectLimitRange"
+	ProjectLimitRangeFieldLimits = "limits"
+)
+
+type ProjectLimitRange struct {
+	Limits []ProjectLimitRangeItem `json:"limits,omitempty" yaml:"limits,omitempty"`
+}
@@ -0,0 +1,20 @@
+package client
+
+const (
+	ProjectLimitRangeItemType                      = "projectLimitRangeItem"
+	ProjectLimitRangeItemFieldDefault              = "default"
+	ProjectLimitRangeItemFieldDefaultRequest       = "defaultRequest"
+	ProjectLimitRangeItemFieldMax                  = "max"
+	ProjectLimitRangeItemFieldMaxLimitRequestRatio = "maxLimitRequestRatio"
+	ProjectLimitRangeItemFieldMin                  = "min"
+	ProjectLimitRangeItemFieldType                 = "type"
+)
+
+type ProjectLimitRangeItem struct {
+	Default              map[string]string `json:"default,omitempty" yaml:"default,omitempty"`
+	DefaultRequest       map[string]string `json:"defaultRequest,omitempty" yaml:"defaultRequest,omitempty"`
+	Max                  map[string]string `json:"max,omitempty" yaml:"max,omitempty"`
+	MaxLimitRequestRatio map[string]string `json:"maxLimitRequestRatio,omitempty" yaml:"maxLimitRequestRatio,omitempty"`
+	Min                  map[string]string `json:"min,omitempty" yaml:"min,omitempty"`
+	Type                 string            `json:"type,omitempty" yaml:"type,omitempty"`
+}
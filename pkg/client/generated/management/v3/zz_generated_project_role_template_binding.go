@@ -9,6 +9,7 @@
 	ProjectRoleTemplateBindingFieldAnnotations      = "annotations"
 	ProjectRoleTemplateBindingFieldCreated          = "created"
 	ProjectRoleTemplateBindingFieldCreatorID        = "creatorId"
+	ProjectRoleTemplateBindingFieldExpiresAt        = "expiresAt"
 	ProjectRoleTemplateBindingFieldGroupID          = "groupId"
 	ProjectRoleTemplateBindingFieldGroupPrincipalID = "groupPrincipalId"
 	ProjectRoleTemplateBindingFieldLabels           = "labels"
@@ -19,6 +20,7 @@
 	ProjectRoleTemplateBindingFieldRemoved          = "removed"
 	ProjectRoleTemplateBindingFieldRoleTemplateID   = "roleTemplateId"
 	ProjectRoleTemplateBindingFieldServiceAccount   = "serviceAccount"
+	ProjectRoleTemplateBindingFieldTTL              = "ttl"
 	ProjectRoleTemplateBindingFieldUUID             = "uuid"
 	ProjectRoleTemplateBindingFieldUserID           = "userId"
 	ProjectRoleTemplateBindingFieldUserPrincipalID  = "userPrincipalId"
@@ -29,6 +31,7 @@ type ProjectRoleTemplateBinding struct {
 	Annotations      map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 	Created          string            `json:"created,omitempty" yaml:"created,omitempty"`
 	CreatorID        string            `json:"creatorId,omitempty" yaml:"creatorId,omitempty"`
+	ExpiresAt        string            `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
 	GroupID          string            `json:"groupId,omitempty" yaml:"groupId,omitempty"`
 	GroupPrincipalID string            `json:"groupPrincipalId,omitempty" yaml:"groupPrincipalId,omitempty"`
 	Labels           map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
@@ -39,6 +42,7 @@ type ProjectRoleTemplateBinding struct {
 	Removed          string            `json:"removed,omitempty" yaml:"removed,omitempty"`
 	RoleTemplateID   string            `json:"roleTemplateId,omitempty" yaml:"roleTemplateId,omitempty"`
 	ServiceAccount   string            `json:"serviceAccount,omitempty" yaml:"serviceAccount,omitempty"`
+	TTL              string            `json:"ttl,omitempty" yaml:"ttl,omitempty"`
 	UUID             string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
 	UserID           string            `json:"userId,omitempty" yaml:"userId,omitempty"`
 	UserPrincipalID  string            `json:"userPrincipalId,omitempty" yaml:"userPrincipalId,omitempty"`
@@ -6,7 +6,10 @@
 	ProjectSpecFieldContainerDefaultResourceLimit = "containerDefaultResourceLimit"
 	ProjectSpecFieldDescription                   = "description"
 	ProjectSpecFieldDisplayName                   = "displayName"
+	ProjectSpecFieldLimitRange                    = "limitRange"
 	ProjectSpecFieldNamespaceDefaultResourceQuota = "namespaceDefaultResourceQuota"
+	ProjectSpecFieldNetworkIsolation              = "networkIsolation"
+	ProjectSpecFieldParentProjectName             = "parentProjectName"
 	ProjectSpecFieldResourceQuota                 = "resourceQuota"
 )
 
@@ -15,6 +18,9 @@ type ProjectSpec struct {
 	ContainerDefaultResourceLimit *ContainerResourceLimit `json:"containerDefaultResourceLimit,omitempty" yaml:"containerDefaultResourceLimit,omitempty"`
 	Description                   string                  `json:"description,omitempty" yaml:"description,omitempty"`
 	DisplayName                   string                  `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	LimitRange                    *ProjectLimitRange      `json:"limitRange,omitempty" yaml:"limitRange,omitempty"`
 	NamespaceDefaultResourceQuota *NamespaceResourceQuota `json:"namespaceDefaultResourceQuota,omitempty" yaml:"namespaceDefaultResourceQuota,omitempty"`
+	NetworkIsolation              string                  `json:"networkIsolation,omitempty" yaml:"networkIsolation,omitempty"`
+	ParentProjectName             string                  `json:"parentProjectName,omitempty" yaml:"parentProjectName,omitempty"`
 	ResourceQuota                 *ProjectResourceQuota   `json:"resourceQuota,omitempty" yaml:"resourceQuota,omitempty"`
 }
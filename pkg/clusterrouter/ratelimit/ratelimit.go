@@ -0,0 +1,288 @@
+// Package ratelimit limits the requests the users make to the downstream clusters through the cluster proxies,
+// with per-user and per-cluster token buckets and caps on the number of concurrent long-running requests.
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/ticker"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+const (
+	// ReasonUserRateLimit and the other reasons label the requests rejected by the limiter.
+	ReasonUserRateLimit           = "user_rate_limit"
+	ReasonClusterRateLimit        = "cluster_rate_limit"
+	ReasonUserLongRunningLimit    = "user_long_running_limit"
+	ReasonClusterLongRunningLimit = "cluster_long_running_limit"
+
+	// longRunningRetryAfter is the delay after which clients are told to retry long-running requests rejected
+	// because too many are open, as there is no telling when one of them will end.
+	longRunningRetryAfter = 5 * time.Second
+	// bucketIdleTimeout is how long the token bucket of a user or cluster is kept without being used.
+	bucketIdleTimeout = 10 * time.Minute
+	gcInterval        = time.Minute
+)
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// Limiter enforces the limits configured by the cluster-proxy-* settings on the requests forwarded to the clusters.
+// A nil Limiter admits every request.
+type Limiter struct {
+	lock                sync.Mutex
+	users               map[string]*bucket
+	clusters            map[string]*bucket
+	longRunningUsers    map[string]int
+	longRunningClusters map[string]int
+	now                 func() time.Time
+
+	rejected    *prometheus.CounterVec
+	longRunning *prometheus.GaugeVec
+}
+
+func NewLimiter(ctx context.Context) *Limiter {
+	l := newLimiter(time.Now)
+	go func() {
+		for range ticker.Context(ctx, gcInterval) {
+			l.gc()
+		}
+	}()
+	return l
+}
+
+func newLimiter(now func() time.Time) *Limiter {
+	return &Limiter{
+		users:               map[string]*bucket{},
+		clusters:            map[string]*bucket{},
+		longRunningUsers:    map[string]int{},
+		longRunningClusters: map[string]int{},
+		now:                 now,
+		rejected: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Subsystem: "cluster_manager",
+				Name:      "cluster_proxy_rejected_requests_total",
+				Help:      "Number of requests to the downstream clusters rejected by the rate limits of the cluster proxies",
+			},
+			[]string{"cluster", "reason"},
+		),
+		longRunning: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Subsystem: "cluster_manager",
+				Name:      "cluster_proxy_long_running_requests",
+				Help:      "Number of long-running requests open to the downstream clusters through the cluster proxies",
+			},
+			[]string{"cluster"},
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (l *Limiter) Describe(ch chan<- *prometheus.Desc) {
+	l.rejected.Describe(ch)
+	l.longRunning.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (l *Limiter) Collect(ch chan<- prometheus.Metric) {
+	l.rejected.Collect(ch)
+	l.longRunning.Collect(ch)
+}
+
+// Admit checks the limits of the user of the request and of the cluster. If the request is admitted, the returned
+// function must be called once the request is served. Otherwise, a 429 response with a Retry-After header has
+// already been written and false is returned.
+func (l *Limiter) Admit(rw http.ResponseWriter, req *http.Request, clusterID string) (func(), bool) {
+	if l == nil {
+		return func() {}, true
+	}
+
+	var userName string
+	if requestUser, ok := request.UserFrom(req.Context()); ok {
+		userName = requestUser.GetName()
+	}
+
+	release, reason, retryAfter := l.admit(userName, clusterID, isLongRunning(req))
+	if reason != "" {
+		l.rejected.WithLabelValues(clusterID, reason).Inc()
+		logrus.Debugf("clusterProxy: rejecting request of user [%s] to cluster [%s]: %s", userName, clusterID, reason)
+		tooManyRequests(rw, reason, retryAfter)
+		return nil, false
+	}
+	return release, true
+}
+
+// admit returns the reason why the request is rejected and when to retry it, or the function releasing it once served.
+// The limit of the user is checked first, so that the requests of a user over its limit don't use the tokens of the
+// cluster shared with the other users.
+func (l *Limiter) admit(userName, clusterID string, longRunning bool) (func(), string, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, reservation := range reservations {
+			if reservation != nil {
+				reservation.CancelAt(now)
+			}
+		}
+	}
+
+	if userName != "" {
+		reservation, delay := reserve(l.users, userName, settings.ClusterProxyUserQPS, settings.ClusterProxyUserBurst, now)
+		if delay > 0 {
+			return nil, ReasonUserRateLimit, delay
+		}
+		reservations = append(reservations, reservation)
+	}
+	reservation, delay := reserve(l.clusters, clusterID, settings.ClusterProxyClusterQPS, settings.ClusterProxyClusterBurst, now)
+	if delay > 0 {
+		cancel()
+		return nil, ReasonClusterRateLimit, delay
+	}
+	reservations = append(reservations, reservation)
+
+	if !longRunning {
+		return func() {}, "", 0
+	}
+
+	if limit := settings.ClusterProxyUserMaxLongRunning.GetInt(); userName != "" && limit > 0 && l.longRunningUsers[userName] >= limit {
+		cancel()
+		return nil, ReasonUserLongRunningLimit, longRunningRetryAfter
+	}
+	if limit := settings.ClusterProxyClusterMaxLongRunning.GetInt(); limit > 0 && l.longRunningClusters[clusterID] >= limit {
+		cancel()
+		return nil, ReasonClusterLongRunningLimit, longRunningRetryAfter
+	}
+
+	l.longRunningUsers[userName]++
+	l.longRunningClusters[clusterID]++
+	l.longRunning.WithLabelValues(clusterID).Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			decrement(l.longRunningUsers, userName)
+			decrement(l.longRunningClusters, clusterID)
+			l.longRunning.WithLabelValues(clusterID).Dec()
+		})
+	}, "", 0
+}
+
+// reserve takes a token from the bucket of the key and returns how long to wait for the token, if it is not available
+// now, in which case the token is not taken. No token is taken when the limit is disabled.
+func reserve(buckets map[string]*bucket, key string, qpsSetting, burstSetting settings.Setting, now time.Time) (*rate.Reservation, time.Duration) {
+	qps, err := strconv.ParseFloat(qpsSetting.Get(), 64)
+	if err != nil || qps <= 0 {
+		delete(buckets, key)
+		return nil, 0
+	}
+	burst := burstSetting.GetInt()
+	if burst <= 0 {
+		burst = int(math.Ceil(qps))
+	}
+
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(qps), burst)}
+		buckets[key] = b
+	}
+	if b.limiter.Limit() != rate.Limit(qps) {
+		b.limiter.SetLimitAt(now, rate.Limit(qps))
+	}
+	if b.limiter.Burst() != burst {
+		b.limiter.SetBurstAt(now, burst)
+	}
+	b.lastUsed = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return nil, delay
+	}
+	return reservation, 0
+}
+
+func decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+// gc removes the buckets which have not been used for a while, their tokens have been replenished since.
+func (l *Limiter) gc() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	for _, buckets := range []map[string]*bucket{l.users, l.clusters} {
+		for key, b := range buckets {
+			if now.Sub(b.lastUsed) > bucketIdleTimeout {
+				delete(buckets, key)
+			}
+		}
+	}
+}
+
+// isLongRunning returns true for the requests which are held open by the clients: watches, upgraded connections
+// (exec, attach, port-forward, websockets), proxied connections and followed logs.
+func isLongRunning(req *http.Request) bool {
+	query := req.URL.Query()
+	if watch := query.Get("watch"); watch == "true" || watch == "1" {
+		return true
+	}
+	if req.Header.Get("Upgrade") != "" {
+		return true
+	}
+	switch path.Base(req.URL.Path) {
+	case "exec", "attach", "portforward", "proxy":
+		return true
+	case "log":
+		return query.Get("follow") == "true" || query.Get("follow") == "1"
+	}
+	return false
+}
+
+// tooManyRequests writes a 429 response with a Kubernetes Status, which Kubernetes clients understand and retry.
+func tooManyRequests(rw http.ResponseWriter, reason string, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Retry-After", strconv.Itoa(seconds))
+	rw.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(rw).Encode(&metav1.Status{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Status",
+			APIVersion: "v1",
+		},
+		Status:  metav1.StatusFailure,
+		Message: fmt.Sprintf("too many requests to the cluster (%s), retry after %d seconds", reason, seconds),
+		Reason:  metav1.StatusReasonTooManyRequests,
+		Details: &metav1.StatusDetails{RetryAfterSeconds: int32(seconds)},
+		Code:    http.StatusTooManyRequests,
+	})
+}
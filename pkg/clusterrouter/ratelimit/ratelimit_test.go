@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func setSettings(t *testing.T, values map[settings.Setting]string) {
+	for setting, value := range values {
+		original := setting.Get()
+		require.NoError(t, setting.Set(value))
+		t.Cleanup(func() { _ = setting.Set(original) })
+	}
+}
+
+func newRequest(userName, target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	return req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: userName}))
+}
+
+func TestUserRateLimit(t *testing.T) {
+	setSettings(t, map[settings.Setting]string{
+		settings.ClusterProxyUserQPS:   "1",
+		settings.ClusterProxyUserBurst: "2",
+	})
+	clock := &fakeClock{now: time.Now()}
+	l := newLimiter(clock.Now)
+
+	for i := 0; i < 2; i++ {
+		_, reason, _ := l.admit("u-1", "c-1", false)
+		assert.Empty(t, reason)
+	}
+	_, reason, retryAfter := l.admit("u-1", "c-1", false)
+	assert.Equal(t, ReasonUserRateLimit, reason)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// other users are not limited by the requests of u-1
+	_, reason, _ = l.admit("u-2", "c-1", false)
+	assert.Empty(t, reason)
+
+	clock.now = clock.now.Add(time.Second)
+	_, reason, _ = l.admit("u-1", "c-1", false)
+	assert.Empty(t, reason)
+}
+
+func TestClusterRateLimitDoesNotConsumeUserTokens(t *testing.T) {
+	setSettings(t, map[settings.Setting]string{
+		settings.ClusterProxyUserQPS:    "1",
+		settings.ClusterProxyUserBurst:  "1",
+		settings.ClusterProxyClusterQPS: "1",
+	})
+	clock := &fakeClock{now: time.Now()}
+	l := newLimiter(clock.Now)
+
+	_, reason, _ := l.admit("u-1", "c-1", false)
+	assert.Empty(t, reason)
+	_, reason, _ = l.admit("u-2", "c-1", false)
+	assert.Equal(t, ReasonClusterRateLimit, reason)
+
+	// the request rejected by the cluster limit did not take the token of u-2
+	_, reason, _ = l.admit("u-2", "c-2", false)
+	assert.Empty(t, reason)
+}
+
+func TestLongRunningLimits(t *testing.T) {
+	setSettings(t, map[settings.Setting]string{
+		settings.ClusterProxyUserMaxLongRunning:    "1",
+		settings.ClusterProxyClusterMaxLongRunning: "2",
+	})
+	l := newLimiter(time.Now)
+
+	release, reason, _ := l.admit("u-1", "c-1", true)
+	require.Empty(t, reason)
+	_, reason, retryAfter := l.admit("u-1", "c-1", true)
+	assert.Equal(t, ReasonUserLongRunningLimit, reason)
+	assert.Equal(t, longRunningRetryAfter, retryAfter)
+
+	// requests which are not long-running are not capped
+	_, reason, _ = l.admit("u-1", "c-1", false)
+	assert.Empty(t, reason)
+
+	_, reason, _ = l.admit("u-2", "c-1", true)
+	require.Empty(t, reason)
+	_, reason, _ = l.admit("u-3", "c-1", true)
+	assert.Equal(t, ReasonClusterLongRunningLimit, reason)
+	assert.Equal(t, float64(2), testutil.ToFloat64(l.longRunning.WithLabelValues("c-1")))
+
+	release()
+	release()
+	_, reason, _ = l.admit("u-3", "c-1", true)
+	assert.Empty(t, reason)
+	assert.Equal(t, float64(2), testutil.ToFloat64(l.longRunning.WithLabelValues("c-1")))
+}
+
+func TestAdmit(t *testing.T) {
+	setSettings(t, map[settings.Setting]string{
+		settings.ClusterProxyClusterQPS:   "0.5",
+		settings.ClusterProxyClusterBurst: "1",
+	})
+	l := newLimiter(time.Now)
+
+	release, ok := l.Admit(httptest.NewRecorder(), newRequest("u-1", "/k8s/clusters/c-1/api/v1/pods"), "c-1")
+	require.True(t, ok)
+	release()
+
+	rw := httptest.NewRecorder()
+	_, ok = l.Admit(rw, newRequest("u-1", "/k8s/clusters/c-1/api/v1/pods"), "c-1")
+	require.False(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "2", rw.Header().Get("Retry-After"))
+
+	var status metav1.Status
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+	assert.Equal(t, metav1.StatusReasonTooManyRequests, status.Reason)
+	assert.Equal(t, int32(2), status.Details.RetryAfterSeconds)
+	assert.Equal(t, float64(1), testutil.ToFloat64(l.rejected.WithLabelValues("c-1", ReasonClusterRateLimit)))
+
+	var nilLimiter *Limiter
+	_, ok = nilLimiter.Admit(httptest.NewRecorder(), newRequest("u-1", "/"), "c-1")
+	assert.True(t, ok)
+}
+
+func TestIsLongRunning(t *testing.T) {
+	tests := []struct {
+		target      string
+		upgrade     bool
+		longRunning bool
+	}{
+		{target: "/k8s/clusters/c-1/api/v1/pods"},
+		{target: "/k8s/clusters/c-1/api/v1/pods?watch=true", longRunning: true},
+		{target: "/k8s/clusters/c-1/api/v1/namespaces/default/pods/p/exec?command=sh", longRunning: true},
+		{target: "/k8s/clusters/c-1/api/v1/namespaces/default/pods/p/log"},
+		{target: "/k8s/clusters/c-1/api/v1/namespaces/default/pods/p/log?follow=true", longRunning: true},
+		{target: "/k8s/clusters/c-1/v1/subscribe", upgrade: true, longRunning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.upgrade {
+				req.Header.Set("Upgrade", "websocket")
+			}
+			assert.Equal(t, tt.longRunning, isLongRunning(req))
+		})
+	}
+}
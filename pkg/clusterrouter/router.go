@@ -6,6 +6,7 @@
 
 	"github.com/rancher/norman/httperror"
 	"github.com/rancher/rancher/pkg/clusterrouter/proxy"
+	"github.com/rancher/rancher/pkg/clusterrouter/ratelimit"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
 	"k8s.io/client-go/rest"
@@ -13,12 +14,14 @@
 
 type Router struct {
 	serverFactory *factory
+	limiter       *ratelimit.Limiter
 }
 
-func New(localConfig *rest.Config, lookup ClusterLookup, dialer dialer.Factory, clusterLister v3.ClusterLister, clusterContextGetter proxy.ClusterContextGetter) http.Handler {
+func New(localConfig *rest.Config, lookup ClusterLookup, dialer dialer.Factory, clusterLister v3.ClusterLister, clusterContextGetter proxy.ClusterContextGetter, limiter *ratelimit.Limiter) http.Handler {
 	serverFactory := newFactory(localConfig, dialer, lookup, clusterLister, clusterContextGetter)
 	return &Router{
 		serverFactory: serverFactory,
+		limiter:       limiter,
 	}
 }
 
@@ -39,6 +42,12 @@ func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	release, ok := r.limiter.Admit(rw, req, c.Name)
+	if !ok {
+		return
+	}
+	defer release()
+
 	handler.ServeHTTP(rw, req)
 }
 
@@ -93,6 +93,7 @@ func main() {
 			"fleet.cattle.io": {
 				Types: []interface{}{
 					fleet.Bundle{},
+					fleet.BundleDeployment{},
 					fleet.Cluster{},
 					fleet.ClusterGroup{},
 				},
@@ -3,14 +3,22 @@
 import (
 	"context"
 	"fmt"
+	"time"
 
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	rbaccontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
 	"github.com/rancher/wrangler/v3/pkg/kstatus"
 	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -23,25 +31,37 @@
 type operationHandler struct {
 	ctx             context.Context
 	pods            corecontrollers.PodCache
+	secrets         corecontrollers.SecretClient
 	k8s             kubernetes.Interface
+	operations      catalogcontrollers.OperationController
 	operationsCache catalogcontrollers.OperationCache
+	roles           rbaccontrollers.RoleController
+	roleBindings    rbaccontrollers.RoleBindingController
 }
 
 func RegisterOperations(ctx context.Context,
 	k8s kubernetes.Interface,
 	pods corecontrollers.PodController,
-	operations catalogcontrollers.OperationController) {
+	secrets corecontrollers.SecretClient,
+	operations catalogcontrollers.OperationController,
+	roles rbaccontrollers.RoleController,
+	roleBindings rbaccontrollers.RoleBindingController) {
 
 	o := operationHandler{
 		ctx:             ctx,
 		k8s:             k8s,
 		pods:            pods.Cache(),
+		secrets:         secrets,
+		operations:      operations,
 		operationsCache: operations.Cache(),
+		roles:           roles,
+		roleBindings:    roleBindings,
 	}
 
 	operations.Cache().AddIndexer(podIndex, indexOperationsByPod)
 	relatedresource.Watch(ctx, "helm-operation", o.findOperationsFromPod, operations, pods)
 	catalogcontrollers.RegisterOperationStatusHandler(ctx, operations, "", "helm-operation", o.onOperationChange)
+	operations.OnChange(ctx, "helm-operation-retention", o.onOperationRetention)
 }
 
 func indexOperationsByPod(obj *catalog.Operation) ([]string, error) {
@@ -83,6 +103,12 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 			kstatus.SetTransitioning(&status, "running operation")
 		} else if container.State.Terminated != nil {
 			status.PodCreated = true
+			if status.LogSecretName == "" {
+				if err := o.persistLog(operation, pod); err != nil {
+					return status, err
+				}
+				status.LogSecretName = helmop.LogSecretName(operation)
+			}
 			if container.State.Terminated.ExitCode == 0 {
 				kstatus.SetActive(&status)
 			} else {
@@ -104,6 +130,124 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 	return status, nil
 }
 
+// persistLog stores the log of the helm container of the operation pod in a secret, so that
+// it can still be served once the pod is deleted.
+func (o *operationHandler) persistLog(operation *catalog.Operation, pod *corev1.Pod) error {
+	stream, err := o.k8s.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: "helm",
+	}).Stream(o.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the log of operation %s/%s: %w", operation.Namespace, operation.Name, err)
+	}
+	defer stream.Close()
+
+	secret, err := helmop.NewLogSecret(operation, stream, settings.HelmOperationLogMaxSize.GetInt())
+	if err != nil {
+		return fmt.Errorf("failed to read the log of operation %s/%s: %w", operation.Namespace, operation.Name, err)
+	}
+
+	_, err = o.secrets.Create(secret)
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// onOperationRetention keeps completed operations, whose log has been stored, around once their pod is
+// deleted and deletes them after the retention duration defined by the helm-operation-retention setting.
+func (o *operationHandler) onOperationRetention(key string, operation *catalog.Operation) (*catalog.Operation, error) {
+	if operation == nil || operation.DeletionTimestamp != nil || operation.Status.LogSecretName == "" {
+		return operation, nil
+	}
+
+	// The operation is owned by the same objects as its pod, release it so that it outlives the pod. The role and binding
+	// letting its user get it and its log are retained along with it: they are made owned by the operation alone first,
+	// so they are deleted with it rather than with the pod.
+	if len(operation.OwnerReferences) > 0 {
+		if err := o.ownRBAC(operation); err != nil {
+			return operation, err
+		}
+		operation = operation.DeepCopy()
+		operation.OwnerReferences = nil
+		return o.operations.Update(operation)
+	}
+
+	retentionSetting := settings.HelmOperationRetention.Get()
+	if retentionSetting == "" {
+		return operation, nil
+	}
+	retention, err := time.ParseDuration(retentionSetting)
+	if err != nil {
+		logrus.Errorf("failed to parse %s setting: %v", settings.HelmOperationRetention.Name, err)
+		return operation, nil
+	}
+	if retention <= 0 {
+		return operation, nil
+	}
+
+	if remaining := time.Until(operation.CreationTimestamp.Add(retention)); remaining > 0 {
+		o.operations.EnqueueAfter(operation.Namespace, operation.Name, remaining)
+		return operation, nil
+	}
+
+	logrus.Debugf("Deleting helm operation %s/%s older than %s", operation.Namespace, operation.Name, retention)
+	err = o.operations.Delete(operation.Namespace, operation.Name, &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return operation, err
+}
+
+// ownRBAC sets the operation as the only owner of the role and the role binding letting its user get it.
+func (o *operationHandler) ownRBAC(operation *catalog.Operation) error {
+	owners := []metav1.OwnerReference{{
+		APIVersion: catalog.SchemeGroupVersion.String(),
+		Kind:       "Operation",
+		Name:       operation.Name,
+		UID:        operation.UID,
+	}}
+
+	roles, err := o.roles.Cache().List(operation.Namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if !ownedBy(role.OwnerReferences, operation) || equality.Semantic.DeepEqual(role.OwnerReferences, owners) {
+			continue
+		}
+		role = role.DeepCopy()
+		role.OwnerReferences = owners
+		if _, err := o.roles.Update(role); err != nil {
+			return err
+		}
+	}
+
+	roleBindings, err := o.roleBindings.Cache().List(operation.Namespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, roleBinding := range roleBindings {
+		if !ownedBy(roleBinding.OwnerReferences, operation) || equality.Semantic.DeepEqual(roleBinding.OwnerReferences, owners) {
+			continue
+		}
+		roleBinding = roleBinding.DeepCopy()
+		roleBinding.OwnerReferences = owners
+		if _, err := o.roleBindings.Update(roleBinding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ownedBy(ownerReferences []metav1.OwnerReference, operation *catalog.Operation) bool {
+	for _, ownerReference := range ownerReferences {
+		if ownerReference.UID == operation.UID {
+			return true
+		}
+	}
+	return false
+}
+
 func (o *operationHandler) cleanup(pod *corev1.Pod) error {
 	running := false
 	success := false
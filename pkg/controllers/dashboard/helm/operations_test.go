@@ -0,0 +1,204 @@
+package helm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var podOwner = metav1.OwnerReference{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "helm-op-abcde", UID: "role-uid"}
+
+func testOperation() *catalog.Operation {
+	return &catalog.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "helm-operation-abcde",
+			Namespace:         "cattle-system",
+			UID:               "op-uid",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			OwnerReferences:   []metav1.OwnerReference{podOwner},
+		},
+		Status: catalog.OperationStatus{
+			PodName:       "helm-operation-abcde",
+			PodNamespace:  "cattle-system",
+			LogSecretName: "helm-operation-abcde-log",
+		},
+	}
+}
+
+func TestOnOperationRetentionReleasesOperation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	operations := fake.NewMockControllerInterface[*catalog.Operation, *catalog.OperationList](ctrl)
+	roles := fake.NewMockControllerInterface[*rbacv1.Role, *rbacv1.RoleList](ctrl)
+	roleCache := fake.NewMockCacheInterface[*rbacv1.Role](ctrl)
+	roleBindings := fake.NewMockControllerInterface[*rbacv1.RoleBinding, *rbacv1.RoleBindingList](ctrl)
+	roleBindingCache := fake.NewMockCacheInterface[*rbacv1.RoleBinding](ctrl)
+	o := &operationHandler{operations: operations, roles: roles, roleBindings: roleBindings}
+
+	op := testOperation()
+	opOwners := []metav1.OwnerReference{{APIVersion: "catalog.cattle.io/v1", Kind: "Operation", Name: op.Name, UID: op.UID}}
+	roles.EXPECT().Cache().Return(roleCache)
+	roleCache.EXPECT().List("cattle-system", gomock.Any()).Return([]*rbacv1.Role{
+		{ObjectMeta: metav1.ObjectMeta{Name: "op-role", Namespace: "cattle-system", OwnerReferences: []metav1.OwnerReference{podOwner, opOwners[0]}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-role", Namespace: "cattle-system", OwnerReferences: []metav1.OwnerReference{podOwner}}},
+	}, nil)
+	// the role and binding are retained with the operation, rather than deleted with the pod
+	roles.EXPECT().Update(gomock.Any()).DoAndReturn(func(role *rbacv1.Role) (*rbacv1.Role, error) {
+		assert.Equal(t, "op-role", role.Name)
+		assert.Equal(t, opOwners, role.OwnerReferences)
+		return role, nil
+	})
+	roleBindings.EXPECT().Cache().Return(roleBindingCache)
+	roleBindingCache.EXPECT().List("cattle-system", gomock.Any()).Return([]*rbacv1.RoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "op-rolebinding", Namespace: "cattle-system", OwnerReferences: []metav1.OwnerReference{podOwner, opOwners[0]}}},
+		// already owned by the operation alone
+		{ObjectMeta: metav1.ObjectMeta{Name: "owned-rolebinding", Namespace: "cattle-system", OwnerReferences: opOwners}},
+	}, nil)
+	roleBindings.EXPECT().Update(gomock.Any()).DoAndReturn(func(roleBinding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+		assert.Equal(t, "op-rolebinding", roleBinding.Name)
+		assert.Equal(t, opOwners, roleBinding.OwnerReferences)
+		return roleBinding, nil
+	})
+	operations.EXPECT().Update(gomock.Any()).DoAndReturn(func(operation *catalog.Operation) (*catalog.Operation, error) {
+		assert.Empty(t, operation.OwnerReferences)
+		return operation, nil
+	})
+
+	_, err := o.onOperationRetention("", op)
+	require.NoError(t, err)
+	assert.Equal(t, []metav1.OwnerReference{podOwner}, op.OwnerReferences, "the cached operation must not be modified")
+}
+
+func TestOnOperationRetention(t *testing.T) {
+	defer settings.HelmOperationRetention.Set(settings.HelmOperationRetention.Default)
+
+	tests := []struct {
+		name      string
+		retention string
+		operation func() *catalog.Operation
+		setup     func(operations *fake.MockControllerInterface[*catalog.Operation, *catalog.OperationList])
+	}{
+		{
+			name:      "log not persisted",
+			retention: "1m",
+			operation: func() *catalog.Operation {
+				op := testOperation()
+				op.OwnerReferences = nil
+				op.Status.LogSecretName = ""
+				return op
+			},
+		},
+		{
+			name:      "retention disabled",
+			retention: "",
+		},
+		{
+			name:      "invalid retention",
+			retention: "a week",
+		},
+		{
+			name:      "not expired",
+			retention: "2h",
+			setup: func(operations *fake.MockControllerInterface[*catalog.Operation, *catalog.OperationList]) {
+				operations.EXPECT().EnqueueAfter("cattle-system", "helm-operation-abcde", gomock.Any()).Do(func(_, _ string, after time.Duration) {
+					assert.InDelta(t, time.Hour, after, float64(time.Minute))
+				})
+			},
+		},
+		{
+			name:      "expired",
+			retention: "30m",
+			setup: func(operations *fake.MockControllerInterface[*catalog.Operation, *catalog.OperationList]) {
+				operations.EXPECT().Delete("cattle-system", "helm-operation-abcde", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:      "expired and already deleted",
+			retention: "30m",
+			setup: func(operations *fake.MockControllerInterface[*catalog.Operation, *catalog.OperationList]) {
+				operations.EXPECT().Delete("cattle-system", "helm-operation-abcde", gomock.Any()).
+					Return(apierrors.NewNotFound(schema.GroupResource{}, "helm-operation-abcde"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, settings.HelmOperationRetention.Set(tt.retention))
+			operations := fake.NewMockControllerInterface[*catalog.Operation, *catalog.OperationList](gomock.NewController(t))
+			if tt.setup != nil {
+				tt.setup(operations)
+			}
+			o := &operationHandler{operations: operations}
+
+			op := testOperation()
+			op.OwnerReferences = nil
+			if tt.operation != nil {
+				op = tt.operation()
+			}
+			_, err := o.onOperationRetention("", op)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestPersistLog(t *testing.T) {
+	defer settings.HelmOperationLogMaxSize.Set(settings.HelmOperationLogMaxSize.Default)
+	require.NoError(t, settings.HelmOperationLogMaxSize.Set("1024"))
+
+	tests := []struct {
+		name      string
+		createErr error
+		wantErr   bool
+	}{
+		{
+			name: "log stored",
+		},
+		{
+			name:      "log already stored",
+			createErr: apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, "helm-operation-abcde-log"),
+		},
+		{
+			name:      "secret not created",
+			createErr: apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "helm-operation-abcde-log", nil),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := fake.NewMockClientInterface[*corev1.Secret, *corev1.SecretList](gomock.NewController(t))
+			o := &operationHandler{ctx: context.Background(), k8s: k8sfake.NewSimpleClientset(), secrets: secrets}
+
+			op := testOperation()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: op.Status.PodName, Namespace: op.Status.PodNamespace}}
+			secrets.EXPECT().Create(gomock.Any()).DoAndReturn(func(secret *corev1.Secret) (*corev1.Secret, error) {
+				assert.Equal(t, helmop.LogSecretName(op), secret.Name)
+				assert.Equal(t, op.Namespace, secret.Namespace)
+				log, err := helmop.ReadLogSecret(secret)
+				require.NoError(t, err)
+				// the fake clientset serves this log for any pod
+				assert.Equal(t, "fake logs", string(log))
+				return secret, tt.createErr
+			})
+
+			err := o.persistLog(op, pod)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/egressproxy"
 	"github.com/rancher/rancher/pkg/wrangler"
 )
 
 func Register(ctx context.Context, wrangler *wrangler.Context) {
 	RegisterRepos(ctx,
 		wrangler.Apply,
+		egressproxy.NewResolver(wrangler.Core.Secret().Cache()).ClusterProxyFunc(wrangler.Mgmt.Cluster().Cache(), "local"),
 		wrangler.Core.Secret().Cache(),
 		wrangler.Catalog.ClusterRepo(),
 		wrangler.Core.ConfigMap(),
@@ -18,6 +20,11 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		wrangler.Catalog.ClusterRepo(),
 		wrangler.Core.ConfigMap(),
 		wrangler.Core.Secret().Cache())
+	RegisterRepoMirror(ctx,
+		wrangler.Apply,
+		wrangler.Catalog.ClusterRepo(),
+		wrangler.Core.Secret().Cache(),
+		wrangler.CatalogContentManager)
 	RegisterApps(ctx,
 		wrangler.Apply,
 		wrangler.ControllerFactory.SharedCacheFactory().SharedClientFactory(),
@@ -27,5 +34,8 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 	RegisterOperations(ctx,
 		wrangler.K8s,
 		wrangler.Core.Pod(),
-		wrangler.Catalog.Operation())
+		wrangler.Core.Secret(),
+		wrangler.Catalog.Operation(),
+		wrangler.RBAC.Role(),
+		wrangler.RBAC.RoleBinding())
 }
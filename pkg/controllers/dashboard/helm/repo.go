@@ -43,6 +43,7 @@
 }
 
 type repoHandler struct {
+	proxy          helmhttp.ProxyFunc
 	secrets        corev1controllers.SecretCache
 	clusterRepos   catalogcontrollers.ClusterRepoController
 	configMaps     corev1controllers.ConfigMapClient
@@ -52,11 +53,13 @@ type repoHandler struct {
 
 func RegisterRepos(ctx context.Context,
 	apply apply.Apply,
+	proxy helmhttp.ProxyFunc,
 	secrets corev1controllers.SecretCache,
 	clusterRepos catalogcontrollers.ClusterRepoController,
 	configMap corev1controllers.ConfigMapController,
 	configMapCache corev1controllers.ConfigMapCache) {
 	h := &repoHandler{
+		proxy:          proxy,
 		secrets:        secrets,
 		clusterRepos:   clusterRepos,
 		configMaps:     configMap,
@@ -281,7 +284,7 @@ func (r *repoHandler) download(repository *catalog.ClusterRepo, newStatus *catal
 			index, err = git.BuildOrGetIndex(metadata.Namespace, metadata.Name, repoSpec.GitRepo)
 		}
 	} else if repoSpec.URL != "" {
-		index, err = helmhttp.DownloadIndex(secret, repoSpec.URL, repoSpec.CABundle, repoSpec.InsecureSkipTLSverify, repoSpec.DisableSameOriginCheck)
+		index, err = helmhttp.DownloadIndex(secret, repoSpec.URL, repoSpec.CABundle, repoSpec.InsecureSkipTLSverify, repoSpec.DisableSameOriginCheck, r.proxy)
 		retriable = true
 		newStatus.URL = repoSpec.URL
 		newStatus.Branch = ""
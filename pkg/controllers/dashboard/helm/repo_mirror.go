@@ -0,0 +1,410 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2"
+	"github.com/rancher/rancher/pkg/catalogv2/content"
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+const (
+	mirrorCondition         = catalog.RepoMirrored
+	mirrorClusterRepoSuffix = "-mirror"
+	mirrorTimeout           = 1 * time.Hour
+	// mirrorProgressInterval is the minimum interval between two updates of the progress of a mirroring
+	mirrorProgressInterval = 10 * time.Second
+)
+
+// chartContent is the subset of the catalog content manager needed to read the charts of a ClusterRepo.
+type chartContent interface {
+	Index(namespace, name, targetK8sVersion string, skipFilter bool) (*repo.IndexFile, error)
+	Chart(namespace, name, chartName, version string, skipFilter bool) (io.ReadCloser, error)
+}
+
+type repoMirrorHandler struct {
+	ctx          context.Context
+	clusterRepos catalogcontrollers.ClusterRepoController
+	secrets      corev1controllers.SecretCache
+	content      chartContent
+	apply        apply.Apply
+	// mirrorCharts mirrors the charts of a ClusterRepo, reporting the number of chart versions processed so far
+	mirrorCharts func(ctx context.Context, clusterRepo *catalog.ClusterRepo, progress func(done, total int)) (int, error)
+
+	lock sync.Mutex
+	// runs are the mirrorings running in the background, by ClusterRepo name
+	runs map[string]*mirrorRun
+}
+
+// mirrorRun is the mirroring of a generation and an index of a ClusterRepo running in the background.
+type mirrorRun struct {
+	generation           int64
+	indexResourceVersion string
+	cancel               context.CancelFunc
+
+	lock     sync.Mutex
+	progress mirrorProgress
+}
+
+type mirrorProgress struct {
+	done     int
+	total    int
+	finished bool
+	mirrored int
+	err      error
+}
+
+func (r *mirrorRun) get() mirrorProgress {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.progress
+}
+
+func (r *mirrorRun) update(f func(progress *mirrorProgress)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	f(&r.progress)
+}
+
+func RegisterRepoMirror(ctx context.Context,
+	apply apply.Apply,
+	clusterRepos catalogcontrollers.ClusterRepoController,
+	secrets corev1controllers.SecretCache,
+	content chartContent) {
+	h := &repoMirrorHandler{
+		ctx:          ctx,
+		clusterRepos: clusterRepos,
+		secrets:      secrets,
+		content:      content,
+		apply:        apply.WithCacheTypes(clusterRepos).WithSetID("helm-clusterrepo-mirror"),
+		runs:         map[string]*mirrorRun{},
+	}
+	h.mirrorCharts = h.mirror
+
+	clusterRepos.OnChange(ctx, "helm-clusterrepo-mirror", h.onClusterRepoChange)
+}
+
+// onClusterRepoChange mirrors the charts of a ClusterRepo into the OCI registry configured in its spec.
+// It is triggered every time the index of the ClusterRepo or the spec changes. The mirroring runs in the
+// background, its progress being reported in the message of the Mirrored condition until it completes.
+func (m *repoMirrorHandler) onClusterRepoChange(key string, clusterRepo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+	if clusterRepo == nil {
+		m.stopMirror(key)
+		return nil, nil
+	}
+
+	if err := m.applyMirrorClusterRepo(clusterRepo); err != nil {
+		return clusterRepo, err
+	}
+
+	// Nothing to mirror until the index of the ClusterRepo has been downloaded
+	if clusterRepo.Spec.Mirror == nil || clusterRepo.Status.IndexConfigMapName == "" {
+		m.stopMirror(clusterRepo.Name)
+		return clusterRepo, nil
+	}
+
+	if clusterRepo.Status.MirrorObservedGeneration == clusterRepo.Generation &&
+		clusterRepo.Status.MirrorIndexConfigMapResourceVersion == clusterRepo.Status.IndexConfigMapResourceVersion &&
+		condition.Cond(mirrorCondition).IsTrue(clusterRepo) {
+		return clusterRepo, nil
+	}
+
+	run := m.startMirror(clusterRepo)
+	progress := run.get()
+
+	newStatus := clusterRepo.Status.DeepCopy()
+	if !progress.finished {
+		condition.Cond(mirrorCondition).Unknown(newStatus)
+		condition.Cond(mirrorCondition).Reason(newStatus, "")
+		if progress.total == 0 {
+			condition.Cond(mirrorCondition).Message(newStatus, "mirroring chart versions")
+		} else {
+			condition.Cond(mirrorCondition).Message(newStatus, fmt.Sprintf("mirrored %d of %d chart versions", progress.done, progress.total))
+		}
+	} else {
+		condition.Cond(mirrorCondition).SetError(newStatus, "", progress.err)
+		if progress.err == nil {
+			newStatus.MirrorObservedGeneration = run.generation
+			newStatus.MirrorIndexConfigMapResourceVersion = run.indexResourceVersion
+			newStatus.MirroredCharts = progress.mirrored
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(newStatus, &clusterRepo.Status) {
+		condition.Cond(mirrorCondition).LastUpdated(newStatus, timeNow().UTC().Format(time.RFC3339))
+		clusterRepo = clusterRepo.DeepCopy()
+		clusterRepo.Status = *newStatus
+		updated, err := m.clusterRepos.UpdateStatus(clusterRepo)
+		if err != nil {
+			return clusterRepo, err
+		}
+		clusterRepo = updated
+	}
+
+	if progress.finished {
+		// The result is only forgotten once recorded, a failed mirroring being retried as a new one
+		m.forgetMirror(clusterRepo.Name, run)
+		return clusterRepo, progress.err
+	}
+	return clusterRepo, nil
+}
+
+// startMirror returns the mirroring of the current generation and index of the ClusterRepo, starting it in the
+// background if needed. A mirroring of a previous generation or index is canceled.
+func (m *repoMirrorHandler) startMirror(clusterRepo *catalog.ClusterRepo) *mirrorRun {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if run, ok := m.runs[clusterRepo.Name]; ok {
+		if run.generation == clusterRepo.Generation && run.indexResourceVersion == clusterRepo.Status.IndexConfigMapResourceVersion {
+			return run
+		}
+		run.cancel()
+	}
+
+	logrus.Debugf("Mirroring clusterrepo %s into %s", clusterRepo.Name, clusterRepo.Spec.Mirror.URL)
+
+	ctx, cancel := context.WithTimeout(m.ctx, mirrorTimeout)
+	run := &mirrorRun{
+		generation:           clusterRepo.Generation,
+		indexResourceVersion: clusterRepo.Status.IndexConfigMapResourceVersion,
+		cancel:               cancel,
+	}
+	m.runs[clusterRepo.Name] = run
+
+	clusterRepo = clusterRepo.DeepCopy()
+	go func() {
+		defer cancel()
+
+		var lastEnqueued time.Time
+		mirrored, err := m.mirrorCharts(ctx, clusterRepo, func(done, total int) {
+			run.update(func(progress *mirrorProgress) {
+				progress.done, progress.total = done, total
+			})
+			if now := timeNow(); now.Sub(lastEnqueued) >= mirrorProgressInterval {
+				lastEnqueued = now
+				m.clusterRepos.Enqueue(clusterRepo.Name)
+			}
+		})
+		run.update(func(progress *mirrorProgress) {
+			progress.finished, progress.mirrored, progress.err = true, mirrored, err
+		})
+		m.clusterRepos.Enqueue(clusterRepo.Name)
+	}()
+
+	return run
+}
+
+// stopMirror cancels the mirroring of the ClusterRepo, if any.
+func (m *repoMirrorHandler) stopMirror(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if run, ok := m.runs[name]; ok {
+		run.cancel()
+		delete(m.runs, name)
+	}
+}
+
+// forgetMirror removes a finished mirroring of the ClusterRepo unless a new one replaced it.
+func (m *repoMirrorHandler) forgetMirror(name string, run *mirrorRun) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.runs[name] == run {
+		delete(m.runs, name)
+	}
+}
+
+// applyMirrorClusterRepo creates the companion ClusterRepo pointing at the mirror registry,
+// or removes it once it is no longer requested.
+func (m *repoMirrorHandler) applyMirrorClusterRepo(clusterRepo *catalog.ClusterRepo) error {
+	owner := toOwnerObject("", metav1.OwnerReference{
+		APIVersion: catalog.SchemeGroupVersion.Group + "/" + catalog.SchemeGroupVersion.Version,
+		Kind:       "ClusterRepo",
+		Name:       clusterRepo.Name,
+		UID:        clusterRepo.UID,
+	})
+
+	mirror := clusterRepo.Spec.Mirror
+	if mirror == nil || !mirror.GenerateClusterRepo {
+		return m.apply.WithOwner(owner).ApplyObjects()
+	}
+
+	return m.apply.WithOwner(owner).ApplyObjects(&catalog.ClusterRepo{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clusterRepo.Name + mirrorClusterRepoSuffix,
+		},
+		Spec: mirrorRepoSpec(mirror),
+	})
+}
+
+// mirror pushes every selected chart version of the ClusterRepo into the mirror registry
+// and returns the number of chart versions present in the mirror registry.
+// Chart versions whose digest is already present in the mirror registry are skipped.
+// The progress func is called with the number of chart versions processed so far.
+func (m *repoMirrorHandler) mirror(ctx context.Context, clusterRepo *catalog.ClusterRepo, progress func(done, total int)) (int, error) {
+	mirror := clusterRepo.Spec.Mirror
+
+	var constraint *semver.Constraints
+	if mirror.VersionRange != "" {
+		var err error
+		constraint, err = semver.NewConstraint(mirror.VersionRange)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse version range %s: %w", mirror.VersionRange, err)
+		}
+	}
+
+	targetSpec := mirrorRepoSpec(mirror)
+	secret, err := catalogv2.GetSecret(m.secrets, &targetSpec, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch the secret of the mirror registry: %w", err)
+	}
+
+	ociClient, err := oci.NewClient(mirror.URL, targetSpec, secret)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create an OCI client for url %s: %w", mirror.URL, err)
+	}
+
+	index, err := m.content.Index("", clusterRepo.Name, "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the index of clusterrepo %s: %w", clusterRepo.Name, err)
+	}
+
+	selected := map[string]bool{}
+	for _, chartName := range mirror.Charts {
+		selected[chartName] = true
+	}
+
+	chartNames := make([]string, 0, len(index.Entries))
+	for chartName := range index.Entries {
+		if !content.IsChartAllowed(clusterRepo.Spec.ChartFilter, chartName) {
+			continue
+		}
+		if len(selected) == 0 || selected[chartName] {
+			chartNames = append(chartNames, chartName)
+		}
+	}
+	sort.Strings(chartNames)
+
+	chartVersions := map[string][]*repo.ChartVersion{}
+	total := 0
+	for _, chartName := range chartNames {
+		for _, chartVersion := range index.Entries[chartName] {
+			if constraint != nil {
+				version, err := semver.NewVersion(chartVersion.Version)
+				if err != nil || !constraint.Check(version) {
+					continue
+				}
+			}
+			chartVersions[chartName] = append(chartVersions[chartName], chartVersion)
+			total++
+		}
+	}
+
+	var (
+		done     int
+		mirrored int
+		errs     []error
+	)
+	progress(done, total)
+	for _, chartName := range chartNames {
+		if len(chartVersions[chartName]) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		orasRepository, err := ociClient.GetChartOrasRepository(chartName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create an oras repository for chart %s: %w", chartName, err))
+			done += len(chartVersions[chartName])
+			progress(done, total)
+			continue
+		}
+
+		for _, chartVersion := range chartVersions[chartName] {
+			err := m.mirrorChartVersion(ctx, clusterRepo.Name, orasRepository, chartVersion)
+			done++
+			progress(done, total)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to mirror chart %s version %s: %w", chartName, chartVersion.Version, err))
+				continue
+			}
+			mirrored++
+		}
+	}
+
+	return mirrored, errors.Join(errs...)
+}
+
+// mirrorChartVersion pushes the chart version into the oras repository unless
+// a chart with the same digest is already tagged with its version.
+func (m *repoMirrorHandler) mirrorChartVersion(ctx context.Context, clusterRepoName string, orasRepository *remote.Repository, chartVersion *repo.ChartVersion) error {
+	existingDigest, err := oci.ChartLayerDigest(ctx, orasRepository, oci.VersionTag(chartVersion.Version))
+	if err != nil {
+		return err
+	}
+	// The digest of a chart in a Helm repository index is the sha256 of the chart tar,
+	// which is also the digest of the chart layer once pushed into an OCI registry.
+	if existingDigest != "" && chartVersion.Digest != "" && existingDigest.Encoded() == chartVersion.Digest {
+		return nil
+	}
+
+	reader, err := m.content.Chart("", clusterRepoName, chartVersion.Name, chartVersion.Version, true)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	chartTar, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if existingDigest == digest.FromBytes(chartTar) {
+		return nil
+	}
+
+	// Index entries of OCI ClusterRepos only hold the name and the version of
+	// most charts, so the metadata is always read from the chart tar itself.
+	helmChart, err := loader.LoadArchive(bytes.NewReader(chartTar))
+	if err != nil {
+		return fmt.Errorf("failed to load the chart: %w", err)
+	}
+
+	_, err = oci.PushChart(ctx, orasRepository, helmChart.Metadata, chartTar)
+	return err
+}
+
+// mirrorRepoSpec returns the RepoSpec of a ClusterRepo pointing at the mirror registry.
+func mirrorRepoSpec(mirror *catalog.RepoMirrorSpec) catalog.RepoSpec {
+	return catalog.RepoSpec{
+		URL:                   mirror.URL,
+		InsecurePlainHTTP:     mirror.InsecurePlainHTTP,
+		InsecureSkipTLSverify: mirror.InsecureSkipTLSverify,
+		CABundle:              mirror.CABundle,
+		ClientSecret:          mirror.ClientSecret,
+	}
+}
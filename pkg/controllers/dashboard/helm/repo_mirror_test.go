@@ -0,0 +1,181 @@
+package helm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	applyfake "github.com/rancher/wrangler/v3/pkg/apply/fake"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testMirroredClusterRepo() *catalog.ClusterRepo {
+	return &catalog.ClusterRepo{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "charts",
+			UID:        "repo-uid",
+			Generation: 2,
+		},
+		Spec: catalog.RepoSpec{
+			URL:    "https://charts.example.com",
+			Mirror: &catalog.RepoMirrorSpec{URL: "oci://registry.example.com/charts"},
+		},
+		Status: catalog.RepoStatus{
+			IndexConfigMapName:            "charts-0-repo-uid",
+			IndexConfigMapResourceVersion: "100",
+		},
+	}
+}
+
+// newRepoMirrorHandler returns a handler whose mirroring reports the progress sent on the returned channel and
+// returns once it is closed.
+func newRepoMirrorHandler(t *testing.T, result error) (*repoMirrorHandler, *fake.MockNonNamespacedControllerInterface[*catalog.ClusterRepo, *catalog.ClusterRepoList], chan int) {
+	clusterRepos := fake.NewMockNonNamespacedControllerInterface[*catalog.ClusterRepo, *catalog.ClusterRepoList](gomock.NewController(t))
+	clusterRepos.EXPECT().Enqueue("charts").AnyTimes()
+	progress := make(chan int)
+	m := &repoMirrorHandler{
+		ctx:          context.Background(),
+		clusterRepos: clusterRepos,
+		apply:        &applyfake.FakeApply{},
+		runs:         map[string]*mirrorRun{},
+		mirrorCharts: func(ctx context.Context, _ *catalog.ClusterRepo, report func(done, total int)) (int, error) {
+			mirrored := 0
+			for {
+				select {
+				case done, ok := <-progress:
+					if !ok {
+						return mirrored, result
+					}
+					mirrored = done
+					report(done, 3)
+				case <-ctx.Done():
+					return mirrored, ctx.Err()
+				}
+			}
+		},
+	}
+	return m, clusterRepos, progress
+}
+
+// waitForMirror waits until the mirroring of the ClusterRepo reports the progress.
+func waitForMirror(t *testing.T, m *repoMirrorHandler, check func(progress mirrorProgress) bool) {
+	assert.Eventually(t, func() bool {
+		m.lock.Lock()
+		run := m.runs["charts"]
+		m.lock.Unlock()
+		return run != nil && check(run.get())
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func expectMirrorStatus(clusterRepos *fake.MockNonNamespacedControllerInterface[*catalog.ClusterRepo, *catalog.ClusterRepoList], status *catalog.RepoStatus) {
+	clusterRepos.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(clusterRepo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+		*status = clusterRepo.Status
+		return clusterRepo, nil
+	})
+}
+
+func TestOnClusterRepoChangeMirrorsInBackground(t *testing.T) {
+	m, clusterRepos, progress := newRepoMirrorHandler(t, nil)
+	clusterRepo := testMirroredClusterRepo()
+
+	// the mirroring is started without waiting for it
+	var status catalog.RepoStatus
+	expectMirrorStatus(clusterRepos, &status)
+	_, err := m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.Equal(t, string(corev1.ConditionUnknown), condition.Cond(mirrorCondition).GetStatus(&status))
+	assert.Equal(t, "mirroring chart versions", condition.Cond(mirrorCondition).GetMessage(&status))
+
+	// its progress is reported while it runs
+	progress <- 1
+	waitForMirror(t, m, func(p mirrorProgress) bool { return p.done == 1 })
+	clusterRepo.Status = status
+	expectMirrorStatus(clusterRepos, &status)
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.Equal(t, string(corev1.ConditionUnknown), condition.Cond(mirrorCondition).GetStatus(&status))
+	assert.Equal(t, "mirrored 1 of 3 chart versions", condition.Cond(mirrorCondition).GetMessage(&status))
+
+	// an unchanged progress doesn't update the status
+	clusterRepo.Status = status
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+
+	// its result is recorded once it completes
+	progress <- 3
+	close(progress)
+	waitForMirror(t, m, func(p mirrorProgress) bool { return p.finished })
+	expectMirrorStatus(clusterRepos, &status)
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.True(t, condition.Cond(mirrorCondition).IsTrue(&status))
+	assert.Equal(t, int64(2), status.MirrorObservedGeneration)
+	assert.Equal(t, "100", status.MirrorIndexConfigMapResourceVersion)
+	assert.Equal(t, 3, status.MirroredCharts)
+	assert.Empty(t, m.runs)
+
+	// and nothing is mirrored again until the index or the spec changes
+	clusterRepo.Status = status
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.Empty(t, m.runs)
+}
+
+func TestOnClusterRepoChangeMirrorFailed(t *testing.T) {
+	m, clusterRepos, progress := newRepoMirrorHandler(t, errors.New("registry unavailable"))
+	clusterRepo := testMirroredClusterRepo()
+
+	var status catalog.RepoStatus
+	expectMirrorStatus(clusterRepos, &status)
+	_, err := m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+
+	close(progress)
+	waitForMirror(t, m, func(p mirrorProgress) bool { return p.finished })
+	clusterRepo.Status = status
+	expectMirrorStatus(clusterRepos, &status)
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	assert.EqualError(t, err, "registry unavailable")
+	assert.True(t, condition.Cond(mirrorCondition).IsFalse(&status))
+	assert.Equal(t, "registry unavailable", condition.Cond(mirrorCondition).GetMessage(&status))
+	assert.Zero(t, status.MirrorObservedGeneration)
+	// the failed mirroring is retried as a new one
+	assert.Empty(t, m.runs)
+}
+
+func TestOnClusterRepoChangeCancelsOutdatedMirror(t *testing.T) {
+	m, clusterRepos, _ := newRepoMirrorHandler(t, nil)
+	clusterRepo := testMirroredClusterRepo()
+
+	var status catalog.RepoStatus
+	expectMirrorStatus(clusterRepos, &status)
+	_, err := m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	outdated := m.runs["charts"]
+
+	// a new index cancels the mirroring of the previous one
+	clusterRepo.Status = status
+	clusterRepo.Status.IndexConfigMapResourceVersion = "101"
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return outdated.get().finished }, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, outdated.get().err, context.Canceled)
+	require.Contains(t, m.runs, "charts")
+	assert.Equal(t, "101", m.runs["charts"].indexResourceVersion)
+
+	// removing the mirror from the spec cancels the running mirroring
+	current := m.runs["charts"]
+	clusterRepo.Spec.Mirror = nil
+	_, err = m.onClusterRepoChange("charts", clusterRepo)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return current.get().finished }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, m.runs)
+}
@@ -0,0 +1,259 @@
+// Package accessrequest validates AccessRequests, lets their requesters read them, and creates the
+// ClusterRoleTemplateBinding or ProjectRoleTemplateBinding granting the requested access once they are approved,
+// provided the approver could grant the requested RoleTemplate themselves.
+package accessrequest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/project_cluster"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/rancher/pkg/types/config"
+	rbaccontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	controllerName = "mgmt-auth-access-request-controller"
+
+	// AccessRequestLabel is set on the bindings created for an AccessRequest, with the name of the request as value.
+	AccessRequestLabel = "management.cattle.io/access-request"
+)
+
+// escalationChecker checks that users only grant the RoleTemplates they could bind themselves.
+type escalationChecker interface {
+	ConfirmNoEscalation(userName, clusterName, projectName string, roleTemplate *v3.RoleTemplate) error
+}
+
+type handler struct {
+	requests      mgmtcontrollers.AccessRequestController
+	roleTemplates mgmtcontrollers.RoleTemplateCache
+	clusters      mgmtcontrollers.ClusterCache
+	projects      mgmtcontrollers.ProjectCache
+	crtbs         mgmtcontrollers.ClusterRoleTemplateBindingClient
+	prtbs         mgmtcontrollers.ProjectRoleTemplateBindingClient
+	roles         rbaccontrollers.RoleClient
+	roleBindings  rbaccontrollers.RoleBindingClient
+	escalation    escalationChecker
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	h := &handler{
+		requests:      management.Wrangler.Mgmt.AccessRequest(),
+		roleTemplates: management.Wrangler.Mgmt.RoleTemplate().Cache(),
+		clusters:      management.Wrangler.Mgmt.Cluster().Cache(),
+		projects:      management.Wrangler.Mgmt.Project().Cache(),
+		crtbs:         management.Wrangler.Mgmt.ClusterRoleTemplateBinding(),
+		prtbs:         management.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+		roles:         management.Wrangler.RBAC.Role(),
+		roleBindings:  management.Wrangler.RBAC.RoleBinding(),
+		escalation:    rbac.NewEscalationChecker(management.Wrangler.Mgmt, management.Wrangler.RBAC),
+	}
+	h.requests.OnChange(ctx, controllerName, h.sync)
+}
+
+func (h *handler) sync(_ string, request *v3.AccessRequest) (*v3.AccessRequest, error) {
+	if request == nil || request.DeletionTimestamp != nil {
+		return request, nil
+	}
+
+	switch request.Status.State {
+	case "":
+		if err := h.ensureRequesterRole(request); err != nil {
+			return request, err
+		}
+		request = request.DeepCopy()
+		if err := h.validate(request); err != nil {
+			request.Status.State = v3.AccessRequestFailed
+			request.Status.Message = err.Error()
+		} else {
+			request.Status.State = v3.AccessRequestPending
+		}
+		return h.requests.UpdateStatus(request)
+	case v3.AccessRequestApproved:
+		if request.Status.BindingName != "" {
+			return request, nil
+		}
+		request = request.DeepCopy()
+		// the request is validated again, as the RoleTemplate may have been locked since it was requested
+		if err := h.validate(request); err != nil {
+			request.Status.State = v3.AccessRequestFailed
+			request.Status.Message = err.Error()
+			return h.requests.UpdateStatus(request)
+		}
+		// the binding is created by Rancher, the approver must be allowed to create it
+		if err := h.confirmApprover(request); errors.Is(err, rbac.ErrEscalation) {
+			request.Status.State = v3.AccessRequestFailed
+			request.Status.Message = err.Error()
+			return h.requests.UpdateStatus(request)
+		} else if err != nil {
+			return request, err
+		}
+		bindingName, err := h.grant(request)
+		if err != nil {
+			return request, err
+		}
+		logrus.Infof("[accessRequest] granted RoleTemplate [%s] to user [%s] for access request [%s/%s], approved by [%s]",
+			request.Spec.RoleTemplateName, request.Spec.UserName, request.Namespace, request.Name, request.Status.DecidedBy)
+		request.Status.BindingName = bindingName
+		return h.requests.UpdateStatus(request)
+	}
+	return request, nil
+}
+
+// ensureRequesterRole lets the requester get and watch their request, to follow its decision: the global role of the
+// users only allows them to create requests. The Role and RoleBinding are owned by the request, so that they are
+// removed with it. Nothing is granted for a request not created by its requester, which fails validation.
+func (h *handler) ensureRequesterRole(request *v3.AccessRequest) error {
+	if request.Spec.UserName == "" || request.Annotations[project_cluster.CreatorIDAnnotation] != request.Spec.UserName {
+		return nil
+	}
+	meta := metav1.ObjectMeta{
+		Name:      name.SafeConcatName("ar", request.Name, "requester"),
+		Namespace: request.Namespace,
+		Labels:    map[string]string{AccessRequestLabel: request.Name},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: v3.SchemeGroupVersion.String(),
+			Kind:       "AccessRequest",
+			Name:       request.Name,
+			UID:        request.UID,
+		}},
+	}
+	_, err := h.roles.Create(&rbacv1.Role{
+		ObjectMeta: meta,
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{v3.SchemeGroupVersion.Group},
+			Resources:     []string{v3.AccessRequestResourceName},
+			ResourceNames: []string{request.Name},
+			Verbs:         []string{"get", "watch"},
+		}},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = h.roleBindings.Create(&rbacv1.RoleBinding{
+		ObjectMeta: meta,
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     meta.Name,
+		},
+		Subjects: []rbacv1.Subject{{
+			APIGroup: rbacv1.GroupName,
+			Kind:     rbacv1.UserKind,
+			Name:     request.Spec.UserName,
+		}},
+	})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// validate checks that the requested access can be granted.
+func (h *handler) validate(request *v3.AccessRequest) error {
+	spec := request.Spec
+	if spec.UserName == "" {
+		return fmt.Errorf("userName is required")
+	}
+	// the requester is the user who created the request, recorded by the API when the request is created
+	if creator := request.Annotations[project_cluster.CreatorIDAnnotation]; creator != spec.UserName {
+		return fmt.Errorf("userName [%s] is not the user who created the request", spec.UserName)
+	}
+	if spec.Duration != "" {
+		if duration, err := time.ParseDuration(spec.Duration); err != nil {
+			return fmt.Errorf("invalid duration %q: %w", spec.Duration, err)
+		} else if duration <= 0 {
+			return fmt.Errorf("duration must be positive")
+		}
+	}
+	if _, err := h.clusters.Get(spec.ClusterName); err != nil {
+		return fmt.Errorf("cluster [%s]: %w", spec.ClusterName, err)
+	}
+
+	roleContext := "cluster"
+	if request.IsProjectRequest() {
+		roleContext = "project"
+		clusterName, projectName, ok := strings.Cut(spec.ProjectName, ":")
+		if !ok || clusterName != spec.ClusterName {
+			return fmt.Errorf("projectName %q must be in the format %s:<project>", spec.ProjectName, spec.ClusterName)
+		}
+		if projectName != request.Namespace {
+			return fmt.Errorf("project access requests must be in the namespace of the project [%s]", projectName)
+		}
+		if _, err := h.projects.Get(clusterName, projectName); err != nil {
+			return fmt.Errorf("project [%s]: %w", spec.ProjectName, err)
+		}
+	} else if spec.ClusterName != request.Namespace {
+		return fmt.Errorf("cluster access requests must be in the namespace of the cluster [%s]", spec.ClusterName)
+	}
+
+	roleTemplate, err := h.roleTemplates.Get(spec.RoleTemplateName)
+	if err != nil {
+		return fmt.Errorf("roleTemplate [%s]: %w", spec.RoleTemplateName, err)
+	}
+	if roleTemplate.Context != roleContext {
+		return fmt.Errorf("roleTemplate [%s] is not a %s role", spec.RoleTemplateName, roleContext)
+	}
+	if roleTemplate.Locked {
+		return fmt.Errorf("roleTemplate [%s] is locked", spec.RoleTemplateName)
+	}
+	return nil
+}
+
+// confirmApprover returns an error wrapping rbac.ErrEscalation if the approver of the request can't grant the
+// requested RoleTemplate.
+func (h *handler) confirmApprover(request *v3.AccessRequest) error {
+	roleTemplate, err := h.roleTemplates.Get(request.Spec.RoleTemplateName)
+	if err != nil {
+		return err
+	}
+	return h.escalation.ConfirmNoEscalation(request.Status.DecidedBy, request.Spec.ClusterName, request.Spec.ProjectName, roleTemplate)
+}
+
+// grant creates the binding granting the access of an approved request and returns its name. The approver is
+// recorded as the creator of the binding.
+func (h *handler) grant(request *v3.AccessRequest) (string, error) {
+	meta := metav1.ObjectMeta{
+		Name:      name.SafeConcatName("ar", request.Name),
+		Namespace: request.Namespace,
+		Labels:    map[string]string{AccessRequestLabel: request.Name},
+		Annotations: map[string]string{
+			project_cluster.CreatorIDAnnotation: request.Status.DecidedBy,
+		},
+	}
+
+	var err error
+	if request.IsProjectRequest() {
+		_, err = h.prtbs.Create(&v3.ProjectRoleTemplateBinding{
+			ObjectMeta:       meta,
+			UserName:         request.Spec.UserName,
+			ProjectName:      request.Spec.ProjectName,
+			RoleTemplateName: request.Spec.RoleTemplateName,
+			TTL:              request.Spec.Duration,
+		})
+	} else {
+		_, err = h.crtbs.Create(&v3.ClusterRoleTemplateBinding{
+			ObjectMeta:       meta,
+			UserName:         request.Spec.UserName,
+			ClusterName:      request.Spec.ClusterName,
+			RoleTemplateName: request.Spec.RoleTemplateName,
+			TTL:              request.Spec.Duration,
+		})
+	}
+	// the binding already exists if the status update failed after it was created
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return meta.Name, nil
+}
@@ -0,0 +1,277 @@
+package accessrequest
+
+import (
+	"fmt"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/project_cluster"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeEscalationChecker allows u-approver to grant any RoleTemplate.
+type fakeEscalationChecker struct{}
+
+func (fakeEscalationChecker) ConfirmNoEscalation(userName, _, _ string, roleTemplate *v3.RoleTemplate) error {
+	if userName != "u-approver" {
+		return fmt.Errorf("%w: user [%s] can't grant RoleTemplate [%s]", rbac.ErrEscalation, userName, roleTemplate.Name)
+	}
+	return nil
+}
+
+type mocks struct {
+	requests *fake.MockControllerInterface[*v3.AccessRequest, *v3.AccessRequestList]
+	crtbs    *fake.MockClientInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList]
+	prtbs    *fake.MockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList]
+	roles    *fake.MockClientInterface[*rbacv1.Role, *rbacv1.RoleList]
+	bindings *fake.MockClientInterface[*rbacv1.RoleBinding, *rbacv1.RoleBindingList]
+}
+
+func newHandler(t *testing.T) (*handler, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		requests: fake.NewMockControllerInterface[*v3.AccessRequest, *v3.AccessRequestList](ctrl),
+		crtbs:    fake.NewMockClientInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList](ctrl),
+		prtbs:    fake.NewMockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList](ctrl),
+		roles:    fake.NewMockClientInterface[*rbacv1.Role, *rbacv1.RoleList](ctrl),
+		bindings: fake.NewMockClientInterface[*rbacv1.RoleBinding, *rbacv1.RoleBindingList](ctrl),
+	}
+	m.roles.EXPECT().Create(gomock.Any()).DoAndReturn(func(obj *rbacv1.Role) (*rbacv1.Role, error) {
+		return obj, nil
+	}).AnyTimes()
+	m.bindings.EXPECT().Create(gomock.Any()).DoAndReturn(func(obj *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+		return obj, nil
+	}).AnyTimes()
+	m.requests.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.AccessRequest) (*v3.AccessRequest, error) {
+		return obj, nil
+	}).AnyTimes()
+
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"cluster-owner": {ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner"}, Context: "cluster"},
+		"project-owner": {ObjectMeta: metav1.ObjectMeta{Name: "project-owner"}, Context: "project"},
+		"locked":        {ObjectMeta: metav1.ObjectMeta{Name: "locked"}, Context: "cluster", Locked: true},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		if rt, ok := roleTemplates[name]; ok {
+			return rt, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+	}).AnyTimes()
+
+	clusterCache := fake.NewMockNonNamespacedCacheInterface[*v3.Cluster](ctrl)
+	clusterCache.EXPECT().Get("c-1").Return(&v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1"}}, nil).AnyTimes()
+	projectCache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+	projectCache.EXPECT().Get("c-1", "p-1").Return(&v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-1", Namespace: "c-1"}}, nil).AnyTimes()
+
+	return &handler{
+		requests:      m.requests,
+		roleTemplates: roleTemplateCache,
+		clusters:      clusterCache,
+		projects:      projectCache,
+		crtbs:         m.crtbs,
+		prtbs:         m.prtbs,
+		roles:         m.roles,
+		roleBindings:  m.bindings,
+		escalation:    fakeEscalationChecker{},
+	}, m
+}
+
+func newRequest(namespace, projectName, roleTemplateName string) *v3.AccessRequest {
+	return &v3.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "request",
+			Namespace:   namespace,
+			Annotations: map[string]string{project_cluster.CreatorIDAnnotation: "u-requester"},
+		},
+		Spec: v3.AccessRequestSpec{
+			UserName:         "u-requester",
+			RoleTemplateName: roleTemplateName,
+			ClusterName:      "c-1",
+			ProjectName:      projectName,
+			Duration:         "8h",
+		},
+	}
+}
+
+func TestSyncValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     *v3.AccessRequest
+		wantState   string
+		wantMessage string
+	}{
+		{
+			name:      "cluster request",
+			request:   newRequest("c-1", "", "cluster-owner"),
+			wantState: v3.AccessRequestPending,
+		},
+		{
+			name:      "project request",
+			request:   newRequest("p-1", "c-1:p-1", "project-owner"),
+			wantState: v3.AccessRequestPending,
+		},
+		{
+			name:        "project role on cluster",
+			request:     newRequest("c-1", "", "project-owner"),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: "roleTemplate [project-owner] is not a cluster role",
+		},
+		{
+			name:        "locked role",
+			request:     newRequest("c-1", "", "locked"),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: "roleTemplate [locked] is locked",
+		},
+		{
+			name:        "wrong namespace",
+			request:     newRequest("p-1", "", "cluster-owner"),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: "cluster access requests must be in the namespace of the cluster [c-1]",
+		},
+		{
+			name: "requested on behalf of another user",
+			request: func() *v3.AccessRequest {
+				request := newRequest("c-1", "", "cluster-owner")
+				request.Annotations[project_cluster.CreatorIDAnnotation] = "u-other"
+				return request
+			}(),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: "userName [u-requester] is not the user who created the request",
+		},
+		{
+			name: "no creator",
+			request: func() *v3.AccessRequest {
+				request := newRequest("c-1", "", "cluster-owner")
+				request.Annotations = nil
+				return request
+			}(),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: "is not the user who created the request",
+		},
+		{
+			name: "invalid duration",
+			request: func() *v3.AccessRequest {
+				request := newRequest("c-1", "", "cluster-owner")
+				request.Spec.Duration = "a day"
+				return request
+			}(),
+			wantState:   v3.AccessRequestFailed,
+			wantMessage: `invalid duration "a day"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newHandler(t)
+			got, err := h.sync("", tt.request)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantState, got.Status.State)
+			if tt.wantMessage != "" {
+				assert.Contains(t, got.Status.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSyncApprovedCluster(t *testing.T) {
+	h, m := newHandler(t)
+	request := newRequest("c-1", "", "cluster-owner")
+	request.Status = v3.AccessRequestStatus{State: v3.AccessRequestApproved, DecidedBy: "u-approver"}
+
+	m.crtbs.EXPECT().Create(gomock.Any()).DoAndReturn(func(crtb *v3.ClusterRoleTemplateBinding) (*v3.ClusterRoleTemplateBinding, error) {
+		assert.Equal(t, "ar-request", crtb.Name)
+		assert.Equal(t, "c-1", crtb.Namespace)
+		assert.Equal(t, "u-requester", crtb.UserName)
+		assert.Equal(t, "cluster-owner", crtb.RoleTemplateName)
+		assert.Equal(t, "8h", crtb.TTL)
+		assert.Equal(t, "u-approver", crtb.Annotations[project_cluster.CreatorIDAnnotation])
+		assert.Equal(t, "request", crtb.Labels[AccessRequestLabel])
+		return crtb, nil
+	})
+
+	got, err := h.sync("", request)
+	require.NoError(t, err)
+	assert.Equal(t, v3.AccessRequestApproved, got.Status.State)
+	assert.Equal(t, "ar-request", got.Status.BindingName)
+
+	// once granted, the request is left alone
+	_, err = h.sync("", got)
+	require.NoError(t, err)
+}
+
+func TestSyncApprovedProject(t *testing.T) {
+	h, m := newHandler(t)
+	request := newRequest("p-1", "c-1:p-1", "project-owner")
+	request.Status = v3.AccessRequestStatus{State: v3.AccessRequestApproved, DecidedBy: "u-approver"}
+
+	// the binding already exists when the status could not be updated after creating it
+	m.prtbs.EXPECT().Create(gomock.Any()).DoAndReturn(func(prtb *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+		assert.Equal(t, "p-1", prtb.Namespace)
+		assert.Equal(t, "c-1:p-1", prtb.ProjectName)
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "projectroletemplatebindings"}, prtb.Name)
+	})
+
+	got, err := h.sync("", request)
+	require.NoError(t, err)
+	assert.Equal(t, "ar-request", got.Status.BindingName)
+}
+
+func TestSyncApprovedEscalation(t *testing.T) {
+	// no binding is created, the mocks fail on any creation
+	h, _ := newHandler(t)
+	request := newRequest("c-1", "", "cluster-owner")
+	request.Status = v3.AccessRequestStatus{State: v3.AccessRequestApproved, DecidedBy: "u-project-member"}
+
+	got, err := h.sync("", request)
+	require.NoError(t, err)
+	assert.Equal(t, v3.AccessRequestFailed, got.Status.State)
+	assert.Contains(t, got.Status.Message, "user [u-project-member] can't grant RoleTemplate [cluster-owner]")
+	assert.Empty(t, got.Status.BindingName)
+}
+
+func TestSyncDenied(t *testing.T) {
+	h, _ := newHandler(t)
+	request := newRequest("c-1", "", "cluster-owner")
+	request.Status = v3.AccessRequestStatus{State: v3.AccessRequestDenied, DecidedBy: "u-approver"}
+
+	got, err := h.sync("", request)
+	require.NoError(t, err)
+	assert.Empty(t, got.Status.BindingName)
+}
+
+func TestEnsureRequesterRole(t *testing.T) {
+	h, m := newHandler(t)
+	m.roles = fake.NewMockClientInterface[*rbacv1.Role, *rbacv1.RoleList](gomock.NewController(t))
+	m.bindings = fake.NewMockClientInterface[*rbacv1.RoleBinding, *rbacv1.RoleBindingList](gomock.NewController(t))
+	h.roles, h.roleBindings = m.roles, m.bindings
+
+	m.roles.EXPECT().Create(gomock.Any()).DoAndReturn(func(role *rbacv1.Role) (*rbacv1.Role, error) {
+		assert.Equal(t, "c-1", role.Namespace)
+		assert.Equal(t, []rbacv1.PolicyRule{{
+			APIGroups:     []string{"management.cattle.io"},
+			Resources:     []string{"accessrequests"},
+			ResourceNames: []string{"request"},
+			Verbs:         []string{"get", "watch"},
+		}}, role.Rules)
+		assert.Equal(t, "request", role.OwnerReferences[0].Name)
+		return role, nil
+	})
+	m.bindings.EXPECT().Create(gomock.Any()).DoAndReturn(func(binding *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+		assert.Equal(t, []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "u-requester"}}, binding.Subjects)
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "rolebindings"}, binding.Name)
+	})
+	require.NoError(t, h.ensureRequesterRole(newRequest("c-1", "", "cluster-owner")))
+
+	// nothing is granted for a request not created by its requester
+	request := newRequest("c-1", "", "cluster-owner")
+	request.Annotations[project_cluster.CreatorIDAnnotation] = "u-other"
+	require.NoError(t, h.ensureRequesterRole(request))
+}
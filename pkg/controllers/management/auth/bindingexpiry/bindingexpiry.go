@@ -0,0 +1,344 @@
+// Package bindingexpiry removes time-bound GlobalRoleBindings, ClusterRoleTemplateBindings and
+// ProjectRoleTemplateBindings once they expire.
+package bindingexpiry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/project_cluster"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	controllerName = "mgmt-auth-binding-expiry-controller"
+
+	// GrantedAnnotation holds the expiry of the binding for which the grant was recorded, so that the grant is
+	// recorded again when the expiry is changed.
+	GrantedAnnotation = "auth.management.cattle.io/expiry-granted"
+	// GrantedByAnnotation holds the user who granted the binding when the grant was recorded: the user who last set its
+	// expiry through the Rancher APIs, or its creator if the expiry wasn't changed since it was created.
+	GrantedByAnnotation = "auth.management.cattle.io/expiry-granted-by"
+	// ExpirySetByAnnotation holds the user who last changed the expiry of the binding through the Rancher APIs, which
+	// set it to the authenticated user making the change.
+	ExpirySetByAnnotation = "auth.management.cattle.io/expiry-set-by"
+	// ExpirySetForAnnotation holds the expiry, as returned by ExpirySpec, that ExpirySetByAnnotation was recorded for,
+	// so that a later change of the expiry made outside the Rancher APIs isn't attributed to that user.
+	ExpirySetForAnnotation = "auth.management.cattle.io/expiry-set-for"
+	// WarnedAnnotation holds the expiry of the binding for which the warning event was created.
+	WarnedAnnotation = "auth.management.cattle.io/expiry-warned"
+
+	grantedEventReason  = "TimeBoundAccessGranted"
+	expiringEventReason = "TimeBoundAccessExpiring"
+	expiredEventReason  = "TimeBoundAccessExpired"
+)
+
+// binding is the part of a GlobalRoleBinding, ClusterRoleTemplateBinding or ProjectRoleTemplateBinding which is
+// relevant to its expiry.
+type binding struct {
+	object
+	kind      schema.GroupVersionKind
+	expiresAt *metav1.Time
+	ttl       string
+	// subject is the user or group the binding grants access to.
+	subject string
+	// access describes the role granted by the binding and where it is granted.
+	access string
+}
+
+type object interface {
+	metav1.Object
+	runtime.Object
+}
+
+// expiry returns when the binding expires, or the zero time if it doesn't.
+func (b *binding) expiry() (time.Time, error) {
+	if b.expiresAt != nil {
+		return b.expiresAt.Time, nil
+	}
+	if b.ttl == "" {
+		return time.Time{}, nil
+	}
+	ttl, err := time.ParseDuration(b.ttl)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ttl %q: %w", b.ttl, err)
+	}
+	return b.GetCreationTimestamp().Add(ttl), nil
+}
+
+// actions are the operations on the typed binding that the handler needs.
+type actions struct {
+	// annotate sets the annotations on the binding and updates it.
+	annotate     func(annotations map[string]string) error
+	delete       func() error
+	enqueueAfter func(after time.Duration)
+}
+
+type handler struct {
+	grbs     mgmtcontrollers.GlobalRoleBindingController
+	crtbs    mgmtcontrollers.ClusterRoleTemplateBindingController
+	prtbs    mgmtcontrollers.ProjectRoleTemplateBindingController
+	recorder record.EventRecorder
+	now      func() time.Time
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	h := &handler{
+		grbs:     management.Wrangler.Mgmt.GlobalRoleBinding(),
+		crtbs:    management.Wrangler.Mgmt.ClusterRoleTemplateBinding(),
+		prtbs:    management.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+		recorder: management.Wrangler.EventRecorder,
+		now:      time.Now,
+	}
+	h.grbs.OnChange(ctx, controllerName, h.syncGRB)
+	h.crtbs.OnChange(ctx, controllerName, h.syncCRTB)
+	h.prtbs.OnChange(ctx, controllerName, h.syncPRTB)
+}
+
+func (h *handler) syncGRB(_ string, grb *v3.GlobalRoleBinding) (*v3.GlobalRoleBinding, error) {
+	if grb == nil || grb.DeletionTimestamp != nil {
+		return grb, nil
+	}
+	b := &binding{
+		object:    grb,
+		kind:      v3.SchemeGroupVersion.WithKind("GlobalRoleBinding"),
+		expiresAt: grb.ExpiresAt,
+		ttl:       grb.TTL,
+		subject:   subjectOf(grb.UserName, grb.GroupPrincipalName),
+		access:    fmt.Sprintf("GlobalRole [%s]", grb.GlobalRoleName),
+	}
+	err := h.sync(b, actions{
+		annotate: func(annotations map[string]string) error {
+			updated := grb.DeepCopy()
+			updated.Annotations = annotations
+			updated, err := h.grbs.Update(updated)
+			if err != nil {
+				return err
+			}
+			grb = updated
+			return nil
+		},
+		delete: func() error {
+			return h.grbs.Delete(grb.Name, &metav1.DeleteOptions{})
+		},
+		enqueueAfter: func(after time.Duration) {
+			h.grbs.EnqueueAfter(grb.Name, after)
+		},
+	})
+	return grb, err
+}
+
+func (h *handler) syncCRTB(_ string, crtb *v3.ClusterRoleTemplateBinding) (*v3.ClusterRoleTemplateBinding, error) {
+	if crtb == nil || crtb.DeletionTimestamp != nil {
+		return crtb, nil
+	}
+	b := &binding{
+		object:    crtb,
+		kind:      v3.SchemeGroupVersion.WithKind("ClusterRoleTemplateBinding"),
+		expiresAt: crtb.ExpiresAt,
+		ttl:       crtb.TTL,
+		subject:   subjectOf(crtb.UserName, crtb.UserPrincipalName, crtb.GroupName, crtb.GroupPrincipalName),
+		access:    fmt.Sprintf("RoleTemplate [%s] in cluster [%s]", crtb.RoleTemplateName, crtb.ClusterName),
+	}
+	err := h.sync(b, actions{
+		annotate: func(annotations map[string]string) error {
+			updated := crtb.DeepCopy()
+			updated.Annotations = annotations
+			updated, err := h.crtbs.Update(updated)
+			if err != nil {
+				return err
+			}
+			crtb = updated
+			return nil
+		},
+		delete: func() error {
+			return h.crtbs.Delete(crtb.Namespace, crtb.Name, &metav1.DeleteOptions{})
+		},
+		enqueueAfter: func(after time.Duration) {
+			h.crtbs.EnqueueAfter(crtb.Namespace, crtb.Name, after)
+		},
+	})
+	return crtb, err
+}
+
+func (h *handler) syncPRTB(_ string, prtb *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+	if prtb == nil || prtb.DeletionTimestamp != nil {
+		return prtb, nil
+	}
+	b := &binding{
+		object:    prtb,
+		kind:      v3.SchemeGroupVersion.WithKind("ProjectRoleTemplateBinding"),
+		expiresAt: prtb.ExpiresAt,
+		ttl:       prtb.TTL,
+		subject:   subjectOf(prtb.UserName, prtb.UserPrincipalName, prtb.GroupName, prtb.GroupPrincipalName),
+		access:    fmt.Sprintf("RoleTemplate [%s] in project [%s]", prtb.RoleTemplateName, prtb.ProjectName),
+	}
+	err := h.sync(b, actions{
+		annotate: func(annotations map[string]string) error {
+			updated := prtb.DeepCopy()
+			updated.Annotations = annotations
+			updated, err := h.prtbs.Update(updated)
+			if err != nil {
+				return err
+			}
+			prtb = updated
+			return nil
+		},
+		delete: func() error {
+			return h.prtbs.Delete(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{})
+		},
+		enqueueAfter: func(after time.Duration) {
+			h.prtbs.EnqueueAfter(prtb.Namespace, prtb.Name, after)
+		},
+	})
+	return prtb, err
+}
+
+// sync records the grant of a time-bound binding, warns before it expires and deletes it once expired. The
+// lifecycles of the bindings then remove the RBAC they created in Rancher and in the downstream clusters.
+func (h *handler) sync(b *binding, a actions) error {
+	expiry, err := b.expiry()
+	if err != nil {
+		// a binding whose expiry can't be known is removed rather than granting access forever
+		message := fmt.Sprintf("%s of [%s] removed: %v", b.access, b.subject, err)
+		logrus.Warnf("[bindingExpiry] %s [%s]: %s", b.kind.Kind, key(b), message)
+		if err := a.delete(); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		h.recordEvent(b, event{eventType: corev1.EventTypeWarning, reason: expiredEventReason, message: message})
+		return nil
+	}
+	if expiry.IsZero() {
+		return nil
+	}
+	expiryValue := expiry.UTC().Format(time.RFC3339)
+	now := h.now()
+
+	annotations := map[string]string{}
+	for k, v := range b.GetAnnotations() {
+		annotations[k] = v
+	}
+	// the events are only created once the annotations recording them are updated, so that conflicts don't
+	// duplicate them
+	var events []event
+
+	if annotations[GrantedAnnotation] != expiryValue {
+		duration := expiry.Sub(b.GetCreationTimestamp().Time).Round(time.Second)
+		grantedBy := grantor(b)
+		message := fmt.Sprintf("%s granted to [%s] by [%s] until %s (%s)", b.access, b.subject, grantedBy, expiryValue, duration)
+		logrus.Infof("[bindingExpiry] %s [%s]: %s", b.kind.Kind, key(b), message)
+		events = append(events, event{eventType: corev1.EventTypeNormal, reason: grantedEventReason, message: message})
+		annotations[GrantedAnnotation] = expiryValue
+		annotations[GrantedByAnnotation] = grantedBy
+	}
+
+	if !now.Before(expiry) {
+		message := fmt.Sprintf("%s of [%s] expired at %s", b.access, b.subject, expiryValue)
+		logrus.Infof("[bindingExpiry] %s [%s]: %s, removing it", b.kind.Kind, key(b), message)
+		if err := a.delete(); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		events = append(events, event{eventType: corev1.EventTypeNormal, reason: expiredEventReason, message: message})
+		h.recordEvents(b, events)
+		return nil
+	}
+
+	warnAt := expiry.Add(-warningPeriod())
+	if annotations[WarnedAnnotation] != expiryValue && !now.Before(warnAt) {
+		message := fmt.Sprintf("%s of [%s] expires in %s", b.access, b.subject, expiry.Sub(now).Round(time.Second))
+		events = append(events, event{eventType: corev1.EventTypeWarning, reason: expiringEventReason, message: message})
+		annotations[WarnedAnnotation] = expiryValue
+	}
+
+	if len(events) > 0 {
+		if err := a.annotate(annotations); err != nil {
+			return err
+		}
+		h.recordEvents(b, events)
+	}
+
+	next := expiry
+	if annotations[WarnedAnnotation] != expiryValue {
+		next = warnAt
+	}
+	a.enqueueAfter(next.Sub(now))
+	return nil
+}
+
+type event struct {
+	eventType string
+	reason    string
+	message   string
+}
+
+func (h *handler) recordEvents(b *binding, events []event) {
+	for _, e := range events {
+		h.recordEvent(b, e)
+	}
+}
+
+func (h *handler) recordEvent(b *binding, e event) {
+	h.recorder.Event(b.object, e.eventType, e.reason, e.message)
+}
+
+// warningPeriod returns how long before the expiry of a binding the warning event is created.
+func warningPeriod() time.Duration {
+	period, err := time.ParseDuration(settings.RoleBindingExpiryWarning.Get())
+	if err != nil {
+		logrus.Warnf("[bindingExpiry] invalid %s setting: %v", settings.RoleBindingExpiryWarning.Name, err)
+		return 0
+	}
+	return period
+}
+
+// grantor returns the user who set the current expiry of the binding: the user the Rancher APIs recorded when they
+// changed it, or the creator of the binding if its spec wasn't changed since it was created. The expiry changed outside
+// the Rancher APIs is granted by an unknown user.
+func grantor(b *binding) string {
+	annotations := b.GetAnnotations()
+	if setBy := annotations[ExpirySetByAnnotation]; setBy != "" && annotations[ExpirySetForAnnotation] == ExpirySpec(b.expiresAt, b.ttl) {
+		return setBy
+	}
+	if creatorID := annotations[project_cluster.CreatorIDAnnotation]; creatorID != "" && b.GetGeneration() <= 1 {
+		return creatorID
+	}
+	return "unknown"
+}
+
+// ExpirySpec returns the value of ExpirySetForAnnotation for the expiresAt and ttl fields of a binding.
+func ExpirySpec(expiresAt *metav1.Time, ttl string) string {
+	if expiresAt != nil {
+		return "expiresAt=" + expiresAt.UTC().Format(time.RFC3339)
+	}
+	if ttl != "" {
+		return "ttl=" + ttl
+	}
+	return ""
+}
+
+func subjectOf(names ...string) string {
+	for _, name := range names {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func key(b *binding) string {
+	if b.GetNamespace() == "" {
+		return b.GetName()
+	}
+	return b.GetNamespace() + "/" + b.GetName()
+}
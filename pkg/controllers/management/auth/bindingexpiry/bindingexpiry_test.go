@@ -0,0 +1,230 @@
+package bindingexpiry
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+type mocks struct {
+	grbs     *fake.MockNonNamespacedControllerInterface[*v3.GlobalRoleBinding, *v3.GlobalRoleBindingList]
+	crtbs    *fake.MockControllerInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList]
+	recorder *record.FakeRecorder
+}
+
+// events returns the events recorded by the handler, as "type reason message".
+func (m *mocks) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-m.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func newHandler(t *testing.T, now time.Time) (*handler, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		grbs:     fake.NewMockNonNamespacedControllerInterface[*v3.GlobalRoleBinding, *v3.GlobalRoleBindingList](ctrl),
+		crtbs:    fake.NewMockControllerInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList](ctrl),
+		recorder: record.NewFakeRecorder(10),
+	}
+	return &handler{
+		grbs:     m.grbs,
+		crtbs:    m.crtbs,
+		recorder: m.recorder,
+		now:      func() time.Time { return now },
+	}, m
+}
+
+func newGRB(created time.Time, annotations map[string]string) *v3.GlobalRoleBinding {
+	return &v3.GlobalRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "grb-1",
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations:       annotations,
+		},
+		UserName:       "u-1",
+		GlobalRoleName: "admin",
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := metav1.NewTime(created.Add(time.Hour))
+	tests := []struct {
+		name      string
+		expiresAt *metav1.Time
+		ttl       string
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "no expiry"},
+		{name: "expires at", expiresAt: &expiresAt, want: expiresAt.Time},
+		{name: "ttl", ttl: "8h", want: created.Add(8 * time.Hour)},
+		{name: "expires at takes precedence", expiresAt: &expiresAt, ttl: "8h", want: expiresAt.Time},
+		{name: "invalid ttl", ttl: "tomorrow", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &binding{
+				object:    newGRB(created, nil),
+				expiresAt: tt.expiresAt,
+				ttl:       tt.ttl,
+			}
+			got, err := b.expiry()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "expected %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestSyncGRBGranted(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h, m := newHandler(t, created.Add(time.Minute))
+	grb := newGRB(created, map[string]string{"field.cattle.io/creatorId": "u-admin"})
+	grb.TTL = "8h"
+
+	m.grbs.EXPECT().Update(gomock.Any()).DoAndReturn(func(obj *v3.GlobalRoleBinding) (*v3.GlobalRoleBinding, error) {
+		assert.Equal(t, "2024-01-01T08:00:00Z", obj.Annotations[GrantedAnnotation])
+		assert.Equal(t, "u-admin", obj.Annotations[GrantedByAnnotation])
+		assert.NotContains(t, obj.Annotations, WarnedAnnotation)
+		return obj, nil
+	})
+	// the binding is requeued for the warning, an hour before it expires
+	m.grbs.EXPECT().EnqueueAfter("grb-1", 7*time.Hour-time.Minute)
+
+	_, err := h.syncGRB("grb-1", grb)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Normal " + grantedEventReason + " GlobalRole [admin] granted to [u-1] by [u-admin] until 2024-01-01T08:00:00Z (8h0m0s)"}, m.events())
+}
+
+func TestGrantor(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		generation  int64
+		annotations map[string]string
+		want        string
+	}{
+		{
+			name:        "creator",
+			generation:  1,
+			annotations: map[string]string{"field.cattle.io/creatorId": "u-admin"},
+			want:        "u-admin",
+		},
+		{
+			name:        "expiry changed through the Rancher APIs",
+			generation:  2,
+			annotations: map[string]string{"field.cattle.io/creatorId": "u-admin", ExpirySetByAnnotation: "u-owner", ExpirySetForAnnotation: "ttl=8h"},
+			want:        "u-owner",
+		},
+		{
+			name:        "expiry changed outside the Rancher APIs",
+			generation:  3,
+			annotations: map[string]string{"field.cattle.io/creatorId": "u-admin", ExpirySetByAnnotation: "u-owner", ExpirySetForAnnotation: "ttl=4h"},
+			want:        "unknown",
+		},
+		{
+			name:        "spec changed since the creation",
+			generation:  2,
+			annotations: map[string]string{"field.cattle.io/creatorId": "u-admin"},
+			want:        "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grb := newGRB(created, tt.annotations)
+			grb.Generation = tt.generation
+			assert.Equal(t, tt.want, grantor(&binding{object: grb, ttl: "8h"}))
+		})
+	}
+}
+
+func TestSyncGRBWarning(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h, m := newHandler(t, created.Add(7*time.Hour+30*time.Minute))
+	grb := newGRB(created, map[string]string{GrantedAnnotation: "2024-01-01T08:00:00Z"})
+	grb.TTL = "8h"
+
+	m.grbs.EXPECT().Update(gomock.Any()).DoAndReturn(func(obj *v3.GlobalRoleBinding) (*v3.GlobalRoleBinding, error) {
+		assert.Equal(t, "2024-01-01T08:00:00Z", obj.Annotations[WarnedAnnotation])
+		return obj, nil
+	})
+	m.grbs.EXPECT().EnqueueAfter("grb-1", 30*time.Minute)
+
+	_, err := h.syncGRB("grb-1", grb)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Warning " + expiringEventReason + " GlobalRole [admin] of [u-1] expires in 30m0s"}, m.events())
+
+	// once warned, the binding is only requeued for its expiry
+	h, m = newHandler(t, created.Add(7*time.Hour+30*time.Minute))
+	grb.Annotations[WarnedAnnotation] = "2024-01-01T08:00:00Z"
+	m.grbs.EXPECT().EnqueueAfter("grb-1", 30*time.Minute)
+
+	_, err = h.syncGRB("grb-1", grb)
+	require.NoError(t, err)
+	assert.Empty(t, m.events())
+}
+
+func TestSyncCRTBExpired(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := metav1.NewTime(created.Add(time.Hour))
+	h, m := newHandler(t, created.Add(2*time.Hour))
+	crtb := &v3.ClusterRoleTemplateBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "crtb-1",
+			Namespace:         "c-1",
+			CreationTimestamp: metav1.NewTime(created),
+			Annotations:       map[string]string{GrantedAnnotation: "2024-01-01T01:00:00Z", WarnedAnnotation: "2024-01-01T01:00:00Z"},
+		},
+		GroupPrincipalName: "okta_group://oncall",
+		ClusterName:        "c-1",
+		RoleTemplateName:   "cluster-owner",
+		ExpiresAt:          &expiresAt,
+	}
+
+	m.crtbs.EXPECT().Delete("c-1", "crtb-1", gomock.Any()).Return(nil)
+
+	_, err := h.syncCRTB("c-1/crtb-1", crtb)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Normal " + expiredEventReason + " RoleTemplate [cluster-owner] in cluster [c-1] of [okta_group://oncall] expired at 2024-01-01T01:00:00Z"}, m.events())
+}
+
+func TestSyncInvalidTTL(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h, m := newHandler(t, created.Add(time.Minute))
+	grb := newGRB(created, nil)
+	grb.TTL = "tomorrow"
+
+	// a binding whose expiry can't be known doesn't grant access forever
+	m.grbs.EXPECT().Delete("grb-1", gomock.Any()).Return(nil)
+
+	_, err := h.syncGRB("grb-1", grb)
+	require.NoError(t, err)
+	events := m.events()
+	require.Len(t, events, 1)
+	assert.Contains(t, events[0], "Warning "+expiredEventReason+" ")
+}
+
+func TestSyncPermanent(t *testing.T) {
+	h, m := newHandler(t, time.Now())
+
+	_, err := h.syncGRB("grb-1", newGRB(time.Now(), nil))
+	require.NoError(t, err)
+	assert.Empty(t, m.events())
+}
@@ -0,0 +1,140 @@
+// Package bindingreview removes the bindings of the BindingReviews opened by the access review which aren't confirmed
+// by their deadline, provided the access review still flags them then. Reviews not opened by the access review, or not
+// in the namespace of their binding, are marked invalid and never remove anything.
+package bindingreview
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/auth/accessreview"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const controllerName = "mgmt-auth-binding-review-controller"
+
+// checker flags a binding again, as the access review does.
+type checker interface {
+	Findings(kind, namespace, name string) ([]string, error)
+}
+
+type handler struct {
+	reviews mgmtcontrollers.BindingReviewController
+	grbs    mgmtcontrollers.GlobalRoleBindingClient
+	crtbs   mgmtcontrollers.ClusterRoleTemplateBindingClient
+	prtbs   mgmtcontrollers.ProjectRoleTemplateBindingClient
+	checker checker
+	now     func() time.Time
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	h := &handler{
+		reviews: management.Wrangler.Mgmt.BindingReview(),
+		grbs:    management.Wrangler.Mgmt.GlobalRoleBinding(),
+		crtbs:   management.Wrangler.Mgmt.ClusterRoleTemplateBinding(),
+		prtbs:   management.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+		checker: accessreview.New(management.Wrangler),
+		now:     time.Now,
+	}
+	h.reviews.OnChange(ctx, controllerName, h.sync)
+}
+
+func (h *handler) sync(_ string, review *v3.BindingReview) (*v3.BindingReview, error) {
+	if review == nil || review.DeletionTimestamp != nil {
+		return review, nil
+	}
+
+	if state := review.Status.State; state == "" || state == v3.BindingReviewOpen {
+		if reason := validate(review); reason != "" {
+			logrus.Warnf("[bindingReview] ignoring review [%s/%s] of %s [%s]: %s",
+				review.Namespace, review.Name, review.Spec.BindingKind, review.Spec.BindingName, reason)
+			review = review.DeepCopy()
+			review.Status.State = v3.BindingReviewInvalid
+			review.Status.Message = reason
+			return h.reviews.UpdateStatus(review)
+		}
+	}
+
+	switch review.Status.State {
+	case "":
+		review = review.DeepCopy()
+		review.Status.State = v3.BindingReviewOpen
+		return h.reviews.UpdateStatus(review)
+	case v3.BindingReviewOpen:
+		if remaining := review.Spec.Deadline.Sub(h.now()); remaining > 0 {
+			h.reviews.EnqueueAfter(review.Namespace, review.Name, remaining)
+			return review, nil
+		}
+		// the binding may have been fixed since it was flagged, e.g. its user may have logged in again
+		findings, err := h.checker.Findings(review.Spec.BindingKind, review.Spec.BindingNamespace, review.Spec.BindingName)
+		if err != nil {
+			return review, err
+		}
+		if len(findings) == 0 {
+			logrus.Infof("[bindingReview] kept %s [%s] of user [%s] as it is no longer flagged, closing review [%s/%s]",
+				review.Spec.BindingKind, review.Spec.BindingName, review.Spec.UserName, review.Namespace, review.Name)
+			review = review.DeepCopy()
+			review.Status.State = v3.BindingReviewResolved
+			review.Status.Message = "the binding was no longer flagged at the deadline"
+			return h.reviews.UpdateStatus(review)
+		}
+		if err := h.removeBinding(&review.Spec); err != nil {
+			return review, err
+		}
+		logrus.Infof("[bindingReview] removed %s [%s] of user [%s] as review [%s/%s] wasn't confirmed by %s",
+			review.Spec.BindingKind, review.Spec.BindingName, review.Spec.UserName, review.Namespace, review.Name,
+			review.Spec.Deadline.UTC().Format(time.RFC3339))
+		review = review.DeepCopy()
+		review.Status.State = v3.BindingReviewRemoved
+		review.Status.Message = "the binding wasn't confirmed by the deadline"
+		return h.reviews.UpdateStatus(review)
+	}
+	return review, nil
+}
+
+// validate returns why the review must not be acted upon, if it mustn't. A review can only remove a binding of its own
+// namespace, so that it can't be used to remove bindings the users allowed to see it have no access to.
+func validate(review *v3.BindingReview) string {
+	if review.Labels[v3.BindingReviewOpenedByLabel] != v3.BindingReviewOpenedByAccessReview {
+		return "the review wasn't opened by the access review"
+	}
+	switch review.Spec.BindingKind {
+	case "GlobalRoleBinding":
+		if review.Namespace != namespace.GlobalNamespace || review.Spec.BindingNamespace != "" {
+			return fmt.Sprintf("reviews of GlobalRoleBindings must be in the %s namespace", namespace.GlobalNamespace)
+		}
+	case "ClusterRoleTemplateBinding", "ProjectRoleTemplateBinding":
+		if review.Spec.BindingNamespace != review.Namespace {
+			return "the review isn't in the namespace of its binding"
+		}
+	default:
+		return fmt.Sprintf("unknown binding kind %q", review.Spec.BindingKind)
+	}
+	return ""
+}
+
+// removeBinding deletes the binding under review. A binding which no longer exists is considered removed.
+func (h *handler) removeBinding(spec *v3.BindingReviewSpec) error {
+	var err error
+	switch spec.BindingKind {
+	case "GlobalRoleBinding":
+		err = h.grbs.Delete(spec.BindingName, &metav1.DeleteOptions{})
+	case "ClusterRoleTemplateBinding":
+		err = h.crtbs.Delete(spec.BindingNamespace, spec.BindingName, &metav1.DeleteOptions{})
+	case "ProjectRoleTemplateBinding":
+		err = h.prtbs.Delete(spec.BindingNamespace, spec.BindingName, &metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("unknown binding kind %q", spec.BindingKind)
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package bindingreview
+
+import (
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var now = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeChecker returns the findings of the bindings by name.
+type fakeChecker map[string][]string
+
+func (c fakeChecker) Findings(_, _, name string) ([]string, error) {
+	return c[name], nil
+}
+
+type mocks struct {
+	reviews *fake.MockControllerInterface[*v3.BindingReview, *v3.BindingReviewList]
+	grbs    *fake.MockNonNamespacedClientInterface[*v3.GlobalRoleBinding, *v3.GlobalRoleBindingList]
+	crtbs   *fake.MockClientInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList]
+	prtbs   *fake.MockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList]
+}
+
+func newHandler(t *testing.T) (*handler, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		reviews: fake.NewMockControllerInterface[*v3.BindingReview, *v3.BindingReviewList](ctrl),
+		grbs:    fake.NewMockNonNamespacedClientInterface[*v3.GlobalRoleBinding, *v3.GlobalRoleBindingList](ctrl),
+		crtbs:   fake.NewMockClientInterface[*v3.ClusterRoleTemplateBinding, *v3.ClusterRoleTemplateBindingList](ctrl),
+		prtbs:   fake.NewMockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList](ctrl),
+	}
+	m.reviews.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.BindingReview) (*v3.BindingReview, error) {
+		return obj, nil
+	}).AnyTimes()
+	return &handler{
+		reviews: m.reviews,
+		grbs:    m.grbs,
+		crtbs:   m.crtbs,
+		prtbs:   m.prtbs,
+		checker: fakeChecker{"binding": {"user last logged in 100 days ago"}},
+		now:     func() time.Time { return now },
+	}, m
+}
+
+func newBindingReview(kind, bindingNamespace, state string, deadline time.Time) *v3.BindingReview {
+	reviewNamespace := bindingNamespace
+	if kind == "GlobalRoleBinding" {
+		reviewNamespace = namespace.GlobalNamespace
+	}
+	return &v3.BindingReview{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "review-x",
+			Namespace: reviewNamespace,
+			Labels:    map[string]string{v3.BindingReviewOpenedByLabel: v3.BindingReviewOpenedByAccessReview},
+		},
+		Spec: v3.BindingReviewSpec{
+			BindingKind:      kind,
+			BindingNamespace: bindingNamespace,
+			BindingName:      "binding",
+			UserName:         "u-inactive",
+			Deadline:         metav1.NewTime(deadline),
+		},
+		Status: v3.BindingReviewStatus{State: state},
+	}
+}
+
+func TestSyncOpens(t *testing.T) {
+	h, _ := newHandler(t)
+
+	got, err := h.sync("", newBindingReview("ClusterRoleTemplateBinding", "c-1", "", now.Add(time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, v3.BindingReviewOpen, got.Status.State)
+}
+
+func TestSyncBeforeDeadline(t *testing.T) {
+	h, m := newHandler(t)
+	m.reviews.EXPECT().EnqueueAfter("c-1", "review-x", time.Hour)
+
+	got, err := h.sync("", newBindingReview("ClusterRoleTemplateBinding", "c-1", v3.BindingReviewOpen, now.Add(time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, v3.BindingReviewOpen, got.Status.State)
+}
+
+func TestSyncRemovesAtDeadline(t *testing.T) {
+	tests := []struct {
+		kind             string
+		bindingNamespace string
+		expect           func(m *mocks)
+	}{
+		{
+			kind: "GlobalRoleBinding",
+			expect: func(m *mocks) {
+				m.grbs.EXPECT().Delete("binding", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			kind:             "ClusterRoleTemplateBinding",
+			bindingNamespace: "c-1",
+			expect: func(m *mocks) {
+				m.crtbs.EXPECT().Delete("c-1", "binding", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			// a binding deleted in the meantime is considered removed
+			kind:             "ProjectRoleTemplateBinding",
+			bindingNamespace: "p-1",
+			expect: func(m *mocks) {
+				m.prtbs.EXPECT().Delete("p-1", "binding", gomock.Any()).
+					Return(apierrors.NewNotFound(schema.GroupResource{Resource: "projectroletemplatebindings"}, "binding"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			h, m := newHandler(t)
+			tt.expect(m)
+
+			got, err := h.sync("", newBindingReview(tt.kind, tt.bindingNamespace, v3.BindingReviewOpen, now))
+			require.NoError(t, err)
+			assert.Equal(t, v3.BindingReviewRemoved, got.Status.State)
+		})
+	}
+}
+
+func TestSyncResolvedAtDeadline(t *testing.T) {
+	// the binding is kept if it is no longer flagged, the mocks fail on any deletion
+	h, _ := newHandler(t)
+	h.checker = fakeChecker{}
+
+	got, err := h.sync("", newBindingReview("ClusterRoleTemplateBinding", "c-1", v3.BindingReviewOpen, now))
+	require.NoError(t, err)
+	assert.Equal(t, v3.BindingReviewResolved, got.Status.State)
+}
+
+func TestSyncConfirmed(t *testing.T) {
+	h, _ := newHandler(t)
+
+	// confirmed reviews are left alone after the deadline
+	got, err := h.sync("", newBindingReview("ClusterRoleTemplateBinding", "c-1", v3.BindingReviewConfirmed, now.Add(-time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, v3.BindingReviewConfirmed, got.Status.State)
+}
+
+func TestSyncInvalid(t *testing.T) {
+	unlabeled := newBindingReview("ClusterRoleTemplateBinding", "c-1", v3.BindingReviewOpen, now.Add(-time.Hour))
+	unlabeled.Labels = nil
+	otherNamespace := newBindingReview("ProjectRoleTemplateBinding", "p-1", "", now.Add(-time.Hour))
+	otherNamespace.Namespace = "p-2"
+	globalOutsideGlobalData := newBindingReview("GlobalRoleBinding", "", v3.BindingReviewOpen, now.Add(-time.Hour))
+	globalOutsideGlobalData.Namespace = "c-1"
+	unknownKind := newBindingReview("RoleBinding", "c-1", v3.BindingReviewOpen, now.Add(-time.Hour))
+
+	for name, review := range map[string]*v3.BindingReview{
+		"not opened by the access review":      unlabeled,
+		"outside the namespace of the binding": otherNamespace,
+		"global binding outside global data":   globalOutsideGlobalData,
+		"unknown kind":                         unknownKind,
+	} {
+		t.Run(name, func(t *testing.T) {
+			// no binding is removed, the mocks fail on any deletion
+			h, _ := newHandler(t)
+
+			got, err := h.sync("", review)
+			require.NoError(t, err)
+			assert.Equal(t, v3.BindingReviewInvalid, got.Status.State)
+			assert.NotEmpty(t, got.Status.Message)
+		})
+	}
+}
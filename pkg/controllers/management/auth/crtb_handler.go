@@ -38,6 +38,7 @@
 )
 
 var clusterManagementPlaneResources = map[string]string{
+	"accessrequests":              "management.cattle.io",
 	"clusterscans":                "management.cattle.io",
 	"catalogtemplates":            "management.cattle.io",
 	"catalogtemplateversions":     "management.cattle.io",
@@ -53,6 +54,7 @@
 	"nodepools":                   "management.cattle.io",
 	"notifiers":                   "management.cattle.io",
 	"projects":                    "management.cattle.io",
+	"projectmembershippolicies":   "management.cattle.io",
 	"etcdsnapshots":               "rke.cattle.io",
 }
 
@@ -0,0 +1,385 @@
+// Package membershippolicy keeps the ProjectRoleTemplateBindings granting the membership of ProjectMembershipPolicies
+// in sync with the groups, users and projects they match, and with the RoleTemplates their authors can grant.
+package membershippolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	controllerName = "mgmt-auth-project-membership-policy-controller"
+	removeHandler  = "mgmt-auth-project-membership-policy-remove"
+	enqueuerName   = "mgmt-auth-project-membership-policy-enqueuer"
+
+	// PolicyLabel is set on the bindings created for a ProjectMembershipPolicy, with the namespace and name of the
+	// policy as value.
+	PolicyLabel = "management.cattle.io/project-membership-policy"
+)
+
+// escalationChecker checks that users only grant the RoleTemplates they could bind themselves.
+type escalationChecker interface {
+	ConfirmNoEscalation(userName, clusterName, projectName string, roleTemplate *v3.RoleTemplate) error
+}
+
+type handler struct {
+	policies       mgmtcontrollers.ProjectMembershipPolicyController
+	projects       mgmtcontrollers.ProjectCache
+	userAttributes mgmtcontrollers.UserAttributeCache
+	roleTemplates  mgmtcontrollers.RoleTemplateCache
+	prtbCache      mgmtcontrollers.ProjectRoleTemplateBindingCache
+	prtbs          mgmtcontrollers.ProjectRoleTemplateBindingClient
+	escalation     escalationChecker
+
+	// matched are the groups and attributes of the users last seen, by name
+	matchedLock sync.Mutex
+	matched     map[string]userMatch
+}
+
+// userMatch is what the policies match in a UserAttribute.
+type userMatch struct {
+	groupPrincipals map[string]v3.Principals
+	extraByProvider map[string]map[string][]string
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	h := &handler{
+		policies:       management.Wrangler.Mgmt.ProjectMembershipPolicy(),
+		projects:       management.Wrangler.Mgmt.Project().Cache(),
+		userAttributes: management.Wrangler.Mgmt.UserAttribute().Cache(),
+		roleTemplates:  management.Wrangler.Mgmt.RoleTemplate().Cache(),
+		prtbCache:      management.Wrangler.Mgmt.ProjectRoleTemplateBinding().Cache(),
+		prtbs:          management.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+		escalation:     rbac.NewEscalationChecker(management.Wrangler.Mgmt, management.Wrangler.RBAC),
+		matched:        map[string]userMatch{},
+	}
+	h.policies.OnChange(ctx, controllerName, h.sync)
+	h.policies.OnRemove(ctx, removeHandler, h.remove)
+	relatedresource.Watch(ctx, enqueuerName, h.enqueuePolicies, h.policies,
+		management.Wrangler.Mgmt.Project(), management.Wrangler.Mgmt.UserAttribute())
+}
+
+// enqueuePolicies enqueues the policies in the namespace of the cluster of a changed project, and all the policies
+// when the groups or the attributes of a UserAttribute change, as they may be matched by any of them.
+func (h *handler) enqueuePolicies(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	if userAttribute, ok := obj.(*v3.UserAttribute); ok || obj == nil && namespace == "" {
+		if !h.matchChanged(name, userAttribute) {
+			return nil, nil
+		}
+	}
+	policies, err := h.policies.Cache().List(namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]relatedresource.Key, 0, len(policies))
+	for _, policy := range policies {
+		keys = append(keys, relatedresource.Key{Namespace: policy.Namespace, Name: policy.Name})
+	}
+	return keys, nil
+}
+
+// matchChanged records the groups and attributes of the user, none if the UserAttribute is removed, and returns
+// whether they changed.
+func (h *handler) matchChanged(name string, userAttribute *v3.UserAttribute) bool {
+	h.matchedLock.Lock()
+	defer h.matchedLock.Unlock()
+	previous, seen := h.matched[name]
+	if userAttribute == nil || userAttribute.DeletionTimestamp != nil {
+		delete(h.matched, name)
+		return seen
+	}
+	current := userMatch{
+		groupPrincipals: userAttribute.GroupPrincipals,
+		extraByProvider: userAttribute.ExtraByProvider,
+	}
+	h.matched[name] = current
+	return !seen || !reflect.DeepEqual(previous, current)
+}
+
+func (h *handler) sync(_ string, policy *v3.ProjectMembershipPolicy) (*v3.ProjectMembershipPolicy, error) {
+	if policy == nil || policy.DeletionTimestamp != nil {
+		return policy, nil
+	}
+
+	// the author is recorded by the API, not by the controller
+	status := v3.ProjectMembershipPolicyStatus{
+		ObservedGeneration: policy.Generation,
+		Author:             policy.Status.Author,
+		AuthorGeneration:   policy.Status.AuthorGeneration,
+	}
+	desired, denied, err := h.desiredBindings(policy)
+	if err != nil {
+		// the bindings of a policy which can't be applied are kept, so a mistake in a policy doesn't revoke access
+		status.Bindings = policy.Status.Bindings
+		status.Message = err.Error()
+		return h.updateStatus(policy, status)
+	}
+	status.Message = strings.Join(denied, "; ")
+
+	current, err := h.prtbCache.List("", labels.SelectorFromSet(labels.Set{PolicyLabel: policyLabelValue(policy)}))
+	if err != nil {
+		return policy, err
+	}
+	for _, prtb := range current {
+		if _, ok := desired[prtb.Name]; ok {
+			delete(desired, prtb.Name)
+			status.Bindings++
+			continue
+		}
+		logrus.Infof("[projectMembershipPolicy] removing ProjectRoleTemplateBinding [%s/%s] no longer granted by policy [%s/%s]",
+			prtb.Namespace, prtb.Name, policy.Namespace, policy.Name)
+		if err := h.prtbs.Delete(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return policy, err
+		}
+	}
+	for _, prtb := range desired {
+		logrus.Infof("[projectMembershipPolicy] granting RoleTemplate [%s] on project [%s] to [%s%s] for policy [%s/%s]",
+			prtb.RoleTemplateName, prtb.ProjectName, prtb.UserName, prtb.GroupPrincipalName, policy.Namespace, policy.Name)
+		if _, err := h.prtbs.Create(prtb); err != nil && !apierrors.IsAlreadyExists(err) {
+			return policy, err
+		}
+		status.Bindings++
+	}
+	return h.updateStatus(policy, status)
+}
+
+// remove deletes the bindings of a removed policy.
+func (h *handler) remove(_ string, policy *v3.ProjectMembershipPolicy) (*v3.ProjectMembershipPolicy, error) {
+	current, err := h.prtbCache.List("", labels.SelectorFromSet(labels.Set{PolicyLabel: policyLabelValue(policy)}))
+	if err != nil {
+		return policy, err
+	}
+	for _, prtb := range current {
+		if err := h.prtbs.Delete(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
+
+func (h *handler) updateStatus(policy *v3.ProjectMembershipPolicy, status v3.ProjectMembershipPolicyStatus) (*v3.ProjectMembershipPolicy, error) {
+	if policy.Status == status {
+		return policy, nil
+	}
+	policy = policy.DeepCopy()
+	policy.Status = status
+	return h.policies.UpdateStatus(policy)
+}
+
+// desiredBindings returns the bindings granting the membership of the policy by name, and why the author of the policy
+// can't grant the other ones.
+func (h *handler) desiredBindings(policy *v3.ProjectMembershipPolicy) (map[string]*v3.ProjectRoleTemplateBinding, []string, error) {
+	if err := h.validate(policy); err != nil {
+		return nil, nil, err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.ProjectSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid projectSelector: %w", err)
+	}
+	projects, err := h.projects.List(policy.Namespace, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	userAttributes, err := h.userAttributes.List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	grantable, denied, err := h.grantable(policy, projects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desired := map[string]*v3.ProjectRoleTemplateBinding{}
+	for _, rule := range policy.Spec.Rules {
+		var groups, users []string
+		if rule.GroupPrincipalPattern != "" {
+			groups = matchGroupPrincipals(rule.GroupPrincipalPattern, userAttributes)
+		} else {
+			users = matchUsers(rule.UserAttribute, userAttributes)
+		}
+		for _, project := range projects {
+			if project.DeletionTimestamp != nil {
+				continue
+			}
+			for _, roleTemplateName := range rule.RoleTemplateNames {
+				if !grantable[grant{project: project.Name, roleTemplate: roleTemplateName}] {
+					continue
+				}
+				for _, group := range groups {
+					prtb := newBinding(policy, project, roleTemplateName)
+					prtb.GroupPrincipalName = group
+					prtb.Name = bindingName(policy, project, roleTemplateName, "group", group)
+					desired[prtb.Name] = prtb
+				}
+				for _, user := range users {
+					prtb := newBinding(policy, project, roleTemplateName)
+					prtb.UserName = user
+					prtb.Name = bindingName(policy, project, roleTemplateName, "user", user)
+					desired[prtb.Name] = prtb
+				}
+			}
+		}
+	}
+	return desired, denied, nil
+}
+
+// grant is a RoleTemplate granted on a project.
+type grant struct {
+	project      string
+	roleTemplate string
+}
+
+// grantable returns the RoleTemplates of the policy its author can grant on each project, as the bindings created by
+// Rancher on behalf of the author must not grant more than the author could, and why the author can't grant the
+// other ones.
+func (h *handler) grantable(policy *v3.ProjectMembershipPolicy, projects []*v3.Project) (map[grant]bool, []string, error) {
+	// the author is only trusted for the generation the API recorded it for
+	author := policy.Status.Author
+	if author == "" || policy.Status.AuthorGeneration != policy.Generation {
+		return nil, []string{"the policy has no author: it wasn't last written through the Rancher API"}, nil
+	}
+
+	grantable := map[grant]bool{}
+	var denied []string
+	for _, rule := range policy.Spec.Rules {
+		for _, roleTemplateName := range rule.RoleTemplateNames {
+			roleTemplate, err := h.roleTemplates.Get(roleTemplateName)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, project := range projects {
+				g := grant{project: project.Name, roleTemplate: roleTemplateName}
+				if _, ok := grantable[g]; ok {
+					continue
+				}
+				err := h.escalation.ConfirmNoEscalation(author, project.Namespace, project.Namespace+":"+project.Name, roleTemplate)
+				if errors.Is(err, rbac.ErrEscalation) {
+					grantable[g] = false
+					denied = append(denied, fmt.Sprintf("project [%s]: %v", project.Name, err))
+					continue
+				} else if err != nil {
+					return nil, nil, err
+				}
+				grantable[g] = true
+			}
+		}
+	}
+	return grantable, denied, nil
+}
+
+// validate checks that the membership of the policy can be granted.
+func (h *handler) validate(policy *v3.ProjectMembershipPolicy) error {
+	if policy.Spec.ClusterName != policy.Namespace {
+		return fmt.Errorf("policies must be in the namespace of their cluster [%s]", policy.Spec.ClusterName)
+	}
+	for i, rule := range policy.Spec.Rules {
+		if (rule.GroupPrincipalPattern == "") == (rule.UserAttribute == nil) {
+			return fmt.Errorf("rules[%d]: exactly one of groupPrincipalPattern and userAttribute must be set", i)
+		}
+		pattern := rule.GroupPrincipalPattern
+		if rule.UserAttribute != nil {
+			if rule.UserAttribute.Provider == "" || rule.UserAttribute.Key == "" {
+				return fmt.Errorf("rules[%d]: userAttribute requires a provider and a key", i)
+			}
+			pattern = rule.UserAttribute.Pattern
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rules[%d]: invalid pattern %q: %w", i, pattern, err)
+		}
+		if len(rule.RoleTemplateNames) == 0 {
+			return fmt.Errorf("rules[%d]: roleTemplateNames must not be empty", i)
+		}
+		for _, roleTemplateName := range rule.RoleTemplateNames {
+			roleTemplate, err := h.roleTemplates.Get(roleTemplateName)
+			if err != nil {
+				return fmt.Errorf("rules[%d]: roleTemplate [%s]: %w", i, roleTemplateName, err)
+			}
+			if roleTemplate.Context != "project" {
+				return fmt.Errorf("rules[%d]: roleTemplate [%s] is not a project role", i, roleTemplateName)
+			}
+			if roleTemplate.Locked {
+				return fmt.Errorf("rules[%d]: roleTemplate [%s] is locked", i, roleTemplateName)
+			}
+		}
+	}
+	return nil
+}
+
+// matchGroupPrincipals returns the names of the group principals of the users matching the pattern.
+func matchGroupPrincipals(pattern string, userAttributes []*v3.UserAttribute) []string {
+	groups := map[string]bool{}
+	for _, userAttribute := range userAttributes {
+		for _, principals := range userAttribute.GroupPrincipals {
+			for _, principal := range principals.Items {
+				if ok, _ := path.Match(pattern, principal.Name); ok {
+					groups[principal.Name] = true
+				}
+			}
+		}
+	}
+	return sortedKeys(groups)
+}
+
+// matchUsers returns the names of the users with a value of the attribute matching the pattern.
+func matchUsers(match *v3.UserAttributeMatch, userAttributes []*v3.UserAttribute) []string {
+	users := map[string]bool{}
+	for _, userAttribute := range userAttributes {
+		for _, value := range userAttribute.ExtraByProvider[match.Provider][match.Key] {
+			if ok, _ := path.Match(match.Pattern, value); ok {
+				users[userAttribute.Name] = true
+				break
+			}
+		}
+	}
+	return sortedKeys(users)
+}
+
+func newBinding(policy *v3.ProjectMembershipPolicy, project *v3.Project, roleTemplateName string) *v3.ProjectRoleTemplateBinding {
+	return &v3.ProjectRoleTemplateBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: project.Name,
+			Labels:    map[string]string{PolicyLabel: policyLabelValue(policy)},
+		},
+		ProjectName:      project.Namespace + ":" + project.Name,
+		RoleTemplateName: roleTemplateName,
+	}
+}
+
+// bindingName returns the name of the binding of a policy granting a RoleTemplate on a project to a subject. The name
+// is deterministic so the bindings of the policy are found by name.
+func bindingName(policy *v3.ProjectMembershipPolicy, project *v3.Project, roleTemplateName, subjectKind, subject string) string {
+	key := strings.Join([]string{policy.Namespace, policy.Name, project.Name, roleTemplateName, subjectKind, subject}, "/")
+	return name.SafeConcatName("mp", policy.Name, name.Hex(key, 10))
+}
+
+func policyLabelValue(policy *v3.ProjectMembershipPolicy) string {
+	return name.SafeConcatName(policy.Namespace, policy.Name)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
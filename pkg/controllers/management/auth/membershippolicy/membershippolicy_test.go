@@ -0,0 +1,383 @@
+package membershippolicy
+
+import (
+	"fmt"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/rbac"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeEscalationChecker lets u-admin grant any RoleTemplate but the denied ones, by project.
+type fakeEscalationChecker struct {
+	denied map[string]string
+}
+
+func (f *fakeEscalationChecker) ConfirmNoEscalation(userName, _, projectName string, roleTemplate *v3.RoleTemplate) error {
+	if userName != "u-admin" || f.denied[projectName] == roleTemplate.Name {
+		return fmt.Errorf("%w: user [%s] can't grant RoleTemplate [%s]", rbac.ErrEscalation, userName, roleTemplate.Name)
+	}
+	return nil
+}
+
+type mocks struct {
+	escalation *fakeEscalationChecker
+	policies   *fake.MockControllerInterface[*v3.ProjectMembershipPolicy, *v3.ProjectMembershipPolicyList]
+	prtbCache  *fake.MockCacheInterface[*v3.ProjectRoleTemplateBinding]
+	prtbs      *fake.MockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList]
+}
+
+func newHandler(t *testing.T) (*handler, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		escalation: &fakeEscalationChecker{},
+		policies:   fake.NewMockControllerInterface[*v3.ProjectMembershipPolicy, *v3.ProjectMembershipPolicyList](ctrl),
+		prtbCache:  fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl),
+		prtbs:      fake.NewMockClientInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList](ctrl),
+	}
+	m.policies.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.ProjectMembershipPolicy) (*v3.ProjectMembershipPolicy, error) {
+		return obj, nil
+	}).AnyTimes()
+
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"project-member": {ObjectMeta: metav1.ObjectMeta{Name: "project-member"}, Context: "project"},
+		"cluster-owner":  {ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner"}, Context: "cluster"},
+		"locked":         {ObjectMeta: metav1.ObjectMeta{Name: "locked"}, Context: "project", Locked: true},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		if rt, ok := roleTemplates[name]; ok {
+			return rt, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+	}).AnyTimes()
+
+	projects := []*v3.Project{
+		{ObjectMeta: metav1.ObjectMeta{Name: "p-web", Namespace: "c-1", Labels: map[string]string{"team": "web"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "p-db", Namespace: "c-1", Labels: map[string]string{"team": "db"}}},
+	}
+	projectCache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+	projectCache.EXPECT().List("c-1", gomock.Any()).DoAndReturn(func(_ string, selector labels.Selector) ([]*v3.Project, error) {
+		var selected []*v3.Project
+		for _, project := range projects {
+			if selector.Matches(labels.Set(project.Labels)) {
+				selected = append(selected, project)
+			}
+		}
+		return selected, nil
+	}).AnyTimes()
+
+	userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+	userAttributeCache.EXPECT().List(gomock.Any()).Return([]*v3.UserAttribute{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "u-alice"},
+			GroupPrincipals: map[string]v3.Principals{
+				"okta": {Items: []v3.Principal{
+					{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://team-web"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://admins"}},
+				}},
+			},
+			ExtraByProvider: map[string]map[string][]string{
+				"openldap": {"department": {"engineering"}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "u-bob"},
+			GroupPrincipals: map[string]v3.Principals{
+				"okta": {Items: []v3.Principal{
+					{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://team-web"}},
+					{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://team-db"}},
+				}},
+			},
+			ExtraByProvider: map[string]map[string][]string{
+				"openldap": {"department": {"sales"}},
+			},
+		},
+	}, nil).AnyTimes()
+
+	return &handler{
+		policies:       m.policies,
+		projects:       projectCache,
+		userAttributes: userAttributeCache,
+		roleTemplates:  roleTemplateCache,
+		prtbCache:      m.prtbCache,
+		prtbs:          m.prtbs,
+		escalation:     m.escalation,
+		matched:        map[string]userMatch{},
+	}, m
+}
+
+func newPolicy(rules ...v3.ProjectMembershipRule) *v3.ProjectMembershipPolicy {
+	return &v3.ProjectMembershipPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "teams",
+			Namespace:  "c-1",
+			Generation: 2,
+		},
+		Spec: v3.ProjectMembershipPolicySpec{
+			ClusterName:     "c-1",
+			ProjectSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+			Rules:           rules,
+		},
+		Status: v3.ProjectMembershipPolicyStatus{Author: "u-admin", AuthorGeneration: 2},
+	}
+}
+
+func TestSyncCreatesBindings(t *testing.T) {
+	h, m := newHandler(t)
+	policy := newPolicy(
+		v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://team-*", RoleTemplateNames: []string{"project-member"}},
+		v3.ProjectMembershipRule{
+			UserAttribute:     &v3.UserAttributeMatch{Provider: "openldap", Key: "department", Pattern: "eng*"},
+			RoleTemplateNames: []string{"project-member"},
+		},
+	)
+
+	m.prtbCache.EXPECT().List("", labels.SelectorFromSet(labels.Set{PolicyLabel: "c-1-teams"})).Return(nil, nil)
+	var created []*v3.ProjectRoleTemplateBinding
+	m.prtbs.EXPECT().Create(gomock.Any()).DoAndReturn(func(prtb *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+		created = append(created, prtb)
+		return prtb, nil
+	}).Times(3)
+
+	got, err := h.sync("", policy)
+	require.NoError(t, err)
+
+	subjects := map[string]bool{}
+	for _, prtb := range created {
+		assert.Equal(t, "p-web", prtb.Namespace)
+		assert.Equal(t, "c-1:p-web", prtb.ProjectName)
+		assert.Equal(t, "project-member", prtb.RoleTemplateName)
+		assert.Equal(t, "c-1-teams", prtb.Labels[PolicyLabel])
+		subjects[prtb.GroupPrincipalName+prtb.UserName] = true
+	}
+	assert.Equal(t, map[string]bool{
+		"okta_group://team-web": true,
+		"okta_group://team-db":  true,
+		"u-alice":               true,
+	}, subjects)
+	assert.Equal(t, v3.ProjectMembershipPolicyStatus{ObservedGeneration: 2, Bindings: 3, Author: "u-admin", AuthorGeneration: 2}, got.Status)
+}
+
+func TestSyncRemovesStaleBindings(t *testing.T) {
+	h, m := newHandler(t)
+	policy := newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://admins", RoleTemplateNames: []string{"project-member"}})
+	desired, _, err := h.desiredBindings(policy)
+	require.NoError(t, err)
+	require.Len(t, desired, 1)
+
+	var current []*v3.ProjectRoleTemplateBinding
+	for _, prtb := range desired {
+		current = append(current, prtb)
+	}
+	stale := &v3.ProjectRoleTemplateBinding{ObjectMeta: metav1.ObjectMeta{Name: "mp-teams-stale", Namespace: "p-db"}}
+	current = append(current, stale)
+
+	m.prtbCache.EXPECT().List("", gomock.Any()).Return(current, nil)
+	m.prtbs.EXPECT().Delete("p-db", "mp-teams-stale", gomock.Any()).Return(nil)
+
+	got, err := h.sync("", policy)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Status.Bindings)
+	assert.Empty(t, got.Status.Message)
+}
+
+func TestSyncRemovesBindingsTheAuthorCantGrant(t *testing.T) {
+	h, m := newHandler(t)
+	m.escalation.denied = map[string]string{"c-1:p-db": "project-member"}
+	policy := newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://admins", RoleTemplateNames: []string{"project-member"}})
+	policy.Spec.ProjectSelector = metav1.LabelSelector{}
+
+	granted := &v3.ProjectRoleTemplateBinding{ObjectMeta: metav1.ObjectMeta{
+		Name:      bindingName(policy, &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-web", Namespace: "c-1"}}, "project-member", "group", "okta_group://admins"),
+		Namespace: "p-web",
+	}}
+	denied := &v3.ProjectRoleTemplateBinding{ObjectMeta: metav1.ObjectMeta{
+		Name:      bindingName(policy, &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-db", Namespace: "c-1"}}, "project-member", "group", "okta_group://admins"),
+		Namespace: "p-db",
+	}}
+	m.prtbCache.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{granted, denied}, nil)
+	m.prtbs.EXPECT().Delete("p-db", denied.Name, gomock.Any()).Return(nil)
+
+	got, err := h.sync("", policy)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Status.Bindings)
+	assert.Contains(t, got.Status.Message, "project [p-db]")
+	assert.Contains(t, got.Status.Message, "can't grant RoleTemplate [project-member]")
+}
+
+func TestSyncPolicyWithoutAuthorGrantsNothing(t *testing.T) {
+	tests := []struct {
+		name             string
+		author           string
+		authorGeneration int64
+	}{
+		{name: "no author", authorGeneration: 2},
+		{name: "author without permissions", author: "u-alice", authorGeneration: 2},
+		// the spec was changed without the API since the author was recorded
+		{name: "author of another generation", author: "u-admin", authorGeneration: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, m := newHandler(t)
+			policy := newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*", RoleTemplateNames: []string{"project-member"}})
+			policy.Status.Author = tt.author
+			policy.Status.AuthorGeneration = tt.authorGeneration
+
+			stale := &v3.ProjectRoleTemplateBinding{ObjectMeta: metav1.ObjectMeta{Name: "mp-teams-stale", Namespace: "p-web"}}
+			m.prtbCache.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectRoleTemplateBinding{stale}, nil)
+			m.prtbs.EXPECT().Delete("p-web", "mp-teams-stale", gomock.Any()).Return(nil)
+
+			got, err := h.sync("", policy)
+			require.NoError(t, err)
+			assert.Equal(t, 0, got.Status.Bindings)
+			assert.NotEmpty(t, got.Status.Message)
+			// the author recorded by the API is kept
+			assert.Equal(t, tt.author, got.Status.Author)
+		})
+	}
+}
+
+func TestEnqueuePoliciesOnUserAttributeChange(t *testing.T) {
+	h, m := newHandler(t)
+	policyCache := fake.NewMockCacheInterface[*v3.ProjectMembershipPolicy](gomock.NewController(t))
+	m.policies.EXPECT().Cache().Return(policyCache).AnyTimes()
+	policyCache.EXPECT().List("", gomock.Any()).Return([]*v3.ProjectMembershipPolicy{newPolicy()}, nil).AnyTimes()
+
+	userAttribute := &v3.UserAttribute{
+		ObjectMeta: metav1.ObjectMeta{Name: "u-alice"},
+		GroupPrincipals: map[string]v3.Principals{
+			"okta": {Items: []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "okta_group://team-web"}}}},
+		},
+	}
+	keys, err := h.enqueuePolicies("", "u-alice", userAttribute)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "first sight of the user")
+
+	seen := userAttribute.DeepCopy()
+	seen.LastRefresh = "now"
+	keys, err = h.enqueuePolicies("", "u-alice", seen)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "groups and attributes unchanged")
+
+	changed := seen.DeepCopy()
+	changed.ExtraByProvider = map[string]map[string][]string{"openldap": {"department": {"sales"}}}
+	keys, err = h.enqueuePolicies("", "u-alice", changed)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "attributes changed")
+
+	keys, err = h.enqueuePolicies("", "u-alice", nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "user removed")
+}
+
+func TestSyncInvalidPolicyKeepsBindings(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *v3.ProjectMembershipPolicy
+		wantMessage string
+	}{
+		{
+			name:        "no subject",
+			policy:      newPolicy(v3.ProjectMembershipRule{RoleTemplateNames: []string{"project-member"}}),
+			wantMessage: "exactly one of groupPrincipalPattern and userAttribute must be set",
+		},
+		{
+			name: "both subjects",
+			policy: newPolicy(v3.ProjectMembershipRule{
+				GroupPrincipalPattern: "okta_group://*",
+				UserAttribute:         &v3.UserAttributeMatch{Provider: "openldap", Key: "department", Pattern: "*"},
+				RoleTemplateNames:     []string{"project-member"},
+			}),
+			wantMessage: "exactly one of groupPrincipalPattern and userAttribute must be set",
+		},
+		{
+			name: "attribute without key",
+			policy: newPolicy(v3.ProjectMembershipRule{
+				UserAttribute:     &v3.UserAttributeMatch{Provider: "openldap", Pattern: "*"},
+				RoleTemplateNames: []string{"project-member"},
+			}),
+			wantMessage: "userAttribute requires a provider and a key",
+		},
+		{
+			name:        "invalid pattern",
+			policy:      newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://[", RoleTemplateNames: []string{"project-member"}}),
+			wantMessage: "invalid pattern",
+		},
+		{
+			name:        "no role templates",
+			policy:      newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*"}),
+			wantMessage: "roleTemplateNames must not be empty",
+		},
+		{
+			name:        "missing role template",
+			policy:      newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*", RoleTemplateNames: []string{"missing"}}),
+			wantMessage: "roleTemplate [missing]",
+		},
+		{
+			name:        "cluster role template",
+			policy:      newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*", RoleTemplateNames: []string{"cluster-owner"}}),
+			wantMessage: "is not a project role",
+		},
+		{
+			name:        "locked role template",
+			policy:      newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*", RoleTemplateNames: []string{"locked"}}),
+			wantMessage: "is locked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newHandler(t)
+			tt.policy.Status.Bindings = 4
+
+			// no binding is listed, created or deleted
+			got, err := h.sync("", tt.policy)
+			require.NoError(t, err)
+			assert.Contains(t, got.Status.Message, tt.wantMessage)
+			assert.Equal(t, 4, got.Status.Bindings)
+		})
+	}
+}
+
+func TestSyncPolicyOutsideClusterNamespace(t *testing.T) {
+	h, _ := newHandler(t)
+	policy := newPolicy(v3.ProjectMembershipRule{GroupPrincipalPattern: "okta_group://*", RoleTemplateNames: []string{"project-member"}})
+	policy.Namespace = "c-2"
+
+	got, err := h.sync("", policy)
+	require.NoError(t, err)
+	assert.Contains(t, got.Status.Message, "policies must be in the namespace of their cluster")
+}
+
+func TestRemove(t *testing.T) {
+	h, m := newHandler(t)
+	policy := newPolicy()
+
+	m.prtbCache.EXPECT().List("", labels.SelectorFromSet(labels.Set{PolicyLabel: "c-1-teams"})).Return([]*v3.ProjectRoleTemplateBinding{
+		{ObjectMeta: metav1.ObjectMeta{Name: "mp-teams-a", Namespace: "p-web"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "mp-teams-b", Namespace: "p-db"}},
+	}, nil)
+	m.prtbs.EXPECT().Delete("p-web", "mp-teams-a", gomock.Any()).Return(nil)
+	m.prtbs.EXPECT().Delete("p-db", "mp-teams-b", gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "mp-teams-b"))
+
+	_, err := h.remove("", policy)
+	require.NoError(t, err)
+}
+
+func TestBindingNameIsStable(t *testing.T) {
+	policy := newPolicy()
+	project := &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-web", Namespace: "c-1"}}
+
+	name := bindingName(policy, project, "project-member", "group", "okta_group://team-web")
+	assert.Equal(t, name, bindingName(policy, project, "project-member", "group", "okta_group://team-web"))
+	assert.NotEqual(t, name, bindingName(policy, project, "project-member", "user", "okta_group://team-web"))
+	assert.LessOrEqual(t, len(name), 63)
+}
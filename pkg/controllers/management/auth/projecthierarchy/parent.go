@@ -0,0 +1,84 @@
+package projecthierarchy
+
+import (
+	"reflect"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	parentControllerName = "mgmt-auth-project-hierarchy-parent-controller"
+	parentEnqueuerName   = "mgmt-auth-project-hierarchy-parent-enqueuer"
+)
+
+// parentHandler validates the ParentProjectName of the projects, however they are written, and records the valid ones
+// in the project.ParentProjectAnnotation the tree of projects is built from. A parent is only valid once it accepts the
+// project as a sub-project, see project.SubProjectsAnnotation. A project whose parent is invalid is a root of the tree
+// until its parent is fixed.
+type parentHandler struct {
+	projects mgmtcontrollers.ProjectController
+}
+
+func (h *parentHandler) sync(_ string, p *v3.Project) (*v3.Project, error) {
+	if p == nil || p.DeletionTimestamp != nil {
+		return p, nil
+	}
+	projects, err := h.projects.Cache().List(p.Namespace, labels.Everything())
+	if err != nil {
+		return p, err
+	}
+
+	toUpdate := p.DeepCopy()
+	if err := project.ValidateParent(p, projects); err != nil {
+		delete(toUpdate.Annotations, project.ParentProjectAnnotation)
+		v3.ProjectConditionParentProjectValidated.False(toUpdate)
+		v3.ProjectConditionParentProjectValidated.Message(toUpdate, err.Error())
+	} else if p.Spec.ParentProjectName != "" {
+		if toUpdate.Annotations == nil {
+			toUpdate.Annotations = map[string]string{}
+		}
+		toUpdate.Annotations[project.ParentProjectAnnotation] = p.Spec.ParentProjectName
+		v3.ProjectConditionParentProjectValidated.True(toUpdate)
+		v3.ProjectConditionParentProjectValidated.Message(toUpdate, "")
+	} else {
+		delete(toUpdate.Annotations, project.ParentProjectAnnotation)
+		if v3.ProjectConditionParentProjectValidated.GetStatus(toUpdate) != "" {
+			v3.ProjectConditionParentProjectValidated.True(toUpdate)
+			v3.ProjectConditionParentProjectValidated.Message(toUpdate, "")
+		}
+	}
+	if reflect.DeepEqual(p, toUpdate) {
+		return p, nil
+	}
+	if project.ParentName(p) != project.ParentName(toUpdate) {
+		logrus.Infof("[projectHierarchy] setting the parent of project [%s/%s] to [%s]", p.Namespace, p.Name, project.ParentName(toUpdate))
+	}
+	return h.projects.Update(toUpdate)
+}
+
+// enqueueProjects enqueues the sub-projects and the sibling projects of a changed project, as the validity of their
+// parent depends on it: its existence, the sub-projects it accepts and its resource quota, and the resource quotas of
+// the other sub-projects of the parent.
+func (h *parentHandler) enqueueProjects(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	parentNames := map[string]bool{name: true}
+	if p, ok := obj.(*v3.Project); ok && p.Spec.ParentProjectName != "" {
+		parentNames[p.Spec.ParentProjectName] = true
+	}
+	projects, err := h.projects.Cache().List(namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var keys []relatedresource.Key
+	for _, p := range projects {
+		if p.Name != name && (parentNames[p.Spec.ParentProjectName] || parentNames[project.ParentName(p)]) {
+			keys = append(keys, relatedresource.Key{Namespace: p.Namespace, Name: p.Name})
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,97 @@
+package projecthierarchy
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newParentHandler(t *testing.T, projects ...*v3.Project) (*parentHandler, *[]*v3.Project) {
+	ctrl := gomock.NewController(t)
+	projectController := fake.NewMockControllerInterface[*v3.Project, *v3.ProjectList](ctrl)
+	projectCache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+	projectController.EXPECT().Cache().Return(projectCache).AnyTimes()
+	projectCache.EXPECT().List("c-1", gomock.Any()).Return(projects, nil).AnyTimes()
+	var updated []*v3.Project
+	projectController.EXPECT().Update(gomock.Any()).DoAndReturn(func(obj *v3.Project) (*v3.Project, error) {
+		updated = append(updated, obj)
+		return obj, nil
+	}).AnyTimes()
+	return &parentHandler{projects: projectController}, &updated
+}
+
+func newProject(name, parent string) *v3.Project {
+	return &v3.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "c-1"},
+		Spec:       v3.ProjectSpec{ParentProjectName: parent},
+	}
+}
+
+func TestParentSync(t *testing.T) {
+	org := newProject("p-org", "")
+	org.Annotations = map[string]string{project.SubProjectsAnnotation: "p-team"}
+	h, updated := newParentHandler(t, org)
+
+	t.Run("valid parent", func(t *testing.T) {
+		*updated = nil
+		_, err := h.sync("", newProject("p-team", "p-org"))
+		require.NoError(t, err)
+		require.Len(t, *updated, 1)
+		assert.Equal(t, "p-org", project.ParentName((*updated)[0]))
+		assert.True(t, v3.ProjectConditionParentProjectValidated.IsTrue((*updated)[0]))
+	})
+
+	t.Run("invalid parent", func(t *testing.T) {
+		*updated = nil
+		team := newProject("p-team", "p-missing")
+		team.Annotations = map[string]string{project.ParentProjectAnnotation: "p-org"}
+		_, err := h.sync("", team)
+		require.NoError(t, err)
+		require.Len(t, *updated, 1)
+		assert.Empty(t, project.ParentName((*updated)[0]))
+		assert.True(t, v3.ProjectConditionParentProjectValidated.IsFalse((*updated)[0]))
+		assert.Equal(t, "project p-missing not found in cluster c-1 or it doesn't accept the project as a sub-project", v3.ProjectConditionParentProjectValidated.GetMessage((*updated)[0]))
+	})
+
+	t.Run("parent doesn't accept the project", func(t *testing.T) {
+		*updated = nil
+		_, err := h.sync("", newProject("p-app", "p-org"))
+		require.NoError(t, err)
+		require.Len(t, *updated, 1)
+		assert.Empty(t, project.ParentName((*updated)[0]))
+		assert.True(t, v3.ProjectConditionParentProjectValidated.IsFalse((*updated)[0]))
+	})
+
+	t.Run("no parent", func(t *testing.T) {
+		*updated = nil
+		_, err := h.sync("", newProject("p-other", ""))
+		require.NoError(t, err)
+		assert.Empty(t, *updated)
+	})
+}
+
+func TestEnqueueProjects(t *testing.T) {
+	team := newProject("p-team", "p-org")
+	team.Annotations = map[string]string{project.ParentProjectAnnotation: "p-org"}
+	h, _ := newParentHandler(t,
+		newProject("p-org", ""),
+		team,
+		newProject("p-pending", "p-org"),
+		newProject("p-app", "p-team"),
+		newProject("p-other", ""),
+	)
+
+	keys, err := h.enqueueProjects("c-1", "p-team", team)
+	require.NoError(t, err)
+	var names []string
+	for _, key := range keys {
+		names = append(names, key.Name)
+	}
+	assert.ElementsMatch(t, []string{"p-pending", "p-app"}, names)
+}
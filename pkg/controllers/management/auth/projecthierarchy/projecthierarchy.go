@@ -0,0 +1,261 @@
+// Package projecthierarchy validates the parents of the projects, and inherits the ProjectRoleTemplateBindings of a
+// project down its tree of sub-projects, by keeping a copy of each binding in every descendant project.
+package projecthierarchy
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/rancher/pkg/ref"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	controllerName = "mgmt-auth-project-hierarchy-prtb-controller"
+	enqueuerName   = "mgmt-auth-project-hierarchy-prtb-enqueuer"
+
+	// InheritedFromLabel is set on the bindings inherited from a binding of an ancestor project, with the namespace
+	// and name of the origin binding as value.
+	InheritedFromLabel = "authz.management.cattle.io/inherited-from"
+	// InheritedFromAnnotation is set on the bindings inherited from a binding of an ancestor project, with the
+	// namespace/name key of the origin binding as value.
+	InheritedFromAnnotation = "authz.management.cattle.io/inherited-from"
+)
+
+type handler struct {
+	prtbs    mgmtcontrollers.ProjectRoleTemplateBindingController
+	projects mgmtcontrollers.ProjectCache
+
+	// parents are the last seen validated parents of the projects, by namespace/name key
+	parentsLock sync.Mutex
+	parents     map[string]string
+}
+
+func Register(ctx context.Context, management *config.ManagementContext) {
+	p := &parentHandler{
+		projects: management.Wrangler.Mgmt.Project(),
+	}
+	p.projects.OnChange(ctx, parentControllerName, p.sync)
+	relatedresource.Watch(ctx, parentEnqueuerName, p.enqueueProjects, p.projects, p.projects)
+
+	h := &handler{
+		prtbs:    management.Wrangler.Mgmt.ProjectRoleTemplateBinding(),
+		projects: management.Wrangler.Mgmt.Project().Cache(),
+		parents:  map[string]string{},
+	}
+	h.prtbs.OnChange(ctx, controllerName, h.sync)
+	relatedresource.Watch(ctx, enqueuerName, h.enqueueBindings, h.prtbs,
+		management.Wrangler.Mgmt.ProjectRoleTemplateBinding(), management.Wrangler.Mgmt.Project())
+}
+
+// enqueueBindings enqueues the origin of a changed inherited binding. When the validated parent of a project changes,
+// it enqueues the bindings of its former and new ancestors, the only ones whose descendants change.
+func (h *handler) enqueueBindings(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	switch o := obj.(type) {
+	case *v3.ProjectRoleTemplateBinding:
+		if origin := o.Annotations[InheritedFromAnnotation]; origin != "" {
+			originNamespace, originName := splitKey(origin)
+			return []relatedresource.Key{{Namespace: originNamespace, Name: originName}}, nil
+		}
+		return nil, nil
+	case *v3.Project:
+		previous, parent := h.swapParent(namespace, name, o)
+		if previous == parent {
+			return nil, nil
+		}
+		projects, err := h.projects.List(namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		tree := project.NewTree(projects)
+		var keys []relatedresource.Key
+		for _, parentName := range []string{previous, parent} {
+			if parentName == "" {
+				continue
+			}
+			ancestors := append([]string{parentName}, names(tree.Ancestors(parentName))...)
+			for _, ancestor := range ancestors {
+				prtbs, err := h.prtbs.Cache().List(ancestor, labels.Everything())
+				if err != nil {
+					return nil, err
+				}
+				for _, prtb := range prtbs {
+					if prtb.Labels[InheritedFromLabel] == "" {
+						keys = append(keys, relatedresource.Key{Namespace: prtb.Namespace, Name: prtb.Name})
+					}
+				}
+			}
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// swapParent records the validated parent of the project, none if it is removed, and returns the previously recorded
+// one along with it.
+func (h *handler) swapParent(namespace, name string, p *v3.Project) (string, string) {
+	key := namespace + "/" + name
+	parent := ""
+	if p != nil && p.DeletionTimestamp == nil {
+		parent = project.ParentName(p)
+	}
+
+	h.parentsLock.Lock()
+	defer h.parentsLock.Unlock()
+	previous := h.parents[key]
+	if parent == "" {
+		delete(h.parents, key)
+	} else {
+		h.parents[key] = parent
+	}
+	return previous, parent
+}
+
+func names(projects []*v3.Project) []string {
+	result := make([]string, 0, len(projects))
+	for _, p := range projects {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+func (h *handler) sync(key string, prtb *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+	if prtb == nil {
+		// the origin is gone, so are the bindings inherited from it
+		originNamespace, originName := splitKey(key)
+		return nil, h.reconcile(originNamespace, originName, nil)
+	}
+	if prtb.Labels[InheritedFromLabel] != "" {
+		return prtb, h.syncInherited(prtb)
+	}
+	desired, err := h.desiredBindings(prtb)
+	if err != nil {
+		return prtb, err
+	}
+	return prtb, h.reconcile(prtb.Namespace, prtb.Name, desired)
+}
+
+// syncInherited removes an inherited binding whose origin no longer exists. The inherited bindings of an existing
+// origin are reconciled by the origin.
+func (h *handler) syncInherited(prtb *v3.ProjectRoleTemplateBinding) error {
+	originNamespace, originName := splitKey(prtb.Annotations[InheritedFromAnnotation])
+	_, err := h.prtbs.Cache().Get(originNamespace, originName)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+	logrus.Infof("[projectHierarchy] removing ProjectRoleTemplateBinding [%s/%s] inherited from removed binding [%s/%s]",
+		prtb.Namespace, prtb.Name, originNamespace, originName)
+	if err := h.prtbs.Delete(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// desiredBindings returns the bindings the origin binding is inherited as in the descendants of its project, by
+// namespace.
+func (h *handler) desiredBindings(origin *v3.ProjectRoleTemplateBinding) (map[string]*v3.ProjectRoleTemplateBinding, error) {
+	desired := map[string]*v3.ProjectRoleTemplateBinding{}
+	// service accounts are local to the namespaces of the project they are bound in
+	if origin.DeletionTimestamp != nil || origin.ServiceAccount != "" {
+		return desired, nil
+	}
+	clusterName, projectName := ref.Parse(origin.ProjectName)
+	if clusterName == "" || projectName == "" {
+		return desired, nil
+	}
+	projects, err := h.projects.List(clusterName, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	tree := project.NewTree(projects)
+	// the sub-projects of a project being deleted are about to lose their parent
+	removed := map[string]bool{}
+	for _, descendant := range tree.Descendants(projectName) {
+		if descendant.DeletionTimestamp != nil || removed[tree.Parent(descendant.Name).Name] {
+			removed[descendant.Name] = true
+			continue
+		}
+		desired[descendant.Name] = newInheritedBinding(origin, descendant)
+	}
+	return desired, nil
+}
+
+// reconcile makes the bindings inherited from the origin match the desired ones.
+func (h *handler) reconcile(originNamespace, originName string, desired map[string]*v3.ProjectRoleTemplateBinding) error {
+	selector := labels.SelectorFromSet(labels.Set{InheritedFromLabel: name.SafeConcatName(originNamespace, originName)})
+	current, err := h.prtbs.Cache().List("", selector)
+	if err != nil {
+		return err
+	}
+	originKey := originNamespace + "/" + originName
+	for _, prtb := range current {
+		if prtb.Annotations[InheritedFromAnnotation] != originKey {
+			continue
+		}
+		if want, ok := desired[prtb.Namespace]; ok && want.Name == prtb.Name && sameGrant(want, prtb) {
+			delete(desired, prtb.Namespace)
+			continue
+		}
+		logrus.Infof("[projectHierarchy] removing ProjectRoleTemplateBinding [%s/%s] no longer inherited from [%s]",
+			prtb.Namespace, prtb.Name, originKey)
+		if err := h.prtbs.Delete(prtb.Namespace, prtb.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	for _, prtb := range desired {
+		logrus.Infof("[projectHierarchy] inheriting ProjectRoleTemplateBinding [%s] in project [%s]", originKey, prtb.ProjectName)
+		if _, err := h.prtbs.Create(prtb); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func newInheritedBinding(origin *v3.ProjectRoleTemplateBinding, descendant *v3.Project) *v3.ProjectRoleTemplateBinding {
+	return &v3.ProjectRoleTemplateBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name.SafeConcatName("inherited", origin.Namespace, origin.Name),
+			Namespace:   descendant.Name,
+			Labels:      map[string]string{InheritedFromLabel: name.SafeConcatName(origin.Namespace, origin.Name)},
+			Annotations: map[string]string{InheritedFromAnnotation: origin.Namespace + "/" + origin.Name},
+		},
+		ProjectName:        descendant.Namespace + ":" + descendant.Name,
+		RoleTemplateName:   origin.RoleTemplateName,
+		UserName:           origin.UserName,
+		UserPrincipalName:  origin.UserPrincipalName,
+		GroupName:          origin.GroupName,
+		GroupPrincipalName: origin.GroupPrincipalName,
+	}
+}
+
+// sameGrant returns whether the inherited binding grants the RoleTemplate of the desired one to its subject on the
+// same project. Subject fields left empty in the desired binding may be filled in on the inherited one.
+func sameGrant(want, have *v3.ProjectRoleTemplateBinding) bool {
+	return want.ProjectName == have.ProjectName &&
+		want.RoleTemplateName == have.RoleTemplateName &&
+		matches(want.UserName, have.UserName) &&
+		matches(want.UserPrincipalName, have.UserPrincipalName) &&
+		matches(want.GroupName, have.GroupName) &&
+		matches(want.GroupPrincipalName, have.GroupPrincipalName)
+}
+
+func matches(want, have string) bool {
+	return want == "" || want == have
+}
+
+// splitKey splits a namespace/name key.
+func splitKey(key string) (string, string) {
+	namespace, name, _ := strings.Cut(key, "/")
+	return namespace, name
+}
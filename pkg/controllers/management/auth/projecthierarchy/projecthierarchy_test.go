@@ -0,0 +1,200 @@
+package projecthierarchy
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type mocks struct {
+	prtbs     *fake.MockControllerInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList]
+	prtbCache *fake.MockCacheInterface[*v3.ProjectRoleTemplateBinding]
+	created   []*v3.ProjectRoleTemplateBinding
+	deleted   []string
+}
+
+func newHandler(t *testing.T, bindings ...*v3.ProjectRoleTemplateBinding) (*handler, *mocks) {
+	ctrl := gomock.NewController(t)
+	m := &mocks{
+		prtbs:     fake.NewMockControllerInterface[*v3.ProjectRoleTemplateBinding, *v3.ProjectRoleTemplateBindingList](ctrl),
+		prtbCache: fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl),
+	}
+	m.prtbs.EXPECT().Cache().Return(m.prtbCache).AnyTimes()
+	m.prtbs.EXPECT().Create(gomock.Any()).DoAndReturn(func(obj *v3.ProjectRoleTemplateBinding) (*v3.ProjectRoleTemplateBinding, error) {
+		m.created = append(m.created, obj)
+		return obj, nil
+	}).AnyTimes()
+	m.prtbs.EXPECT().Delete(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(namespace, name string, _ *metav1.DeleteOptions) error {
+		m.deleted = append(m.deleted, namespace+"/"+name)
+		return nil
+	}).AnyTimes()
+	m.prtbCache.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(func(namespace string, selector labels.Selector) ([]*v3.ProjectRoleTemplateBinding, error) {
+		var result []*v3.ProjectRoleTemplateBinding
+		for _, b := range bindings {
+			if (namespace == "" || b.Namespace == namespace) && selector.Matches(labels.Set(b.Labels)) {
+				result = append(result, b)
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+	m.prtbCache.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(namespace, name string) (*v3.ProjectRoleTemplateBinding, error) {
+		for _, b := range bindings {
+			if b.Namespace == namespace && b.Name == name {
+				return b, nil
+			}
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "projectroletemplatebindings"}, name)
+	}).AnyTimes()
+
+	projects := []*v3.Project{
+		newProject("p-org", ""),
+		withValidatedParent(newProject("p-team", "p-org")),
+		withValidatedParent(newProject("p-app", "p-team")),
+		newProject("p-other", ""),
+	}
+	projectCache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+	projectCache.EXPECT().List("c-1", gomock.Any()).Return(projects, nil).AnyTimes()
+
+	return &handler{prtbs: m.prtbs, projects: projectCache, parents: map[string]string{}}, m
+}
+
+func withValidatedParent(p *v3.Project) *v3.Project {
+	p.Annotations = map[string]string{project.ParentProjectAnnotation: p.Spec.ParentProjectName}
+	return p
+}
+
+func origin(namespace string) *v3.ProjectRoleTemplateBinding {
+	return &v3.ProjectRoleTemplateBinding{
+		ObjectMeta:       metav1.ObjectMeta{Name: "prtb-1", Namespace: namespace},
+		ProjectName:      "c-1:" + namespace,
+		RoleTemplateName: "project-member",
+		UserName:         "u-alice",
+	}
+}
+
+func TestSyncCreatesInheritedBindings(t *testing.T) {
+	h, m := newHandler(t)
+
+	_, err := h.sync("p-org/prtb-1", origin("p-org"))
+	require.NoError(t, err)
+
+	require.Len(t, m.created, 2)
+	namespaces := map[string]bool{}
+	for _, prtb := range m.created {
+		namespaces[prtb.Namespace] = true
+		assert.Equal(t, "c-1:"+prtb.Namespace, prtb.ProjectName)
+		assert.Equal(t, "project-member", prtb.RoleTemplateName)
+		assert.Equal(t, "u-alice", prtb.UserName)
+		assert.Equal(t, "p-org/prtb-1", prtb.Annotations[InheritedFromAnnotation])
+		assert.Equal(t, "p-org-prtb-1", prtb.Labels[InheritedFromLabel])
+	}
+	assert.Equal(t, map[string]bool{"p-team": true, "p-app": true}, namespaces)
+	assert.Empty(t, m.deleted)
+}
+
+func TestSyncKeepsCurrentAndRemovesStaleBindings(t *testing.T) {
+	current := newInheritedBinding(origin("p-team"), &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-app", Namespace: "c-1"}})
+	// the project of this binding is no longer a descendant
+	stale := newInheritedBinding(origin("p-team"), &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-other", Namespace: "c-1"}})
+	h, m := newHandler(t, current, stale)
+
+	_, err := h.sync("p-team/prtb-1", origin("p-team"))
+	require.NoError(t, err)
+
+	assert.Empty(t, m.created)
+	assert.Equal(t, []string{"p-other/" + stale.Name}, m.deleted)
+}
+
+func TestSyncRemovesBindingsOfRemovedOrigin(t *testing.T) {
+	deleting := origin("p-team")
+	deleting.DeletionTimestamp = &metav1.Time{}
+	serviceAccount := origin("p-team")
+	serviceAccount.UserName = ""
+	serviceAccount.ServiceAccount = "default:sa"
+
+	tests := []struct {
+		name   string
+		origin *v3.ProjectRoleTemplateBinding
+	}{
+		{name: "removed origin"},
+		{name: "deleting origin", origin: deleting},
+		{name: "service account origin", origin: serviceAccount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inherited := newInheritedBinding(origin("p-team"), &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-app", Namespace: "c-1"}})
+			h, m := newHandler(t, inherited)
+
+			_, err := h.sync("p-team/prtb-1", tt.origin)
+			require.NoError(t, err)
+
+			assert.Empty(t, m.created)
+			assert.Equal(t, []string{"p-app/" + inherited.Name}, m.deleted)
+		})
+	}
+}
+
+func TestSyncInherited(t *testing.T) {
+	inherited := newInheritedBinding(origin("p-team"), &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-app", Namespace: "c-1"}})
+
+	t.Run("origin exists", func(t *testing.T) {
+		h, m := newHandler(t, origin("p-team"), inherited)
+		_, err := h.sync("p-app/"+inherited.Name, inherited)
+		require.NoError(t, err)
+		assert.Empty(t, m.deleted)
+	})
+	t.Run("origin removed", func(t *testing.T) {
+		h, m := newHandler(t, inherited)
+		_, err := h.sync("p-app/"+inherited.Name, inherited)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"p-app/" + inherited.Name}, m.deleted)
+	})
+}
+
+func TestEnqueueBindings(t *testing.T) {
+	inherited := newInheritedBinding(origin("p-team"), &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-app", Namespace: "c-1"}})
+	h, _ := newHandler(t, origin("p-org"), origin("p-team"), inherited)
+
+	keys, err := h.enqueueBindings("p-app", inherited.Name, inherited)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "p-team", keys[0].Namespace)
+	assert.Equal(t, "prtb-1", keys[0].Name)
+
+	// the bindings of the ancestors of a project are enqueued when its parent is first seen
+	keys, err = h.enqueueBindings("c-1", "p-app", withValidatedParent(newProject("p-app", "p-team")))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"p-team/prtb-1", "p-org/prtb-1"}, keyStrings(keys))
+
+	keys, err = h.enqueueBindings("c-1", "p-app", withValidatedParent(newProject("p-app", "p-team")))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// a project without a parent has no ancestors
+	keys, err = h.enqueueBindings("c-1", "p-other", newProject("p-other", ""))
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	// the bindings of its former ancestors are enqueued when the parent changes
+	keys, err = h.enqueueBindings("c-1", "p-app", withValidatedParent(newProject("p-app", "p-other")))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"p-team/prtb-1", "p-org/prtb-1"}, keyStrings(keys))
+}
+
+func keyStrings(keys []relatedresource.Key) []string {
+	var result []string
+	for _, key := range keys {
+		result = append(result, key.Namespace+"/"+key.Name)
+	}
+	return result
+}
@@ -24,6 +24,7 @@
 )
 
 var projectManagementPlaneResources = map[string]string{
+	"accessrequests":              "management.cattle.io",
 	"apps":                        "project.cattle.io",
 	"apprevisions":                "project.cattle.io",
 	"catalogtemplates":            "management.cattle.io",
@@ -4,8 +4,13 @@
 	"context"
 
 	"github.com/rancher/rancher/pkg/clustermanager"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/accessrequest"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/bindingexpiry"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/bindingreview"
 	"github.com/rancher/rancher/pkg/controllers/management/auth/globalroles"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/membershippolicy"
 	"github.com/rancher/rancher/pkg/controllers/management/auth/project_cluster"
+	"github.com/rancher/rancher/pkg/controllers/management/auth/projecthierarchy"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/wrangler"
 	v1 "k8s.io/api/rbac/v1"
@@ -86,6 +91,11 @@ func RegisterEarly(ctx context.Context, management *config.ManagementContext, cl
 	management.Management.GlobalRoleBindings("").AddHandler(ctx, "legacy-grb-cleaner", grbLegacy.sync)
 	management.Management.RoleTemplates("").AddHandler(ctx, "legacy-rt-cleaner", rtLegacy.sync)
 	globalroles.Register(ctx, management, clusterManager)
+	bindingexpiry.Register(ctx, management)
+	accessrequest.Register(ctx, management)
+	bindingreview.Register(ctx, management)
+	membershippolicy.Register(ctx, management)
+	projecthierarchy.Register(ctx, management)
 }
 
 func RegisterLate(ctx context.Context, management *config.ManagementContext) {
@@ -3,6 +3,7 @@
 import (
 	"context"
 
+	"github.com/rancher/rancher/pkg/auth/accessreview"
 	"github.com/rancher/rancher/pkg/auth/providerrefresh"
 	"github.com/rancher/rancher/pkg/auth/providers/azure"
 	"github.com/rancher/rancher/pkg/auth/userretention"
@@ -19,16 +20,19 @@
 type SettingController struct {
 	ensureUserRetentionLabels func() error
 	scheduleUserRetention     func(string) error
+	scheduleAccessReview      func(string) error
 }
 
 func newAuthSettingController(ctx context.Context, mgmt *config.ManagementContext) *SettingController {
 	userRetention := userretention.New(mgmt.Wrangler)
 	userRetentionDaemon := crondaemon.New(ctx, "userretention", userRetention.Run)
 	userRetentionLabeler := userretention.NewUserLabeler(ctx, mgmt.Wrangler)
+	accessReviewDaemon := crondaemon.New(ctx, "accessreview", accessreview.New(mgmt.Wrangler).Run)
 
 	return &SettingController{
 		ensureUserRetentionLabels: userRetentionLabeler.EnsureForAll,
 		scheduleUserRetention:     userRetentionDaemon.Schedule,
+		scheduleAccessReview:      accessReviewDaemon.Schedule,
 	}
 }
 
@@ -55,6 +59,10 @@ func (c *SettingController) sync(key string, obj *v3.Setting) (runtime.Object, e
 		if err := c.ensureUserRetentionLabels(); err != nil {
 			logrus.Errorf("error updating retention labels for users: %v", err)
 		}
+	case settings.AccessReviewCron.Name:
+		if err := c.scheduleAccessReview(obj.Value); err != nil {
+			logrus.Errorf("error scheduling access review daemon: %v", err)
+		}
 	}
 	return nil, nil
 }
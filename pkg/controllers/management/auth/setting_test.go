@@ -60,3 +60,26 @@ func TestSettingsSyncScheduleUserRetention(t *testing.T) {
 		t.Fatalf("Expected scheduleRetentionCalledTimes: %d got %d", want, got)
 	}
 }
+
+func TestSettingsSyncScheduleAccessReview(t *testing.T) {
+	var scheduledExp string
+	controller := &SettingController{
+		scheduleAccessReview: func(exp string) error {
+			scheduledExp = exp
+			return nil
+		},
+	}
+
+	name := settings.AccessReviewCron.Name
+	_, err := controller.sync(name, &v3.Setting{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Value:      "0 0 1 */3 *",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, got := "0 0 1 */3 *", scheduledExp; want != got {
+		t.Fatalf("Expected scheduled expression: %q got %q", want, got)
+	}
+}
@@ -10,6 +10,7 @@
 	"github.com/rancher/rancher/pkg/api/steve/proxy"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	managementcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/tunnelserver"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/remotedialer"
 	"github.com/rancher/wrangler/v3/pkg/condition"
@@ -29,6 +30,7 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		clusterCache: wrangler.Mgmt.Cluster().Cache(),
 		clusters:     wrangler.Mgmt.Cluster(),
 		tunnelServer: wrangler.TunnelServer,
+		sessions:     wrangler.TunnelSessions,
 	}
 
 	go func() {
@@ -44,6 +46,7 @@ type checker struct {
 	clusterCache managementcontrollers.ClusterCache
 	clusters     managementcontrollers.ClusterClient
 	tunnelServer *remotedialer.Server
+	sessions     *tunnelserver.SessionHistory
 }
 
 func (c *checker) check() error {
@@ -83,7 +86,11 @@ func (c *checker) hasSession(cluster *v3.Cluster) bool {
 		ioutil.ReadAll(resp.Body)
 		resp.Body.Close()
 	}()
-	return resp.StatusCode == http.StatusOK
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	c.sessions.Seen(clientKey)
+	return true
 }
 
 func (c *checker) checkCluster(cluster *v3.Cluster) error {
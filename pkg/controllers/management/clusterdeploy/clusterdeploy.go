@@ -69,9 +69,15 @@ func Register(ctx context.Context, management *config.ManagementContext, cluster
 		clusterManager:       clusterManager,
 		secretLister:         management.Core.Secrets("").Controller().Lister(),
 		ctx:                  ctx,
+		rollout: &agentRollout{
+			clusterLister: management.Management.Clusters("").Controller().Lister(),
+			configMaps:    management.Wrangler.Core.ConfigMap(),
+			reserved:      map[string]time.Time{},
+		},
 	}
 
 	management.Management.Clusters("").AddHandler(ctx, "cluster-deploy", c.sync)
+	go c.rollout.syncStatus(ctx)
 }
 
 type clusterDeploy struct {
@@ -83,6 +89,7 @@ type clusterDeploy struct {
 	nodeLister           v3.NodeLister
 	secretLister         v1.SecretLister
 	ctx                  context.Context
+	rollout              *agentRollout
 }
 
 func (cd *clusterDeploy) sync(key string, cluster *apimgmtv3.Cluster) (runtime.Object, error) {
@@ -291,7 +298,30 @@ func (cd *clusterDeploy) deployAgent(cluster *apimgmtv3.Cluster) error {
 	logrus.Tracef("clusterDeploy: deployAgent: desiredTaints is [%v] for cluster [%s]", desiredTaints, cluster.Name)
 
 	if !redeployAgent(cluster, desiredAgent, desiredAuth, desiredFeatures, desiredTaints) {
-		return nil
+		return cd.checkAgentRollout(cluster)
+	}
+
+	// An agent upgrade whose YAML keeps failing to apply times out as one that isn't rolled out
+	if cluster.Annotations[AgentRolloutStartedAnn] != "" {
+		if failed, err := cd.failTimedOutAgentRollout(cluster); err != nil || failed {
+			return err
+		}
+	}
+
+	// Upgrades of the agent, ie. after a Rancher upgrade, are staged across the clusters according to the rollout policy
+	if cluster.Annotations[AgentForceDeployAnn] != "true" && cluster.Annotations[AgentRolloutStartedAnn] == "" && agentUpgradeNeeded(cluster) {
+		started, err := cd.rollout.start(cluster)
+		if err != nil {
+			return err
+		}
+		if !started {
+			cd.clusters.Controller().EnqueueAfter("", cluster.Name, agentRolloutRequeueInterval)
+			return nil
+		}
+		if cluster.Annotations == nil {
+			cluster.Annotations = map[string]string{}
+		}
+		cluster.Annotations[AgentRolloutStartedAnn] = time.Now().UTC().Format(time.RFC3339)
 	}
 
 	kubeConfig, tokenName, err := cd.getKubeConfig(cluster)
@@ -0,0 +1,411 @@
+package clusterdeploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/controllers/management/clusterconnected"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	wcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/ticker"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// AgentRolloutStartedAnn is set on a cluster to the time the upgrade of its agent started, until the upgraded
+	// agent is rolled out and connected.
+	AgentRolloutStartedAnn = "management.cattle.io/agent-rollout-started"
+	// AgentRolloutFailedAnn is set on a cluster whose upgraded agent was not rolled out and connected in time.
+	// The rollout of agent upgrades is paused until the annotation is removed.
+	AgentRolloutFailedAnn = "management.cattle.io/agent-rollout-failed"
+
+	// AgentRolloutStatusConfigMap is the name of the config map in the system namespace holding the status of the
+	// rollout of agent upgrades.
+	AgentRolloutStatusConfigMap = "agent-rollout-status"
+
+	agentRolloutRequeueInterval = 30 * time.Second
+	agentRolloutCheckInterval   = 15 * time.Second
+	// agentRolloutReservationTTL is how long a cluster whose agent upgrade started counts as being upgraded before
+	// its annotation is visible in the cache.
+	agentRolloutReservationTTL = time.Minute
+)
+
+// Phases of the rollout of agent upgrades.
+const (
+	AgentRolloutPhaseCanary     = "Canary"
+	AgentRolloutPhaseInProgress = "InProgress"
+	AgentRolloutPhasePaused     = "Paused"
+	AgentRolloutPhaseComplete   = "Complete"
+)
+
+// rolloutState is the state of the agent upgrade of a cluster.
+type rolloutState string
+
+const (
+	rolloutStateUpToDate  rolloutState = "UpToDate"
+	rolloutStatePending   rolloutState = "Pending"
+	rolloutStateUpgrading rolloutState = "Upgrading"
+	rolloutStateFailed    rolloutState = "Failed"
+)
+
+// AgentRolloutStatus is the status of the rollout of agent upgrades across the clusters.
+type AgentRolloutStatus struct {
+	Phase string `json:"phase"`
+	// AgentImages are the clusters running each agent image.
+	AgentImages map[string][]string `json:"agentImages"`
+	// Pending are the clusters whose agent is waiting to be upgraded.
+	Pending []string `json:"pending,omitempty"`
+	// Upgrading are the clusters whose agent is being upgraded.
+	Upgrading []string `json:"upgrading,omitempty"`
+	// Failed are the clusters whose agent upgrade failed, with the reason.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// rolloutPolicy is the policy of the rollout of agent upgrades, read from the settings.
+type rolloutPolicy struct {
+	batchSize int
+	canary    labels.Selector
+	paused    bool
+}
+
+func getRolloutPolicy() (rolloutPolicy, error) {
+	var (
+		policy rolloutPolicy
+		err    error
+	)
+
+	if policy.batchSize, err = strconv.Atoi(settings.AgentRolloutBatchSize.Get()); err != nil || policy.batchSize < 0 {
+		return policy, fmt.Errorf("invalid %s setting %q", settings.AgentRolloutBatchSize.Name, settings.AgentRolloutBatchSize.Get())
+	}
+	if selector := settings.AgentRolloutCanarySelector.Get(); selector != "" {
+		if policy.canary, err = labels.Parse(selector); err != nil {
+			return policy, fmt.Errorf("invalid %s setting %q: %w", settings.AgentRolloutCanarySelector.Name, selector, err)
+		}
+	}
+	policy.paused = settings.AgentRolloutPaused.Get() == "true"
+
+	return policy, nil
+}
+
+// enabled returns false when agent upgrades are not restricted, in which case they all start immediately.
+func (p rolloutPolicy) enabled() bool {
+	return p.batchSize > 0 || p.canary != nil || p.paused
+}
+
+func (p rolloutPolicy) isCanary(cluster *apimgmtv3.Cluster) bool {
+	return p.canary != nil && p.canary.Matches(labels.Set(cluster.Labels))
+}
+
+// canStart returns whether the agent upgrade of the cluster can start given the other clusters, or why it can't.
+// Clusters in reserved are counted as being upgraded.
+func (p rolloutPolicy) canStart(cluster *apimgmtv3.Cluster, clusters []*apimgmtv3.Cluster, reserved map[string]bool) (bool, string) {
+	if p.paused {
+		return false, "the rollout of agent upgrades is paused"
+	}
+
+	upgrading := 0
+	canaryRemaining := false
+	for _, c := range clusters {
+		if c.Name == cluster.Name {
+			continue
+		}
+		state := agentRolloutState(c)
+		switch {
+		case state == rolloutStateFailed:
+			return false, fmt.Sprintf("the agent upgrade of cluster %s failed", c.Name)
+		case state == rolloutStateUpgrading || reserved[c.Name]:
+			upgrading++
+		}
+		if p.isCanary(c) && (state == rolloutStatePending || state == rolloutStateUpgrading || reserved[c.Name]) {
+			canaryRemaining = true
+		}
+	}
+
+	if canaryRemaining && !p.isCanary(cluster) {
+		return false, "waiting for the agent upgrade of the canary clusters"
+	}
+	if p.batchSize > 0 && upgrading >= p.batchSize {
+		return false, fmt.Sprintf("waiting for the agent upgrade of %d clusters", upgrading)
+	}
+	return true, ""
+}
+
+// agentUpgradeNeeded returns true if the agent of the cluster was deployed but its image or features changed,
+// ie. after a Rancher upgrade.
+func agentUpgradeNeeded(cluster *apimgmtv3.Cluster) bool {
+	if !apimgmtv3.ClusterConditionAgentDeployed.IsTrue(cluster) {
+		return false
+	}
+	return cluster.Status.AgentImage != systemtemplate.GetDesiredAgentImage(cluster) ||
+		cluster.Status.AuthImage != systemtemplate.GetDesiredAuthImage(cluster) ||
+		agentFeaturesChanged(systemtemplate.GetDesiredFeatures(cluster), cluster.Status.AgentFeatures)
+}
+
+func agentRolloutState(cluster *apimgmtv3.Cluster) rolloutState {
+	switch {
+	case cluster.Annotations[AgentRolloutFailedAnn] != "":
+		return rolloutStateFailed
+	case cluster.Annotations[AgentRolloutStartedAnn] != "":
+		return rolloutStateUpgrading
+	case agentUpgradeNeeded(cluster):
+		return rolloutStatePending
+	}
+	return rolloutStateUpToDate
+}
+
+// rolloutClusters returns the clusters whose agent is deployed by Rancher.
+func rolloutClusters(clusters []*apimgmtv3.Cluster) []*apimgmtv3.Cluster {
+	var result []*apimgmtv3.Cluster
+	for _, cluster := range clusters {
+		if cluster.Spec.Internal || cluster.DeletionTimestamp != nil || !apimgmtv3.ClusterConditionAgentDeployed.IsTrue(cluster) {
+			continue
+		}
+		result = append(result, cluster)
+	}
+	return result
+}
+
+// agentRollout stages the upgrades of the agents across the clusters according to the rollout policy.
+type agentRollout struct {
+	clusterLister v3.ClusterLister
+	configMaps    wcorev1.ConfigMapClient
+
+	lock sync.Mutex
+	// reserved are the clusters whose agent upgrade started, with the time it started.
+	reserved map[string]time.Time
+}
+
+// start returns true if the agent upgrade of the cluster can start now. When it can, the cluster is counted as being
+// upgraded until release is called.
+func (r *agentRollout) start(cluster *apimgmtv3.Cluster) (bool, error) {
+	policy, err := getRolloutPolicy()
+	if err != nil {
+		return false, err
+	}
+	if !policy.enabled() {
+		return true, nil
+	}
+
+	clusters, err := r.clusterLister.List("", labels.Everything())
+	if err != nil {
+		return false, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	reserved := map[string]bool{}
+	for name, startedAt := range r.reserved {
+		if time.Since(startedAt) > agentRolloutReservationTTL {
+			delete(r.reserved, name)
+			continue
+		}
+		reserved[name] = true
+	}
+
+	ok, reason := policy.canStart(cluster, rolloutClusters(clusters), reserved)
+	if !ok {
+		logrus.Debugf("clusterDeploy: agent upgrade of cluster [%s] postponed: %s", cluster.Name, reason)
+		return false, nil
+	}
+	r.reserved[cluster.Name] = time.Now()
+	return true, nil
+}
+
+func (r *agentRollout) release(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.reserved, name)
+}
+
+// checkAgentRollout checks whether the upgraded agent of a cluster is rolled out and connected, and marks the agent
+// upgrade as failed if it isn't in time.
+func (cd *clusterDeploy) checkAgentRollout(cluster *apimgmtv3.Cluster) error {
+	if cluster.Annotations[AgentRolloutStartedAnn] == "" {
+		return nil
+	}
+
+	ready, err := cd.clusterAgentReady(cluster)
+	if err != nil {
+		return err
+	}
+	if ready {
+		logrus.Infof("clusterDeploy: agent of cluster [%s] upgraded to [%s]", cluster.Name, cluster.Status.AgentImage)
+		delete(cluster.Annotations, AgentRolloutStartedAnn)
+		cd.rollout.release(cluster.Name)
+		return nil
+	}
+
+	if failed, err := cd.failTimedOutAgentRollout(cluster); err != nil || failed {
+		return err
+	}
+	if cluster.Annotations[AgentRolloutStartedAnn] != "" {
+		cd.clusters.Controller().EnqueueAfter("", cluster.Name, agentRolloutCheckInterval)
+	}
+	return nil
+}
+
+// failTimedOutAgentRollout marks the agent upgrade of the cluster as failed and returns true if it started longer than
+// the agent-rollout-failure-timeout setting ago, whether the agent YAML was applied or its apply keeps failing.
+func (cd *clusterDeploy) failTimedOutAgentRollout(cluster *apimgmtv3.Cluster) (bool, error) {
+	startedAt := cluster.Annotations[AgentRolloutStartedAnn]
+	started, err := time.Parse(time.RFC3339, startedAt)
+	if err != nil {
+		logrus.Warnf("clusterDeploy: invalid annotation %s=%s on cluster [%s], ignoring it", AgentRolloutStartedAnn, startedAt, cluster.Name)
+		delete(cluster.Annotations, AgentRolloutStartedAnn)
+		cd.rollout.release(cluster.Name)
+		return false, nil
+	}
+
+	timeout, err := time.ParseDuration(settings.AgentRolloutFailureTimeout.Get())
+	if err != nil {
+		return false, fmt.Errorf("invalid %s setting %q: %w", settings.AgentRolloutFailureTimeout.Name, settings.AgentRolloutFailureTimeout.Get(), err)
+	}
+	if time.Since(started) <= timeout {
+		return false, nil
+	}
+
+	message := fmt.Sprintf("agent [%s] was not rolled out and connected within %s", systemtemplate.GetDesiredAgentImage(cluster), timeout)
+	logrus.Errorf("clusterDeploy: agent upgrade of cluster [%s] failed, pausing the rollout of agent upgrades: %s", cluster.Name, message)
+	delete(cluster.Annotations, AgentRolloutStartedAnn)
+	cluster.Annotations[AgentRolloutFailedAnn] = message
+	cd.rollout.release(cluster.Name)
+	return true, nil
+}
+
+// clusterAgentReady returns true if the cluster agent is connected and its deployment is rolled out.
+func (cd *clusterDeploy) clusterAgentReady(cluster *apimgmtv3.Cluster) (bool, error) {
+	if !clusterconnected.Connected.IsTrue(cluster) {
+		return false, nil
+	}
+
+	uc, err := cd.clusterManager.UserContextNoControllers(cluster.Name)
+	if err != nil {
+		return false, err
+	}
+	d, err := uc.Apps.Deployments(namespace.System).Get("cattle-cluster-agent", metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.AvailableReplicas == replicas, nil
+}
+
+// agentRolloutStatus computes the status of the rollout of agent upgrades across the clusters.
+func agentRolloutStatus(policy rolloutPolicy, clusters []*apimgmtv3.Cluster) AgentRolloutStatus {
+	status := AgentRolloutStatus{
+		AgentImages: map[string][]string{},
+	}
+
+	canaryRemaining := false
+	for _, cluster := range clusters {
+		status.AgentImages[cluster.Status.AgentImage] = append(status.AgentImages[cluster.Status.AgentImage], cluster.Name)
+
+		state := agentRolloutState(cluster)
+		switch state {
+		case rolloutStatePending:
+			status.Pending = append(status.Pending, cluster.Name)
+		case rolloutStateUpgrading:
+			status.Upgrading = append(status.Upgrading, cluster.Name)
+		case rolloutStateFailed:
+			if status.Failed == nil {
+				status.Failed = map[string]string{}
+			}
+			status.Failed[cluster.Name] = cluster.Annotations[AgentRolloutFailedAnn]
+		}
+		if policy.isCanary(cluster) && (state == rolloutStatePending || state == rolloutStateUpgrading) {
+			canaryRemaining = true
+		}
+	}
+
+	for _, names := range status.AgentImages {
+		sort.Strings(names)
+	}
+	sort.Strings(status.Pending)
+	sort.Strings(status.Upgrading)
+
+	switch {
+	case policy.paused || len(status.Failed) > 0:
+		status.Phase = AgentRolloutPhasePaused
+	case canaryRemaining:
+		status.Phase = AgentRolloutPhaseCanary
+	case len(status.Pending) > 0 || len(status.Upgrading) > 0:
+		status.Phase = AgentRolloutPhaseInProgress
+	default:
+		status.Phase = AgentRolloutPhaseComplete
+	}
+
+	return status
+}
+
+// syncStatus periodically writes the status of the rollout of agent upgrades to the AgentRolloutStatusConfigMap.
+func (r *agentRollout) syncStatus(ctx context.Context) {
+	for range ticker.Context(ctx, agentRolloutRequeueInterval) {
+		if err := r.updateStatus(); err != nil {
+			logrus.Errorf("clusterDeploy: failed to update the status of the rollout of agent upgrades: %v", err)
+		}
+	}
+}
+
+func (r *agentRollout) updateStatus() error {
+	policy, err := getRolloutPolicy()
+	if err != nil {
+		return err
+	}
+	clusters, err := r.clusterLister.List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(agentRolloutStatus(policy, rolloutClusters(clusters)))
+	if err != nil {
+		return err
+	}
+
+	configMap, err := r.configMaps.Get(namespace.System, AgentRolloutStatusConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = r.configMaps.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      AgentRolloutStatusConfigMap,
+				Namespace: namespace.System,
+			},
+			Data: map[string]string{"status": string(data)},
+		})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if configMap.Data["status"] == string(data) {
+		return nil
+	}
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data["status"] = string(data)
+	_, err = r.configMaps.Update(configMap)
+	return err
+}
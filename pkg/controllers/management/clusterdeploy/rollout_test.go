@@ -0,0 +1,205 @@
+package clusterdeploy
+
+import (
+	"testing"
+	"time"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/systemtemplate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	oldAgentImage = "rancher/rancher-agent:v2.8.0"
+	newAgentImage = "rancher/rancher-agent:v2.9.0"
+)
+
+func TestRolloutPolicyCanStart(t *testing.T) {
+	settings.AgentImage.Set(newAgentImage)
+	defer settings.AgentImage.Set("")
+
+	canary := labels.SelectorFromSet(labels.Set{"canary": "true"})
+	tests := []struct {
+		name     string
+		policy   rolloutPolicy
+		cluster  *apimgmtv3.Cluster
+		clusters []*apimgmtv3.Cluster
+		reserved map[string]bool
+		expected bool
+	}{
+		{
+			name:     "paused",
+			policy:   rolloutPolicy{paused: true},
+			cluster:  testCluster("c-1", oldAgentImage, nil, nil),
+			expected: false,
+		},
+		{
+			name:    "failed cluster pauses the rollout",
+			policy:  rolloutPolicy{batchSize: 5},
+			cluster: testCluster("c-1", oldAgentImage, nil, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-2", newAgentImage, nil, map[string]string{AgentRolloutFailedAnn: "timeout"}),
+			},
+			expected: false,
+		},
+		{
+			name:    "batch full",
+			policy:  rolloutPolicy{batchSize: 2},
+			cluster: testCluster("c-1", oldAgentImage, nil, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-2", newAgentImage, nil, map[string]string{AgentRolloutStartedAnn: "2024-01-01T00:00:00Z"}),
+				testCluster("c-3", oldAgentImage, nil, nil),
+			},
+			reserved: map[string]bool{"c-3": true},
+			expected: false,
+		},
+		{
+			name:    "batch not full",
+			policy:  rolloutPolicy{batchSize: 2},
+			cluster: testCluster("c-1", oldAgentImage, nil, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-2", newAgentImage, nil, map[string]string{AgentRolloutStartedAnn: "2024-01-01T00:00:00Z"}),
+				testCluster("c-3", oldAgentImage, nil, nil),
+			},
+			expected: true,
+		},
+		{
+			name:    "waiting for canary clusters",
+			policy:  rolloutPolicy{canary: canary},
+			cluster: testCluster("c-1", oldAgentImage, nil, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-2", oldAgentImage, map[string]string{"canary": "true"}, nil),
+			},
+			expected: false,
+		},
+		{
+			name:    "canary cluster starts first",
+			policy:  rolloutPolicy{canary: canary, batchSize: 1},
+			cluster: testCluster("c-2", oldAgentImage, map[string]string{"canary": "true"}, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-1", oldAgentImage, nil, nil),
+			},
+			expected: true,
+		},
+		{
+			name:    "canary clusters upgraded",
+			policy:  rolloutPolicy{canary: canary},
+			cluster: testCluster("c-1", oldAgentImage, nil, nil),
+			clusters: []*apimgmtv3.Cluster{
+				testCluster("c-2", newAgentImage, map[string]string{"canary": "true"}, nil),
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := append([]*apimgmtv3.Cluster{tt.cluster}, tt.clusters...)
+			ok, reason := tt.policy.canStart(tt.cluster, clusters, tt.reserved)
+			assert.Equal(t, tt.expected, ok, reason)
+		})
+	}
+}
+
+func TestAgentRolloutStatus(t *testing.T) {
+	settings.AgentImage.Set(newAgentImage)
+	defer settings.AgentImage.Set("")
+
+	policy := rolloutPolicy{canary: labels.SelectorFromSet(labels.Set{"canary": "true"}), batchSize: 1}
+	clusters := []*apimgmtv3.Cluster{
+		testCluster("c-3", oldAgentImage, nil, nil),
+		testCluster("c-1", newAgentImage, map[string]string{"canary": "true"}, map[string]string{AgentRolloutStartedAnn: "2024-01-01T00:00:00Z"}),
+		testCluster("c-2", oldAgentImage, nil, nil),
+	}
+
+	status := agentRolloutStatus(policy, clusters)
+	assert.Equal(t, AgentRolloutPhaseCanary, status.Phase)
+	assert.Equal(t, map[string][]string{
+		oldAgentImage: {"c-2", "c-3"},
+		newAgentImage: {"c-1"},
+	}, status.AgentImages)
+	assert.Equal(t, []string{"c-2", "c-3"}, status.Pending)
+	assert.Equal(t, []string{"c-1"}, status.Upgrading)
+
+	delete(clusters[1].Annotations, AgentRolloutStartedAnn)
+	assert.Equal(t, AgentRolloutPhaseInProgress, agentRolloutStatus(policy, clusters).Phase)
+
+	clusters[1].Annotations[AgentRolloutFailedAnn] = "timeout"
+	status = agentRolloutStatus(policy, clusters)
+	assert.Equal(t, AgentRolloutPhasePaused, status.Phase)
+	assert.Equal(t, map[string]string{"c-1": "timeout"}, status.Failed)
+
+	for _, cluster := range clusters {
+		cluster.Status.AgentImage = newAgentImage
+		delete(cluster.Annotations, AgentRolloutFailedAnn)
+	}
+	assert.Equal(t, AgentRolloutPhaseComplete, agentRolloutStatus(policy, clusters).Phase)
+}
+
+func testCluster(name, agentImage string, labels, annotations map[string]string) *apimgmtv3.Cluster {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	cluster := &apimgmtv3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Status: apimgmtv3.ClusterStatus{
+			AgentImage: agentImage,
+		},
+	}
+	cluster.Status.AgentFeatures = systemtemplate.GetDesiredFeatures(cluster)
+	apimgmtv3.ClusterConditionAgentDeployed.True(cluster)
+	return cluster
+}
+
+func TestFailTimedOutAgentRollout(t *testing.T) {
+	settings.AgentImage.Set(newAgentImage)
+	defer settings.AgentImage.Set("")
+
+	tests := []struct {
+		name        string
+		startedAt   string
+		wantFailed  bool
+		wantStarted bool
+	}{
+		{
+			name:        "within timeout",
+			startedAt:   time.Now().UTC().Format(time.RFC3339),
+			wantStarted: true,
+		},
+		{
+			name:       "timed out",
+			startedAt:  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+			wantFailed: true,
+		},
+		{
+			name:      "invalid annotation",
+			startedAt: "yesterday",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd := &clusterDeploy{rollout: &agentRollout{reserved: map[string]time.Time{"c-1": time.Now()}}}
+			// the agent of the cluster wasn't upgraded, eg. its YAML keeps failing to apply
+			cluster := testCluster("c-1", oldAgentImage, nil, map[string]string{AgentRolloutStartedAnn: tt.startedAt})
+
+			failed, err := cd.failTimedOutAgentRollout(cluster)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFailed, failed)
+			assert.Equal(t, tt.wantStarted, cluster.Annotations[AgentRolloutStartedAnn] != "")
+			assert.Equal(t, tt.wantStarted, cd.rollout.reserved["c-1"] != time.Time{}, "reservation")
+			if tt.wantFailed {
+				assert.Contains(t, cluster.Annotations[AgentRolloutFailedAnn], newAgentImage)
+			} else {
+				assert.Empty(t, cluster.Annotations[AgentRolloutFailedAnn])
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@
 	"github.com/rancher/rancher/pkg/controllers/management/imported"
 	kd "github.com/rancher/rancher/pkg/controllers/management/kontainerdrivermetadata"
 	"github.com/rancher/rancher/pkg/controllers/management/secretmigrator"
+	"github.com/rancher/rancher/pkg/egressproxy"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/apps/v1"
 	corev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
@@ -68,8 +69,9 @@ type Provisioner struct {
 }
 
 func Register(ctx context.Context, management *config.ManagementContext) {
+	proxies := egressproxy.NewResolver(management.Core.Secrets("").Controller().Lister())
 	p := &Provisioner{
-		engineService:         service.NewEngineService(NewPersistentStore(management.Core.Namespaces(""), management.Core)),
+		engineService:         service.NewEngineService(NewPersistentStore(management.Core.Namespaces(""), management.Core)).WithEgressProxy(proxies),
 		Clusters:              management.Management.Clusters(""),
 		ConfigMaps:            management.Core.ConfigMaps(""),
 		ClusterController:     management.Management.Clusters("").Controller(),
@@ -15,10 +15,13 @@
 	v3 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/wrangler"
 	wranglerv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -33,6 +36,8 @@ type clusterRefreshController struct {
 	clusterClient       v3.ClusterClient
 	clusterCache        v3.ClusterCache
 	clusterEnqueueAfter func(name string, duration time.Duration)
+	recorder            record.EventRecorder
+	dynamicClient       dynamic.Interface
 }
 
 // for other cloud drivers, please edit HERE
@@ -42,13 +47,15 @@ type clusterConfig struct {
 	gkeConfig *gkev1.GKEClusterConfigSpec
 }
 
-func Register(ctx context.Context, wContext *wrangler.Context) {
+func Register(ctx context.Context, wContext *wrangler.Context, mgmtCtx *config.ManagementContext) {
 	c := clusterRefreshController{
 		secretsCache:        wContext.Core.Secret().Cache(),
 		secretClient:        wContext.Core.Secret(),
 		clusterClient:       wContext.Mgmt.Cluster(),
 		clusterCache:        wContext.Mgmt.Cluster().Cache(),
 		clusterEnqueueAfter: wContext.Mgmt.Cluster().EnqueueAfter,
+		recorder:            wContext.EventRecorder,
+		dynamicClient:       mgmtCtx.DynamicClient,
 	}
 
 	wContext.Mgmt.Cluster().OnChange(ctx, "cluster-refresher-controller", c.onClusterChange)
@@ -226,20 +233,29 @@ func (c *clusterRefreshController) refreshClusterUpstreamSpec(cluster *mgmtv3.Cl
 		return cluster, err
 	}
 
-	var updateClusterConfig bool
-	for key, value := range upstreamSpecMap {
-		if specMap[key] == nil {
-			continue
-		}
-		if reflect.DeepEqual(specMap[key], value) {
-			continue
-		}
-		updateClusterConfig = true
-		specMap[key] = value
+	drift := diffUpstreamSpec(specMap, upstreamSpecMap)
+	policy := getDriftPolicy(cluster)
+	cluster = c.setDriftCondition(cluster, drift, policy)
+	if len(drift) == 0 {
+		logrus.Debugf("cluster [%s] matches upstream, skipping spec sync", cluster.Name)
+		return c.updateCluster(cluster)
 	}
 
-	if updateClusterConfig {
+	switch policy {
+	case DriftPolicyReport:
+		logrus.Debugf("change detected for cluster [%s], reporting only", cluster.Name)
+	case DriftPolicyReconcile:
+		if err := c.requestReconcile(cluster, cloudDriver); err != nil {
+			return cluster, err
+		}
+	default:
 		logrus.Debugf("change detected for cluster [%s], updating spec", cluster.Name)
+		for key, value := range upstreamSpecMap {
+			if specMap[key] != nil {
+				specMap[key] = value
+			}
+		}
+		cluster = cluster.DeepCopy()
 		// for other cloud drivers, please edit HERE
 		switch cloudDriver {
 		case apimgmtv3.ClusterDriverAKS:
@@ -258,8 +274,6 @@ func (c *clusterRefreshController) refreshClusterUpstreamSpec(cluster *mgmtv3.Cl
 				return cluster, err
 			}
 		}
-	} else {
-		logrus.Debugf("cluster [%s] matches upstream, skipping spec sync", cluster.Name)
 	}
 
 	return c.updateCluster(cluster)
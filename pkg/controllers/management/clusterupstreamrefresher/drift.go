@@ -0,0 +1,259 @@
+package clusterupstreamrefresher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DriftPolicyAnnotation overrides the hosted-cluster-drift-policy setting for a cluster.
+	DriftPolicyAnnotation = "clusters.management.cattle.io/drift-policy"
+
+	// operatorActivePhase and operatorUpdatingPhase are the phases of the cluster config objects of the operators.
+	// In the updating phase, the operators reconcile the upstream cluster with the spec of the config.
+	operatorActivePhase   = "active"
+	operatorUpdatingPhase = "updating"
+
+	DriftPolicyAdopt     = "adopt"
+	DriftPolicyReport    = "report"
+	DriftPolicyReconcile = "reconcile"
+
+	driftEventReason = "UpstreamDrift"
+	// maxDriftMessageFields is the number of differing fields listed in the message of the NoUpstreamDrift condition.
+	maxDriftMessageFields = 10
+)
+
+// listKeys are the fields identifying the elements of the lists of the configs, ie. node groups and node pools, so
+// that they are compared by name rather than by position.
+var listKeys = []string{"nodegroupName", "name"}
+
+var operatorConfigResources = map[string]schema.GroupVersionResource{
+	apimgmtv3.ClusterDriverAKS: {Group: "aks.cattle.io", Version: "v1", Resource: "aksclusterconfigs"},
+	apimgmtv3.ClusterDriverEKS: {Group: "eks.cattle.io", Version: "v1", Resource: "eksclusterconfigs"},
+	apimgmtv3.ClusterDriverGKE: {Group: "gke.cattle.io", Version: "v1", Resource: "gkeclusterconfigs"},
+}
+
+// fieldDrift is a field of the config of a hosted cluster whose value in Rancher differs from the upstream value.
+type fieldDrift struct {
+	Path     string
+	Rancher  interface{}
+	Upstream interface{}
+}
+
+func (d fieldDrift) String() string {
+	return fmt.Sprintf("%s: rancher=%s upstream=%s", d.Path, toJSON(d.Rancher), toJSON(d.Upstream))
+}
+
+func toJSON(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "<unset>"
+	case map[string]interface{}:
+		// the fields of added or removed objects are not listed
+		return "{...}"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// getDriftPolicy returns the drift policy of the cluster, adopt when it is not valid.
+func getDriftPolicy(cluster *mgmtv3.Cluster) string {
+	policy := settings.HostedClusterDriftPolicy.Get()
+	if value, ok := cluster.Annotations[DriftPolicyAnnotation]; ok {
+		policy = value
+	}
+	switch policy {
+	case DriftPolicyReport, DriftPolicyReconcile:
+		return policy
+	case DriftPolicyAdopt:
+	default:
+		logrus.Warnf("invalid drift policy [%s] for cluster [%s], using %s", policy, cluster.Name, DriftPolicyAdopt)
+	}
+	return DriftPolicyAdopt
+}
+
+// diffUpstreamSpec returns the fields differing between the config of the cluster in Rancher and the upstream config,
+// sorted by path. Like when the upstream config is adopted, the top-level fields which are not set in Rancher are not
+// compared.
+func diffUpstreamSpec(specMap, upstreamSpecMap map[string]interface{}) []fieldDrift {
+	var drift []fieldDrift
+	for key, value := range upstreamSpecMap {
+		if specMap[key] == nil {
+			continue
+		}
+		drift = diffValues(key, specMap[key], value, drift)
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].Path < drift[j].Path
+	})
+	return drift
+}
+
+func diffValues(path string, rancher, upstream interface{}, drift []fieldDrift) []fieldDrift {
+	if reflect.DeepEqual(rancher, upstream) {
+		return drift
+	}
+
+	switch rancherValue := rancher.(type) {
+	case map[string]interface{}:
+		upstreamValue, ok := upstream.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for key := range rancherValue {
+			keys[key] = true
+		}
+		for key := range upstreamValue {
+			keys[key] = true
+		}
+		for key := range keys {
+			drift = diffValues(path+"."+key, rancherValue[key], upstreamValue[key], drift)
+		}
+		return drift
+	case []interface{}:
+		upstreamValue, ok := upstream.([]interface{})
+		if !ok {
+			break
+		}
+		rancherByKey, rancherOK := listByKey(rancherValue)
+		upstreamByKey, upstreamOK := listByKey(upstreamValue)
+		if !rancherOK || !upstreamOK {
+			break
+		}
+		keys := map[string]bool{}
+		for key := range rancherByKey {
+			keys[key] = true
+		}
+		for key := range upstreamByKey {
+			keys[key] = true
+		}
+		for key := range keys {
+			elementPath := fmt.Sprintf("%s[%s]", path, key)
+			rancherElement, inRancher := rancherByKey[key]
+			upstreamElement, inUpstream := upstreamByKey[key]
+			if !inRancher || !inUpstream {
+				// the element was added or removed, report it as a whole
+				drift = append(drift, fieldDrift{Path: elementPath, Rancher: nilIfMissing(rancherElement, inRancher), Upstream: nilIfMissing(upstreamElement, inUpstream)})
+				continue
+			}
+			drift = diffValues(elementPath, rancherElement, upstreamElement, drift)
+		}
+		return drift
+	}
+
+	return append(drift, fieldDrift{Path: path, Rancher: rancher, Upstream: upstream})
+}
+
+func nilIfMissing(value interface{}, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// listByKey returns the elements of the list by the value of their key field, if they all have one.
+func listByKey(list []interface{}) (map[string]interface{}, bool) {
+	byKey := make(map[string]interface{}, len(list))
+	for _, element := range list {
+		m, ok := element.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		var key string
+		for _, listKey := range listKeys {
+			if value, ok := m[listKey].(string); ok && value != "" {
+				key = value
+				break
+			}
+		}
+		if key == "" {
+			return nil, false
+		}
+		byKey[key] = element
+	}
+	return byKey, true
+}
+
+func driftMessage(drift []fieldDrift, policy string) string {
+	fields := make([]string, 0, maxDriftMessageFields)
+	for i, d := range drift {
+		if i == maxDriftMessageFields {
+			fields = append(fields, fmt.Sprintf("and %d more", len(drift)-maxDriftMessageFields))
+			break
+		}
+		fields = append(fields, d.String())
+	}
+	return fmt.Sprintf("%d fields differ from the upstream cluster (drift policy %s): %s", len(drift), policy, strings.Join(fields, "; "))
+}
+
+// setDriftCondition records the drift of the cluster on its NoUpstreamDrift condition, and creates an event when
+// the drift changes, unless it is adopted.
+func (c *clusterRefreshController) setDriftCondition(cluster *mgmtv3.Cluster, drift []fieldDrift, policy string) *mgmtv3.Cluster {
+	if len(drift) == 0 {
+		if apimgmtv3.ClusterConditionNoUpstreamDrift.IsTrue(cluster) {
+			return cluster
+		}
+		cluster = cluster.DeepCopy()
+		apimgmtv3.ClusterConditionNoUpstreamDrift.True(cluster)
+		apimgmtv3.ClusterConditionNoUpstreamDrift.Message(cluster, "")
+		return cluster
+	}
+
+	message := driftMessage(drift, policy)
+	if apimgmtv3.ClusterConditionNoUpstreamDrift.IsFalse(cluster) && apimgmtv3.ClusterConditionNoUpstreamDrift.GetMessage(cluster) == message {
+		return cluster
+	}
+	logrus.Infof("upstream drift detected for cluster [%s]: %s", cluster.Name, message)
+	if policy != DriftPolicyAdopt {
+		c.recorder.Event(cluster, corev1.EventTypeWarning, driftEventReason, message)
+	}
+
+	cluster = cluster.DeepCopy()
+	apimgmtv3.ClusterConditionNoUpstreamDrift.False(cluster)
+	apimgmtv3.ClusterConditionNoUpstreamDrift.Message(cluster, message)
+	return cluster
+}
+
+// requestReconcile moves the cluster config object of the operator of the cluster to the updating phase, as the
+// operator does itself when the spec changes, so that it reconciles the upstream cluster with the spec, which holds
+// the config of the cluster in Rancher. Configs which are not active are left alone, as their operator is already
+// reconciling them.
+func (c *clusterRefreshController) requestReconcile(cluster *mgmtv3.Cluster, cloudDriver string) error {
+	resource, ok := operatorConfigResources[cloudDriver]
+	if !ok {
+		return nil
+	}
+	client := c.dynamicClient.Resource(resource).Namespace(namespace.GlobalNamespace)
+	config, err := client.Get(context.TODO(), cluster.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if phase, _, _ := unstructured.NestedString(config.Object, "status", "phase"); phase != operatorActivePhase {
+		logrus.Debugf("config of drifted cluster [%s] is in phase [%s], not requesting a reconcile", cluster.Name, phase)
+		return nil
+	}
+	if err := unstructured.SetNestedField(config.Object, operatorUpdatingPhase, "status", "phase"); err != nil {
+		return err
+	}
+	logrus.Infof("reconciling drifted cluster [%s] with its config in Rancher", cluster.Name)
+	_, err = client.UpdateStatus(context.TODO(), config, metav1.UpdateOptions{})
+	return err
+}
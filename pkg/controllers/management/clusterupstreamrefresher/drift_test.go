@@ -0,0 +1,216 @@
+package clusterupstreamrefresher
+
+import (
+	"context"
+	"testing"
+
+	eksv1 "github.com/rancher/eks-operator/pkg/apis/eks.cattle.io/v1"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtv3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func toMap(t *testing.T, spec *eksv1.EKSClusterConfigSpec) map[string]interface{} {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	require.NoError(t, err)
+	return m
+}
+
+func TestDiffUpstreamSpec(t *testing.T) {
+	version := func(v string) *string { return &v }
+	size := func(s int32) *int32 { return &s }
+
+	spec := &eksv1.EKSClusterConfigSpec{
+		DisplayName:       "eks",
+		KubernetesVersion: version("1.27"),
+		Tags:              map[string]string{"team": "a"},
+		NodeGroups: []eksv1.NodeGroup{
+			{NodegroupName: version("ng1"), DesiredSize: size(2)},
+			{NodegroupName: version("ng2"), DesiredSize: size(1)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		upstream *eksv1.EKSClusterConfigSpec
+		expected []string
+	}{
+		{
+			name:     "no drift",
+			upstream: spec.DeepCopy(),
+		},
+		{
+			name: "field drift",
+			upstream: func() *eksv1.EKSClusterConfigSpec {
+				upstream := spec.DeepCopy()
+				upstream.KubernetesVersion = version("1.28")
+				upstream.Tags["owner"] = "b"
+				upstream.NodeGroups[1].DesiredSize = size(3)
+				return upstream
+			}(),
+			expected: []string{
+				`kubernetesVersion: rancher="1.27" upstream="1.28"`,
+				`nodeGroups[ng2].desiredSize: rancher=1 upstream=3`,
+				`tags.owner: rancher=<unset> upstream="b"`,
+			},
+		},
+		{
+			name: "node group removed and added upstream",
+			upstream: func() *eksv1.EKSClusterConfigSpec {
+				upstream := spec.DeepCopy()
+				upstream.NodeGroups[0] = eksv1.NodeGroup{NodegroupName: version("ng3")}
+				return upstream
+			}(),
+			expected: []string{
+				`nodeGroups[ng1]: rancher={...} upstream=<unset>`,
+				`nodeGroups[ng3]: rancher=<unset> upstream={...}`,
+			},
+		},
+		{
+			name: "fields not set in rancher are not compared",
+			upstream: func() *eksv1.EKSClusterConfigSpec {
+				upstream := spec.DeepCopy()
+				upstream.LoggingTypes = []string{"api"}
+				return upstream
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual []string
+			for _, d := range diffUpstreamSpec(toMap(t, spec), toMap(t, tt.upstream)) {
+				actual = append(actual, d.String())
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestGetDriftPolicy(t *testing.T) {
+	original := settings.HostedClusterDriftPolicy.Get()
+	t.Cleanup(func() { _ = settings.HostedClusterDriftPolicy.Set(original) })
+	require.NoError(t, settings.HostedClusterDriftPolicy.Set(DriftPolicyReport))
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name:     "setting",
+			expected: DriftPolicyReport,
+		},
+		{
+			name:        "annotation",
+			annotations: map[string]string{DriftPolicyAnnotation: DriftPolicyReconcile},
+			expected:    DriftPolicyReconcile,
+		},
+		{
+			name:        "invalid annotation",
+			annotations: map[string]string{DriftPolicyAnnotation: "ignore"},
+			expected:    DriftPolicyAdopt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &mgmtv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1", Annotations: tt.annotations}}
+			assert.Equal(t, tt.expected, getDriftPolicy(cluster))
+		})
+	}
+}
+
+func TestDriftMessage(t *testing.T) {
+	drift := make([]fieldDrift, 12)
+	for i := range drift {
+		drift[i] = fieldDrift{Path: "tags.t", Rancher: "a"}
+	}
+	message := driftMessage(drift, DriftPolicyReport)
+	assert.Contains(t, message, "12 fields differ from the upstream cluster (drift policy report): ")
+	assert.Contains(t, message, `tags.t: rancher="a" upstream=<unset>; and 2 more`)
+}
+
+func newOperatorConfig(phase string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "eks.cattle.io/v1",
+		"kind":       "EKSClusterConfig",
+		"metadata":   map[string]interface{}{"name": "c-1", "namespace": namespace.GlobalNamespace},
+		"spec":       map[string]interface{}{"kubernetesVersion": "1.29"},
+		"status":     map[string]interface{}{"phase": phase},
+	}}
+}
+
+func TestRequestReconcile(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     string
+		wantPhase string
+	}{
+		{name: "active", phase: operatorActivePhase, wantPhase: operatorUpdatingPhase},
+		{name: "already updating", phase: operatorUpdatingPhase, wantPhase: operatorUpdatingPhase},
+		{name: "creating", phase: "creating", wantPhase: "creating"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := operatorConfigResources[apimgmtv3.ClusterDriverEKS]
+			dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{resource: "EKSClusterConfigList"}, newOperatorConfig(tt.phase))
+			c := &clusterRefreshController{dynamicClient: dynamicClient}
+
+			err := c.requestReconcile(&mgmtv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1"}}, apimgmtv3.ClusterDriverEKS)
+			require.NoError(t, err)
+
+			config, err := dynamicClient.Resource(resource).Namespace(namespace.GlobalNamespace).Get(context.Background(), "c-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			phase, _, _ := unstructured.NestedString(config.Object, "status", "phase")
+			assert.Equal(t, tt.wantPhase, phase)
+			assert.Empty(t, config.GetAnnotations())
+		})
+	}
+}
+
+func TestSetDriftConditionEvent(t *testing.T) {
+	drift := []fieldDrift{{Path: "kubernetesVersion", Rancher: "1.29", Upstream: "1.30"}}
+	tests := []struct {
+		policy    string
+		wantEvent bool
+	}{
+		{policy: DriftPolicyAdopt},
+		{policy: DriftPolicyReport, wantEvent: true},
+		{policy: DriftPolicyReconcile, wantEvent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(2)
+			c := &clusterRefreshController{recorder: recorder}
+
+			cluster := c.setDriftCondition(&mgmtv3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1"}}, drift, tt.policy)
+			assert.True(t, apimgmtv3.ClusterConditionNoUpstreamDrift.IsFalse(cluster))
+			assert.Equal(t, driftMessage(drift, tt.policy), apimgmtv3.ClusterConditionNoUpstreamDrift.GetMessage(cluster))
+
+			// an unchanged drift is not reported again
+			c.setDriftCondition(cluster, drift, tt.policy)
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			if tt.wantEvent {
+				assert.Equal(t, []string{corev1.EventTypeWarning + " " + driftEventReason + " " + driftMessage(drift, tt.policy)}, events)
+			} else {
+				assert.Empty(t, events)
+			}
+		})
+	}
+}
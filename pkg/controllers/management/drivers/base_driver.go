@@ -16,18 +16,30 @@
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+// imageURLPrefix is the prefix of the URL of drivers distributed as a container image.
+const imageURLPrefix = "oci://"
+
 type BaseDriver struct {
 	Builtin      bool
 	URL          string
 	DriverHash   string
 	DriverName   string
 	BinaryPrefix string
+
+	// imageDigest is the digest the image of a driver distributed as a container image resolves to.
+	imageDigest string
 }
 
 func (d *BaseDriver) Name() string {
@@ -47,6 +59,11 @@ func (d *BaseDriver) FriendlyName() string {
 }
 
 func (d *BaseDriver) Remove() error {
+	if err := d.resolveImage(); err != nil {
+		logrus.Warnf("Not removing driver %s: %v", d.URL, err)
+		return nil
+	}
+
 	cacheFilePrefix := d.cacheFile()
 	content, err := os.ReadFile(cacheFilePrefix)
 	if os.IsNotExist(err) {
@@ -74,7 +91,7 @@ func (d *BaseDriver) Stage(forceUpdate bool) error {
 }
 
 func (d *BaseDriver) setError(err error) error {
-	errFile := d.cacheFile() + ".error"
+	errFile := d.errorFile()
 
 	if err != nil {
 		_ = os.MkdirAll(path.Dir(errFile), 0700)
@@ -84,7 +101,7 @@ func (d *BaseDriver) setError(err error) error {
 }
 
 func (d *BaseDriver) getError() error {
-	errFile := d.cacheFile() + ".error"
+	errFile := d.errorFile()
 
 	if content, err := os.ReadFile(errFile); err == nil {
 		logrus.Errorf("Returning previous error: %s", content)
@@ -96,7 +113,7 @@ func (d *BaseDriver) getError() error {
 }
 
 func (d *BaseDriver) ClearError() {
-	errFile := d.cacheFile() + ".error"
+	errFile := d.errorFile()
 	_ = os.Remove(errFile)
 }
 
@@ -105,6 +122,10 @@ func (d *BaseDriver) stage(forceUpdate bool) error {
 		return nil
 	}
 
+	if err := d.resolveImage(); err != nil {
+		return err
+	}
+
 	cacheFilePrefix := d.cacheFile()
 
 	driverName, err := isInstalled(cacheFilePrefix)
@@ -120,22 +141,28 @@ func (d *BaseDriver) stage(forceUpdate bool) error {
 	defer os.Remove(tempFile.Name())
 	defer tempFile.Close()
 
-	hasher, err := getHasher(d.DriverHash)
-	if err != nil {
-		return err
-	}
+	if isImage(d.URL) {
+		if err := d.pullImage(tempFile); err != nil {
+			return err
+		}
+	} else {
+		hasher, err := getHasher(d.DriverHash)
+		if err != nil {
+			return err
+		}
 
-	downloadDest := io.Writer(tempFile)
-	if hasher != nil {
-		downloadDest = io.MultiWriter(tempFile, hasher)
-	}
+		downloadDest := io.Writer(tempFile)
+		if hasher != nil {
+			downloadDest = io.MultiWriter(tempFile, hasher)
+		}
 
-	if err := d.download(downloadDest); err != nil {
-		return err
-	}
+		if err := d.download(downloadDest); err != nil {
+			return err
+		}
 
-	if got, ok := compare(hasher, d.DriverHash); !ok {
-		return fmt.Errorf("hash does not match, got %s, expected %s", got, d.DriverHash)
+		if got, ok := compare(hasher, d.DriverHash); !ok {
+			return fmt.Errorf("hash does not match, got %s, expected %s", got, d.DriverHash)
+		}
 	}
 
 	if err := tempFile.Close(); err != nil {
@@ -161,6 +188,10 @@ func (d *BaseDriver) Exists() bool {
 	if d.Builtin {
 		return true
 	}
+	if err := d.resolveImage(); err != nil {
+		logrus.Warnf("Failed to check driver %s: %v", d.URL, err)
+		return false
+	}
 	_, err := os.Stat(d.binName())
 	if err == nil {
 		// The executable is there but does it come from the right version?
@@ -326,9 +357,83 @@ func (d *BaseDriver) download(dest io.Writer) error {
 	return err
 }
 
+// isImage returns true if the driver is distributed as a container image, ie. oci://registry/repository:tag.
+func isImage(driverURL string) bool {
+	return strings.HasPrefix(driverURL, imageURLPrefix)
+}
+
+// resolveImage resolves the tag of the image of the driver to the digest it currently points to, so that the
+// cache is keyed by the content of the image and a tag that is pushed again is pulled again. References pinned
+// to a digest are not looked up.
+func (d *BaseDriver) resolveImage() error {
+	if !isImage(d.URL) || d.imageDigest != "" {
+		return nil
+	}
+
+	ref, err := name.ParseReference(strings.TrimPrefix(d.URL, imageURLPrefix))
+	if err != nil {
+		return fmt.Errorf("invalid image %s: %w", d.URL, err)
+	}
+
+	if digest, ok := ref.(name.Digest); ok {
+		d.imageDigest = digest.DigestStr()
+		return nil
+	}
+
+	desc, err := remote.Head(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return fmt.Errorf("failed to resolve image %s: %w", d.URL, err)
+	}
+	d.imageDigest = desc.Digest.String()
+	return nil
+}
+
+// pullImage pulls the image of the driver for the platform Rancher is running on and writes its flattened
+// filesystem as a tar archive to dest. The driver binary is then found in the archive like in any other archive.
+// The image is pulled by the digest resolved by resolveImage. When set, the hash of the driver is the sha256
+// digest of the image.
+func (d *BaseDriver) pullImage(dest io.Writer) error {
+	logrus.Infof("Pull %s", d.URL)
+	ref, err := name.ParseReference(strings.TrimPrefix(d.URL, imageURLPrefix))
+	if err != nil {
+		return fmt.Errorf("invalid image %s: %w", d.URL, err)
+	}
+
+	img, err := remote.Image(ref.Context().Digest(d.imageDigest),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithPlatform(v1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}))
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", d.URL, err)
+	}
+
+	if d.DriverHash != "" {
+		digest, err := img.Digest()
+		if err != nil {
+			return err
+		}
+		expected := strings.TrimPrefix(strings.TrimSpace(strings.ToLower(d.DriverHash)), "sha256:")
+		if digest.Hex != expected {
+			return fmt.Errorf("hash does not match, got %s, expected %s", digest.Hex, d.DriverHash)
+		}
+	}
+
+	fs := mutate.Extract(img)
+	defer fs.Close()
+
+	_, err = io.Copy(dest, fs)
+	return err
+}
+
 func (d *BaseDriver) cacheFile() string {
-	key := sha256Bytes([]byte(d.URL + d.DriverHash))
+	return cachePath(sha256Bytes([]byte(d.URL + d.imageDigest + d.DriverHash)))
+}
+
+// errorFile is keyed by the URL and hash of the driver only, so that an image that can't be resolved reports its error too.
+func (d *BaseDriver) errorFile() string {
+	return cachePath(sha256Bytes([]byte(d.URL+d.DriverHash))) + ".error"
+}
 
+func cachePath(key string) string {
 	base := os.Getenv("CATTLE_HOME")
 	if base == "" {
 		base = "./management-state"
@@ -21,7 +21,7 @@ func RegisterWrangler(ctx context.Context, wranglerContext *wrangler.Context, ma
 	aks.Register(ctx, wranglerContext, management)
 	eks.Register(ctx, wranglerContext, management)
 	gke.Register(ctx, wranglerContext, management)
-	clusterupstreamrefresher.Register(ctx, wranglerContext)
+	clusterupstreamrefresher.Register(ctx, wranglerContext, management)
 
 	feature.Register(ctx, wranglerContext)
 
@@ -0,0 +1,441 @@
+package healthsyncer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rancher/rancher/pkg/features"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	APIServerComponent    = "apiserver"
+	EtcdComponent         = "etcd"
+	NodesComponent        = "nodes"
+	DNSComponent          = "dns"
+	CertificatesComponent = "certificates"
+
+	// HTTPChecksConfigMap is the name of the ConfigMap, in the cattle-system namespace of a cluster, which defines
+	// the HTTP checks of the cluster under its HTTPChecksKey.
+	HTTPChecksConfigMap = "cattle-cluster-health-checks"
+	HTTPChecksKey       = "checks"
+
+	checkTimeout            = 10 * time.Second
+	defaultHTTPCheckTimeout = 5 * time.Second
+	inClusterDNSName        = "kubernetes.default.svc"
+	kubeDNSSelector         = "k8s-app=kube-dns"
+	readyzCheckFailedPrefix = "[-]"
+	readyzCheckPassedPrefix = "[+]"
+)
+
+// Result is the health of a component of a cluster, as reported by a Check.
+type Result struct {
+	Component string
+	Healthy   bool
+	Message   string
+}
+
+// Check checks the health of components of a cluster.
+type Check interface {
+	Run(ctx context.Context) []Result
+}
+
+// CheckFactory creates a check for the cluster of the user context.
+type CheckFactory func(workload *config.UserContext) (Check, error)
+
+var checkFactories = []CheckFactory{
+	newReadyzCheck,
+	newNodesCheck,
+	newDNSCheck,
+	newCertificatesCheck,
+	newHTTPChecks,
+}
+
+// AddCheck adds a check run by the health syncers of the clusters registered afterwards.
+func AddCheck(factory CheckFactory) {
+	checkFactories = append(checkFactories, factory)
+}
+
+// runChecks runs the checks concurrently and returns the status of the components they checked, sorted by name. When
+// several checks report the same component, only the result of the first check is kept.
+func runChecks(ctx context.Context, checks []Check) []Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	checkResults := make([][]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkResults[i] = check.Run(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	var results []Result
+	seen := map[string]bool{}
+	for _, r := range checkResults {
+		for _, result := range r {
+			if seen[result.Component] {
+				logrus.Warnf("[healthSyncer] ignoring the result of another check of component %s", result.Component)
+				continue
+			}
+			seen[result.Component] = true
+			results = append(results, result)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Component < results[j].Component
+	})
+	return results
+}
+
+// readyzCheck reports the health of the API server and etcd from the verbose output of the /readyz endpoint.
+type readyzCheck struct {
+	client rest.Interface
+}
+
+func newReadyzCheck(workload *config.UserContext) (Check, error) {
+	return &readyzCheck{client: workload.K8sClient.Discovery().RESTClient()}, nil
+}
+
+func (c *readyzCheck) Run(ctx context.Context) []Result {
+	// the body holds the state of every subcheck even when /readyz fails
+	body, err := c.client.Get().AbsPath("/readyz").Param("verbose", "true").DoRaw(ctx)
+	results := parseReadyz(string(body))
+	if len(results) == 0 && err != nil {
+		return []Result{{Component: APIServerComponent, Message: err.Error()}}
+	}
+	return results
+}
+
+// parseReadyz returns the health of the API server and etcd from the lines of the subchecks of /readyz?verbose,
+// ie. "[+]ping ok" or "[-]etcd failed: reason withheld".
+func parseReadyz(body string) []Result {
+	failed := map[string][]string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		var ok bool
+		switch {
+		case strings.HasPrefix(line, readyzCheckPassedPrefix):
+			ok = true
+		case strings.HasPrefix(line, readyzCheckFailedPrefix):
+		default:
+			continue
+		}
+
+		name, _, _ := strings.Cut(line[len(readyzCheckFailedPrefix):], " ")
+		component := APIServerComponent
+		if strings.HasPrefix(name, EtcdComponent) {
+			component = EtcdComponent
+		}
+		seen[component] = true
+		if !ok {
+			failed[component] = append(failed[component], name)
+		}
+	}
+
+	var results []Result
+	for _, component := range []string{APIServerComponent, EtcdComponent} {
+		if !seen[component] {
+			continue
+		}
+		result := Result{Component: component, Healthy: len(failed[component]) == 0}
+		if !result.Healthy {
+			result.Message = "failed checks: " + strings.Join(failed[component], ", ")
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// nodesCheck reports the nodes unhealthy when the ratio of ready nodes is under cluster-health-min-ready-nodes-ratio.
+type nodesCheck struct {
+	k8s kubernetes.Interface
+}
+
+func newNodesCheck(workload *config.UserContext) (Check, error) {
+	return &nodesCheck{k8s: workload.K8sClient}, nil
+}
+
+func (c *nodesCheck) Run(ctx context.Context) []Result {
+	nodes, err := c.k8s.CoreV1().Nodes().List(ctx, metav1.ListOptions{ResourceVersion: "0"})
+	if err != nil {
+		return []Result{{Component: NodesComponent, Message: fmt.Sprintf("failed to list nodes: %v", err)}}
+	}
+
+	var ready int
+	for _, node := range nodes.Items {
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == v1.NodeReady && cond.Status == v1.ConditionTrue {
+				ready++
+				break
+			}
+		}
+	}
+
+	minRatio, err := strconv.ParseFloat(settings.ClusterHealthMinReadyNodesRatio.Get(), 64)
+	if err != nil {
+		minRatio = 1
+	}
+	total := len(nodes.Items)
+	return []Result{{
+		Component: NodesComponent,
+		Healthy:   total > 0 && float64(ready)/float64(total) >= minRatio,
+		Message:   fmt.Sprintf("%d/%d nodes ready", ready, total),
+	}}
+}
+
+// dnsCheck resolves the name of the kubernetes service when running in the cluster, ie. in the cluster agent.
+// Otherwise, it checks that the DNS service of the cluster has ready endpoints.
+type dnsCheck struct {
+	k8s       kubernetes.Interface
+	inCluster bool
+	lookup    func(ctx context.Context, host string) ([]string, error)
+}
+
+func newDNSCheck(workload *config.UserContext) (Check, error) {
+	return &dnsCheck{
+		k8s:       workload.K8sClient,
+		inCluster: features.MCMAgent.Enabled(),
+		lookup:    net.DefaultResolver.LookupHost,
+	}, nil
+}
+
+func (c *dnsCheck) Run(ctx context.Context) []Result {
+	if c.inCluster {
+		if _, err := c.lookup(ctx, inClusterDNSName); err != nil {
+			return []Result{{Component: DNSComponent, Message: fmt.Sprintf("failed to resolve %s: %v", inClusterDNSName, err)}}
+		}
+		return []Result{{Component: DNSComponent, Healthy: true}}
+	}
+
+	services, err := c.k8s.CoreV1().Services(metav1.NamespaceSystem).List(ctx, metav1.ListOptions{LabelSelector: kubeDNSSelector})
+	if err != nil {
+		return []Result{{Component: DNSComponent, Message: fmt.Sprintf("failed to list DNS services: %v", err)}}
+	}
+	if len(services.Items) == 0 {
+		// the cluster has no DNS service this check knows of
+		return nil
+	}
+
+	for _, service := range services.Items {
+		endpoints, err := c.k8s.CoreV1().Endpoints(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		for _, subset := range endpoints.Subsets {
+			if len(subset.Addresses) > 0 {
+				return []Result{{Component: DNSComponent, Healthy: true}}
+			}
+		}
+	}
+	return []Result{{Component: DNSComponent, Message: "no ready DNS server endpoints"}}
+}
+
+// certificatesCheck reports the certificates unhealthy when the serving certificate of the API server expires within
+// cluster-health-cert-expiry-threshold.
+type certificatesCheck struct {
+	client *http.Client
+	host   string
+	now    func() time.Time
+}
+
+func newCertificatesCheck(workload *config.UserContext) (Check, error) {
+	client, err := rest.HTTPClientFor(&workload.RESTConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &certificatesCheck{
+		client: client,
+		host:   strings.TrimSuffix(workload.RESTConfig.Host, "/"),
+		now:    time.Now,
+	}, nil
+}
+
+func (c *certificatesCheck) Run(ctx context.Context) []Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/livez", nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// the API server not being reachable is reported by the readyz check
+		logrus.Debugf("[healthSyncer] failed to get the serving certificate of %s: %v", c.host, err)
+		return nil
+	}
+	resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+
+	threshold, err := time.ParseDuration(settings.ClusterHealthCertExpiryThreshold.Get())
+	if err != nil {
+		threshold = 0
+	}
+	notAfter := resp.TLS.PeerCertificates[0].NotAfter
+	return []Result{{
+		Component: CertificatesComponent,
+		Healthy:   notAfter.Sub(c.now()) > threshold,
+		Message:   fmt.Sprintf("API server serving certificate expires at %s", notAfter.UTC().Format(time.RFC3339)),
+	}}
+}
+
+// HTTPCheckSpec is a user-defined HTTP check of a component of a cluster, requested through the service proxy of the
+// Kubernetes API of the cluster.
+type HTTPCheckSpec struct {
+	// Name is the name of the component reported by the check, a DNS label which isn't the name of a component
+	// checked by Rancher.
+	Name string `json:"name" yaml:"name"`
+	// Namespace and Service are the namespace and name of the service of the component.
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Service   string `json:"service" yaml:"service"`
+	// Port is the name or number of the port of the service, its only port by default.
+	Port string `json:"port,omitempty" yaml:"port,omitempty"`
+	// Scheme is "http" or "https", "http" by default.
+	Scheme string `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	// Path is the path, and query, requested with a GET, ie. "/healthz".
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// ExpectedStatus is the status code of the response of a healthy component, 200 by default.
+	ExpectedStatus int `json:"expectedStatus,omitempty" yaml:"expectedStatus,omitempty"`
+	// TimeoutSeconds is how long to wait for the response, 5 seconds by default.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+}
+
+// builtinComponents are the components checked by Rancher, which the HTTP checks can't report.
+var builtinComponents = map[string]bool{
+	APIServerComponent:    true,
+	EtcdComponent:         true,
+	NodesComponent:        true,
+	DNSComponent:          true,
+	CertificatesComponent: true,
+}
+
+// validate returns why the check is invalid, or an empty string.
+func (spec HTTPCheckSpec) validate() string {
+	if errs := validation.IsDNS1123Label(spec.Name); len(errs) > 0 {
+		return fmt.Sprintf("invalid name %q: %s", spec.Name, strings.Join(errs, ", "))
+	}
+	if builtinComponents[spec.Name] {
+		return fmt.Sprintf("name %q is the name of a component checked by Rancher", spec.Name)
+	}
+	if errs := validation.IsDNS1123Label(spec.Namespace); len(errs) > 0 {
+		return fmt.Sprintf("invalid namespace %q: %s", spec.Namespace, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsDNS1035Label(spec.Service); len(errs) > 0 {
+		return fmt.Sprintf("invalid service %q: %s", spec.Service, strings.Join(errs, ", "))
+	}
+	if strings.ContainsAny(spec.Port, ":/") {
+		return fmt.Sprintf("invalid port %q", spec.Port)
+	}
+	if spec.Scheme != "" && spec.Scheme != "http" && spec.Scheme != "https" {
+		return fmt.Sprintf("invalid scheme %q", spec.Scheme)
+	}
+	if spec.Path != "" {
+		u, err := url.Parse(spec.Path)
+		if err != nil || !strings.HasPrefix(spec.Path, "/") || strings.Contains(spec.Path, "..") || strings.Contains(u.Path, "..") {
+			return fmt.Sprintf("invalid path %q", spec.Path)
+		}
+	}
+	return ""
+}
+
+// proxyURI returns the URI of the check in the service proxy of the Kubernetes API.
+func (spec HTTPCheckSpec) proxyURI() string {
+	service := spec.Service
+	if spec.Scheme != "" {
+		service = spec.Scheme + ":" + service
+	}
+	if spec.Port != "" {
+		service += ":" + spec.Port
+	}
+	return fmt.Sprintf("/api/v1/namespaces/%s/services/%s/proxy%s", spec.Namespace, service, spec.Path)
+}
+
+// httpChecks runs the HTTP checks defined in the cattle-cluster-health-checks ConfigMap of the cluster.
+type httpChecks struct {
+	k8s kubernetes.Interface
+	api rest.Interface
+}
+
+func newHTTPChecks(workload *config.UserContext) (Check, error) {
+	return &httpChecks{
+		k8s: workload.K8sClient,
+		api: workload.K8sClient.Discovery().RESTClient(),
+	}, nil
+}
+
+func (c *httpChecks) Run(ctx context.Context) []Result {
+	cm, err := c.k8s.CoreV1().ConfigMaps(namespace.System).Get(ctx, HTTPChecksConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		logrus.Debugf("[healthSyncer] failed to get the HTTP checks: %v", err)
+		return nil
+	}
+
+	var specs []HTTPCheckSpec
+	if err := yaml.Unmarshal([]byte(cm.Data[HTTPChecksKey]), &specs); err != nil {
+		logrus.Warnf("[healthSyncer] invalid HTTP checks in %s/%s: %v", namespace.System, HTTPChecksConfigMap, err)
+		return nil
+	}
+
+	var results []Result
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		if reason := spec.validate(); reason != "" {
+			logrus.Warnf("[healthSyncer] ignoring HTTP check in %s/%s: %s", namespace.System, HTTPChecksConfigMap, reason)
+			continue
+		}
+		if seen[spec.Name] {
+			logrus.Warnf("[healthSyncer] ignoring HTTP check in %s/%s: duplicate name %q", namespace.System, HTTPChecksConfigMap, spec.Name)
+			continue
+		}
+		seen[spec.Name] = true
+		results = append(results, c.run(ctx, spec))
+	}
+	return results
+}
+
+func (c *httpChecks) run(ctx context.Context, spec HTTPCheckSpec) Result {
+	expectedStatus := spec.ExpectedStatus
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
+	timeout := defaultHTTPCheckTimeout
+	if spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(spec.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	uri := spec.proxyURI()
+	var status int
+	if err := c.api.Get().RequestURI(uri).Do(ctx).StatusCode(&status).Error(); err != nil && status == 0 {
+		return Result{Component: spec.Name, Message: err.Error()}
+	}
+	if status != expectedStatus {
+		return Result{Component: spec.Name, Message: fmt.Sprintf("GET %s returned %d, expected %d", uri, status, expectedStatus)}
+	}
+	return Result{Component: spec.Name, Healthy: true, Message: fmt.Sprintf("GET %s returned %d", uri, status)}
+}
@@ -0,0 +1,300 @@
+package healthsyncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestParseReadyz(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []Result
+	}{
+		{
+			name: "healthy",
+			body: "[+]ping ok\n[+]log ok\n[+]etcd ok\n[+]etcd-readiness ok\nreadyz check passed\n",
+			expected: []Result{
+				{Component: APIServerComponent, Healthy: true},
+				{Component: EtcdComponent, Healthy: true},
+			},
+		},
+		{
+			name: "failed subchecks",
+			body: "[+]ping ok\n[-]informer-sync failed: reason withheld\n[-]poststarthook/rbac/bootstrap-roles failed: reason withheld\n[-]etcd-readiness failed: reason withheld\nreadyz check failed\n",
+			expected: []Result{
+				{Component: APIServerComponent, Message: "failed checks: informer-sync, poststarthook/rbac/bootstrap-roles"},
+				{Component: EtcdComponent, Message: "failed checks: etcd-readiness"},
+			},
+		},
+		{
+			name: "no subchecks",
+			body: "404 page not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseReadyz(tt.body))
+		})
+	}
+}
+
+func newRESTClient(t *testing.T, host string) rest.Interface {
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: host})
+	require.NoError(t, err)
+	return client.Discovery().RESTClient()
+}
+
+func TestReadyzCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/readyz", req.URL.Path)
+		assert.Equal(t, "true", req.URL.Query().Get("verbose"))
+		rw.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(rw, "[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed\n")
+	}))
+	defer srv.Close()
+
+	results := (&readyzCheck{client: newRESTClient(t, srv.URL)}).Run(context.Background())
+	assert.Equal(t, []Result{
+		{Component: APIServerComponent, Healthy: true},
+		{Component: EtcdComponent, Message: "failed checks: etcd"},
+	}, results)
+}
+
+func newNode(name string, ready bool) *v1.Node {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+			{Type: v1.NodeReady, Status: status},
+		}},
+	}
+}
+
+func TestNodesCheck(t *testing.T) {
+	original := settings.ClusterHealthMinReadyNodesRatio.Get()
+	t.Cleanup(func() { _ = settings.ClusterHealthMinReadyNodesRatio.Set(original) })
+
+	k8s := fake.NewSimpleClientset(newNode("n1", true), newNode("n2", true), newNode("n3", false), newNode("n4", true))
+	check := &nodesCheck{k8s: k8s}
+
+	require.NoError(t, settings.ClusterHealthMinReadyNodesRatio.Set("1"))
+	assert.Equal(t, []Result{{Component: NodesComponent, Message: "3/4 nodes ready"}}, check.Run(context.Background()))
+
+	require.NoError(t, settings.ClusterHealthMinReadyNodesRatio.Set("0.75"))
+	assert.Equal(t, []Result{{Component: NodesComponent, Healthy: true, Message: "3/4 nodes ready"}}, check.Run(context.Background()))
+
+	check = &nodesCheck{k8s: fake.NewSimpleClientset()}
+	assert.Equal(t, []Result{{Component: NodesComponent, Message: "0/0 nodes ready"}}, check.Run(context.Background()))
+}
+
+func TestDNSCheck(t *testing.T) {
+	dnsService := &v1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name:      "kube-dns",
+		Namespace: metav1.NamespaceSystem,
+		Labels:    map[string]string{"k8s-app": "kube-dns"},
+	}}
+	readyEndpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: metav1.NamespaceSystem},
+		Subsets:    []v1.EndpointSubset{{Addresses: []v1.EndpointAddress{{IP: "10.42.0.10"}}}},
+	}
+	notReadyEndpoints := &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: metav1.NamespaceSystem},
+		Subsets:    []v1.EndpointSubset{{NotReadyAddresses: []v1.EndpointAddress{{IP: "10.42.0.10"}}}},
+	}
+
+	tests := []struct {
+		name     string
+		check    *dnsCheck
+		expected []Result
+	}{
+		{
+			name:     "ready endpoints",
+			check:    &dnsCheck{k8s: fake.NewSimpleClientset(dnsService, readyEndpoints)},
+			expected: []Result{{Component: DNSComponent, Healthy: true}},
+		},
+		{
+			name:     "no ready endpoints",
+			check:    &dnsCheck{k8s: fake.NewSimpleClientset(dnsService, notReadyEndpoints)},
+			expected: []Result{{Component: DNSComponent, Message: "no ready DNS server endpoints"}},
+		},
+		{
+			name:  "no DNS service",
+			check: &dnsCheck{k8s: fake.NewSimpleClientset()},
+		},
+		{
+			name: "in cluster resolution",
+			check: &dnsCheck{inCluster: true, lookup: func(ctx context.Context, host string) ([]string, error) {
+				return []string{"10.43.0.1"}, nil
+			}},
+			expected: []Result{{Component: DNSComponent, Healthy: true}},
+		},
+		{
+			name: "in cluster resolution failure",
+			check: &dnsCheck{inCluster: true, lookup: func(ctx context.Context, host string) ([]string, error) {
+				return nil, errors.New("i/o timeout")
+			}},
+			expected: []Result{{Component: DNSComponent, Message: "failed to resolve kubernetes.default.svc: i/o timeout"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.check.Run(context.Background()))
+		})
+	}
+}
+
+func TestCertificatesCheck(t *testing.T) {
+	original := settings.ClusterHealthCertExpiryThreshold.Get()
+	t.Cleanup(func() { _ = settings.ClusterHealthCertExpiryThreshold.Set(original) })
+	require.NoError(t, settings.ClusterHealthCertExpiryThreshold.Set("720h"))
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+	notAfter := srv.Certificate().NotAfter
+
+	check := &certificatesCheck{
+		client: srv.Client(),
+		host:   srv.URL,
+		now:    func() time.Time { return notAfter.Add(-1000 * time.Hour) },
+	}
+	results := check.Run(context.Background())
+	require.Len(t, results, 1)
+	assert.Equal(t, CertificatesComponent, results[0].Component)
+	assert.True(t, results[0].Healthy)
+
+	check.now = func() time.Time { return notAfter.Add(-24 * time.Hour) }
+	results = check.Run(context.Background())
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Healthy)
+	assert.Contains(t, results[0].Message, notAfter.UTC().Format(time.RFC3339))
+}
+
+func TestHTTPChecks(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v1/namespaces/app/services/app/proxy/healthz", "/api/v1/namespaces/app/services/https:app:metrics/proxy/ready":
+			if req.URL.RawQuery == "" || req.URL.Query().Get("full") == "1" {
+				return
+			}
+		case "/api/v1/namespaces/app/services/app:8080/proxy/down":
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+	defer api.Close()
+
+	k8s := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: HTTPChecksConfigMap, Namespace: namespace.System},
+		Data: map[string]string{HTTPChecksKey: `
+- name: app
+  namespace: app
+  service: app
+  path: /healthz?full=1
+- name: app-tls
+  namespace: app
+  service: app
+  scheme: https
+  port: metrics
+  path: /ready
+- name: app-down
+  namespace: app
+  service: app
+  port: "8080"
+  path: /down
+- name: app-maintenance
+  namespace: app
+  service: app
+  port: "8080"
+  path: /down
+  expectedStatus: 503
+- name: missing
+  namespace: app
+  service: missing
+  path: /healthz
+- name: app
+  namespace: app
+  service: other
+- name: etcd
+  namespace: kube-system
+  service: etcd
+- name: external
+  namespace: app
+  service: example.com
+- name: traversal
+  namespace: app
+  service: app
+  path: /../../../../api/v1/secrets
+- name: encoded-traversal
+  namespace: app
+  service: app
+  path: /%2e%2e/%2e%2e/api/v1/secrets
+- name: ftp
+  namespace: app
+  service: app
+  scheme: ftp
+- name: no-service
+  namespace: app
+`},
+	})
+
+	results := (&httpChecks{k8s: k8s, api: newRESTClient(t, api.URL)}).Run(context.Background())
+	assert.Equal(t, []Result{
+		{Component: "app", Healthy: true, Message: "GET /api/v1/namespaces/app/services/app/proxy/healthz?full=1 returned 200"},
+		{Component: "app-tls", Healthy: true, Message: "GET /api/v1/namespaces/app/services/https:app:metrics/proxy/ready returned 200"},
+		{Component: "app-down", Message: "GET /api/v1/namespaces/app/services/app:8080/proxy/down returned 503, expected 200"},
+		{Component: "app-maintenance", Healthy: true, Message: "GET /api/v1/namespaces/app/services/app:8080/proxy/down returned 503"},
+		{Component: "missing", Message: "GET /api/v1/namespaces/app/services/missing/proxy/healthz returned 404, expected 200"},
+	}, results)
+
+	assert.Empty(t, (&httpChecks{k8s: fake.NewSimpleClientset()}).Run(context.Background()))
+}
+
+type staticCheck []Result
+
+func (s staticCheck) Run(ctx context.Context) []Result {
+	return s
+}
+
+func TestRunChecks(t *testing.T) {
+	results := runChecks(context.Background(), []Check{
+		staticCheck{{Component: "nodes", Healthy: true}},
+		staticCheck{{Component: "etcd"}, {Component: "apiserver", Healthy: true}},
+		staticCheck(nil),
+	})
+	assert.Equal(t, []Result{
+		{Component: "apiserver", Healthy: true},
+		{Component: "etcd"},
+		{Component: "nodes", Healthy: true},
+	}, results)
+
+	results = runChecks(context.Background(), []Check{
+		staticCheck{{Component: "etcd", Message: "failed checks: etcd"}},
+		staticCheck{{Component: "etcd", Healthy: true}, {Component: "app", Healthy: true}},
+	})
+	assert.Equal(t, []Result{
+		{Component: "app", Healthy: true},
+		{Component: "etcd", Message: "failed checks: etcd"},
+	}, results)
+}
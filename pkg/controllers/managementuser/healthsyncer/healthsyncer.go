@@ -6,6 +6,8 @@
 	"reflect"
 	"regexp"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/blang/semver"
@@ -55,6 +57,11 @@ type HealthSyncer struct {
 	componentStatuses corev1.ComponentStatusInterface
 	namespaces        corev1.NamespaceInterface
 	k8s               kubernetes.Interface
+	checks            []Check
+
+	// results are the results of the last run of the checks, nil until they first ran.
+	resultsLock sync.Mutex
+	results     []Result
 }
 
 func Register(ctx context.Context, workload *config.UserContext) {
@@ -68,7 +75,17 @@ func Register(ctx context.Context, workload *config.UserContext) {
 		k8s:               workload.K8sClient,
 	}
 
+	for _, factory := range checkFactories {
+		check, err := factory(workload)
+		if err != nil {
+			logrus.Errorf("[healthSyncer] failed to create health check for cluster [%s]: %v", workload.ClusterName, err)
+			continue
+		}
+		h.checks = append(h.checks, check)
+	}
+
 	go h.syncHealth(ctx, syncInterval)
+	go h.syncChecks(ctx, syncInterval)
 }
 
 func (h *HealthSyncer) syncHealth(ctx context.Context, syncHealth time.Duration) {
@@ -80,6 +97,27 @@ func (h *HealthSyncer) syncHealth(ctx context.Context, syncHealth time.Duration)
 	}
 }
 
+// syncChecks runs the health checks apart from the update of the Ready condition, so that slow checks, bounded by
+// their own timeout, don't delay it. The last results are reported by updateClusterHealth.
+func (h *HealthSyncer) syncChecks(ctx context.Context, interval time.Duration) {
+	for range ticker.Context(ctx, interval) {
+		results := runChecks(ctx, h.checks)
+		if results == nil {
+			results = []Result{}
+		}
+
+		h.resultsLock.Lock()
+		h.results = results
+		h.resultsLock.Unlock()
+	}
+}
+
+func (h *HealthSyncer) checkResults() []Result {
+	h.resultsLock.Lock()
+	defer h.resultsLock.Unlock()
+	return h.results
+}
+
 func (h *HealthSyncer) getComponentStatus(cluster *v3.Cluster) error {
 	// Prior to k8s v1.14, we only needed to list the ComponentStatuses from the user cluster.
 	// As of k8s v1.14, kubeapi returns a successful ComponentStatuses response even if etcd is not available.
@@ -128,6 +166,34 @@ func (h *HealthSyncer) getComponentStatus(cluster *v3.Cluster) error {
 	return nil
 }
 
+// setCheckConditions reports the status of the components checked by the health checks in the conditions of the
+// cluster, one per component, and removes the conditions of the components which are no longer checked. Unhealthy
+// components don't make the cluster not ready.
+func setCheckConditions(cluster *v3.Cluster, results []Result) {
+	checked := map[v32.ClusterConditionType]bool{}
+	for _, result := range results {
+		cond := condition.Cond(v32.ClusterHealthCheckConditionPrefix + result.Component)
+		checked[v32.ClusterConditionType(cond)] = true
+		if result.Healthy {
+			cond.True(cluster)
+			cond.Reason(cluster, "")
+		} else {
+			cond.False(cluster)
+			cond.Reason(cluster, "Unhealthy")
+		}
+		cond.Message(cluster, result.Message)
+	}
+
+	conditions := cluster.Status.Conditions[:0]
+	for _, c := range cluster.Status.Conditions {
+		if strings.HasPrefix(string(c.Type), v32.ClusterHealthCheckConditionPrefix) && !checked[c.Type] {
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	cluster.Status.Conditions = conditions
+}
+
 // IsAPIUp checks if the Kubernetes API server is up and etcd is available.
 // It gets a namespace from the API, even if not found, it means the API is up.
 // It returns nil if the API is up, otherwise it returns an error.
@@ -177,6 +243,10 @@ func (h *HealthSyncer) updateClusterHealth() error {
 		v32.ClusterConditionWaiting.Message(newObj, "")
 	}
 
+	if results := h.checkResults(); results != nil {
+		setCheckConditions(newObj.(*v3.Cluster), results)
+	}
+
 	if !reflect.DeepEqual(oldCluster, newObj) {
 		logrus.Tracef("[healthSyncer] update cluster %s", cluster.Name)
 		if _, err := h.clusters.Update(newObj.(*v3.Cluster)); err != nil {
@@ -5,6 +5,11 @@
 	"net"
 	"testing"
 
+	"github.com/rancher/norman/condition"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -116,3 +121,27 @@ func (m mockNamespaces) ApplyStatus(ctx context.Context, namespace *applycorev1.
 func (m mockNamespaces) Finalize(ctx context.Context, item *v1.Namespace, opts metav1.UpdateOptions) (*v1.Namespace, error) {
 	panic("implement me")
 }
+
+func TestSetCheckConditions(t *testing.T) {
+	cluster := &v3.Cluster{Status: v32.ClusterStatus{Conditions: []v32.ClusterCondition{
+		{Type: v32.ClusterConditionType(v32.ClusterConditionReady), Status: v1.ConditionTrue},
+		{Type: v32.ClusterHealthCheckConditionPrefix + "etcd", Status: v1.ConditionTrue},
+		{Type: v32.ClusterHealthCheckConditionPrefix + "removed", Status: v1.ConditionTrue},
+	}}}
+	setCheckConditions(cluster, []Result{
+		{Component: "etcd", Message: "failed checks: etcd"},
+		{Component: "nodes", Healthy: true, Message: "3/3 nodes ready"},
+	})
+
+	require.Len(t, cluster.Status.Conditions, 3)
+	assert.Equal(t, v32.ClusterConditionType(v32.ClusterConditionReady), cluster.Status.Conditions[0].Type)
+	assert.True(t, v32.ClusterConditionReady.IsTrue(cluster))
+	etcd := condition.Cond(v32.ClusterHealthCheckConditionPrefix + "etcd")
+	assert.True(t, etcd.IsFalse(cluster))
+	assert.Equal(t, "Unhealthy", etcd.GetReason(cluster))
+	assert.Equal(t, "failed checks: etcd", etcd.GetMessage(cluster))
+	nodes := condition.Cond(v32.ClusterHealthCheckConditionPrefix + "nodes")
+	assert.True(t, nodes.IsTrue(cluster))
+	assert.Equal(t, "3/3 nodes ready", nodes.GetMessage(cluster))
+	assert.Empty(t, cluster.Status.ComponentStatuses)
+}
@@ -14,6 +14,7 @@
 	typescorev1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	rnetworkingv1 "github.com/rancher/rancher/pkg/generated/norman/networking.k8s.io/v1"
+	"github.com/rancher/rancher/pkg/project"
 
 	cluster2 "github.com/rancher/rancher/pkg/controllers/provisioningv2/cluster"
 	rkecluster "github.com/rancher/rke/cluster"
@@ -113,6 +114,13 @@ func (npmgr *netpolMgr) programNetworkPolicy(projectID string, clusterNamespace
 		return fmt.Errorf("netpolMgr: programNetworkPolicy getSystemNamespaces: err=%v", err)
 	}
 
+	// the namespaces of the project are isolated together with the namespaces of the projects of its isolation group
+	projects, err := npmgr.projLister.List(clusterNamespace, labels.Everything())
+	if err != nil {
+		return fmt.Errorf("netpolMgr: couldn't list projects of cluster %v err=%v", clusterNamespace, err)
+	}
+	isolationGroup := project.NewTree(projects).IsolationGroup(projectID)
+
 	for _, aNS := range namespaces {
 		id, _ := aNS.Labels[nslabels.ProjectIDFieldLabel]
 
@@ -165,7 +173,7 @@ func (npmgr *netpolMgr) programNetworkPolicy(projectID string, clusterNamespace
 			continue
 		}
 
-		np := generateDefaultNamespaceNetworkPolicy(aNS, projectID, systemProjectID)
+		np := generateDefaultNamespaceNetworkPolicy(aNS, projectID, isolationGroup, systemProjectID)
 		if err := npmgr.program(np); err != nil {
 			return fmt.Errorf("netpolMgr: programNetworkPolicy: error programming default network policy for ns=%v err=%v", aNS.Name, err)
 		}
@@ -390,7 +398,22 @@ func (npmgr *netpolMgr) getSystemNSInfo(clusterNamespace string) (map[string]boo
 	return systemNamespaces, systemProjectID, nil
 }
 
-func generateDefaultNamespaceNetworkPolicy(aNS *corev1.Namespace, projectID string, systemProjectID string) *knetworkingv1.NetworkPolicy {
+// generateDefaultNamespaceNetworkPolicy generates the policy allowing the ingress traffic from the namespaces of the
+// projects of the isolation group of the project of the namespace, and from the namespaces of the system project.
+func generateDefaultNamespaceNetworkPolicy(aNS *corev1.Namespace, projectID string, isolationGroup []string, systemProjectID string) *knetworkingv1.NetworkPolicy {
+	var from []knetworkingv1.NetworkPolicyPeer
+	for _, groupProjectID := range isolationGroup {
+		from = append(from, knetworkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &v1.LabelSelector{
+				MatchLabels: map[string]string{nslabels.ProjectIDFieldLabel: groupProjectID},
+			},
+		})
+	}
+	from = append(from, knetworkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &v1.LabelSelector{
+			MatchLabels: map[string]string{nslabels.ProjectIDFieldLabel: systemProjectID},
+		},
+	})
 	return &knetworkingv1.NetworkPolicy{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      defaultNamespacePolicyName,
@@ -405,18 +428,7 @@ func generateDefaultNamespaceNetworkPolicy(aNS *corev1.Namespace, projectID stri
 			PodSelector: v1.LabelSelector{},
 			Ingress: []knetworkingv1.NetworkPolicyIngressRule{
 				{
-					From: []knetworkingv1.NetworkPolicyPeer{
-						{
-							NamespaceSelector: &v1.LabelSelector{
-								MatchLabels: map[string]string{nslabels.ProjectIDFieldLabel: projectID},
-							},
-						},
-						{
-							NamespaceSelector: &v1.LabelSelector{
-								MatchLabels: map[string]string{nslabels.ProjectIDFieldLabel: systemProjectID},
-							},
-						},
-					},
+					From: from,
 				},
 			},
 			PolicyTypes: []knetworkingv1.PolicyType{
@@ -3,11 +3,14 @@
 import (
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 
 	"github.com/rancher/norman/condition"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/project"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -21,6 +24,17 @@ type projectSyncer struct {
 	projClient       v3.ProjectInterface
 	clusterLister    v3.ClusterLister
 	clusterNamespace string
+	npmgr            *netpolMgr
+
+	// trees are the parents and network isolations of the projects their trees were last programmed for, by name
+	treesLock sync.Mutex
+	trees     map[string]projectTreeState
+}
+
+// projectTreeState is what the network isolation of the tree of a project depends on.
+type projectTreeState struct {
+	parent    string
+	isolation string
 }
 
 // Sync is responsible for creating a default ProjectNetworkPolicy for
@@ -29,7 +43,8 @@ type projectSyncer struct {
 // automatically.
 func (ps *projectSyncer) Sync(key string, p *v3.Project) (runtime.Object, error) {
 	if p == nil || p.DeletionTimestamp != nil {
-		return nil, nil
+		_, name, _ := strings.Cut(key, "/")
+		return nil, ps.programRemovedProjectTree(name)
 	}
 	disabled, err := isNetworkPolicyDisabled(ps.clusterNamespace, ps.clusterLister)
 	if err != nil {
@@ -51,7 +66,83 @@ func (ps *projectSyncer) Sync(key string, p *v3.Project) (runtime.Object, error)
 		}
 	}
 
-	return nil, nil
+	return nil, ps.programProjectTrees(p)
+}
+
+// programProjectTrees programs the network policies of the namespaces of the projects of the former and new trees of
+// the project when its parent or its network isolation changed, as the isolation groups of these projects may have
+// changed. The namespaces of a project without a tree are programmed by their own handlers.
+func (ps *projectSyncer) programProjectTrees(p *v3.Project) error {
+	state := projectTreeState{parent: project.ParentName(p), isolation: p.Spec.NetworkIsolation}
+	ps.treesLock.Lock()
+	previous, seen := ps.trees[p.Name]
+	ps.treesLock.Unlock()
+	if seen && previous == state {
+		return nil
+	}
+	// a project seen for the first time is only in a tree if it has a parent or isolates its sub-projects with it
+	if !seen && state.parent == "" && state.isolation != v32.ProjectNetworkIsolationTree {
+		ps.treesLock.Lock()
+		ps.trees[p.Name] = state
+		ps.treesLock.Unlock()
+		return nil
+	}
+
+	projects, err := ps.npmgr.projLister.List(ps.clusterNamespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	tree := project.NewTree(projects)
+	if err := ps.programTrees(tree, p.Name, previous.parent); err != nil {
+		return err
+	}
+
+	ps.treesLock.Lock()
+	ps.trees[p.Name] = state
+	ps.treesLock.Unlock()
+	return nil
+}
+
+// programRemovedProjectTree programs the network policies of the namespaces of the projects of the former tree of a
+// removed project.
+func (ps *projectSyncer) programRemovedProjectTree(name string) error {
+	ps.treesLock.Lock()
+	previous, seen := ps.trees[name]
+	delete(ps.trees, name)
+	ps.treesLock.Unlock()
+	if !seen || previous.parent == "" {
+		return nil
+	}
+	disabled, err := isNetworkPolicyDisabled(ps.clusterNamespace, ps.clusterLister)
+	if err != nil || disabled {
+		return err
+	}
+	projects, err := ps.npmgr.projLister.List(ps.clusterNamespace, labels.Everything())
+	if err != nil {
+		return err
+	}
+	return ps.programTrees(project.NewTree(projects), previous.parent)
+}
+
+// programTrees programs the network policies of the namespaces of the projects of the trees of the projects.
+func (ps *projectSyncer) programTrees(tree *project.Tree, projectNames ...string) error {
+	programmed := map[string]bool{}
+	for _, name := range projectNames {
+		root := tree.Root(name)
+		if name == "" || root == nil || programmed[root.Name] {
+			continue
+		}
+		for _, p := range append([]*v32.Project{root}, tree.Descendants(root.Name)...) {
+			programmed[p.Name] = true
+			if p.DeletionTimestamp != nil {
+				continue
+			}
+			if err := ps.npmgr.programNetworkPolicy(p.Name, ps.clusterNamespace); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (ps *projectSyncer) createDefaultNetworkPolicy(p *v3.Project) (*v3.Project, error) {
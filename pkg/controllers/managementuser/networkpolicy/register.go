@@ -51,7 +51,15 @@ func registerDeferred(ctx context.Context, cluster *config.UserContext) {
 
 	npmgr := &netpolMgr{clusterLister, clusters, nsLister, nodeLister, pods, projects,
 		npLister, npClient, projectLister, cluster.ClusterName}
-	ps := &projectSyncer{pnpLister, pnps, projects, clusterLister, cluster.ClusterName}
+	ps := &projectSyncer{
+		pnpLister:        pnpLister,
+		pnpClient:        pnps,
+		projClient:       projects,
+		clusterLister:    clusterLister,
+		clusterNamespace: cluster.ClusterName,
+		npmgr:            npmgr,
+		trees:            map[string]projectTreeState{},
+	}
 	nss := &nsSyncer{npmgr, clusterLister, serviceLister, podLister,
 		services, pods, cluster.ClusterName}
 	pnpsyncer := &projectNetworkPolicySyncer{npmgr}
@@ -3,7 +3,6 @@
 import (
 	"fmt"
 
-	"github.com/rancher/norman/types/slice"
 	apisv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	rbacv1 "github.com/rancher/rancher/pkg/generated/norman/rbac.authorization.k8s.io/v1"
@@ -130,29 +129,7 @@ func (c *grbHandler) isAdminRole(rtName string) (bool, error) {
 		return false, err
 	}
 
-	// global role is builtin admin role
-	if gr.Builtin && gr.Name == rbac.GlobalAdmin {
-		return true, nil
-	}
-
-	var hasResourceRule, hasNonResourceRule bool
-	for _, rule := range gr.Rules {
-		if slice.ContainsString(rule.Resources, "*") && slice.ContainsString(rule.APIGroups, "*") && slice.ContainsString(rule.Verbs, "*") {
-			hasResourceRule = true
-			continue
-		}
-		if slice.ContainsString(rule.NonResourceURLs, "*") && slice.ContainsString(rule.Verbs, "*") {
-			hasNonResourceRule = true
-			continue
-		}
-	}
-
-	// global role has an admin resource rule, and admin nonResourceURLs rule
-	if hasResourceRule && hasNonResourceRule {
-		return true, nil
-	}
-
-	return false, nil
+	return rbac.IsAdminGlobalRole(gr), nil
 }
 
 func grbByUserAndRole(obj interface{}) ([]string, error) {
@@ -25,6 +25,9 @@
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -51,9 +54,15 @@
 	rolesCircularHardLimit = 500
 )
 
-func Register(ctx context.Context, workload *config.UserContext) {
+func Register(ctx context.Context, workload *config.UserContext) error {
 	management := workload.Management.WithAgent("rbac-handler-base")
 
+	// The metadata client lists the objects the resource name patterns of NamespaceSelectedRules expand to
+	metadataClient, err := metadata.NewForConfig(&workload.RESTConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create metadata client: %w", err)
+	}
+
 	// Add cache informer to project role template bindings
 	prtbInformer := workload.Management.Management.ProjectRoleTemplateBindings("").Controller().Informer()
 	crtbInformer := workload.Management.Management.ClusterRoleTemplateBindings("").Controller().Informer()
@@ -98,6 +107,8 @@ func Register(ctx context.Context, workload *config.UserContext) {
 		crbLister:           workload.RBAC.ClusterRoleBindings("").Controller().Lister(),
 		crLister:            workload.RBAC.ClusterRoles("").Controller().Lister(),
 		clusterRoles:        workload.RBAC.ClusterRoles(""),
+		roleLister:          workload.RBAC.Roles("").Controller().Lister(),
+		roles:               workload.RBAC.Roles(""),
 		clusterRoleBindings: workload.RBAC.ClusterRoleBindings(""),
 		nsLister:            workload.Core.Namespaces("").Controller().Lister(),
 		nsController:        workload.Core.Namespaces("").Controller(),
@@ -105,6 +116,8 @@ func Register(ctx context.Context, workload *config.UserContext) {
 		projectLister:       management.Management.Projects(workload.ClusterName).Controller().Lister(),
 		userLister:          management.Management.Users("").Controller().Lister(),
 		userAttributeLister: management.Management.UserAttributes("").Controller().Lister(),
+		metadataClient:      metadataClient,
+		restMapper:          restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(workload.K8sClient.Discovery())),
 		clusterName:         workload.ClusterName,
 	}
 	management.Management.Projects(workload.ClusterName).AddClusterScopedLifecycle(ctx, "project-namespace-auth", workload.ClusterName, newProjectLifecycle(r))
@@ -129,6 +142,7 @@ func Register(ctx context.Context, workload *config.UserContext) {
 	management.Management.RoleTemplates("").AddHandler(ctx, "cluster-roletemplate-sync", newRTLifecycle(r))
 	relatedresource.WatchClusterScoped(ctx, "enqueue-beneficiary-roletemplates", newRTEnqueueFunc(rtInformer.GetIndexer()),
 		management.Wrangler.Mgmt.RoleTemplate(), management.Wrangler.Mgmt.RoleTemplate())
+	return nil
 }
 
 type managerInterface interface {
@@ -153,6 +167,8 @@ type manager struct {
 	crbIndexer          cache.Indexer
 	crLister            typesrbacv1.ClusterRoleLister
 	clusterRoles        typesrbacv1.ClusterRoleInterface
+	roleLister          typesrbacv1.RoleLister
+	roles               typesrbacv1.RoleInterface
 	crbLister           typesrbacv1.ClusterRoleBindingLister
 	clusterRoleBindings typesrbacv1.ClusterRoleBindingInterface
 	rbLister            typesrbacv1.RoleBindingLister
@@ -162,6 +178,8 @@ type manager struct {
 	projectLister       v3.ProjectLister
 	userLister          v3.UserLister
 	userAttributeLister v3.UserAttributeLister
+	metadataClient      metadata.Interface
+	restMapper          meta.RESTMapper
 	clusterName         string
 }
 
@@ -236,20 +254,7 @@ func ToLowerRoleTemplates(roleTemplates map[string]*v3.RoleTemplate) {
 
 		var toLowerRules []rbacv1.PolicyRule
 		for _, r := range rt.Rules {
-			rule := r.DeepCopy()
-
-			var resources []string
-			for _, re := range r.Resources {
-				resources = append(resources, strings.ToLower(re))
-			}
-			rule.Resources = resources
-
-			var verbs []string
-			for _, v := range r.Verbs {
-				verbs = append(verbs, strings.ToLower(v))
-			}
-			rule.Verbs = verbs
-			toLowerRules = append(toLowerRules, *rule)
+			toLowerRules = append(toLowerRules, toLowerRule(r))
 		}
 		rt.Rules = toLowerRules
 		roleTemplates[key] = rt
@@ -314,7 +319,7 @@ func (m *manager) ensureClusterBindings(roles map[string]*v3.RoleTemplate, bindi
 		return crb.Name, crb.RoleRef.Name, crb.Subjects
 	}
 
-	return m.ensureBindings("", roles, binding, m.workload.RBAC.ClusterRoleBindings("").ObjectClient(), create, list, convert)
+	return m.ensureBindings("", roles, nil, binding, m.workload.RBAC.ClusterRoleBindings("").ObjectClient(), create, list, convert)
 }
 
 func (m *manager) ensureProjectRoleBindings(ns string, roles map[string]*v3.RoleTemplate, binding *v3.ProjectRoleTemplateBinding) error {
@@ -340,17 +345,24 @@ func (m *manager) ensureProjectRoleBindings(ns string, roles map[string]*v3.Role
 
 	convert := func(i interface{}) (string, string, []rbacv1.Subject) {
 		rb, _ := i.(*rbacv1.RoleBinding)
-		return rb.Name, rb.RoleRef.Name, rb.Subjects
+		return rb.Name, roleRefKey(rb.RoleRef), rb.Subjects
+	}
+
+	namespacedRoles, err := m.ensureNamespaceSelectedRoles(ns, roles)
+	if err != nil {
+		return err
 	}
 
-	return m.ensureBindings(ns, roles, binding, m.workload.RBAC.RoleBindings(ns).ObjectClient(), create, list, convert)
+	return m.ensureBindings(ns, roles, namespacedRoles, binding, m.workload.RBAC.RoleBindings(ns).ObjectClient(), create, list, convert)
 }
 
 type createFn func(objectMeta metav1.ObjectMeta, subjects []rbacv1.Subject, roleRef rbacv1.RoleRef) runtime.Object
 type listFn func(ns string, selector labels.Selector) ([]interface{}, error)
 type convertFn func(i interface{}) (string, string, []rbacv1.Subject)
 
-func (m *manager) ensureBindings(ns string, roles map[string]*v3.RoleTemplate, binding metav1.Object, client *objectclient.ObjectClient,
+// ensureBindings ensures the bindings of the subject of binding to the ClusterRoles of roles and to the namespacedRoles,
+// which are Roles in ns, and removes the other bindings owned by binding.
+func (m *manager) ensureBindings(ns string, roles map[string]*v3.RoleTemplate, namespacedRoles []string, binding metav1.Object, client *objectclient.ObjectClient,
 	create createFn, list listFn, convert convertFn) error {
 	objMeta := meta.AsPartialObjectMetadata(binding).ObjectMeta
 
@@ -360,7 +372,11 @@ func (m *manager) ensureBindings(ns string, roles map[string]*v3.RoleTemplate, b
 		return err
 	}
 	for roleName := range roles {
-		rbKey, objectMeta, subjects, roleRef := bindingParts(ns, roleName, objMeta, subject)
+		rbKey, objectMeta, subjects, roleRef := bindingParts(ns, "ClusterRole", roleName, objMeta, subject)
+		desiredRBs[rbKey] = create(objectMeta, subjects, roleRef)
+	}
+	for _, roleName := range namespacedRoles {
+		rbKey, objectMeta, subjects, roleRef := bindingParts(ns, "Role", roleName, objMeta, subject)
 		desiredRBs[rbKey] = create(objectMeta, subjects, roleRef)
 	}
 
@@ -423,13 +439,12 @@ func (m *manager) ensureBindings(ns string, roles map[string]*v3.RoleTemplate, b
 	return nil
 }
 
-func bindingParts(namespace, roleName string, objMeta metav1.ObjectMeta, subject rbacv1.Subject) (string, metav1.ObjectMeta, []rbacv1.Subject, rbacv1.RoleRef) {
-	key := rbRoleSubjectKey(roleName, subject)
-
+func bindingParts(namespace, roleKind, roleName string, objMeta metav1.ObjectMeta, subject rbacv1.Subject) (string, metav1.ObjectMeta, []rbacv1.Subject, rbacv1.RoleRef) {
 	roleRef := rbacv1.RoleRef{
-		Kind: "ClusterRole",
+		Kind: roleKind,
 		Name: roleName,
 	}
+	key := rbRoleSubjectKey(roleRefKey(roleRef), subject)
 
 	var name string
 	if namespace == "" { // if namespace is empty, binding will be ClusterRoleBinding, so name accordingly
@@ -503,6 +518,14 @@ func crbRoleSubjectKeys(roleName string, subjects []rbacv1.Subject) []string {
 	return keys
 }
 
+// roleRefKey identifies the role referenced by a binding, telling the namespaced Roles apart from the ClusterRoles.
+func roleRefKey(roleRef rbacv1.RoleRef) string {
+	if roleRef.Kind == "Role" {
+		return "Role/" + roleRef.Name
+	}
+	return roleRef.Name
+}
+
 func rbRoleSubjectKey(roleName string, subject rbacv1.Subject) string {
 	return subject.Kind + " " + subject.Name + " Role " + roleName
 }
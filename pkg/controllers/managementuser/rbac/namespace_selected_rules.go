@@ -0,0 +1,240 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	pkgrbac "github.com/rancher/rancher/pkg/rbac"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// resourceNamePatternResync is how often the namespaces with Roles granting access to resource name patterns are
+// resynced, so the patterns expand to the objects created since.
+const resourceNamePatternResync = 5 * time.Minute
+
+// ensureNamespaceSelectedRoles ensures the Roles in the namespace holding the NamespaceSelectedRules of the RoleTemplates
+// whose selectors match the namespace, and returns their names. The Roles of RoleTemplates whose rules no longer
+// apply in the namespace, or which no longer have any, are removed.
+func (m *manager) ensureNamespaceSelectedRoles(namespace string, rts map[string]*v3.RoleTemplate) ([]string, error) {
+	var ns *v1.Namespace
+	var roleNames []string
+	resync := false
+	for _, rt := range rts {
+		roleName := pkgrbac.NamespaceSelectedRoleName(rt.Name)
+		if len(rt.NamespaceSelectedRules) == 0 {
+			if err := m.deleteNamespaceSelectedRole(namespace, roleName, rt.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if ns == nil {
+			var err error
+			ns, err = m.nsLister.Get("", namespace)
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("couldn't get namespace %s: %w", namespace, err)
+			}
+		}
+
+		rules, hasPatterns, err := m.namespaceSelectedRules(ns, rt)
+		if err != nil {
+			return nil, err
+		}
+		resync = resync || hasPatterns
+
+		if len(rules) == 0 {
+			if err := m.deleteNamespaceSelectedRole(namespace, roleName, rt.Name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		owned, err := m.ensureNamespaceSelectedRole(namespace, roleName, rt, rules)
+		if err != nil {
+			return nil, err
+		}
+		if owned {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+
+	if resync {
+		m.nsController.EnqueueAfter("", namespace, resourceNamePatternResync)
+	}
+	sort.Strings(roleNames)
+	return roleNames, nil
+}
+
+// namespaceSelectedRules returns the rules of the NamespaceSelectedRules of the RoleTemplate whose selectors match the
+// namespace, with their resource name patterns expanded, and whether any of them held patterns.
+func (m *manager) namespaceSelectedRules(ns *v1.Namespace, rt *v3.RoleTemplate) ([]rbacv1.PolicyRule, bool, error) {
+	if err := pkgrbac.ValidateNamespaceSelectedRules(rt); err != nil {
+		// The rules of an invalid RoleTemplate aren't granted, without blocking the other bindings of the namespace.
+		// The Rancher APIs reject them, and the effectivePermissions action reports them.
+		logrus.Errorf("Not granting the invalid namespace selected rules of roleTemplate %v in %v: %v", rt.Name, ns.Name, err)
+		return nil, false, nil
+	}
+
+	var rules []rbacv1.PolicyRule
+	hasPatterns := false
+	for _, selected := range rt.NamespaceSelectedRules {
+		selector, err := metav1.LabelSelectorAsSelector(&selected.NamespaceSelector)
+		if err != nil {
+			return nil, false, err
+		}
+		if !selector.Matches(labels.Set(ns.Labels)) {
+			continue
+		}
+		for _, rule := range selected.Rules {
+			rule = toLowerRule(rule)
+			if !pkgrbac.HasResourceNamePatterns(rule) {
+				rules = append(rules, rule)
+				continue
+			}
+			hasPatterns = true
+			names, err := m.expandResourceNames(ns.Name, rule)
+			if err != nil {
+				return nil, false, err
+			}
+			// a rule without resource names would grant access to all the objects
+			if len(names) == 0 {
+				continue
+			}
+			rule.ResourceNames = names
+			rules = append(rules, rule)
+		}
+	}
+	return rules, hasPatterns, nil
+}
+
+// expandResourceNames replaces the resource name patterns of the rule with the names of the existing objects of its
+// resources in the namespace which start with their prefixes.
+func (m *manager) expandResourceNames(namespace string, rule rbacv1.PolicyRule) ([]string, error) {
+	names := sets.New[string]()
+	var prefixes []string
+	for _, name := range rule.ResourceNames {
+		if prefix, ok := pkgrbac.ResourceNamePrefix(name); ok {
+			prefixes = append(prefixes, prefix)
+		} else {
+			names.Insert(name)
+		}
+	}
+
+	for _, group := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			// the names of the objects of subresources are the ones of their parent resource
+			resource, _, _ = strings.Cut(resource, "/")
+			gvr, err := m.restMapper.ResourceFor(schema.GroupVersionResource{Group: group, Resource: resource})
+			if meta.IsNoMatchError(err) {
+				// the resource isn't served by the cluster, or was added since the mapper was last refreshed
+				meta.MaybeResetRESTMapper(m.restMapper)
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("couldn't map resource %s in group %q: %w", resource, group, err)
+			}
+			objects, err := m.metadataClient.Resource(gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("couldn't list %s in %s: %w", gvr.Resource, namespace, err)
+			}
+			for _, object := range objects.Items {
+				for _, prefix := range prefixes {
+					if strings.HasPrefix(object.Name, prefix) {
+						names.Insert(object.Name)
+					}
+				}
+			}
+		}
+	}
+	return sets.List(names), nil
+}
+
+// ensureNamespaceSelectedRole creates or updates the Role holding the namespace selected rules of the RoleTemplate, and
+// returns whether the Role is owned by the RoleTemplate. A Role of the same name which wasn't created for the
+// RoleTemplate is left alone and must not be bound.
+func (m *manager) ensureNamespaceSelectedRole(namespace, roleName string, rt *v3.RoleTemplate, rules []rbacv1.PolicyRule) (bool, error) {
+	role, err := m.roleLister.Get(namespace, roleName)
+	if apierrors.IsNotFound(err) {
+		logrus.Infof("Creating role %v in %v for the namespace selected rules of roleTemplate %v (%v).", roleName, namespace, rt.DisplayName, rt.Name)
+		_, err := m.roles.Create(&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        roleName,
+				Namespace:   namespace,
+				Annotations: map[string]string{clusterRoleOwner: rt.Name},
+			},
+			Rules: rules,
+		})
+		// a Role which already exists is checked on the retry, once it is in the cache
+		if err != nil {
+			return false, fmt.Errorf("couldn't create role %v in %v: %w", roleName, namespace, err)
+		}
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("couldn't get role %v in %v: %w", roleName, namespace, err)
+	}
+
+	if !ownsNamespaceSelectedRole(role, rt.Name) {
+		logrus.Warnf("Role %v in %v wasn't created for the namespace selected rules of roleTemplate %v (%v), not binding it.", roleName, namespace, rt.DisplayName, rt.Name)
+		return false, nil
+	}
+	if equality.Semantic.DeepEqual(role.Rules, rules) {
+		return true, nil
+	}
+	role = role.DeepCopy()
+	role.Rules = rules
+	logrus.Infof("Updating role %v in %v because of rules difference with roleTemplate %v (%v).", roleName, namespace, rt.DisplayName, rt.Name)
+	if _, err := m.roles.Update(role); err != nil {
+		return false, fmt.Errorf("couldn't update role %v in %v: %w", roleName, namespace, err)
+	}
+	return true, nil
+}
+
+// deleteNamespaceSelectedRole deletes the Role holding the namespace selected rules of the RoleTemplate, if it was
+// created for it.
+func (m *manager) deleteNamespaceSelectedRole(namespace, roleName, rtName string) error {
+	role, err := m.roleLister.Get(namespace, roleName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("couldn't get role %v in %v: %w", roleName, namespace, err)
+	}
+	if !ownsNamespaceSelectedRole(role, rtName) {
+		return nil
+	}
+	logrus.Infof("Deleting role %v in %v as its namespace selected rules no longer apply.", roleName, namespace)
+	if err := m.roles.DeleteNamespaced(namespace, roleName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("couldn't delete role %v in %v: %w", roleName, namespace, err)
+	}
+	return nil
+}
+
+// ownsNamespaceSelectedRole returns whether the Role was created for the namespace selected rules of the RoleTemplate.
+func ownsNamespaceSelectedRole(role *rbacv1.Role, rtName string) bool {
+	return role.Annotations[clusterRoleOwner] == rtName
+}
+
+// toLowerRule cleans a rule for kubernetes: lowercase resources and verbs.
+func toLowerRule(r rbacv1.PolicyRule) rbacv1.PolicyRule {
+	rule := r.DeepCopy()
+	rule.Resources = nil
+	for _, re := range r.Resources {
+		rule.Resources = append(rule.Resources, strings.ToLower(re))
+	}
+	rule.Verbs = nil
+	for _, v := range r.Verbs {
+		rule.Verbs = append(rule.Verbs, strings.ToLower(v))
+	}
+	return *rule
+}
@@ -0,0 +1,258 @@
+package rbac
+
+import (
+	"testing"
+	"time"
+
+	apisV3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	coreFakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/generated/norman/rbac.authorization.k8s.io/v1/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+var webDeveloperRoleTemplate = &v3.RoleTemplate{
+	ObjectMeta: metav1.ObjectMeta{Name: "web-developer"},
+	Context:    "project",
+	NamespaceSelectedRules: []apisV3.NamespaceSelectedRules{
+		{
+			NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"ConfigMaps"}, Verbs: []string{"GET"}},
+				{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"web-*", "shared"}, Verbs: []string{"get"}},
+			},
+		},
+		{
+			NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "db"}},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}},
+			},
+		},
+	},
+}
+
+type selectedRolesRecorder struct {
+	created  []*rbacv1.Role
+	updated  []*rbacv1.Role
+	deleted  []string
+	enqueued []string
+}
+
+func secretMetadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+}
+
+func newSelectedRolesManager(t *testing.T, nsLabels map[string]string, existing *rbacv1.Role) (*manager, *selectedRolesRecorder) {
+	recorder := &selectedRolesRecorder{}
+
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	metadataClient := metadatafake.NewSimpleMetadataClient(scheme,
+		secretMetadata("web-ns", "web-a"),
+		secretMetadata("web-ns", "web-b"),
+		secretMetadata("web-ns", "db-x"),
+		secretMetadata("other-ns", "web-c"),
+	)
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, meta.RESTScopeNamespace)
+
+	return &manager{
+		nsLister: &coreFakes.NamespaceListerMock{
+			GetFunc: func(namespace, name string) (*corev1.Namespace, error) {
+				return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}, nil
+			},
+		},
+		nsController: &coreFakes.NamespaceControllerMock{
+			EnqueueAfterFunc: func(namespace, name string, after time.Duration) {
+				assert.Equal(t, resourceNamePatternResync, after)
+				recorder.enqueued = append(recorder.enqueued, name)
+			},
+		},
+		roleLister: &fakes.RoleListerMock{
+			GetFunc: func(namespace, name string) (*rbacv1.Role, error) {
+				if existing != nil && existing.Namespace == namespace && existing.Name == name {
+					return existing, nil
+				}
+				return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roles"}, name)
+			},
+		},
+		roles: &fakes.RoleInterfaceMock{
+			CreateFunc: func(role *rbacv1.Role) (*rbacv1.Role, error) {
+				recorder.created = append(recorder.created, role)
+				return role, nil
+			},
+			UpdateFunc: func(role *rbacv1.Role) (*rbacv1.Role, error) {
+				recorder.updated = append(recorder.updated, role)
+				return role, nil
+			},
+			DeleteNamespacedFunc: func(namespace, name string, _ *metav1.DeleteOptions) error {
+				recorder.deleted = append(recorder.deleted, namespace+"/"+name)
+				return nil
+			},
+		},
+		metadataClient: metadataClient,
+		restMapper:     restMapper,
+	}, recorder
+}
+
+func TestEnsureNamespaceSelectedRoles(t *testing.T) {
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, nil)
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{
+		"web-developer": webDeveloperRoleTemplate,
+		"plain":         {ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"web-developer-namespace-selected"}, roleNames)
+	require.Len(t, recorder.created, 1)
+	role := recorder.created[0]
+	assert.Equal(t, "web-ns", role.Namespace)
+	assert.Equal(t, "web-developer", role.Annotations[clusterRoleOwner])
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+		{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"shared", "web-a", "web-b"}, Verbs: []string{"get"}},
+	}, role.Rules)
+	// the namespace is resynced to expand the patterns to new secrets
+	assert.Equal(t, []string{"web-ns"}, recorder.enqueued)
+}
+
+func TestEnsureNamespaceSelectedRolesUpdatesRole(t *testing.T) {
+	existing := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "web-ns",
+			Name:        "web-developer-namespace-selected",
+			Annotations: map[string]string{clusterRoleOwner: "web-developer"},
+		},
+		Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+	}
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, existing)
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": webDeveloperRoleTemplate})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"web-developer-namespace-selected"}, roleNames)
+	assert.Empty(t, recorder.created)
+	require.Len(t, recorder.updated, 1)
+	assert.Len(t, recorder.updated[0].Rules, 2)
+}
+
+func TestEnsureNamespaceSelectedRolesRemovesRole(t *testing.T) {
+	existing := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "web-ns",
+			Name:        "web-developer-namespace-selected",
+			Annotations: map[string]string{clusterRoleOwner: "web-developer"},
+		},
+	}
+	// the namespace was relabeled and no longer matches the selectors
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "ops"}, existing)
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": webDeveloperRoleTemplate})
+	require.NoError(t, err)
+
+	assert.Empty(t, roleNames)
+	assert.Equal(t, []string{"web-ns/web-developer-namespace-selected"}, recorder.deleted)
+	assert.Empty(t, recorder.enqueued)
+}
+
+func TestEnsureNamespaceSelectedRolesRemovesRoleOfRemovedRules(t *testing.T) {
+	existing := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "web-ns",
+			Name:        "web-developer-namespace-selected",
+			Annotations: map[string]string{clusterRoleOwner: "web-developer"},
+		},
+	}
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, existing)
+	rt := webDeveloperRoleTemplate.DeepCopy()
+	rt.NamespaceSelectedRules = nil
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": rt})
+	require.NoError(t, err)
+
+	assert.Empty(t, roleNames)
+	assert.Equal(t, []string{"web-ns/web-developer-namespace-selected"}, recorder.deleted)
+}
+
+func TestEnsureNamespaceSelectedRolesLeavesForeignRole(t *testing.T) {
+	// a Role of the same name created by a user
+	existing := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web-ns", Name: "web-developer-namespace-selected"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+	}
+
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, existing)
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": webDeveloperRoleTemplate})
+	require.NoError(t, err)
+	// it is neither taken over nor bound
+	assert.Empty(t, roleNames)
+	assert.Empty(t, recorder.created)
+	assert.Empty(t, recorder.updated)
+
+	m, recorder = newSelectedRolesManager(t, map[string]string{"team": "ops"}, existing)
+	_, err = m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": webDeveloperRoleTemplate})
+	require.NoError(t, err)
+	// nor deleted
+	assert.Empty(t, recorder.deleted)
+}
+
+func TestEnsureNamespaceSelectedRolesWithoutMatchingObjects(t *testing.T) {
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, nil)
+	rt := &v3.RoleTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-reader"},
+		Context:    "project",
+		NamespaceSelectedRules: []apisV3.NamespaceSelectedRules{
+			{
+				Rules: []rbacv1.PolicyRule{
+					{APIGroups: []string{""}, Resources: []string{"secrets"}, ResourceNames: []string{"api-*"}, Verbs: []string{"get"}},
+				},
+			},
+		},
+	}
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"api-reader": rt})
+	require.NoError(t, err)
+
+	// a pattern matching no object must not grant access to every secret
+	assert.Empty(t, roleNames)
+	assert.Empty(t, recorder.created)
+	assert.Equal(t, []string{"web-ns"}, recorder.enqueued)
+}
+
+func TestEnsureNamespaceSelectedRolesInvalidRoleTemplate(t *testing.T) {
+	m, recorder := newSelectedRolesManager(t, map[string]string{"team": "web"}, nil)
+	rt := webDeveloperRoleTemplate.DeepCopy()
+	rt.Context = "cluster"
+
+	roleNames, err := m.ensureNamespaceSelectedRoles("web-ns", map[string]*v3.RoleTemplate{"web-developer": rt})
+	require.NoError(t, err)
+
+	assert.Empty(t, roleNames)
+	assert.Empty(t, recorder.created)
+}
+
+func TestBindingPartsNamespacedRole(t *testing.T) {
+	subject := rbacv1.Subject{Kind: "User", Name: "u-1"}
+	objMeta := metav1.ObjectMeta{Namespace: "p-1", Name: "prtb-1"}
+
+	clusterRoleKey, _, _, clusterRoleRef := bindingParts("web-ns", "ClusterRole", "web-developer", objMeta, subject)
+	roleKey, objectMeta, _, roleRef := bindingParts("web-ns", "Role", "web-developer", objMeta, subject)
+
+	assert.Equal(t, rbacv1.RoleRef{Kind: "Role", Name: "web-developer"}, roleRef)
+	assert.Equal(t, rbacv1.RoleRef{Kind: "ClusterRole", Name: "web-developer"}, clusterRoleRef)
+	assert.NotEqual(t, clusterRoleKey, roleKey)
+	assert.Equal(t, rbRoleSubjectKey(roleRefKey(roleRef), subject), roleKey)
+	assert.Equal(t, "p-1_prtb-1", objectMeta.Labels[rtbOwnerLabel])
+}
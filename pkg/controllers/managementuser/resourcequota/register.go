@@ -25,6 +25,7 @@ func Register(ctx context.Context, cluster *config.UserContext) {
 		if obj != nil &&
 			(obj.Spec.ResourceQuota != nil ||
 				obj.Spec.ContainerDefaultResourceLimit != nil ||
+				obj.Spec.LimitRange != nil ||
 				obj.Spec.NamespaceDefaultResourceQuota != nil) {
 			return obj, starter()
 		}
@@ -6,10 +6,12 @@
 
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	namespaceutil "github.com/rancher/rancher/pkg/namespace"
+	projectpkg "github.com/rancher/rancher/pkg/project"
 	"github.com/rancher/rancher/pkg/ref"
 	validate "github.com/rancher/rancher/pkg/resourcequota"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	quota "k8s.io/apiserver/pkg/quota/v1"
 	clientcache "k8s.io/client-go/tools/cache"
@@ -38,11 +40,52 @@ func (c *calculateLimitController) calculateResourceQuotaUsed(key string, ns *co
 }
 
 func (c *calculateLimitController) calculateResourceQuotaUsedProject(key string, p *v3.Project) (runtime.Object, error) {
-	if p == nil || p.DeletionTimestamp != nil {
+	if p == nil {
 		return nil, nil
 	}
 
-	return nil, c.calculateProjectResourceQuota(fmt.Sprintf("%s:%s", c.clusterName, p.Name))
+	if p.DeletionTimestamp == nil {
+		if err := c.calculateProjectResourceQuota(fmt.Sprintf("%s:%s", c.clusterName, p.Name)); err != nil {
+			return nil, err
+		}
+	}
+	// the limit of a sub-project is used in its parent, and no longer in its previous parent
+	parent := projectpkg.ParentName(p)
+	previous := p.Annotations[usedInProjectAnnotation]
+	for _, projectName := range []string{parent, previous} {
+		if projectName == "" {
+			continue
+		}
+		if err := c.calculateProjectResourceQuota(fmt.Sprintf("%s:%s", c.clusterName, projectName)); err != nil {
+			return nil, err
+		}
+		if previous == parent {
+			break
+		}
+	}
+	if previous == parent || p.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return nil, c.setUsedInProject(p, parent)
+}
+
+// setUsedInProject records the project the limit of the project is used in.
+func (c *calculateLimitController) setUsedInProject(p *v3.Project, parent string) error {
+	// the project was just updated if its own used limit changed
+	toUpdate, err := c.projects.GetNamespaced(p.Namespace, p.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if toUpdate.Annotations == nil {
+		toUpdate.Annotations = map[string]string{}
+	}
+	if parent == "" {
+		delete(toUpdate.Annotations, usedInProjectAnnotation)
+	} else {
+		toUpdate.Annotations[usedInProjectAnnotation] = parent
+	}
+	_, err = c.projects.Update(toUpdate)
+	return err
 }
 
 func (c *calculateLimitController) calculateProjectResourceQuota(projectID string) error {
@@ -84,7 +127,18 @@ func (c *calculateLimitController) calculateProjectResourceQuota(projectID strin
 		}
 		nssResourceList = quota.Add(nssResourceList, nsResourceList)
 	}
-	limit, err := convertResourceListToLimit(nssResourceList)
+	childLimits, err := getChildProjectsLimits(projectID, c.projectLister)
+	if err != nil {
+		return err
+	}
+	for _, childLimit := range childLimits {
+		childResourceList, err := validate.ConvertLimitToResourceList(childLimit)
+		if err != nil {
+			return err
+		}
+		nssResourceList = quota.Add(nssResourceList, childResourceList)
+	}
+	limit, err := validate.ConvertResourceListToLimit(nssResourceList)
 	if err != nil {
 		return err
 	}
@@ -6,29 +6,14 @@
 	"github.com/rancher/norman/types/convert"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	projectpkg "github.com/rancher/rancher/pkg/project"
 	"github.com/rancher/rancher/pkg/ref"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-func convertResourceListToLimit(rList corev1.ResourceList) (*v32.ResourceQuotaLimit, error) {
-	converted, err := convert.EncodeToMap(rList)
-	if err != nil {
-		return nil, err
-	}
-
-	convertedMap := map[string]string{}
-	for key, value := range converted {
-		convertedMap[key] = convert.ToString(value)
-	}
-
-	toReturn := &v32.ResourceQuotaLimit{}
-	err = convert.ToObj(convertedMap, toReturn)
-
-	return toReturn, err
-}
-
 func convertResourceLimitResourceQuotaSpec(limit *v32.ResourceQuotaLimit) (*corev1.ResourceQuotaSpec, error) {
 	converted, err := convertProjectResourceLimitToResourceList(limit)
 	if err != nil {
@@ -42,7 +27,9 @@ func convertResourceLimitResourceQuotaSpec(limit *v32.ResourceQuotaLimit) (*core
 
 // convertProjectResourceLimitToResourceList tries to convert a Rancher-defined resource quota limit to its native Kubernetes notation.
 func convertProjectResourceLimitToResourceList(limit *v32.ResourceQuotaLimit) (corev1.ResourceList, error) {
-	in, err := json.Marshal(limit)
+	standard := limit.DeepCopy()
+	standard.Extended = nil
+	in, err := json.Marshal(standard)
 	if err != nil {
 		return nil, err
 	}
@@ -68,61 +55,15 @@ func convertProjectResourceLimitToResourceList(limit *v32.ResourceQuotaLimit) (c
 
 		limits[resourceName] = resourceQuantity
 	}
-	return limits, nil
-}
-
-func convertContainerResourceLimitToResourceList(limit *v32.ContainerResourceLimit) (corev1.ResourceList, corev1.ResourceList, error) {
-	in, err := json.Marshal(limit)
-	if err != nil {
-		return nil, nil, err
-	}
-	limitsMap := map[string]string{}
-	err = json.Unmarshal(in, &limitsMap)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	if len(limitsMap) == 0 {
-		return nil, nil, nil
-	}
-
-	limits := corev1.ResourceList{}
-	requests := corev1.ResourceList{}
-	for key, value := range limitsMap {
-		var resourceName corev1.ResourceName
-		request := false
-		if val, ok := limitRangerRequestConversion[key]; ok {
-			resourceName = corev1.ResourceName(val)
-			request = true
-		} else if val, ok := limitRangerLimitConversion[key]; ok {
-			resourceName = corev1.ResourceName(val)
-		}
-		if resourceName == "" {
-			continue
-		}
-
+	// extended resources are already keyed by their Kubernetes name
+	for key, value := range limit.Extended {
 		resourceQuantity, err := resource.ParseQuantity(value)
 		if err != nil {
-			return nil, nil, err
-		}
-		if request {
-			requests[resourceName] = resourceQuantity
-		} else {
-			limits[resourceName] = resourceQuantity
+			return nil, err
 		}
-
+		limits[corev1.ResourceName(key)] = resourceQuantity
 	}
-	return requests, limits, nil
-}
-
-var limitRangerRequestConversion = map[string]string{
-	"requestsCpu":    "cpu",
-	"requestsMemory": "memory",
-}
-
-var limitRangerLimitConversion = map[string]string{
-	"limitsCpu":    "cpu",
-	"limitsMemory": "memory",
+	return limits, nil
 }
 
 var resourceQuotaConversion = map[string]string{
@@ -173,6 +114,27 @@ func getProjectResourceQuotaLimit(ns *corev1.Namespace, projectLister v3.Project
 	return &project.Spec.ResourceQuota.Limit, projectID, nil
 }
 
+// getChildProjectsLimits returns the resource quota limits of the sub-projects of the project. The limit of a
+// sub-project is carved out of the limit of its parent, so it counts as used in the parent.
+func getChildProjectsLimits(projectID string, projectLister v3.ProjectLister) ([]*v32.ResourceQuotaLimit, error) {
+	projectNamespace, projectName := ref.Parse(projectID)
+	if projectName == "" {
+		return nil, nil
+	}
+	projects, err := projectLister.List(projectNamespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var limits []*v32.ResourceQuotaLimit
+	for _, p := range projects {
+		if projectpkg.ParentName(p) != projectName || p.Name == projectName || p.DeletionTimestamp != nil || p.Spec.ResourceQuota == nil {
+			continue
+		}
+		limits = append(limits, p.Spec.ResourceQuota.Limit.DeepCopy())
+	}
+	return limits, nil
+}
+
 func getProjectNamespaceDefaultQuota(ns *corev1.Namespace, projectLister v3.ProjectLister) (*v32.NamespaceResourceQuota, error) {
 	projectID := getProjectID(ns)
 	if projectID == "" {
@@ -215,6 +177,27 @@ func getProjectContainerDefaultLimit(ns *corev1.Namespace, projectLister v3.Proj
 	return project.Spec.ContainerDefaultResourceLimit, nil
 }
 
+func getProjectLimitRange(ns *corev1.Namespace, projectLister v3.ProjectLister) (*v32.ProjectLimitRange, error) {
+	projectID := getProjectID(ns)
+	if projectID == "" {
+		return nil, nil
+	}
+	projectNamespace, projectName := ref.Parse(projectID)
+	if projectName == "" {
+		return nil, nil
+	}
+	project, err := projectLister.Get(projectNamespace, projectName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// If Rancher is unaware of a project, we should ignore trying to get the limit range
+			// A non-existent project is likely managed by another Rancher (e.g. Hosted Rancher)
+			return nil, nil
+		}
+		return nil, err
+	}
+	return project.Spec.LimitRange, nil
+}
+
 func getNamespaceResourceQuotaLimit(ns *corev1.Namespace) (*v32.ResourceQuotaLimit, error) {
 	value := getNamespaceResourceQuota(ns)
 	if value == "" {
@@ -250,24 +233,3 @@ func getProjectID(ns *corev1.Namespace) string {
 	}
 	return ""
 }
-
-func convertPodResourceLimitToLimitRangeSpec(podResourceLimit *v32.ContainerResourceLimit) (*corev1.LimitRangeSpec, error) {
-	request, limit, err := convertContainerResourceLimitToResourceList(podResourceLimit)
-	if err != nil {
-		return nil, err
-	}
-	if request == nil && limit == nil {
-		return nil, nil
-	}
-
-	item := corev1.LimitRangeItem{
-		Type:           corev1.LimitTypeContainer,
-		Default:        limit,
-		DefaultRequest: request,
-	}
-	limits := []corev1.LimitRangeItem{item}
-	limitRangeSpec := &corev1.LimitRangeSpec{
-		Limits: limits,
-	}
-	return limitRangeSpec, err
-}
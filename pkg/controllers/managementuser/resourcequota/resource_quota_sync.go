@@ -3,7 +3,6 @@
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"time"
 
 	"github.com/rancher/norman/types/convert"
@@ -16,6 +15,7 @@
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -29,6 +29,11 @@
 	limitRangeAnnotation            = "field.cattle.io/containerDefaultResourceLimit"
 	ResourceQuotaValidatedCondition = "ResourceQuotaValidated"
 	ResourceQuotaInitCondition      = "ResourceQuotaInit"
+	LimitRangeValidatedCondition    = "LimitRangeValidated"
+
+	// usedInProjectAnnotation is set on a sub-project to the parent project its limit was last used in, so that the
+	// used limit of the former parent is calculated again when the parent changes.
+	usedInProjectAnnotation = "resourcequota.management.cattle.io/used-in-project"
 )
 
 /*
@@ -50,10 +55,13 @@ func (c *SyncController) syncResourceQuota(key string, ns *corev1.Namespace) (ru
 		return nil, nil
 	}
 
-	_, err := c.CreateResourceQuota(ns)
+	updated, err := c.CreateResourceQuota(ns)
 	if err != nil {
 		return nil, err
 	}
+	if updated, ok := updated.(*corev1.Namespace); ok && updated != nil {
+		ns = updated
+	}
 
 	return nil, c.createLimitRange(ns)
 }
@@ -64,10 +72,23 @@ func (c *SyncController) createLimitRange(ns *corev1.Namespace) error {
 		return err
 	}
 
-	limitRangeSpec, err := c.getResourceLimitToUpdate(ns)
+	containerDefaults, projectLimitRange, err := c.getLimitRangeSources(ns)
+	if err != nil {
+		return err
+	}
+	items, err := validate.LimitRangeItems(containerDefaults, projectLimitRange)
 	if err != nil {
+		// retrying doesn't fix the limits of the project or the namespace: the problem is reported on the namespace,
+		// which is synced again once they change, and the existing LimitRange is left in place
+		return c.setLimitRangeValidated(ns, false, err.Error())
+	}
+	if err := c.setLimitRangeValidated(ns, true, ""); err != nil {
 		return err
 	}
+	var limitRangeSpec *corev1.LimitRangeSpec
+	if len(items) > 0 {
+		limitRangeSpec = &corev1.LimitRangeSpec{Limits: items}
+	}
 
 	operation := "none"
 	if existing == nil {
@@ -101,12 +122,15 @@ func limitsChanged(existing []corev1.LimitRangeItem, toUpdate []corev1.LimitRang
 	if len(existing) == 0 || len(toUpdate) == 0 {
 		return true
 	}
-	if !apiequality.Semantic.DeepEqual(existing[0].DefaultRequest, toUpdate[0].DefaultRequest) {
-		return true
-	}
-
-	if !apiequality.Semantic.DeepEqual(existing[0].Default, toUpdate[0].Default) {
-		return true
+	for i := range existing {
+		if existing[i].Type != toUpdate[i].Type ||
+			!apiequality.Semantic.DeepEqual(existing[i].Max, toUpdate[i].Max) ||
+			!apiequality.Semantic.DeepEqual(existing[i].Min, toUpdate[i].Min) ||
+			!apiequality.Semantic.DeepEqual(existing[i].Default, toUpdate[i].Default) ||
+			!apiequality.Semantic.DeepEqual(existing[i].DefaultRequest, toUpdate[i].DefaultRequest) ||
+			!apiequality.Semantic.DeepEqual(existing[i].MaxLimitRequestRatio, toUpdate[i].MaxLimitRequestRatio) {
+			return true
+		}
 	}
 	return false
 }
@@ -386,7 +410,11 @@ func (c *SyncController) getNamespacesLimits(ns *v1.Namespace, projectID string)
 		}
 		nsLimits = append(nsLimits, nsLimit)
 	}
-	return nsLimits, nil
+	childLimits, err := getChildProjectsLimits(projectID, c.ProjectLister)
+	if err != nil {
+		return nil, err
+	}
+	return append(nsLimits, childLimits...), nil
 }
 
 func (c *SyncController) setValidated(ns *corev1.Namespace, value bool, msg string) (*corev1.Namespace, error) {
@@ -397,50 +425,66 @@ func (c *SyncController) setValidated(ns *corev1.Namespace, value bool, msg stri
 	return c.Namespaces.Update(toUpdate)
 }
 
-func (c *SyncController) getResourceLimitToUpdate(ns *corev1.Namespace) (*corev1.LimitRangeSpec, error) {
-	nsLimit, err := getNamespaceContainerResourceLimit(ns)
+// setLimitRangeValidated reports whether the limits of the namespace and its project make a valid LimitRange, unless it
+// is already reported. The condition is only set on the namespaces whose limits were invalid once.
+func (c *SyncController) setLimitRangeValidated(ns *corev1.Namespace, value bool, msg string) error {
+	set, err := namespaceutil.IsNamespaceConditionSet(ns, LimitRangeValidatedCondition, value)
 	if err != nil {
-		return nil, err
-	}
-	projectLimit, err := getProjectContainerDefaultLimit(ns, c.ProjectLister)
-	if err != nil {
-		return nil, err
+		return err
 	}
-
-	// rework after api framework change is done
-	// when annotation field is passed as null, the annotation should be removed
-	// instead of being updated with the null value
-	var updatedLimit *v32.ContainerResourceLimit
-	if nsLimit != nil {
-		// check if fields need to be removed or set
-		// based on the default quota
-		updatedLimit, err = completeLimit(nsLimit, projectLimit)
-		if err != nil {
-			return nil, err
+	if value {
+		invalid, err := namespaceutil.IsNamespaceConditionSet(ns, LimitRangeValidatedCondition, false)
+		if err != nil || set || !invalid {
+			return err
+		}
+	} else if set {
+		current, err := namespaceutil.GetNamespaceConditionMessage(ns, LimitRangeValidatedCondition)
+		if err != nil || current == msg {
+			return err
 		}
 	}
+	toUpdate := ns.DeepCopy()
+	if err := namespaceutil.SetNamespaceCondition(toUpdate, time.Second*1, LimitRangeValidatedCondition, value, msg); err != nil {
+		return err
+	}
+	_, err = c.Namespaces.Update(toUpdate)
+	return err
+}
 
-	if updatedLimit != nil {
-		return convertPodResourceLimitToLimitRangeSpec(updatedLimit)
+// getLimitRangeSources returns the container defaults applied to the namespace and the LimitRange of its project.
+func (c *SyncController) getLimitRangeSources(ns *corev1.Namespace) (*v32.ContainerResourceLimit, *v32.ProjectLimitRange, error) {
+	nsLimit, err := getNamespaceContainerResourceLimit(ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	projectLimit, err := getProjectContainerDefaultLimit(ns, c.ProjectLister)
+	if err != nil {
+		return nil, nil, err
 	}
-	if nsLimit != nil {
-		return convertPodResourceLimitToLimitRangeSpec(nsLimit)
+	containerDefaults, err := validate.NamespaceContainerDefaults(nsLimit, projectLimit)
+	if err != nil {
+		return nil, nil, err
 	}
-	if projectLimit != nil {
-		return convertPodResourceLimitToLimitRangeSpec(projectLimit)
+	projectLimitRange, err := getProjectLimitRange(ns, c.ProjectLister)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil, nil
+	return containerDefaults, projectLimitRange, nil
 }
 
 func completeQuota(requestedQuota *v32.ResourceQuotaLimit, defaultQuota *v32.ResourceQuotaLimit) (*v32.ResourceQuotaLimit, error) {
 	if requestedQuota == nil || defaultQuota == nil {
 		return nil, nil
 	}
-	requestedQuotaMap, err := convert.EncodeToMap(requestedQuota)
+	requested := requestedQuota.DeepCopy()
+	requested.Extended = nil
+	requestedQuotaMap, err := convert.EncodeToMap(requested)
 	if err != nil {
 		return nil, err
 	}
-	newLimitMap, err := convert.EncodeToMap(defaultQuota)
+	newLimit := defaultQuota.DeepCopy()
+	newLimit.Extended = nil
+	newLimitMap, err := convert.EncodeToMap(newLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -452,51 +496,32 @@ func completeQuota(requestedQuota *v32.ResourceQuotaLimit, defaultQuota *v32.Res
 	}
 
 	toReturn := &v32.ResourceQuotaLimit{}
-	err = convert.ToObj(newLimitMap, toReturn)
-	return toReturn, err
-}
-
-func completeLimit(existingLimit *v32.ContainerResourceLimit, defaultLimit *v32.ContainerResourceLimit) (*v32.ContainerResourceLimit, error) {
-	if defaultLimit == nil {
-		return nil, nil
-	}
-	existingLimitMap, err := convert.EncodeToMap(existingLimit)
-	if err != nil {
-		return nil, err
-	}
-	newLimitMap, err := convert.EncodeToMap(defaultLimit)
-	if err != nil {
+	if err := convert.ToObj(newLimitMap, toReturn); err != nil {
 		return nil, err
 	}
-	for key, value := range existingLimitMap {
-		if _, ok := newLimitMap[key]; ok {
-			newLimitMap[key] = value
+	for key, value := range defaultQuota.Extended {
+		if toReturn.Extended == nil {
+			toReturn.Extended = map[string]string{}
+		}
+		toReturn.Extended[key] = value
+		if requestedValue, ok := requestedQuota.Extended[key]; ok {
+			toReturn.Extended[key] = requestedValue
 		}
 	}
-
-	if reflect.DeepEqual(existingLimitMap, newLimitMap) {
-		return nil, nil
-	}
-
-	newLimit := &v32.ContainerResourceLimit{}
-	err = convert.ToObj(newLimitMap, newLimit)
-	return newLimit, err
+	return toReturn, nil
 }
 
 // zeroOutResourceQuotaLimit takes a resource quota limit and a list of resources exceeding the quota,
 // and returns a new quota limit with exceeded resources zeroed out.
 func zeroOutResourceQuotaLimit(limit *v32.ResourceQuotaLimit, exceeded corev1.ResourceList) (*v32.ResourceQuotaLimit, error) {
-	limitMap, err := convert.EncodeToMap(limit)
+	resourceList, err := validate.ConvertLimitToResourceList(limit)
 	if err != nil {
 		return nil, err
 	}
 
 	for k := range exceeded {
-		resource := string(k)
-		limitMap[resource] = "0"
+		resourceList[k] = resource.MustParse("0")
 	}
 
-	toReturn := &v32.ResourceQuotaLimit{}
-	err = convert.ToObj(limitMap, toReturn)
-	return toReturn, err
+	return validate.ConvertResourceListToLimit(resourceList)
 }
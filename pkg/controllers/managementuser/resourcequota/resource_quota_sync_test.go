@@ -5,11 +5,19 @@
 	"testing"
 
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	corefakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	mgmtfakes "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3/fakes"
+	namespaceutil "github.com/rancher/rancher/pkg/namespace"
+	projectpkg "github.com/rancher/rancher/pkg/project"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestLimitsChanged(t *testing.T) {
@@ -107,3 +115,291 @@ func TestSemanticDeepEqual(t *testing.T) {
 	}
 
 }
+
+func TestConvertProjectResourceLimitToResourceListExtended(t *testing.T) {
+	limit := &v32.ResourceQuotaLimit{
+		Pods:        "30",
+		RequestsCPU: "1",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu":                           "4",
+			"gold.storageclass.storage.k8s.io/requests.storage": "10Gi",
+			"count/deployments.apps":                            "20",
+		},
+	}
+
+	resourceList, err := convertProjectResourceLimitToResourceList(limit)
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.ResourceList{
+		corev1.ResourcePods:                                 resource.MustParse("30"),
+		corev1.ResourceRequestsCPU:                          resource.MustParse("1"),
+		"requests.nvidia.com/gpu":                           resource.MustParse("4"),
+		"gold.storageclass.storage.k8s.io/requests.storage": resource.MustParse("10Gi"),
+		"count/deployments.apps":                            resource.MustParse("20"),
+	}, resourceList)
+}
+
+func TestCompleteQuotaExtended(t *testing.T) {
+	requested := &v32.ResourceQuotaLimit{
+		Pods: "5",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu": "1",
+			"count/jobs.batch":        "3",
+		},
+	}
+	defaultQuota := &v32.ResourceQuotaLimit{
+		Pods:     "10",
+		Services: "10",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu":  "2",
+			"limits.ephemeral-storage": "1Gi",
+		},
+	}
+
+	completed, err := completeQuota(requested, defaultQuota)
+	assert.NoError(t, err)
+	assert.Equal(t, &v32.ResourceQuotaLimit{
+		Pods:     "5",
+		Services: "10",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu":  "1",
+			"limits.ephemeral-storage": "1Gi",
+		},
+	}, completed)
+	// the default quota is left untouched
+	assert.Equal(t, "2", defaultQuota.Extended["requests.nvidia.com/gpu"])
+}
+
+func TestZeroOutResourceQuotaLimitExtended(t *testing.T) {
+	limit := &v32.ResourceQuotaLimit{
+		Pods:        "10",
+		RequestsCPU: "1",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu": "2",
+			"count/jobs.batch":        "3",
+		},
+	}
+	exceeded := corev1.ResourceList{
+		"pods":                    resource.MustParse("11"),
+		"requests.nvidia.com/gpu": resource.MustParse("3"),
+	}
+
+	zeroed, err := zeroOutResourceQuotaLimit(limit, exceeded)
+	assert.NoError(t, err)
+	assert.Equal(t, &v32.ResourceQuotaLimit{
+		Pods:        "0",
+		RequestsCPU: "1",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu": "0",
+			"count/jobs.batch":        "3",
+		},
+	}, zeroed)
+}
+
+func TestLimitsChangedAllItems(t *testing.T) {
+	existing := []corev1.LimitRangeItem{
+		{Type: corev1.LimitTypeContainer, Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+		{Type: corev1.LimitTypePod, Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+	}
+
+	assert.False(t, limitsChanged(existing, []corev1.LimitRangeItem{
+		{Type: corev1.LimitTypeContainer, Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1000m")}},
+		{Type: corev1.LimitTypePod, Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1024Mi")}},
+	}))
+	assert.True(t, limitsChanged(existing, []corev1.LimitRangeItem{
+		{Type: corev1.LimitTypeContainer, Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+		{Type: corev1.LimitTypePod, Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")}},
+	}))
+	assert.True(t, limitsChanged(existing, []corev1.LimitRangeItem{
+		{Type: corev1.LimitTypeContainer, Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+		{Type: corev1.LimitTypePersistentVolumeClaim, Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+	}))
+}
+
+func TestCreateLimitRangeOnNamespaceMove(t *testing.T) {
+	projects := map[string]*v32.Project{
+		"p-small": {
+			ObjectMeta: metav1.ObjectMeta{Name: "p-small", Namespace: "c-1"},
+			Spec: v32.ProjectSpec{LimitRange: &v32.ProjectLimitRange{Limits: []v32.ProjectLimitRangeItem{
+				{Type: "Pod", Max: map[string]string{"memory": "1Gi"}},
+			}}},
+		},
+		"p-large": {
+			ObjectMeta: metav1.ObjectMeta{Name: "p-large", Namespace: "c-1"},
+			Spec: v32.ProjectSpec{LimitRange: &v32.ProjectLimitRange{Limits: []v32.ProjectLimitRangeItem{
+				{Type: "Pod", Max: map[string]string{"memory": "8Gi"}},
+			}}},
+		},
+		"p-none": {ObjectMeta: metav1.ObjectMeta{Name: "p-none", Namespace: "c-1"}},
+	}
+	existing := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "default-abc", Namespace: "ns-1"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{
+			{Type: corev1.LimitTypePod, Max: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}},
+		}},
+	}
+
+	tests := []struct {
+		name       string
+		projectID  string
+		existing   *corev1.LimitRange
+		wantCreate bool
+		wantUpdate string
+		wantDelete bool
+	}{
+		{name: "unchanged", projectID: "c-1:p-small", existing: existing},
+		{name: "moved to a project with another limit range", projectID: "c-1:p-large", existing: existing, wantUpdate: "8Gi"},
+		{name: "moved to a project without limit range", projectID: "c-1:p-none", existing: existing, wantDelete: true},
+		{name: "moved out of projects", existing: existing, wantDelete: true},
+		{name: "moved into a project with a limit range", projectID: "c-1:p-small", wantCreate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limitRanges := &corefakes.LimitRangeInterfaceMock{
+				CreateFunc:           func(in *corev1.LimitRange) (*corev1.LimitRange, error) { return in, nil },
+				UpdateFunc:           func(in *corev1.LimitRange) (*corev1.LimitRange, error) { return in, nil },
+				DeleteNamespacedFunc: func(string, string, *metav1.DeleteOptions) error { return nil },
+			}
+			c := &SyncController{
+				ProjectLister: &mgmtfakes.ProjectListerMock{
+					GetFunc: func(namespace, name string) (*v32.Project, error) {
+						return projects[name], nil
+					},
+				},
+				LimitRange: limitRanges,
+				LimitRangeLister: &corefakes.LimitRangeListerMock{
+					ListFunc: func(string, labels.Selector) ([]*corev1.LimitRange, error) {
+						if tt.existing == nil {
+							return nil, nil
+						}
+						return []*corev1.LimitRange{tt.existing}, nil
+					},
+				},
+			}
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-1"}}
+			if tt.projectID != "" {
+				ns.Annotations = map[string]string{projectIDAnnotation: tt.projectID}
+			}
+
+			assert.NoError(t, c.createLimitRange(ns))
+
+			assert.Equal(t, tt.wantCreate, len(limitRanges.CreateCalls()) == 1)
+			assert.Equal(t, tt.wantDelete, len(limitRanges.DeleteNamespacedCalls()) == 1)
+			if tt.wantUpdate == "" {
+				assert.Empty(t, limitRanges.UpdateCalls())
+				return
+			}
+			require.Len(t, limitRanges.UpdateCalls(), 1)
+			updated := limitRanges.UpdateCalls()[0].In1
+			assert.Equal(t, tt.wantUpdate, updated.Spec.Limits[0].Max.Memory().String())
+		})
+	}
+}
+
+func TestCreateLimitRangeInvalid(t *testing.T) {
+	project := &v32.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: "p-1", Namespace: "c-1"},
+		Spec: v32.ProjectSpec{
+			ContainerDefaultResourceLimit: &v32.ContainerResourceLimit{LimitsCPU: "3"},
+			LimitRange: &v32.ProjectLimitRange{Limits: []v32.ProjectLimitRangeItem{
+				{Type: "Container", Max: map[string]string{"cpu": "2"}},
+			}},
+		},
+	}
+	limitRanges := &corefakes.LimitRangeInterfaceMock{}
+	namespaces := &corefakes.NamespaceInterfaceMock{
+		UpdateFunc: func(in *corev1.Namespace) (*corev1.Namespace, error) { return in, nil },
+	}
+	c := &SyncController{
+		ProjectLister: &mgmtfakes.ProjectListerMock{
+			GetFunc: func(namespace, name string) (*v32.Project, error) {
+				return project, nil
+			},
+		},
+		Namespaces: namespaces,
+		LimitRange: limitRanges,
+		LimitRangeLister: &corefakes.LimitRangeListerMock{
+			ListFunc: func(string, labels.Selector) ([]*corev1.LimitRange, error) { return nil, nil },
+		},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "ns-1",
+		Annotations: map[string]string{projectIDAnnotation: "c-1:p-1"},
+	}}
+
+	// the problem is reported once, instead of failing the sync
+	require.NoError(t, c.createLimitRange(ns))
+	assert.Empty(t, limitRanges.CreateCalls())
+	require.Len(t, namespaces.UpdateCalls(), 1)
+	ns = namespaces.UpdateCalls()[0].In1
+	invalid, err := namespaceutil.IsNamespaceConditionSet(ns, LimitRangeValidatedCondition, false)
+	require.NoError(t, err)
+	assert.True(t, invalid)
+	require.NoError(t, c.createLimitRange(ns))
+	assert.Len(t, namespaces.UpdateCalls(), 1)
+
+	// once the project is fixed, the LimitRange is created and the condition cleared
+	project.Spec.ContainerDefaultResourceLimit.LimitsCPU = "1"
+	limitRanges.CreateFunc = func(in *corev1.LimitRange) (*corev1.LimitRange, error) { return in, nil }
+	require.NoError(t, c.createLimitRange(ns))
+	assert.Len(t, limitRanges.CreateCalls(), 1)
+	require.Len(t, namespaces.UpdateCalls(), 2)
+	valid, err := namespaceutil.IsNamespaceConditionSet(namespaces.UpdateCalls()[1].In1, LimitRangeValidatedCondition, true)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestCalculateResourceQuotaUsedProjectMovedToAnotherParent(t *testing.T) {
+	newProject := func(name, pods, usedPods string) *v32.Project {
+		return &v32.Project{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "c-1"},
+			Spec: v32.ProjectSpec{ResourceQuota: &v32.ProjectResourceQuota{
+				Limit:     v32.ResourceQuotaLimit{Pods: pods},
+				UsedLimit: v32.ResourceQuotaLimit{Pods: usedPods},
+			}},
+		}
+	}
+	app := newProject("p-app", "4", "")
+	app.Spec.ParentProjectName = "p-new"
+	app.Annotations = map[string]string{
+		projectpkg.ParentProjectAnnotation: "p-new",
+		usedInProjectAnnotation:            "p-old",
+	}
+	projects := map[string]*v32.Project{
+		"p-app": app,
+		"p-old": newProject("p-old", "10", "4"),
+		"p-new": newProject("p-new", "10", ""),
+	}
+
+	updated := map[string]*v32.Project{}
+	c := &calculateLimitController{
+		projectLister: &mgmtfakes.ProjectListerMock{
+			GetFunc: func(namespace, name string) (*v32.Project, error) {
+				return projects[name], nil
+			},
+			ListFunc: func(namespace string, selector labels.Selector) ([]*v32.Project, error) {
+				return []*v32.Project{projects["p-app"], projects["p-old"], projects["p-new"]}, nil
+			},
+		},
+		projects: &mgmtfakes.ProjectInterfaceMock{
+			GetNamespacedFunc: func(namespace, name string, opts metav1.GetOptions) (*v32.Project, error) {
+				return projects[name].DeepCopy(), nil
+			},
+			UpdateFunc: func(in *v32.Project) (*v32.Project, error) {
+				updated[in.Name] = in
+				return in, nil
+			},
+		},
+		nsIndexer:   cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{nsByProjectIndex: nsByProjectID}),
+		clusterName: "c-1",
+	}
+
+	_, err := c.calculateResourceQuotaUsedProject("c-1/p-app", app)
+	require.NoError(t, err)
+
+	require.Contains(t, updated, "p-new")
+	assert.Equal(t, "4", updated["p-new"].Spec.ResourceQuota.UsedLimit.Pods)
+	require.Contains(t, updated, "p-old")
+	assert.Empty(t, updated["p-old"].Spec.ResourceQuota.UsedLimit.Pods)
+	require.Contains(t, updated, "p-app")
+	assert.Equal(t, "p-new", updated["p-app"].Annotations[usedInProjectAnnotation])
+}
@@ -0,0 +1,560 @@
+// Package secretreplication copies the source secret of SecretReplications to the selected namespaces of a downstream
+// cluster, keeps the copies in sync and removes them once their namespace is no longer selected.
+package secretreplication
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rancher/lasso/pkg/cache"
+	"github.com/rancher/lasso/pkg/controller"
+	"github.com/rancher/norman/lifecycle"
+	"github.com/rancher/norman/resource"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/types/config"
+	wcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/kv"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// ReplicationLabel is set on the copies to select them by SecretReplication.
+	ReplicationLabel = "management.cattle.io/secret-replication"
+	// ReplicationAnnotation is set on the copies to the namespace/name of their SecretReplication.
+	ReplicationAnnotation = "management.cattle.io/secret-replication"
+
+	projectIDAnnotation = "field.cattle.io/projectId"
+	localCluster        = "local"
+
+	// finalizerName is the name of the cluster scoped finalizer keeping a SecretReplication until its copies are
+	// removed from a cluster. The finalizers of a removed cluster are cleaned up with it.
+	finalizerName = "secret-replication"
+
+	copiesSyncInterval = 5 * time.Second
+)
+
+var secretReplicationResource = v3.SchemeGroupVersion.WithResource(v3.SecretReplicationResourceName)
+
+type handler struct {
+	ctx              context.Context
+	clusterName      string
+	replications     mgmtcontrollers.SecretReplicationController
+	replicationCache mgmtcontrollers.SecretReplicationCache
+	clusterCache     mgmtcontrollers.ClusterCache
+	projectCache     mgmtcontrollers.ProjectCache
+	sourceSecrets    wcorev1.SecretCache
+	namespaces       v1.NamespaceLister
+	copies           wcorev1.SecretCache
+	copiesSynced     func() bool
+	secrets          typedcorev1.SecretsGetter
+	finalizer        string
+
+	// namespaceSelection is what the selectors match of the namespaces last seen, by name
+	namespaceLock      sync.Mutex
+	namespaceSelection map[string]string
+
+	// ownerLabels are the labels of the cluster and of its projects last seen, by kind/namespace/name
+	ownerLock   sync.Mutex
+	ownerLabels map[string]map[string]string
+}
+
+// Register registers the controllers replicating secrets to the cluster. The copies are cached by a dedicated cache
+// only holding the secrets labeled as copies, since the secret cache of the cluster is limited to the impersonation
+// namespace, and written with the client of the cluster.
+func Register(ctx context.Context, cluster *config.UserContext) {
+	mgmt := cluster.Management.Wrangler
+	copiesFactory := controller.NewSharedControllerFactory(cache.NewSharedCachedFactory(cluster.ControllerFactory.SharedCacheFactory().SharedClientFactory(), &cache.SharedCacheFactoryOptions{
+		DefaultTweakList: func(opts *metav1.ListOptions) {
+			opts.LabelSelector = ReplicationLabel
+		},
+	}), nil)
+	copies := wcorev1.New(copiesFactory).Secret()
+
+	h := &handler{
+		ctx:                ctx,
+		clusterName:        cluster.ClusterName,
+		replications:       mgmt.Mgmt.SecretReplication(),
+		replicationCache:   mgmt.Mgmt.SecretReplication().Cache(),
+		clusterCache:       mgmt.Mgmt.Cluster().Cache(),
+		projectCache:       mgmt.Mgmt.Project().Cache(),
+		sourceSecrets:      mgmt.Core.Secret().Cache(),
+		namespaces:         cluster.Core.Namespaces("").Controller().Lister(),
+		copies:             copies.Cache(),
+		copiesSynced:       copies.Informer().HasSynced,
+		secrets:            cluster.K8sClient.CoreV1(),
+		finalizer:          lifecycle.ScopedFinalizerKey + finalizerName + "_" + cluster.ClusterName,
+		namespaceSelection: map[string]string{},
+		ownerLabels:        map[string]map[string]string{},
+	}
+
+	resource.PutClusterScoped(secretReplicationResource)
+	controllerName := "secret-replication-" + cluster.ClusterName
+	h.replications.OnChange(ctx, controllerName, h.sync)
+	relatedresource.Watch(ctx, controllerName, h.resolveSourceSecret, h.replications, mgmt.Core.Secret())
+	relatedresource.Watch(ctx, controllerName, h.resolveClusterOrProject("Cluster"), h.replications, mgmt.Mgmt.Cluster())
+	relatedresource.Watch(ctx, controllerName, h.resolveClusterOrProject("Project"), h.replications, mgmt.Mgmt.Project())
+	relatedresource.Watch(ctx, controllerName, resolveCopy, h.replications, copies)
+	cluster.Core.Namespaces("").AddHandler(ctx, controllerName, h.onNamespaceChange)
+
+	go func() {
+		if err := copiesFactory.Start(ctx, 1); err != nil {
+			logrus.Errorf("[secretReplication] failed to start the cache of the copies in cluster [%s]: %v", cluster.ClusterName, err)
+		}
+	}()
+}
+
+// resolveCopy enqueues the SecretReplication of a copy, so that modified copies are restored.
+func resolveCopy(_, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return nil, nil
+	}
+	namespace, name := kv.Split(secret.Annotations[ReplicationAnnotation], "/")
+	if namespace == "" || name == "" {
+		return nil, nil
+	}
+	return []relatedresource.Key{relatedresource.NewKey(namespace, name)}, nil
+}
+
+// onNamespaceChange enqueues all the SecretReplications when a namespace is created or removed, or when its labels or
+// its project change, as it may then be selected or no longer be selected by any of them.
+func (h *handler) onNamespaceChange(key string, obj *corev1.Namespace) (runtime.Object, error) {
+	if !h.namespaceSelectionChanged(key, obj) {
+		return obj, nil
+	}
+	return obj, h.enqueueAll()
+}
+
+// namespaceSelectionChanged records the labels and the project of the namespace, none if it is removed, and returns
+// whether they changed.
+func (h *handler) namespaceSelectionChanged(key string, obj *corev1.Namespace) bool {
+	h.namespaceLock.Lock()
+	defer h.namespaceLock.Unlock()
+	previous, seen := h.namespaceSelection[key]
+	if obj == nil || obj.DeletionTimestamp != nil {
+		delete(h.namespaceSelection, key)
+		return seen
+	}
+	current := labels.Set(obj.Labels).String() + ";" + obj.Annotations[projectIDAnnotation]
+	h.namespaceSelection[key] = current
+	return !seen || previous != current
+}
+
+// resolveSourceSecret enqueues the SecretReplications of a secret of the management cluster.
+func (h *handler) resolveSourceSecret(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	replications, err := h.replicationCache.List(namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var keys []relatedresource.Key
+	for _, replication := range replications {
+		if replication.Spec.SourceSecret == name {
+			keys = append(keys, relatedresource.NewKey(replication.Namespace, replication.Name))
+		}
+	}
+	return keys, nil
+}
+
+// resolveClusterOrProject returns a resolver enqueuing all the SecretReplications when the cluster, or one of its
+// projects, of the given kind is added, removed or its labels change. Other changes, like the status updates of the
+// cluster, are ignored.
+func (h *handler) resolveClusterOrProject(kind string) relatedresource.Resolver {
+	return func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		if kind == "Cluster" && name != h.clusterName || kind == "Project" && namespace != h.clusterName {
+			return nil, nil
+		}
+		if !h.ownerLabelsChanged(kind+"/"+namespace+"/"+name, obj) {
+			return nil, nil
+		}
+		return h.allKeys()
+	}
+}
+
+// ownerLabelsChanged records the labels of the cluster or project, none if it is removed, and returns whether they
+// changed.
+func (h *handler) ownerLabelsChanged(key string, obj runtime.Object) bool {
+	var (
+		current map[string]string
+		exists  bool
+	)
+	switch obj := obj.(type) {
+	case *v3.Cluster:
+		if obj != nil && obj.DeletionTimestamp == nil {
+			current, exists = obj.Labels, true
+		}
+	case *v3.Project:
+		if obj != nil && obj.DeletionTimestamp == nil {
+			current, exists = obj.Labels, true
+		}
+	}
+
+	h.ownerLock.Lock()
+	defer h.ownerLock.Unlock()
+	previous, seen := h.ownerLabels[key]
+	if !exists {
+		delete(h.ownerLabels, key)
+		return seen
+	}
+	h.ownerLabels[key] = current
+	return !seen || !labels.Equals(previous, current)
+}
+
+func (h *handler) allKeys() ([]relatedresource.Key, error) {
+	replications, err := h.replicationCache.List("", labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]relatedresource.Key, 0, len(replications))
+	for _, replication := range replications {
+		keys = append(keys, relatedresource.NewKey(replication.Namespace, replication.Name))
+	}
+	return keys, nil
+}
+
+func (h *handler) enqueueAll() error {
+	keys, err := h.allKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		h.replications.Enqueue(key.Namespace, key.Name)
+	}
+	return nil
+}
+
+func (h *handler) sync(key string, obj *v3.SecretReplication) (*v3.SecretReplication, error) {
+	namespace, name := kv.RSplit(key, "/")
+	if !h.copiesSynced() {
+		// the copies which aren't cached yet would be seen as missing
+		h.replications.EnqueueAfter(namespace, name, copiesSyncInterval)
+		return obj, nil
+	}
+
+	if obj == nil || obj.DeletionTimestamp != nil {
+		copies, err := h.listCopies(namespace, name)
+		if err != nil {
+			return obj, err
+		}
+		if err := h.deleteCopies(copies); err != nil || obj == nil {
+			return obj, err
+		}
+		return h.removeFinalizer(obj)
+	}
+
+	targets, err := h.targetNamespaces(obj)
+	if err != nil {
+		return obj, err
+	}
+	if targets.Len() > 0 {
+		// the copies are only removed from a cluster once it's known they are, even if it's disconnected
+		if obj, err = h.addFinalizer(obj); err != nil {
+			return obj, err
+		}
+	}
+	copies, err := h.listCopies(obj.Namespace, obj.Name)
+	if err != nil {
+		return obj, err
+	}
+	current := map[string]*corev1.Secret{}
+	var stale []*corev1.Secret
+	for _, secret := range copies {
+		if targets.Has(secret.Namespace) && secret.Name == targetName(obj) {
+			current[secret.Namespace] = secret
+		} else {
+			stale = append(stale, secret)
+		}
+	}
+
+	var statuses []v3.SecretReplicationTarget
+	if targets.Len() > 0 {
+		source, err := h.sourceSecrets.Get(obj.Namespace, obj.Spec.SourceSecret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return obj, err
+		}
+		for _, namespace := range sets.List(targets) {
+			status := v3.SecretReplicationTarget{
+				ClusterName: h.clusterName,
+				Namespace:   namespace,
+				State:       v3.SecretReplicationTargetSynced,
+			}
+			if source == nil {
+				// the copies are kept until the source secret is recreated or the SecretReplication is deleted
+				err = fmt.Errorf("source secret %s/%s not found", obj.Namespace, obj.Spec.SourceSecret)
+			} else {
+				err = h.ensureCopy(obj, source, namespace, current[namespace])
+			}
+			if err != nil {
+				status.State = v3.SecretReplicationTargetError
+				status.Message = err.Error()
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	if err := h.deleteCopies(stale); err != nil {
+		return obj, err
+	}
+	if targets.Len() == 0 {
+		if obj, err = h.removeFinalizer(obj); err != nil {
+			return obj, err
+		}
+	}
+	return h.updateStatus(obj, statuses)
+}
+
+func (h *handler) addFinalizer(obj *v3.SecretReplication) (*v3.SecretReplication, error) {
+	if slices.Contains(obj.Finalizers, h.finalizer) {
+		return obj, nil
+	}
+	obj = obj.DeepCopy()
+	obj.Finalizers = append(obj.Finalizers, h.finalizer)
+	return h.replications.Update(obj)
+}
+
+func (h *handler) removeFinalizer(obj *v3.SecretReplication) (*v3.SecretReplication, error) {
+	if !slices.Contains(obj.Finalizers, h.finalizer) {
+		return obj, nil
+	}
+	obj = obj.DeepCopy()
+	obj.Finalizers = slices.DeleteFunc(obj.Finalizers, func(finalizer string) bool {
+		return finalizer == h.finalizer
+	})
+	updated, err := h.replications.Update(obj)
+	if apierrors.IsNotFound(err) {
+		return obj, nil
+	}
+	return updated, err
+}
+
+// targetNamespaces returns the namespaces of the cluster selected by the SecretReplication.
+func (h *handler) targetNamespaces(obj *v3.SecretReplication) (sets.Set[string], error) {
+	targets := sets.New[string]()
+	if obj.Spec.ProjectSelector == nil && obj.Spec.NamespaceSelector == nil {
+		return targets, nil
+	}
+
+	if len(obj.Spec.ClusterSelector.MatchLabels) == 0 && len(obj.Spec.ClusterSelector.MatchExpressions) == 0 {
+		// rejected by the CRD, an empty selector selects no cluster rather than all of them
+		return targets, nil
+	}
+
+	cluster, err := h.clusterCache.Get(h.clusterName)
+	if apierrors.IsNotFound(err) {
+		return targets, nil
+	} else if err != nil {
+		return nil, err
+	}
+	clusterSelector, err := metav1.LabelSelectorAsSelector(&obj.Spec.ClusterSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster selector of SecretReplication %s/%s: %w", obj.Namespace, obj.Name, err)
+	}
+	if !clusterSelector.Matches(labels.Set(cluster.Labels)) {
+		return targets, nil
+	}
+
+	var projectIDs sets.Set[string]
+	if obj.Spec.ProjectSelector != nil {
+		projectSelector, err := metav1.LabelSelectorAsSelector(obj.Spec.ProjectSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project selector of SecretReplication %s/%s: %w", obj.Namespace, obj.Name, err)
+		}
+		projects, err := h.projectCache.List(h.clusterName, projectSelector)
+		if err != nil {
+			return nil, err
+		}
+		projectIDs = sets.New[string]()
+		for _, project := range projects {
+			projectIDs.Insert(h.clusterName + ":" + project.Name)
+		}
+	}
+
+	namespaceSelector := labels.Everything()
+	if obj.Spec.NamespaceSelector != nil {
+		namespaceSelector, err = metav1.LabelSelectorAsSelector(obj.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector of SecretReplication %s/%s: %w", obj.Namespace, obj.Name, err)
+		}
+	}
+	namespaces, err := h.namespaces.List("", namespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaces {
+		if namespace.DeletionTimestamp != nil {
+			continue
+		}
+		if projectIDs != nil && !projectIDs.Has(namespace.Annotations[projectIDAnnotation]) {
+			continue
+		}
+		if h.clusterName == localCluster && namespace.Name == obj.Namespace && targetName(obj) == obj.Spec.SourceSecret {
+			// never replicate the source secret onto itself
+			continue
+		}
+		targets.Insert(namespace.Name)
+	}
+	return targets, nil
+}
+
+// listCopies returns the copies of the SecretReplication in the cluster.
+func (h *handler) listCopies(namespace, name string) ([]*corev1.Secret, error) {
+	secrets, err := h.copies.List("", labels.SelectorFromSet(labels.Set{ReplicationLabel: replicationLabelValue(namespace, name)}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the copies of SecretReplication %s/%s: %w", namespace, name, err)
+	}
+	var copies []*corev1.Secret
+	for _, secret := range secrets {
+		if secret.Annotations[ReplicationAnnotation] == namespace+"/"+name {
+			copies = append(copies, secret)
+		}
+	}
+	return copies, nil
+}
+
+// ensureCopy creates or updates the copy of the source secret in the namespace. Secrets of the same name that are not
+// copies of the SecretReplication are left untouched.
+func (h *handler) ensureCopy(obj *v3.SecretReplication, source *corev1.Secret, namespace string, existing *corev1.Secret) error {
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      targetName(obj),
+			Namespace: namespace,
+			Labels: map[string]string{
+				ReplicationLabel: replicationLabelValue(obj.Namespace, obj.Name),
+			},
+			Annotations: map[string]string{
+				ReplicationAnnotation: obj.Namespace + "/" + obj.Name,
+			},
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+	secrets := h.secrets.Secrets(namespace)
+
+	if existing != nil && existing.Type != desired.Type {
+		// the type of a secret is immutable
+		if err := secrets.Delete(h.ctx, existing.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		existing = nil
+	}
+
+	if existing == nil {
+		_, err := secrets.Create(h.ctx, desired, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("secret %s/%s already exists and is not managed by this SecretReplication", namespace, desired.Name)
+		}
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Data, desired.Data) {
+		return nil
+	}
+	existing = existing.DeepCopy()
+	existing.Data = desired.Data
+	_, err := secrets.Update(h.ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteCopies deletes copies which are no longer selected.
+func (h *handler) deleteCopies(copies []*corev1.Secret) error {
+	for _, secret := range copies {
+		logrus.Debugf("[secretReplication] deleting copy %s/%s of SecretReplication %s from cluster [%s]", secret.Namespace, secret.Name, secret.Annotations[ReplicationAnnotation], h.clusterName)
+		if err := h.secrets.Secrets(secret.Namespace).Delete(h.ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateStatus replaces the targets of the cluster in the status of the SecretReplication, leaving the targets of the
+// other clusters untouched.
+func (h *handler) updateStatus(obj *v3.SecretReplication, statuses []v3.SecretReplicationTarget) (*v3.SecretReplication, error) {
+	if targetsEqual(clusterTargets(obj.Status.Targets, h.clusterName), statuses) {
+		return obj, nil
+	}
+
+	var updated *v3.SecretReplication
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.replications.Get(obj.Namespace, obj.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest = latest.DeepCopy()
+		latest.Status.Targets = mergeTargets(latest.Status.Targets, h.clusterName, statuses)
+		updated, err = h.replications.UpdateStatus(latest)
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return obj, nil
+	}
+	return updated, err
+}
+
+func clusterTargets(targets []v3.SecretReplicationTarget, clusterName string) []v3.SecretReplicationTarget {
+	var result []v3.SecretReplicationTarget
+	for _, target := range targets {
+		if target.ClusterName == clusterName {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+func targetsEqual(a, b []v3.SecretReplicationTarget) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeTargets replaces the targets of the cluster and sorts the targets by cluster and namespace.
+func mergeTargets(targets []v3.SecretReplicationTarget, clusterName string, statuses []v3.SecretReplicationTarget) []v3.SecretReplicationTarget {
+	var result []v3.SecretReplicationTarget
+	for _, target := range targets {
+		if target.ClusterName != clusterName {
+			result = append(result, target)
+		}
+	}
+	result = append(result, statuses...)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClusterName != result[j].ClusterName {
+			return result[i].ClusterName < result[j].ClusterName
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+func targetName(obj *v3.SecretReplication) string {
+	if obj.Spec.TargetName != "" {
+		return obj.Spec.TargetName
+	}
+	return obj.Spec.SourceSecret
+}
+
+// replicationLabelValue returns a label value identifying the SecretReplication, hashed when longer than the 63
+// characters allowed in label values. The annotation of the copies holds the unhashed namespace and name.
+func replicationLabelValue(namespace, replication string) string {
+	return name.SafeConcatName(namespace, replication)
+}
@@ -0,0 +1,384 @@
+package secretreplication
+
+import (
+	"context"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	normanFakes "github.com/rancher/rancher/pkg/generated/norman/core/v1/fakes"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const (
+	clusterName   = "c-1"
+	testFinalizer = "clusterscoped.controller.cattle.io/secret-replication_c-1"
+)
+
+func newNamespace(name, projectID string, nsLabels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      nsLabels,
+			Annotations: map[string]string{projectIDAnnotation: projectID},
+		},
+	}
+}
+
+func newCopy(namespace, name string, data string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{ReplicationLabel: replicationLabelValue("fleet-default", "registry")},
+			Annotations: map[string]string{ReplicationAnnotation: "fleet-default/registry"},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(data)},
+	}
+}
+
+func newHandler(t *testing.T, clusterLabels map[string]string, source *corev1.Secret, downstream ...*corev1.Secret) (*handler, *k8sfake.Clientset, *v3.SecretReplication) {
+	ctrl := gomock.NewController(t)
+
+	clusterCache := fake.NewMockNonNamespacedCacheInterface[*v3.Cluster](ctrl)
+	clusterCache.EXPECT().Get(clusterName).Return(&v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Labels: clusterLabels}}, nil).AnyTimes()
+
+	projects := []*v3.Project{
+		{ObjectMeta: metav1.ObjectMeta{Name: "p-prod", Namespace: clusterName, Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "p-dev", Namespace: clusterName, Labels: map[string]string{"env": "dev"}}},
+	}
+	projectCache := fake.NewMockCacheInterface[*v3.Project](ctrl)
+	projectCache.EXPECT().List(clusterName, gomock.Any()).DoAndReturn(func(namespace string, selector labels.Selector) ([]*v3.Project, error) {
+		var result []*v3.Project
+		for _, project := range projects {
+			if selector.Matches(labels.Set(project.Labels)) {
+				result = append(result, project)
+			}
+		}
+		return result, nil
+	}).AnyTimes()
+
+	sourceSecrets := fake.NewMockCacheInterface[*corev1.Secret](ctrl)
+	sourceSecrets.EXPECT().Get("fleet-default", "registry-source").DoAndReturn(func(namespace, name string) (*corev1.Secret, error) {
+		if source == nil {
+			return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+		}
+		return source, nil
+	}).AnyTimes()
+
+	namespaces := []*corev1.Namespace{
+		newNamespace("prod-a", clusterName+":p-prod", map[string]string{"team": "a"}),
+		newNamespace("prod-b", clusterName+":p-prod", map[string]string{"team": "b"}),
+		newNamespace("dev-a", clusterName+":p-dev", map[string]string{"team": "a"}),
+		newNamespace("other", "", map[string]string{"team": "a"}),
+	}
+	namespaceLister := &normanFakes.NamespaceListerMock{
+		ListFunc: func(namespace string, selector labels.Selector) ([]*corev1.Namespace, error) {
+			var result []*corev1.Namespace
+			for _, ns := range namespaces {
+				if selector.Matches(labels.Set(ns.Labels)) {
+					result = append(result, ns)
+				}
+			}
+			return result, nil
+		},
+	}
+
+	replication := &v3.SecretReplication{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "fleet-default"},
+		Spec: v3.SecretReplicationSpec{
+			SourceSecret:    "registry-source",
+			TargetName:      "registry",
+			ClusterSelector: metav1.LabelSelector{MatchLabels: map[string]string{"registry": "enabled"}},
+			ProjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+		},
+		Status: v3.SecretReplicationStatus{
+			Targets: []v3.SecretReplicationTarget{
+				{ClusterName: "c-0", Namespace: "default", State: v3.SecretReplicationTargetSynced},
+			},
+		},
+	}
+	replications := fake.NewMockControllerInterface[*v3.SecretReplication, *v3.SecretReplicationList](ctrl)
+	replications.EXPECT().Get("fleet-default", "registry", gomock.Any()).DoAndReturn(func(namespace, name string, opts metav1.GetOptions) (*v3.SecretReplication, error) {
+		return replication, nil
+	}).AnyTimes()
+	replications.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(obj *v3.SecretReplication) (*v3.SecretReplication, error) {
+		replication = obj
+		return obj, nil
+	}).AnyTimes()
+	replications.EXPECT().Update(gomock.Any()).DoAndReturn(func(obj *v3.SecretReplication) (*v3.SecretReplication, error) {
+		replication = obj
+		return obj, nil
+	}).AnyTimes()
+
+	var objs []runtime.Object
+	for _, secret := range downstream {
+		objs = append(objs, secret)
+	}
+	client := k8sfake.NewSimpleClientset(objs...)
+	copies := fake.NewMockCacheInterface[*corev1.Secret](ctrl)
+	copies.EXPECT().List("", gomock.Any()).DoAndReturn(func(_ string, selector labels.Selector) ([]*corev1.Secret, error) {
+		secrets, err := client.CoreV1().Secrets("").List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		var result []*corev1.Secret
+		for i := range secrets.Items {
+			result = append(result, &secrets.Items[i])
+		}
+		return result, nil
+	}).AnyTimes()
+
+	return &handler{
+		ctx:           context.Background(),
+		clusterName:   clusterName,
+		replications:  replications,
+		clusterCache:  clusterCache,
+		projectCache:  projectCache,
+		sourceSecrets: sourceSecrets,
+		namespaces:    namespaceLister,
+		copies:        copies,
+		copiesSynced:  func() bool { return true },
+		secrets:       client.CoreV1(),
+		finalizer:     testFinalizer,
+	}, client, replication
+}
+
+func getSecret(t *testing.T, client *k8sfake.Clientset, namespace, name string) *corev1.Secret {
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return secret
+}
+
+func TestSync(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-source", Namespace: "fleet-default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("new")},
+	}
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "prod-b"},
+		Data:       map[string][]byte{"foo": []byte("bar")},
+	}
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, source,
+		newCopy("prod-a", "registry", "old"),
+		newCopy("dev-a", "registry", "old"),
+		foreign,
+	)
+
+	_, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	prodA := getSecret(t, client, "prod-a", "registry")
+	require.NotNil(t, prodA)
+	assert.Equal(t, "new", string(prodA.Data[corev1.DockerConfigJsonKey]))
+	assert.Nil(t, getSecret(t, client, "dev-a", "registry"), "copy of a namespace that is no longer selected must be deleted")
+	assert.Equal(t, foreign.Data, getSecret(t, client, "prod-b", "registry").Data, "secrets which are not copies must be left untouched")
+
+	got, err := h.replications.Get("fleet-default", "registry", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{testFinalizer}, got.Finalizers)
+	require.Len(t, got.Status.Targets, 3)
+	assert.Equal(t, v3.SecretReplicationTarget{ClusterName: "c-0", Namespace: "default", State: v3.SecretReplicationTargetSynced}, got.Status.Targets[0])
+	assert.Equal(t, v3.SecretReplicationTarget{ClusterName: clusterName, Namespace: "prod-a", State: v3.SecretReplicationTargetSynced}, got.Status.Targets[1])
+	assert.Equal(t, clusterName, got.Status.Targets[2].ClusterName)
+	assert.Equal(t, "prod-b", got.Status.Targets[2].Namespace)
+	assert.Equal(t, v3.SecretReplicationTargetError, got.Status.Targets[2].State)
+	assert.Contains(t, got.Status.Targets[2].Message, "not managed by this SecretReplication")
+}
+
+func TestSyncNamespaceSelector(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-source", Namespace: "fleet-default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("data")},
+	}
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, source)
+	replication.Spec.ProjectSelector = nil
+	replication.Spec.NamespaceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+
+	_, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	for _, namespace := range []string{"prod-a", "dev-a", "other"} {
+		assert.NotNil(t, getSecret(t, client, namespace, "registry"), namespace)
+	}
+	assert.Nil(t, getSecret(t, client, "prod-b", "registry"))
+}
+
+func TestSyncClusterNotSelected(t *testing.T) {
+	h, client, replication := newHandler(t, nil, nil, newCopy("prod-a", "registry", "old"))
+
+	_, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	assert.Nil(t, getSecret(t, client, "prod-a", "registry"))
+	got, err := h.replications.Get("fleet-default", "registry", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []v3.SecretReplicationTarget{{ClusterName: "c-0", Namespace: "default", State: v3.SecretReplicationTargetSynced}}, got.Status.Targets)
+}
+
+func TestSyncClusterNoLongerSelectedRemovesFinalizer(t *testing.T) {
+	h, client, replication := newHandler(t, nil, nil, newCopy("prod-a", "registry", "old"))
+	replication.Finalizers = []string{"other", testFinalizer}
+
+	got, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	assert.Nil(t, getSecret(t, client, "prod-a", "registry"))
+	assert.Equal(t, []string{"other"}, got.Finalizers)
+}
+
+func TestSyncEmptyClusterSelector(t *testing.T) {
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, nil, newCopy("prod-a", "registry", "old"))
+	replication.Spec.ClusterSelector = metav1.LabelSelector{}
+
+	got, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	assert.Nil(t, getSecret(t, client, "prod-a", "registry"), "an empty cluster selector selects no cluster")
+	assert.Empty(t, got.Finalizers)
+}
+
+func TestSyncWaitsForCopiesCache(t *testing.T) {
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, nil)
+	h.copiesSynced = func() bool { return false }
+	h.replications.(*fake.MockControllerInterface[*v3.SecretReplication, *v3.SecretReplicationList]).EXPECT().
+		EnqueueAfter("fleet-default", "registry", copiesSyncInterval)
+
+	_, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	secrets, err := client.CoreV1().Secrets("").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, secrets.Items)
+}
+
+func TestSyncSourceNotFound(t *testing.T) {
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, nil, newCopy("prod-a", "registry", "old"))
+
+	_, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	prodA := getSecret(t, client, "prod-a", "registry")
+	require.NotNil(t, prodA, "copies are kept while the source secret is missing")
+	assert.Equal(t, "old", string(prodA.Data[corev1.DockerConfigJsonKey]))
+
+	got, err := h.replications.Get("fleet-default", "registry", metav1.GetOptions{})
+	require.NoError(t, err)
+	for _, target := range got.Status.Targets[1:] {
+		assert.Equal(t, v3.SecretReplicationTargetError, target.State)
+		assert.Contains(t, target.Message, "source secret fleet-default/registry-source not found")
+	}
+}
+
+func TestSyncRemoved(t *testing.T) {
+	other := newCopy("prod-a", "other", "data")
+	other.Labels[ReplicationLabel] = replicationLabelValue("fleet-default", "other")
+	other.Annotations[ReplicationAnnotation] = "fleet-default/other"
+	h, client, _ := newHandler(t, map[string]string{"registry": "enabled"}, nil,
+		newCopy("prod-a", "registry", "data"),
+		newCopy("prod-b", "registry", "data"),
+		other,
+	)
+
+	_, err := h.sync("fleet-default/registry", nil)
+	require.NoError(t, err)
+
+	assert.Nil(t, getSecret(t, client, "prod-a", "registry"))
+	assert.Nil(t, getSecret(t, client, "prod-b", "registry"))
+	assert.NotNil(t, getSecret(t, client, "prod-a", "other"), "copies of other SecretReplications must be kept")
+}
+
+func TestSyncDeleting(t *testing.T) {
+	h, client, replication := newHandler(t, map[string]string{"registry": "enabled"}, nil,
+		newCopy("prod-a", "registry", "data"),
+	)
+	now := metav1.Now()
+	replication.DeletionTimestamp = &now
+	replication.Finalizers = []string{testFinalizer}
+
+	got, err := h.sync("fleet-default/registry", replication.DeepCopy())
+	require.NoError(t, err)
+
+	assert.Nil(t, getSecret(t, client, "prod-a", "registry"))
+	assert.Empty(t, got.Finalizers)
+}
+
+func TestNamespaceSelectionChanged(t *testing.T) {
+	h := &handler{namespaceSelection: map[string]string{}}
+	namespace := newNamespace("prod-a", clusterName+":p-prod", map[string]string{"team": "a"})
+
+	assert.True(t, h.namespaceSelectionChanged("prod-a", namespace), "created")
+	unchanged := namespace.DeepCopy()
+	unchanged.Annotations["other"] = "value"
+	assert.False(t, h.namespaceSelectionChanged("prod-a", unchanged), "unrelated annotation")
+
+	moved := unchanged.DeepCopy()
+	moved.Annotations[projectIDAnnotation] = clusterName + ":p-dev"
+	assert.True(t, h.namespaceSelectionChanged("prod-a", moved), "project changed")
+
+	relabeled := moved.DeepCopy()
+	relabeled.Labels["team"] = "b"
+	assert.True(t, h.namespaceSelectionChanged("prod-a", relabeled), "labels changed")
+
+	assert.True(t, h.namespaceSelectionChanged("prod-a", nil), "removed")
+	assert.False(t, h.namespaceSelectionChanged("prod-a", nil), "already removed")
+}
+
+func TestResolveClusterOrProject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	replications := fake.NewMockCacheInterface[*v3.SecretReplication](ctrl)
+	replications.EXPECT().List("", labels.Everything()).Return([]*v3.SecretReplication{
+		{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "fleet-default"}},
+	}, nil).AnyTimes()
+	h := &handler{clusterName: clusterName, replicationCache: replications, ownerLabels: map[string]map[string]string{}}
+	resolveCluster := h.resolveClusterOrProject("Cluster")
+	resolveProject := h.resolveClusterOrProject("Project")
+
+	cluster := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Labels: map[string]string{"env": "prod"}}}
+	keys, err := resolveCluster("", clusterName, cluster)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "added")
+
+	updated := cluster.DeepCopy()
+	updated.Status.Conditions = []v3.ClusterCondition{{Type: "Ready", Status: corev1.ConditionTrue}}
+	keys, err = resolveCluster("", clusterName, updated)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "status update")
+
+	relabeled := updated.DeepCopy()
+	relabeled.Labels["env"] = "dev"
+	keys, err = resolveCluster("", clusterName, relabeled)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "labels changed")
+
+	other := &v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-2"}}
+	keys, err = resolveCluster("", "c-2", other)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "other cluster")
+
+	project := &v3.Project{ObjectMeta: metav1.ObjectMeta{Name: "p-prod", Namespace: clusterName}}
+	keys, err = resolveProject(clusterName, "p-prod", project)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "project added")
+	keys, err = resolveProject(clusterName, "p-prod", nil)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1, "project removed")
+	keys, err = resolveProject(clusterName, "p-prod", nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys, "project already removed")
+}
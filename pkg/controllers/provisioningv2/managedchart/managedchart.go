@@ -6,9 +6,12 @@
 	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"fmt"
 	"io"
+	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
@@ -16,13 +19,18 @@
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/catalogv2/content"
+	"github.com/rancher/rancher/pkg/egressproxy"
 	fleetcontrollers "github.com/rancher/rancher/pkg/generated/controllers/fleet.cattle.io/v1alpha1"
 	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/rancher/wrangler/v3/pkg/data"
 	"github.com/rancher/wrangler/v3/pkg/name"
 	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -30,16 +38,24 @@
 	chartByRepo = "chartByRepo"
 )
 
+// clusterValuesCondition reports whether the ClusterValues of a ManagedChart apply to their clusters.
+var clusterValuesCondition = condition.Cond("ClusterValues")
+
 func Register(ctx context.Context, clients *wrangler.Context) {
 	h := &handler{
 		charts: content.NewManager(
 			clients.K8s.Discovery(),
 			clients.Core.ConfigMap().Cache(),
 			clients.Core.Secret().Cache(),
-			clients.Catalog.ClusterRepo().Cache()),
-		mccCache:      clients.Mgmt.ManagedChart().Cache(),
-		mccController: clients.Mgmt.ManagedChart(),
-		bundleCache:   clients.Fleet.Bundle().Cache(),
+			clients.Catalog.ClusterRepo().Cache(),
+			egressproxy.NewResolver(clients.Core.Secret().Cache()).ClusterProxyFunc(clients.Mgmt.Cluster().Cache(), "local")),
+		mccCache:          clients.Mgmt.ManagedChart().Cache(),
+		mccController:     clients.Mgmt.ManagedChart(),
+		bundleCache:       clients.Fleet.Bundle().Cache(),
+		bdCache:           clients.Fleet.BundleDeployment().Cache(),
+		clusterCache:      clients.Fleet.Cluster().Cache(),
+		clusterGroupCache: clients.Fleet.ClusterGroup().Cache(),
+		targeted:          map[string]targeting{},
 	}
 
 	clients.Catalog.ClusterRepo().OnChange(ctx, "mcc-repo", h.OnRepoChange)
@@ -48,6 +64,21 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 		relatedresource.OwnerResolver(true, v3.SchemeGroupVersion.String(), "ManagedChart"),
 		clients.Mgmt.ManagedChart(),
 		clients.Fleet.Bundle())
+	relatedresource.Watch(ctx,
+		"mcc-from-bundledeployment-trigger",
+		h.resolveBundleDeployment,
+		clients.Mgmt.ManagedChart(),
+		clients.Fleet.BundleDeployment())
+	relatedresource.Watch(ctx,
+		"mcc-from-cluster-trigger",
+		h.resolveClusterValues("Cluster"),
+		clients.Mgmt.ManagedChart(),
+		clients.Fleet.Cluster())
+	relatedresource.Watch(ctx,
+		"mcc-from-clustergroup-trigger",
+		h.resolveClusterValues("ClusterGroup"),
+		clients.Mgmt.ManagedChart(),
+		clients.Fleet.ClusterGroup())
 	mgmtcontrollers.RegisterManagedChartGeneratingHandler(ctx,
 		clients.Mgmt.ManagedChart(),
 		clients.Apply.
@@ -65,10 +96,110 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 }
 
 type handler struct {
-	charts        *content.Manager
-	mccCache      mgmtcontrollers.ManagedChartCache
-	mccController mgmtcontrollers.ManagedChartController
-	bundleCache   fleetcontrollers.BundleCache
+	charts            *content.Manager
+	mccCache          mgmtcontrollers.ManagedChartCache
+	mccController     mgmtcontrollers.ManagedChartController
+	bundleCache       fleetcontrollers.BundleCache
+	bdCache           fleetcontrollers.BundleDeploymentCache
+	clusterCache      fleetcontrollers.ClusterCache
+	clusterGroupCache fleetcontrollers.ClusterGroupCache
+
+	// targeted is what the targets match in the Fleet clusters and cluster groups last seen, by kind, namespace and name
+	targetedLock sync.Mutex
+	targeted     map[string]targeting
+}
+
+// targeting is what the targets of a ManagedChart match in a Fleet cluster or cluster group: their labels, and the
+// selector of the clusters of a group.
+type targeting struct {
+	labels   map[string]string
+	selector *metav1.LabelSelector
+}
+
+// resolveBundleDeployment enqueues the ManagedChart owning the bundle of a BundleDeployment, so that the
+// per-cluster status of the ManagedChart follows the state of the release in every cluster.
+func (h *handler) resolveBundleDeployment(_, _ string, obj runtime.Object) ([]relatedresource.Key, error) {
+	bd, ok := obj.(*v1alpha1.BundleDeployment)
+	if !ok {
+		return nil, nil
+	}
+
+	bundleName, bundleNamespace := bd.Labels[v1alpha1.BundleLabel], bd.Labels[v1alpha1.BundleNamespaceLabel]
+	if bundleName == "" || bundleNamespace == "" {
+		return nil, nil
+	}
+
+	bundle, err := h.bundleCache.Get(bundleNamespace, bundleName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, owner := range bundle.OwnerReferences {
+		if owner.APIVersion == v3.SchemeGroupVersion.String() && owner.Kind == "ManagedChart" {
+			return []relatedresource.Key{{
+				Namespace: bundle.Namespace,
+				Name:      owner.Name,
+			}}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// resolveClusterValues returns a resolver enqueuing the ManagedCharts having ClusterValues in the namespace of a Fleet
+// cluster or cluster group of the given kind, when it is added, removed or what the targets match in it changes. The
+// targets the clusters match depend on the labels of both, while other changes, like the status updates of the
+// clusters, are ignored.
+func (h *handler) resolveClusterValues(kind string) relatedresource.Resolver {
+	return func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		if !h.targetingChanged(kind+"/"+namespace+"/"+name, obj) {
+			return nil, nil
+		}
+
+		mccs, err := h.mccCache.List(namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []relatedresource.Key
+		for _, mcc := range mccs {
+			if len(mcc.Spec.ClusterValues) > 0 {
+				keys = append(keys, relatedresource.Key{Namespace: mcc.Namespace, Name: mcc.Name})
+			}
+		}
+		return keys, nil
+	}
+}
+
+// targetingChanged records what the targets match in the Fleet cluster or cluster group, nothing if it is removed, and
+// returns whether it changed.
+func (h *handler) targetingChanged(key string, obj runtime.Object) bool {
+	var (
+		current targeting
+		exists  bool
+	)
+	switch obj := obj.(type) {
+	case *v1alpha1.Cluster:
+		if obj != nil && obj.DeletionTimestamp == nil {
+			current, exists = targeting{labels: obj.Labels}, true
+		}
+	case *v1alpha1.ClusterGroup:
+		if obj != nil && obj.DeletionTimestamp == nil {
+			current, exists = targeting{labels: obj.Labels, selector: obj.Spec.Selector}, true
+		}
+	}
+
+	h.targetedLock.Lock()
+	defer h.targetedLock.Unlock()
+	previous, seen := h.targeted[key]
+	if !exists {
+		delete(h.targeted, key)
+		return seen
+	}
+	h.targeted[key] = current
+	return !seen || !reflect.DeepEqual(previous, current)
 }
 
 func (h *handler) OnRepoChange(key string, _ *v1.ClusterRepo) (*v1.ClusterRepo, error) {
@@ -118,6 +249,23 @@ func (h *handler) OnChange(mcc *v3.ManagedChart, status v3.ManagedChartStatus) (
 		},
 	}
 
+	var clusterValuesErr error
+	if len(mcc.Spec.ClusterValues) > 0 {
+		targets, ignored, err := h.clusterValuesTargets(mcc)
+		if err != nil {
+			return nil, status, err
+		}
+		if len(targets) > 0 {
+			// Fleet picks the first target matching a cluster, so the per-cluster targets come first. The
+			// targets of the spec are turned into restrictions so that overrides can't widen the targeted clusters.
+			bundle.Spec.Targets = append(targets, mcc.Spec.Targets...)
+			bundle.Spec.TargetRestrictions = targetRestrictions(mcc.Spec.Targets)
+		}
+		if len(ignored) > 0 {
+			clusterValuesErr = fmt.Errorf("clusterValues of clusters %v ignored: not selected by the targets", ignored)
+		}
+	}
+
 	gz, err := gzip.NewReader(chart)
 	if err != nil {
 		return nil, status, err
@@ -158,6 +306,9 @@ func (h *handler) OnChange(mcc *v3.ManagedChart, status v3.ManagedChartStatus) (
 	})
 
 	status, err = h.updateStatus(status, bundle)
+	if len(mcc.Spec.ClusterValues) > 0 {
+		clusterValuesCondition.SetError(&status, "", clusterValuesErr)
+	}
 	return []runtime.Object{
 		bundle,
 	}, status, err
@@ -172,5 +323,182 @@ func (h *handler) updateStatus(status v3.ManagedChartStatus, bundle *v1alpha1.Bu
 	}
 
 	status.BundleStatus = bundle.Status
+
+	bds, err := h.bdCache.List("", labels.SelectorFromSet(labels.Set{
+		v1alpha1.BundleLabel:          bundle.Name,
+		v1alpha1.BundleNamespaceLabel: bundle.Namespace,
+	}))
+	if err != nil {
+		return status, err
+	}
+	status.Clusters = clusterStatuses(bds)
+
 	return status, nil
 }
+
+// clusterValuesTargets returns a target per cluster having its own Helm values, sorted by cluster name. Each is a copy
+// of the first target of the spec matching the cluster, with the values of the cluster merged into its own, so that
+// the cluster keeps the options it was targeted with. The clusters matching no target are returned as ignored.
+func (h *handler) clusterValuesTargets(mcc *v3.ManagedChart) ([]v1alpha1.BundleTarget, []string, error) {
+	clusterNames := make([]string, 0, len(mcc.Spec.ClusterValues))
+	for clusterName := range mcc.Spec.ClusterValues {
+		clusterNames = append(clusterNames, clusterName)
+	}
+	sort.Strings(clusterNames)
+
+	groups, err := h.clusterGroupCache.List(mcc.Namespace, labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		targets []v1alpha1.BundleTarget
+		ignored []string
+	)
+	for _, clusterName := range clusterNames {
+		cluster, err := h.clusterCache.Get(mcc.Namespace, clusterName)
+		if apierrors.IsNotFound(err) {
+			ignored = append(ignored, clusterName)
+			continue
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		target, err := matchingTarget(mcc.Spec.Targets, cluster, groups)
+		if err != nil {
+			return nil, nil, err
+		}
+		if target == nil {
+			ignored = append(ignored, clusterName)
+			continue
+		}
+		targets = append(targets, clusterValuesTarget(target, clusterName, mcc.Spec.ClusterValues[clusterName]))
+	}
+	return targets, ignored, nil
+}
+
+// clusterValuesTarget returns a copy of the target limited to the cluster, with the values of the cluster merged
+// into the Helm values of the target.
+func clusterValuesTarget(target *v1alpha1.BundleTarget, clusterName string, values *v1alpha1.GenericMap) v1alpha1.BundleTarget {
+	result := target.DeepCopy()
+	result.Name = "cluster-values-" + clusterName
+	result.ClusterName = clusterName
+	result.ClusterSelector = nil
+	result.ClusterGroup = ""
+	result.ClusterGroupSelector = nil
+
+	if result.Helm == nil {
+		result.Helm = &v1alpha1.HelmOptions{}
+	}
+	var base, overlay map[string]interface{}
+	if result.Helm.Values != nil {
+		base = result.Helm.Values.Data
+	}
+	if values != nil {
+		overlay = values.DeepCopy().Data
+	}
+	result.Helm.Values = &v1alpha1.GenericMap{Data: data.MergeMaps(base, overlay)}
+	return *result
+}
+
+// matchingTarget returns the first target matching the cluster the way Fleet does: every criteria set on the
+// target must match the cluster or one of the groups it belongs to. It returns nil if no target matches.
+func matchingTarget(targets []v1alpha1.BundleTarget, cluster *v1alpha1.Cluster, groups []*v1alpha1.ClusterGroup) (*v1alpha1.BundleTarget, error) {
+	clusterLabels := labels.Set(cluster.Labels)
+
+	var clusterGroups []*v1alpha1.ClusterGroup
+	for _, group := range groups {
+		if group.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(group.Spec.Selector)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(clusterLabels) {
+			clusterGroups = append(clusterGroups, group)
+		}
+	}
+
+	for i := range targets {
+		target := &targets[i]
+		if target.ClusterName == "" && target.ClusterSelector == nil && target.ClusterGroup == "" && target.ClusterGroupSelector == nil {
+			continue
+		}
+		if target.ClusterName != "" && target.ClusterName != cluster.Name {
+			continue
+		}
+		if target.ClusterSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(target.ClusterSelector)
+			if err != nil {
+				return nil, err
+			}
+			if !selector.Matches(clusterLabels) {
+				continue
+			}
+		}
+		if target.ClusterGroup == "" && target.ClusterGroupSelector == nil {
+			return target, nil
+		}
+		for _, group := range clusterGroups {
+			if target.ClusterGroup != "" && target.ClusterGroup != group.Name {
+				continue
+			}
+			if target.ClusterGroupSelector != nil {
+				selector, err := metav1.LabelSelectorAsSelector(target.ClusterGroupSelector)
+				if err != nil {
+					return nil, err
+				}
+				if !selector.Matches(labels.Set(group.Labels)) {
+					continue
+				}
+			}
+			return target, nil
+		}
+	}
+	return nil, nil
+}
+
+// targetRestrictions returns the restrictions limiting the clusters of a bundle to the ones matched by the targets.
+func targetRestrictions(targets []v1alpha1.BundleTarget) []v1alpha1.BundleTargetRestriction {
+	restrictions := make([]v1alpha1.BundleTargetRestriction, 0, len(targets))
+	for _, target := range targets {
+		restrictions = append(restrictions, v1alpha1.BundleTargetRestriction{
+			Name:                 target.Name,
+			ClusterName:          target.ClusterName,
+			ClusterSelector:      target.ClusterSelector,
+			ClusterGroup:         target.ClusterGroup,
+			ClusterGroupSelector: target.ClusterGroupSelector,
+		})
+	}
+	return restrictions
+}
+
+// clusterStatuses returns the state of the release of every BundleDeployment, sorted by cluster.
+func clusterStatuses(bds []*v1alpha1.BundleDeployment) []v3.ManagedChartClusterStatus {
+	var result []v3.ManagedChartClusterStatus
+	for _, bd := range bds {
+		clusterStatus := v3.ManagedChartClusterStatus{
+			ClusterName:      bd.Labels[v1alpha1.ClusterLabel],
+			ClusterNamespace: bd.Labels[v1alpha1.ClusterNamespaceLabel],
+			Release:          bd.Status.Release,
+			State:            bd.Status.Display.State,
+			Ready:            bd.Status.Ready,
+		}
+		for _, cond := range bd.Status.Conditions {
+			if cond.Status == corev1.ConditionFalse && cond.Message != "" {
+				clusterStatus.Message = cond.Message
+				break
+			}
+		}
+		result = append(result, clusterStatus)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClusterNamespace != result[j].ClusterNamespace {
+			return result[i].ClusterNamespace < result[j].ClusterNamespace
+		}
+		return result[i].ClusterName < result[j].ClusterName
+	})
+	return result
+}
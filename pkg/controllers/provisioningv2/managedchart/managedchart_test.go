@@ -0,0 +1,241 @@
+package managedchart
+
+import (
+	"testing"
+
+	"github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClusterValuesTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	clusters := fake.NewMockCacheInterface[*v1alpha1.Cluster](ctrl)
+	clusterGroups := fake.NewMockCacheInterface[*v1alpha1.ClusterGroup](ctrl)
+	h := &handler{clusterCache: clusters, clusterGroupCache: clusterGroups}
+
+	clusterGroups.EXPECT().List("fleet-default", gomock.Any()).Return([]*v1alpha1.ClusterGroup{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "fleet-default"},
+			Spec:       v1alpha1.ClusterGroupSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"site": "edge"}}},
+		},
+	}, nil)
+	for _, cluster := range []*v1alpha1.Cluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-1", Namespace: "fleet-default", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-2", Namespace: "fleet-default", Labels: map[string]string{"env": "dev", "site": "edge"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c-3", Namespace: "fleet-default", Labels: map[string]string{"env": "dev"}}},
+	} {
+		clusters.EXPECT().Get("fleet-default", cluster.Name).Return(cluster, nil)
+	}
+	clusters.EXPECT().Get("fleet-default", "c-4").Return(nil, apierrors.NewNotFound(schema.GroupResource{}, "c-4"))
+
+	mcc := &v3.ManagedChart{
+		ObjectMeta: metav1.ObjectMeta{Name: "chart", Namespace: "fleet-default"},
+		Spec: v3.ManagedChartSpec{
+			Targets: []v1alpha1.BundleTarget{
+				{
+					Name:            "prod",
+					ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+					BundleDeploymentOptions: v1alpha1.BundleDeploymentOptions{
+						DefaultNamespace: "prod",
+						Helm: &v1alpha1.HelmOptions{
+							Values: &v1alpha1.GenericMap{Data: map[string]interface{}{
+								"image": map[string]interface{}{"tag": "stable", "pullPolicy": "Always"},
+							}},
+						},
+					},
+				},
+				{
+					Name:         "edge",
+					ClusterGroup: "edge",
+				},
+			},
+			ClusterValues: map[string]*v1alpha1.GenericMap{
+				"c-2": {Data: map[string]interface{}{"replicas": 2}},
+				"c-1": {Data: map[string]interface{}{"image": map[string]interface{}{"tag": "canary"}}},
+				"c-3": {Data: map[string]interface{}{"replicas": 3}},
+				"c-4": {Data: map[string]interface{}{"replicas": 4}},
+			},
+		},
+	}
+
+	targets, ignored, err := h.clusterValuesTargets(mcc)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"c-3", "c-4"}, ignored)
+	require.Len(t, targets, 2)
+	assert.Equal(t, v1alpha1.BundleTarget{
+		Name:        "cluster-values-c-1",
+		ClusterName: "c-1",
+		BundleDeploymentOptions: v1alpha1.BundleDeploymentOptions{
+			DefaultNamespace: "prod",
+			Helm: &v1alpha1.HelmOptions{
+				Values: &v1alpha1.GenericMap{Data: map[string]interface{}{
+					"image": map[string]interface{}{"tag": "canary", "pullPolicy": "Always"},
+				}},
+			},
+		},
+	}, targets[0])
+	assert.Equal(t, v1alpha1.BundleTarget{
+		Name:        "cluster-values-c-2",
+		ClusterName: "c-2",
+		BundleDeploymentOptions: v1alpha1.BundleDeploymentOptions{
+			Helm: &v1alpha1.HelmOptions{
+				Values: &v1alpha1.GenericMap{Data: map[string]interface{}{"replicas": 2}},
+			},
+		},
+	}, targets[1])
+	// the targets of the spec are left untouched
+	assert.Equal(t, "stable", mcc.Spec.Targets[0].Helm.Values.Data["image"].(map[string]interface{})["tag"])
+}
+
+func TestClusterValuesTargetsWithoutTargets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	clusters := fake.NewMockCacheInterface[*v1alpha1.Cluster](ctrl)
+	clusterGroups := fake.NewMockCacheInterface[*v1alpha1.ClusterGroup](ctrl)
+	h := &handler{clusterCache: clusters, clusterGroupCache: clusterGroups}
+
+	clusterGroups.EXPECT().List("fleet-default", gomock.Any()).Return(nil, nil)
+	clusters.EXPECT().Get("fleet-default", "c-1").Return(&v1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1", Namespace: "fleet-default"}}, nil)
+
+	targets, ignored, err := h.clusterValuesTargets(&v3.ManagedChart{
+		ObjectMeta: metav1.ObjectMeta{Name: "chart", Namespace: "fleet-default"},
+		Spec: v3.ManagedChartSpec{
+			ClusterValues: map[string]*v1alpha1.GenericMap{"c-1": {Data: map[string]interface{}{"replicas": 1}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, targets)
+	assert.Equal(t, []string{"c-1"}, ignored)
+}
+
+func TestResolveClusterValues(t *testing.T) {
+	mccCache := fake.NewMockCacheInterface[*v3.ManagedChart](gomock.NewController(t))
+	mccCache.EXPECT().List("fleet-default", gomock.Any()).Return([]*v3.ManagedChart{
+		{ObjectMeta: metav1.ObjectMeta{Name: "with-values", Namespace: "fleet-default"}, Spec: v3.ManagedChartSpec{
+			ClusterValues: map[string]*v1alpha1.GenericMap{"c-1": {Data: map[string]interface{}{"replicas": 2}}},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "without-values", Namespace: "fleet-default"}},
+	}, nil).AnyTimes()
+	h := &handler{mccCache: mccCache, targeted: map[string]targeting{}}
+	resolveCluster := h.resolveClusterValues("Cluster")
+	resolveGroup := h.resolveClusterValues("ClusterGroup")
+	enqueued := []relatedresource.Key{{Namespace: "fleet-default", Name: "with-values"}}
+
+	cluster := &v1alpha1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "c-1", Namespace: "fleet-default", Labels: map[string]string{"env": "dev"}}}
+	keys, err := resolveCluster("fleet-default", "c-1", cluster)
+	require.NoError(t, err)
+	assert.Equal(t, enqueued, keys, "a new cluster is resolved")
+
+	// a status update doesn't change the targeted clusters
+	cluster = cluster.DeepCopy()
+	cluster.Status.Summary.Ready = 1
+	keys, err = resolveCluster("fleet-default", "c-1", cluster)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	cluster = cluster.DeepCopy()
+	cluster.Labels["env"] = "prod"
+	keys, err = resolveCluster("fleet-default", "c-1", cluster)
+	require.NoError(t, err)
+	assert.Equal(t, enqueued, keys, "a label change is resolved")
+
+	// a group with the same name is tracked apart from the cluster
+	group := &v1alpha1.ClusterGroup{ObjectMeta: metav1.ObjectMeta{Name: "c-1", Namespace: "fleet-default"}}
+	keys, err = resolveGroup("fleet-default", "c-1", group)
+	require.NoError(t, err)
+	assert.Equal(t, enqueued, keys)
+	group = group.DeepCopy()
+	group.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	keys, err = resolveGroup("fleet-default", "c-1", group)
+	require.NoError(t, err)
+	assert.Equal(t, enqueued, keys, "a selector change is resolved")
+
+	keys, err = resolveCluster("fleet-default", "c-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, enqueued, keys, "a removed cluster is resolved")
+	keys, err = resolveCluster("fleet-default", "c-1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestTargetRestrictions(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	targets := []v1alpha1.BundleTarget{
+		{
+			Name:            "prod",
+			ClusterSelector: selector,
+			BundleDeploymentOptions: v1alpha1.BundleDeploymentOptions{
+				DefaultNamespace: "ignored",
+			},
+		},
+		{
+			ClusterName: "local",
+		},
+	}
+
+	assert.Equal(t, []v1alpha1.BundleTargetRestriction{
+		{Name: "prod", ClusterSelector: selector},
+		{ClusterName: "local"},
+	}, targetRestrictions(targets))
+}
+
+func TestClusterStatuses(t *testing.T) {
+	bds := []*v1alpha1.BundleDeployment{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha1.ClusterLabel:          "c-2",
+					v1alpha1.ClusterNamespaceLabel: "fleet-default",
+				},
+			},
+			Status: v1alpha1.BundleDeploymentStatus{
+				Release: "cattle-system/chart",
+				Display: v1alpha1.BundleDeploymentDisplay{State: "ErrApplied"},
+				Conditions: []genericcondition.GenericCondition{
+					{Type: "Ready", Status: corev1.ConditionTrue, Message: "ignored"},
+					{Type: "Deployed", Status: corev1.ConditionFalse, Message: "install failed"},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1alpha1.ClusterLabel:          "c-1",
+					v1alpha1.ClusterNamespaceLabel: "fleet-default",
+				},
+			},
+			Status: v1alpha1.BundleDeploymentStatus{
+				Release: "cattle-system/chart",
+				Ready:   true,
+				Display: v1alpha1.BundleDeploymentDisplay{State: "Ready"},
+			},
+		},
+	}
+
+	assert.Equal(t, []v3.ManagedChartClusterStatus{
+		{
+			ClusterName:      "c-1",
+			ClusterNamespace: "fleet-default",
+			Release:          "cattle-system/chart",
+			State:            "Ready",
+			Ready:            true,
+		},
+		{
+			ClusterName:      "c-2",
+			ClusterNamespace: "fleet-default",
+			Release:          "cattle-system/chart",
+			State:            "ErrApplied",
+			Message:          "install failed",
+		},
+	}, clusterStatuses(bds))
+}
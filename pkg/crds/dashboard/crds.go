@@ -2,6 +2,7 @@
 
 import (
 	"context"
+	"fmt"
 
 	fleetv1alpha1api "github.com/rancher/fleet/pkg/apis/fleet.cattle.io/v1alpha1"
 	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
@@ -14,6 +15,8 @@
 	"github.com/rancher/wrangler/v3/pkg/apply"
 	"github.com/rancher/wrangler/v3/pkg/crd"
 	"github.com/rancher/wrangler/v3/pkg/generated/controllers/apiextensions.k8s.io"
+	"github.com/rancher/wrangler/v3/pkg/schemas/openapi"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -123,6 +126,59 @@ func List(cfg *rest.Config) (_ []crd.CRD, err error) {
 		}
 	}
 
+	if features.MCM.Enabled() {
+		secretReplication, err := requireClusterSelector(newCRD(&v3.SecretReplication{}, func(c crd.CRD) crd.CRD {
+			c.GVK.Kind = "SecretReplication"
+			c.GVK.Group = "management.cattle.io"
+			c.GVK.Version = "v3"
+			return c.
+				WithStatus().
+				WithColumn("Source Secret", ".spec.sourceSecret")
+		}))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, secretReplication)
+		// the spec of a request can't change once an approver may have looked at it
+		accessRequest, err := immutableSpec(newCRD(&v3.AccessRequest{}, func(c crd.CRD) crd.CRD {
+			c.GVK.Kind = "AccessRequest"
+			c.GVK.Group = "management.cattle.io"
+			c.GVK.Version = "v3"
+			return c.
+				WithStatus().
+				WithColumn("User", ".spec.userName").
+				WithColumn("Role", ".spec.roleTemplateName").
+				WithColumn("State", ".status.state")
+		}))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, accessRequest)
+		bindingReview, err := immutableSpec(newCRD(&v3.BindingReview{}, func(c crd.CRD) crd.CRD {
+			c.GVK.Kind = "BindingReview"
+			c.GVK.Group = "management.cattle.io"
+			c.GVK.Version = "v3"
+			return c.
+				WithStatus().
+				WithColumn("Kind", ".spec.bindingKind").
+				WithColumn("Binding", ".spec.bindingName").
+				WithColumn("User", ".spec.userName").
+				WithColumn("Deadline", ".spec.deadline").
+				WithColumn("State", ".status.state")
+		}))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, bindingReview)
+		result = append(result, newCRD(&v3.ProjectMembershipPolicy{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithStatus().
+				WithColumn("Cluster", ".spec.clusterName").
+				WithColumn("Bindings", ".status.bindings").
+				WithColumn("Message", ".status.message")
+		}))
+	}
+
 	if features.ProvisioningV2.Enabled() {
 		result = append(result, provisioningv2.List()...)
 	}
@@ -221,3 +277,43 @@ func newCRD(obj interface{}, customize func(crd.CRD) crd.CRD) crd.CRD {
 	}
 	return crd
 }
+
+// immutableSpec replaces the schema generated from the type of the CRD by one rejecting any update of the spec.
+func immutableSpec(c crd.CRD) (crd.CRD, error) {
+	schema, err := openapi.ToOpenAPIFromStruct(c.SchemaObject)
+	if err != nil {
+		return c, err
+	}
+	spec, ok := schema.Properties["spec"]
+	if !ok {
+		return c, fmt.Errorf("%s has no spec", c.GVK.Kind)
+	}
+	spec.XValidations = append(spec.XValidations, apiextv1.ValidationRule{
+		Rule:    "self == oldSelf",
+		Message: "spec is immutable",
+	})
+	schema.Properties["spec"] = spec
+	c.SchemaObject = nil
+	return c.WithSchema(schema), nil
+}
+
+// requireClusterSelector replaces the schema generated from the type of the CRD by one rejecting an empty
+// clusterSelector in the spec, so that a secret is never copied to all the clusters by mistake.
+func requireClusterSelector(c crd.CRD) (crd.CRD, error) {
+	schema, err := openapi.ToOpenAPIFromStruct(c.SchemaObject)
+	if err != nil {
+		return c, err
+	}
+	spec, ok := schema.Properties["spec"]
+	if !ok {
+		return c, fmt.Errorf("%s has no spec", c.GVK.Kind)
+	}
+	spec.XValidations = append(spec.XValidations, apiextv1.ValidationRule{
+		Rule: "has(self.clusterSelector) && (has(self.clusterSelector.matchLabels) && size(self.clusterSelector.matchLabels) > 0 || " +
+			"has(self.clusterSelector.matchExpressions) && size(self.clusterSelector.matchExpressions) > 0)",
+		Message: "clusterSelector must not be empty",
+	})
+	schema.Properties["spec"] = spec
+	c.SchemaObject = nil
+	return c.WithSchema(schema), nil
+}
@@ -42,3 +42,38 @@ func TestList(t *testing.T) {
 		}
 	}
 }
+
+func TestBindingReviewSpecImmutable(t *testing.T) {
+	result, err := List(nil)
+	require.NoError(t, err)
+	for _, c := range result {
+		if c.Name() != "bindingreviews.management.cattle.io" {
+			continue
+		}
+		schema := c.Schema
+		require.NotNil(t, schema)
+		require.Len(t, schema.Properties["spec"].XValidations, 1)
+		require.Equal(t, "self == oldSelf", schema.Properties["spec"].XValidations[0].Rule)
+		require.Contains(t, schema.Properties["status"].Properties, "state")
+		return
+	}
+	require.FailNow(t, "missing bindingreviews CRD")
+}
+
+func TestSecretReplicationRequiresClusterSelector(t *testing.T) {
+	result, err := List(nil)
+	require.NoError(t, err)
+	for _, c := range result {
+		if c.Name() != "secretreplications.management.cattle.io" {
+			continue
+		}
+		schema := c.Schema
+		require.NotNil(t, schema)
+		require.Len(t, schema.Properties["spec"].XValidations, 1)
+		require.Equal(t, "clusterSelector must not be empty", schema.Properties["spec"].XValidations[0].Message)
+		require.Contains(t, schema.Properties["spec"].Properties["clusterSelector"].Properties, "matchLabels")
+		require.Contains(t, schema.Properties["status"].Properties, "targets")
+		return
+	}
+	require.FailNow(t, "missing secretreplications CRD")
+}
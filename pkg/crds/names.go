@@ -120,7 +120,9 @@ func ClusterAuthCRDs() []string {
 // MCMCRDs returns a list of CRD names needed for Multi CLuster Management.
 func MCMCRDs() []string {
 	return []string{
+		"accessrequests.management.cattle.io",
 		"authconfigs.management.cattle.io",
+		"bindingreviews.management.cattle.io",
 		"catalogs.management.cattle.io",
 		"catalogtemplates.management.cattle.io",
 		"catalogtemplateversions.management.cattle.io",
@@ -161,6 +163,7 @@ func MCMCRDs() []string {
 		"projectalertgroups.management.cattle.io",
 		"projectalertrules.management.cattle.io",
 		"projectcatalogs.management.cattle.io",
+		"projectmembershippolicies.management.cattle.io",
 		"projectmonitorgraphs.management.cattle.io",
 		"projectnetworkpolicys.management.cattle.io",
 		"projectroletemplatebindings.management.cattle.io",
@@ -170,6 +173,7 @@ func MCMCRDs() []string {
 		"rkek8ssystemimages.management.cattle.io",
 		"roletemplates.management.cattle.io",
 		"samltokens.management.cattle.io",
+		"secretreplications.management.cattle.io",
 		"settings.management.cattle.io",
 		"templates.management.cattle.io",
 		"templatecontents.management.cattle.io",
@@ -189,6 +193,7 @@ func UIPluginsCRD() []string {
 
 // MigratedResources map list of resource that have been migrated after all resource have a CRD this can be removed.
 var MigratedResources = map[string]bool{
+	"accessrequests.management.cattle.io":                             false,
 	"activedirectoryproviders.management.cattle.io":                   false,
 	"apiservices.management.cattle.io":                                false,
 	"apprevisions.project.cattle.io":                                  false,
@@ -199,6 +204,7 @@ func UIPluginsCRD() []string {
 	"authtokens.management.cattle.io":                                 false,
 	"azureadproviders.management.cattle.io":                           false,
 	"basicauths.project.cattle.io":                                    false,
+	"bindingreviews.management.cattle.io":                             false,
 	"catalogs.management.cattle.io":                                   false,
 	"catalogtemplates.management.cattle.io":                           false,
 	"catalogtemplateversions.management.cattle.io":                    false,
@@ -267,6 +273,7 @@ func UIPluginsCRD() []string {
 	"projectalertrules.management.cattle.io":                          false,
 	"projectalerts.management.cattle.io":                              false,
 	"projectloggings.management.cattle.io":                            false,
+	"projectmembershippolicies.management.cattle.io":                  false,
 	"projectmonitorgraphs.management.cattle.io":                       false,
 	"projectnetworkpolicies.management.cattle.io":                     false,
 	"projectroletemplatebindings.management.cattle.io":                true,
@@ -282,6 +289,7 @@ func UIPluginsCRD() []string {
 	"roletemplates.management.cattle.io":                              true,
 	"samlproviders.management.cattle.io":                              false,
 	"samltokens.management.cattle.io":                                 false,
+	"secretreplications.management.cattle.io":                         false,
 	"serviceaccounttokens.project.cattle.io":                          false,
 	"settings.management.cattle.io":                                   false,
 	"sshauths.project.cattle.io":                                      false,
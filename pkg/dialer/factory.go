@@ -12,6 +12,7 @@
 
 	"github.com/rancher/norman/types/slice"
 	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/egressproxy"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/types/config"
 	"github.com/rancher/rancher/pkg/types/config/dialer"
@@ -21,6 +22,7 @@
 	"github.com/rancher/rke/services"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
@@ -35,6 +37,7 @@ func NewFactory(apiContext *config.ScaledContext, wrangler *wrangler.Context) (*
 	return &Factory{
 		clusterLister: apiContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:    apiContext.Management.Nodes("").Controller().Lister(),
+		proxies:       egressproxy.NewResolver(wrangler.Core.Secret().Cache()),
 		TunnelServer:  wrangler.TunnelServer,
 	}, nil
 }
@@ -42,6 +45,7 @@ func NewFactory(apiContext *config.ScaledContext, wrangler *wrangler.Context) (*
 type Factory struct {
 	nodeLister    v3.NodeLister
 	clusterLister v3.ClusterLister
+	proxies       *egressproxy.Resolver
 	TunnelServer  *remotedialer.Server
 }
 
@@ -188,7 +192,7 @@ func (f *Factory) clusterDialer(clusterName, address string, retryOnError bool)
 	logrus.Tracef("dialerFactory: apiEndpoint hostPort for cluster [%s] is [%s]", clusterName, hostPort)
 	if (address == hostPort || isProxyAddress(address)) && IsPublicCloudDriver(cluster) {
 		// For cloud drivers we just connect directly to the k8s API, not through the tunnel.  All other go through tunnel
-		return native()
+		return f.proxiedNative(cluster)
 	}
 
 	if f.TunnelServer.HasSession(cluster.Name) {
@@ -279,6 +283,22 @@ func native() (dialer.Dialer, error) {
 	return netDialer.DialContext, nil
 }
 
+// proxiedNative returns a dialer connecting directly, or through the egress proxy of the cluster or of its cloud credential.
+func (f *Factory) proxiedNative(cluster *v3.Cluster) (dialer.Dialer, error) {
+	d, err := native()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := f.proxies.ForCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if proxy != nil {
+		logrus.Tracef("dialerFactory: connecting to cluster [%s] through egress proxy [%s]", cluster.Name, proxy.URL.Host)
+	}
+	return proxy.Dialer(d), nil
+}
+
 func (f *Factory) DockerDialer(clusterName, machineName string) (dialer.Dialer, error) {
 	machine, err := f.nodeLister.Get(clusterName, machineName)
 	if err != nil {
@@ -303,6 +323,9 @@ func (f *Factory) DockerDialer(clusterName, machineName string) (dialer.Dialer,
 func (f *Factory) NodeDialer(clusterName, machineName string) (dialer.Dialer, error) {
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
 		d, err := f.nodeDialer(clusterName, machineName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			d, err = f.proxiedNodeDialer(clusterName, machineName, err)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -310,6 +333,29 @@ func (f *Factory) NodeDialer(clusterName, machineName string) (dialer.Dialer, er
 	}, nil
 }
 
+// proxiedNodeDialer returns a dialer connecting directly to a node without an agent through the egress proxy of its
+// cluster, or tunnelErr if the cluster has no egress proxy.
+func (f *Factory) proxiedNodeDialer(clusterName, machineName string, tunnelErr error) (dialer.Dialer, error) {
+	cluster, err := f.clusterLister.Get("", clusterName)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := f.proxies.ForCluster(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		return nil, tunnelErr
+	}
+	logrus.Tracef("dialerFactory: no tunnel session found for node [%s:%s], connecting through egress proxy [%s]",
+		clusterName, machineName, proxy.URL.Host)
+	d, err := native()
+	if err != nil {
+		return nil, err
+	}
+	return proxy.Dialer(d), nil
+}
+
 func (f *Factory) nodeDialer(clusterName, machineName string) (dialer.Dialer, error) {
 	machine, err := f.nodeLister.Get(clusterName, machineName)
 	if err != nil {
@@ -339,7 +385,7 @@ func isProxyAddress(address string) bool {
 		return false
 	}
 
-	parsed, err := parseProxy(proxy)
+	parsed, err := egressproxy.ParseURL(proxy)
 	if err != nil {
 		logrus.Warnf("Failed to parse http_proxy url %s: %v", proxy, err)
 		return false
@@ -355,25 +401,3 @@ func getEnvAny(names ...string) string {
 	}
 	return ""
 }
-
-func parseProxy(proxy string) (*url.URL, error) {
-	if proxy == "" {
-		return nil, nil
-	}
-
-	proxyURL, err := url.Parse(proxy)
-	if err != nil ||
-		(proxyURL.Scheme != "http" &&
-			proxyURL.Scheme != "https" &&
-			proxyURL.Scheme != "socks5") {
-		// proxy was bogus. Try pre-pending "http://" to it and
-		// see if that parses correctly. If not, fall through
-		if proxyURL, err := url.Parse("http://" + proxy); err == nil {
-			return proxyURL, nil
-		}
-	}
-	if err != nil {
-		return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
-	}
-	return proxyURL, nil
-}
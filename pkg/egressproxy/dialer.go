@@ -0,0 +1,142 @@
+package egressproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rancher/rancher/pkg/types/config/dialer"
+	"golang.org/x/net/proxy"
+)
+
+// Dialer returns a dialer connecting through the proxy, with HTTP CONNECT or SOCKS5 depending on the scheme of the
+// proxy URL, to the addresses not matched by NoProxy. The forward dialer is used to connect to the proxy and to the
+// addresses reached without it. A nil configuration returns the forward dialer.
+func (c *Config) Dialer(forward dialer.Dialer) dialer.Dialer {
+	if c == nil {
+		return forward
+	}
+
+	var proxied dialer.Dialer
+	switch c.URL.Scheme {
+	case "socks5":
+		proxied = c.socks5Dialer(forward)
+	default:
+		proxied = c.connectDialer(forward)
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if !c.UseProxy(address) {
+			return forward(ctx, network, address)
+		}
+		return proxied(ctx, network, address)
+	}
+}
+
+func (c *Config) socks5Dialer(forward dialer.Dialer) dialer.Dialer {
+	var auth *proxy.Auth
+	if c.URL.User != nil {
+		password, _ := c.URL.User.Password()
+		auth = &proxy.Auth{
+			User:     c.URL.User.Username(),
+			Password: password,
+		}
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		socks5, err := proxy.SOCKS5("tcp", c.URL.Host, auth, contextDialer(forward))
+		if err != nil {
+			return nil, err
+		}
+		return socks5.(proxy.ContextDialer).DialContext(ctx, network, address)
+	}
+}
+
+func (c *Config) connectDialer(forward dialer.Dialer) dialer.Dialer {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := forward(ctx, "tcp", c.URL.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to proxy %s: %w", c.URL.Host, err)
+		}
+
+		if c.URL.Scheme == "https" {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: c.URL.Hostname()})
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("failed to connect to proxy %s: %w", c.URL.Host, err)
+			}
+			conn = tlsConn
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetDeadline(deadline)
+		}
+		tunnel, err := c.connect(conn, address)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		_ = conn.SetDeadline(time.Time{})
+		return tunnel, nil
+	}
+}
+
+// connect asks the proxy to tunnel the connection to the address.
+func (c *Config) connect(conn net.Conn, address string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if c.URL.User != nil {
+		password, _ := c.URL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.URL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to write CONNECT request to proxy %s: %w", c.URL.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy %s: %w", c.URL.Host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", c.URL.Host, address, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		// the data of the tunnel read along with the response must not be lost
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// contextDialer adapts a dialer to the dialers of golang.org/x/net/proxy.
+type contextDialer dialer.Dialer
+
+func (d contextDialer) Dial(network, address string) (net.Conn, error) {
+	return d(context.Background(), network, address)
+}
+
+func (d contextDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d(ctx, network, address)
+}
@@ -0,0 +1,241 @@
+// Package egressproxy resolves the HTTP CONNECT or SOCKS5 proxy Rancher uses to reach a cluster, configured
+// per cluster or per cloud credential, and builds the dialers, proxy functions and environment honoring it.
+package egressproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/ref"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// URLAnnotation is set on a cloud credential to the URL of the proxy used by the clusters using it.
+	URLAnnotation = "cattle.io/egress-proxy-url"
+	// NoProxyAnnotation is set on a cloud credential to the hosts reached without its proxy.
+	NoProxyAnnotation = "cattle.io/egress-proxy-no-proxy"
+	// CredentialSecretAnnotation is set on a cloud credential to the "namespace:name" of the secret
+	// holding the credentials of its proxy, another cloud credential with the same creator.
+	CredentialSecretAnnotation = "cattle.io/egress-proxy-credential-secret"
+
+	// UsernameKey and PasswordKey are the keys of the credentials in the credential secret of a proxy.
+	UsernameKey = "username"
+	PasswordKey = "password"
+
+	creatorIDAnnotation = "field.cattle.io/creatorId"
+)
+
+// SecretGetter gets secrets, it is satisfied by the secret caches and listers.
+type SecretGetter interface {
+	Get(namespace, name string) (*corev1.Secret, error)
+}
+
+// Config is a resolved proxy configuration.
+type Config struct {
+	// URL of the proxy, including the credentials to authenticate to it if any.
+	URL *url.URL
+	// NoProxy is the comma separated list of hosts, domains and CIDRs reached without the proxy.
+	NoProxy string
+}
+
+// ProxyFunc returns the proxy function of a http.Transport honoring the configuration.
+// A nil configuration uses the proxy environment variables, like http.ProxyFromEnvironment.
+func (c *Config) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if c == nil {
+		return http.ProxyFromEnvironment
+	}
+	proxyFunc := c.httpproxy().ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// UseProxy returns true if the connections to the address, in the "host:port" form, must go through the proxy.
+func (c *Config) UseProxy(address string) bool {
+	if c == nil {
+		return false
+	}
+	proxyURL, err := c.httpproxy().ProxyFunc()(&url.URL{Scheme: "https", Host: address})
+	return err == nil && proxyURL != nil
+}
+
+// Env returns the proxy environment variables for processes honoring the configuration.
+func (c *Config) Env() []string {
+	if c == nil {
+		return nil
+	}
+	proxy := c.URL.String()
+	return []string{
+		"HTTP_PROXY=" + proxy,
+		"HTTPS_PROXY=" + proxy,
+		"NO_PROXY=" + c.NoProxy,
+	}
+}
+
+func (c *Config) httpproxy() *httpproxy.Config {
+	return &httpproxy.Config{
+		HTTPProxy:  c.URL.String(),
+		HTTPSProxy: c.URL.String(),
+		NoProxy:    c.NoProxy,
+	}
+}
+
+// Resolver resolves the proxy configuration of clusters, reading the credentials of the proxies from their secrets.
+type Resolver struct {
+	secrets SecretGetter
+}
+
+func NewResolver(secrets SecretGetter) *Resolver {
+	return &Resolver{
+		secrets: secrets,
+	}
+}
+
+// ClusterGetter gets management clusters, it is satisfied by the cluster caches and listers.
+type ClusterGetter interface {
+	Get(name string) (*v3.Cluster, error)
+}
+
+// ClusterProxyFunc returns the proxy function of a http.Transport honoring the egress proxy of the cluster,
+// resolved for every request so that changes of the configuration apply to existing transports.
+// The proxy environment variables are used while the cluster does not exist.
+func (r *Resolver) ClusterProxyFunc(clusters ClusterGetter, clusterName string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		cluster, err := clusters.Get(clusterName)
+		if apierrors.IsNotFound(err) {
+			return http.ProxyFromEnvironment(req)
+		} else if err != nil {
+			return nil, err
+		}
+		config, err := r.ForCluster(cluster)
+		if err != nil {
+			return nil, err
+		}
+		return config.ProxyFunc()(req)
+	}
+}
+
+// ForCluster returns the proxy configuration of the cluster, or the one of the cloud credential of the cluster if it
+// has none. A nil configuration is returned if neither configures a proxy. The credential secret of the proxy of the
+// cluster must be in the namespace of the cluster or in the namespace of the cloud credentials.
+func (r *Resolver) ForCluster(cluster *v3.Cluster) (*Config, error) {
+	if cluster.Spec.EgressProxy != nil {
+		config, _, err := r.resolve(cluster.Spec.EgressProxy, cluster.Name, namespace.GlobalNamespace)
+		return config, err
+	}
+
+	credential := cloudCredential(cluster)
+	if credential == "" {
+		return nil, nil
+	}
+	namespace, name := ref.Parse(credential)
+	secret, err := r.secrets.Get(namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cloud credential %s of cluster %s: %w", credential, cluster.Name, err)
+	}
+	return r.ForCloudCredential(secret)
+}
+
+// ForCloudCredential returns the proxy configuration of the cloud credential, or nil if it configures no proxy. The
+// credential secret of the proxy must be in the namespace of the cloud credential and have the same creator, as
+// anyone able to annotate the cloud credential would otherwise send the credentials of another user to their proxy.
+func (r *Resolver) ForCloudCredential(secret *corev1.Secret) (*Config, error) {
+	proxyURL := secret.Annotations[URLAnnotation]
+	if proxyURL == "" {
+		return nil, nil
+	}
+	config, credentialSecret, err := r.resolve(&v3.EgressProxyConfig{
+		URL:              proxyURL,
+		NoProxy:          secret.Annotations[NoProxyAnnotation],
+		CredentialSecret: secret.Annotations[CredentialSecretAnnotation],
+	}, secret.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if credentialSecret != nil {
+		creator := secret.Annotations[creatorIDAnnotation]
+		if creator == "" || credentialSecret.Annotations[creatorIDAnnotation] != creator {
+			return nil, fmt.Errorf("proxy credential secret %s of cloud credential %s/%s doesn't have the same creator",
+				secret.Annotations[CredentialSecretAnnotation], secret.Namespace, secret.Name)
+		}
+	}
+	return config, nil
+}
+
+// resolve parses the proxy URL and sets the credentials of the credential secret on it, returning the credential
+// secret if any. The credential secret must be in one of the namespaces.
+func (r *Resolver) resolve(spec *v3.EgressProxyConfig, namespaces ...string) (*Config, *corev1.Secret, error) {
+	if spec == nil || spec.URL == "" {
+		return nil, nil, nil
+	}
+
+	proxyURL, err := ParseURL(spec.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var secret *corev1.Secret
+	if spec.CredentialSecret != "" {
+		secretNamespace, name := ref.Parse(spec.CredentialSecret)
+		if secretNamespace == "" || !slices.Contains(namespaces, secretNamespace) {
+			return nil, nil, fmt.Errorf("proxy credential secret %s must be in one of the namespaces %v", spec.CredentialSecret, namespaces)
+		}
+		secret, err = r.secrets.Get(secretNamespace, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get proxy credential secret %s: %w", spec.CredentialSecret, err)
+		}
+		username := string(secret.Data[UsernameKey])
+		if username == "" {
+			return nil, nil, fmt.Errorf("proxy credential secret %s has no %s", spec.CredentialSecret, UsernameKey)
+		}
+		proxyURL.User = url.UserPassword(username, string(secret.Data[PasswordKey]))
+	}
+
+	return &Config{
+		URL:     proxyURL,
+		NoProxy: spec.NoProxy,
+	}, secret, nil
+}
+
+// ParseURL parses the URL of a proxy, defaulting to the http scheme when the URL has none.
+func ParseURL(proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil ||
+		(proxyURL.Scheme != "http" &&
+			proxyURL.Scheme != "https" &&
+			proxyURL.Scheme != "socks5") {
+		// proxy was bogus. Try pre-pending "http://" to it and
+		// see if that parses correctly. If not, fall through
+		if proxyURL, err := url.Parse("http://" + proxy); err == nil {
+			return proxyURL, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address %q: %v", proxy, err)
+	}
+	return proxyURL, nil
+}
+
+// cloudCredential returns the "namespace:name" of the cloud credential of a hosted cluster.
+func cloudCredential(cluster *v3.Cluster) string {
+	switch {
+	case cluster.Spec.EKSConfig != nil:
+		return cluster.Spec.EKSConfig.AmazonCredentialSecret
+	case cluster.Spec.AKSConfig != nil:
+		return cluster.Spec.AKSConfig.AzureCredentialSecret
+	case cluster.Spec.GKEConfig != nil:
+		return cluster.Spec.GKEConfig.GoogleCredentialSecret
+	}
+	return ""
+}
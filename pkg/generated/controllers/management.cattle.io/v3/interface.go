@@ -32,11 +32,13 @@ func init() {
 
 type Interface interface {
 	APIService() APIServiceController
+	AccessRequest() AccessRequestController
 	ActiveDirectoryProvider() ActiveDirectoryProviderController
 	AuthConfig() AuthConfigController
 	AuthProvider() AuthProviderController
 	AuthToken() AuthTokenController
 	AzureADProvider() AzureADProviderController
+	BindingReview() BindingReviewController
 	Catalog() CatalogController
 	CatalogTemplate() CatalogTemplateController
 	CatalogTemplateVersion() CatalogTemplateVersionController
@@ -81,6 +83,7 @@ type Interface interface {
 	Project() ProjectController
 	ProjectCatalog() ProjectCatalogController
 	ProjectLogging() ProjectLoggingController
+	ProjectMembershipPolicy() ProjectMembershipPolicyController
 	ProjectNetworkPolicy() ProjectNetworkPolicyController
 	ProjectRoleTemplateBinding() ProjectRoleTemplateBindingController
 	RancherUserNotification() RancherUserNotificationController
@@ -90,6 +93,7 @@ type Interface interface {
 	RoleTemplate() RoleTemplateController
 	SamlProvider() SamlProviderController
 	SamlToken() SamlTokenController
+	SecretReplication() SecretReplicationController
 	Setting() SettingController
 	Template() TemplateController
 	TemplateContent() TemplateContentController
@@ -113,6 +117,10 @@ func (v *version) APIService() APIServiceController {
 	return generic.NewNonNamespacedController[*v3.APIService, *v3.APIServiceList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "APIService"}, "apiservices", v.controllerFactory)
 }
 
+func (v *version) AccessRequest() AccessRequestController {
+	return generic.NewController[*v3.AccessRequest, *v3.AccessRequestList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "AccessRequest"}, "accessrequests", true, v.controllerFactory)
+}
+
 func (v *version) ActiveDirectoryProvider() ActiveDirectoryProviderController {
 	return generic.NewNonNamespacedController[*v3.ActiveDirectoryProvider, *v3.ActiveDirectoryProviderList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "ActiveDirectoryProvider"}, "activedirectoryproviders", v.controllerFactory)
 }
@@ -133,6 +141,10 @@ func (v *version) AzureADProvider() AzureADProviderController {
 	return generic.NewNonNamespacedController[*v3.AzureADProvider, *v3.AzureADProviderList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "AzureADProvider"}, "azureadproviders", v.controllerFactory)
 }
 
+func (v *version) BindingReview() BindingReviewController {
+	return generic.NewController[*v3.BindingReview, *v3.BindingReviewList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "BindingReview"}, "bindingreviews", true, v.controllerFactory)
+}
+
 func (v *version) Catalog() CatalogController {
 	return generic.NewNonNamespacedController[*v3.Catalog, *v3.CatalogList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "Catalog"}, "catalogs", v.controllerFactory)
 }
@@ -309,6 +321,10 @@ func (v *version) ProjectLogging() ProjectLoggingController {
 	return generic.NewController[*v3.ProjectLogging, *v3.ProjectLoggingList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "ProjectLogging"}, "projectloggings", true, v.controllerFactory)
 }
 
+func (v *version) ProjectMembershipPolicy() ProjectMembershipPolicyController {
+	return generic.NewController[*v3.ProjectMembershipPolicy, *v3.ProjectMembershipPolicyList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "ProjectMembershipPolicy"}, "projectmembershippolicies", true, v.controllerFactory)
+}
+
 func (v *version) ProjectNetworkPolicy() ProjectNetworkPolicyController {
 	return generic.NewController[*v3.ProjectNetworkPolicy, *v3.ProjectNetworkPolicyList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "ProjectNetworkPolicy"}, "projectnetworkpolicies", true, v.controllerFactory)
 }
@@ -345,6 +361,10 @@ func (v *version) SamlToken() SamlTokenController {
 	return generic.NewNonNamespacedController[*v3.SamlToken, *v3.SamlTokenList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "SamlToken"}, "samltokens", v.controllerFactory)
 }
 
+func (v *version) SecretReplication() SecretReplicationController {
+	return generic.NewController[*v3.SecretReplication, *v3.SecretReplicationList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "SecretReplication"}, "secretreplications", true, v.controllerFactory)
+}
+
 func (v *version) Setting() SettingController {
 	return generic.NewNonNamespacedController[*v3.Setting, *v3.SettingList](schema.GroupVersionKind{Group: "management.cattle.io", Version: "v3", Kind: "Setting"}, "settings", v.controllerFactory)
 }
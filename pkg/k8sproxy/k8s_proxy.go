@@ -13,5 +13,5 @@
 func New(scaledContext *config.ScaledContext, dialer dialer.Factory, clusterContextGetter proxy.ClusterContextGetter) http.Handler {
 	return clusterrouter.New(&scaledContext.RESTConfig, k8slookup.New(scaledContext, true), dialer,
 		scaledContext.Management.Clusters("").Controller().Lister(),
-		clusterContextGetter)
+		clusterContextGetter, scaledContext.Wrangler.ClusterProxyLimiter)
 }
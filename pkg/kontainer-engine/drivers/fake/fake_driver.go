@@ -0,0 +1,245 @@
+// Package fake implements a reference kontainer driver which keeps its clusters in memory.
+// It implements the whole Driver protocol and is used to test the contract between Rancher
+// and out-of-process drivers.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rancher/rancher/pkg/kontainer-engine/types"
+)
+
+const (
+	defaultVersion   = "v1.30.0"
+	defaultNodeCount = 3
+
+	// clusterNameMetadata is the key of the metadata of the ClusterInfo holding the name of the cluster.
+	clusterNameMetadata = "name"
+)
+
+// Driver is a kontainer driver keeping its clusters in memory.
+type Driver struct {
+	lock     sync.Mutex
+	clusters map[string]*types.ClusterInfo
+
+	driverCapabilities types.Capabilities
+}
+
+// NewDriver returns a fake driver with no clusters, speaking the current version of the Driver protocol.
+func NewDriver() *Driver {
+	d := &Driver{
+		clusters: map[string]*types.ClusterInfo{},
+		driverCapabilities: types.Capabilities{
+			Capabilities: make(map[int64]bool),
+		},
+	}
+
+	d.driverCapabilities.AddCapability(types.GetVersionCapability)
+	d.driverCapabilities.AddCapability(types.SetVersionCapability)
+	d.driverCapabilities.AddCapability(types.GetClusterSizeCapability)
+	d.driverCapabilities.AddCapability(types.SetClusterSizeCapability)
+	d.driverCapabilities.AddProtocolVersion(types.ProtocolVersion)
+
+	return d
+}
+
+func (d *Driver) GetDriverCreateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	driverFlag := types.DriverFlags{
+		Options: make(map[string]*types.Flag),
+	}
+	driverFlag.Options["name"] = &types.Flag{
+		Type:  types.StringType,
+		Usage: "the name of the cluster",
+	}
+	driverFlag.Options["kubernetes-version"] = &types.Flag{
+		Type:  types.StringType,
+		Usage: "the kubernetes version of the cluster",
+		Default: &types.Default{
+			DefaultString: defaultVersion,
+		},
+	}
+	driverFlag.Options["node-count"] = &types.Flag{
+		Type:  types.IntType,
+		Usage: "the number of nodes of the cluster",
+		Default: &types.Default{
+			DefaultInt: defaultNodeCount,
+		},
+	}
+	return &driverFlag, nil
+}
+
+func (d *Driver) GetDriverUpdateOptions(ctx context.Context) (*types.DriverFlags, error) {
+	driverFlag := types.DriverFlags{
+		Options: make(map[string]*types.Flag),
+	}
+	driverFlag.Options["kubernetes-version"] = &types.Flag{
+		Type:  types.StringType,
+		Usage: "the kubernetes version of the cluster",
+	}
+	driverFlag.Options["node-count"] = &types.Flag{
+		Type:  types.IntType,
+		Usage: "the number of nodes of the cluster",
+	}
+	return &driverFlag, nil
+}
+
+func (d *Driver) Create(ctx context.Context, opts *types.DriverOptions, clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+	name := opts.StringOptions["name"]
+	if name == "" {
+		return nil, fmt.Errorf("cluster name is required")
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if info, ok := d.clusters[name]; ok {
+		return copyInfo(info), nil
+	}
+
+	info := &types.ClusterInfo{
+		Version:   defaultVersion,
+		NodeCount: defaultNodeCount,
+		Endpoint:  fmt.Sprintf("https://%s.fake.invalid", name),
+		Status:    "running",
+		Metadata:  map[string]string{clusterNameMetadata: name},
+	}
+	applyOptions(info, opts)
+	d.clusters[name] = info
+
+	return copyInfo(info), nil
+}
+
+func (d *Driver) Update(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions) (*types.ClusterInfo, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return nil, err
+	}
+	applyOptions(info, opts)
+
+	return copyInfo(info), nil
+}
+
+func (d *Driver) PostCheck(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return nil, err
+	}
+	return copyInfo(info), nil
+}
+
+func (d *Driver) Remove(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.clusters, clusterInfo.Metadata[clusterNameMetadata])
+	return nil
+}
+
+func (d *Driver) GetVersion(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.KubernetesVersion, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &types.KubernetesVersion{Version: info.Version}, nil
+}
+
+func (d *Driver) SetVersion(ctx context.Context, clusterInfo *types.ClusterInfo, version *types.KubernetesVersion) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return err
+	}
+	info.Version = version.Version
+	return nil
+}
+
+func (d *Driver) GetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo) (*types.NodeCount, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &types.NodeCount{Count: info.NodeCount}, nil
+}
+
+func (d *Driver) SetClusterSize(ctx context.Context, clusterInfo *types.ClusterInfo, count *types.NodeCount) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	info, err := d.get(clusterInfo)
+	if err != nil {
+		return err
+	}
+	info.NodeCount = count.Count
+	return nil
+}
+
+func (d *Driver) GetCapabilities(ctx context.Context) (*types.Capabilities, error) {
+	return &d.driverCapabilities, nil
+}
+
+func (d *Driver) GetK8SCapabilities(ctx context.Context, opts *types.DriverOptions) (*types.K8SCapabilities, error) {
+	return &types.K8SCapabilities{}, nil
+}
+
+func (d *Driver) RemoveLegacyServiceAccount(ctx context.Context, clusterInfo *types.ClusterInfo) error {
+	return nil
+}
+
+func (d *Driver) ETCDSave(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
+	return fmt.Errorf("ETCD backup operations are not implemented")
+}
+
+func (d *Driver) ETCDRestore(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) (*types.ClusterInfo, error) {
+	return nil, fmt.Errorf("ETCD backup operations are not implemented")
+}
+
+func (d *Driver) ETCDRemoveSnapshot(ctx context.Context, clusterInfo *types.ClusterInfo, opts *types.DriverOptions, snapshotName string) error {
+	return fmt.Errorf("ETCD backup operations are not implemented")
+}
+
+// get returns the stored cluster matching the cluster info. The lock must be held.
+func (d *Driver) get(clusterInfo *types.ClusterInfo) (*types.ClusterInfo, error) {
+	name := clusterInfo.Metadata[clusterNameMetadata]
+	info, ok := d.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %s not found", name)
+	}
+	return info, nil
+}
+
+func applyOptions(info *types.ClusterInfo, opts *types.DriverOptions) {
+	if opts == nil {
+		return
+	}
+	if version := opts.StringOptions["kubernetes-version"]; version != "" {
+		info.Version = version
+	}
+	if count := opts.IntOptions["node-count"]; count > 0 {
+		info.NodeCount = count
+	}
+}
+
+func copyInfo(info *types.ClusterInfo) *types.ClusterInfo {
+	result := *info
+	result.Metadata = make(map[string]string, len(info.Metadata))
+	for k, v := range info.Metadata {
+		result.Metadata[k] = v
+	}
+	return &result
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rancher/rancher/pkg/kontainer-engine/drivers/fake"
+	"github.com/rancher/rancher/pkg/kontainer-engine/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunningDriverHealthy(t *testing.T) {
+	addr := make(chan string)
+	errChan := make(chan error, 1)
+	server := types.NewServer(fake.NewDriver(), addr)
+	go server.Serve("127.0.0.1:0", errChan)
+
+	var listenAddress string
+	select {
+	case listenAddress = <-addr:
+	case err := <-errChan:
+		t.Fatalf("failed to serve fake driver: %v", err)
+	}
+	defer server.Stop()
+
+	r := &RunningDriver{Name: "fake", listenAddress: listenAddress}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	version, err := r.waitForHealthy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.ProtocolVersion, version)
+}
+
+func TestRunningDriverExitedBeforeHealthy(t *testing.T) {
+	r := &RunningDriver{Name: "fake", listenAddress: unusedAddress(t), exited: make(chan error, 1)}
+	r.exited <- errors.New("exit status 1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.waitForHealthy(ctx)
+	assert.ErrorContains(t, err, "exited before becoming healthy")
+	// the exit status is still available to Stop
+	assert.Len(t, r.exited, 1)
+}
+
+func TestRunningDriverNotHealthy(t *testing.T) {
+	r := &RunningDriver{Name: "fake", listenAddress: unusedAddress(t)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.waitForHealthy(ctx)
+	assert.ErrorContains(t, err, "did not become healthy")
+}
+
+func unusedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := l.Addr().String()
+	require.NoError(t, l.Close())
+	return address
+}
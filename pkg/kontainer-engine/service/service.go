@@ -15,6 +15,7 @@
 	"github.com/rancher/rancher/pkg/jailer"
 
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/egressproxy"
 
 	"github.com/pkg/errors"
 	"github.com/rancher/rancher/pkg/kontainer-engine/cluster"
@@ -26,6 +27,7 @@
 	"github.com/rancher/rancher/pkg/kontainer-engine/types"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -163,7 +165,8 @@ func toMap(obj interface{}, format string) (map[string]interface{}, error) {
 }
 
 type EngineService struct {
-	store cluster.PersistentStore
+	store   cluster.PersistentStore
+	proxies *egressproxy.Resolver
 }
 
 func NewEngineService(store cluster.PersistentStore) *EngineService {
@@ -172,6 +175,13 @@ func NewEngineService(store cluster.PersistentStore) *EngineService {
 	}
 }
 
+// WithEgressProxy makes the service give the egress proxy of the clusters, resolved with the resolver,
+// to the external drivers through the proxy environment variables.
+func (e *EngineService) WithEgressProxy(proxies *egressproxy.Resolver) *EngineService {
+	e.proxies = proxies
+	return e
+}
+
 func (e *EngineService) convertCluster(name string, listenAddr string, spec v3.ClusterSpec) (*cluster.Cluster, error) {
 	// todo: decide whether we need a driver field
 	driverName := ""
@@ -219,7 +229,7 @@ func (e *EngineService) convertCluster(name string, listenAddr string, spec v3.C
 
 // Create creates the stub for cluster manager to call
 func (e *EngineService) Create(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (string, string, string, error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -248,17 +258,27 @@ func (e *EngineService) Create(ctx context.Context, name string, kontainerDriver
 	return endpoint, cls.ServiceAccountToken, cls.RootCACert, nil
 }
 
-func (e *EngineService) getRunningDriver(kontainerDriver *v3.KontainerDriver) (*RunningDriver, error) {
-	return &RunningDriver{
+func (e *EngineService) getRunningDriver(clusterName string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (*RunningDriver, error) {
+	runningDriver := &RunningDriver{
 		Name:    kontainerDriver.Name,
 		Builtin: kontainerDriver.Spec.BuiltIn,
 		Path:    kontainerDriver.Status.ExecutablePath,
-	}, nil
+	}
+
+	if e.proxies != nil && !runningDriver.Builtin {
+		proxy, err := e.proxies.ForCluster(&v3.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}, Spec: clusterSpec})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the egress proxy of driver %s: %w", kontainerDriver.Name, err)
+		}
+		runningDriver.Env = proxy.Env()
+	}
+
+	return runningDriver, nil
 }
 
 // Update creates the stub for cluster manager to call
 func (e *EngineService) Update(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (string, string, string, error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -289,7 +309,7 @@ func (e *EngineService) Update(ctx context.Context, name string, kontainerDriver
 
 // Remove removes stub for cluster manager to call
 func (e *EngineService) Remove(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec, forceRemove bool) error {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return err
 	}
@@ -313,7 +333,7 @@ func (e *EngineService) Remove(ctx context.Context, name string, kontainerDriver
 
 func (e *EngineService) GetDriverCreateOptions(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (*types.DriverFlags,
 	error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +357,7 @@ func (e *EngineService) GetDriverCreateOptions(ctx context.Context, name string,
 
 func (e *EngineService) GetDriverUpdateOptions(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (*types.DriverFlags,
 	error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -361,7 +381,7 @@ func (e *EngineService) GetDriverUpdateOptions(ctx context.Context, name string,
 
 func (e *EngineService) GetK8sCapabilities(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver,
 	clusterSpec v3.ClusterSpec) (*types.K8SCapabilities, error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return nil, err
 	}
@@ -383,15 +403,28 @@ func (e *EngineService) GetK8sCapabilities(ctx context.Context, name string, kon
 	return cls.GetK8SCapabilities(ctx)
 }
 
+const (
+	// driverStartTimeout is how long a driver is given to answer its first health check after being started.
+	driverStartTimeout = 30 * time.Second
+	// driverHealthCheckInterval is the interval between the health checks of a driver which is starting.
+	driverHealthCheckInterval = 250 * time.Millisecond
+)
+
 type RunningDriver struct {
 	Name    string
 	Path    string
 	Builtin bool
-	Server  *types.GrpcServer
+	// Env is appended to the environment of an external driver, ie. to set its egress proxy.
+	Env    []string
+	Server *types.GrpcServer
+	// ProtocolVersion is the version of the Driver protocol negotiated with the driver once started.
+	ProtocolVersion int64
 
 	listenAddress string
 	cancel        context.CancelFunc
 	cmd           *exec.Cmd
+	// exited receives the result of waiting for the driver process, once it exits.
+	exited chan error
 }
 
 func (r *RunningDriver) Start() (string, error) {
@@ -445,6 +478,8 @@ func (r *RunningDriver) Start() (string, error) {
 		if err != nil {
 			return "", errors.WithMessage(err, "failed to setup jail command")
 		}
+		// set last, to take precedence over the whitelisted environment variables of the process
+		cmd.Env = append(cmd.Env, r.Env...)
 
 		// redirect output to console
 		cmd.Stdout = os.Stdout
@@ -455,13 +490,24 @@ func (r *RunningDriver) Start() (string, error) {
 			return "", fmt.Errorf("error starting driver: %v", err)
 		}
 
-		time.Sleep(5 * time.Second)
+		exited := make(chan error, 1)
+		go func() {
+			exited <- cmd.Wait()
+		}()
 
 		r.listenAddress = listenAddress
 		r.cmd = cmd
+		r.exited = exited
 	}
 
-	logrus.Infof("kontainerdriver %v listening on address %v", r.Name, r.listenAddress)
+	ctx, cancel := context.WithTimeout(context.Background(), driverStartTimeout)
+	defer cancel()
+	if r.ProtocolVersion, err = r.waitForHealthy(ctx); err != nil {
+		r.Stop()
+		return "", fmt.Errorf("error starting driver: %w", err)
+	}
+
+	logrus.Infof("kontainerdriver %v listening on address %v, protocol version %d", r.Name, r.listenAddress, r.ProtocolVersion)
 
 	return r.listenAddress, nil
 }
@@ -487,6 +533,46 @@ func portOnly(address string) (string, error) {
 	return port, nil
 }
 
+// waitForHealthy health checks the driver until it answers or the context is done, and negotiates
+// the version of the Driver protocol used with the driver. It fails early if the driver process exits.
+func (r *RunningDriver) waitForHealthy(ctx context.Context) (int64, error) {
+	ticker := time.NewTicker(driverHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		version, err := r.Healthy(ctx)
+		if err == nil {
+			return version, nil
+		}
+
+		select {
+		case exitErr := <-r.exited:
+			// the exit status is kept for Stop, which waits for the driver process to exit
+			r.exited <- exitErr
+			return 0, fmt.Errorf("driver %s exited before becoming healthy: %v", r.Name, exitErr)
+		case <-ctx.Done():
+			return 0, fmt.Errorf("driver %s did not become healthy: %w", r.Name, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy checks the driver answers the GetCapabilities call of the Driver protocol and returns the
+// version of the protocol negotiated with the driver.
+func (r *RunningDriver) Healthy(ctx context.Context) (int64, error) {
+	client, err := types.NewClient(r.Name, r.listenAddress)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	capabilities, err := client.GetCapabilities(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return types.NegotiateProtocolVersion(capabilities)
+}
+
 func (r *RunningDriver) Stop() {
 	if r.Builtin {
 		r.Server.Stop()
@@ -494,16 +580,17 @@ func (r *RunningDriver) Stop() {
 		r.cancel()
 	}
 
-	if r.cmd != nil {
-		_ = r.cmd.Wait()
-		r.cmd = nil
+	if r.exited != nil {
+		<-r.exited
+		r.exited = nil
 	}
+	r.cmd = nil
 
 	logrus.Infof("kontainerdriver %v stopped", r.Name)
 }
 
 func (e *EngineService) ETCDSave(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec, snapshotName string) error {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return err
 	}
@@ -524,7 +611,7 @@ func (e *EngineService) ETCDSave(ctx context.Context, name string, kontainerDriv
 }
 
 func (e *EngineService) ETCDRestore(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec, backup string) (string, string, string, error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -554,7 +641,7 @@ func (e *EngineService) ETCDRestore(ctx context.Context, name string, kontainerD
 }
 
 func (e *EngineService) ETCDRemoveSnapshot(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec, snapshotName string) error {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return err
 	}
@@ -575,7 +662,7 @@ func (e *EngineService) ETCDRemoveSnapshot(ctx context.Context, name string, kon
 }
 
 func (e *EngineService) GenerateServiceAccount(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) (string, error) {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return "", err
 	}
@@ -601,7 +688,7 @@ func (e *EngineService) GenerateServiceAccount(ctx context.Context, name string,
 }
 
 func (e *EngineService) RemoveLegacyServiceAccount(ctx context.Context, name string, kontainerDriver *v3.KontainerDriver, clusterSpec v3.ClusterSpec) error {
-	runningDriver, err := e.getRunningDriver(kontainerDriver)
+	runningDriver, err := e.getRunningDriver(name, kontainerDriver, clusterSpec)
 	if err != nil {
 		return err
 	}
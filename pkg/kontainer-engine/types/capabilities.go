@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"slices"
+)
+
 const (
 	GetVersionCapability     = iota
 	SetVersionCapability     = iota
@@ -8,10 +13,40 @@
 	EtcdBackupCapability     = iota
 )
 
+const (
+	// ProtocolVersion is the version of the Driver protocol spoken by Rancher.
+	ProtocolVersion int64 = 1
+	// MinProtocolVersion is the oldest version of the Driver protocol still supported by Rancher.
+	MinProtocolVersion int64 = 1
+)
+
 func (c *Capabilities) AddCapability(cap int64) {
 	c.Capabilities[cap] = true
 }
 
+// AddProtocolVersion advertises that the driver speaks the given version of the Driver protocol.
+func (c *Capabilities) AddProtocolVersion(version int64) {
+	if !slices.Contains(c.ProtocolVersions, version) {
+		c.ProtocolVersions = append(c.ProtocolVersions, version)
+	}
+}
+
+// NegotiateProtocolVersion returns the highest version of the Driver protocol spoken by both Rancher and the driver.
+// Drivers which don't advertise any version only speak version 1 of the protocol.
+func NegotiateProtocolVersion(c *Capabilities) (int64, error) {
+	versions := slices.Clone(c.GetProtocolVersions())
+	if len(versions) == 0 {
+		versions = []int64{1}
+	}
+	slices.Sort(versions)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] >= MinProtocolVersion && versions[i] <= ProtocolVersion {
+			return versions[i], nil
+		}
+	}
+	return 0, fmt.Errorf("driver speaks protocol versions %v, supported versions are %d to %d", versions, MinProtocolVersion, ProtocolVersion)
+}
+
 func (c *Capabilities) HasGetVersionCapability() bool {
 	return c.Capabilities[GetVersionCapability]
 }
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		versions      []int64
+		expected      int64
+		expectedError bool
+	}{
+		{
+			name:     "legacy drivers speak version 1",
+			expected: 1,
+		},
+		{
+			name:     "current version",
+			versions: []int64{ProtocolVersion},
+			expected: ProtocolVersion,
+		},
+		{
+			name:     "highest common version",
+			versions: []int64{1, ProtocolVersion + 1},
+			expected: ProtocolVersion,
+		},
+		{
+			name:          "no common version",
+			versions:      []int64{ProtocolVersion + 1},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capabilities := &Capabilities{Capabilities: map[int64]bool{}}
+			capabilities.AddCapability(GetVersionCapability)
+			for _, version := range tt.versions {
+				capabilities.AddProtocolVersion(version)
+			}
+
+			version, err := NegotiateProtocolVersion(capabilities)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, version)
+		})
+	}
+}
+
+func TestProtocolVersionsHandshake(t *testing.T) {
+	capabilities := &Capabilities{Capabilities: map[int64]bool{}}
+	capabilities.AddCapability(GetVersionCapability)
+	capabilities.AddProtocolVersion(ProtocolVersion)
+	capabilities.AddProtocolVersion(ProtocolVersion)
+
+	data, err := proto.Marshal(capabilities)
+	assert.NoError(t, err)
+	received := &Capabilities{}
+	assert.NoError(t, proto.Unmarshal(data, received))
+
+	// the protocol versions are sent apart from the capabilities
+	assert.Equal(t, []int64{ProtocolVersion}, received.GetProtocolVersions())
+	assert.Equal(t, map[int64]bool{GetVersionCapability: true}, received.GetCapabilities())
+}
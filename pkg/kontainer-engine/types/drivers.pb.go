@@ -536,6 +536,7 @@ func (m *NodeCount) GetCount() int64 {
 
 type Capabilities struct {
 	Capabilities         map[int64]bool `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	ProtocolVersions     []int64        `protobuf:"varint,2,rep,packed,name=protocol_versions,json=protocolVersions,proto3" json:"protocol_versions,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
 	XXX_unrecognized     []byte         `json:"-"`
 	XXX_sizecache        int32          `json:"-"`
@@ -573,6 +574,13 @@ func (m *Capabilities) GetCapabilities() map[int64]bool {
 	return nil
 }
 
+func (m *Capabilities) GetProtocolVersions() []int64 {
+	if m != nil {
+		return m.ProtocolVersions
+	}
+	return nil
+}
+
 type CreateRequest struct {
 	DriverOptions        *DriverOptions `protobuf:"bytes,1,opt,name=driver_options,json=driverOptions,proto3" json:"driver_options,omitempty"`
 	ClusterInfo          *ClusterInfo   `protobuf:"bytes,2,opt,name=cluster_info,json=clusterInfo,proto3" json:"cluster_info,omitempty"`
@@ -1132,94 +1140,95 @@ func init() {
 func init() { proto.RegisterFile("drivers.proto", fileDescriptor_81dfd49b5b303fb4) }
 
 var fileDescriptor_81dfd49b5b303fb4 = []byte{
-	// 1383 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0x4f, 0x73, 0xdb, 0x44,
-	0x14, 0x8f, 0x12, 0x27, 0xb1, 0x9f, 0x9c, 0xb4, 0x79, 0x09, 0xad, 0xf0, 0x0c, 0xc5, 0x11, 0xa5,
-	0xf8, 0xd0, 0xfa, 0x60, 0xda, 0x4e, 0x69, 0x0b, 0x94, 0xb8, 0xa9, 0x1b, 0x5a, 0x4a, 0x46, 0x2e,
-	0xcc, 0x70, 0xc1, 0xb3, 0x91, 0x37, 0xa9, 0x26, 0x8a, 0xd6, 0x68, 0xd7, 0x61, 0x72, 0x85, 0x0b,
-	0x07, 0x2e, 0x5c, 0x39, 0x32, 0xf0, 0x0d, 0xe0, 0x53, 0xf0, 0x55, 0xf8, 0x10, 0xcc, 0xfe, 0x91,
-	0xbc, 0xb2, 0x65, 0xd2, 0x0c, 0x97, 0xde, 0xfc, 0xfe, 0xfd, 0xde, 0x9f, 0x7d, 0xfb, 0xde, 0xca,
-	0xb0, 0x36, 0x4c, 0xa3, 0x53, 0x9a, 0xf2, 0xf6, 0x28, 0x65, 0x82, 0xe1, 0xb2, 0x38, 0x1b, 0x51,
-	0xee, 0xaf, 0xc2, 0xf2, 0xee, 0xc9, 0x48, 0x9c, 0xf9, 0xbf, 0x38, 0xe0, 0x3e, 0x56, 0x1a, 0x4f,
-	0x62, 0x72, 0xc4, 0xf1, 0x23, 0x58, 0x65, 0x23, 0x11, 0xb1, 0x84, 0x7b, 0x4e, 0x73, 0xa9, 0xe5,
-	0x76, 0xde, 0x6d, 0x2b, 0x8b, 0xb6, 0xa5, 0xd4, 0xfe, 0x52, 0x6b, 0xec, 0x26, 0x22, 0x3d, 0x0b,
-	0x32, 0xfd, 0x46, 0x0f, 0xea, 0xb6, 0x00, 0x2f, 0xc3, 0xd2, 0x31, 0x3d, 0xf3, 0x9c, 0xa6, 0xd3,
-	0xaa, 0x05, 0xf2, 0x27, 0x6e, 0xc3, 0xf2, 0x29, 0x89, 0xc7, 0xd4, 0x5b, 0x6c, 0x3a, 0x2d, 0xb7,
-	0xe3, 0x1a, 0x68, 0x09, 0x1a, 0x68, 0xc9, 0xfd, 0xc5, 0x7b, 0x8e, 0xff, 0xb3, 0x03, 0x15, 0xc9,
-	0x43, 0x84, 0x8a, 0xd4, 0x30, 0x10, 0xea, 0x37, 0x6e, 0xc1, 0xf2, 0x98, 0x93, 0x23, 0x8d, 0x51,
-	0x0b, 0x34, 0x21, 0xb9, 0x1a, 0x79, 0x49, 0x73, 0x15, 0x81, 0x2d, 0x58, 0x1d, 0xd2, 0x43, 0x32,
-	0x8e, 0x85, 0x57, 0x51, 0x1e, 0xd7, 0xb3, 0x64, 0x34, 0x37, 0xc8, 0xc4, 0xd8, 0x80, 0xea, 0x88,
-	0x70, 0xfe, 0x3d, 0x4b, 0x87, 0xde, 0x72, 0xd3, 0x69, 0x55, 0x83, 0x9c, 0xf6, 0xff, 0x72, 0x60,
-	0xd5, 0x18, 0x60, 0x13, 0x5c, 0x63, 0xb2, 0xc3, 0x58, 0xac, 0x02, 0xab, 0x06, 0x36, 0x0b, 0xaf,
-	0xc3, 0x9a, 0x21, 0xfb, 0x22, 0x8d, 0x92, 0x23, 0x13, 0x67, 0x91, 0x89, 0x3b, 0x80, 0x05, 0x46,
-	0x3f, 0x8e, 0x42, 0x1d, 0xbc, 0xdb, 0x41, 0x13, 0xa4, 0x25, 0x09, 0x4a, 0xb4, 0xf1, 0x1a, 0x80,
-	0xe1, 0xee, 0x25, 0x3a, 0xc1, 0xa5, 0xc0, 0xe2, 0xf8, 0xff, 0x54, 0x60, 0x4d, 0x9f, 0x9a, 0x39,
-	0x16, 0x7c, 0x0a, 0xf5, 0x03, 0xc6, 0xe2, 0x41, 0xf1, 0x84, 0xdf, 0x2f, 0x9c, 0xb0, 0xd1, 0x6d,
-	0xcb, 0x64, 0x0a, 0xe7, 0xec, 0x1e, 0x4c, 0x38, 0xf8, 0x02, 0xd6, 0xb9, 0x0a, 0x25, 0xc7, 0x5a,
-	0x54, 0x58, 0x1f, 0x94, 0x62, 0xe9, 0xa8, 0x0b, 0x68, 0x6b, 0xdc, 0xe6, 0xe1, 0x2e, 0xb8, 0x51,
-	0x22, 0x72, 0xb0, 0x25, 0x05, 0x76, 0xbd, 0x14, 0x6c, 0x2f, 0x11, 0x05, 0x24, 0x88, 0x72, 0x06,
-	0x7e, 0x0b, 0x5b, 0x26, 0x2c, 0x2e, 0x4b, 0x94, 0xe3, 0x55, 0x14, 0xde, 0xcd, 0xff, 0x08, 0x4e,
-	0x95, 0xb4, 0x80, 0x8b, 0x7c, 0x46, 0xd0, 0xf8, 0x04, 0x2e, 0x4f, 0xd7, 0xa5, 0xa4, 0xcd, 0xb7,
-	0xec, 0x36, 0xaf, 0x5a, 0x9d, 0xdd, 0x78, 0x04, 0x38, 0x5b, 0x8b, 0xf3, 0x10, 0x6a, 0x36, 0xc2,
-	0xc7, 0x70, 0x69, 0xaa, 0x00, 0xe7, 0x99, 0x2f, 0xd9, 0xe6, 0xdf, 0xc0, 0xd5, 0x39, 0xf9, 0x96,
-	0xc0, 0xb4, 0x8a, 0xd7, 0xb5, 0xac, 0x2f, 0xad, 0x5b, 0xfb, 0x1e, 0xb8, 0x76, 0x77, 0xe6, 0x31,
-	0xc8, 0x26, 0xcb, 0x52, 0xf0, 0x7f, 0xa8, 0x80, 0xdb, 0x8d, 0xc7, 0x5c, 0xd0, 0x74, 0x2f, 0x39,
-	0x64, 0xe8, 0xc1, 0xaa, 0x1c, 0x4e, 0x11, 0x4b, 0x8c, 0xe3, 0x8c, 0xc4, 0x0e, 0xbc, 0xc5, 0x69,
-	0x7a, 0x2a, 0x4f, 0x91, 0x84, 0x21, 0x1b, 0x27, 0x62, 0x20, 0xd8, 0x31, 0x4d, 0x4c, 0x49, 0x36,
-	0x8d, 0xf0, 0x33, 0x2d, 0x7b, 0x29, 0x45, 0xf2, 0x16, 0xd3, 0x64, 0x38, 0x62, 0x51, 0x22, 0xcc,
-	0x20, 0xc8, 0x69, 0x29, 0x1b, 0x73, 0x9a, 0x26, 0xe4, 0x84, 0xaa, 0xbb, 0x52, 0x0b, 0x72, 0x7a,
-	0xe6, 0xf6, 0xd7, 0x26, 0xb7, 0x1f, 0xdb, 0xb0, 0x99, 0x32, 0x26, 0x06, 0x21, 0x19, 0x84, 0x34,
-	0x15, 0xd1, 0x61, 0x14, 0x12, 0x41, 0xbd, 0x15, 0xa5, 0xb6, 0x21, 0x45, 0x5d, 0xd2, 0x9d, 0x08,
-	0xf0, 0x16, 0x60, 0x18, 0x47, 0x34, 0x11, 0x05, 0xf5, 0x55, 0xad, 0xae, 0x25, 0xb6, 0xfa, 0x3b,
-	0x00, 0x46, 0x5d, 0x16, 0xbf, 0xaa, 0xd4, 0x6a, 0x9a, 0xf3, 0x8c, 0x9e, 0x49, 0x71, 0xc2, 0x86,
-	0x74, 0xa0, 0x92, 0xf4, 0x6a, 0xea, 0x38, 0x6b, 0x92, 0xd3, 0x95, 0x0c, 0x7c, 0x08, 0xd5, 0x13,
-	0x2a, 0xc8, 0x90, 0x08, 0xe2, 0x81, 0xea, 0xf1, 0xa6, 0x39, 0x24, 0xab, 0xc8, 0xed, 0x2f, 0x8c,
-	0x8a, 0xee, 0xeb, 0xdc, 0x02, 0xaf, 0xc0, 0x0a, 0x17, 0x44, 0x8c, 0xb9, 0xe7, 0x2a, 0xbf, 0x86,
-	0xc2, 0x6d, 0xa8, 0x87, 0x29, 0x25, 0x82, 0x0e, 0x68, 0x9a, 0xb2, 0xd4, 0xab, 0x2b, 0xa9, 0xab,
-	0x79, 0xbb, 0x92, 0xd5, 0x78, 0x00, 0x6b, 0x05, 0xd4, 0x8b, 0xf4, 0xb0, 0x7f, 0x0b, 0x36, 0x9e,
-	0x8d, 0x0f, 0x68, 0x9a, 0x50, 0x41, 0xf9, 0xd7, 0xe6, 0xbc, 0xe7, 0x76, 0x82, 0xbf, 0x0d, 0xb5,
-	0x17, 0x79, 0xc6, 0x5b, 0xb0, 0xac, 0x6b, 0xe1, 0xe8, 0xd6, 0x56, 0x84, 0xff, 0xab, 0x03, 0xf5,
-	0x2e, 0x19, 0x91, 0x83, 0x28, 0x8e, 0x44, 0x44, 0x39, 0xee, 0x41, 0x3d, 0xb4, 0xe8, 0xa9, 0x49,
-	0x67, 0xab, 0x16, 0x08, 0x5d, 0xa1, 0x82, 0x69, 0xe3, 0x53, 0xd8, 0x98, 0x51, 0xb1, 0xd3, 0x5d,
-	0x3a, 0xe7, 0xd2, 0xfb, 0x3f, 0x3a, 0xb0, 0xd6, 0x55, 0xb5, 0x0b, 0xe8, 0x77, 0x63, 0xca, 0x05,
-	0x3e, 0x80, 0x75, 0xbd, 0x95, 0xad, 0x49, 0x2c, 0x6f, 0xd8, 0x56, 0xd9, 0x80, 0x0a, 0xcc, 0x06,
-	0xcf, 0x66, 0xdc, 0x1d, 0xa8, 0x87, 0xfa, 0x70, 0x07, 0x51, 0x72, 0xc8, 0xa6, 0x2e, 0xa7, 0x75,
-	0xee, 0x81, 0x1b, 0x4e, 0x08, 0x15, 0xc5, 0x57, 0xa3, 0xa1, 0x15, 0xc5, 0x34, 0x90, 0xf3, 0x5a,
-	0x40, 0x25, 0xc1, 0x2f, 0xbe, 0x76, 0xf0, 0x3e, 0x83, 0x8d, 0x3e, 0x15, 0xe6, 0xcc, 0xb3, 0x40,
-	0x6e, 0x40, 0xe5, 0x9c, 0x00, 0x94, 0x1c, 0x3b, 0x93, 0x16, 0xd1, 0x2e, 0x3d, 0xa3, 0x3a, 0xd3,
-	0x4d, 0x93, 0xe6, 0xa1, 0xb0, 0xd9, 0xa7, 0x22, 0xef, 0x9f, 0x8b, 0xba, 0xbc, 0x91, 0xb5, 0x9b,
-	0x76, 0x78, 0xd9, 0x28, 0x4e, 0xf0, 0x4c, 0x03, 0xfe, 0xe6, 0xc0, 0xd5, 0x3e, 0x39, 0xa5, 0xbb,
-	0x2f, 0xbb, 0x8f, 0xfb, 0x09, 0x19, 0xf1, 0x57, 0xec, 0xc2, 0xbe, 0xfe, 0x4f, 0x61, 0xd1, 0x87,
-	0x7a, 0xe6, 0xf7, 0x85, 0x1c, 0x71, 0x7a, 0xfc, 0x15, 0x78, 0xfe, 0x1f, 0x0e, 0x34, 0x02, 0xca,
-	0x05, 0x4b, 0xdf, 0xec, 0x38, 0x7f, 0x77, 0xe0, 0xed, 0x80, 0x9e, 0xb0, 0x37, 0xbc, 0x9c, 0x3f,
-	0x2d, 0xc2, 0xa5, 0x67, 0xf7, 0x78, 0x61, 0xee, 0xf4, 0x60, 0xfd, 0xf9, 0xed, 0xe7, 0x8c, 0x0c,
-	0x77, 0x48, 0x4c, 0x92, 0x90, 0xa6, 0x26, 0xcc, 0xec, 0x15, 0x6d, 0x8b, 0x6c, 0xc3, 0x60, 0xca,
-	0x0c, 0x3f, 0x07, 0xdc, 0x4b, 0x8e, 0x52, 0xca, 0x79, 0x97, 0x25, 0x22, 0x65, 0x71, 0x4c, 0xd3,
-	0xec, 0x91, 0xd5, 0x30, 0x60, 0x99, 0x82, 0x8d, 0x53, 0x62, 0x85, 0xf7, 0xc1, 0x93, 0x0d, 0xbb,
-	0xcf, 0x58, 0xdc, 0x0f, 0x49, 0x2c, 0x57, 0xf4, 0x78, 0x34, 0x62, 0xa9, 0xa0, 0x43, 0x95, 0x58,
-	0x35, 0x98, 0x2b, 0x97, 0xcf, 0x59, 0x2d, 0x4b, 0x45, 0x40, 0x92, 0xa3, 0x6c, 0x77, 0x16, 0x99,
-	0xfe, 0x9f, 0x0e, 0x78, 0xf3, 0x52, 0x93, 0x93, 0x7d, 0x37, 0x21, 0x07, 0x31, 0x1d, 0x9a, 0xf7,
-	0x72, 0x46, 0xca, 0xbd, 0xbb, 0x9f, 0xb2, 0xd3, 0x68, 0x48, 0x53, 0xb3, 0x25, 0x72, 0x1a, 0xdb,
-	0x80, 0xfb, 0xf2, 0x8b, 0x25, 0x64, 0x31, 0xb7, 0xc3, 0x95, 0x8f, 0x89, 0x12, 0x09, 0x76, 0x60,
-	0xeb, 0x29, 0x25, 0xb1, 0x78, 0xd5, 0x7d, 0x45, 0xc3, 0xe3, 0x89, 0x45, 0x45, 0xb9, 0x2c, 0x95,
-	0xf9, 0x1c, 0x36, 0x4b, 0x6a, 0x88, 0x2d, 0xf9, 0xc6, 0x52, 0xec, 0x3c, 0x3a, 0xbd, 0x92, 0xa6,
-	0xd9, 0xd2, 0x69, 0x77, 0xcc, 0x05, 0x3b, 0x31, 0xdf, 0x07, 0x3b, 0x24, 0x3c, 0xa6, 0xc9, 0xd0,
-	0xec, 0x80, 0x52, 0x59, 0xe7, 0xef, 0x55, 0x58, 0xd1, 0xbd, 0x87, 0xb7, 0x61, 0x45, 0x2f, 0x06,
-	0xcc, 0x9a, 0xb2, 0xb0, 0x27, 0x1a, 0x25, 0xcd, 0xed, 0x2f, 0x48, 0x2b, 0x3d, 0xc8, 0x73, 0xab,
-	0xc2, 0x5c, 0x9f, 0x63, 0x75, 0x07, 0x6a, 0xfb, 0x8c, 0x0b, 0x55, 0x01, 0x2c, 0x51, 0x99, 0x63,
-	0x76, 0x13, 0x56, 0xf4, 0x55, 0x2c, 0xb5, 0xa9, 0x1b, 0x9e, 0xfe, 0x96, 0x5c, 0xc0, 0x87, 0x70,
-	0xa5, 0x47, 0x85, 0xce, 0x4e, 0xa7, 0x92, 0x5d, 0xa8, 0x82, 0x66, 0xee, 0xcb, 0xfa, 0xa8, 0x9c,
-	0xb2, 0xd6, 0x29, 0x5d, 0xcc, 0x1a, 0x7a, 0xf9, 0x6a, 0x29, 0x8d, 0x76, 0xee, 0xba, 0xf0, 0x17,
-	0xf0, 0x2e, 0xc0, 0x64, 0x31, 0x61, 0xa6, 0x39, 0xb3, 0xab, 0x66, 0x32, 0xbe, 0x0b, 0xf5, 0x9e,
-	0xb5, 0x5f, 0x4a, 0xfd, 0xce, 0x6c, 0x0d, 0x7f, 0x01, 0xef, 0x43, 0xdd, 0xde, 0x4b, 0xd8, 0x98,
-	0x78, 0x9c, 0x5e, 0x56, 0x25, 0x3e, 0x2f, 0xf5, 0xa8, 0x28, 0xb4, 0x6c, 0xb1, 0x40, 0x9b, 0x25,
-	0xef, 0x1c, 0xe5, 0xb3, 0xaa, 0x06, 0x2a, 0x39, 0xa5, 0x78, 0x2d, 0xf3, 0x57, 0xbe, 0xb4, 0x66,
-	0x7c, 0x3e, 0x01, 0x57, 0xaa, 0x99, 0xf5, 0x81, 0xdb, 0x46, 0x3c, 0x7f, 0x9d, 0xcc, 0xe9, 0xa7,
-	0x27, 0x80, 0x1a, 0x47, 0xf6, 0x54, 0x66, 0x82, 0xcd, 0x1c, 0x6e, 0xce, 0xd4, 0x9f, 0x89, 0xe7,
-	0x31, 0x60, 0x8f, 0x8a, 0xe9, 0xf1, 0x5b, 0x3a, 0xdb, 0x1b, 0x57, 0xb2, 0x73, 0x2f, 0x6a, 0xfb,
-	0x0b, 0xf8, 0x48, 0x2e, 0x44, 0xe9, 0xf2, 0x39, 0x3d, 0x22, 0xe1, 0x59, 0xbf, 0xf0, 0x4d, 0xf1,
-	0x3a, 0x1d, 0x7f, 0xb0, 0xa2, 0xfe, 0x56, 0xf9, 0xf0, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x95,
-	0x0c, 0xfe, 0x37, 0x67, 0x11, 0x00, 0x00,
+	// 1403 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x57, 0xcd, 0x72, 0x1b, 0xc5,
+	0x16, 0xf6, 0x58, 0xb2, 0x2d, 0x9d, 0x91, 0x1d, 0xfb, 0xd8, 0x37, 0x99, 0xab, 0xaa, 0x9b, 0x6b,
+	0xcf, 0xcd, 0x0d, 0xaa, 0x22, 0xd1, 0x42, 0x24, 0xa9, 0x90, 0x04, 0x08, 0x56, 0x1c, 0xc5, 0x24,
+	0x04, 0xd7, 0x28, 0x50, 0xc5, 0x06, 0x55, 0x7b, 0xd4, 0x76, 0xa6, 0x3c, 0x9e, 0x16, 0xd3, 0x2d,
+	0x53, 0xde, 0xc2, 0x86, 0x05, 0x1b, 0x5e, 0x81, 0x82, 0x37, 0x80, 0x67, 0x60, 0xc1, 0xab, 0xf0,
+	0x10, 0x54, 0xff, 0xcc, 0xa8, 0x47, 0x1a, 0xe1, 0xb8, 0xd8, 0x64, 0x37, 0xe7, 0xef, 0x3b, 0x3f,
+	0x7d, 0xfa, 0x9c, 0x1e, 0x58, 0x1d, 0xa6, 0xd1, 0x19, 0x4d, 0x79, 0x7b, 0x94, 0x32, 0xc1, 0x70,
+	0x49, 0x9c, 0x8f, 0x28, 0xf7, 0x57, 0x60, 0x69, 0xef, 0x74, 0x24, 0xce, 0xfd, 0x1f, 0x1d, 0x70,
+	0x9f, 0x28, 0x8d, 0xa7, 0x31, 0x39, 0xe6, 0xf8, 0x3e, 0xac, 0xb0, 0x91, 0x88, 0x58, 0xc2, 0x3d,
+	0x67, 0xbb, 0xd2, 0x72, 0x3b, 0xff, 0x6d, 0x2b, 0x8b, 0xb6, 0xa5, 0xd4, 0xfe, 0x4c, 0x6b, 0xec,
+	0x25, 0x22, 0x3d, 0x0f, 0x32, 0xfd, 0x66, 0x0f, 0x1a, 0xb6, 0x00, 0xd7, 0xa1, 0x72, 0x42, 0xcf,
+	0x3d, 0x67, 0xdb, 0x69, 0xd5, 0x03, 0xf9, 0x89, 0x3b, 0xb0, 0x74, 0x46, 0xe2, 0x31, 0xf5, 0x16,
+	0xb7, 0x9d, 0x96, 0xdb, 0x71, 0x0d, 0xb4, 0x04, 0x0d, 0xb4, 0xe4, 0xc1, 0xe2, 0x7d, 0xc7, 0xff,
+	0xc1, 0x81, 0xaa, 0xe4, 0x21, 0x42, 0x55, 0x6a, 0x18, 0x08, 0xf5, 0x8d, 0x5b, 0xb0, 0x34, 0xe6,
+	0xe4, 0x58, 0x63, 0xd4, 0x03, 0x4d, 0x48, 0xae, 0x46, 0xae, 0x68, 0xae, 0x22, 0xb0, 0x05, 0x2b,
+	0x43, 0x7a, 0x44, 0xc6, 0xb1, 0xf0, 0xaa, 0xca, 0xe3, 0x5a, 0x96, 0x8c, 0xe6, 0x06, 0x99, 0x18,
+	0x9b, 0x50, 0x1b, 0x11, 0xce, 0xbf, 0x61, 0xe9, 0xd0, 0x5b, 0xda, 0x76, 0x5a, 0xb5, 0x20, 0xa7,
+	0xfd, 0xdf, 0x1c, 0x58, 0x31, 0x06, 0xb8, 0x0d, 0xae, 0x31, 0xd9, 0x65, 0x2c, 0x56, 0x81, 0xd5,
+	0x02, 0x9b, 0x85, 0x37, 0x60, 0xd5, 0x90, 0x7d, 0x91, 0x46, 0xc9, 0xb1, 0x89, 0xb3, 0xc8, 0xc4,
+	0x5d, 0xc0, 0x02, 0xa3, 0x1f, 0x47, 0xa1, 0x0e, 0xde, 0xed, 0xa0, 0x09, 0xd2, 0x92, 0x04, 0x25,
+	0xda, 0x78, 0x1d, 0xc0, 0x70, 0xf7, 0x13, 0x9d, 0x60, 0x25, 0xb0, 0x38, 0xfe, 0x9f, 0x55, 0x58,
+	0xd5, 0xa7, 0x66, 0x8e, 0x05, 0x9f, 0x41, 0xe3, 0x90, 0xb1, 0x78, 0x50, 0x3c, 0xe1, 0xff, 0x17,
+	0x4e, 0xd8, 0xe8, 0xb6, 0x65, 0x32, 0x85, 0x73, 0x76, 0x0f, 0x27, 0x1c, 0x7c, 0x09, 0x6b, 0x5c,
+	0x85, 0x92, 0x63, 0x2d, 0x2a, 0xac, 0x77, 0x4a, 0xb1, 0x74, 0xd4, 0x05, 0xb4, 0x55, 0x6e, 0xf3,
+	0x70, 0x0f, 0xdc, 0x28, 0x11, 0x39, 0x58, 0x45, 0x81, 0xdd, 0x28, 0x05, 0xdb, 0x4f, 0x44, 0x01,
+	0x09, 0xa2, 0x9c, 0x81, 0x5f, 0xc1, 0x96, 0x09, 0x8b, 0xcb, 0x12, 0xe5, 0x78, 0x55, 0x85, 0x77,
+	0xeb, 0x6f, 0x82, 0x53, 0x25, 0x2d, 0xe0, 0x22, 0x9f, 0x11, 0x34, 0x3f, 0x84, 0xf5, 0xe9, 0xba,
+	0x94, 0xb4, 0xf9, 0x96, 0xdd, 0xe6, 0x35, 0xab, 0xb3, 0x9b, 0x8f, 0x01, 0x67, 0x6b, 0x71, 0x11,
+	0x42, 0xdd, 0x46, 0xf8, 0x00, 0xae, 0x4c, 0x15, 0xe0, 0x22, 0xf3, 0x8a, 0x6d, 0xfe, 0x25, 0x5c,
+	0x9b, 0x93, 0x6f, 0x09, 0x4c, 0xab, 0x78, 0x5d, 0xcb, 0xfa, 0xd2, 0xba, 0xb5, 0xff, 0x03, 0xd7,
+	0xee, 0xce, 0x3c, 0x06, 0xd9, 0x64, 0x59, 0x0a, 0xfe, 0xb7, 0x55, 0x70, 0xbb, 0xf1, 0x98, 0x0b,
+	0x9a, 0xee, 0x27, 0x47, 0x0c, 0x3d, 0x58, 0x91, 0xc3, 0x29, 0x62, 0x89, 0x71, 0x9c, 0x91, 0xd8,
+	0x81, 0x7f, 0x71, 0x9a, 0x9e, 0xc9, 0x53, 0x24, 0x61, 0xc8, 0xc6, 0x89, 0x18, 0x08, 0x76, 0x42,
+	0x13, 0x53, 0x92, 0x4d, 0x23, 0xfc, 0x58, 0xcb, 0x5e, 0x49, 0x91, 0xbc, 0xc5, 0x34, 0x19, 0x8e,
+	0x58, 0x94, 0x08, 0x33, 0x08, 0x72, 0x5a, 0xca, 0xc6, 0x9c, 0xa6, 0x09, 0x39, 0xa5, 0xea, 0xae,
+	0xd4, 0x83, 0x9c, 0x9e, 0xb9, 0xfd, 0xf5, 0xc9, 0xed, 0xc7, 0x36, 0x6c, 0xa6, 0x8c, 0x89, 0x41,
+	0x48, 0x06, 0x21, 0x4d, 0x45, 0x74, 0x14, 0x85, 0x44, 0x50, 0x6f, 0x59, 0xa9, 0x6d, 0x48, 0x51,
+	0x97, 0x74, 0x27, 0x02, 0xbc, 0x0d, 0x18, 0xc6, 0x11, 0x4d, 0x44, 0x41, 0x7d, 0x45, 0xab, 0x6b,
+	0x89, 0xad, 0xfe, 0x1f, 0x00, 0xa3, 0x2e, 0x8b, 0x5f, 0x53, 0x6a, 0x75, 0xcd, 0x79, 0x4e, 0xcf,
+	0xa5, 0x38, 0x61, 0x43, 0x3a, 0x50, 0x49, 0x7a, 0x75, 0x75, 0x9c, 0x75, 0xc9, 0xe9, 0x4a, 0x06,
+	0x3e, 0x82, 0xda, 0x29, 0x15, 0x64, 0x48, 0x04, 0xf1, 0x40, 0xf5, 0xf8, 0xb6, 0x39, 0x24, 0xab,
+	0xc8, 0xed, 0x4f, 0x8d, 0x8a, 0xee, 0xeb, 0xdc, 0x02, 0xaf, 0xc2, 0x32, 0x17, 0x44, 0x8c, 0xb9,
+	0xe7, 0x2a, 0xbf, 0x86, 0xc2, 0x1d, 0x68, 0x84, 0x29, 0x25, 0x82, 0x0e, 0x68, 0x9a, 0xb2, 0xd4,
+	0x6b, 0x28, 0xa9, 0xab, 0x79, 0x7b, 0x92, 0xd5, 0x7c, 0x08, 0xab, 0x05, 0xd4, 0xcb, 0xf4, 0xb0,
+	0x7f, 0x1b, 0x36, 0x9e, 0x8f, 0x0f, 0x69, 0x9a, 0x50, 0x41, 0xf9, 0x17, 0xe6, 0xbc, 0xe7, 0x76,
+	0x82, 0xbf, 0x03, 0xf5, 0x97, 0x79, 0xc6, 0x5b, 0xb0, 0xa4, 0x6b, 0xe1, 0xe8, 0xd6, 0x56, 0x84,
+	0xff, 0xbb, 0x03, 0x8d, 0x2e, 0x19, 0x91, 0xc3, 0x28, 0x8e, 0x44, 0x44, 0x39, 0xee, 0x43, 0x23,
+	0xb4, 0xe8, 0xa9, 0x49, 0x67, 0xab, 0x16, 0x08, 0x5d, 0xa1, 0x82, 0x29, 0xbe, 0x0b, 0x1b, 0x6a,
+	0x75, 0x86, 0x2c, 0x1e, 0x98, 0x90, 0xf4, 0xb4, 0xab, 0x04, 0xeb, 0x99, 0xc0, 0x24, 0xc1, 0x9b,
+	0x1f, 0xc1, 0xc6, 0x0c, 0x9e, 0x5d, 0x9b, 0xca, 0x05, 0x13, 0xc2, 0xff, 0xce, 0x81, 0xd5, 0xae,
+	0x2a, 0x74, 0x40, 0xbf, 0x1e, 0x53, 0x2e, 0xf0, 0x21, 0xac, 0xe9, 0x15, 0x6e, 0x8d, 0x6d, 0x79,
+	0x1d, 0xb7, 0xca, 0xa6, 0x59, 0x60, 0xd6, 0x7d, 0x36, 0x10, 0xef, 0x42, 0x23, 0xd4, 0x9d, 0x30,
+	0x88, 0x92, 0x23, 0x36, 0x75, 0x93, 0xad, 0x26, 0x09, 0xdc, 0x70, 0x42, 0xa8, 0x28, 0x3e, 0x1f,
+	0x0d, 0xad, 0x28, 0xa6, 0x81, 0x9c, 0x37, 0x02, 0x2a, 0x09, 0x7e, 0xf1, 0x8d, 0x83, 0xf7, 0x19,
+	0x6c, 0xf4, 0xa9, 0x30, 0xb5, 0xcd, 0x02, 0xb9, 0x09, 0xd5, 0x0b, 0x02, 0x50, 0x72, 0xec, 0x4c,
+	0xfa, 0x49, 0xbb, 0xf4, 0x8c, 0xea, 0x4c, 0xeb, 0x4d, 0x3a, 0x8d, 0xc2, 0x66, 0x9f, 0x8a, 0xbc,
+	0xd9, 0x2e, 0xeb, 0xf2, 0x66, 0xd6, 0x9b, 0xda, 0xe1, 0xba, 0x51, 0x9c, 0xe0, 0x99, 0x6e, 0xfd,
+	0xc9, 0x81, 0x6b, 0x7d, 0x72, 0x46, 0xf7, 0x5e, 0x75, 0x9f, 0xf4, 0x13, 0x32, 0xe2, 0xaf, 0xd9,
+	0xa5, 0x7d, 0xfd, 0x93, 0xc2, 0xa2, 0x0f, 0x8d, 0xcc, 0xef, 0x4b, 0x39, 0x0f, 0xf5, 0xac, 0x2c,
+	0xf0, 0xfc, 0x5f, 0x1c, 0x68, 0x06, 0x94, 0x0b, 0x96, 0xbe, 0xdd, 0x71, 0xfe, 0xec, 0xc0, 0xbf,
+	0x03, 0x7a, 0xca, 0xde, 0xf2, 0x72, 0x7e, 0xbf, 0x08, 0x57, 0x9e, 0xdf, 0xe7, 0x85, 0x21, 0xd5,
+	0x83, 0xb5, 0x17, 0x77, 0x5e, 0x30, 0x32, 0xdc, 0x25, 0x31, 0x49, 0x42, 0x9a, 0x9a, 0x30, 0xb3,
+	0x27, 0xb7, 0x2d, 0xb2, 0x0d, 0x83, 0x29, 0x33, 0xfc, 0x04, 0x70, 0x3f, 0x39, 0x4e, 0x29, 0xe7,
+	0x5d, 0x96, 0x88, 0x94, 0xc5, 0x31, 0x4d, 0xb3, 0x17, 0x59, 0xd3, 0x80, 0x65, 0x0a, 0x36, 0x4e,
+	0x89, 0x15, 0x3e, 0x00, 0x4f, 0x36, 0xec, 0x01, 0x63, 0x71, 0x3f, 0x24, 0xb1, 0xdc, 0xe7, 0xe3,
+	0xd1, 0x88, 0xa5, 0x82, 0x0e, 0x55, 0x62, 0xb5, 0x60, 0xae, 0x5c, 0xbe, 0x7d, 0xb5, 0x2c, 0x15,
+	0x01, 0x49, 0x8e, 0xb3, 0x45, 0x5b, 0x64, 0xfa, 0xbf, 0x3a, 0xe0, 0xcd, 0x4b, 0x4d, 0xae, 0x81,
+	0xbd, 0x84, 0x1c, 0xc6, 0x74, 0x68, 0x1e, 0xd7, 0x19, 0x29, 0x97, 0xf4, 0x41, 0xca, 0xce, 0xa2,
+	0x21, 0x4d, 0xcd, 0x4a, 0xc9, 0x69, 0x6c, 0x03, 0x1e, 0x98, 0x51, 0xcc, 0xed, 0x70, 0xe5, 0xcb,
+	0xa3, 0x44, 0x82, 0x1d, 0xd8, 0x7a, 0x46, 0x49, 0x2c, 0x5e, 0x77, 0x5f, 0xd3, 0xf0, 0x64, 0x62,
+	0x51, 0x55, 0x2e, 0x4b, 0x65, 0x3e, 0x87, 0xcd, 0x92, 0x1a, 0x62, 0x4b, 0x3e, 0xc8, 0x14, 0x3b,
+	0x8f, 0x4e, 0xef, 0xaf, 0x69, 0xb6, 0x74, 0xda, 0x1d, 0x73, 0xc1, 0x4e, 0xcd, 0xcf, 0xc4, 0x2e,
+	0x09, 0x4f, 0x68, 0x32, 0x34, 0x3b, 0xa0, 0x54, 0xd6, 0xf9, 0x63, 0x05, 0x96, 0x75, 0xef, 0xe1,
+	0x1d, 0x58, 0xd6, 0x8b, 0x01, 0xb3, 0xa6, 0x2c, 0xec, 0x89, 0x66, 0x49, 0x73, 0xfb, 0x0b, 0xd2,
+	0x4a, 0x0f, 0xf2, 0xdc, 0xaa, 0x30, 0xd7, 0xe7, 0x58, 0xdd, 0x85, 0xfa, 0x01, 0xe3, 0x42, 0x55,
+	0x00, 0x4b, 0x54, 0xe6, 0x98, 0xdd, 0x82, 0x65, 0x7d, 0x15, 0x4b, 0x6d, 0x1a, 0x86, 0xa7, 0x7f,
+	0x3c, 0x17, 0xf0, 0x11, 0x5c, 0xed, 0x51, 0xa1, 0xb3, 0xd3, 0xa9, 0x64, 0x17, 0xaa, 0xa0, 0x99,
+	0xfb, 0xb2, 0xfe, 0x40, 0xa7, 0xac, 0x75, 0x4a, 0x97, 0xb3, 0x86, 0x5e, 0xbe, 0x5a, 0x4a, 0xa3,
+	0x9d, 0xbb, 0x2e, 0xfc, 0x05, 0xbc, 0x07, 0x30, 0x59, 0x4c, 0x98, 0x69, 0xce, 0xec, 0xaa, 0x99,
+	0x8c, 0xef, 0x41, 0xa3, 0x67, 0xed, 0x97, 0x52, 0xbf, 0x33, 0x5b, 0xc3, 0x5f, 0xc0, 0x07, 0xd0,
+	0xb0, 0xf7, 0x12, 0x36, 0x27, 0x1e, 0xa7, 0x97, 0x55, 0x89, 0xcf, 0x2b, 0x3d, 0x2a, 0x0a, 0x2d,
+	0x5b, 0x2c, 0xd0, 0x66, 0xc9, 0xa3, 0x48, 0xf9, 0xac, 0xa9, 0x81, 0x4a, 0xce, 0x28, 0x5e, 0xcf,
+	0xfc, 0x95, 0x2f, 0xad, 0x19, 0x9f, 0x4f, 0xc1, 0x95, 0x6a, 0x66, 0x7d, 0xe0, 0x8e, 0x11, 0xcf,
+	0x5f, 0x27, 0x73, 0xfa, 0xe9, 0x29, 0xa0, 0xc6, 0x91, 0x3d, 0x95, 0x99, 0xe0, 0x76, 0x0e, 0x37,
+	0x67, 0xea, 0xcf, 0xc4, 0xf3, 0x04, 0xb0, 0x47, 0xc5, 0xf4, 0xf8, 0x2d, 0x9d, 0xed, 0xcd, 0xab,
+	0xd9, 0xb9, 0x17, 0xb5, 0xfd, 0x05, 0x7c, 0x2c, 0x17, 0xa2, 0x74, 0xf9, 0x82, 0x1e, 0x93, 0xf0,
+	0xbc, 0x5f, 0xf8, 0x01, 0x79, 0x93, 0x8e, 0x3f, 0x5c, 0x56, 0xef, 0xc5, 0xf7, 0xfe, 0x0a, 0x00,
+	0x00, 0xff, 0xff, 0xd7, 0xe8, 0x94, 0xb2, 0x94, 0x11, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var clusterComponentHealthyDesc = prometheus.NewDesc(
+	prometheus.BuildFQName("", "cluster_manager", "cluster_component_healthy"),
+	"Whether a component of a downstream cluster is healthy (1) or not (0), as reported by the health checks of the cluster",
+	[]string{"cluster", "component"},
+	nil,
+)
+
+// clusterHealthCollector exports the health of the components of the clusters from their health check conditions at
+// scrape time.
+type clusterHealthCollector struct {
+	clusterCache mgmtcontrollers.ClusterCache
+}
+
+// Describe implements prometheus.Collector.
+func (c *clusterHealthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clusterComponentHealthyDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *clusterHealthCollector) Collect(ch chan<- prometheus.Metric) {
+	clusters, err := c.clusterCache.List(labels.Everything())
+	if err != nil {
+		logrus.Errorf("[prometheus-cluster-health-metrics] couldn't list clusters: %v", err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		for _, cond := range cluster.Status.Conditions {
+			component, ok := strings.CutPrefix(string(cond.Type), v3.ClusterHealthCheckConditionPrefix)
+			if !ok {
+				continue
+			}
+			var value float64
+			if cond.Status == v1.ConditionTrue {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(clusterComponentHealthyDesc, prometheus.GaugeValue, value, cluster.Name, component)
+		}
+	}
+}
@@ -45,6 +45,15 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	// Cluster Owner
 	prometheus.MustRegister(clusterOwner)
 
+	// tunnel sessions of the agents
+	prometheus.MustRegister(scaledContext.Wrangler.TunnelSessions)
+
+	// requests rejected by the rate limits of the cluster proxies
+	prometheus.MustRegister(scaledContext.Wrangler.ClusterProxyLimiter)
+
+	// health of the components of the clusters
+	prometheus.MustRegister(&clusterHealthCollector{clusterCache: scaledContext.Wrangler.Mgmt.Cluster().Cache()})
+
 	// node and node core metrics
 	prometheus.MustRegister(numNodes)
 	prometheus.MustRegister(numCores)
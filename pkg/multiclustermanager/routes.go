@@ -40,9 +40,9 @@
 func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcmauthorizer.Authorizer, scaledContext *config.ScaledContext, clusterManager *clustermanager.Manager) (func(http.Handler) http.Handler, error) {
 	var (
 		k8sProxy             = k8sProxyPkg.New(scaledContext, scaledContext.Dialer, clusterManager)
-		connectHandler       = scaledContext.Dialer.(*rancherdialer.Factory).TunnelServer
+		connectHandler       = scaledContext.Wrangler.TunnelSessions.Handler(scaledContext.Dialer.(*rancherdialer.Factory).TunnelServer)
 		connectConfigHandler = rkenodeconfigserver.Handler(tunnelAuthorizer, scaledContext)
-		clusterImport        = clusterregistrationtokens.ClusterImport{Clusters: scaledContext.Management.Clusters("")}
+		clusterImport        = clusterregistrationtokens.ClusterImport{Clusters: scaledContext.Management.Clusters(""), ClusterByToken: tunnelAuthorizer.ClusterByToken}
 	)
 
 	tokenAPI, err := tokens.NewAPIHandler(ctx, scaledContext, norman.ConfigureAPIUI)
@@ -77,6 +77,8 @@ func router(ctx context.Context, localClusterEnabled bool, tunnelAuthorizer *mcm
 	unauthed.Handle("/v3/connect", connectHandler)
 	unauthed.Handle("/v3/connect/register", connectHandler)
 	unauthed.Handle("/v3/import/{token}_{clusterId}.yaml", http.HandlerFunc(clusterImport.ClusterImportHandler))
+	unauthed.Handle("/v3/import/{token}_{clusterId}/preflight.yaml", http.HandlerFunc(clusterImport.PreflightHandler)).Methods(http.MethodGet)
+	unauthed.Handle("/v3/import/{token}_{clusterId}/preflight", http.HandlerFunc(clusterImport.PreflightReportHandler)).Methods(http.MethodPost)
 	unauthed.Handle("/v3/settings/cacerts", managementAPI).MatcherFunc(onlyGet)
 	unauthed.Handle("/v3/settings/first-login", managementAPI).MatcherFunc(onlyGet)
 	unauthed.Handle("/v3/settings/ui-banners", managementAPI).MatcherFunc(onlyGet)
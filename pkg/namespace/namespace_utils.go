@@ -109,6 +109,24 @@ func IsNamespaceConditionSet(namespace *v1.Namespace, conditionType string, cond
 	return false, nil
 }
 
+// GetNamespaceConditionMessage returns the message of the condition of the namespace, or an empty string if it isn't set.
+func GetNamespaceConditionMessage(namespace *v1.Namespace, conditionType string) (string, error) {
+	ann := namespace.ObjectMeta.Annotations[statusAnn]
+	if ann == "" {
+		return "", nil
+	}
+	status := &status{}
+	if err := json.Unmarshal([]byte(ann), status); err != nil {
+		return "", err
+	}
+	for _, c := range status.Conditions {
+		if c.Type == conditionType {
+			return c.Message, nil
+		}
+	}
+	return "", nil
+}
+
 type status struct {
 	Conditions []condition
 }
@@ -0,0 +1,194 @@
+package project
+
+import (
+	"sort"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	mgmtcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// ParentProjectAnnotation is set on a project to its ParentProjectName once the parent is validated, see
+// ValidateParent. The tree of projects is built from this annotation, so that an invalid parent written by any client
+// has no effect.
+const ParentProjectAnnotation = "authz.management.cattle.io/parent-project"
+
+// SubProjectsAnnotation is set on a project to the comma separated names of the projects it accepts as sub-projects. A
+// ParentProjectName is only validated when the parent accepts the project, so that setting it takes rights on the
+// parent: the API stores accept a sub-project for the users who can update the parent or create its
+// ProjectRoleTemplateBindings, see AcceptSubProject, and only such users can write the annotation themselves.
+const SubProjectsAnnotation = "authz.management.cattle.io/sub-projects"
+
+// ParentName returns the name of the validated parent of the project, or an empty string.
+func ParentName(p *v3.Project) string {
+	return p.Annotations[ParentProjectAnnotation]
+}
+
+// AcceptsSubProject returns whether the parent accepts the project with the name as a sub-project.
+func AcceptsSubProject(parent *v3.Project, name string) bool {
+	for _, accepted := range strings.Split(parent.Annotations[SubProjectsAnnotation], ",") {
+		if accepted != "" && accepted == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptSubProject records that the parent accepts the project with the name as a sub-project. It is a no-op if the
+// parent doesn't exist.
+func AcceptSubProject(projects mgmtcontrollers.ProjectClient, namespace, parentName, name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		parent, err := projects.Get(namespace, parentName, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if AcceptsSubProject(parent, name) {
+			return nil
+		}
+		parent = parent.DeepCopy()
+		if parent.Annotations == nil {
+			parent.Annotations = map[string]string{}
+		}
+		accepted := []string{name}
+		if value := parent.Annotations[SubProjectsAnnotation]; value != "" {
+			accepted = append(strings.Split(value, ","), name)
+		}
+		sort.Strings(accepted)
+		parent.Annotations[SubProjectsAnnotation] = strings.Join(accepted, ",")
+		_, err = projects.Update(parent)
+		return err
+	})
+}
+
+// Tree is the hierarchy of the projects of a cluster, built from their validated parent. Projects whose parent doesn't
+// exist, and projects in a cycle, are roots of the tree.
+type Tree struct {
+	projects map[string]*v3.Project
+	children map[string][]*v3.Project
+}
+
+// NewTree builds the tree of the projects of a single cluster.
+func NewTree(projects []*v3.Project) *Tree {
+	t := &Tree{
+		projects: make(map[string]*v3.Project, len(projects)),
+		children: map[string][]*v3.Project{},
+	}
+	for _, p := range projects {
+		t.projects[p.Name] = p
+	}
+	for _, p := range projects {
+		if parent := t.Parent(p.Name); parent != nil {
+			t.children[parent.Name] = append(t.children[parent.Name], p)
+		}
+	}
+	for _, children := range t.children {
+		sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	}
+	return t
+}
+
+// Get returns the project with the name, or nil if it isn't in the tree.
+func (t *Tree) Get(name string) *v3.Project {
+	return t.projects[name]
+}
+
+// Parent returns the parent of the project, or nil if it is a root of the tree.
+func (t *Tree) Parent(name string) *v3.Project {
+	p := t.projects[name]
+	if p == nil || ParentName(p) == "" || ParentName(p) == name {
+		return nil
+	}
+	parent := t.projects[ParentName(p)]
+	if parent == nil {
+		return nil
+	}
+	// the projects in a cycle are roots, so that the cycle is broken
+	seen := map[string]bool{name: true}
+	for ancestor := parent; ancestor != nil; ancestor = t.projects[ParentName(ancestor)] {
+		if ancestor.Name == name {
+			return nil
+		}
+		if seen[ancestor.Name] {
+			break
+		}
+		seen[ancestor.Name] = true
+	}
+	return parent
+}
+
+// Ancestors returns the ancestors of the project, from its parent to the root of the tree.
+func (t *Tree) Ancestors(name string) []*v3.Project {
+	var ancestors []*v3.Project
+	for parent := t.Parent(name); parent != nil; parent = t.Parent(parent.Name) {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// Descendants returns the sub-projects of the project, and their own sub-projects, in breadth-first order.
+func (t *Tree) Descendants(name string) []*v3.Project {
+	var descendants []*v3.Project
+	queue := t.children[name]
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		descendants = append(descendants, p)
+		queue = append(queue, t.children[p.Name]...)
+	}
+	return descendants
+}
+
+// Root returns the root of the tree of the project, which is the project itself if it is a root, or nil if the project
+// isn't in the tree.
+func (t *Tree) Root(name string) *v3.Project {
+	root := t.projects[name]
+	for _, ancestor := range t.Ancestors(name) {
+		root = ancestor
+	}
+	return root
+}
+
+// IsDescendant returns whether the project is a descendant of the ancestor.
+func (t *Tree) IsDescendant(name, ancestor string) bool {
+	for _, p := range t.Ancestors(name) {
+		if p.Name == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// IsolationRoot returns the project the network isolation of the project applies at: its highest ancestor with the
+// Tree network isolation, or the project itself.
+func (t *Tree) IsolationRoot(name string) *v3.Project {
+	root := t.projects[name]
+	for _, ancestor := range t.Ancestors(name) {
+		if ancestor.Spec.NetworkIsolation == v3.ProjectNetworkIsolationTree {
+			root = ancestor
+		}
+	}
+	return root
+}
+
+// IsolationGroup returns the names of the projects whose namespaces are isolated together with the namespaces of the
+// project, including itself.
+func (t *Tree) IsolationGroup(name string) []string {
+	root := t.IsolationRoot(name)
+	if root == nil {
+		return []string{name}
+	}
+	group := []string{root.Name}
+	if root.Spec.NetworkIsolation == v3.ProjectNetworkIsolationTree {
+		for _, p := range t.Descendants(root.Name) {
+			group = append(group, p.Name)
+		}
+	}
+	sort.Strings(group)
+	return group
+}
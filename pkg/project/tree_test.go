@@ -0,0 +1,162 @@
+package project
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newProject returns a project whose parent is validated.
+func newProject(name, parent, isolation string) *v3.Project {
+	p := &v3.Project{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "c-1"},
+		Spec: v3.ProjectSpec{
+			ParentProjectName: parent,
+			NetworkIsolation:  isolation,
+		},
+	}
+	if parent != "" {
+		p.Annotations = map[string]string{ParentProjectAnnotation: parent}
+	}
+	return p
+}
+
+func names(projects []*v3.Project) []string {
+	var result []string
+	for _, p := range projects {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+func TestTree(t *testing.T) {
+	tree := NewTree([]*v3.Project{
+		newProject("org", "", ""),
+		newProject("team-b", "org", ""),
+		newProject("team-a", "org", ""),
+		newProject("app", "team-a", ""),
+		newProject("orphan", "missing", ""),
+		newProject("self", "self", ""),
+	})
+
+	assert.Equal(t, "team-a", tree.Parent("app").Name)
+	assert.Nil(t, tree.Parent("org"))
+	assert.Nil(t, tree.Parent("orphan"))
+	assert.Nil(t, tree.Parent("self"))
+	assert.Nil(t, tree.Parent("unknown"))
+
+	assert.Equal(t, []string{"team-a", "org"}, names(tree.Ancestors("app")))
+	assert.Equal(t, []string{"team-a", "team-b", "app"}, names(tree.Descendants("org")))
+	assert.Empty(t, tree.Descendants("app"))
+
+	assert.Equal(t, "org", tree.Root("app").Name)
+	assert.Equal(t, "org", tree.Root("org").Name)
+	assert.Nil(t, tree.Root("unknown"))
+
+	assert.True(t, tree.IsDescendant("app", "org"))
+	assert.False(t, tree.IsDescendant("org", "app"))
+	assert.False(t, tree.IsDescendant("team-b", "team-a"))
+}
+
+func TestTreeIgnoresParentsNotValidated(t *testing.T) {
+	pending := newProject("app", "", "")
+	pending.Spec.ParentProjectName = "org"
+	tree := NewTree([]*v3.Project{newProject("org", "", ""), pending})
+
+	assert.Nil(t, tree.Parent("app"))
+	assert.Empty(t, tree.Descendants("org"))
+}
+
+func TestTreeCycle(t *testing.T) {
+	tree := NewTree([]*v3.Project{
+		newProject("a", "b", ""),
+		newProject("b", "a", ""),
+		newProject("c", "a", ""),
+	})
+
+	assert.Nil(t, tree.Parent("a"))
+	assert.Nil(t, tree.Parent("b"))
+	assert.Equal(t, "a", tree.Parent("c").Name)
+	assert.Equal(t, []string{"a"}, names(tree.Ancestors("c")))
+	assert.Empty(t, tree.Descendants("b"))
+}
+
+func TestIsolationGroup(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects []*v3.Project
+		project  string
+		want     []string
+	}{
+		{
+			name: "project isolation",
+			projects: []*v3.Project{
+				newProject("org", "", ""),
+				newProject("team", "org", ""),
+			},
+			project: "team",
+			want:    []string{"team"},
+		},
+		{
+			name: "tree isolation at the root",
+			projects: []*v3.Project{
+				newProject("org", "", v3.ProjectNetworkIsolationTree),
+				newProject("team", "org", ""),
+				newProject("app", "team", v3.ProjectNetworkIsolationProject),
+			},
+			project: "app",
+			want:    []string{"app", "org", "team"},
+		},
+		{
+			name: "highest tree isolation wins",
+			projects: []*v3.Project{
+				newProject("org", "", ""),
+				newProject("team", "org", v3.ProjectNetworkIsolationTree),
+				newProject("app", "team", v3.ProjectNetworkIsolationTree),
+				newProject("other", "org", ""),
+			},
+			project: "app",
+			want:    []string{"app", "team"},
+		},
+		{
+			name:     "unknown project",
+			projects: []*v3.Project{newProject("org", "", v3.ProjectNetworkIsolationTree)},
+			project:  "unknown",
+			want:     []string{"unknown"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewTree(tt.projects).IsolationGroup(tt.project))
+		})
+	}
+}
+
+func TestAcceptSubProject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	projects := fake.NewMockClientInterface[*v3.Project, *v3.ProjectList](ctrl)
+	projects.EXPECT().Get("c-1", "org", gomock.Any()).Return(accepting(newProject("org", "", ""), "team"), nil)
+	var updated *v3.Project
+	projects.EXPECT().Update(gomock.Any()).DoAndReturn(func(p *v3.Project) (*v3.Project, error) {
+		updated = p
+		return p, nil
+	})
+
+	assert.NoError(t, AcceptSubProject(projects, "c-1", "org", "app"))
+	assert.Equal(t, "app,team", updated.Annotations[SubProjectsAnnotation])
+	assert.True(t, AcceptsSubProject(updated, "app"))
+	assert.False(t, AcceptsSubProject(updated, "ap"))
+}
+
+func TestAcceptSubProjectMissingParent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	projects := fake.NewMockClientInterface[*v3.Project, *v3.ProjectList](ctrl)
+	projects.EXPECT().Get("c-1", "missing", gomock.Any()).Return(nil, apierrors.NewNotFound(v3.Resource("projects"), "missing"))
+
+	assert.NoError(t, AcceptSubProject(projects, "c-1", "missing", "app"))
+}
@@ -0,0 +1,79 @@
+package project
+
+import (
+	"fmt"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/resourcequota"
+	"github.com/rancher/rancher/pkg/utils"
+)
+
+// ValidateParent returns an error if the ParentProjectName of the project is invalid, given the projects of its cluster.
+// The parent must be another project of the cluster which accepts the project as a sub-project, see
+// SubProjectsAnnotation, and isn't being deleted, neither of them can be the System
+// Project, and the parent can't be a sub-project of the project. When the parent has a resource quota, the project must
+// have one too, whose limit fits in the limit of the parent left by its other sub-projects.
+func ValidateParent(p *v3.Project, projects []*v3.Project) error {
+	parentName := p.Spec.ParentProjectName
+	if parentName == "" {
+		return nil
+	}
+	if parentName == p.Name {
+		return fmt.Errorf("project cannot be its own parent")
+	}
+	if IsSystemProject(p) {
+		return fmt.Errorf("System Project cannot have a parent project")
+	}
+
+	byName := make(map[string]*v3.Project, len(projects))
+	for _, project := range projects {
+		byName[project.Name] = project
+	}
+	parent := byName[parentName]
+	// a parent which doesn't accept the project is reported as a missing one, so that its existence isn't revealed
+	if parent == nil || !AcceptsSubProject(parent, p.Name) {
+		return fmt.Errorf("project %s not found in cluster %s or it doesn't accept the project as a sub-project", parentName, p.Namespace)
+	}
+	if parent.DeletionTimestamp != nil {
+		return fmt.Errorf("project %s is being deleted", parentName)
+	}
+	if IsSystemProject(parent) {
+		return fmt.Errorf("System Project cannot have sub-projects")
+	}
+	// the parents requested by the projects are followed, whether they are validated or not, so that two projects
+	// can't become each other's parent
+	seen := map[string]bool{}
+	for ancestor := parent; ancestor != nil && !seen[ancestor.Name]; ancestor = byName[ancestor.Spec.ParentProjectName] {
+		if ancestor.Spec.ParentProjectName == p.Name {
+			return fmt.Errorf("project %s is a sub-project of the project", parentName)
+		}
+		seen[ancestor.Name] = true
+	}
+
+	if parent.Spec.ResourceQuota == nil {
+		return nil
+	}
+	if p.Spec.ResourceQuota == nil {
+		return fmt.Errorf("resource quota is required when the parent project %s has a resource quota", parentName)
+	}
+	var siblingLimits []*v3.ResourceQuotaLimit
+	for _, sibling := range projects {
+		if sibling.Name == p.Name || ParentName(sibling) != parentName || sibling.DeletionTimestamp != nil || sibling.Spec.ResourceQuota == nil {
+			continue
+		}
+		siblingLimits = append(siblingLimits, &sibling.Spec.ResourceQuota.Limit)
+	}
+	isFit, exceeded, err := resourcequota.IsQuotaFit(&p.Spec.ResourceQuota.Limit, siblingLimits, &parent.Spec.ResourceQuota.Limit)
+	if err != nil {
+		return err
+	}
+	if !isFit {
+		return fmt.Errorf("resource quota exceeds the limit left in the parent project %s on fields: %s", parentName, utils.FormatResourceList(exceeded))
+	}
+	return nil
+}
+
+// IsSystemProject returns whether the project is the System Project of its cluster.
+func IsSystemProject(p *v3.Project) bool {
+	return p.Labels["authz.management.cattle.io/system-project"] == "true"
+}
@@ -0,0 +1,115 @@
+package project
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func withQuota(p *v3.Project, pods string) *v3.Project {
+	p.Spec.ResourceQuota = &v3.ProjectResourceQuota{Limit: v3.ResourceQuotaLimit{Pods: pods}}
+	return p
+}
+
+func withSpecParent(p *v3.Project, parent string) *v3.Project {
+	p.Spec.ParentProjectName = parent
+	return p
+}
+
+func accepting(p *v3.Project, subProjects string) *v3.Project {
+	if p.Annotations == nil {
+		p.Annotations = map[string]string{}
+	}
+	p.Annotations[SubProjectsAnnotation] = subProjects
+	return p
+}
+
+func TestValidateParent(t *testing.T) {
+	system := accepting(newProject("system", "", ""), "app")
+	system.Labels = map[string]string{"authz.management.cattle.io/system-project": "true"}
+	deleting := accepting(newProject("deleting", "", ""), "app")
+	deleting.DeletionTimestamp = &metav1.Time{}
+	projects := []*v3.Project{
+		accepting(withQuota(newProject("org", "", ""), "10"), "app,team"),
+		withQuota(newProject("team", "org", ""), "6"),
+		accepting(newProject("free", "", ""), "app"),
+		accepting(withSpecParent(newProject("pending", "", ""), "app"), "app"),
+		system,
+		deleting,
+	}
+
+	tests := []struct {
+		name    string
+		project *v3.Project
+		wantErr string
+	}{
+		{
+			name:    "no parent",
+			project: newProject("app", "", ""),
+		},
+		{
+			name:    "parent without quota",
+			project: withSpecParent(newProject("app", "", ""), "free"),
+		},
+		{
+			name:    "own parent",
+			project: withSpecParent(newProject("app", "", ""), "app"),
+			wantErr: "project cannot be its own parent",
+		},
+		{
+			name:    "missing parent",
+			project: withSpecParent(newProject("app", "", ""), "missing"),
+			wantErr: "project missing not found in cluster c-1 or it doesn't accept the project as a sub-project",
+		},
+		{
+			name:    "parent doesn't accept the project",
+			project: withSpecParent(newProject("other", "", ""), "free"),
+			wantErr: "project free not found in cluster c-1 or it doesn't accept the project as a sub-project",
+		},
+		{
+			name:    "parent being deleted",
+			project: withSpecParent(newProject("app", "", ""), "deleting"),
+			wantErr: "project deleting is being deleted",
+		},
+		{
+			name:    "System Project parent",
+			project: withSpecParent(newProject("app", "", ""), "system"),
+			wantErr: "System Project cannot have sub-projects",
+		},
+		{
+			name:    "cycle through a parent not validated yet",
+			project: withSpecParent(newProject("app", "", ""), "pending"),
+			wantErr: "project pending is a sub-project of the project",
+		},
+		{
+			name:    "quota required",
+			project: withSpecParent(newProject("app", "", ""), "org"),
+			wantErr: "resource quota is required when the parent project org has a resource quota",
+		},
+		{
+			name:    "quota fits",
+			project: withQuota(withSpecParent(newProject("app", "", ""), "org"), "4"),
+		},
+		{
+			name:    "quota exceeds the limit left by the other sub-projects",
+			project: withQuota(withSpecParent(newProject("app", "", ""), "org"), "5"),
+			wantErr: "resource quota exceeds the limit left in the parent project org on fields: pods=11",
+		},
+		{
+			name:    "own limit isn't counted twice",
+			project: withQuota(withSpecParent(newProject("team", "org", ""), "org"), "10"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParent(tt.project, projects)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -211,6 +211,7 @@ func New(ctx context.Context, clientConfg clientcmd.ClientConfig, opts *Options)
 	clusterProxy, err := proxy.NewProxyMiddleware(wranglerContext.K8s.AuthorizationV1(),
 		wranglerContext.TunnelServer.Dialer,
 		wranglerContext.Mgmt.Cluster().Cache(),
+		wranglerContext.ClusterProxyLimiter,
 		localClusterEnabled(opts),
 		steve,
 	)
@@ -3,6 +3,7 @@
 import (
 	"crypto/sha256"
 	"encoding/base32"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -221,34 +222,53 @@ func getBindingHash(namespace string, role rbacv1.RoleRef, subject rbacv1.Subjec
 // RulesFromTemplate gets all rules from the template and all referenced templates
 func RulesFromTemplate(clusterRoles k8srbacv1.ClusterRoleCache, roleTemplates v32.RoleTemplateCache, rt *v3.RoleTemplate) ([]rbacv1.PolicyRule, error) {
 	var rules []rbacv1.PolicyRule
-	var err error
-	templatesSeen := make(map[string]bool)
-
-	// Kickoff gathering rules
-	rules, err = gatherRules(clusterRoles, roleTemplates, rt, rules, templatesSeen)
+	err := gatherRules(clusterRoles, roleTemplates, rt, make(map[string]bool), func(_ *v3.RoleTemplate, templateRules []rbacv1.PolicyRule) {
+		rules = append(rules, templateRules...)
+	})
 	if err != nil {
-		return rules, err
+		return nil, err
 	}
 	return rules, nil
 }
 
-// gatherRules appends the rules from current template and does a recursive call to get all inherited roles referenced
-func gatherRules(clusterRoles k8srbacv1.ClusterRoleCache, roleTemplates v32.RoleTemplateCache, rt *v3.RoleTemplate, rules []rbacv1.PolicyRule, seen map[string]bool) ([]rbacv1.PolicyRule, error) {
+// TemplateRules are the rules of a single RoleTemplate, without the rules of the templates it references.
+type TemplateRules struct {
+	RoleTemplate *v3.RoleTemplate
+	Rules        []rbacv1.PolicyRule
+}
+
+// RulesByTemplate gets the rules from the template and all referenced templates, grouped by the template defining
+// them, in the same order as RulesFromTemplate.
+func RulesByTemplate(clusterRoles k8srbacv1.ClusterRoleCache, roleTemplates v32.RoleTemplateCache, rt *v3.RoleTemplate) ([]TemplateRules, error) {
+	var result []TemplateRules
+	err := gatherRules(clusterRoles, roleTemplates, rt, make(map[string]bool), func(rt *v3.RoleTemplate, templateRules []rbacv1.PolicyRule) {
+		result = append(result, TemplateRules{RoleTemplate: rt, Rules: templateRules})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// gatherRules passes the rules from current template to add and does a recursive call to get all inherited roles referenced
+func gatherRules(clusterRoles k8srbacv1.ClusterRoleCache, roleTemplates v32.RoleTemplateCache, rt *v3.RoleTemplate, seen map[string]bool, add func(*v3.RoleTemplate, []rbacv1.PolicyRule)) error {
 	seen[rt.Name] = true
 
+	var rules []rbacv1.PolicyRule
 	if rt.External {
 		if rt.ExternalRules != nil {
 			rules = append(rules, rt.ExternalRules...)
 		} else if rt.Context == "cluster" {
 			cr, err := clusterRoles.Get(rt.Name)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			rules = append(rules, cr.Rules...)
 		}
 	}
 
 	rules = append(rules, rt.Rules...)
+	add(rt, rules)
 
 	for _, r := range rt.RoleTemplateNames {
 		// If we have already seen the roleTemplate, skip it
@@ -257,14 +277,34 @@ func gatherRules(clusterRoles k8srbacv1.ClusterRoleCache, roleTemplates v32.Role
 		}
 		next, err := roleTemplates.Get(r)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rules, err = gatherRules(clusterRoles, roleTemplates, next, rules, seen)
-		if err != nil {
-			return nil, err
+		if err := gatherRules(clusterRoles, roleTemplates, next, seen, add); err != nil {
+			return err
 		}
 	}
-	return rules, nil
+	return nil
+}
+
+// IsAdminGlobalRole returns whether a GlobalRole grants full admin permissions: it is either the builtin admin role,
+// or it has rules granting all verbs on all resources and on all nonResourceURLs.
+func IsAdminGlobalRole(gr *v3.GlobalRole) bool {
+	if gr.Builtin && gr.Name == GlobalAdmin {
+		return true
+	}
+
+	var hasResourceRule, hasNonResourceRule bool
+	for _, rule := range gr.Rules {
+		if slices.Contains(rule.Resources, "*") && slices.Contains(rule.APIGroups, "*") && slices.Contains(rule.Verbs, "*") {
+			hasResourceRule = true
+			continue
+		}
+		if slices.Contains(rule.NonResourceURLs, "*") && slices.Contains(rule.Verbs, "*") {
+			hasNonResourceRule = true
+			continue
+		}
+	}
+	return hasResourceRule && hasNonResourceRule
 }
 
 func ProvisioningClusterAdminName(cluster *provv1.Cluster) string {
@@ -9,6 +9,10 @@
 	"github.com/rancher/norman/types"
 	mgmt "github.com/rancher/rancher/pkg/apis/management.cattle.io"
 	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -191,6 +195,57 @@ type testCase struct {
 	}
 }
 
+func TestRulesByTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	readRule := rbacv1.PolicyRule{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+	writeRule := rbacv1.PolicyRule{Verbs: []string{"create"}, APIGroups: []string{""}, Resources: []string{"pods"}}
+	externalRule := rbacv1.PolicyRule{Verbs: []string{"list"}, APIGroups: []string{""}, Resources: []string{"nodes"}}
+
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"view":     {ObjectMeta: metav1.ObjectMeta{Name: "view"}, Rules: []rbacv1.PolicyRule{readRule}},
+		"edit":     {ObjectMeta: metav1.ObjectMeta{Name: "edit"}, Rules: []rbacv1.PolicyRule{writeRule}, RoleTemplateNames: []string{"view"}},
+		"external": {ObjectMeta: metav1.ObjectMeta{Name: "external"}, Context: "cluster", External: true},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		return roleTemplates[name], nil
+	}).AnyTimes()
+	clusterRoleCache := fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl)
+	clusterRoleCache.EXPECT().Get("external").Return(&rbacv1.ClusterRole{Rules: []rbacv1.PolicyRule{externalRule}}, nil).AnyTimes()
+
+	owner := &v3.RoleTemplate{
+		ObjectMeta:        metav1.ObjectMeta{Name: "owner"},
+		RoleTemplateNames: []string{"edit", "view", "external"},
+	}
+	got, err := RulesByTemplate(clusterRoleCache, roleTemplateCache, owner)
+	require.NoError(t, err)
+
+	var names []string
+	for _, templateRules := range got {
+		names = append(names, templateRules.RoleTemplate.Name)
+	}
+	// view is inherited by both owner and edit, but only gathered once
+	assert.Equal(t, []string{"owner", "edit", "view", "external"}, names)
+	assert.Equal(t, []rbacv1.PolicyRule{writeRule}, got[1].Rules)
+	assert.Equal(t, []rbacv1.PolicyRule{externalRule}, got[3].Rules)
+
+	rules, err := RulesFromTemplate(clusterRoleCache, roleTemplateCache, owner)
+	require.NoError(t, err)
+	assert.Equal(t, []rbacv1.PolicyRule{writeRule, readRule, externalRule}, rules)
+}
+
+func TestIsAdminGlobalRole(t *testing.T) {
+	t.Parallel()
+	adminRules := []rbacv1.PolicyRule{
+		{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}},
+		{Verbs: []string{"*"}, NonResourceURLs: []string{"*"}},
+	}
+	assert.True(t, IsAdminGlobalRole(&v3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: GlobalAdmin}, Builtin: true}))
+	assert.True(t, IsAdminGlobalRole(&v3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "custom-admin"}, Rules: adminRules}))
+	assert.False(t, IsAdminGlobalRole(&v3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: "custom"}, Rules: adminRules[:1]}))
+	assert.False(t, IsAdminGlobalRole(&v3.GlobalRole{ObjectMeta: metav1.ObjectMeta{Name: GlobalAdmin}}))
+}
+
 func TestGetRTBLabel(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -0,0 +1,188 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	v32 "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	v3 "github.com/rancher/rancher/pkg/generated/norman/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/ref"
+	k8srbacv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/rbac/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/component-helpers/auth/rbac/validation"
+)
+
+// ErrEscalation is returned by EscalationChecker.ConfirmNoEscalation when a user isn't allowed to grant a RoleTemplate.
+var ErrEscalation = errors.New("privilege escalation")
+
+// EscalationChecker checks that users only grant the RoleTemplates they could bind themselves, for the controllers
+// creating ClusterRoleTemplateBindings and ProjectRoleTemplateBindings on behalf of a user. As for the bindings users
+// create, a RoleTemplate can be granted by the users holding all of its rules in the cluster or project, the admins,
+// and the users allowed to bind it.
+type EscalationChecker struct {
+	grbs           v32.GlobalRoleBindingCache
+	globalRoles    v32.GlobalRoleCache
+	crtbs          v32.ClusterRoleTemplateBindingCache
+	prtbs          v32.ProjectRoleTemplateBindingCache
+	roleTemplates  v32.RoleTemplateCache
+	clusterRoles   k8srbacv1.ClusterRoleCache
+	userAttributes v32.UserAttributeCache
+}
+
+// NewEscalationChecker returns an EscalationChecker using the caches of the management controllers.
+func NewEscalationChecker(mgmt v32.Interface, rbac k8srbacv1.Interface) *EscalationChecker {
+	return &EscalationChecker{
+		grbs:           mgmt.GlobalRoleBinding().Cache(),
+		globalRoles:    mgmt.GlobalRole().Cache(),
+		crtbs:          mgmt.ClusterRoleTemplateBinding().Cache(),
+		prtbs:          mgmt.ProjectRoleTemplateBinding().Cache(),
+		roleTemplates:  mgmt.RoleTemplate().Cache(),
+		clusterRoles:   rbac.ClusterRole().Cache(),
+		userAttributes: mgmt.UserAttribute().Cache(),
+	}
+}
+
+// ConfirmNoEscalation returns an error wrapping ErrEscalation if the user can't grant the RoleTemplate in the cluster,
+// or in the project if projectName, in the "cluster:project" format, isn't empty.
+func (e *EscalationChecker) ConfirmNoEscalation(userName, clusterName, projectName string, roleTemplate *v3.RoleTemplate) error {
+	byTemplate, err := RulesByTemplate(e.clusterRoles, e.roleTemplates, roleTemplate)
+	if err != nil {
+		return err
+	}
+	var requested []rbacv1.PolicyRule
+	for _, templateRules := range byTemplate {
+		requested = append(requested, templateRules.Rules...)
+		// the NamespaceSelectedRules can only be granted by the users holding them in the whole project
+		requested = append(requested, NamespaceSelectedPolicyRules(templateRules.RoleTemplate)...)
+	}
+	isSubject, err := e.subjectFilter(userName)
+	if err != nil {
+		return err
+	}
+
+	var held []rbacv1.PolicyRule
+	grbs, err := e.grbs.List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, grb := range grbs {
+		if grb.DeletionTimestamp != nil || !isSubject(grb.UserName, grb.GroupPrincipalName) {
+			continue
+		}
+		globalRole, err := e.globalRoles.Get(grb.GlobalRoleName)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if IsAdminGlobalRole(globalRole) || allowsBind(globalRole.Rules, roleTemplate.Name) {
+			return nil
+		}
+		// the InheritedClusterRoles don't apply in the local cluster
+		if clusterName == "local" {
+			continue
+		}
+		for _, name := range globalRole.InheritedClusterRoles {
+			rules, err := e.templateRules(name)
+			if err != nil {
+				return err
+			}
+			held = append(held, rules...)
+		}
+	}
+
+	crtbs, err := e.crtbs.List(clusterName, labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, crtb := range crtbs {
+		if crtb.DeletionTimestamp != nil || crtb.ClusterName != clusterName || !isSubject(crtb.UserName, crtb.GroupPrincipalName) {
+			continue
+		}
+		rules, err := e.templateRules(crtb.RoleTemplateName)
+		if err != nil {
+			return err
+		}
+		held = append(held, rules...)
+	}
+
+	if projectName != "" {
+		_, projectNamespace := ref.Parse(projectName)
+		prtbs, err := e.prtbs.List(projectNamespace, labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, prtb := range prtbs {
+			if prtb.DeletionTimestamp != nil || prtb.ProjectName != projectName || !isSubject(prtb.UserName, prtb.GroupPrincipalName) {
+				continue
+			}
+			rules, err := e.templateRules(prtb.RoleTemplateName)
+			if err != nil {
+				return err
+			}
+			held = append(held, rules...)
+		}
+	}
+
+	if allowsBind(held, roleTemplate.Name) {
+		return nil
+	}
+	if covered, missing := validation.Covers(held, requested); !covered {
+		return fmt.Errorf("%w: user [%s] can't grant RoleTemplate [%s] without holding its permissions, missing %d rules",
+			ErrEscalation, userName, roleTemplate.Name, len(missing))
+	}
+	return nil
+}
+
+// subjectFilter returns whether a binding to a user or a group principal applies to the user.
+func (e *EscalationChecker) subjectFilter(userName string) (func(userName, groupPrincipalName string) bool, error) {
+	var groups []string
+	userAttribute, err := e.userAttributes.Get(userName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if userAttribute != nil {
+		for _, principals := range userAttribute.GroupPrincipals {
+			for _, principal := range principals.Items {
+				groups = append(groups, principal.Name)
+			}
+		}
+	}
+	return func(bindingUserName, groupPrincipalName string) bool {
+		if bindingUserName != "" {
+			return bindingUserName == userName
+		}
+		return groupPrincipalName != "" && slices.Contains(groups, groupPrincipalName)
+	}, nil
+}
+
+// templateRules returns the rules of a RoleTemplate and the templates it inherits from. A binding to a missing
+// RoleTemplate holds no rules.
+func (e *EscalationChecker) templateRules(roleTemplateName string) ([]rbacv1.PolicyRule, error) {
+	roleTemplate, err := e.roleTemplates.Get(roleTemplateName)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return RulesFromTemplate(e.clusterRoles, e.roleTemplates, roleTemplate)
+}
+
+// allowsBind returns whether one of the rules allows binding the RoleTemplate.
+func allowsBind(rules []rbacv1.PolicyRule, roleTemplateName string) bool {
+	for _, rule := range rules {
+		if hasValue(rule.Verbs, "bind") && hasValue(rule.APIGroups, "management.cattle.io") &&
+			hasValue(rule.Resources, "roletemplates") &&
+			(len(rule.ResourceNames) == 0 || slices.Contains(rule.ResourceNames, roleTemplateName)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasValue(values []string, value string) bool {
+	return slices.Contains(values, value) || slices.Contains(values, rbacv1.VerbAll)
+}
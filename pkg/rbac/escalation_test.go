@@ -0,0 +1,207 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newEscalationChecker(t *testing.T, grbs []*v3.GlobalRoleBinding, crtbs []*v3.ClusterRoleTemplateBinding, prtbs []*v3.ProjectRoleTemplateBinding) *EscalationChecker {
+	ctrl := gomock.NewController(t)
+
+	roleTemplates := map[string]*v3.RoleTemplate{
+		"cluster-owner": {
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-owner"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+		},
+		"project-owner": {
+			ObjectMeta: metav1.ObjectMeta{Name: "project-owner"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{""}, Resources: []string{"pods", "secrets"}}},
+		},
+		"project-member": {
+			ObjectMeta:        metav1.ObjectMeta{Name: "project-member"},
+			Rules:             []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+			RoleTemplateNames: []string{"read-only"},
+		},
+		"read-only": {
+			ObjectMeta: metav1.ObjectMeta{Name: "read-only"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"configmaps"}}},
+		},
+		"web-editor": {
+			ObjectMeta: metav1.ObjectMeta{Name: "web-editor"},
+			Context:    "project",
+			NamespaceSelectedRules: []v3.NamespaceSelectedRules{{
+				NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+				Rules:             []rbacv1.PolicyRule{{Verbs: []string{"update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"web-*"}}},
+			}},
+		},
+		"web-config-editor": {
+			ObjectMeta: metav1.ObjectMeta{Name: "web-config-editor"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}, ResourceNames: []string{"web-config"}}},
+		},
+		"config-editor": {
+			ObjectMeta: metav1.ObjectMeta{Name: "config-editor"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"update"}, APIGroups: []string{""}, Resources: []string{"configmaps"}}},
+		},
+	}
+	roleTemplateCache := fake.NewMockNonNamespacedCacheInterface[*v3.RoleTemplate](ctrl)
+	roleTemplateCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.RoleTemplate, error) {
+		if rt, ok := roleTemplates[name]; ok {
+			return rt, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "roletemplates"}, name)
+	}).AnyTimes()
+
+	globalRoles := map[string]*v3.GlobalRole{
+		"admin": {ObjectMeta: metav1.ObjectMeta{Name: "admin"}, Builtin: true},
+		"binder": {
+			ObjectMeta: metav1.ObjectMeta{Name: "binder"},
+			Rules: []rbacv1.PolicyRule{{
+				Verbs: []string{"bind"}, APIGroups: []string{"management.cattle.io"}, Resources: []string{"roletemplates"},
+				ResourceNames: []string{"project-owner"},
+			}},
+		},
+	}
+	globalRoleCache := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRole](ctrl)
+	globalRoleCache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.GlobalRole, error) {
+		if gr, ok := globalRoles[name]; ok {
+			return gr, nil
+		}
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "globalroles"}, name)
+	}).AnyTimes()
+
+	grbCache := fake.NewMockNonNamespacedCacheInterface[*v3.GlobalRoleBinding](ctrl)
+	grbCache.EXPECT().List(gomock.Any()).Return(grbs, nil).AnyTimes()
+	crtbCache := fake.NewMockCacheInterface[*v3.ClusterRoleTemplateBinding](ctrl)
+	crtbCache.EXPECT().List("c-1", gomock.Any()).Return(crtbs, nil).AnyTimes()
+	prtbCache := fake.NewMockCacheInterface[*v3.ProjectRoleTemplateBinding](ctrl)
+	prtbCache.EXPECT().List("p-1", gomock.Any()).Return(prtbs, nil).AnyTimes()
+
+	userAttributeCache := fake.NewMockNonNamespacedCacheInterface[*v3.UserAttribute](ctrl)
+	userAttributeCache.EXPECT().Get("u-alice").Return(&v3.UserAttribute{
+		GroupPrincipals: map[string]v3.Principals{
+			"github": {Items: []v3.Principal{{ObjectMeta: metav1.ObjectMeta{Name: "github_team://devs"}}}},
+		},
+	}, nil).AnyTimes()
+
+	return &EscalationChecker{
+		grbs:           grbCache,
+		globalRoles:    globalRoleCache,
+		crtbs:          crtbCache,
+		prtbs:          prtbCache,
+		roleTemplates:  roleTemplateCache,
+		clusterRoles:   fake.NewMockNonNamespacedCacheInterface[*rbacv1.ClusterRole](ctrl),
+		userAttributes: userAttributeCache,
+	}
+}
+
+func TestConfirmNoEscalation(t *testing.T) {
+	tests := []struct {
+		name         string
+		grbs         []*v3.GlobalRoleBinding
+		crtbs        []*v3.ClusterRoleTemplateBinding
+		prtbs        []*v3.ProjectRoleTemplateBinding
+		roleTemplate string
+		wantErr      bool
+	}{
+		{
+			name:         "no bindings",
+			roleTemplate: "project-member",
+			wantErr:      true,
+		},
+		{
+			name:         "admin",
+			grbs:         []*v3.GlobalRoleBinding{{UserName: "u-alice", GlobalRoleName: "admin"}},
+			roleTemplate: "cluster-owner",
+		},
+		{
+			name:         "allowed to bind",
+			grbs:         []*v3.GlobalRoleBinding{{UserName: "u-alice", GlobalRoleName: "binder"}},
+			roleTemplate: "project-owner",
+		},
+		{
+			name:         "allowed to bind another role template",
+			grbs:         []*v3.GlobalRoleBinding{{UserName: "u-alice", GlobalRoleName: "binder"}},
+			roleTemplate: "cluster-owner",
+			wantErr:      true,
+		},
+		{
+			name:         "cluster owner",
+			crtbs:        []*v3.ClusterRoleTemplateBinding{{ClusterName: "c-1", UserName: "u-alice", RoleTemplateName: "cluster-owner"}},
+			roleTemplate: "project-owner",
+		},
+		{
+			name:         "cluster owner through a group",
+			crtbs:        []*v3.ClusterRoleTemplateBinding{{ClusterName: "c-1", GroupPrincipalName: "github_team://devs", RoleTemplateName: "cluster-owner"}},
+			roleTemplate: "project-owner",
+		},
+		{
+			name:         "cluster owner of another user",
+			crtbs:        []*v3.ClusterRoleTemplateBinding{{ClusterName: "c-1", UserName: "u-bob", RoleTemplateName: "cluster-owner"}},
+			roleTemplate: "project-owner",
+			wantErr:      true,
+		},
+		{
+			name:         "project owner grants project member",
+			prtbs:        []*v3.ProjectRoleTemplateBinding{{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "project-owner"}},
+			roleTemplate: "project-member",
+			// project-member inherits read-only, granting configmaps the project owner doesn't hold
+			wantErr: true,
+		},
+		{
+			name: "project owner and read-only grant project member",
+			prtbs: []*v3.ProjectRoleTemplateBinding{
+				{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "project-owner"},
+				{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "read-only"},
+			},
+			roleTemplate: "project-member",
+		},
+		{
+			name:         "project member grants project owner",
+			prtbs:        []*v3.ProjectRoleTemplateBinding{{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "project-member"}},
+			roleTemplate: "project-owner",
+			wantErr:      true,
+		},
+		{
+			name:         "project owner grants namespace selected rules",
+			prtbs:        []*v3.ProjectRoleTemplateBinding{{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "project-owner"}},
+			roleTemplate: "web-editor",
+			wantErr:      true,
+		},
+		{
+			name:         "names matching the pattern grant namespace selected rules",
+			prtbs:        []*v3.ProjectRoleTemplateBinding{{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "web-config-editor"}},
+			roleTemplate: "web-editor",
+			// the pattern also matches the objects created later
+			wantErr: true,
+		},
+		{
+			name:         "all names grant namespace selected rules",
+			prtbs:        []*v3.ProjectRoleTemplateBinding{{ProjectName: "c-1:p-1", UserName: "u-alice", RoleTemplateName: "config-editor"}},
+			roleTemplate: "web-editor",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEscalationChecker(t, tt.grbs, tt.crtbs, tt.prtbs)
+			roleTemplate, err := e.roleTemplates.Get(tt.roleTemplate)
+			require.NoError(t, err)
+
+			err = e.ConfirmNoEscalation("u-alice", "c-1", "c-1:p-1", roleTemplate)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrEscalation), "unexpected error %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
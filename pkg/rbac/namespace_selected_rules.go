@@ -0,0 +1,114 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// resourceNameWildcard ends the resource names of the rules of NamespaceSelectedRules which are prefix patterns.
+	resourceNameWildcard = "*"
+	// namespaceSelectedRoleSuffix suffixes the name of the Roles holding the NamespaceSelectedRules of a RoleTemplate.
+	namespaceSelectedRoleSuffix = "-namespace-selected"
+)
+
+// NamespaceSelectedRoleName returns the name of the Roles holding the NamespaceSelectedRules of a RoleTemplate in the
+// namespaces they apply in.
+func NamespaceSelectedRoleName(roleTemplateName string) string {
+	return roleTemplateName + namespaceSelectedRoleSuffix
+}
+
+// NamespaceSelectedPolicyRules returns the rules of all the NamespaceSelectedRules of a RoleTemplate, as if they applied
+// in every namespace of the project. Their resource name patterns expand to objects created later, so the names of
+// the rules with patterns are dropped: granting them requires access to all the objects of their resources.
+func NamespaceSelectedPolicyRules(rt *v3.RoleTemplate) []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	for _, selected := range rt.NamespaceSelectedRules {
+		for _, rule := range selected.Rules {
+			if HasResourceNamePatterns(rule) {
+				rule = *rule.DeepCopy()
+				rule.ResourceNames = nil
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ResourceNamePrefix returns the prefix of a resource name pattern like "web-*", and whether the name is a pattern.
+func ResourceNamePrefix(name string) (string, bool) {
+	if !strings.HasSuffix(name, resourceNameWildcard) {
+		return "", false
+	}
+	return strings.TrimSuffix(name, resourceNameWildcard), true
+}
+
+// HasResourceNamePatterns returns whether the ResourceNames of the rule hold prefix patterns.
+func HasResourceNamePatterns(rule rbacv1.PolicyRule) bool {
+	for _, name := range rule.ResourceNames {
+		if _, ok := ResourceNamePrefix(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateNamespaceSelectedRules validates the NamespaceSelectedRules of a RoleTemplate. They're only allowed in project
+// RoleTemplates, their selectors must be valid label selectors and the resource name patterns of their rules must be
+// a non-empty prefix followed by a single "*", in rules listing their API groups and resources without wildcards.
+func ValidateNamespaceSelectedRules(rt *v3.RoleTemplate) error {
+	if len(rt.NamespaceSelectedRules) == 0 {
+		return nil
+	}
+	if rt.Context != "project" {
+		return fmt.Errorf("namespaceSelectedRules are only allowed in role templates with the project context")
+	}
+	for i, selected := range rt.NamespaceSelectedRules {
+		if _, err := metav1.LabelSelectorAsSelector(&selected.NamespaceSelector); err != nil {
+			return fmt.Errorf("invalid namespaceSelector in namespaceSelectedRules[%d]: %w", i, err)
+		}
+		for j, rule := range selected.Rules {
+			if err := validateNamespaceSelectedRule(rule); err != nil {
+				return fmt.Errorf("invalid rule namespaceSelectedRules[%d].rules[%d]: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateNamespaceSelectedRule(rule rbacv1.PolicyRule) error {
+	if len(rule.Verbs) == 0 {
+		return fmt.Errorf("verbs must not be empty")
+	}
+	if len(rule.NonResourceURLs) > 0 {
+		return fmt.Errorf("nonResourceURLs can't be granted in namespaces")
+	}
+	for _, name := range rule.ResourceNames {
+		prefix, ok := ResourceNamePrefix(name)
+		if !ok {
+			if strings.Contains(name, resourceNameWildcard) {
+				return fmt.Errorf("resource name pattern %q must end with a single %q", name, resourceNameWildcard)
+			}
+			continue
+		}
+		if prefix == "" || strings.Contains(prefix, resourceNameWildcard) {
+			return fmt.Errorf("resource name pattern %q must be a non-empty prefix followed by a single %q", name, resourceNameWildcard)
+		}
+	}
+	if !HasResourceNamePatterns(rule) {
+		return nil
+	}
+	if len(rule.APIGroups) == 0 || len(rule.Resources) == 0 {
+		return fmt.Errorf("rules with resource name patterns must list their apiGroups and resources")
+	}
+	for _, value := range append(append([]string{}, rule.APIGroups...), rule.Resources...) {
+		if value == rbacv1.APIGroupAll || value == rbacv1.ResourceAll {
+			return fmt.Errorf("rules with resource name patterns can't use wildcard apiGroups or resources")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateNamespaceSelectedRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		context string
+		rules   []v3.NamespaceSelectedRules
+		wantErr string
+	}{
+		{
+			name:    "no rules",
+			context: "cluster",
+		},
+		{
+			name:    "valid rules",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{
+					NamespaceSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+					Rules: []rbacv1.PolicyRule{
+						{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"get"}},
+						{APIGroups: []string{""}, Resources: []string{"configmaps", "pods/log"}, ResourceNames: []string{"web-*", "shared"}, Verbs: []string{"get"}},
+					},
+				},
+			},
+		},
+		{
+			name:    "cluster context",
+			context: "cluster",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: "only allowed in role templates with the project context",
+		},
+		{
+			name:    "invalid selector",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{
+					NamespaceSelector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Bogus"}}},
+					Rules:             []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+				},
+			},
+			wantErr: "invalid namespaceSelector in namespaceSelectedRules[0]",
+		},
+		{
+			name:    "no verbs",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}}}},
+			},
+			wantErr: "verbs must not be empty",
+		},
+		{
+			name:    "non resource urls",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{NonResourceURLs: []string{"/healthz"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: "nonResourceURLs can't be granted in namespaces",
+		},
+		{
+			name:    "wildcard in the middle of a pattern",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web-*-db"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: `resource name pattern "web-*-db" must end with a single "*"`,
+		},
+		{
+			name:    "empty prefix",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"*"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: `resource name pattern "*" must be a non-empty prefix`,
+		},
+		{
+			name:    "several wildcards",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, ResourceNames: []string{"web**"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: `resource name pattern "web**" must be a non-empty prefix`,
+		},
+		{
+			name:    "pattern with wildcard resources",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"*"}, ResourceNames: []string{"web-*"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: "can't use wildcard apiGroups or resources",
+		},
+		{
+			name:    "pattern without api groups",
+			context: "project",
+			rules: []v3.NamespaceSelectedRules{
+				{Rules: []rbacv1.PolicyRule{{Resources: []string{"pods"}, ResourceNames: []string{"web-*"}, Verbs: []string{"get"}}}},
+			},
+			wantErr: "must list their apiGroups and resources",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNamespaceSelectedRules(&v3.RoleTemplate{Context: tt.context, NamespaceSelectedRules: tt.rules})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package resourcequota
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rancher/norman/types/convert"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// limitTypeOrder is the order of the items of the LimitRanges created for projects.
+var limitTypeOrder = map[api.LimitType]int{
+	api.LimitTypeContainer:             0,
+	api.LimitTypePod:                   1,
+	api.LimitTypePersistentVolumeClaim: 2,
+}
+
+// ConvertLimitRangeToLimitRangeItems converts the LimitRange of a project to the items of a Kubernetes LimitRange,
+// ordered by type.
+func ConvertLimitRangeToLimitRangeItems(limitRange *v32.ProjectLimitRange) ([]api.LimitRangeItem, error) {
+	if limitRange == nil {
+		return nil, nil
+	}
+	var items []api.LimitRangeItem
+	for _, limit := range limitRange.Limits {
+		item := api.LimitRangeItem{Type: api.LimitType(limit.Type)}
+		var err error
+		if item.Max, err = convertResourceMap(limit.Max); err != nil {
+			return nil, fmt.Errorf("%s max: %w", limit.Type, err)
+		}
+		if item.Min, err = convertResourceMap(limit.Min); err != nil {
+			return nil, fmt.Errorf("%s min: %w", limit.Type, err)
+		}
+		if item.Default, err = convertResourceMap(limit.Default); err != nil {
+			return nil, fmt.Errorf("%s default: %w", limit.Type, err)
+		}
+		if item.DefaultRequest, err = convertResourceMap(limit.DefaultRequest); err != nil {
+			return nil, fmt.Errorf("%s defaultRequest: %w", limit.Type, err)
+		}
+		if item.MaxLimitRequestRatio, err = convertResourceMap(limit.MaxLimitRequestRatio); err != nil {
+			return nil, fmt.Errorf("%s maxLimitRequestRatio: %w", limit.Type, err)
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return limitTypeOrder[items[i].Type] < limitTypeOrder[items[j].Type]
+	})
+	return items, nil
+}
+
+// ValidateLimitRangeItems checks that the items of a LimitRange are consistent: at most one item per supported type,
+// only the constraints supported by the type, and min <= default request <= default <= max for every resource.
+func ValidateLimitRangeItems(items []api.LimitRangeItem) error {
+	seen := map[api.LimitType]bool{}
+	for _, item := range items {
+		if _, ok := limitTypeOrder[item.Type]; !ok {
+			return fmt.Errorf("unsupported limit type %q", item.Type)
+		}
+		if seen[item.Type] {
+			return fmt.Errorf("limit type %s is set more than once", item.Type)
+		}
+		seen[item.Type] = true
+
+		if item.Type != api.LimitTypeContainer && (len(item.Default) > 0 || len(item.DefaultRequest) > 0) {
+			return fmt.Errorf("%s: default and defaultRequest are only valid for the %s type", item.Type, api.LimitTypeContainer)
+		}
+		if item.Type == api.LimitTypePersistentVolumeClaim && len(item.MaxLimitRequestRatio) > 0 {
+			return fmt.Errorf("%s: maxLimitRequestRatio is not valid for the %s type", item.Type, item.Type)
+		}
+		for name, ratio := range item.MaxLimitRequestRatio {
+			if ratio.Cmp(resource.MustParse("1")) < 0 {
+				return fmt.Errorf("%s: maxLimitRequestRatio of %s must be greater than or equal to 1", item.Type, name)
+			}
+		}
+		for _, check := range []struct {
+			lowName, highName string
+			low, high         api.ResourceList
+		}{
+			{"min", "max", item.Min, item.Max},
+			{"min", "defaultRequest", item.Min, item.DefaultRequest},
+			{"defaultRequest", "default", item.DefaultRequest, item.Default},
+			{"default", "max", item.Default, item.Max},
+		} {
+			for name, low := range check.low {
+				if high, ok := check.high[name]; ok && low.Cmp(high) > 0 {
+					return fmt.Errorf("%s: %s of %s must be less than or equal to %s", item.Type, check.lowName, name, check.highName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func convertResourceMap(values map[string]string) (api.ResourceList, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	toReturn := api.ResourceList{}
+	for key, value := range values {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid resource name %s: %s", key, strings.Join(errs, ", "))
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %s for resource %s: %w", value, key, err)
+		}
+		toReturn[api.ResourceName(key)] = q
+	}
+	return toReturn, nil
+}
+
+var limitRangerRequestConversion = map[string]string{
+	"requestsCpu":    "cpu",
+	"requestsMemory": "memory",
+}
+
+var limitRangerLimitConversion = map[string]string{
+	"limitsCpu":    "cpu",
+	"limitsMemory": "memory",
+}
+
+// NamespaceContainerDefaults returns the container defaults applied to a namespace: the ones of the namespace, completed
+// by the ones of its project, or else the ones of the project.
+func NamespaceContainerDefaults(nsLimit, projectLimit *v32.ContainerResourceLimit) (*v32.ContainerResourceLimit, error) {
+	if nsLimit == nil {
+		return projectLimit, nil
+	}
+	completed, err := completeLimit(nsLimit, projectLimit)
+	if err != nil || completed == nil {
+		return nsLimit, err
+	}
+	return completed, nil
+}
+
+// LimitRangeItems returns the items of the LimitRange created in a namespace: the items of the LimitRange of its
+// project, into which the container defaults are merged, the defaults taking priority over the ones of the project. It
+// returns an error if the merged items aren't valid, e.g. when a container default is above the max of the project.
+func LimitRangeItems(containerDefaults *v32.ContainerResourceLimit, limitRange *v32.ProjectLimitRange) ([]api.LimitRangeItem, error) {
+	items, err := ConvertLimitRangeToLimitRangeItems(limitRange)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := convertContainerResourceLimitToLimitRangeItem(containerDefaults)
+	if err != nil {
+		return nil, err
+	}
+	if defaults != nil {
+		merged := false
+		for i := range items {
+			if items[i].Type != api.LimitTypeContainer {
+				continue
+			}
+			items[i].Default = mergeResourceList(items[i].Default, defaults.Default)
+			items[i].DefaultRequest = mergeResourceList(items[i].DefaultRequest, defaults.DefaultRequest)
+			merged = true
+		}
+		if !merged {
+			items = append([]api.LimitRangeItem{*defaults}, items...)
+		}
+	}
+	for i := range items {
+		setLimitRangeItemDefaults(&items[i])
+	}
+	if err := ValidateLimitRangeItems(items); err != nil {
+		return nil, fmt.Errorf("invalid limit range: %w", err)
+	}
+	return items, nil
+}
+
+func convertContainerResourceLimitToLimitRangeItem(limit *v32.ContainerResourceLimit) (*api.LimitRangeItem, error) {
+	in, err := json.Marshal(limit)
+	if err != nil {
+		return nil, err
+	}
+	limitsMap := map[string]string{}
+	if err := json.Unmarshal(in, &limitsMap); err != nil {
+		return nil, err
+	}
+	if len(limitsMap) == 0 {
+		return nil, nil
+	}
+
+	limits := api.ResourceList{}
+	requests := api.ResourceList{}
+	for key, value := range limitsMap {
+		var resourceName api.ResourceName
+		request := false
+		if val, ok := limitRangerRequestConversion[key]; ok {
+			resourceName = api.ResourceName(val)
+			request = true
+		} else if val, ok := limitRangerLimitConversion[key]; ok {
+			resourceName = api.ResourceName(val)
+		}
+		if resourceName == "" {
+			continue
+		}
+
+		resourceQuantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity %s for %s: %w", value, key, err)
+		}
+		if request {
+			requests[resourceName] = resourceQuantity
+		} else {
+			limits[resourceName] = resourceQuantity
+		}
+	}
+	return &api.LimitRangeItem{
+		Type:           api.LimitTypeContainer,
+		Default:        limits,
+		DefaultRequest: requests,
+	}, nil
+}
+
+func completeLimit(existingLimit *v32.ContainerResourceLimit, defaultLimit *v32.ContainerResourceLimit) (*v32.ContainerResourceLimit, error) {
+	if defaultLimit == nil {
+		return nil, nil
+	}
+	existingLimitMap, err := convert.EncodeToMap(existingLimit)
+	if err != nil {
+		return nil, err
+	}
+	newLimitMap, err := convert.EncodeToMap(defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range existingLimitMap {
+		if _, ok := newLimitMap[key]; ok {
+			newLimitMap[key] = value
+		}
+	}
+
+	if reflect.DeepEqual(existingLimitMap, newLimitMap) {
+		return nil, nil
+	}
+
+	newLimit := &v32.ContainerResourceLimit{}
+	err = convert.ToObj(newLimitMap, newLimit)
+	return newLimit, err
+}
+
+func mergeResourceList(base, overrides api.ResourceList) api.ResourceList {
+	if len(overrides) == 0 {
+		return base
+	}
+	merged := api.ResourceList{}
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range overrides {
+		merged[name] = value
+	}
+	return merged
+}
+
+// setLimitRangeItemDefaults sets the defaults Kubernetes sets on the container items of a LimitRange, so the desired
+// items can be compared to the existing ones: the default limit defaults to the max, and the default request to the
+// default limit, then to the min.
+func setLimitRangeItemDefaults(item *api.LimitRangeItem) {
+	if item.Type != api.LimitTypeContainer {
+		return
+	}
+	for _, from := range []struct {
+		values api.ResourceList
+		to     *api.ResourceList
+	}{
+		{item.Max, &item.Default},
+		{item.Default, &item.DefaultRequest},
+		{item.Min, &item.DefaultRequest},
+	} {
+		for name, value := range from.values {
+			if *from.to == nil {
+				*from.to = api.ResourceList{}
+			}
+			if _, ok := (*from.to)[name]; !ok {
+				(*from.to)[name] = value.DeepCopy()
+			}
+		}
+	}
+}
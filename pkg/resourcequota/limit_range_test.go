@@ -0,0 +1,189 @@
+package resourcequota
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	api "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestConvertLimitRangeToLimitRangeItems(t *testing.T) {
+	items, err := ConvertLimitRangeToLimitRangeItems(&v32.ProjectLimitRange{
+		Limits: []v32.ProjectLimitRangeItem{
+			{Type: "PersistentVolumeClaim", Max: map[string]string{"storage": "10Gi"}},
+			{Type: "Pod", Max: map[string]string{"cpu": "4"}, MaxLimitRequestRatio: map[string]string{"memory": "2"}},
+			{Type: "Container", Default: map[string]string{"ephemeral-storage": "1Gi"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, api.LimitTypeContainer, items[0].Type)
+	assert.Equal(t, "1Gi", items[0].Default.StorageEphemeral().String())
+	assert.Equal(t, api.LimitTypePod, items[1].Type)
+	assert.Equal(t, "4", items[1].Max.Cpu().String())
+	assert.Equal(t, "2", items[1].MaxLimitRequestRatio.Memory().String())
+	assert.Equal(t, api.LimitTypePersistentVolumeClaim, items[2].Type)
+	assert.Equal(t, "10Gi", items[2].Max.Storage().String())
+
+	items, err = ConvertLimitRangeToLimitRangeItems(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, items)
+
+	_, err = ConvertLimitRangeToLimitRangeItems(&v32.ProjectLimitRange{
+		Limits: []v32.ProjectLimitRangeItem{{Type: "Pod", Max: map[string]string{"cpu": "four"}}},
+	})
+	assert.Error(t, err)
+}
+
+func TestValidateLimitRangeItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  []v32.ProjectLimitRangeItem
+		wantErr bool
+	}{
+		{
+			name: "valid limit range",
+			limits: []v32.ProjectLimitRangeItem{
+				{
+					Type:                 "Container",
+					Min:                  map[string]string{"cpu": "100m"},
+					Max:                  map[string]string{"cpu": "2", "ephemeral-storage": "4Gi"},
+					Default:              map[string]string{"cpu": "1", "ephemeral-storage": "2Gi"},
+					DefaultRequest:       map[string]string{"cpu": "500m", "ephemeral-storage": "1Gi"},
+					MaxLimitRequestRatio: map[string]string{"cpu": "4"},
+				},
+				{Type: "Pod", Min: map[string]string{"memory": "64Mi"}, Max: map[string]string{"memory": "8Gi"}},
+				{Type: "PersistentVolumeClaim", Min: map[string]string{"storage": "1Gi"}, Max: map[string]string{"storage": "100Gi"}},
+			},
+		},
+		{
+			name:    "unsupported type",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Image", Max: map[string]string{"storage": "1Gi"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate type",
+			limits: []v32.ProjectLimitRangeItem{
+				{Type: "Pod", Max: map[string]string{"cpu": "1"}},
+				{Type: "Pod", Max: map[string]string{"memory": "1Gi"}},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "default on pods",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Pod", Default: map[string]string{"cpu": "1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "ratio on persistent volume claims",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "PersistentVolumeClaim", MaxLimitRequestRatio: map[string]string{"storage": "2"}}},
+			wantErr: true,
+		},
+		{
+			name:    "ratio below one",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Container", MaxLimitRequestRatio: map[string]string{"cpu": "500m"}}},
+			wantErr: true,
+		},
+		{
+			name:    "min above max",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Pod", Min: map[string]string{"cpu": "2"}, Max: map[string]string{"cpu": "1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "default above max",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Container", Default: map[string]string{"memory": "2Gi"}, Max: map[string]string{"memory": "1Gi"}}},
+			wantErr: true,
+		},
+		{
+			name:    "default request above default",
+			limits:  []v32.ProjectLimitRangeItem{{Type: "Container", Default: map[string]string{"cpu": "1"}, DefaultRequest: map[string]string{"cpu": "2"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := ConvertLimitRangeToLimitRangeItems(&v32.ProjectLimitRange{Limits: tt.limits})
+			require.NoError(t, err)
+			err = ValidateLimitRangeItems(items)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLimitRangeItems(t *testing.T) {
+	limitRange := &v32.ProjectLimitRange{
+		Limits: []v32.ProjectLimitRangeItem{
+			{
+				Type: "PersistentVolumeClaim",
+				Min:  map[string]string{"storage": "1Gi"},
+				Max:  map[string]string{"storage": "10Gi"},
+			},
+			{
+				Type:                 "Container",
+				Max:                  map[string]string{"cpu": "2", "ephemeral-storage": "4Gi"},
+				Default:              map[string]string{"cpu": "1", "ephemeral-storage": "2Gi"},
+				MaxLimitRequestRatio: map[string]string{"cpu": "4"},
+			},
+		},
+	}
+	containerDefaults := &v32.ContainerResourceLimit{
+		LimitsCPU:      "500m",
+		RequestsMemory: "128Mi",
+	}
+
+	items, err := LimitRangeItems(containerDefaults, limitRange)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	container := items[0]
+	assert.Equal(t, api.LimitTypeContainer, container.Type)
+	assert.True(t, apiequality.Semantic.DeepEqual(api.ResourceList{
+		api.ResourceCPU:              resource.MustParse("500m"),
+		api.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+	}, container.Default))
+	assert.True(t, apiequality.Semantic.DeepEqual(api.ResourceList{
+		api.ResourceCPU:              resource.MustParse("500m"),
+		api.ResourceMemory:           resource.MustParse("128Mi"),
+		api.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+	}, container.DefaultRequest))
+	assert.Equal(t, api.LimitTypePersistentVolumeClaim, items[1].Type)
+
+	// the container defaults alone are kept as they were
+	items, err = LimitRangeItems(containerDefaults, nil)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	items, err = LimitRangeItems(nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// container defaults above the max of the project are rejected
+	_, err = LimitRangeItems(&v32.ContainerResourceLimit{LimitsCPU: "3"}, &v32.ProjectLimitRange{
+		Limits: []v32.ProjectLimitRangeItem{{Type: "Container", Max: map[string]string{"cpu": "2"}}},
+	})
+	assert.EqualError(t, err, "invalid limit range: Container: default of cpu must be less than or equal to max")
+}
+
+func TestNamespaceContainerDefaults(t *testing.T) {
+	project := &v32.ContainerResourceLimit{LimitsCPU: "1", RequestsCPU: "500m"}
+
+	limit, err := NamespaceContainerDefaults(nil, project)
+	require.NoError(t, err)
+	assert.Equal(t, project, limit)
+
+	limit, err = NamespaceContainerDefaults(&v32.ContainerResourceLimit{LimitsCPU: "2"}, project)
+	require.NoError(t, err)
+	assert.Equal(t, &v32.ContainerResourceLimit{LimitsCPU: "2", RequestsCPU: "500m"}, limit)
+
+	namespace := &v32.ContainerResourceLimit{LimitsMemory: "1Gi"}
+	limit, err = NamespaceContainerDefaults(namespace, nil)
+	require.NoError(t, err)
+	assert.Equal(t, namespace, limit)
+}
@@ -1,6 +1,8 @@
 package resourcequota
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,6 +11,7 @@
 	api "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/validation"
 	quota "k8s.io/apiserver/pkg/quota/v1"
 )
 
@@ -57,9 +60,34 @@ func IsQuotaFit(nsLimit *v32.ResourceQuotaLimit, nsLimits []*v32.ResourceQuotaLi
 	return false, failedHard, nil
 }
 
+// standardResourceNames are the Kubernetes names of the resources with a field of their own in a ResourceQuotaLimit,
+// by field.
+var standardResourceNames = map[string]api.ResourceName{
+	"pods":                   api.ResourcePods,
+	"services":               api.ResourceServices,
+	"replicationControllers": api.ResourceReplicationControllers,
+	"secrets":                api.ResourceSecrets,
+	"configMaps":             api.ResourceConfigMaps,
+	"persistentVolumeClaims": api.ResourcePersistentVolumeClaims,
+	"servicesNodePorts":      api.ResourceServicesNodePorts,
+	"servicesLoadBalancers":  api.ResourceServicesLoadBalancers,
+	"requestsCpu":            api.ResourceRequestsCPU,
+	"requestsMemory":         api.ResourceRequestsMemory,
+	"requestsStorage":        api.ResourceRequestsStorage,
+	"limitsCpu":              api.ResourceLimitsCPU,
+	"limitsMemory":           api.ResourceLimitsMemory,
+}
+
+// ConvertLimitToResourceList converts a limit to a resource list, keyed by field for the resources with a field of
+// their own and by Kubernetes resource name for the extended ones.
 func ConvertLimitToResourceList(limit *v32.ResourceQuotaLimit) (api.ResourceList, error) {
 	toReturn := api.ResourceList{}
-	converted, err := convert.EncodeToMap(limit)
+	if limit == nil {
+		return toReturn, nil
+	}
+	standard := limit.DeepCopy()
+	standard.Extended = nil
+	converted, err := convert.EncodeToMap(standard)
 	if err != nil {
 		return nil, err
 	}
@@ -70,5 +98,71 @@ func ConvertLimitToResourceList(limit *v32.ResourceQuotaLimit) (api.ResourceList
 		}
 		toReturn[api.ResourceName(key)] = q
 	}
+	for key, value := range limit.Extended {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, err
+		}
+		toReturn[api.ResourceName(key)] = q
+	}
 	return toReturn, nil
 }
+
+// ConvertResourceListToLimit converts a resource list built by ConvertLimitToResourceList back to a limit.
+func ConvertResourceListToLimit(rList api.ResourceList) (*v32.ResourceQuotaLimit, error) {
+	standard := map[string]string{}
+	extended := map[string]string{}
+	for key, value := range rList {
+		if _, ok := standardResourceNames[string(key)]; ok {
+			standard[string(key)] = value.String()
+		} else {
+			extended[string(key)] = value.String()
+		}
+	}
+	toReturn := &v32.ResourceQuotaLimit{}
+	if err := convert.ToObj(standard, toReturn); err != nil {
+		return nil, err
+	}
+	if len(extended) > 0 {
+		toReturn.Extended = extended
+	}
+	return toReturn, nil
+}
+
+// ValidateExtendedLimit checks that the extended resources of a limit are valid Kubernetes resource names with valid
+// quantities, and that they aren't resources with a field of their own.
+func ValidateExtendedLimit(limit *v32.ResourceQuotaLimit) error {
+	if limit == nil {
+		return nil
+	}
+	for key, value := range limit.Extended {
+		if standardResource(key) {
+			return fmt.Errorf("resource %s must be set with its own field rather than as an extended resource", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid resource name %s: %s", key, strings.Join(errs, ", "))
+		}
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("invalid quantity %s for resource %s: %w", value, key, err)
+		}
+	}
+	return nil
+}
+
+// standardResource returns whether the key is a field of a ResourceQuotaLimit, or the Kubernetes name or an alias of
+// the resource of one.
+func standardResource(key string) bool {
+	if _, ok := standardResourceNames[key]; ok {
+		return true
+	}
+	switch api.ResourceName(key) {
+	case api.ResourceCPU, api.ResourceMemory:
+		return true
+	}
+	for _, name := range standardResourceNames {
+		if name == api.ResourceName(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package resourcequota
+
+import (
+	"testing"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestConvertLimitRoundTrip(t *testing.T) {
+	limit := &v32.ResourceQuotaLimit{
+		Pods:         "10",
+		LimitsMemory: "1Gi",
+		Extended: map[string]string{
+			"requests.nvidia.com/gpu": "2",
+			"count/jobs.batch":        "5",
+		},
+	}
+
+	resourceList, err := ConvertLimitToResourceList(limit)
+	assert.NoError(t, err)
+	assert.Equal(t, api.ResourceList{
+		"pods":                    resource.MustParse("10"),
+		"limitsMemory":            resource.MustParse("1Gi"),
+		"requests.nvidia.com/gpu": resource.MustParse("2"),
+		"count/jobs.batch":        resource.MustParse("5"),
+	}, resourceList)
+
+	converted, err := ConvertResourceListToLimit(resourceList)
+	assert.NoError(t, err)
+	assert.Equal(t, limit, converted)
+}
+
+func TestIsQuotaFitExtended(t *testing.T) {
+	projectLimit := &v32.ResourceQuotaLimit{
+		Pods:     "10",
+		Extended: map[string]string{"requests.nvidia.com/gpu": "4"},
+	}
+	other := &v32.ResourceQuotaLimit{
+		Pods:     "5",
+		Extended: map[string]string{"requests.nvidia.com/gpu": "3"},
+	}
+
+	isFit, _, err := IsQuotaFit(&v32.ResourceQuotaLimit{Pods: "5", Extended: map[string]string{"requests.nvidia.com/gpu": "1"}},
+		[]*v32.ResourceQuotaLimit{other}, projectLimit)
+	assert.NoError(t, err)
+	assert.True(t, isFit)
+
+	isFit, exceeded, err := IsQuotaFit(&v32.ResourceQuotaLimit{Pods: "5", Extended: map[string]string{"requests.nvidia.com/gpu": "2"}},
+		[]*v32.ResourceQuotaLimit{other}, projectLimit)
+	assert.NoError(t, err)
+	assert.False(t, isFit)
+	assert.Len(t, exceeded, 1)
+	assert.Equal(t, "5", exceeded.Name("requests.nvidia.com/gpu", resource.DecimalSI).String())
+}
+
+func TestValidateExtendedLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		extended map[string]string
+		wantErr  bool
+	}{
+		{
+			name: "valid resources",
+			extended: map[string]string{
+				"requests.nvidia.com/gpu":                           "2",
+				"gold.storageclass.storage.k8s.io/requests.storage": "10Gi",
+				"limits.ephemeral-storage":                          "5Gi",
+				"count/widgets.example.com":                         "100",
+			},
+		},
+		{
+			name:     "field name",
+			extended: map[string]string{"requestsCpu": "1"},
+			wantErr:  true,
+		},
+		{
+			name:     "kubernetes name of a field",
+			extended: map[string]string{"requests.cpu": "1"},
+			wantErr:  true,
+		},
+		{
+			name:     "alias of a field",
+			extended: map[string]string{"memory": "1Gi"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid name",
+			extended: map[string]string{"requests nvidia.com/gpu": "1"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid quantity",
+			extended: map[string]string{"requests.nvidia.com/gpu": "two"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtendedLimit(&v32.ResourceQuotaLimit{Extended: tt.extended})
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
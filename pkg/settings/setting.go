@@ -55,7 +55,17 @@
 		"cattle-elemental-system",
 	}
 
-	AgentImage          = NewSetting("agent-image", "rancher/rancher-agent:v2.9-head")
+	AgentImage = NewSetting("agent-image", "rancher/rancher-agent:v2.9-head")
+	// AgentRolloutBatchSize is the maximum number of clusters whose agent is upgraded at the same time when the agent image
+	// or features change, ie. after a Rancher upgrade. 0 upgrades the agent of every cluster at once.
+	AgentRolloutBatchSize = NewSetting("agent-rollout-batch-size", "0")
+	// AgentRolloutCanarySelector is the label selector of the clusters whose agent is upgraded before the agent of any other cluster.
+	AgentRolloutCanarySelector = NewSetting("agent-rollout-canary-selector", "")
+	// AgentRolloutFailureTimeout is how long an upgraded agent is given to be rolled out and reconnect before the rollout of
+	// agent upgrades is paused.
+	AgentRolloutFailureTimeout = NewSetting("agent-rollout-failure-timeout", "10m")
+	// AgentRolloutPaused pauses the rollout of agent upgrades when set to true.
+	AgentRolloutPaused  = NewSetting("agent-rollout-paused", "false")
 	AgentRolloutTimeout = NewSetting("agent-rollout-timeout", "300s")
 	AgentRolloutWait    = NewSetting("agent-rollout-wait", "true")
 	// AgentTLSMode is translated to the environment variable STRICT_VERIFY when rendering the cluster/node agent manifests and should not be specified as a default agent setting as it has no direct effect on the agent itself.
@@ -179,6 +189,15 @@
 	// The value should be a valid cron expression e.g. "0 * * * *" (every hour)
 	UserRetentionCron = NewSetting("user-retention-cron", "")
 
+	// HelmOperationLogMaxSize is the maximum size in bytes of the log kept for a completed helm operation
+	// once its pod is gone. Only the end of bigger logs is kept.
+	HelmOperationLogMaxSize = NewSetting("helm-operation-log-max-size", "1048576") // 1 MiB
+
+	// HelmOperationRetention is the duration a completed helm operation and its log are kept before being deleted.
+	// The value should be expressed in valid time.Duration units e.g. "168h". See https://pkg.go.dev/time#ParseDuration
+	// An empty string or a zero value means completed helm operations are never deleted.
+	HelmOperationRetention = NewSetting("helm-operation-retention", "720h") // 30 days
+
 	// ConfigMapName name of the configmap that stores rancher configuration information.
 	// Deprecated: to be removed in 2.8.0
 	ConfigMapName = NewSetting("config-map-name", "rancher-config")
@@ -318,6 +337,74 @@
 	// UIPreferred Ensure that the new Dashboard is the default UI.
 	UIPreferred = NewSetting("ui-preferred", "vue")
 
+	// ClusterProxyUserQPS is the number of requests per second each user can make to the downstream clusters through the
+	// cluster proxies, with bursts of ClusterProxyUserBurst requests. 0 disables the limit.
+	ClusterProxyUserQPS   = NewSetting("cluster-proxy-user-qps", "0")
+	ClusterProxyUserBurst = NewSetting("cluster-proxy-user-burst", "0")
+
+	// ClusterProxyClusterQPS is the number of requests per second the cluster proxies forward to each downstream cluster,
+	// with bursts of ClusterProxyClusterBurst requests. 0 disables the limit.
+	ClusterProxyClusterQPS   = NewSetting("cluster-proxy-cluster-qps", "0")
+	ClusterProxyClusterBurst = NewSetting("cluster-proxy-cluster-burst", "0")
+
+	// ClusterProxyUserMaxLongRunning is the maximum number of long-running requests (watch, exec, attach, port-forward,
+	// followed logs) each user can have open through the cluster proxies at the same time. 0 disables the limit.
+	ClusterProxyUserMaxLongRunning = NewSetting("cluster-proxy-user-max-long-running", "0")
+
+	// ClusterProxyClusterMaxLongRunning is the maximum number of long-running requests open to each downstream cluster
+	// through the cluster proxies at the same time. 0 disables the limit.
+	ClusterProxyClusterMaxLongRunning = NewSetting("cluster-proxy-cluster-max-long-running", "0")
+
+	// ClusterHealthMinReadyNodesRatio is the minimum ratio of ready nodes for the nodes of a cluster to be reported healthy.
+	ClusterHealthMinReadyNodesRatio = NewSetting("cluster-health-min-ready-nodes-ratio", "1")
+
+	// ClusterHealthCertExpiryThreshold is how long before it expires the serving certificate of the API server of a cluster
+	// is reported unhealthy.
+	ClusterHealthCertExpiryThreshold = NewSetting("cluster-health-cert-expiry-threshold", "720h")
+
+	// HostedClusterDriftPolicy is what is done when the configuration of an EKS, AKS or GKE cluster changes outside of
+	// Rancher: "adopt" the upstream configuration, only "report" the drift, or "reconcile" the cluster back to the
+	// configuration in Rancher. It is overridden per cluster by the clusters.management.cattle.io/drift-policy annotation.
+	HostedClusterDriftPolicy = NewSetting("hosted-cluster-drift-policy", "adopt")
+
+	// ImportClusterK8sVersionRange is the range of Kubernetes versions of the clusters which the pre-flight checks of
+	// imported clusters accept. When empty, the range of the minor versions of the K3s and RKE2 releases in KDM for this
+	// version of Rancher is used.
+	ImportClusterK8sVersionRange = NewSetting("import-cluster-k8s-version-range", "")
+
+	// AgentCacheMaxStaleness is how long the cluster agent keeps accepting the cluster auth tokens it cached while it
+	// is disconnected from Rancher, as a duration. Once exceeded the cached tokens are disabled until the agent
+	// reconnects. A value of 0 keeps accepting them for the whole outage.
+	AgentCacheMaxStaleness = NewSetting("agent-cache-max-staleness", "24h")
+
+	// TunnelTrustedProxies is the comma separated list of the CIDRs of the proxies and load balancers in front of
+	// Rancher whose X-Forwarded-For header is trusted for the IPs of the agents recorded in their tunnel sessions.
+	TunnelTrustedProxies = NewSetting("tunnel-trusted-proxies", "")
+
+	// RoleBindingExpiryWarning is how long before the expiry of a time-bound GlobalRoleBinding,
+	// ClusterRoleTemplateBinding or ProjectRoleTemplateBinding a warning event is created for it, as a duration.
+	RoleBindingExpiryWarning = NewSetting("role-binding-expiry-warning", "1h")
+
+	// AccessRequestClusterApproverRoles is the comma separated list of the cluster RoleTemplates whose members approve
+	// the access requests of their cluster and of its projects.
+	AccessRequestClusterApproverRoles = NewSetting("access-request-cluster-approver-roles", "cluster-owner")
+
+	// AccessRequestProjectApproverRoles is the comma separated list of the project RoleTemplates whose members approve
+	// the access requests of their project.
+	AccessRequestProjectApproverRoles = NewSetting("access-request-project-approver-roles", "project-owner")
+
+	// AccessReviewCron determines how often the access review reporting on all the role bindings runs. The access
+	// review is disabled if empty.
+	AccessReviewCron = NewSetting("access-review-cron", "")
+
+	// AccessReviewInactiveUserAfter is how long since their last login the bindings of a user are flagged as stale by
+	// the access review, as a duration. Bindings aren't flagged for inactivity if empty.
+	AccessReviewInactiveUserAfter = NewSetting("access-review-inactive-user-after", "2160h")
+
+	// AccessReviewConfirmationPeriod is how long the stale bindings flagged by the access review have to be confirmed
+	// in a BindingReview before they are removed, as a duration. No BindingReviews are opened if empty.
+	AccessReviewConfirmationPeriod = NewSetting("access-review-confirmation-period", "")
+
 	// SkipHostedClusterChartInstallation controls whether the hosted cluster chart is installed on the server. Defaults to false.
 	// This setting is for development purposes only.
 	SkipHostedClusterChartInstallation = NewSetting("skip-hosted-cluster-chart-installation", os.Getenv("CATTLE_SKIP_HOSTED_CLUSTER_CHART_INSTALLATION"))
@@ -511,6 +598,7 @@ func DefaultAgentSettings() []Setting {
 		ServerVersion,
 		InstallUUID,
 		IngressIPDomain,
+		AgentCacheMaxStaleness,
 	}
 }
 
@@ -0,0 +1,90 @@
+package systemtemplate
+
+import (
+	stdcontext "context"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/blang/semver"
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/channelserver"
+	util "github.com/rancher/rancher/pkg/cluster"
+	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rke/templates"
+)
+
+var preflightTemplate = template.Must(template.New("preflight").Funcs(templateFuncMap).Parse(preflightTemplateSource))
+
+type preflightContext struct {
+	AgentImage            string
+	AgentEnvVars          string
+	URLPlain              string
+	CAChecksum            string
+	K8sVersionRange       string
+	ReportURL             string
+	PrivateRegistryConfig string
+}
+
+// PreflightTemplate writes the manifest of the job checking, before the agent is installed, that a cluster can be
+// imported into Rancher at url. The report of the checks is logged by the job and sent to reportURL, if set.
+func PreflightTemplate(resp io.Writer, agentImage, url, reportURL string, cluster *apimgmtv3.Cluster, secretLister v1.SecretLister) error {
+	_, registryConfig, err := util.GeneratePrivateRegistryEncodedDockerConfig(cluster, secretLister)
+	if err != nil {
+		return err
+	}
+
+	// the env vars of the agent, ie. its proxy configuration, apply to the checks
+	envVars := settings.DefaultAgentSettingsAsEnvVars()
+	if cluster != nil {
+		envVars = append(envVars, cluster.Spec.AgentEnvVars...)
+	}
+
+	return preflightTemplate.Execute(resp, &preflightContext{
+		AgentImage:            agentImage,
+		AgentEnvVars:          templates.ToYAML(envVars),
+		URLPlain:              url,
+		CAChecksum:            CAChecksum(),
+		K8sVersionRange:       importK8sVersionRange(),
+		ReportURL:             reportURL,
+		PrivateRegistryConfig: registryConfig,
+	})
+}
+
+// importK8sVersionRange returns the import-cluster-k8s-version-range setting or, if it is not set, the range of the
+// minor versions of the K3s and RKE2 releases KDM has for this version of Rancher.
+func importK8sVersionRange() string {
+	if versionRange := settings.ImportClusterK8sVersionRange.Get(); versionRange != "" {
+		return versionRange
+	}
+	var versions []string
+	for _, runtime := range []string{"k3s", "rke2"} {
+		for _, release := range channelserver.GetReleaseConfigByRuntime(stdcontext.TODO(), runtime).ReleasesConfig().Releases {
+			versions = append(versions, release.Version)
+		}
+	}
+	return minorVersionRange(versions)
+}
+
+// minorVersionRange returns the range from the lowest to the highest minor version of the versions, or an empty range
+// accepting every version if none can be parsed.
+func minorVersionRange(versions []string) string {
+	var lowest, highest *semver.Version
+	for _, v := range versions {
+		version, err := semver.ParseTolerant(v)
+		if err != nil {
+			continue
+		}
+		if lowest == nil || version.LT(*lowest) {
+			lowest = &version
+		}
+		if highest == nil || version.GT(*highest) {
+			highest = &version
+		}
+	}
+	if lowest == nil {
+		return ""
+	}
+	return fmt.Sprintf(">=%d.%d.0 <%d.%d.0", lowest.Major, lowest.Minor, highest.Major, highest.Minor+1)
+}
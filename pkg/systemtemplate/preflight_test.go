@@ -0,0 +1,89 @@
+package systemtemplate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestPreflightTemplate(t *testing.T) {
+	cluster := &apimgmtv3.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-1"},
+		Spec: apimgmtv3.ClusterSpec{
+			ClusterSpecBase: apimgmtv3.ClusterSpecBase{
+				AgentEnvVars: []corev1.EnvVar{{Name: "HTTPS_PROXY", Value: "http://proxy:3128"}},
+			},
+		},
+	}
+
+	require.NoError(t, settings.ImportClusterK8sVersionRange.Set(">=1.27.0 <1.31.0"))
+	t.Cleanup(func() { _ = settings.ImportClusterK8sVersionRange.Set("") })
+
+	var b bytes.Buffer
+	err := PreflightTemplate(&b, "rancher/rancher-agent:v2.9.0", "https://rancher.example.com", "https://rancher.example.com/v3/import/token_c-1/preflight", cluster, nil)
+	require.NoError(t, err)
+
+	var job *batchv1.Job
+	bindings := map[string]*rbacv1.ClusterRoleBinding{}
+	clusterRoles := map[string]*rbacv1.ClusterRole{}
+	decoder := scheme.Codecs.UniversalDeserializer()
+	for _, r := range strings.Split(b.String(), "---") {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		obj, _, err := decoder.Decode([]byte(r), nil, nil)
+		require.NoError(t, err)
+		switch o := obj.(type) {
+		case *batchv1.Job:
+			job = o
+		case *rbacv1.ClusterRoleBinding:
+			bindings[o.Name] = o
+		case *rbacv1.ClusterRole:
+			clusterRoles[o.Name] = o
+		}
+	}
+
+	// a stand-in of the agent is bound like the agent, without touching its RBAC, the checks to their own scoped role
+	assert.NotContains(t, bindings, "cattle-admin-binding")
+	assert.NotContains(t, clusterRoles, "cattle-admin")
+	require.Contains(t, bindings, "cattle-preflight-agent")
+	assert.Equal(t, "cattle-preflight-agent", bindings["cattle-preflight-agent"].Subjects[0].Name)
+	assert.Equal(t, "cattle-preflight-agent", bindings["cattle-preflight-agent"].RoleRef.Name)
+	require.Contains(t, bindings, "cattle-preflight-binding")
+	assert.Equal(t, "cattle-preflight", bindings["cattle-preflight-binding"].RoleRef.Name)
+	require.Contains(t, clusterRoles, "cattle-preflight")
+	for _, rule := range clusterRoles["cattle-preflight"].Rules {
+		assert.NotContains(t, rule.Verbs, "*")
+		assert.NotContains(t, rule.Resources, "*")
+	}
+
+	require.NotNil(t, job)
+	require.Len(t, job.Spec.Template.Spec.Containers, 1)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rancher/rancher-agent:v2.9.0", container.Image)
+	assert.Equal(t, []string{"agent", "preflight"}, container.Command)
+
+	env := map[string]string{}
+	for _, envVar := range container.Env {
+		env[envVar.Name] = envVar.Value
+	}
+	assert.Equal(t, "https://rancher.example.com", env["CATTLE_SERVER"])
+	assert.Equal(t, "https://rancher.example.com/v3/import/token_c-1/preflight", env["CATTLE_PREFLIGHT_REPORT_URL"])
+	assert.Equal(t, ">=1.27.0 <1.31.0", env["CATTLE_PREFLIGHT_K8S_VERSION_RANGE"])
+	assert.Equal(t, "http://proxy:3128", env["HTTPS_PROXY"])
+}
+
+func TestMinorVersionRange(t *testing.T) {
+	assert.Equal(t, ">=1.27.0 <1.31.0", minorVersionRange([]string{"v1.28.9+k3s1", "v1.30.2+rke2r1", "v1.27.15+k3s2", "invalid"}))
+	assert.Equal(t, "", minorVersionRange(nil))
+}
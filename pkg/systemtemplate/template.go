@@ -504,3 +504,204 @@
     namespace: cattle-system
 `
 )
+
+var preflightTemplateSource = `
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: cattle-system
+
+---
+
+# A stand-in for the service account of the agent, bound to the same permissions as in the manifest of the agent, so
+# that applying this manifest fails if the user is not allowed to grant them, and the checks verify that they are
+# effective. The checks delete it with its RBAC when they are done, nothing of the agent is left behind.
+
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: cattle-preflight-agent
+  namespace: cattle-system
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: cattle-preflight-agent
+subjects:
+- kind: ServiceAccount
+  name: cattle-preflight-agent
+  namespace: cattle-system
+roleRef:
+  kind: ClusterRole
+  name: cattle-preflight-agent
+  apiGroup: rbac.authorization.k8s.io
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cattle-preflight-agent
+rules:
+- apiGroups:
+  - '*'
+  resources:
+  - '*'
+  verbs:
+  - '*'
+- nonResourceURLs:
+  - '*'
+  verbs:
+  - '*'
+
+---
+
+# The checks run as their own service account, only allowed to review the permissions of the agent and to delete the
+# stand-in of the agent and their own service account, role and binding when they are done.
+
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: cattle-preflight
+  namespace: cattle-system
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: cattle-preflight
+rules:
+- apiGroups: ["authorization.k8s.io"]
+  resources: ["subjectaccessreviews"]
+  verbs: ["create"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["clusterroles"]
+  resourceNames: ["cattle-preflight"]
+  verbs: ["get", "delete"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["clusterrolebindings"]
+  resourceNames: ["cattle-preflight-binding"]
+  verbs: ["get", "patch"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["clusterroles", "clusterrolebindings"]
+  resourceNames: ["cattle-preflight-agent"]
+  verbs: ["delete"]
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: cattle-preflight-binding
+subjects:
+- kind: ServiceAccount
+  name: cattle-preflight
+  namespace: cattle-system
+roleRef:
+  kind: ClusterRole
+  name: cattle-preflight
+  apiGroup: rbac.authorization.k8s.io
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: cattle-preflight
+  namespace: cattle-system
+rules:
+- apiGroups: [""]
+  resources: ["serviceaccounts"]
+  resourceNames: ["cattle-preflight"]
+  verbs: ["get", "patch"]
+- apiGroups: [""]
+  resources: ["serviceaccounts"]
+  resourceNames: ["cattle-preflight-agent"]
+  verbs: ["delete"]
+- apiGroups: ["rbac.authorization.k8s.io"]
+  resources: ["roles", "rolebindings"]
+  resourceNames: ["cattle-preflight"]
+  verbs: ["get", "patch"]
+
+---
+
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: cattle-preflight
+  namespace: cattle-system
+subjects:
+- kind: ServiceAccount
+  name: cattle-preflight
+  namespace: cattle-system
+roleRef:
+  kind: Role
+  name: cattle-preflight
+  apiGroup: rbac.authorization.k8s.io
+
+---
+
+{{- if .PrivateRegistryConfig}}
+apiVersion: v1
+kind: Secret
+metadata:
+  name: cattle-private-registry
+  namespace: cattle-system
+type: kubernetes.io/dockerconfigjson
+data:
+  .dockerconfigjson: "{{.PrivateRegistryConfig}}"
+
+---
+{{- end }}
+
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: cattle-preflight
+  namespace: cattle-system
+spec:
+  backoffLimit: 0
+  ttlSecondsAfterFinished: 3600
+  template:
+    metadata:
+      labels:
+        app: cattle-preflight
+    spec:
+      serviceAccountName: cattle-preflight
+      restartPolicy: Never
+      tolerations:
+      - effect: NoSchedule
+        key: node-role.kubernetes.io/controlplane
+        value: "true"
+      - effect: NoSchedule
+        key: "node-role.kubernetes.io/control-plane"
+        operator: "Exists"
+      - effect: NoSchedule
+        key: "node-role.kubernetes.io/master"
+        operator: "Exists"
+      containers:
+        - name: preflight
+          image: {{.AgentImage}}
+          imagePullPolicy: IfNotPresent
+          command: ["agent", "preflight"]
+          env:
+          - name: CATTLE_SERVER
+            value: "{{.URLPlain}}"
+          - name: CATTLE_CA_CHECKSUM
+            value: "{{.CAChecksum}}"
+          - name: CATTLE_PREFLIGHT_K8S_VERSION_RANGE
+            value: "{{.K8sVersionRange}}"
+          - name: CATTLE_PREFLIGHT_REPORT_URL
+            value: "{{.ReportURL}}"
+      {{- if .AgentEnvVars}}
+{{ .AgentEnvVars | indent 10 }}
+      {{- end }}
+      {{- if .PrivateRegistryConfig}}
+      imagePullSecrets:
+      - name: cattle-private-registry
+      {{- end }}
+`
@@ -36,6 +36,7 @@ func (a *Authorizers) Authorize(req *http.Request) (clientKey string, authed boo
 			}
 			continue
 		}
+		authorized(req, key)
 		return key, authed, err
 	}
 
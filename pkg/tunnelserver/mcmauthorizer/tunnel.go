@@ -387,6 +387,11 @@ func machineName(machine *client.Node) string {
 	return machineNameMD5
 }
 
+// ClusterByToken returns the cluster of the registration token, ErrClusterNotFound if there is none.
+func (t *Authorizer) ClusterByToken(token string) (*v3.Cluster, error) {
+	return t.getClusterByToken(token)
+}
+
 func (t *Authorizer) getClusterByToken(token string) (*v3.Cluster, error) {
 	keys, err := t.crtIndexer.ByIndex(crtKeyIndex, token)
 	if err != nil {
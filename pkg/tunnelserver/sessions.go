@@ -0,0 +1,305 @@
+package tunnelserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/tunnelserver/tunnelheaders"
+	"github.com/rancher/remotedialer"
+)
+
+const (
+	// Reasons why a tunnel session was disconnected.
+	DisconnectReasonConnectionClosed = "ConnectionClosed"
+	DisconnectReasonReplaced         = "Replaced"
+	DisconnectReasonServerShutdown   = "ServerShutdown"
+
+	// sessionHistorySize is the number of sessions kept in the history of a client.
+	sessionHistorySize = 32
+	// clientHistoryRetention is how long the history of a client without sessions is kept.
+	clientHistoryRetention = 24 * time.Hour
+)
+
+var (
+	sessionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("", "cluster_manager", "tunnel_sessions"),
+		"Number of tunnel sessions of an agent handled by this Rancher server",
+		[]string{"client"}, nil)
+	connectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("", "cluster_manager", "tunnel_connects_total"),
+		"Number of tunnel sessions opened by an agent on this Rancher server",
+		[]string{"client"}, nil)
+	disconnectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("", "cluster_manager", "tunnel_disconnects_total"),
+		"Number of tunnel sessions of an agent closed on this Rancher server",
+		[]string{"client", "reason"}, nil)
+	lastSeenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("", "cluster_manager", "tunnel_last_seen_timestamp_seconds"),
+		"Last time an agent was seen connected to this Rancher server",
+		[]string{"client"}, nil)
+)
+
+// SessionRecord describes a tunnel session opened by an agent.
+type SessionRecord struct {
+	ConnectedAt      time.Time  `json:"connectedAt"`
+	DisconnectedAt   *time.Time `json:"disconnectedAt,omitempty"`
+	DisconnectReason string     `json:"disconnectReason,omitempty"`
+	// Peer is the ID of the Rancher server which handled the session.
+	Peer         string `json:"peer,omitempty"`
+	ClientIP     string `json:"clientIP,omitempty"`
+	AgentVersion string `json:"agentVersion,omitempty"`
+}
+
+// ClientSessions is the session history of an agent, identified by its tunnel client key.
+type ClientSessions struct {
+	ClientKey      string    `json:"clientKey"`
+	ActiveSessions int       `json:"activeSessions"`
+	LastSeen       time.Time `json:"lastSeen"`
+	// Sessions are the most recent sessions of the agent, oldest first.
+	Sessions []SessionRecord `json:"sessions"`
+}
+
+type clientHistory struct {
+	// sessions is a ring buffer of the most recent sessions, next is the index of the oldest one once full.
+	sessions    []*SessionRecord
+	next        int
+	active      int
+	lastSeen    time.Time
+	connects    float64
+	disconnects map[string]float64
+}
+
+func (c *clientHistory) add(record *SessionRecord) {
+	if len(c.sessions) < sessionHistorySize {
+		c.sessions = append(c.sessions, record)
+		return
+	}
+	c.sessions[c.next] = record
+	c.next = (c.next + 1) % sessionHistorySize
+}
+
+func (c *clientHistory) list() []SessionRecord {
+	result := make([]SessionRecord, 0, len(c.sessions))
+	for i := range c.sessions {
+		result = append(result, *c.sessions[(c.next+i)%len(c.sessions)])
+	}
+	return result
+}
+
+// SessionHistory records the tunnel sessions opened by agents on this Rancher server, in a bounded history per
+// agent, and exposes them as Prometheus metrics. Only the sessions handled by this Rancher server are recorded.
+type SessionHistory struct {
+	ctx    context.Context
+	server *remotedialer.Server
+
+	lock    sync.Mutex
+	clients map[string]*clientHistory
+}
+
+func NewSessionHistory(ctx context.Context, server *remotedialer.Server) *SessionHistory {
+	return &SessionHistory{
+		ctx:     ctx,
+		server:  server,
+		clients: map[string]*clientHistory{},
+	}
+}
+
+type sessionContextKey struct{}
+
+// session tracks a tunnel request through the authorizers of the tunnel server.
+type session struct {
+	history   *SessionHistory
+	clientKey string
+	record    *SessionRecord
+}
+
+// Handler wraps the tunnel server handler to record the sessions authorized by the Authorizers.
+func (h *SessionHistory) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s := &session{history: h}
+		next.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), sessionContextKey{}, s)))
+		if s.record != nil {
+			h.disconnected(s)
+		}
+	})
+}
+
+// authorized records the session of the tunnel request, if its history is recorded, once an authorizer accepted it.
+func authorized(req *http.Request, clientKey string) {
+	s, ok := req.Context().Value(sessionContextKey{}).(*session)
+	if !ok || s.record != nil {
+		return
+	}
+	s.history.connected(s, req, clientKey)
+}
+
+func (h *SessionHistory) connected(s *session, req *http.Request, clientKey string) {
+	now := time.Now()
+	s.clientKey = clientKey
+	s.record = &SessionRecord{
+		ConnectedAt:  now,
+		Peer:         h.server.PeerID,
+		ClientIP:     clientIP(req),
+		AgentVersion: req.Header.Get(tunnelheaders.AgentVersion),
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.prune(now)
+	client, ok := h.clients[clientKey]
+	if !ok {
+		client = &clientHistory{disconnects: map[string]float64{}}
+		h.clients[clientKey] = client
+	}
+	client.add(s.record)
+	client.active++
+	client.connects++
+	client.lastSeen = now
+}
+
+func (h *SessionHistory) disconnected(s *session) {
+	now := time.Now()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	client, ok := h.clients[s.clientKey]
+	if !ok {
+		return
+	}
+
+	reason := DisconnectReasonConnectionClosed
+	if h.ctx.Err() != nil {
+		reason = DisconnectReasonServerShutdown
+	} else {
+		// the agent opened a new session while this one was still served
+		for _, record := range client.sessions {
+			if record != s.record && !record.ConnectedAt.Before(s.record.ConnectedAt) {
+				reason = DisconnectReasonReplaced
+				break
+			}
+		}
+	}
+
+	s.record.DisconnectedAt = &now
+	s.record.DisconnectReason = reason
+	client.active--
+	client.disconnects[reason]++
+	client.lastSeen = now
+}
+
+// Seen records that the agent with the given client key is connected, ie. it answered a ping through its session.
+func (h *SessionHistory) Seen(clientKey string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if client, ok := h.clients[clientKey]; ok {
+		client.lastSeen = time.Now()
+	}
+}
+
+// Get returns the session history of the agent with the given client key, if any session was recorded.
+func (h *SessionHistory) Get(clientKey string) (ClientSessions, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	client, ok := h.clients[clientKey]
+	if !ok {
+		return ClientSessions{}, false
+	}
+	return ClientSessions{
+		ClientKey:      clientKey,
+		ActiveSessions: client.active,
+		LastSeen:       client.lastSeen,
+		Sessions:       client.list(),
+	}, true
+}
+
+// prune removes the history of the clients without sessions which weren't seen for a while. The lock must be held.
+func (h *SessionHistory) prune(now time.Time) {
+	for clientKey, client := range h.clients {
+		if client.active == 0 && now.Sub(client.lastSeen) > clientHistoryRetention {
+			delete(h.clients, clientKey)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (h *SessionHistory) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sessionsDesc
+	ch <- connectsDesc
+	ch <- disconnectsDesc
+	ch <- lastSeenDesc
+}
+
+// Collect implements prometheus.Collector.
+func (h *SessionHistory) Collect(ch chan<- prometheus.Metric) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	clientKeys := make([]string, 0, len(h.clients))
+	for clientKey := range h.clients {
+		clientKeys = append(clientKeys, clientKey)
+	}
+	sort.Strings(clientKeys)
+
+	for _, clientKey := range clientKeys {
+		client := h.clients[clientKey]
+		ch <- prometheus.MustNewConstMetric(sessionsDesc, prometheus.GaugeValue, float64(client.active), clientKey)
+		ch <- prometheus.MustNewConstMetric(connectsDesc, prometheus.CounterValue, client.connects, clientKey)
+		for reason, count := range client.disconnects {
+			ch <- prometheus.MustNewConstMetric(disconnectsDesc, prometheus.CounterValue, count, clientKey, reason)
+		}
+		ch <- prometheus.MustNewConstMetric(lastSeenDesc, prometheus.GaugeValue, float64(client.lastSeen.Unix()), clientKey)
+	}
+}
+
+// clientIP returns the IP of the agent. X-Forwarded-For is only honoured for the hops added by the proxies trusted by
+// the tunnel-trusted-proxies setting, so that an agent can't set the IP it is recorded with.
+func clientIP(req *http.Request) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	trusted := trustedProxies()
+	forwardedFor := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	// walk back the hops added by the trusted proxies, from the closest one
+	for i := len(forwardedFor) - 1; i >= 0 && isTrustedProxy(ip, trusted); i-- {
+		if hop := strings.TrimSpace(forwardedFor[i]); hop != "" {
+			ip = hop
+		}
+	}
+	return ip
+}
+
+func trustedProxies() []*net.IPNet {
+	var result []*net.IPNet
+	for _, cidr := range strings.Split(settings.TunnelTrustedProxies.Get(), ",") {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr)); err == nil {
+			result = append(result, ipNet)
+		}
+	}
+	return result
+}
+
+func isTrustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package tunnelserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/tunnelserver/tunnelheaders"
+	"github.com/rancher/remotedialer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := remotedialer.New(nil, nil)
+	server.PeerID = "10.0.0.1"
+	history := NewSessionHistory(ctx, server)
+
+	auth := &Authorizers{}
+	auth.Add(func(req *http.Request) (string, bool, error) {
+		return "c-abcde", true, nil
+	})
+
+	// the tunnel handler blocks while the session is served
+	var serve func(req *http.Request)
+	handler := history.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _, _ = auth.Authorize(req)
+		serve(req)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/v3/connect", nil)
+		req.RemoteAddr = "192.168.0.10:41234"
+		req.Header.Set(tunnelheaders.AgentVersion, "v2.9.0")
+		return req
+	}
+
+	// the first session is replaced by a second one, which is closed
+	serve = func(req *http.Request) {
+		serve = func(*http.Request) {}
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	sessions, ok := history.Get("c-abcde")
+	require.True(t, ok)
+	assert.Equal(t, 0, sessions.ActiveSessions)
+	require.Len(t, sessions.Sessions, 2)
+	assert.Equal(t, "10.0.0.1", sessions.Sessions[0].Peer)
+	assert.Equal(t, "192.168.0.10", sessions.Sessions[0].ClientIP)
+	assert.Equal(t, "v2.9.0", sessions.Sessions[0].AgentVersion)
+	assert.Equal(t, DisconnectReasonReplaced, sessions.Sessions[0].DisconnectReason)
+	assert.Equal(t, DisconnectReasonConnectionClosed, sessions.Sessions[1].DisconnectReason)
+
+	// the history is bounded
+	for i := 0; i < sessionHistorySize; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	}
+	sessions, _ = history.Get("c-abcde")
+	assert.Len(t, sessions.Sessions, sessionHistorySize)
+	for i := 1; i < len(sessions.Sessions); i++ {
+		assert.False(t, sessions.Sessions[i].ConnectedAt.Before(sessions.Sessions[i-1].ConnectedAt))
+	}
+
+	cancel()
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+	sessions, _ = history.Get("c-abcde")
+	assert.Equal(t, DisconnectReasonServerShutdown, sessions.Sessions[len(sessions.Sessions)-1].DisconnectReason)
+
+	expected := `
+# HELP cluster_manager_tunnel_connects_total Number of tunnel sessions opened by an agent on this Rancher server
+# TYPE cluster_manager_tunnel_connects_total counter
+cluster_manager_tunnel_connects_total{client="c-abcde"} 35
+# HELP cluster_manager_tunnel_disconnects_total Number of tunnel sessions of an agent closed on this Rancher server
+# TYPE cluster_manager_tunnel_disconnects_total counter
+cluster_manager_tunnel_disconnects_total{client="c-abcde",reason="ConnectionClosed"} 33
+cluster_manager_tunnel_disconnects_total{client="c-abcde",reason="Replaced"} 1
+cluster_manager_tunnel_disconnects_total{client="c-abcde",reason="ServerShutdown"} 1
+# HELP cluster_manager_tunnel_sessions Number of tunnel sessions of an agent handled by this Rancher server
+# TYPE cluster_manager_tunnel_sessions gauge
+cluster_manager_tunnel_sessions{client="c-abcde"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(history, strings.NewReader(expected),
+		"cluster_manager_tunnel_connects_total", "cluster_manager_tunnel_disconnects_total", "cluster_manager_tunnel_sessions"))
+}
+
+func TestSessionHistoryUnauthorized(t *testing.T) {
+	history := NewSessionHistory(context.Background(), remotedialer.New(nil, nil))
+	auth := &Authorizers{}
+	auth.Add(func(req *http.Request) (string, bool, error) {
+		return "", false, nil
+	})
+
+	handler := history.Handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _, _ = auth.Authorize(req)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v3/connect", nil))
+
+	_, ok := history.Get("")
+	assert.False(t, ok)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v3/connect", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	assert.Equal(t, "10.0.0.2", clientIP(req))
+
+	// the header is ignored unless the request comes from a trusted proxy
+	req.Header.Set("X-Forwarded-For", "192.168.0.1, 10.0.0.3")
+	assert.Equal(t, "10.0.0.2", clientIP(req))
+
+	original := settings.TunnelTrustedProxies.Get()
+	t.Cleanup(func() { _ = settings.TunnelTrustedProxies.Set(original) })
+	require.NoError(t, settings.TunnelTrustedProxies.Set("10.0.0.0/24"))
+	assert.Equal(t, "192.168.0.1", clientIP(req))
+
+	// hops added before the trusted proxies can be forged by the client
+	req.Header.Set("X-Forwarded-For", "10.0.0.4, 192.168.0.1, 10.0.0.3")
+	assert.Equal(t, "192.168.0.1", clientIP(req))
+}
@@ -0,0 +1,7 @@
+// Package tunnelheaders holds the headers shared by the tunnel server and its clients, without their dependencies.
+package tunnelheaders
+
+const (
+	// AgentVersion is the header an agent sets to its version when opening a tunnel session.
+	AgentVersion = "X-API-Tunnel-Agent-Version"
+)
@@ -24,7 +24,9 @@
 	helmcfg "github.com/rancher/rancher/pkg/catalogv2/helm"
 	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	"github.com/rancher/rancher/pkg/catalogv2/system"
+	"github.com/rancher/rancher/pkg/clusterrouter/ratelimit"
 	"github.com/rancher/rancher/pkg/controllers"
+	"github.com/rancher/rancher/pkg/egressproxy"
 	"github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io"
 	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	capi "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io"
@@ -64,6 +66,7 @@
 	"github.com/rancher/wrangler/v3/pkg/leader"
 	"github.com/rancher/wrangler/v3/pkg/schemes"
 	"github.com/sirupsen/logrus"
+	k8scorev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -75,9 +78,11 @@
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
 	apiregistrationv12 "k8s.io/kube-aggregator/pkg/apis/apiregistration/v1"
 	capiv1beta1api "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -124,6 +129,8 @@ type Context struct {
 	MultiClusterManager MultiClusterManager
 	TunnelServer        *remotedialer.Server
 	TunnelAuthorizer    *tunnelserver.Authorizers
+	TunnelSessions      *tunnelserver.SessionHistory
+	ClusterProxyLimiter *ratelimit.Limiter
 	PeerManager         peermanager.PeerManager
 	Provisioning        provisioningv1.Interface
 	RBAC                rbacv1.Interface
@@ -131,6 +138,8 @@ type Context struct {
 	API                 apiregv1.Interface
 	CRD                 crdv1.Interface
 	K8s                 *kubernetes.Clientset
+	// EventRecorder records the events of the controllers of Rancher.
+	EventRecorder record.EventRecorder
 
 	ASL                     accesscontrol.AccessSetLookup
 	ClientConfig            clientcmd.ClientConfig
@@ -360,6 +369,9 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 
 	asl := accesscontrol.NewAccessStore(ctx, true, rbac.Rbac().V1())
 
+	eventBroadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8s.CoreV1().Events("")})
+
 	cg, err := client.NewFactory(restConfig, false)
 	if err != nil {
 		return nil, err
@@ -369,13 +381,15 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		k8s.Discovery(),
 		core.Core().V1().ConfigMap().Cache(),
 		core.Core().V1().Secret().Cache(),
-		helm.Catalog().V1().ClusterRepo().Cache())
+		helm.Catalog().V1().ClusterRepo().Cache(),
+		egressproxy.NewResolver(core.Core().V1().Secret().Cache()).ClusterProxyFunc(mgmt.Management().V3().Cluster().Cache(), "local"))
 
 	helmop := helmop.NewOperations(cg,
 		helm.Catalog().V1(),
 		rbac.Rbac().V1(),
 		content,
 		core.Core().V1().Pod(),
+		core.Core().V1().Secret(),
 		core.Core().V1().Node())
 
 	cache := memory.NewMemCacheClient(k8s.Discovery())
@@ -428,6 +442,7 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		API:                     api.Apiregistration().V1(),
 		CRD:                     crd.Apiextensions().V1(),
 		K8s:                     k8s,
+		EventRecorder:           eventBroadcaster.NewRecorder(Scheme, k8scorev1.EventSource{Component: "rancher"}),
 		ControllerFactory:       controllerFactory,
 		ASL:                     asl,
 		ClientConfig:            clientConfig,
@@ -443,6 +458,8 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		SystemChartsManager:     systemCharts,
 		TunnelAuthorizer:        tunnelAuth,
 		TunnelServer:            tunnelServer,
+		TunnelSessions:          tunnelserver.NewSessionHistory(ctx, tunnelServer),
+		ClusterProxyLimiter:     ratelimit.NewLimiter(ctx),
 
 		mgmt:         mgmt,
 		apps:         apps,